@@ -0,0 +1,110 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+)
+
+// aggregationView は create_aggregation_views で作成する集計テーブルとマテリアライズドビューです
+// 集計テーブルの名前はメインテーブル名に suffix を付けたもの、ビューはさらに _mv を付けたものです
+type aggregationView struct {
+	signal      string
+	suffix      string
+	description string
+	orderBy     string // SummingMergeTree で合算しない列はすべて含める
+	tableFile   string
+	viewFile    string
+}
+
+// aggregationViews はシグナルごとの集計です（ダッシュボードでよく使う集計をメインテーブルを走査せずに参照するため）
+var aggregationViews = []aggregationView{
+	{
+		signal:      "traces",
+		suffix:      "_errors_1m",
+		description: "trace errors per minute",
+		orderBy:     "(ServiceName, Timestamp)",
+		tableFile:   "traces_errors_1m_table.sql",
+		viewFile:    "traces_errors_1m_mv.sql",
+	},
+	{
+		signal:      "logs",
+		suffix:      "_severity_1m",
+		description: "log severity per minute",
+		orderBy:     "(ServiceName, SeverityNumber, SeverityText, Timestamp)",
+		tableFile:   "logs_severity_1m_table.sql",
+		viewFile:    "logs_severity_1m_mv.sql",
+	},
+}
+
+// signalAggregationViews はシグナルの集計を返します（create_aggregation_views 無効時は空）
+func (cfg *Config) signalAggregationViews(signal string) []aggregationView {
+	if !cfg.CreateAggregationViews {
+		return nil
+	}
+	var views []aggregationView
+	for _, v := range aggregationViews {
+		if v.signal == signal {
+			views = append(views, v)
+		}
+	}
+	return views
+}
+
+// renderTableSQL は集計テーブル作成SQLを生成します（保持期間・クラスター・レプリケーションはメインテーブルと同じ）
+func (v aggregationView) renderTableSQL(cfg *Config, database, table string) (string, error) {
+	return internal.RenderSQLTemplate(v.tableFile, internal.TableTemplateData{
+		Database: database,
+		Table:    cfg.localTable(table),
+		Cluster:  cfg.clusterString(),
+		Engine:   cfg.replicatedEngine("SummingMergeTree()"),
+		OrderBy:  v.orderBy,
+		TTL:      internal.GenerateTTLExpr(cfg.signalTTL(v.signal), "Timestamp"),
+		Settings: cfg.tableSettings(),
+	})
+}
+
+// renderViewSQL はメインテーブルから集計テーブルに書き込むマテリアライズドビュー作成SQLを生成します
+func (v aggregationView) renderViewSQL(cfg *Config, database, table string) (string, error) {
+	return internal.RenderSQLTemplate(v.viewFile, internal.TableTemplateData{
+		Database: database,
+		Table:    cfg.localTable(table),
+		Cluster:  cfg.clusterString(),
+	})
+}
+
+// createAggregationViews はシグナルの集計テーブルとマテリアライズドビューを作成します（create_aggregation_views 有効時のみ）
+// ビューは作成後に挿入された行のみを集計します（既存の行は集計しない）
+func createAggregationViews(ctx context.Context, cfg *Config, db *sql.DB, telemetry *exporterTelemetry, signal, database, table string, logger *zap.Logger) error {
+	for _, v := range cfg.signalAggregationViews(signal) {
+		tableSQL, err := v.renderTableSQL(cfg, database, table)
+		if err != nil {
+			telemetry.recordRenderFailure(ctx, v.tableFile)
+			return err
+		}
+		if _, err := db.ExecContext(ctx, tableSQL); err != nil {
+			return fmt.Errorf("集計テーブル %s.%s の作成に失敗しました: %w", database, table+v.suffix, err)
+		}
+		viewSQL, err := v.renderViewSQL(cfg, database, table)
+		if err != nil {
+			telemetry.recordRenderFailure(ctx, v.viewFile)
+			return err
+		}
+		if _, err := db.ExecContext(ctx, viewSQL); err != nil {
+			return fmt.Errorf("集計のマテリアライズドビュー %s.%s_mv の作成に失敗しました: %w", database, table+v.suffix, err)
+		}
+		if err := createDistributedTable(ctx, cfg, db, database, table+v.suffix, cfg.localTable(table)+v.suffix, logger); err != nil {
+			return err
+		}
+		logger.Info("集計テーブルとマテリアライズドビューを作成しました",
+			zap.String("database", database), zap.String("table", table+v.suffix))
+	}
+	return nil
+}
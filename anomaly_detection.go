@@ -0,0 +1,222 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// anomalyScoreColumn はサービスの異常度を保存する列の名前です（挿入SQLの末尾に追加される）
+const anomalyScoreColumn = stable.ColumnAnomalyScore
+
+// 基準値の標準偏差の下限（値がほぼ一定のサービスでわずかな変化を異常としないため）
+const (
+	minErrorRateDeviation   = 0.01 // エラー率（0〜1）の標準偏差の下限
+	minLatencyDeviationRate = 0.05 // 平均レイテンシに対する標準偏差の下限の割合
+)
+
+// AnomalyDetectionConfig - サービスごとのエラー率とレイテンシの基準値からの逸脱を検出する設定（トレースのみ）
+// 送信ごとにサービスのエラー率と平均レイテンシを指数移動平均の基準値と比較し、
+// 標準偏差の何倍離れているか（増加方向のみ）を異常度として AnomalyScore 列に保存します
+// 基準値はメモリ上にのみ保持するため、再起動後は min_samples 回の送信まで異常度は0です
+// 既存のテーブルには列が追加されないため、column を有効化する場合は ALTER TABLE で列を追加してください
+type AnomalyDetectionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Alpha は基準値の指数移動平均の平滑化係数です（大きいほど直近の送信の影響が大きい）
+	Alpha float64 `mapstructure:"alpha"`
+	// Threshold は異常とみなす異常度です（超えた場合は警告ログとライフサイクルイベントを送信する）
+	Threshold float64 `mapstructure:"threshold"`
+	// MinSamples は異常度の計算を始めるまでの送信回数です（基準値が安定するまで待つ）
+	MinSamples int `mapstructure:"min_samples"`
+	// MinSpans は1回の送信でサービスの統計を計算する最小スパン数です（少ないスパンの送信は基準値に含めない）
+	MinSpans int `mapstructure:"min_spans"`
+	// MaxServices は基準値を保持するサービス数の上限です（超えた新しいサービスは検出しない）
+	MaxServices int `mapstructure:"max_services"`
+	// Column は異常度を AnomalyScore 列に保存するかどうかです（false の場合はログとイベントのみ）
+	Column bool `mapstructure:"column"`
+}
+
+// validate は異常検出の設定を検証します
+func (c AnomalyDetectionConfig) validate(cfg *Config) error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs error
+	if c.Alpha <= 0 || c.Alpha > 1 {
+		errs = errors.Join(errs, fmt.Errorf("anomaly_detection.alpha は0より大きく1以下である必要があります: %g", c.Alpha))
+	}
+	if c.Threshold <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("anomaly_detection.threshold は0より大きい必要があります: %g", c.Threshold))
+	}
+	if c.MinSamples < 1 {
+		errs = errors.Join(errs, fmt.Errorf("anomaly_detection.min_samples は1以上である必要があります: %d", c.MinSamples))
+	}
+	if c.MinSpans < 1 {
+		errs = errors.Join(errs, fmt.Errorf("anomaly_detection.min_spans は1以上である必要があります: %d", c.MinSpans))
+	}
+	if c.MaxServices < 1 {
+		errs = errors.Join(errs, fmt.Errorf("anomaly_detection.max_services は1以上である必要があります: %d", c.MaxServices))
+	}
+	if c.Column && cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では anomaly_detection.column を使用できません"))
+	}
+	return errs
+}
+
+// columnEnabled は AnomalyScore 列を作成・挿入するかどうかを返します
+func (c AnomalyDetectionConfig) columnEnabled() bool {
+	return c.Enabled && c.Column
+}
+
+// insertColumns は AnomalyScore 列が有効な場合に列を追加した挿入列を返します
+func (c AnomalyDetectionConfig) insertColumns(columns []string) []string {
+	if !c.columnEnabled() {
+		return columns
+	}
+	return slices.Concat(columns, []string{anomalyScoreColumn})
+}
+
+// ewmaStat は指数移動平均による平均と分散です
+type ewmaStat struct {
+	mean     float64
+	variance float64
+}
+
+// zscore は値が平均から標準偏差（minDeviation 以上）の何倍大きいかを返します（平均以下の場合は0）
+func (s ewmaStat) zscore(value, minDeviation float64) float64 {
+	deviation := math.Max(math.Sqrt(s.variance), minDeviation)
+	return math.Max(0, (value-s.mean)/deviation)
+}
+
+// update は値で平均と分散を更新します
+func (s *ewmaStat) update(value, alpha float64) {
+	diff := value - s.mean
+	s.mean += alpha * diff
+	s.variance = (1 - alpha) * (s.variance + alpha*diff*diff)
+}
+
+// serviceBaseline はサービスのエラー率とレイテンシの基準値です
+type serviceBaseline struct {
+	samples   int
+	errorRate ewmaStat
+	latency   ewmaStat // 平均レイテンシ（ミリ秒）
+	score     float64  // 直近の送信の異常度
+}
+
+// anomalyDetector はサービスごとの基準値を保持し、送信ごとの異常度を計算します
+type anomalyDetector struct {
+	config AnomalyDetectionConfig
+	events *lifecycleEvents
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	services map[string]*serviceBaseline
+}
+
+// newAnomalyDetector は異常検出を作成します（anomaly_detection 無効の場合は nil）
+func newAnomalyDetector(cfg AnomalyDetectionConfig, events *lifecycleEvents, logger *zap.Logger) *anomalyDetector {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &anomalyDetector{config: cfg, events: events, logger: logger, services: map[string]*serviceBaseline{}}
+}
+
+// observe は送信されたスパンからサービスごとのエラー率と平均レイテンシを計算し、異常度を更新します
+// 異常度は更新前の基準値と比較して計算し、その後に基準値を更新します
+func (d *anomalyDetector) observe(td ptrace.Traces) {
+	if d == nil {
+		return
+	}
+	type batchStat struct {
+		spans, errors int
+		latencyMillis float64
+	}
+	stats := map[string]*batchStat{}
+	for _, rs := range td.ResourceSpans().All() {
+		service := resourceAttributeString(rs.Resource(), "service.name")
+		stat, ok := stats[service]
+		if !ok {
+			stat = &batchStat{}
+			stats[service] = stat
+		}
+		for _, ss := range rs.ScopeSpans().All() {
+			for _, span := range ss.Spans().All() {
+				stat.spans++
+				if span.Status().Code() == ptrace.StatusCodeError {
+					stat.errors++
+				}
+				stat.latencyMillis += float64(span.EndTimestamp()-span.StartTimestamp()) / 1e6
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for service, stat := range stats {
+		if stat.spans < d.config.MinSpans {
+			continue
+		}
+		baseline, ok := d.services[service]
+		if !ok {
+			if len(d.services) >= d.config.MaxServices {
+				continue
+			}
+			baseline = &serviceBaseline{}
+			d.services[service] = baseline
+		}
+		errorRate := float64(stat.errors) / float64(stat.spans)
+		latency := stat.latencyMillis / float64(stat.spans)
+
+		if baseline.samples == 0 {
+			// 最初の送信は分散が0のため、値そのものを平均とする
+			baseline.errorRate.mean, baseline.latency.mean = errorRate, latency
+		} else if baseline.samples >= d.config.MinSamples {
+			errorScore := baseline.errorRate.zscore(errorRate, minErrorRateDeviation)
+			latencyScore := baseline.latency.zscore(latency, baseline.latency.mean*minLatencyDeviationRate)
+			baseline.score = math.Max(errorScore, latencyScore)
+			if baseline.score >= d.config.Threshold {
+				d.logger.Warn("サービスのエラー率またはレイテンシが基準値から大きく逸脱しています",
+					zap.String("service", service),
+					zap.Float64("anomaly_score", baseline.score),
+					zap.Float64("error_rate", errorRate), zap.Float64("baseline_error_rate", baseline.errorRate.mean),
+					zap.Float64("latency_ms", latency), zap.Float64("baseline_latency_ms", baseline.latency.mean))
+				d.events.anomalyDetected(service, baseline.score, errorRate, baseline.errorRate.mean, latency, baseline.latency.mean)
+			}
+		}
+		baseline.samples++
+		baseline.errorRate.update(errorRate, d.config.Alpha)
+		baseline.latency.update(latency, d.config.Alpha)
+	}
+}
+
+// stamp は各行の末尾にサービスの直近の異常度を追加します（AnomalyScore 列が無効の場合は何もしない）
+// スプールからの再挿入では、挿入時点の異常度が保存されます
+func (d *anomalyDetector) stamp(rows [][]any) {
+	if d == nil || !d.config.Column {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, row := range rows {
+		var score float32
+		if service, ok := row[traceServiceNameIndex].(string); ok {
+			if baseline, ok := d.services[service]; ok {
+				score = float32(baseline.score)
+			}
+		}
+		rows[i] = append(row, score)
+	}
+}
+
+// traceServiceNameIndex は行における ServiceName 列の位置です
+var traceServiceNameIndex = slices.Index(traceInsertColumns, "ServiceName")
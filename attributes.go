@@ -0,0 +1,92 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/dtamura/myexporter/pdatarows"
+)
+
+// ResourceAttributesConfig - 保存するリソース属性の許可/拒否リスト（全シグナル共通）
+// パターンは完全一致、または末尾の * による前方一致（例: k8s.*）で指定します
+type ResourceAttributesConfig struct {
+	// Include を指定した場合、いずれかのパターンに一致する属性のみ保存します
+	Include []string `mapstructure:"include"`
+	// Exclude のいずれかのパターンに一致する属性は保存しません（Include より優先）
+	Exclude []string `mapstructure:"exclude"`
+}
+
+// validate はパターンの形式を検証します
+func (c ResourceAttributesConfig) validate() error {
+	for _, pattern := range slices.Concat(c.Include, c.Exclude) {
+		if pattern == "" || strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+			return fmt.Errorf("resource_attributes のパターン %q が不正です（* は末尾にのみ指定できます）", pattern)
+		}
+	}
+	return nil
+}
+
+// enabled はリソース属性のフィルタが設定されているかを判定します
+func (c ResourceAttributesConfig) enabled() bool {
+	return len(c.Include) > 0 || len(c.Exclude) > 0
+}
+
+// keep は指定キーのリソース属性を保存するかどうかを判定します
+func (c ResourceAttributesConfig) keep(key string) bool {
+	if slices.ContainsFunc(c.Exclude, func(pattern string) bool { return matchAttributePattern(pattern, key) }) {
+		return false
+	}
+	return len(c.Include) == 0 ||
+		slices.ContainsFunc(c.Include, func(pattern string) bool { return matchAttributePattern(pattern, key) })
+}
+
+// matchAttributePattern は属性キーがパターン（完全一致または末尾 * の前方一致）に一致するかを判定します
+func matchAttributePattern(pattern, key string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(key, prefix)
+	}
+	return pattern == key
+}
+
+// rowOptions は行への変換（pdatarows）の設定を返します（json は属性カラムをJSON型として変換するか）
+func (cfg *Config) rowOptions(json bool) pdatarows.Options {
+	opts := pdatarows.Options{
+		JSONAttributes:          json,
+		MaxAttributeKeyLength:   cfg.MaxAttributeKeyLength,
+		MaxAttributeValueLength: cfg.MaxAttributeLength,
+		MaxBodyLength:           cfg.MaxBodyLength,
+		StoreEvents:             cfg.Traces.StoreEvents,
+		StoreLinks:              cfg.Traces.StoreLinks,
+		FillObservedTimestamp:   cfg.logsTimeColumn() == "ObservedTimestamp",
+		OmitScopeAttributes:     !cfg.StoreScopeAttributes,
+		TraceScope:              cfg.Traces.StoreScope,
+		TimestampAttribute:      cfg.TimestampOverrideAttribute,
+	}
+	if cfg.ResourceAttributes.enabled() {
+		opts.KeepResourceAttribute = cfg.ResourceAttributes.keep
+	}
+	return opts
+}
+
+// attributeLookupSQL は属性カラムから指定キーの値を文字列として取り出すSQL式を返します
+// key にはSQLリテラルまたはバインドパラメータ（?）を指定します
+func attributeLookupSQL(column, key string, jsonColumn bool) string {
+	if jsonColumn {
+		return fmt.Sprintf("toString(getSubcolumn(%s, %s))", column, key)
+	}
+	return fmt.Sprintf("%s[%s]", column, key)
+}
+
+// resourceAttributeString はリソース属性の値を文字列で返します（存在しない場合は空文字）
+func resourceAttributeString(res pcommon.Resource, key string) string {
+	if v, ok := res.Attributes().Get(key); ok {
+		return v.AsString()
+	}
+	return ""
+}
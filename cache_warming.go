@@ -0,0 +1,137 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CacheWarmingConfig - 大きなフラッシュの後に最新のパーティションを読み込み、ダッシュボードの初回表示を速くする設定
+// デモ・POC環境向けで、読み込みのクエリはフラッシュとは別に実行するため挿入を遅らせません（トレース・ログのみ）
+type CacheWarmingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Mode はキャッシュを温めるクエリの種類です
+	//   limit_zero: SELECT ... LIMIT 0 でテーブルのメタデータとマークのみを読み込む（軽量）
+	//   uncompressed_cache: 当日のパーティションの全列を非圧縮キャッシュに読み込む
+	Mode string `mapstructure:"mode"`
+	// MinRows はクエリを実行するフラッシュの最小行数です（小さなフラッシュの後には実行しない）
+	MinRows int `mapstructure:"min_rows"`
+	// Interval はクエリを実行する最短の間隔です
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// キャッシュを温めるクエリの種類
+const (
+	cacheWarmingLimitZero         = "limit_zero"
+	cacheWarmingUncompressedCache = "uncompressed_cache"
+)
+
+// validate はキャッシュの事前読み込みの設定を検証します
+func (c CacheWarmingConfig) validate(cfg *Config) error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs error
+	switch c.Mode {
+	case cacheWarmingLimitZero, cacheWarmingUncompressedCache:
+	default:
+		errs = errors.Join(errs, fmt.Errorf("cache_warming.mode は %s または %s を指定してください: %s", cacheWarmingLimitZero, cacheWarmingUncompressedCache, c.Mode))
+	}
+	if c.MinRows < 0 {
+		errs = errors.Join(errs, fmt.Errorf("cache_warming.min_rows は0以上である必要があります: %d", c.MinRows))
+	}
+	if c.Interval < 0 {
+		errs = errors.Join(errs, fmt.Errorf("cache_warming.interval は0以上である必要があります: %s", c.Interval))
+	}
+	if cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では cache_warming を使用できません"))
+	}
+	return errs
+}
+
+// cacheWarmer はフラッシュ後に最新のパーティションを読み込むクエリを実行します
+type cacheWarmer struct {
+	config CacheWarmingConfig
+	db     *sql.DB
+	query  string
+	logger *zap.Logger
+
+	running atomic.Bool // クエリを実行中かどうか（同時に1つだけ実行する）
+	mu      sync.Mutex
+	last    time.Time // 前回クエリを実行した時刻
+	wg      sync.WaitGroup
+}
+
+// newCacheWarmer はキャッシュの事前読み込みを作成します（無効またはDB未接続の場合は nil）
+func newCacheWarmer(cfg *Config, db *sql.DB, database, table, timeColumn string, logger *zap.Logger) *cacheWarmer {
+	if !cfg.CacheWarming.Enabled || db == nil {
+		return nil
+	}
+	return &cacheWarmer{
+		config: cfg.CacheWarming,
+		db:     db,
+		query:  renderCacheWarmingSQL(cfg.CacheWarming.Mode, database, table, timeColumn),
+		logger: logger.With(zap.String("table", table)),
+	}
+}
+
+// renderCacheWarmingSQL はキャッシュを温めるクエリを返します
+// uncompressed_cache の場合は NOT ignore(*) で全列を読み込ませ、結果は件数のみを返します
+func renderCacheWarmingSQL(mode, database, table, timeColumn string) string {
+	if mode == cacheWarmingUncompressedCache {
+		return fmt.Sprintf(`SELECT count() FROM "%s"."%s" WHERE %s >= toStartOfDay(now()) AND NOT ignore(*) SETTINGS use_uncompressed_cache = 1`,
+			database, table, timeColumn)
+	}
+	return fmt.Sprintf(`SELECT * FROM "%s"."%s" WHERE %s >= toStartOfDay(now()) LIMIT 0`, database, table, timeColumn)
+}
+
+// afterFlush は flushed 行のフラッシュに成功した後に呼び出し、条件を満たす場合はクエリをバックグラウンドで実行します
+func (w *cacheWarmer) afterFlush(flushed int) {
+	if w == nil || flushed < w.config.MinRows {
+		return
+	}
+	w.mu.Lock()
+	if time.Since(w.last) < w.config.Interval || !w.running.CompareAndSwap(false, true) {
+		w.mu.Unlock()
+		return
+	}
+	w.last = time.Now()
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer w.running.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		start := time.Now()
+		rows, err := w.db.QueryContext(ctx, w.query)
+		if err == nil {
+			for rows.Next() {
+			}
+			err = errors.Join(rows.Err(), rows.Close())
+		}
+		if err != nil {
+			w.logger.Debug("キャッシュの事前読み込みに失敗しました", zap.Error(err))
+			return
+		}
+		w.logger.Debug("キャッシュの事前読み込みを実行しました", zap.Int("flushed_rows", flushed), zap.Duration("elapsed", time.Since(start)))
+	}()
+}
+
+// shutdown は実行中のクエリの終了を待ちます（接続を解放する前に呼び出す）
+func (w *cacheWarmer) shutdown() {
+	if w == nil {
+		return
+	}
+	w.wg.Wait()
+}
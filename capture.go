@@ -0,0 +1,117 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CaptureConfig - 挿入バッチのキャプチャ設定（デバッグ用）
+// テーブルごとに最初の MaxBatches 件のバッチを、挿入SQLと行（JSON）としてファイルに出力します
+// DB未接続の場合も出力されるため、「実際に何が書き込まれるか」をDBなしで確認できます
+type CaptureConfig struct {
+	// Directory は出力先ディレクトリです（指定した場合のみキャプチャを有効化）
+	Directory string `mapstructure:"directory"`
+	// MaxBatches はテーブルごとにキャプチャするバッチ数です。この件数に達するとキャプチャを停止します
+	MaxBatches int `mapstructure:"max_batches"`
+}
+
+// validate はキャプチャ設定を検証します
+func (c CaptureConfig) validate() error {
+	if c.Directory != "" && c.MaxBatches <= 0 {
+		return fmt.Errorf("capture.max_batches は1以上である必要があります: %d", c.MaxBatches)
+	}
+	return nil
+}
+
+// capturedBatch はファイルに出力する1バッチ分の内容です
+type capturedBatch struct {
+	Table      string           `json:"table"`
+	CapturedAt time.Time        `json:"captured_at"`
+	SQL        string           `json:"sql"`
+	Rows       []map[string]any `json:"rows"`
+}
+
+// batchCapture はテーブルごとのキャプチャ件数を管理し、バッチをファイルに出力します
+type batchCapture struct {
+	config CaptureConfig
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	counts map[string]int // テーブルごとのキャプチャ済みバッチ数
+}
+
+// newBatchCapture はキャプチャが設定されている場合のみ batchCapture を作成します（未設定の場合は nil）
+func newBatchCapture(cfg CaptureConfig, logger *zap.Logger) *batchCapture {
+	if cfg.Directory == "" {
+		return nil
+	}
+	return &batchCapture{
+		config: cfg,
+		logger: logger,
+		counts: map[string]int{},
+	}
+}
+
+// write はテーブルのキャプチャ件数が上限に達していなければ、バッチをファイルに出力します
+// デバッグ用の機能のため、出力に失敗してもエラーはログに記録するのみで挿入処理は継続します
+func (c *batchCapture) write(table, insertSQL string, columns []string, rows [][]any) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	seq := c.counts[table]
+	if seq >= c.config.MaxBatches {
+		c.mu.Unlock()
+		return
+	}
+	c.counts[table] = seq + 1
+	c.mu.Unlock()
+
+	batch := capturedBatch{
+		Table:      table,
+		CapturedAt: time.Now(),
+		SQL:        insertSQL,
+		Rows:       make([]map[string]any, 0, len(rows)),
+	}
+	for _, row := range rows {
+		named := make(map[string]any, len(columns))
+		for i, column := range columns {
+			named[column] = row[i]
+		}
+		batch.Rows = append(batch.Rows, named)
+	}
+
+	path := filepath.Join(c.config.Directory, fmt.Sprintf("%s-%04d.json", table, seq+1))
+	if err := writeCaptureFile(path, batch); err != nil {
+		c.logger.Warn("挿入バッチのキャプチャに失敗しました", zap.String("path", path), zap.Error(err))
+		return
+	}
+	c.logger.Info("挿入バッチをキャプチャしました",
+		zap.String("path", path), zap.String("table", table), zap.Int("rows", len(rows)))
+	if seq+1 == c.config.MaxBatches {
+		c.logger.Info("キャプチャ件数が上限に達したため、このテーブルのキャプチャを停止します",
+			zap.String("table", table), zap.Int("max_batches", c.config.MaxBatches))
+	}
+}
+
+// writeCaptureFile はバッチをJSONファイルとして書き込みます
+func writeCaptureFile(path string, batch capturedBatch) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
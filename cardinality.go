@@ -0,0 +1,229 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"slices"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// overflowAttributeKey は上限を超えたストリームを集約した系列に付与する属性です（SDKのカーディナリティ制限と同じキー）
+const overflowAttributeKey = "otel.metric.overflow"
+
+// CardinalityLimitConfig - メトリクスストリーム数（カーディナリティ）のソフトリミット設定
+// ラベルの爆発（リクエストIDなどの高カーディナリティ属性）からClickHouseを保護するため、
+// 1回の送信（フラッシュ）内でメトリクス名ごとに異なるストリームの数を数え、上限を超えたストリームを
+// otel.metric.overflow=true の1系列に集約します
+type CardinalityLimitConfig struct {
+	// MaxStreams はメトリクス名ごとに許容するストリーム（リソース属性・スコープ・データポイント属性の組）の数です
+	// 0 の場合は無制限
+	MaxStreams int `mapstructure:"max_streams"`
+}
+
+// validate はカーディナリティ制限の設定を検証します
+func (c CardinalityLimitConfig) validate() error {
+	if c.MaxStreams < 0 {
+		return fmt.Errorf("cardinality_limit.max_streams は0以上である必要があります: %d", c.MaxStreams)
+	}
+	return nil
+}
+
+// cardinalityLimiter は1回の送信内のストリームを数え、上限を超えたデータポイントを集約します
+// 送信ごとに作成するため、ストリームの計数はフラッシュ単位でリセットされます
+type cardinalityLimiter struct {
+	limit   int
+	streams map[string]map[uint64]struct{} // メトリクス名ごとの許容済みストリーム（識別子のハッシュ）
+	merged  int                            // オーバーフロー系列に集約したデータポイント数
+	dropped int                            // 集約できずに破棄したデータポイント数
+}
+
+// newCardinalityLimiter は送信1回分のカーディナリティ制限を作成します（無制限の場合は nil）
+func newCardinalityLimiter(cfg CardinalityLimitConfig) *cardinalityLimiter {
+	if cfg.MaxStreams <= 0 {
+		return nil
+	}
+	return &cardinalityLimiter{limit: cfg.MaxStreams, streams: map[string]map[uint64]struct{}{}}
+}
+
+// apply はメトリクスデータに上限を適用し、上限を超えたデータポイントを各メトリクスのオーバーフロー系列に置き換えます
+// Gauge は最新の値、Sum は合計、Histogram はバケット境界が同じ場合に合算します
+// 集約の意味を持たない Summary と、スケールの異なるバケットを持つ ExponentialHistogram は破棄します
+func (l *cardinalityLimiter) apply(md pmetric.Metrics) {
+	if l == nil {
+		return
+	}
+	h := fnv.New64a()
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			sm := sms.At(j)
+			// ストリーム識別子のうちリソースとスコープの部分は共通なので先に計算する
+			h.Reset()
+			writeAttributes(h, rm.Resource().Attributes())
+			h.Write([]byte(sm.Scope().Name() + "\x00" + sm.Scope().Version() + "\x00"))
+			scopeHash := h.Sum64()
+
+			metrics := sm.Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				l.limitMetric(metrics.At(k), h, scopeHash)
+			}
+		}
+	}
+}
+
+// limitMetric はメトリクスのデータポイントに上限を適用します
+func (l *cardinalityLimiter) limitMetric(m pmetric.Metric, h hash.Hash64, scopeHash uint64) {
+	seen := l.streams[m.Name()]
+	if seen == nil {
+		seen = map[uint64]struct{}{}
+		l.streams[m.Name()] = seen
+	}
+	admit := func(attrs pcommon.Map) bool {
+		h.Reset()
+		_ = binary.Write(h, binary.LittleEndian, scopeHash)
+		writeAttributes(h, attrs)
+		id := h.Sum64()
+		if _, ok := seen[id]; ok {
+			return true
+		}
+		if len(seen) >= l.limit {
+			return false
+		}
+		seen[id] = struct{}{}
+		return true
+	}
+
+	var merged, dropped int
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		merged, dropped = limitDataPoints(m.Gauge().DataPoints(), pmetric.NewNumberDataPoint, admit, mergeGaugeDataPoint)
+	case pmetric.MetricTypeSum:
+		merged, dropped = limitDataPoints(m.Sum().DataPoints(), pmetric.NewNumberDataPoint, admit, mergeSumDataPoint)
+	case pmetric.MetricTypeHistogram:
+		merged, dropped = limitDataPoints(m.Histogram().DataPoints(), pmetric.NewHistogramDataPoint, admit, mergeHistogramDataPoint)
+	case pmetric.MetricTypeExponentialHistogram:
+		merged, dropped = limitDataPoints(m.ExponentialHistogram().DataPoints(), pmetric.NewExponentialHistogramDataPoint, admit, nil)
+	case pmetric.MetricTypeSummary:
+		merged, dropped = limitDataPoints(m.Summary().DataPoints(), pmetric.NewSummaryDataPoint, admit, nil)
+	}
+	l.merged += merged
+	l.dropped += dropped
+}
+
+// overflowDataPoint は各種データポイントのうち集約に必要な操作です
+type overflowDataPoint[P any] interface {
+	Attributes() pcommon.Map
+	CopyTo(dest P)
+	MoveTo(dest P)
+}
+
+// overflowDataPointSlice は各種データポイントスライスのうち集約に必要な操作です
+type overflowDataPointSlice[P any] interface {
+	RemoveIf(f func(P) bool)
+	AppendEmpty() P
+}
+
+// limitDataPoints は上限を超えたストリームのデータポイントを取り除き、1つのオーバーフロー系列に集約して追加します
+// merge が nil、または merge が false を返したデータポイントは集約せずに破棄します
+func limitDataPoints[P overflowDataPoint[P]](points overflowDataPointSlice[P], newPoint func() P,
+	admit func(pcommon.Map) bool, merge func(dst, src P) bool,
+) (merged, dropped int) {
+	overflow := newPoint()
+	points.RemoveIf(func(p P) bool {
+		if admit(p.Attributes()) {
+			return false
+		}
+		switch {
+		case merge == nil:
+			dropped++
+		case merged == 0:
+			p.CopyTo(overflow)
+			merged++
+		case merge(overflow, p):
+			merged++
+		default:
+			dropped++
+		}
+		return true
+	})
+	if merged > 0 {
+		overflow.Attributes().Clear()
+		overflow.Attributes().PutBool(overflowAttributeKey, true)
+		overflow.MoveTo(points.AppendEmpty())
+	}
+	return merged, dropped
+}
+
+// mergeGaugeDataPoint はより新しいデータポイントの値を採用します
+func mergeGaugeDataPoint(dst, src pmetric.NumberDataPoint) bool {
+	if src.Timestamp() >= dst.Timestamp() {
+		src.CopyTo(dst)
+	}
+	return true
+}
+
+// mergeSumDataPoint は値を合算します（整数同士以外は浮動小数点数で合算）
+func mergeSumDataPoint(dst, src pmetric.NumberDataPoint) bool {
+	if dst.ValueType() == pmetric.NumberDataPointValueTypeInt && src.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		dst.SetIntValue(dst.IntValue() + src.IntValue())
+	} else {
+		dst.SetDoubleValue(numberValue(dst) + numberValue(src))
+	}
+	dst.SetStartTimestamp(min(dst.StartTimestamp(), src.StartTimestamp()))
+	dst.SetTimestamp(max(dst.Timestamp(), src.Timestamp()))
+	return true
+}
+
+// mergeHistogramDataPoint はバケット境界が同じ場合にバケットごとの件数と合計を合算します
+func mergeHistogramDataPoint(dst, src pmetric.HistogramDataPoint) bool {
+	if !dst.ExplicitBounds().Equal(src.ExplicitBounds()) || dst.BucketCounts().Len() != src.BucketCounts().Len() {
+		return false
+	}
+	for i := 0; i < dst.BucketCounts().Len(); i++ {
+		dst.BucketCounts().SetAt(i, dst.BucketCounts().At(i)+src.BucketCounts().At(i))
+	}
+	dst.SetCount(dst.Count() + src.Count())
+	if dst.HasSum() || src.HasSum() {
+		dst.SetSum(dst.Sum() + src.Sum())
+	}
+	if src.HasMin() && (!dst.HasMin() || src.Min() < dst.Min()) {
+		dst.SetMin(src.Min())
+	}
+	if src.HasMax() && (!dst.HasMax() || src.Max() > dst.Max()) {
+		dst.SetMax(src.Max())
+	}
+	dst.SetStartTimestamp(min(dst.StartTimestamp(), src.StartTimestamp()))
+	dst.SetTimestamp(max(dst.Timestamp(), src.Timestamp()))
+	return true
+}
+
+// numberValue はデータポイントの値を浮動小数点数で返します
+func numberValue(p pmetric.NumberDataPoint) float64 {
+	if p.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		return float64(p.IntValue())
+	}
+	return p.DoubleValue()
+}
+
+// writeAttributes は属性をキー順に書き込みます（順序に依存しないストリーム識別子を作るため）
+func writeAttributes(h hash.Hash64, attrs pcommon.Map) {
+	keys := make([]string, 0, attrs.Len())
+	attrs.Range(func(k string, _ pcommon.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	slices.Sort(keys)
+	for _, k := range keys {
+		v, _ := attrs.Get(k)
+		h.Write([]byte(k + "\x00" + v.AsString() + "\x00"))
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql/driver"
+	"math/rand/v2"
+	"time"
+
+	"go.opentelemetry.io/collector/featuregate"
+)
+
+// chaosFeatureGate - DB層への障害注入を有効化するフィーチャーゲート
+// ステージング環境でリトライ・キュー設定を検証するためのもので、本番では有効化しないこと
+var chaosFeatureGate = featuregate.GlobalRegistry().MustRegister(
+	"exporter.mylogexporter.chaosInjection",
+	featuregate.StageAlpha,
+	featuregate.WithRegisterDescription("DB層に遅延・接続断の障害を注入します（chaos 設定と併用）"),
+)
+
+// ChaosConfig - DB層への障害注入設定
+// exporter.mylogexporter.chaosInjection フィーチャーゲートが有効な場合のみ適用される
+type ChaosConfig struct {
+	Latency            time.Duration `mapstructure:"latency"`              // 各DB操作に追加する遅延
+	LatencyJitter      time.Duration `mapstructure:"latency_jitter"`       // 遅延に加えるランダムな揺らぎの最大値
+	ConnectionDropRate float64       `mapstructure:"connection_drop_rate"` // DB操作ごとに接続を切断する確率（0.0〜1.0）
+}
+
+// enabled - 障害注入が有効かどうかを判定します
+func (c ChaosConfig) enabled() bool {
+	if !chaosFeatureGate.IsEnabled() {
+		return false
+	}
+	return c.Latency > 0 || c.LatencyJitter > 0 || c.ConnectionDropRate > 0
+}
+
+// newChaosConnector は障害注入ラッパー経由で接続する driver.Connector を作成します
+func newChaosConnector(cfg ChaosConfig, driverName, dsn string) (driver.Connector, error) {
+	inner, err := lookupDriver(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosConnector{cfg: cfg, dsn: dsn, driver: inner}, nil
+}
+
+// chaosConnector - 障害注入付きの接続を生成する driver.Connector
+type chaosConnector struct {
+	cfg    ChaosConfig
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *chaosConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.inject(ctx); err != nil {
+		return nil, err
+	}
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosConn{Conn: conn, connector: c}, nil
+}
+
+func (c *chaosConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// inject は設定に従って遅延を発生させ、確率的に接続断エラーを返します
+func (c *chaosConnector) inject(ctx context.Context) error {
+	delay := c.cfg.Latency
+	if c.cfg.LatencyJitter > 0 {
+		delay += time.Duration(rand.Int64N(int64(c.cfg.LatencyJitter)))
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if c.cfg.ConnectionDropRate > 0 && rand.Float64() < c.cfg.ConnectionDropRate {
+		// ErrBadConn を返すと database/sql は接続を破棄して再接続する
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// chaosConn - 各DB操作の前に障害を注入する driver.Conn ラッパー
+type chaosConn struct {
+	driver.Conn
+	connector *chaosConnector
+}
+
+func (c *chaosConn) Ping(ctx context.Context) error {
+	if err := c.connector.inject(ctx); err != nil {
+		return err
+	}
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *chaosConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *chaosConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *chaosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.connector.inject(ctx); err != nil {
+		return nil, err
+	}
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint:staticcheck // ConnBeginTx 未実装ドライバー向けのフォールバック
+}
+
+func (c *chaosConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.connector.inject(ctx); err != nil {
+		return nil, err
+	}
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *chaosConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.connector.inject(ctx); err != nil {
+		return nil, err
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *chaosConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.connector.inject(ctx); err != nil {
+		return nil, err
+	}
+	return queryer.QueryContext(ctx, query, args)
+}
@@ -0,0 +1,134 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+// CircuitBreakerConfig - 実行中にClickHouseへ到達できなくなった場合の縮退動作の設定
+// 挿入が連続して失敗するとサーキットをオープンし、一時的にログ出力のみモードで動作します
+// クールダウン経過後に接続を確認（プローブ）し、復旧していれば挿入を再開します
+type CircuitBreakerConfig struct {
+	// Enabled はサーキットブレーカーを有効化します
+	// オープン中に受信したデータはログ出力のみ行い、ClickHouseには保存されません
+	Enabled bool `mapstructure:"enabled"`
+	// FailureThreshold はサーキットをオープンする連続失敗回数です
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	// Cooldown はオープンしてから復旧を確認するまでの待機時間です
+	Cooldown time.Duration `mapstructure:"cooldown"`
+}
+
+// validate はサーキットブレーカー設定を検証します
+func (c CircuitBreakerConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.FailureThreshold <= 0 {
+		return fmt.Errorf("circuit_breaker.failure_threshold は1以上である必要があります: %d", c.FailureThreshold)
+	}
+	if c.Cooldown <= 0 {
+		return fmt.Errorf("circuit_breaker.cooldown は0より大きい必要があります: %s", c.Cooldown)
+	}
+	return nil
+}
+
+// circuitBreaker はシグナルごとの挿入失敗を監視し、ログ出力のみモードへの切り替えと復旧を管理します
+type circuitBreaker struct {
+	config CircuitBreakerConfig
+	signal string
+	db     *sql.DB
+	events *lifecycleEvents // 接続断・復旧のイベント送信（lifecycle_events 有効時のみ）
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	failures  int       // 連続失敗回数
+	open      bool      // オープン中（ログ出力のみモード）かどうか
+	openUntil time.Time // 次に復旧を確認する時刻
+	probing   bool      // 復旧確認中かどうか（同時に1つだけ実行する）
+}
+
+// newCircuitBreaker はサーキットブレーカーを作成します（無効またはDB未接続の場合は nil）
+func newCircuitBreaker(cfg CircuitBreakerConfig, signal string, db *sql.DB, events *lifecycleEvents, logger *zap.Logger) *circuitBreaker {
+	if !cfg.Enabled || db == nil {
+		return nil
+	}
+	return &circuitBreaker{
+		config: cfg,
+		signal: signal,
+		db:     db,
+		events: events,
+		logger: logger,
+	}
+}
+
+// allow は挿入を試みてよいかを返します
+// オープン中はクールダウン経過後に1つの呼び出しだけが接続を確認し、成功すればクローズして挿入を再開します
+func (b *circuitBreaker) allow(ctx context.Context) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	if !b.open {
+		b.mu.Unlock()
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		b.mu.Unlock()
+		return false
+	}
+	b.probing = true
+	b.mu.Unlock()
+
+	err := b.db.PingContext(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err != nil {
+		b.openUntil = time.Now().Add(b.config.Cooldown)
+		b.logger.Warn("ClickHouseはまだ復旧していません、ログ出力のみモードを継続します",
+			zap.String("signal", b.signal), zap.Duration("cooldown", b.config.Cooldown), zap.Error(err))
+		return false
+	}
+	b.open = false
+	b.failures = 0
+	b.logger.Info("ClickHouseへの接続が復旧しました、挿入を再開します", zap.String("signal", b.signal))
+	b.events.connectionRestored()
+	return true
+}
+
+// record は挿入結果を記録し、連続失敗回数がしきい値に達した場合はサーキットをオープンします
+func (b *circuitBreaker) record(err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// 永続的なエラー（スキーマの不一致など）はClickHouseに到達できているため失敗として数えない
+	if err == nil || consumererror.IsPermanent(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if !b.open && b.failures >= b.config.FailureThreshold {
+		b.open = true
+		b.openUntil = time.Now().Add(b.config.Cooldown)
+		b.logger.Error("挿入が連続して失敗したため、ログ出力のみモードに切り替えます",
+			zap.String("signal", b.signal),
+			zap.Int("consecutive_failures", b.failures),
+			zap.Duration("cooldown", b.config.Cooldown),
+			zap.Error(err))
+		b.events.connectionLost(b.failures, err)
+	}
+}
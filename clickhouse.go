@@ -6,54 +6,194 @@ package myexporter
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"net"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 
+	"github.com/dtamura/myexporter/internal"
+
 	// ClickHouse driver - clickhouseexporterと同様
 	_ "github.com/ClickHouse/clickhouse-go/v2"
 )
 
 var driverName = "clickhouse" // for testing - clickhouseexporterと同様
 
+// connectionManager は同一DSNのエクスポーター間でDB接続プールを共有します
+var connectionManager = internal.NewConnectionManager()
+
 // buildDBConnection creates a database connection
 // clickhouseexporterのnewClickhouseClient関数とbuildDB関数を参考
-func buildDBConnection(cfg *Config) (*sql.DB, error) {
-	return buildDB(cfg, cfg.Database)
+func buildDBConnection(cfg *Config, logger *zap.Logger) (*sql.DB, error) {
+	return buildDB(cfg, cfg.Database, logger)
+}
+
+// acquireDBConnection は同じDSNを持つエクスポーター間で共有されるDB接続を取得します
+// 取得した接続は releaseDBConnection で解放すること
+func acquireDBConnection(cfg *Config, logger *zap.Logger) (*sql.DB, error) {
+	key, err := buildDSN(cfg, cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	// 障害注入の設定が異なる場合は別の接続プールを使用する
+	if cfg.Chaos.enabled() {
+		key = fmt.Sprintf("%s#chaos=%+v", key, cfg.Chaos)
+	}
+	// 接続プールの設定が異なる場合も別の接続プールを使用する
+	key = fmt.Sprintf("%s#pool=%+v", key, cfg.ConnectionPool)
+	// clickhouse_cloud はキープアライブの既定値を変えるため別の接続プールを使用する
+	if cfg.ClickHouseCloud {
+		key += "#clickhouse_cloud"
+	}
+	// password_file のパスワードはDSNに含まれないため、ファイルごとに別の接続プールを使用する
+	if cfg.PasswordFile != "" {
+		key = fmt.Sprintf("%s#password_file=%s", key, cfg.PasswordFile)
+	}
+	if cfg.multiEndpoint() {
+		key = fmt.Sprintf("%s#lb=%+v", key, cfg.LoadBalancing)
+	}
+	return connectionManager.Acquire(key, func() (*sql.DB, error) {
+		return buildDBConnection(cfg, logger)
+	})
+}
+
+// releaseDBConnection は共有DB接続の参照を解放し、最後の参照であれば接続を閉じます
+func releaseDBConnection(db *sql.DB) error {
+	return connectionManager.Release(db)
 }
 
 // buildDB creates a database connection to specified database
 // clickhouseexporterのbuildDB関数を参考
-func buildDB(cfg *Config, database string) (*sql.DB, error) {
+func buildDB(cfg *Config, database string, logger *zap.Logger) (*sql.DB, error) {
+	var connector driver.Connector
+	var err error
+	if cfg.PasswordFile != "" {
+		// パスワードファイルの変更を検知して、新しいパスワードで接続し直す
+		connector, err = newPasswordFileConnector(cfg, database, logger)
+	} else {
+		connector, err = newConnector(cfg, database, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// キープアライブが有効な場合は、接続プールを閉じる際に停止できるよう Connector を包む
+	var keepalive *keepaliveConnector
+	if interval := cfg.keepaliveInterval(); interval > 0 {
+		keepalive = newKeepaliveConnector(connector, interval, logger)
+		connector = keepalive
+	}
+
+	// エンドポイントの名前解決の監視（dns_refresh_interval 指定時のみ）も同様に Connector を包む
+	var dnsWatch *dnsWatchConnector
+	if cfg.ConnectionPool.DNSRefreshInterval > 0 && len(cfg.endpointHosts()) > 0 {
+		dnsWatch = newDNSWatchConnector(connector, cfg, logger)
+		connector = dnsWatch
+	}
+
+	conn := sql.OpenDB(connector)
+	cfg.ConnectionPool.configure(conn)
+	if keepalive != nil {
+		keepalive.start(conn)
+	}
+	if dnsWatch != nil {
+		dnsWatch.start(conn)
+	}
+	return conn, nil
+}
+
+// newConnector は設定に応じて接続を作成する driver.Connector を返します
+func newConnector(cfg *Config, database string, logger *zap.Logger) (driver.Connector, error) {
 	dsn, err := buildDSN(cfg, database)
 	if err != nil {
 		return nil, err
 	}
 
+	// 障害注入が有効な場合はラッパー経由で接続（フィーチャーゲートで制御）
+	// 障害注入と複数エンドポイントを併用する場合は、ドライバーがDSNのホストを順に試して接続する
+	switch {
+	case cfg.Chaos.enabled():
+		return newChaosConnector(cfg.Chaos, cfg.sqlDriverName(), dsn)
+	case cfg.multiEndpoint():
+		return newFailoverConnector(cfg, database, logger)
+	}
+
 	// ClickHouse sql driver will read clickhouse settings from the DSN string.
-	// clickhouseexporterと同様の実装
-	conn, err := sql.Open(driverName, dsn)
+	// clickhouseexporterと同様の実装（sql.Open と同じくドライバーの Connector を使用する）
+	d, err := lookupDriver(cfg.sqlDriverName(), dsn)
 	if err != nil {
 		return nil, err
 	}
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return &dsnConnector{dsn: dsn, driver: d}, nil
+}
 
-	return conn, nil
+// lookupDriver は登録済みのドライバーを返します
+func lookupDriver(driverName, dsn string) (driver.Driver, error) {
+	// sql.Open は接続を確立しないため、ドライバーの取得のみに使用する
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := probe.Driver()
+	_ = probe.Close()
+	return d, nil
+}
+
+// dsnConnector - DriverContext を実装しないドライバー向けに、DSNで接続する driver.Connector
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
 }
 
 // buildDSN constructs database connection string
 // clickhouseexporterのbuildDSN関数を参考（アップデート版）
 func buildDSN(cfg *Config, database string) (string, error) {
-	if cfg.Endpoint == "" {
+	if cfg.isPostgres() {
+		return buildPostgresDSN(cfg)
+	}
+	endpoints := cfg.endpoints()
+	if len(endpoints) == 0 {
 		return "", fmt.Errorf("endpoint must be specified")
 	}
 
-	dsnURL, err := url.Parse(cfg.Endpoint)
+	dsnURL, err := parseEndpoint(endpoints[0])
 	if err != nil {
-		return "", fmt.Errorf("invalid endpoint format: %w", err)
+		return "", err
+	}
+	// 複数のエンドポイントはホストをカンマ区切りで並べる（clickhouse-goの複数ホストDSN）
+	hosts := []string{dsnURL.Host}
+	for _, endpoint := range endpoints[1:] {
+		u, err := parseEndpoint(endpoint)
+		if err != nil {
+			return "", err
+		}
+		if u.Scheme != dsnURL.Scheme || u.Path != dsnURL.Path || u.RawQuery != dsnURL.RawQuery {
+			return "", fmt.Errorf("複数のエンドポイントは同じスキーム・データベース・パラメータで指定してください: %s, %s", endpoints[0], endpoint)
+		}
+		hosts = append(hosts, u.Host)
 	}
+	dsnURL.Host = strings.Join(hosts, ",")
 
 	queryParams := dsnURL.Query()
+	if len(hosts) > 1 && !queryParams.Has("connection_open_strategy") {
+		queryParams.Set("connection_open_strategy", "round_robin")
+	}
 
 	// 追加接続パラメータを適用
 	for k, v := range cfg.ConnectionParams {
@@ -64,19 +204,31 @@ func buildDSN(cfg *Config, database string) (string, error) {
 	if dsnURL.Scheme == "https" {
 		queryParams.Set("secure", "true")
 	}
+	// ClickHouse Cloud ではネイティブプロトコルでもセキュア接続を必須とする
+	if err := cfg.applyCloudSecure(queryParams, dsnURL.Scheme); err != nil {
+		return "", err
+	}
 
 	// 圧縮設定を追加（clickhouseexporterアップデート版）
-	if !queryParams.Has("compress") && (cfg.Compress == "" || cfg.Compress == "true") {
-		queryParams.Set("compress", "lz4")
-	} else if !queryParams.Has("compress") {
-		queryParams.Set("compress", cfg.Compress)
-	}
+	cfg.applyCompression(queryParams, dsnURL.Scheme)
 
 	// AsyncInsert設定を追加（clickhouseexporterアップデート版）
 	if !queryParams.Has("async_insert") {
 		queryParams.Set("async_insert", fmt.Sprintf("%t", cfg.AsyncInsert))
 	}
 
+	// 非同期挿入とリトライを併用する場合は重複書き込みを避ける設定を既定で有効化（明示的な指定は優先）
+	// wait_for_async_insert=0 ではバッファへの受付時点で成功扱いになり、フラッシュ失敗が検知できない
+	// async_insert_deduplicate=1 はリトライで同じデータが再送された場合に重複を排除する（Replicated*テーブルのみ有効）
+	if cfg.AsyncInsert && cfg.BackOffConfig.Enabled {
+		if !queryParams.Has("wait_for_async_insert") {
+			queryParams.Set("wait_for_async_insert", "1")
+		}
+		if !queryParams.Has("async_insert_deduplicate") {
+			queryParams.Set("async_insert_deduplicate", "1")
+		}
+	}
+
 	// データベース名を設定 - clickhouseexporterと同様のロジック
 	if cfg.Database != "" {
 		dsnURL.Path = cfg.Database
@@ -101,23 +253,115 @@ func buildDSN(cfg *Config, database string) (string, error) {
 	return dsnURL.String(), nil
 }
 
-// createDatabase はデータベースのみを作成します（テーブルは作成しません）
+// warnUnsafeAsyncInsert は非同期挿入とリトライの組み合わせでリトライ時に重複書き込みが起こりうる設定を警告します
+// connection_params やエンドポイントのクエリで重複排除の設定を明示的に無効化した場合が対象で、
+// 重複排除が効かない（Replicated*ではない）テーブルに書き込む場合はその旨を通知します
+func warnUnsafeAsyncInsert(cfg *Config, logger *zap.Logger) {
+	if !cfg.BackOffConfig.Enabled || cfg.isPostgres() {
+		return
+	}
+	dsn, err := buildDSN(cfg, cfg.Database)
+	if err != nil {
+		return
+	}
+	dsnURL, err := url.Parse(dsn)
+	if err != nil {
+		return
+	}
+	params := dsnURL.Query()
+	if enabled, _ := strconv.ParseBool(params.Get("async_insert")); !enabled {
+		return
+	}
+
+	if wait, err := strconv.ParseBool(params.Get("wait_for_async_insert")); err == nil && !wait {
+		logger.Warn("async_insert が有効で wait_for_async_insert=0 が指定されています、" +
+			"挿入はバッファへの受付時点で成功扱いとなり、フラッシュ失敗時のデータ欠落やタイムアウト後のリトライによる重複書き込みが起こりえます")
+	}
+	if dedup, err := strconv.ParseBool(params.Get("async_insert_deduplicate")); err == nil && !dedup {
+		logger.Warn("async_insert が有効で async_insert_deduplicate=0 が指定されています、リトライ時に同じデータが重複して書き込まれる可能性があります")
+	} else if !cfg.Replication.Enabled {
+		// 既定の構成で毎回警告しないよう情報ログとする
+		logger.Info("async_insert_deduplicate は Replicated* テーブルでのみ有効です、"+
+			"replication.enabled を有効にするか非レプリケーションテーブルに non_replicated_deduplication_window を設定しない限り、リトライ時に重複書き込みが起こりえます",
+			zap.String("table_engine", cfg.tableEngineString()))
+	}
+}
+
+// endpointSchemes はclickhouse-goのDSNとして使用できるスキーム
+var endpointSchemes = []string{"tcp", "clickhouse", "http", "https"}
+
+// parseEndpoint parses the endpoint, accepting shorthand "host:port" forms.
+// スキームが省略された場合はポート番号から推測する（8123: http, 8443: https, 9440: tcp+secure、それ以外はtcp）
+func parseEndpoint(endpoint string) (*url.URL, error) {
+	if !strings.Contains(endpoint, "://") {
+		host, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			// ポートも省略された場合はネイティブプロトコルの既定ポートを使用
+			host, port = endpoint, "9000"
+		}
+		if host == "" || strings.ContainsAny(host, "/?#") {
+			return nil, fmt.Errorf("invalid endpoint %q: expected host:port (e.g. clickhouse:9000) or a URL (e.g. tcp://clickhouse:9000)", endpoint)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: port %q is not a number", endpoint, port)
+		}
+
+		scheme := "tcp"
+		query := ""
+		switch port {
+		case "8123":
+			scheme = "http"
+		case "8443":
+			scheme = "https"
+		case "9440":
+			query = "secure=true"
+		}
+		return &url.URL{Scheme: scheme, Host: net.JoinHostPort(host, port), RawQuery: query}, nil
+	}
+
+	dsnURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint format: %w", err)
+	}
+	if !slices.Contains(endpointSchemes, dsnURL.Scheme) {
+		return nil, fmt.Errorf("invalid endpoint %q: unsupported scheme %q (supported: %s)",
+			endpoint, dsnURL.Scheme, strings.Join(endpointSchemes, ", "))
+	}
+	if dsnURL.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q: host is missing", endpoint)
+	}
+	return dsnURL, nil
+}
+
+// createDatabase は指定されたデータベースのみを作成します（テーブルは作成しません）
+// 接続プールは database（接続先のデータベース）に接続するため、シグナルごとのデータベースを指定した場合も
+// 接続先のデータベースを先に作成します（すべてのシグナルのデータベースを上書きした場合も接続できるようにする）
 // clickhouseexporterのCreateDatabase関数を参考にした実装（アップデート版）
-func createDatabase(ctx context.Context, cfg *Config, logger *zap.Logger) error {
-	// CreateSchemaが無効な場合は何もしない
-	if !cfg.CreateSchema {
+func createDatabase(ctx context.Context, cfg *Config, database string, logger *zap.Logger) error {
+	// CreateSchemaが無効な場合（ドライランを含む）は何もしない
+	if !cfg.shouldCreateSchema() {
 		logger.Info("スキーマ作成が無効化されています、データベース作成をスキップします")
 		return nil
 	}
 
-	if cfg.Database == "" || cfg.Database == "default" {
+	// PostgreSQLではデータベースをスキーマとして扱い、テーブルと合わせて作成する
+	if cfg.isPostgres() {
+		return nil
+	}
+
+	databases := []string{cfg.database()}
+	if database != cfg.database() {
+		databases = append(databases, database)
+	}
+	databases = slices.DeleteFunc(databases, func(database string) bool { return database == "" || database == internal.DefaultDatabase })
+	if len(databases) == 0 {
 		logger.Info("デフォルトデータベースを使用します、作成をスキップします")
 		return nil
 	}
 
 	// データベース作成用に 'default' データベースに接続
 	// clickhouseexporterと同様の実装
-	db, err := buildDB(cfg, "default")
+	db, err := buildDB(cfg, internal.DefaultDatabase, logger)
 	if err != nil {
 		return fmt.Errorf("データベース接続の構築に失敗しました: %w", err)
 	}
@@ -125,15 +369,68 @@ func createDatabase(ctx context.Context, cfg *Config, logger *zap.Logger) error
 		_ = db.Close()
 	}()
 
-	// データベース作成クエリを実行 - clickhouseexporterと同様
-	createDbQuery := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", cfg.Database)
-	logger.Info("データベースを作成しています", zap.String("database", cfg.Database))
+	for _, database := range databases {
+		// データベース作成クエリを実行 - clickhouseexporterと同様
+		createDbQuery := renderCreateDatabaseSQL(cfg, database)
+		logger.Info("データベースを作成しています", zap.String("database", database))
 
-	_, err = db.ExecContext(ctx, createDbQuery)
-	if err != nil {
-		return fmt.Errorf("データベース作成に失敗しました: %w", err)
+		if _, err := db.ExecContext(ctx, createDbQuery); err != nil {
+			return fmt.Errorf("データベース %s の作成に失敗しました: %w", database, err)
+		}
+
+		logger.Info("データベース作成が完了しました", zap.String("database", database))
+	}
+	return nil
+}
+
+// renderCreateDatabaseSQL - データベース作成SQLを生成します
+// cluster_name が設定されている場合は ON CLUSTER で全ノードに作成し、database_engine が指定されている場合はそのエンジンで作成します
+// Replicated データベースもレプリカごとに作成が必要なため、テーブルと異なり常に ON CLUSTER を付与します
+func renderCreateDatabaseSQL(cfg *Config, database string) string {
+	query := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", database)
+	if cfg.ClusterName != "" {
+		query += fmt.Sprintf(" ON CLUSTER '%s'", cfg.ClusterName)
 	}
+	if cfg.DatabaseEngine != "" {
+		query += " ENGINE = " + cfg.DatabaseEngine
+	}
+	return query
+}
+
+// lowCardinalityMapKeyVersion は Map 型が正式にサポートされ、キーに LowCardinality を使用できる ClickHouse のバージョンです
+var lowCardinalityMapKeyVersion = [2]int{21, 8}
 
-	logger.Info("データベース作成が完了しました", zap.String("database", cfg.Database))
+// checkServerVersion はテーブル作成前に、設定で使用する型をサーバーがサポートしているか確認します
+// 古いサーバーでは CREATE TABLE が型の解析エラーで失敗するため、対処方法を示すエラーを返します
+func checkServerVersion(ctx context.Context, cfg *Config, db *sql.DB) error {
+	if cfg.isPostgres() || !cfg.AttributesLowCardinalityKeys {
+		return nil
+	}
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT version()").Scan(&version); err != nil {
+		return fmt.Errorf("サーバーのバージョンの取得に失敗しました: %w", err)
+	}
+	major, minor, ok := parseServerVersion(version)
+	if ok && (major < lowCardinalityMapKeyVersion[0] || (major == lowCardinalityMapKeyVersion[0] && minor < lowCardinalityMapKeyVersion[1])) {
+		return fmt.Errorf("ClickHouse %s は Map のキーに LowCardinality を使用できません（%d.%d 以降が必要）、attributes_low_cardinality_keys: false を指定してください",
+			version, lowCardinalityMapKeyVersion[0], lowCardinalityMapKeyVersion[1])
+	}
 	return nil
 }
+
+// parseServerVersion は version() の結果（例: 24.8.4.13）からメジャー・マイナーバージョンを取り出します
+func parseServerVersion(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,83 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cloudKeepaliveInterval は clickhouse_cloud 有効時の既定のキープアライブ間隔です（connection_pool.keepalive_interval 未指定の場合）
+// ClickHouse Cloud のロードバランサーはしばらく使われなかった接続を切断するため、それより短い間隔で確認する
+const cloudKeepaliveInterval = time.Minute
+
+// validateClickHouseCloud は clickhouse_cloud と組み合わせられない設定を検証します
+// ClickHouse Cloud ではレプリケーションをサービス側が管理するため、Replicated エンジンや分散テーブルは使用しない
+func (cfg *Config) validateClickHouseCloud() error {
+	if !cfg.ClickHouseCloud {
+		return nil
+	}
+	var errs error
+	if cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では clickhouse_cloud を使用できません"))
+	}
+	if cfg.Replication.Enabled || cfg.Replication.Distributed {
+		errs = errors.Join(errs, errors.New("clickhouse_cloud ではレプリケーションをサービスが管理するため、replication.enabled と replication.distributed は使用できません"))
+	}
+	if strings.HasPrefix(engineName(cfg.TableEngine), "Replicated") {
+		errs = errors.Join(errs, fmt.Errorf("clickhouse_cloud では Replicated エンジンを使用できません（SharedMergeTree 系のエンジンで作成します）: %s", cfg.TableEngine))
+	}
+	if v, ok := cfg.ConnectionParams["secure"]; ok {
+		if secure, err := strconv.ParseBool(v); err == nil && !secure {
+			errs = errors.Join(errs, errors.New("clickhouse_cloud ではセキュア接続が必須のため、connection_params に secure=false を指定できません"))
+		}
+	}
+	return errs
+}
+
+// applyCloudSecure は clickhouse_cloud 有効時にセキュア接続を有効化します
+// ClickHouse Cloud は平文の接続を受け付けないため、http スキームのエンドポイントはエラーとします
+func (cfg *Config) applyCloudSecure(queryParams url.Values, scheme string) error {
+	if !cfg.ClickHouseCloud {
+		return nil
+	}
+	switch scheme {
+	case "http":
+		return errors.New("clickhouse_cloud ではセキュア接続が必須です、https（8443）または tcp（9440）のエンドポイントを指定してください")
+	case "https":
+		// HTTPS は scheme でセキュア接続となる
+	default:
+		queryParams.Set("secure", "true")
+	}
+	return nil
+}
+
+// sharedEngine は MergeTree 系エンジンを ClickHouse Cloud の Shared 版に変換します
+// 例: ReplacingMergeTree(LastSeen) → SharedReplacingMergeTree(LastSeen)
+// MergeTree 系以外のエンジン（Memory など）はそのまま返します
+func sharedEngine(engine string) string {
+	name := engineName(engine)
+	if !strings.HasSuffix(name, "MergeTree") || strings.HasPrefix(name, "Shared") {
+		return engine
+	}
+	if args := engineArgs(engine); args != "" {
+		return fmt.Sprintf("Shared%s(%s)", name, args)
+	}
+	return "Shared" + name
+}
+
+// keepaliveInterval はアイドル接続のキープアライブ間隔を返します（0 の場合はキープアライブしない）
+func (cfg *Config) keepaliveInterval() time.Duration {
+	if cfg.ConnectionPool.KeepaliveInterval > 0 {
+		return cfg.ConnectionPool.KeepaliveInterval
+	}
+	if cfg.ClickHouseCloud {
+		return cloudKeepaliveInterval
+	}
+	return 0
+}
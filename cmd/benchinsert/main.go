@@ -0,0 +1,213 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// benchinsert は書き込み経路の性能比較ツールです
+// エクスポーターの既定の database/sql 経由の挿入（トランザクション内の準備済みINSERT）と、
+// use_native_batch: true で使用する clickhouse-go のネイティブバッチ（PrepareBatch/Append/Send）を、行の幅（属性数）ごとに比較します
+//
+// ClickHouseはDockerで起動できます（テーブルは bench_insert_* として作成し、終了時に削除します）:
+//
+//	docker run -d --name ch-bench -p 9000:9000 clickhouse/clickhouse-server
+//	go run ./cmd/benchinsert -dsn clickhouse://localhost:9000/default -rows 100000 -widths 4,16,64
+//
+// 同じ比較は go test のベンチマークとしても実行できます（ClickHouseは testutil が起動します）:
+//
+//	go test ./cmd/benchinsert -run '^$' -bench . -benchtime 20x
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// writePath は比較対象の書き込み経路です
+type writePath struct {
+	name   string
+	insert func(ctx context.Context, table string, rows [][]any) error
+}
+
+func main() {
+	dsn := flag.String("dsn", "clickhouse://localhost:9000/default", "ClickHouseの接続先DSN")
+	rows := flag.Int("rows", 100000, "1回の計測で挿入する行数")
+	batch := flag.Int("batch", 10000, "1バッチ（1トランザクション）あたりの行数")
+	widths := flag.String("widths", "4,16,64", "比較する行の幅（1行あたりの属性数）のカンマ区切りリスト")
+	runs := flag.Int("runs", 3, "経路・幅ごとの計測回数（中央値を表示）")
+	flag.Parse()
+
+	if err := run(*dsn, *rows, *batch, *widths, *runs); err != nil {
+		fmt.Fprintf(os.Stderr, "ベンチマークに失敗しました: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dsn string, rowCount, batchSize int, widthList string, runs int) error {
+	if rowCount <= 0 || batchSize <= 0 || runs <= 0 {
+		return fmt.Errorf("-rows, -batch, -runs は1以上を指定してください")
+	}
+	var widths []int
+	for _, w := range strings.Split(widthList, ",") {
+		width, err := strconv.Atoi(strings.TrimSpace(w))
+		if err != nil || width < 0 {
+			return fmt.Errorf("-widths の値が不正です: %q", w)
+		}
+		widths = append(widths, width)
+	}
+
+	ctx := context.Background()
+	db, err := sql.Open("clickhouse", dsn)
+	if err != nil {
+		return fmt.Errorf("database/sql 接続の作成に失敗しました: %w", err)
+	}
+	defer db.Close()
+	options, err := clickhouse.ParseDSN(dsn)
+	if err != nil {
+		return fmt.Errorf("DSNの解析に失敗しました: %w", err)
+	}
+	conn, err := clickhouse.Open(options)
+	if err != nil {
+		return fmt.Errorf("ネイティブ接続の作成に失敗しました: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.Ping(ctx); err != nil {
+		return fmt.Errorf("ClickHouseに接続できません: %w", err)
+	}
+
+	paths := newWritePaths(db, conn)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "width\tpath\trows/s\tns/row\tmedian\t")
+	for _, width := range widths {
+		rows := syntheticRows(rowCount, width)
+		for _, path := range paths {
+			table := benchTableName(width, path)
+			elapsed, err := measure(ctx, conn, table, rows, batchSize, runs, path)
+			if err != nil {
+				return fmt.Errorf("%s（幅 %d）: %w", path.name, width, err)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%.0f\t%.0f\t%s\t\n", width, path.name,
+				float64(rowCount)/elapsed.Seconds(), float64(elapsed.Nanoseconds())/float64(rowCount), elapsed.Round(time.Millisecond))
+		}
+	}
+	return w.Flush()
+}
+
+// newWritePaths は比較対象の書き込み経路を返します（ベンチマーク関数と共通）
+func newWritePaths(db *sql.DB, conn clickhouse.Conn) []writePath {
+	return []writePath{
+		{"database/sql", func(ctx context.Context, table string, rows [][]any) error {
+			return insertStd(ctx, db, table, rows)
+		}},
+		{"native batch", func(ctx context.Context, table string, rows [][]any) error {
+			return insertNative(ctx, conn, table, rows)
+		}},
+	}
+}
+
+// benchTableName は経路・幅ごとの計測用テーブル名を返します
+func benchTableName(width int, path writePath) string {
+	return fmt.Sprintf("bench_insert_w%d_%s", width, strings.NewReplacer("/", "_", " ", "_").Replace(path.name))
+}
+
+// createTable は計測用のテーブルを作り直します
+func createTable(ctx context.Context, conn clickhouse.Conn, table string) error {
+	if err := conn.Exec(ctx, "DROP TABLE IF EXISTS "+table); err != nil {
+		return err
+	}
+	return conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE %s (
+		Timestamp DateTime64(9),
+		TraceId String,
+		ServiceName LowCardinality(String),
+		Body String,
+		Attributes Map(LowCardinality(String), String)
+	) ENGINE = MergeTree ORDER BY (ServiceName, Timestamp)`, table))
+}
+
+// measure はテーブルを作り直しながら runs 回挿入し、所要時間の中央値を返します
+func measure(ctx context.Context, conn clickhouse.Conn, table string, rows [][]any, batchSize, runs int, path writePath) (time.Duration, error) {
+	defer func() { _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+table) }()
+
+	durations := make([]time.Duration, 0, runs)
+	for range runs {
+		if err := createTable(ctx, conn, table); err != nil {
+			return 0, err
+		}
+
+		start := time.Now()
+		for i := 0; i < len(rows); i += batchSize {
+			if err := path.insert(ctx, table, rows[i:min(i+batchSize, len(rows))]); err != nil {
+				return 0, err
+			}
+		}
+		durations = append(durations, time.Since(start))
+	}
+
+	// 外れ値の影響を抑えるため中央値を使用
+	slices.Sort(durations)
+	return durations[len(durations)/2], nil
+}
+
+// insertStd はエクスポーターと同じ database/sql の経路（トランザクション内の準備済みINSERT）で挿入します
+func insertStd(ctx context.Context, db *sql.DB, table string, rows [][]any) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s (Timestamp, TraceId, ServiceName, Body, Attributes) VALUES (?, ?, ?, ?, ?)", table))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertNative は clickhouse-go のネイティブバッチで挿入します
+func insertNative(ctx context.Context, conn clickhouse.Conn, table string, rows [][]any) error {
+	batch, err := conn.PrepareBatch(ctx, fmt.Sprintf("INSERT INTO %s (Timestamp, TraceId, ServiceName, Body, Attributes)", table))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = batch.Abort() }()
+	for _, row := range rows {
+		if err := batch.Append(row...); err != nil {
+			return err
+		}
+	}
+	return batch.Send()
+}
+
+// syntheticRows はログ行に似た合成データを生成します（width は1行あたりの属性数）
+func syntheticRows(count, width int) [][]any {
+	now := time.Now()
+	rows := make([][]any, count)
+	for i := range rows {
+		attrs := make(map[string]string, width)
+		for j := 0; j < width; j++ {
+			attrs["attr."+strconv.Itoa(j)] = "value-" + strconv.Itoa((i+j)%1000)
+		}
+		rows[i] = []any{
+			now.Add(time.Duration(i) * time.Microsecond),
+			fmt.Sprintf("%032x", i),
+			"service-" + strconv.Itoa(i%10),
+			"synthetic log line " + strconv.Itoa(i),
+			attrs,
+		}
+	}
+	return rows
+}
@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+
+	"github.com/dtamura/myexporter/testutil"
+)
+
+// benchBatchSize はベンチマークの1回の操作（1バッチ）で挿入する行数です
+const benchBatchSize = 1000
+
+// BenchmarkInsert は database/sql（トランザクション内の準備済みINSERT）とネイティブバッチ（PrepareBatch/Append/Send）で、
+// 1バッチの挿入にかかる時間を行の幅（属性数）ごとに比較します
+func BenchmarkInsert(b *testing.B) {
+	ch := testutil.StartClickHouse(b)
+	ctx := context.Background()
+
+	options, err := clickhouse.ParseDSN(fmt.Sprintf("%s?username=%s&password=%s", ch.Endpoint, ch.Username, ch.Password))
+	if err != nil {
+		b.Fatalf("DSNの解析に失敗しました: %v", err)
+	}
+	conn, err := clickhouse.Open(options)
+	if err != nil {
+		b.Fatalf("ネイティブ接続の作成に失敗しました: %v", err)
+	}
+	b.Cleanup(func() { _ = conn.Close() })
+
+	for _, width := range []int{4, 16, 64} {
+		rows := syntheticRows(benchBatchSize, width)
+		for _, path := range newWritePaths(ch.DB, conn) {
+			b.Run(fmt.Sprintf("width=%d/%s", width, path.name), func(b *testing.B) {
+				table := benchTableName(width, path)
+				if err := createTable(ctx, conn, table); err != nil {
+					b.Fatalf("テーブルの作成に失敗しました: %v", err)
+				}
+				b.Cleanup(func() { _ = conn.Exec(ctx, "DROP TABLE IF EXISTS "+table) })
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					if err := path.insert(ctx, table, rows); err != nil {
+						b.Fatalf("挿入に失敗しました: %v", err)
+					}
+				}
+				b.StopTimer()
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(rows)), "ns/row")
+			})
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// schematool はコレクターをデプロイする前にスキーマを作成するプロビジョニング用ツールです
+// コレクター形式のYAMLからこのエクスポーターの設定を読み込み、データベース・テーブル・ビューの作成のみを実行します
+// データの挿入は行わず、-dry-run の場合はDBに接続せずに実行予定のDDLを出力します
+//
+// 使い方:
+//
+//	go run ./cmd/schematool -config collector.yaml [-exporter mylogexporter/prod] [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dtamura/myexporter"
+)
+
+func main() {
+	configPath := flag.String("config", "", "コレクター設定YAML（またはエクスポーター設定のみのフラグメント）のパス")
+	exporterID := flag.String("exporter", "", "exporters セクション内のコンポーネントID（例: mylogexporter/prod）")
+	dryRun := flag.Bool("dry-run", false, "DBに接続せず、実行予定のDDLを出力する")
+	timeout := flag.Duration("timeout", 5*time.Minute, "スキーマ作成全体のタイムアウト")
+	flag.Parse()
+
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, "-config を指定してください")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*configPath, *exporterID, *dryRun, *timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "スキーマの作成に失敗しました: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, exporterID string, dryRun bool, timeout time.Duration) error {
+	cfg, err := myexporter.LoadConfigFile(configPath, exporterID)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("設定が不正です: %w", err)
+	}
+
+	if dryRun {
+		stmts, err := myexporter.RenderSchemaDDL(cfg)
+		if err != nil {
+			return err
+		}
+		return myexporter.WriteSchemaDDL(os.Stdout, stmts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := myexporter.CreateSchema(ctx, cfg, os.Stdout); err != nil {
+		return err
+	}
+	fmt.Println("-- スキーマの作成に成功しました")
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// validate はGitOpsパイプライン向けの事前チェックツールです
+// コレクター形式のYAMLからこのエクスポーターの設定を読み込み、
+// Validateの実行、全DDLのレンダリング、（任意で）読み取り専用の権限確認を行います
+//
+// 使い方:
+//
+//	go run ./cmd/validate -config collector.yaml [-exporter mylogexporter/prod] [-connect]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dtamura/myexporter"
+)
+
+func main() {
+	configPath := flag.String("config", "", "コレクター設定YAML（またはエクスポーター設定のみのフラグメント）のパス")
+	exporterID := flag.String("exporter", "", "exporters セクション内のコンポーネントID（例: mylogexporter/prod）")
+	connect := flag.Bool("connect", false, "データベースに読み取り専用で接続して権限を確認する")
+	timeout := flag.Duration("timeout", 30*time.Second, "接続確認のタイムアウト")
+	flag.Parse()
+
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, "-config を指定してください")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*configPath, *exporterID, *connect, *timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "検証に失敗しました: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, exporterID string, connect bool, timeout time.Duration) error {
+	cfg, err := myexporter.LoadConfigFile(configPath, exporterID)
+	if err != nil {
+		return err
+	}
+
+	// 1. 設定の検証
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("設定が不正です: %w", err)
+	}
+	fmt.Println("-- 設定の検証に成功しました")
+
+	// 2. DDLのレンダリング
+	stmts, err := myexporter.RenderSchemaDDL(cfg)
+	if err != nil {
+		return err
+	}
+	if err := myexporter.WriteSchemaDDL(os.Stdout, stmts); err != nil {
+		return err
+	}
+
+	// 3. 読み取り専用の権限確認（任意）
+	if connect {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := myexporter.CheckPermissions(ctx, cfg); err != nil {
+			return err
+		}
+		fmt.Println("\n-- データベース接続と権限の確認に成功しました")
+	}
+
+	return nil
+}
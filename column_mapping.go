@@ -0,0 +1,112 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ColumnMappingConfig - 既存のテーブル（ユーザー管理のスキーマ）へ挿入するための列の対応付け
+// 挿入先の列名ごとに、既定の列名（例: Timestamp）または属性列のキー（例: SpanAttributes[http.method]）を指定します
+// 指定した列のみを挿入するため、create_schema: false とし、traces_table_name などで既存のテーブルを指定して使用します
+//
+//	column_mapping:
+//	  traces:
+//	    ts: Timestamp
+//	    trace_id: TraceId
+//	    http_method: SpanAttributes[http.method]
+type ColumnMappingConfig struct {
+	Traces map[string]string `mapstructure:"traces"` // トレースの挿入先の列名と値
+	Logs   map[string]string `mapstructure:"logs"`   // ログの挿入先の列名と値
+}
+
+// validate は対応付けを検証します（insert_sql と同じシグナルには指定できない）
+func (c ColumnMappingConfig) validate(cfg *Config) error {
+	var errs error
+	for _, signal := range []struct {
+		name    string
+		mapping map[string]string
+		custom  string
+		columns []string
+	}{
+		{"traces", c.Traces, cfg.InsertSQL.Traces, cfg.insertColumns("traces")},
+		{"logs", c.Logs, cfg.InsertSQL.Logs, cfg.insertColumns("logs")},
+	} {
+		if len(signal.mapping) == 0 {
+			continue
+		}
+		if signal.custom != "" {
+			errs = errors.Join(errs, fmt.Errorf("column_mapping.%s と insert_sql.%s は同時に指定できません", signal.name, signal.name))
+			continue
+		}
+		valid := true
+		for target := range signal.mapping {
+			if target == "" || strings.ContainsAny(target, "\"`") {
+				errs = errors.Join(errs, fmt.Errorf("column_mapping.%s の列名 %q が不正です", signal.name, target))
+				valid = false
+			}
+		}
+		if !valid {
+			continue
+		}
+		if _, _, _, err := bindNamedPlaceholders(renderColumnMappingSQL(signal.mapping), signal.columns); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("column_mapping.%s: %w", signal.name, err))
+		}
+	}
+	return errs
+}
+
+// renderColumnMappingSQL は対応付けから insert_sql と同じ形式の挿入SQLテンプレートを生成します（列は列名順）
+func renderColumnMappingSQL(mapping map[string]string) string {
+	targets := slices.Sorted(maps.Keys(mapping))
+	values := make([]string, len(targets))
+	for i, target := range targets {
+		values[i] = "@" + strings.TrimSpace(mapping[target])
+		targets[i] = `"` + target + `"`
+	}
+	return fmt.Sprintf(`INSERT INTO "{{.Database}}"."{{.Table}}" (%s) VALUES (%s)`,
+		strings.Join(targets, ", "), strings.Join(values, ", "))
+}
+
+// customInsertSQL はシグナルの挿入SQLの上書き（insert_sql、または column_mapping から生成したSQL）を返します（上書きしない場合は空）
+func (cfg *Config) customInsertSQL(signal string) string {
+	switch signal {
+	case "traces":
+		if len(cfg.ColumnMapping.Traces) > 0 {
+			return renderColumnMappingSQL(cfg.ColumnMapping.Traces)
+		}
+		return cfg.InsertSQL.Traces
+	case "logs":
+		if len(cfg.ColumnMapping.Logs) > 0 {
+			return renderColumnMappingSQL(cfg.ColumnMapping.Logs)
+		}
+		return cfg.InsertSQL.Logs
+	}
+	return ""
+}
+
+// expectedColumns はテーブル定義の確認に使用する挿入先の列を返します（column_mapping 指定時はその列名）
+func (cfg *Config) expectedColumns(signal string) []string {
+	mapping := cfg.ColumnMapping.Traces
+	if signal == "logs" {
+		mapping = cfg.ColumnMapping.Logs
+	}
+	if len(mapping) == 0 {
+		return cfg.insertColumns(signal)
+	}
+	return slices.Sorted(maps.Keys(mapping))
+}
+
+// insertColumns は既定の挿入SQLの列順です
+// （traces.store_scope・max_attribute_length・source_columns・anomaly_detection・logs.parse_body_json・log_embeddings・record_ingest_time で追加される列を含む）
+func (cfg *Config) insertColumns(signal string) []string {
+	if signal == "logs" {
+		return cfg.ingestTimeInsertColumns(cfg.LogEmbeddings.insertColumns(cfg.Logs.insertColumns(cfg.SourceColumns.insertColumns(cfg.truncatedInsertColumns(signal, logInsertColumns)))))
+	}
+	return cfg.ingestTimeInsertColumns(cfg.AnomalyDetection.insertColumns(cfg.SourceColumns.insertColumns(cfg.truncatedInsertColumns(signal, cfg.Traces.insertColumns(traceInsertColumns)))))
+}
@@ -0,0 +1,131 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+)
+
+// ColumnTTLConfig - 列単位のTTL設定（シグナルごとに 列名: 保持期間）
+// 行は ttl / ttl_days の期間保持したまま、スタックトレースや属性など容量の大きい列だけを先に空（既定値）にします
+//
+//	column_ttl:
+//	  logs:
+//	    Body: 168h
+//	  traces:
+//	    Events.Attributes: 168h
+type ColumnTTLConfig struct {
+	Traces   map[string]time.Duration `mapstructure:"traces"`
+	Logs     map[string]time.Duration `mapstructure:"logs"`
+	Metrics  map[string]time.Duration `mapstructure:"metrics"` // 全タイプのメトリクステーブルに適用
+	Profiles map[string]time.Duration `mapstructure:"profiles"`
+}
+
+// forSignal はシグナルの列TTLを返します
+func (c ColumnTTLConfig) forSignal(signal string) map[string]time.Duration {
+	switch signal {
+	case "traces":
+		return c.Traces
+	case "logs":
+		return c.Logs
+	case "metrics":
+		return c.Metrics
+	case "profiles":
+		return c.Profiles
+	default:
+		return nil
+	}
+}
+
+// validate は列TTLの設定を検証します
+// ClickHouseは並び替えキーに含まれる列にTTLを設定できないため、ORDER BY 式に含まれる列は拒否します
+func (c ColumnTTLConfig) validate(cfg *Config) error {
+	var errs error
+	for _, s := range []struct{ signal, orderBy string }{
+		{"traces", orderBy(cfg.TracesOrderBy, defaultTracesOrderBy)},
+		{"logs", cfg.logsOrderBy()},
+		{"metrics", orderBy(cfg.MetricsOrderBy, defaultMetricsOrderBy)},
+		{"profiles", orderBy(cfg.ProfilesOrderBy, defaultProfilesOrderBy)},
+	} {
+		for column, ttl := range c.forSignal(s.signal) {
+			if ttl <= 0 {
+				errs = errors.Join(errs, fmt.Errorf("column_ttl.%s.%s は0より大きい必要があります: %s", s.signal, column, ttl))
+			}
+			if column == "" || strings.ContainsAny(column, "`;") {
+				errs = errors.Join(errs, fmt.Errorf("column_ttl.%s の列名が不正です: %q", s.signal, column))
+			}
+			if column == ttlTimeColumn(cfg, s.signal) || regexp.MustCompile(`\b`+regexp.QuoteMeta(column)+`\b`).MatchString(s.orderBy) {
+				errs = errors.Join(errs, fmt.Errorf("column_ttl.%s.%s: 時刻列と ORDER BY に含まれる列にはTTLを設定できません", s.signal, column))
+			}
+		}
+	}
+	return errs
+}
+
+// ttlTimeColumn はTTLの基準とするシグナルのテーブルの時刻列です
+func ttlTimeColumn(cfg *Config, signal string) string {
+	switch signal {
+	case "metrics":
+		return "TimeUnix"
+	case "logs":
+		return cfg.logsTimeColumn()
+	}
+	return "Timestamp"
+}
+
+// renderColumnTTLSQL は列TTLを設定する ALTER TABLE 文を返します（signal が空の場合は全シグナル）
+// MODIFY COLUMN ... TTL は既存テーブルにも適用でき、何度実行しても結果は同じです
+// 分散テーブル構成ではデータを保持するローカルテーブルに設定します
+func renderColumnTTLSQL(cfg *Config, signal string) []SchemaStatement {
+	var stmts []SchemaStatement
+	cluster := ""
+	if cfg.ClusterName != "" {
+		cluster = " " + cfg.clusterString()
+	}
+	for _, table := range managedTables(cfg) {
+		if signal != "" && table.signal != signal {
+			continue
+		}
+		columns := cfg.ColumnTTL.forSignal(table.signal)
+		// 出力を安定させるため列名でソート
+		names := make([]string, 0, len(columns))
+		for column := range columns {
+			names = append(names, column)
+		}
+		slices.Sort(names)
+
+		for _, column := range names {
+			ttl := internal.GenerateTTLExpr(columns[column], fmt.Sprintf("toDateTime(%s)", ttlTimeColumn(cfg, table.signal)))
+			stmts = append(stmts, SchemaStatement{
+				Description: fmt.Sprintf("column TTL %s.%s", table.name, column),
+				SQL: fmt.Sprintf("ALTER TABLE \"%s\".\"%s\"%s MODIFY COLUMN `%s` %s",
+					table.database, cfg.localTable(table.name), cluster, column, ttl),
+			})
+		}
+	}
+	return stmts
+}
+
+// applyColumnTTL はシグナルのテーブルに列TTLを設定します
+func applyColumnTTL(ctx context.Context, cfg *Config, signal string, db *sql.DB, logger *zap.Logger) error {
+	for _, stmt := range renderColumnTTLSQL(cfg, signal) {
+		logger.Debug("列TTLを設定しています", zap.String("description", stmt.Description), zap.String("sql", stmt.SQL))
+		if _, err := db.ExecContext(ctx, stmt.SQL); err != nil {
+			return fmt.Errorf("%s の設定に失敗しました: %w", stmt.Description, err)
+		}
+		logger.Info("列TTLを設定しました", zap.String("description", stmt.Description))
+	}
+	return nil
+}
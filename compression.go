@@ -0,0 +1,94 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// 圧縮アルゴリズム（compress）
+const (
+	compressLZ4  = "lz4"  // 既定（clickhouseexporterと同様）
+	compressZSTD = "zstd" // lz4より圧縮率が高く、CPU負荷も高い
+	compressGZIP = "gzip" // HTTPプロトコルのみ
+	compressNone = "none"
+)
+
+// compressionLevelRanges は compression_level に指定できる範囲です（lz4 と none はレベルを持たない）
+var compressionLevelRanges = map[string][2]int{
+	compressZSTD: {1, 22},
+	compressGZIP: {1, 9},
+}
+
+// compressionMethod は compress の値を圧縮アルゴリズムに正規化します
+// 以前の設定との互換のため、未指定と "true" は lz4、"false" は none として扱います
+func (cfg *Config) compressionMethod() string {
+	switch cfg.Compress {
+	case "", "true":
+		return compressLZ4
+	case "false":
+		return compressNone
+	}
+	return cfg.Compress
+}
+
+// validateCompression は圧縮アルゴリズムとレベルの組み合わせ、エンドポイントのプロトコルとの対応を検証します
+func (cfg *Config) validateCompression() error {
+	method := cfg.compressionMethod()
+	switch method {
+	case compressLZ4, compressZSTD, compressGZIP, compressNone:
+	default:
+		return fmt.Errorf("compress は lz4, zstd, gzip, none のいずれかを指定してください: %s", cfg.Compress)
+	}
+
+	if cfg.CompressionLevel != 0 {
+		levels, ok := compressionLevelRanges[method]
+		if !ok {
+			return fmt.Errorf("compression_level は compress が zstd または gzip の場合のみ指定できます: %s", method)
+		}
+		if cfg.CompressionLevel < levels[0] || cfg.CompressionLevel > levels[1] {
+			return fmt.Errorf("%s の compression_level は%dから%dの範囲で指定してください: %d", method, levels[0], levels[1], cfg.CompressionLevel)
+		}
+	}
+
+	// clickhouse-go はネイティブプロトコルで gzip を扱えず、接続時に初めて失敗するため設定時に検出する
+	if endpoints := cfg.endpoints(); method == compressGZIP && len(endpoints) > 0 && !cfg.isPostgres() {
+		if dsnURL, err := parseEndpoint(endpoints[0]); err == nil && !isHTTPScheme(dsnURL.Scheme) {
+			return fmt.Errorf("compress: gzip はHTTPプロトコル（http, https）のエンドポイントでのみ使用できます: %s", cfg.Endpoint)
+		}
+	}
+	return nil
+}
+
+// isHTTPScheme はエンドポイントがHTTPプロトコルかどうかを返します
+func isHTTPScheme(scheme string) bool {
+	return scheme == "http" || scheme == "https"
+}
+
+// applyCompression は圧縮設定をDSNのパラメータに反映します（接続パラメータやエンドポイントでの明示的な指定を優先）
+// compress と compress_level は clickhouse-go がネイティブ・HTTPの両プロトコルで送信データの圧縮に使用します
+// HTTPの gzip では応答の圧縮もサーバーに要求します（enable_http_compression, http_zlib_compression_level）
+// zstd のレベルはドライバーに渡しますが、ドライバーのバージョンによっては既定のレベルで圧縮されます
+func (cfg *Config) applyCompression(params url.Values, scheme string) {
+	if params.Has("compress") {
+		return
+	}
+	method := cfg.compressionMethod()
+	params.Set("compress", method)
+	if cfg.CompressionLevel != 0 && !params.Has("compress_level") {
+		params.Set("compress_level", strconv.Itoa(cfg.CompressionLevel))
+	}
+
+	if method != compressGZIP || !isHTTPScheme(scheme) {
+		return
+	}
+	if !params.Has("enable_http_compression") {
+		params.Set("enable_http_compression", "1")
+	}
+	if cfg.CompressionLevel != 0 && !params.Has("http_zlib_compression_level") {
+		params.Set("http_zlib_compression_level", strconv.Itoa(cfg.CompressionLevel))
+	}
+}
@@ -4,18 +4,26 @@
 package myexporter
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/configopaque"
 	"go.opentelemetry.io/collector/config/configretry"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/dtamura/myexporter/pdatarows"
 )
 
 // Config は my-log エクスポーターの設定を定義します。
 type Config struct {
 	// エクスポーター標準設定（新しいAPIに対応）
+	// sending_queue.storage にストレージ拡張機能（file_storage など）を指定すると永続キューを使用し、
+	// キュー内のデータがコレクターの再起動後も失われません（未指定の場合はメモリ上のキュー）
 	TimeoutSettings           exporterhelper.TimeoutConfig `mapstructure:",squash"`
 	configretry.BackOffConfig `mapstructure:"retry_on_failure"`
 	QueueSettings             exporterhelper.QueueBatchConfig `mapstructure:"sending_queue"`
@@ -24,49 +32,725 @@ type Config struct {
 	Prefix   string `mapstructure:"prefix"`
 	Detailed bool   `mapstructure:"detailed"`
 
+	// 詳細モードの出力形式（text, json, otlp_json）
+	// json と otlp_json は受信した各データを1行ずつ標準出力に書き込み、jq や Loki に直接渡せるようにします
+	LogFormat string `mapstructure:"log_format"`
+
+	// 詳細モードで出力するデータのサンプリング設定（未指定の場合は全件）
+	DetailedSampling DetailedSamplingConfig `mapstructure:"detailed_sampling"`
+
+	// 保存先のデータベース（clickhouse または postgres、未指定の場合は clickhouse）
+	Driver string `mapstructure:"driver"`
+
 	// DB接続設定（clickhouseexporterを参考）
-	Endpoint         string              `mapstructure:"endpoint"`          // データベースのエンドポイント
+	Endpoint         string              `mapstructure:"endpoint"`          // データベースのエンドポイント（カンマ区切りまたはリストで複数指定可）
 	Username         string              `mapstructure:"username"`          // 認証用ユーザー名
 	Password         configopaque.String `mapstructure:"password"`          // 認証用パスワード
 	Database         string              `mapstructure:"database"`          // データベース名
 	TableName        string              `mapstructure:"table_name"`        // テーブル名
 	ConnectionParams map[string]string   `mapstructure:"connection_params"` // 追加接続パラメータ
 
+	// パスワードを読み込むファイル（password と同時には指定できない、パスは ${env:...} でも指定可）
+	// Vault Agent などがローテーションで書き換えるファイルを想定し、変更を検知すると新しいパスワードで接続し直す
+	// use_native_batch のネイティブバッチ用の接続は開始時のパスワードを使用し続けるため、ローテーション後は再起動が必要
+	PasswordFile string `mapstructure:"password_file"`
+	// password_file の変更を確認する間隔（既定: 30秒）
+	PasswordFileReloadInterval time.Duration `mapstructure:"password_file_reload_interval"`
+
+	// 追加の書き込み先（DRリージョンなど、トレースとログを endpoint と同時に書き込む別のクラスター）
+	Targets []TargetConfig `mapstructure:"targets"`
+
+	// 接続プールの設定（アイドル接続の再作成と開始時の事前接続）
+	ConnectionPool ConnectionPoolConfig `mapstructure:"connection_pool"`
+
+	// ClickHouse Cloud 向けの既定値を使用する（driver: clickhouse のみ）
+	// セキュア接続を必須とし、テーブルを SharedMergeTree 系のエンジンで作成し、アイドル接続をキープアライブする
+	ClickHouseCloud bool `mapstructure:"clickhouse_cloud"`
+
+	// 再起動後も引き継ぐ状態（スパン名の上限の計数など）の保存先ストレージ拡張（file_storage など）
+	// 未指定の場合は状態を保存せず、再起動で初期化される
+	StateStorage *component.ID `mapstructure:"state_storage"`
+
+	// 複数エンドポイント指定時の負荷分散とフェイルオーバーの設定
+	LoadBalancing LoadBalancingConfig `mapstructure:"load_balancing"`
+
+	// シグナルごとのデータベース（未指定の場合は Database を使用）
+	// 接続プールは Database に接続するため、すべてのシグナルで上書きした場合も Database は存在する必要があります
+	// （create_schema 有効時は Database も作成します）
+	TracesDatabase   string `mapstructure:"traces_database"`   // トレース用データベース名
+	LogsDatabase     string `mapstructure:"logs_database"`     // ログ用データベース名
+	MetricsDatabase  string `mapstructure:"metrics_database"`  // メトリクス用データベース名
+	ProfilesDatabase string `mapstructure:"profiles_database"` // プロファイル用データベース名
+
 	// 新しく追加された設定（clickhouseexporterと同様）
-	CreateSchema    bool          `mapstructure:"create_schema"`     // データベース作成の制御
-	Compress        string        `mapstructure:"compress"`          // 圧縮アルゴリズム
-	AsyncInsert     bool          `mapstructure:"async_insert"`      // 非同期挿入
-	TTL             time.Duration `mapstructure:"ttl"`               // データ保持期間
-	TTLDays         int           `mapstructure:"ttl_days"`          // データ保持期間（日数）
-	TracesTableName string        `mapstructure:"traces_table_name"` // トレーステーブル名
-	LogsTableName   string        `mapstructure:"logs_table_name"`   // ログテーブル名
-	TableEngine     string        `mapstructure:"table_engine"`      // ClickHouseテーブルエンジン
-	ClusterName     string        `mapstructure:"cluster_name"`      // ClickHouseクラスタ名
+	CreateSchema      bool          `mapstructure:"create_schema"`       // データベース作成の制御
+	VerifySchema      bool          `mapstructure:"verify_schema"`       // create_schema 無効時に開始時に既存のテーブルの列を確認
+	Compress          string        `mapstructure:"compress"`            // 圧縮アルゴリズム（lz4, zstd, gzip, none）
+	CompressionLevel  int           `mapstructure:"compression_level"`   // 圧縮レベル（zstd: 1-22, gzip: 1-9、0の場合はドライバーの既定）
+	AsyncInsert       bool          `mapstructure:"async_insert"`        // 非同期挿入
+	UseNativeBatch    bool          `mapstructure:"use_native_batch"`    // clickhouse-go のネイティブバッチ（列単位の送信）で挿入（driver: clickhouse のみ）
+	InsertTimeout     time.Duration `mapstructure:"insert_timeout"`      // 1回の挿入（バッチ送信）のタイムアウト（0の場合は timeout のみ）
+	MaxInsertRows     int           `mapstructure:"max_insert_rows"`     // 1回の挿入の最大行数（超えるバッチは分割して挿入、0の場合は無制限、トレース・ログのみ）
+	StartTimeout      time.Duration `mapstructure:"start_timeout"`       // 開始処理（データベース・テーブルの作成、接続テスト）全体のタイムアウト（0の場合は無制限）
+	TTL               time.Duration `mapstructure:"ttl"`                 // データ保持期間（全シグナル共通、0の場合は無期限）
+	TTLDays           int           `mapstructure:"ttl_days"`            // データ保持期間（日数、非推奨: ttl を使用）
+	TracesTTL         time.Duration `mapstructure:"traces_ttl"`          // トレーステーブルのデータ保持期間（未指定の場合は ttl）
+	LogsTTL           time.Duration `mapstructure:"logs_ttl"`            // ログテーブルのデータ保持期間（未指定の場合は ttl）
+	MetricsTTL        time.Duration `mapstructure:"metrics_ttl"`         // メトリクステーブルのデータ保持期間（未指定の場合は ttl）
+	TracesTableName   string        `mapstructure:"traces_table_name"`   // トレーステーブル名
+	LogsTableName     string        `mapstructure:"logs_table_name"`     // ログテーブル名
+	ProfilesTableName string        `mapstructure:"profiles_table_name"` // プロファイルテーブル名
+	TableEngine       string        `mapstructure:"table_engine"`        // ClickHouseテーブルエンジン
+	ClusterName       string        `mapstructure:"cluster_name"`        // ClickHouseクラスタ名
+	DatabaseEngine    string        `mapstructure:"database_engine"`     // ClickHouseデータベースエンジン（未指定の場合はサーバーの既定）
+
+	// 挿入ごとに付与するClickHouseの設定（例: max_insert_block_size, insert_deduplicate, async_insert_busy_timeout_ms）
+	// 接続パラメータと異なりこのエクスポーターの挿入にのみ適用されるため、同じエンドポイントを使う他のエクスポーターに影響しません
+	InsertSettings map[string]string `mapstructure:"insert_settings"`
+
+	// トレース・ログの挿入ごとに、バッチの内容のハッシュを insert_deduplication_token として付与する
+	// タイムアウト後のリトライなどで同じバッチが再送された場合に、ClickHouseが挿入済みのブロックを破棄します
+	// Replicated*MergeTree のテーブル、または non_replicated_deduplication_window を設定したテーブルでのみ有効です
+	// 重複排除の範囲は replicated_deduplication_window（既定: 直近1000ブロック、Replicated*）の内側に限られ、
+	// 大量のリトライが続く場合はそれより古いバッチの再送は重複として検出されません
+	InsertDeduplicationToken bool `mapstructure:"insert_deduplication_token"`
+
+	// スキーマ作成のドライラン（DDLを実行せず、設定値でレンダリングした全DDLを出力する）
+	// DDLの実行権限がない環境で、DBAがレビューして手動で適用するために使用する
+	CreateSchemaDryRun     bool   `mapstructure:"create_schema_dry_run"`      // ドライランの有効化
+	CreateSchemaDryRunFile string `mapstructure:"create_schema_dry_run_file"` // DDLの出力先ファイル（未指定の場合はログに出力）
+
+	// よく使う集計（サービスごと・1分ごとのスパンのエラー率、重要度ごと・1分ごとのログ件数）のテーブルとマテリアライズドビューを作成する
+	// テーブル名はメインテーブル名に _errors_1m（トレース）、_severity_1m（ログ）を付けたもの（create_schema 有効時のみ作成、driver: clickhouse のみ）
+	CreateAggregationViews bool `mapstructure:"create_aggregation_views"`
+
+	// テーブル作成時のORDER BY（主キー）式の上書き（未指定の場合は既定の並び順）
+	// 主要な検索パターンに合わせて指定する（例: (ServiceName, Timestamp) や (Timestamp)）
+	TracesOrderBy   string `mapstructure:"traces_order_by"`   // トレーステーブルのORDER BY
+	LogsOrderBy     string `mapstructure:"logs_order_by"`     // ログテーブルのORDER BY
+	MetricsOrderBy  string `mapstructure:"metrics_order_by"`  // メトリクステーブル（全タイプ共通）のORDER BY
+	ProfilesOrderBy string `mapstructure:"profiles_order_by"` // プロファイルテーブルのORDER BY
+
+	// ログテーブルのパーティション・TTL・既定のORDER BYの基準とする時刻（timestamp または observed_timestamp）
+	// バッチで送られるログは元の Timestamp が大きくずれていることがあり、パーティションが分散して検索時の絞り込みが効かなくなるため、
+	// observed_timestamp を指定すると収集時刻を基準にします（テーブル作成時のみ反映され、既存のテーブルは変更しない）
+	LogsPrimaryTime string `mapstructure:"logs_primary_time"`
+
+	// 指定した場合、スパン・ログレコードのこの属性の値を Timestamp 列に使用します（例: ログ本文から取り出した元のイベント時刻）
+	// 過去のデータを TTL・パーティションが時刻で分かれたテーブルに投入する場合に使用します
+	// 値は RFC 3339 の文字列または Unix 時刻（ナノ秒）の整数で、属性がない・解釈できない場合は OTLP の時刻を使用します
+	TimestampOverrideAttribute string `mapstructure:"timestamp_override_attribute"`
+
+	// 全シグナルのテーブルに取り込み時刻の列を作成する（IngestTimestamp: ClickHouseが書き込んだ時刻、ExportTimestamp: エクスポーターが送信した時刻）
+	// イベントの時刻との差からパイプラインの遅延をClickHouse上で直接集計できます（driver: clickhouse のみ）
+	// 既存のテーブルには列が追加されないため、有効化する場合は ALTER TABLE で列を追加してください
+	RecordIngestTime bool `mapstructure:"record_ingest_time"`
+
+	// 属性カラムの形式（map または json、トレース・ログテーブルのリソース/スパン/ログ属性に適用）
+	// json を指定する場合は JSON 型をサポートする ClickHouse 25.3 以降が必要
+	AttributesFormat string `mapstructure:"attributes_format"`
+
+	// Map 型の属性カラムのキーを LowCardinality(String) で作成するかどうか（既定: true）
+	// 属性キーの種類は少ないため、辞書エンコードで属性の多いテーブルのサイズを削減できる
+	// 21.8 未満の ClickHouse ではテーブル作成前にエラーとなるため false を指定する
+	AttributesLowCardinalityKeys bool `mapstructure:"attributes_low_cardinality_keys"`
+
+	// スコープ属性を保存するかどうか（全シグナル共通、既定: true）
+	// false の場合は ScopeAttributes 列に空の値を保存して行の幅を抑える（スコープ名・バージョン・スキーマURLは保存する）
+	StoreScopeAttributes bool `mapstructure:"store_scope_attributes"`
+
+	// 属性キーの最大長（バイト、0 の場合は無制限）
+	// 超えるキーは元のキーのハッシュを接尾辞に付けて短縮する（Mapキーのカーディナリティ・ClickHouseの制限対策）
+	MaxAttributeKeyLength int `mapstructure:"max_attribute_key_length"`
+
+	// 文字列の属性値・ログ本文の最大長（バイト、0 の場合は無制限）
+	// 超える値は先頭のみを保存し、Truncated 列に短縮した行であることを記録する（1件の巨大なレコードによるパートの肥大化・挿入の失敗を防ぐ）
+	// 既存のテーブルには Truncated 列が追加されないため、有効化する場合は ALTER TABLE で列を追加してください
+	MaxAttributeLength int `mapstructure:"max_attribute_length"` // トレース・ログの属性値（リソース・スコープ・イベント・リンクを含む）
+	MaxBodyLength      int `mapstructure:"max_body_length"`      // ログ本文
+
+	// driver: postgres の場合のPostgreSQL（TimescaleDB）固有の設定
+	Postgres PostgresConfig `mapstructure:"postgres"`
+
+	// 列単位のTTL（容量の大きい列を行より先に期限切れにする）
+	ColumnTTL ColumnTTLConfig `mapstructure:"column_ttl"`
+
+	// クラスター展開向けのレプリケーション・分散テーブル設定
+	Replication ReplicationConfig `mapstructure:"replication"`
+
+	// 起動時のスキーママイグレーション設定
+	Migrations MigrationsConfig `mapstructure:"migrations"`
+
+	// トレーステーブルに保存するスパンの内容（イベント・リンク）の設定
+	Traces TracesConfig `mapstructure:"traces"`
+
+	// ログテーブルに保存するログレコードの内容（本文のJSON解析）の設定
+	Logs LogsConfig `mapstructure:"logs"`
+
+	// メトリクスのデータポイントの扱い（ステールマーカー）の設定
+	Metrics MetricsConfig `mapstructure:"metrics"`
+
+	// トレースID-タイムスタンプ検索テーブルの設定
+	TraceIDLookup TraceIDLookupConfig `mapstructure:"trace_id_lookup"`
+
+	// マルチテナント設定
+	MultiTenancy MultiTenancyConfig `mapstructure:"multi_tenancy"`
+
+	// 軽量DELETE（論理削除）設定
+	SoftDelete SoftDeleteConfig `mapstructure:"soft_delete"`
+
+	// ログの最小重要度（TRACE, DEBUG, INFO, WARN, ERROR, FATAL）
+	// 指定した重要度未満のログレコードは出力・挿入前に破棄する（未指定の場合は全て出力）
+	MinSeverity string `mapstructure:"min_severity"`
+
+	// メトリクスストリーム数のソフトリミット（上限を超えたストリームはオーバーフロー系列に集約）
+	CardinalityLimit CardinalityLimitConfig `mapstructure:"cardinality_limit"`
+
+	// 型が不明、またはデータポイントのないメトリクスの扱い（keep, skip, warn, reject、既定: keep）
+	// データ品質の低下（送信元の不具合による空のメトリクスなど）を検出するために warn または reject を指定する
+	UnsupportedMetrics string `mapstructure:"unsupported_metrics"`
+
+	// ヒストグラム（明示的なバケット・指数バケット）の1データポイントあたりのバケット数の上限（0 の場合は無制限）
+	// 数千のバケットを持つヒストグラムによる行の肥大化を防ぐ
+	MaxHistogramBuckets int `mapstructure:"max_histogram_buckets"`
+	// バケット数が上限を超えたヒストグラムの扱い（truncate, reject、既定: truncate）
+	// truncate は明示的なバケットの末尾をオーバーフローバケットにまとめ、指数バケットはスケールを下げてまとめる
+	HistogramBucketOverflow string `mapstructure:"histogram_bucket_overflow"`
+
+	// OTTL の条件式によるシグナルごとのフィルタ（条件に一致したアイテムを破棄、または一致したアイテムのみを残す）
+	Filter FilterConfig `mapstructure:"filter"`
+
+	// フィルタのプレビュー（min_severity, filter, cardinality_limit を評価して件数のみを記録し、実際には破棄・集約しない）
+	// 規則を有効にする前に、どれだけのデータが影響を受けるかを確認するために使用する
+	FilterPreview bool `mapstructure:"filter_preview"`
+
+	// 処理完了ログの集計間隔（0 の場合は送信ごとにログを記録し、指定した場合は間隔ごとに1行のサマリーを記録する）
+	SummaryInterval time.Duration `mapstructure:"summary_interval"`
+
+	// prefix, detailed, detailed_sampling, summary_interval を再起動せずに変更する上書きファイル
+	LiveReload LiveReloadConfig `mapstructure:"live_reload"`
+
+	// サービスごとの挿入の公平性（1サービスがバッチを占有しないよう、上限を超えた分を後回しにする）
+	Fairness FairnessConfig `mapstructure:"fairness"`
+
+	// メトリクスのリソース・スコープをディメンションテーブルに分離する正規化スキーマの設定
+	MetricsDimensions MetricsDimensionsConfig `mapstructure:"metrics_dimensions"`
+
+	// メトリクステーブルのスキーマ（full または lite、既定: full）
+	// lite はエグゼンプラー・フラグ・スコープのメタデータ列を持たない簡易なテーブルを作成し、保存容量を抑えてクエリを単純にします
+	// データポイントの挿入はテーブルと同じ列構成で行うため、既存のテーブルのスキーマと一致させてください（テーブル作成時のみ反映され、既存のテーブルは変更しません）
+	MetricsSchema string `mapstructure:"metrics_schema"`
+
+	// 保存するリソース属性の許可/拒否リスト（全シグナル共通）
+	ResourceAttributes ResourceAttributesConfig `mapstructure:"resource_attributes"`
+
+	// シグナルごとの挿入SQLの上書き（上級者向け）
+	InsertSQL InsertSQLConfig `mapstructure:"insert_sql"`
+
+	// ログ本文の埋め込みベクトルの設定（意味検索の実験向け）
+	LogEmbeddings LogEmbeddingsConfig `mapstructure:"log_embeddings"`
+
+	// 既存のテーブル（ユーザー管理のスキーマ）へ挿入するための列の対応付け
+	ColumnMapping ColumnMappingConfig `mapstructure:"column_mapping"`
+
+	// オートスケーリング向けの使用率メトリクスの設定
+	Utilization UtilizationConfig `mapstructure:"utilization"`
+
+	// 大きなフラッシュの後に最新のパーティションを読み込むキャッシュの事前読み込み設定（デモ・POC向け）
+	CacheWarming CacheWarmingConfig `mapstructure:"cache_warming"`
+
+	// 実行中にClickHouseへ到達できなくなった場合の縮退動作の設定
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// 各行に送信元（コレクター、パイプライン、レシーバー）を記録する設定
+	SourceColumns SourceColumnsConfig `mapstructure:"source_columns"`
+
+	// サービスごとのエラー率・レイテンシの基準値からの逸脱を検出する設定（トレースのみ）
+	AnomalyDetection AnomalyDetectionConfig `mapstructure:"anomaly_detection"`
+
+	// スパンの親子関係からサービス間の呼び出し関係（サービスグラフ）のテーブルを作成する設定（トレースのみ）
+	ServiceGraph ServiceGraphConfig `mapstructure:"service_graph"`
+
+	// ClickHouseに保存しないシグナルのOTLP転送設定
+	Passthrough PassthroughConfig `mapstructure:"passthrough"`
+
+	// 受信したテレメトリをKafkaへ発行する設定（下流でのバッファリング用）
+	Kafka KafkaConfig `mapstructure:"kafka"`
+
+	// テーブル定義の再読み込み（DBAによる直接のテーブル変更への追従）の設定
+	SchemaRefresh SchemaRefreshConfig `mapstructure:"schema_refresh"`
+
+	// 実行状態（DB接続、最後の挿入、処理中のデータ量、直近のエラー）をJSONで返すデバッグ用エンドポイントの設定
+	Debug DebugConfig `mapstructure:"debug"`
+
+	// エクスポーター自身のイベント（スキーマ作成、接続断・復旧、データ破棄）をログレコードとして送信する設定
+	LifecycleEvents LifecycleEventsConfig `mapstructure:"lifecycle_events"`
+
+	// DB障害時のディスク退避（スプール）設定
+	Spool SpoolConfig `mapstructure:"spool"`
+
+	// 挿入バッチのキャプチャ設定（デバッグ用）
+	Capture CaptureConfig `mapstructure:"capture"`
+
+	// スキーマURLに基づく属性変換設定
+	SchemaTranslation SchemaTranslationConfig `mapstructure:"schema_translation"`
+
+	// 障害注入設定（exporter.mylogexporter.chaosInjection フィーチャーゲート有効時のみ）
+	Chaos ChaosConfig `mapstructure:"chaos"`
+}
+
+var _ component.Config = (*Config)(nil)
+
+// テーブルごとの既定のORDER BY式
+const (
+	defaultTracesOrderBy   = "(ServiceName, SpanName, toDateTime(Timestamp))"
+	defaultLogsOrderBy     = "(ServiceName, SeverityNumber, Timestamp, TraceId)"
+	defaultMetricsOrderBy  = "(ServiceName, MetricName, Attributes, toUnixTimestamp64Nano(TimeUnix))"
+	defaultProfilesOrderBy = "(ServiceName, PeriodType, Timestamp)"
+)
+
+// 属性カラムの形式
+const (
+	attributesFormatMap  = "map"  // Map(LowCardinality(String), String) 型で保存
+	attributesFormatJSON = "json" // JSON 型で保存
+)
+
+// メトリクステーブルのスキーマ（metrics_schema）
+const (
+	metricsSchemaFull = "full" // エグゼンプラー・フラグ・スコープを含むすべての列
+	metricsSchemaLite = "lite" // エグゼンプラー・フラグ・スコープのメタデータ列を省略
+)
+
+// ログテーブルの基準とする時刻（logs_primary_time）
+const (
+	logsPrimaryTimestamp         = "timestamp"          // ログイベントの発生時刻（Timestamp）
+	logsPrimaryObservedTimestamp = "observed_timestamp" // ログの収集時刻（ObservedTimestamp）
+)
+
+// Validate は設定値の妥当性を検証します
+func (cfg *Config) Validate() error {
+	var errs error
+
+	switch cfg.Driver {
+	case "", driverClickHouse:
+	case driverPostgres:
+		if err := cfg.validatePostgres(); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	default:
+		errs = errors.Join(errs, fmt.Errorf("driver は clickhouse または postgres を指定してください: %s", cfg.Driver))
+	}
+
+	if err := cfg.DetailedSampling.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if cfg.LogFormat != "" && !slices.Contains(validLogFormats, cfg.LogFormat) {
+		errs = errors.Join(errs, fmt.Errorf("log_format は text, json, otlp_json のいずれかを指定してください: %s", cfg.LogFormat))
+	}
+
+	if cfg.multiEndpoint() {
+		if cfg.isPostgres() {
+			errs = errors.Join(errs, fmt.Errorf("driver: postgres では endpoint に複数のエンドポイントを指定できません"))
+		}
+		if err := cfg.LoadBalancing.validate(); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	if err := cfg.validateCompression(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+
+	// エンドポイントが指定されている場合のみDSNを検証（未指定はログ出力のみモード）
+	if cfg.Endpoint != "" {
+		if _, err := buildDSN(cfg, cfg.Database); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+
+	if err := cfg.validateTargets(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validatePasswordFile(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+
+	if cfg.UseNativeBatch && cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では use_native_batch を使用できません"))
+	}
+
+	if cfg.CreateSchemaDryRunFile != "" && !cfg.CreateSchemaDryRun {
+		errs = errors.Join(errs, fmt.Errorf("create_schema_dry_run_file は create_schema_dry_run が有効な場合のみ指定できます"))
+	}
+
+	if err := cfg.validateTTL(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+
+	for _, o := range []struct{ name, expr string }{
+		{"traces_order_by", cfg.TracesOrderBy},
+		{"logs_order_by", cfg.LogsOrderBy},
+		{"metrics_order_by", cfg.MetricsOrderBy},
+		{"profiles_order_by", cfg.ProfilesOrderBy},
+	} {
+		if err := validateOrderBy(o.expr); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", o.name, err))
+		}
+	}
+
+	switch cfg.AttributesFormat {
+	case "", attributesFormatMap, attributesFormatJSON:
+	default:
+		errs = errors.Join(errs, fmt.Errorf("attributes_format は map または json を指定してください: %s", cfg.AttributesFormat))
+	}
+
+	switch cfg.MetricsSchema {
+	case "", metricsSchemaFull, metricsSchemaLite:
+	default:
+		errs = errors.Join(errs, fmt.Errorf("metrics_schema は full または lite を指定してください: %s", cfg.MetricsSchema))
+	}
+
+	// 短縮後のキーにはハッシュ接尾辞が付くため、接尾辞より長い必要がある
+	if cfg.MaxAttributeKeyLength < 0 || (cfg.MaxAttributeKeyLength > 0 && cfg.MaxAttributeKeyLength < 2*pdatarows.AttributeKeyHashLength) {
+		errs = errors.Join(errs, fmt.Errorf("max_attribute_key_length は0（無制限）または%d以上である必要があります: %d", 2*pdatarows.AttributeKeyHashLength, cfg.MaxAttributeKeyLength))
+	}
+
+	switch cfg.LogsPrimaryTime {
+	case "", logsPrimaryTimestamp, logsPrimaryObservedTimestamp:
+	default:
+		errs = errors.Join(errs, fmt.Errorf("logs_primary_time は %s または %s を指定してください: %s", logsPrimaryTimestamp, logsPrimaryObservedTimestamp, cfg.LogsPrimaryTime))
+	}
+	if err := cfg.validateValueLengths(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validateUnsupportedMetrics(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Metrics.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validateMaxInsertRows(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validateHistogramBuckets(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validateStartTimeout(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validateInsertTimeout(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validateInsertSettings(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if cfg.InsertDeduplicationToken && cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では insert_deduplication_token を使用できません"))
+	}
+	if cfg.SummaryInterval < 0 {
+		errs = errors.Join(errs, fmt.Errorf("summary_interval は0以上である必要があります: %s", cfg.SummaryInterval))
+	}
+	if err := cfg.LiveReload.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validateFairness(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+
+	if err := cfg.ColumnTTL.validate(cfg); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Replication.validate(cfg); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Migrations.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Traces.SpanNameNormalization.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if cfg.CreateAggregationViews {
+		switch {
+		case cfg.isPostgres():
+			errs = errors.Join(errs, errors.New("driver: postgres では create_aggregation_views を使用できません"))
+		case cfg.MultiTenancy.rowPoliciesEnabled():
+			// 集計テーブルにはリソース属性がなく、テナントごとの行ポリシーを作成できない
+			errs = errors.Join(errs, errors.New("create_aggregation_views と multi_tenancy.create_row_policies は同時に指定できません"))
+		}
+	}
+	if cfg.RecordIngestTime && cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では record_ingest_time を使用できません"))
+	}
+	if cfg.Traces.StoreScope && cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では traces.store_scope を使用できません"))
+	}
+	if cfg.Traces.LinksTable {
+		switch {
+		case !cfg.Traces.StoreLinks:
+			errs = errors.Join(errs, errors.New("traces.links_table を有効にする場合は traces.store_links も有効にしてください"))
+		case cfg.isPostgres():
+			errs = errors.Join(errs, errors.New("driver: postgres では traces.links_table を使用できません"))
+		case cfg.MultiTenancy.rowPoliciesEnabled():
+			// リンクテーブルにはリソース属性がなく、テナントごとの行ポリシーを作成できない
+			errs = errors.Join(errs, errors.New("traces.links_table と multi_tenancy.create_row_policies は同時に指定できません"))
+		}
+	}
+	if cfg.Traces.RootsTable {
+		switch {
+		case cfg.isPostgres():
+			errs = errors.Join(errs, errors.New("driver: postgres では traces.roots_table を使用できません"))
+		case cfg.MultiTenancy.rowPoliciesEnabled():
+			// ルートスパンテーブルにはリソース属性がなく、テナントごとの行ポリシーを作成できない
+			errs = errors.Join(errs, errors.New("traces.roots_table と multi_tenancy.create_row_policies は同時に指定できません"))
+		}
+	}
+	if err := cfg.TraceIDLookup.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.MultiTenancy.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if _, err := parseSeverity(cfg.MinSeverity); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Filter.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.ResourceAttributes.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.CardinalityLimit.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.MetricsDimensions.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	// 正規化スキーマではデータポイントの行にリソース属性がないため、テナント属性で行を絞り込めない
+	if cfg.MetricsDimensions.Enabled && cfg.MultiTenancy.rowPoliciesEnabled() {
+		errs = errors.Join(errs, errors.New("metrics_dimensions.enabled と multi_tenancy.create_row_policies は同時に指定できません"))
+	}
+	// 行ポリシーはテナント属性を参照するため、保存対象から除外するとポリシーが機能しない
+	if cfg.MultiTenancy.Enabled && cfg.MultiTenancy.TenantAttribute != "" && !cfg.ResourceAttributes.keep(cfg.MultiTenancy.TenantAttribute) {
+		errs = errors.Join(errs, fmt.Errorf("multi_tenancy.tenant_attribute %q が resource_attributes により保存対象から除外されています", cfg.MultiTenancy.TenantAttribute))
+	}
+	if err := cfg.InsertSQL.validate(cfg.insertColumns("traces"), cfg.insertColumns("logs")); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.ColumnMapping.validate(cfg); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Utilization.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.AnomalyDetection.validate(cfg); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.ServiceGraph.validate(cfg); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if cfg.Logs.ParseBodyJSON && cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では logs.parse_body_json を使用できません"))
+	}
+	if err := cfg.LogEmbeddings.validate(cfg); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.CacheWarming.validate(cfg); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.CircuitBreaker.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.ConnectionPool.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validateClickHouseCloud(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Passthrough.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Kafka.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.SchemaRefresh.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Debug.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.LifecycleEvents.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Spool.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.validateQueueStorage(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.Capture.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if err := cfg.SchemaTranslation.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if cfg.SoftDelete.MinInterval < 0 {
+		errs = errors.Join(errs, fmt.Errorf("soft_delete.min_interval は0以上である必要があります: %s", cfg.SoftDelete.MinInterval))
+	}
+	if cfg.Chaos.Latency < 0 || cfg.Chaos.LatencyJitter < 0 {
+		errs = errors.Join(errs, fmt.Errorf("chaos.latency と chaos.latency_jitter は0以上である必要があります"))
+	}
+	if cfg.Chaos.ConnectionDropRate < 0 || cfg.Chaos.ConnectionDropRate > 1 {
+		errs = errors.Join(errs, fmt.Errorf("chaos.connection_drop_rate は0.0〜1.0の範囲である必要があります: %v", cfg.Chaos.ConnectionDropRate))
+	}
+
+	return errs
 }
 
 func createDefaultConfig() component.Config {
 	return &Config{
-		TimeoutSettings:  exporterhelper.NewDefaultTimeoutConfig(),
-		QueueSettings:    exporterhelper.NewDefaultQueueConfig(),
-		BackOffConfig:    configretry.NewDefaultBackOffConfig(),
-		Prefix:           "[MyLogExporter]",
-		Detailed:         false,
-		Database:         "otel",        // 独自のデータベース名
-		TableName:        "otel_logs",   // ClickHouseらしいテーブル名
-		TracesTableName:  "otel_traces", // トレーステーブル名
-		LogsTableName:    "otel_logs",   // ログテーブル名
-		ConnectionParams: map[string]string{},
-		CreateSchema:     true,        // デフォルトでスキーマ作成を有効
-		Compress:         "lz4",       // clickhouseexporterと同様のデフォルト圧縮
-		AsyncInsert:      true,        // 非同期挿入をデフォルトで有効
-		TTL:              0,           // デフォルトではTTL無効（0 = 無制限）
-		TableEngine:      "MergeTree", // ClickHouseの標準的なエンジン
-	}
-}
-
-// shouldCreateSchema - スキーマ作成が必要かどうかを判定します
+		TimeoutSettings:              exporterhelper.NewDefaultTimeoutConfig(),
+		QueueSettings:                exporterhelper.NewDefaultQueueConfig(),
+		BackOffConfig:                configretry.NewDefaultBackOffConfig(),
+		Prefix:                       "[MyLogExporter]",
+		Detailed:                     false,
+		LogFormat:                    logFormatText,
+		Driver:                       driverClickHouse,
+		Database:                     "otel",          // 独自のデータベース名
+		TableName:                    "otel_logs",     // ClickHouseらしいテーブル名
+		TracesTableName:              "otel_traces",   // トレーステーブル名
+		LogsTableName:                "otel_logs",     // ログテーブル名
+		ProfilesTableName:            "otel_profiles", // プロファイルテーブル名
+		ConnectionParams:             map[string]string{},
+		CreateSchema:                 true,             // デフォルトでスキーマ作成を有効
+		VerifySchema:                 true,             // スキーマを作成しない場合は開始時に列を確認
+		Compress:                     "lz4",            // clickhouseexporterと同様のデフォルト圧縮
+		AsyncInsert:                  true,             // 非同期挿入をデフォルトで有効
+		StartTimeout:                 30 * time.Second, // 開始処理が応答しないノードで止まり続けないようにする
+		TTL:                          0,                // デフォルトではTTL無効（0 = 無制限）
+		TableEngine:                  "MergeTree",      // ClickHouseの標準的なエンジン
+		AttributesFormat:             attributesFormatMap,
+		LogsPrimaryTime:              logsPrimaryTimestamp,
+		AttributesLowCardinalityKeys: true,
+		ConnectionPool: ConnectionPoolConfig{
+			MaxIdleTime: 4 * time.Minute, // 一般的なNAT・ロードバランサーのアイドルタイムアウトより短くする
+		},
+		LoadBalancing: LoadBalancingConfig{
+			HealthCheckInterval: 10 * time.Second,
+			HealthCheckTimeout:  5 * time.Second,
+		},
+		Utilization: UtilizationConfig{
+			BufferBudget:  64 << 20,        // 処理中のデータ量は64MiBを目安とする
+			TargetLatency: 1 * time.Second, // 送信処理は1秒以内を目標とする
+		},
+		AnomalyDetection: AnomalyDetectionConfig{
+			Alpha:       0.1,
+			Threshold:   4,
+			MinSamples:  20,
+			MinSpans:    20,
+			MaxServices: 1000,
+			Column:      true,
+		},
+		ServiceGraph: ServiceGraphConfig{
+			TableName: "otel_service_graph",
+			LatencyBuckets: []time.Duration{
+				2 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond,
+				50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+				time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+			},
+			// 呼び出し先のサービス名、データベース・メッセージングシステム、接続先ホストの順に参照する
+			PeerAttributes: []string{"peer.service", "db.name", "db.system", "messaging.system", "server.address", "net.peer.name"},
+		},
+		LogEmbeddings: LogEmbeddingsConfig{
+			BatchSize:   64,
+			Timeout:     10 * time.Second,
+			VectorIndex: true,
+			Distance:    "cosineDistance",
+		},
+		CacheWarming: CacheWarmingConfig{
+			Mode:     cacheWarmingLimitZero,
+			MinRows:  10000,       // 1万行以上のフラッシュの後に実行
+			Interval: time.Minute, // 1分に1回まで
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			FailureThreshold: 5,                // 5回連続で失敗したらログ出力のみモードへ
+			Cooldown:         30 * time.Second, // 30秒ごとに復旧を確認
+		},
+		Spool: SpoolConfig{
+			MaxSize:        1 << 30,          // シグナルごとに最大1GiBを退避
+			MaxAge:         24 * time.Hour,   // 24時間を過ぎたセグメントは破棄
+			ReplayInterval: 30 * time.Second, // 30秒ごとに再挿入を試行
+		},
+		Kafka: KafkaConfig{
+			Encoding:      kafkaEncodingProto,
+			Mode:          kafkaModeCopy, // データベースへの保存と並行して発行
+			TracesTopic:   "otlp_spans",  // kafkaexporter と同じ既定のトピック名
+			LogsTopic:     "otlp_logs",
+			MetricsTopic:  "otlp_metrics",
+			ProfilesTopic: "otlp_profiles",
+		},
+		LifecycleEvents: LifecycleEventsConfig{
+			DropSummaryInterval: time.Minute, // 破棄件数は1分ごとにまとめて送信
+		},
+		Capture: CaptureConfig{
+			MaxBatches: 10, // テーブルごとに最初の10バッチをキャプチャ
+		},
+		TraceIDLookup: TraceIDLookupConfig{
+			LookupTableEnabled: true,
+		},
+		MultiTenancy: MultiTenancyConfig{
+			Routing: TenantRoutingConfig{
+				DatabasePattern:  "{database}",
+				TablePattern:     "{table}_{tenant}",
+				MaxCachedTenants: 1000,
+			},
+		},
+		Fairness: FairnessConfig{
+			MinBatchSize: 1000, // 小さいバッチは挿入を後回しにしても効果が小さい
+		},
+		MetricsDimensions: MetricsDimensionsConfig{
+			TableName: "otel_metrics_dimensions",
+		},
+		MetricsSchema:           metricsSchemaFull,
+		UnsupportedMetrics:      unsupportedMetricsKeep,
+		HistogramBucketOverflow: histogramBucketsTruncate,
+		StoreScopeAttributes:    true,
+		Traces: TracesConfig{
+			StoreEvents: true,
+			StoreLinks:  true,
+		},
+		Metrics: MetricsConfig{
+			StalenessHandling: stalenessDrop,
+		},
+		Migrations: MigrationsConfig{
+			Enabled: true, // 起動時に未適用のマイグレーションを自動適用
+			Table:   "schema_version",
+		},
+		Postgres: PostgresConfig{
+			Hypertables:   true,           // TimescaleDBのハイパーテーブルを作成
+			ChunkInterval: 24 * time.Hour, // 1日単位のチャンク
+		},
+		Replication: ReplicationConfig{
+			ZooKeeperPath: "/clickhouse/tables/{shard}/{database}/{table}", // ClickHouseの推奨パス
+			ReplicaName:   "{replica}",
+		},
+		SoftDelete: SoftDeleteConfig{
+			MinInterval: time.Second, // DELETE文は最短1秒間隔で実行
+		},
+		PasswordFileReloadInterval: 30 * time.Second, // 30秒ごとにパスワードファイルの変更を確認
+		LiveReload: LiveReloadConfig{
+			Interval: 10 * time.Second,
+		},
+	}
+}
+
+// shouldCreateSchema - スキーマ作成が必要かどうかを判定します（ドライランの場合はDDLを実行しない）
 func (cfg *Config) shouldCreateSchema() bool {
-	return cfg.CreateSchema
+	return cfg.CreateSchema && !cfg.CreateSchemaDryRun
+}
+
+// shouldVerifySchema - 開始時に既存のテーブルの列を確認するかを判定します
+// スキーマを作成しない場合のみ対象とし、ドライラン中はDDLが未適用の可能性があるため確認しません
+func (cfg *Config) shouldVerifySchema() bool {
+	return cfg.VerifySchema && !cfg.CreateSchema && !cfg.CreateSchemaDryRun
 }
 
 // database - データベース名を返します（空の場合はdefaultを返す）
@@ -77,9 +761,49 @@ func (cfg *Config) database() string {
 	return cfg.Database
 }
 
-// clusterString - クラスター指定文字列を生成します
+// tracesDatabase - トレース用のデータベース名を返します（未指定の場合は共通のデータベース）
+func (cfg *Config) tracesDatabase() string {
+	return cfg.signalDatabase(cfg.TracesDatabase)
+}
+
+// logsDatabase - ログ用のデータベース名を返します（未指定の場合は共通のデータベース）
+func (cfg *Config) logsDatabase() string {
+	return cfg.signalDatabase(cfg.LogsDatabase)
+}
+
+// metricsDatabase - メトリクス用のデータベース名を返します（未指定の場合は共通のデータベース）
+func (cfg *Config) metricsDatabase() string {
+	return cfg.signalDatabase(cfg.MetricsDatabase)
+}
+
+// profilesDatabase - プロファイル用のデータベース名を返します（未指定の場合は共通のデータベース）
+func (cfg *Config) profilesDatabase() string {
+	return cfg.signalDatabase(cfg.ProfilesDatabase)
+}
+
+// signalDatabase - シグナル固有のデータベース名、なければ共通のデータベース名を返します
+func (cfg *Config) signalDatabase(database string) string {
+	if database != "" {
+		return database
+	}
+	return cfg.database()
+}
+
+// databases - 全シグナルで使用するデータベース名を重複なく返します
+func (cfg *Config) databases() []string {
+	var databases []string
+	for _, database := range []string{cfg.tracesDatabase(), cfg.logsDatabase(), cfg.metricsDatabase(), cfg.profilesDatabase()} {
+		if !slices.Contains(databases, database) {
+			databases = append(databases, database)
+		}
+	}
+	return databases
+}
+
+// clusterString - テーブル等のDDLに付与するクラスター指定文字列を生成します
+// Replicated データベースではDDLがデータベースによって複製されるため付与しません
 func (cfg *Config) clusterString() string {
-	if cfg.ClusterName == "" {
+	if cfg.ClusterName == "" || cfg.replicatedDatabase() {
 		return ""
 	}
 	return fmt.Sprintf("ON CLUSTER '%s'", cfg.ClusterName)
@@ -92,3 +816,244 @@ func (cfg *Config) tableEngineString() string {
 	}
 	return cfg.TableEngine
 }
+
+// orderBy - 上書き指定があればその式を、なければ既定のORDER BY式を返します
+func orderBy(override, defaultOrderBy string) string {
+	if override = strings.TrimSpace(override); override != "" {
+		return override
+	}
+	return defaultOrderBy
+}
+
+// logsTimeColumn - ログテーブルのパーティション・TTLの基準とする時刻列を返します（logs_primary_time）
+func (cfg *Config) logsTimeColumn() string {
+	if cfg.LogsPrimaryTime == logsPrimaryObservedTimestamp {
+		return "ObservedTimestamp"
+	}
+	return "Timestamp"
+}
+
+// logsOrderBy - ログテーブルのORDER BY式を返します（既定の並び順の時刻列は logs_primary_time に従う）
+func (cfg *Config) logsOrderBy() string {
+	return orderBy(cfg.LogsOrderBy, strings.Replace(defaultLogsOrderBy, "Timestamp", cfg.logsTimeColumn(), 1))
+}
+
+// validateOrderBy - ORDER BY式の括弧の対応と、別の句やコメントが含まれていないことを検証します
+// 式はCREATE TABLE文にそのまま埋め込まれるため、文の構造を壊す指定を拒否します
+func validateOrderBy(expr string) error {
+	depth := 0
+	for _, r := range expr {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ';':
+			return fmt.Errorf("ORDER BY式に ; は使用できません: %s", expr)
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("ORDER BY式の括弧が対応していません: %s", expr)
+	}
+	if strings.Contains(expr, "--") {
+		return fmt.Errorf("ORDER BY式にコメントは使用できません: %s", expr)
+	}
+	return nil
+}
+
+// liteMetricsSchema - メトリクステーブルを lite スキーマで作成するかどうかを判定します
+func (cfg *Config) liteMetricsSchema() bool {
+	return cfg.MetricsSchema == metricsSchemaLite
+}
+
+// jsonAttributes - 属性をJSON型カラムに保存するかどうかを判定します
+func (cfg *Config) jsonAttributes() bool {
+	return cfg.AttributesFormat == attributesFormatJSON
+}
+
+// attributesColumnType - 属性カラムの型定義を返します
+func (cfg *Config) attributesColumnType() string {
+	if cfg.jsonAttributes() {
+		// JSON型にはコーデックを指定しない
+		return "JSON"
+	}
+	return cfg.mapColumnType() + " CODEC(ZSTD(1))"
+}
+
+// mapColumnType - Map 型の属性カラムの型を返します（attributes_low_cardinality_keys が無効の場合はキーを String で保存）
+func (cfg *Config) mapColumnType() string {
+	if cfg.AttributesLowCardinalityKeys {
+		return "Map(LowCardinality(String), String)"
+	}
+	return "Map(String, String)"
+}
+
+// mutatesData - 指定シグナルのエクスポーターが受信データを書き換えるかどうかを判定します
+// trueの場合、ファンアウト構成ではパイプラインがデータを複製してから渡すため兄弟エクスポーターとの競合を防げる
+// 受信データを変更する処理（変換・フィルタ等）を追加した場合はここに条件を追加すること
+func (cfg *Config) mutatesData(signal string) bool {
+	switch signal {
+	case "traces":
+		// スキーマ変換は属性と schema_url を直接書き換え、fairness は後回しにするスパンを取り除く
+		// テナントの振り分けは挿入に失敗したテナント以外のスパンを取り除く
+		// filter はスパンを取り除く（filter_preview では件数を数えるのみ）
+		// max_insert_rows による分割挿入は、挿入に成功した分割のスパンを取り除く
+		return cfg.SchemaTranslation.Enabled || cfg.Fairness.MaxServiceShare > 0 || cfg.MultiTenancy.Routing.Enabled || cfg.Filter.removesItems(signal, cfg.FilterPreview) ||
+			cfg.MaxInsertRows > 0
+	case "logs":
+		// 重要度フィルタはログレコードを削除する（filter_preview では件数を数えるのみ）
+		return cfg.SchemaTranslation.Enabled || cfg.Fairness.MaxServiceShare > 0 || cfg.MultiTenancy.Routing.Enabled || (cfg.MinSeverity != "" && !cfg.FilterPreview) ||
+			cfg.Filter.removesItems(signal, cfg.FilterPreview) || cfg.MaxInsertRows > 0
+	case "metrics":
+		// カーディナリティ制限はデータポイントを削除・集約する（filter_preview ではコピーに適用する）
+		// unsupported_metrics の skip・warn は型が不明・データポイントのないメトリクスを取り除き、
+		// max_histogram_buckets はバケットをまとめ、metrics.staleness_handling の drop はステールマーカーを取り除く
+		return (cfg.CardinalityLimit.MaxStreams > 0 && !cfg.FilterPreview) || cfg.removesUnsupportedMetrics() || cfg.truncatesHistogramBuckets() ||
+			cfg.Filter.removesItems(signal, cfg.FilterPreview) || cfg.Metrics.StalenessHandling == stalenessDrop
+	default:
+		return false
+	}
+}
+
+// minSeverityNumber - ログの最小重要度を返します（未指定の場合は SeverityNumberUnspecified）
+func (cfg *Config) minSeverityNumber() plog.SeverityNumber {
+	severity, _ := parseSeverity(cfg.MinSeverity)
+	return severity
+}
+
+// parseSeverity - 重要度名をOpenTelemetryの重要度番号（各レベルの最小値）に変換します
+func parseSeverity(name string) (plog.SeverityNumber, error) {
+	switch strings.ToUpper(name) {
+	case "":
+		return plog.SeverityNumberUnspecified, nil
+	case "TRACE":
+		return plog.SeverityNumberTrace, nil
+	case "DEBUG":
+		return plog.SeverityNumberDebug, nil
+	case "INFO":
+		return plog.SeverityNumberInfo, nil
+	case "WARN":
+		return plog.SeverityNumberWarn, nil
+	case "ERROR":
+		return plog.SeverityNumberError, nil
+	case "FATAL":
+		return plog.SeverityNumberFatal, nil
+	default:
+		return plog.SeverityNumberUnspecified, fmt.Errorf("min_severity は TRACE, DEBUG, INFO, WARN, ERROR, FATAL のいずれかを指定してください: %s", name)
+	}
+}
+
+// tableSettings - テーブル作成時に追加するSETTINGS項目を生成します
+func (cfg *Config) tableSettings() string {
+	if cfg.SoftDelete.Enabled {
+		// プロジェクションを持つテーブルでも軽量DELETEを許可する
+		return ", lightweight_mutation_projection_mode = 'rebuild'"
+	}
+	return ""
+}
+
+// TracesConfig - トレーステーブルに保存するスパンの内容の設定
+// 無効にした場合も列は作成され、空の配列を保存します（行を小さくしたい場合に使用）
+type TracesConfig struct {
+	StoreEvents bool `mapstructure:"store_events"` // スパンイベントを Events.* 列に保存する
+	StoreLinks  bool `mapstructure:"store_links"`  // スパンリンクを Links.* 列に保存する
+
+	// StoreScope はリソース・スコープのスキーマURLとスコープ属性を ResourceSchemaUrl・ScopeSchemaUrl・ScopeAttributes 列に保存します
+	// ログ・メトリクス・プロファイルのテーブルと同じ列で、既存のトレーステーブルには列が追加されないため ALTER TABLE で列を追加してください
+	StoreScope bool `mapstructure:"store_scope"`
+
+	// LinksTable はスパンリンクを1件1行に展開したテーブル（<traces_table_name>_links）をマテリアライズドビューで作成します
+	// リンク先のトレースIDからリンク元のスパンを検索するなど、トレースをまたぐ因果関係の検索に使用します
+	LinksTable bool `mapstructure:"links_table"`
+
+	// RootsTable は親を持たないスパン（ルートスパン）のみのテーブル（<traces_table_name>_roots）をマテリアライズドビューで作成します
+	// 所要時間・ステータスを含み、全スパンを走査せずに「最近のトレースの一覧」を検索するために使用します
+	RootsTable bool `mapstructure:"roots_table"`
+
+	// スパン名の正規化（保存する SpanName のみが対象で、転送・Kafkaへの発行には適用しない）
+	SpanNameNormalization SpanNameNormalizationConfig `mapstructure:"span_name_normalization"`
+}
+
+// LogsConfig - ログテーブルに保存するログレコードの内容の設定
+type LogsConfig struct {
+	// ParseBodyJSON はJSONオブジェクトの本文を BodyJSON 列（JSON 型）にも保存します（Body 列には元の文字列を保存）
+	// JSONオブジェクトでない本文は空のオブジェクトを保存します
+	// JSON 型をサポートする ClickHouse 25.3 以降が必要で、既存のテーブルには列が追加されないため ALTER TABLE で列を追加してください
+	ParseBodyJSON bool `mapstructure:"parse_body_json"`
+}
+
+// MetricsConfig - メトリクスのデータポイントの扱いの設定
+type MetricsConfig struct {
+	// StalenessHandling はステールマーカー（FLAG_NO_RECORDED_VALUE、Prometheus の系列の終わり）のデータポイントの扱いです
+	// drop（既定）は取り除き、テーブルへの挿入や以降の処理（Kafka への発行、OTLP での転送、詳細モードの出力）に渡しません
+	// store は取り除かずに保存し、Stale 列で区別できるようにします（既存のテーブルには列が追加されないため、ALTER TABLE で追加してください）
+	StalenessHandling string `mapstructure:"staleness_handling"`
+}
+
+// TraceIDLookupConfig - トレースID-タイムスタンプ検索テーブルの設定
+// 検索テーブルは1トレースにつき1行程度と小さいため、スパンテーブルより長く保持できる
+type TraceIDLookupConfig struct {
+	// LookupTableEnabled は検索テーブルとマテリアライズドビューを作成します
+	// 無効にするとスパンの挿入ごとのビューへの書き込み（書き込み増幅）がなくなりますが、FindTraceTimeRange は使用できません
+	// 既に作成済みのビューは削除しないため、不要な場合は手動で DROP VIEW してください
+	LookupTableEnabled bool `mapstructure:"lookup_table_enabled"`
+
+	TTL        time.Duration `mapstructure:"ttl"`         // 保持期間（0の場合はメインテーブルの ttl を継承）
+	DisableTTL bool          `mapstructure:"disable_ttl"` // trueの場合はTTLを設定せず無期限に保持
+	// Engine は MergeTree / ReplacingMergeTree / AggregatingMergeTree のいずれか（空の場合は table_engine）
+	// ReplacingMergeTree は同一トレースIDの行を最新の1行に集約し、
+	// AggregatingMergeTree は Start/End をそれぞれ min/max で集約する
+	Engine string `mapstructure:"engine"`
+}
+
+// validate - 検索テーブル設定の妥当性を検証します
+func (c TraceIDLookupConfig) validate() error {
+	if c.TTL < 0 {
+		return fmt.Errorf("trace_id_lookup.ttl は0以上である必要があります: %s", c.TTL)
+	}
+	switch c.Engine {
+	case "", "MergeTree", "ReplacingMergeTree", "AggregatingMergeTree":
+		return nil
+	default:
+		return fmt.Errorf("trace_id_lookup.engine は MergeTree, ReplacingMergeTree, AggregatingMergeTree のいずれかを指定してください: %s", c.Engine)
+	}
+}
+
+// ttl - 検索テーブルに適用するTTLを返します（0はTTLなし）
+func (c TraceIDLookupConfig) ttl(tableTTL time.Duration) time.Duration {
+	switch {
+	case c.DisableTTL:
+		return 0
+	case c.TTL > 0:
+		return c.TTL
+	default:
+		return tableTTL
+	}
+}
+
+// engineString - 検索テーブルのエンジン文字列を返します
+func (c TraceIDLookupConfig) engineString(defaultEngine string) string {
+	switch c.Engine {
+	case "":
+		return defaultEngine
+	case "ReplacingMergeTree":
+		// Endをバージョン列として、最も新しい終了時刻を持つ行を残す
+		return "ReplacingMergeTree(End)"
+	default:
+		return c.Engine
+	}
+}
+
+// orderBy - 検索テーブルのORDER BY式を返します
+// 集約系エンジンではトレースIDごとに1行へ集約するため TraceId のみをキーにする
+func (c TraceIDLookupConfig) orderBy() string {
+	switch c.Engine {
+	case "ReplacingMergeTree", "AggregatingMergeTree":
+		return "TraceId"
+	default:
+		return "(TraceId, Start)"
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.yaml.in/yaml/v3"
+)
+
+// LoadConfigFile はコレクター形式のYAMLファイルを読み込み、デフォルト設定に上書きした設定を返します（cmd/validate・cmd/schematool で使用）
+// exporters セクションを持つコレクター設定の場合は exporterID（省略時は唯一のエクスポーター）を使用します
+func LoadConfigFile(configPath, exporterID string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("YAMLの解析に失敗しました: %w", err)
+	}
+
+	conf := confmap.NewFromStringMap(raw)
+	if conf.IsSet("exporters") {
+		exporters, err := conf.Sub("exporters")
+		if err != nil {
+			return nil, fmt.Errorf("exporters セクションの解析に失敗しました: %w", err)
+		}
+		if exporterID == "" {
+			ids := exporters.ToStringMap()
+			if len(ids) != 1 {
+				return nil, fmt.Errorf("エクスポーターが %d 個定義されています、-exporter で指定してください", len(ids))
+			}
+			for id := range ids {
+				exporterID = id
+			}
+		}
+		if conf, err = exporters.Sub(exporterID); err != nil {
+			return nil, fmt.Errorf("エクスポーター %q の解析に失敗しました: %w", exporterID, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	if err := conf.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("設定の変換に失敗しました: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,146 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+var _ confmap.Unmarshaler = (*Config)(nil)
+
+// Unmarshal はコレクター設定の内容を Config に変換します
+// 未知のキーはエラーとし（confmap の ErrorUnused）、既定値のまま起動してしまうことを防ぎます
+// 入力ミス（endpont, ttl_day など）に気づけるよう、エラーには近い名前の既知のキーを併せて示します
+// endpoint はリストでも指定できるよう、カンマ区切りの文字列に変換してから読み込みます
+func (cfg *Config) Unmarshal(conf *confmap.Conf) error {
+	if endpoints, ok := conf.Get("endpoint").([]any); ok {
+		list := make([]string, 0, len(endpoints))
+		for _, endpoint := range endpoints {
+			list = append(list, fmt.Sprint(endpoint))
+		}
+		if err := conf.Merge(confmap.NewFromStringMap(map[string]any{"endpoint": strings.Join(list, ",")})); err != nil {
+			return err
+		}
+	}
+	err := conf.Unmarshal(cfg)
+	if err == nil {
+		return nil
+	}
+	if hints := unknownKeyHints(conf); len(hints) > 0 {
+		return fmt.Errorf("%w（%s）", err, strings.Join(hints, "、"))
+	}
+	return err
+}
+
+// unknownKeyHints は設定に含まれる未知のキーごとに、近い名前の既知のキーを示す文字列を返します
+func unknownKeyHints(conf *confmap.Conf) []string {
+	known := map[string]bool{}
+	var open []string
+	collectConfigKeys(reflect.TypeOf(Config{}), "", known, &open)
+
+	var hints []string
+	for _, key := range conf.AllKeys() {
+		if known[key] || slices.ContainsFunc(open, func(prefix string) bool { return strings.HasPrefix(key, prefix) }) {
+			continue
+		}
+		// 未知のキーのうち最も上位の部分（親は既知）を対象にする
+		parts := strings.Split(key, confmap.KeyDelimiter)
+		for i := range parts {
+			path := strings.Join(parts[:i+1], confmap.KeyDelimiter)
+			if known[path] {
+				continue
+			}
+			hint := fmt.Sprintf("不明なキー %q", path)
+			if suggestion := closestKey(path, known); suggestion != "" {
+				hint += fmt.Sprintf(" は %q の誤りではありませんか", suggestion)
+			}
+			if !slices.Contains(hints, hint) {
+				hints = append(hints, hint)
+			}
+			break
+		}
+	}
+	return hints
+}
+
+// collectConfigKeys は設定の型から既知のキーのパスを集めます
+// map 型や独自の Unmarshal を持つ型の配下は任意のキーを許可するため open に追加します
+func collectConfigKeys(t reflect.Type, prefix string, known map[string]bool, open *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if opts == "squash" {
+			collectConfigKeys(ft, prefix, known, open)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		path := prefix + name
+		known[path] = true
+
+		switch {
+		case ft.Kind() == reflect.Map || reflect.PointerTo(ft).Implements(reflect.TypeFor[confmap.Unmarshaler]()):
+			*open = append(*open, path+confmap.KeyDelimiter)
+		case ft.Kind() == reflect.Struct && ft != reflect.TypeFor[time.Time]():
+			collectConfigKeys(ft, path+confmap.KeyDelimiter, known, open)
+		}
+	}
+}
+
+// closestKey は同じ階層の既知のキーのうち、編集距離が最も近いもの（2以下）を返します
+func closestKey(path string, known map[string]bool) string {
+	parent, name := "", path
+	if i := strings.LastIndex(path, confmap.KeyDelimiter); i >= 0 {
+		parent, name = path[:i+len(confmap.KeyDelimiter)], path[i+len(confmap.KeyDelimiter):]
+	}
+	best, bestDistance := "", 3
+	for candidate := range known {
+		rest, ok := strings.CutPrefix(candidate, parent)
+		if !ok || strings.Contains(rest, confmap.KeyDelimiter) {
+			continue
+		}
+		if d := editDistance(name, rest); d < bestDistance || (d == bestDistance && rest < best) {
+			best, bestDistance = rest, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return parent + best
+}
+
+// editDistance は2つの文字列のレーベンシュタイン距離を返します
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
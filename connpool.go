@@ -0,0 +1,186 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// database/sql の既定のアイドル接続数（SetMaxIdleConns を呼ばない場合）
+const defaultMaxIdleConns = 2
+
+// ConnectionPoolConfig - DB接続プールの設定
+// NAT・ロードバランサーの背後では、しばらく使われなかった接続が経路上で黙って切断されていることがあり、
+// 静かな期間の後の最初の送信がタイムアウトと再接続を待つことになります
+type ConnectionPoolConfig struct {
+	// MaxIdleTime は使われていない接続を閉じて作り直すまでの時間です（0 の場合は閉じない）
+	// 経路上のアイドルタイムアウト（例: AWS NLB は350秒）より短くします
+	MaxIdleTime time.Duration `mapstructure:"max_idle_time"`
+	// MinConnections は開始時にあらかじめ確立しておく接続数です（0 の場合は最初の送信時に接続）
+	MinConnections int `mapstructure:"min_connections"`
+	// KeepaliveInterval はアイドル接続に ping を送る間隔です（0 の場合は送らない、clickhouse_cloud 有効時の既定: 1分）
+	// 経路上のアイドルタイムアウトより短くすると、接続を閉じずに使い続けられます（max_idle_time より優先される）
+	KeepaliveInterval time.Duration `mapstructure:"keepalive_interval"`
+	// ReconnectInterval は接続を閉じて作り直すまでの最大の存続時間です（0 の場合は作り直さない）
+	// 新しい接続は接続時に名前解決されるため、ClickHouse のフェイルオーバー後も再起動せずに新しいアドレスへ接続できます
+	ReconnectInterval time.Duration `mapstructure:"reconnect_interval"`
+	// DNSRefreshInterval はエンドポイントのホスト名を名前解決し直す間隔です（0 の場合は名前解決しない）
+	// アドレスが変わった場合は reconnect_interval を待たずにアイドル接続を閉じます
+	DNSRefreshInterval time.Duration `mapstructure:"dns_refresh_interval"`
+}
+
+// validate は接続プールの設定を検証します
+func (c ConnectionPoolConfig) validate() error {
+	var errs error
+	if c.MaxIdleTime < 0 {
+		errs = errors.Join(errs, fmt.Errorf("connection_pool.max_idle_time は0以上である必要があります: %s", c.MaxIdleTime))
+	}
+	if c.MinConnections < 0 {
+		errs = errors.Join(errs, fmt.Errorf("connection_pool.min_connections は0以上である必要があります: %d", c.MinConnections))
+	}
+	if c.KeepaliveInterval < 0 {
+		errs = errors.Join(errs, fmt.Errorf("connection_pool.keepalive_interval は0以上である必要があります: %s", c.KeepaliveInterval))
+	}
+	if c.ReconnectInterval < 0 {
+		errs = errors.Join(errs, fmt.Errorf("connection_pool.reconnect_interval は0以上である必要があります: %s", c.ReconnectInterval))
+	}
+	if c.DNSRefreshInterval < 0 {
+		errs = errors.Join(errs, fmt.Errorf("connection_pool.dns_refresh_interval は0以上である必要があります: %s", c.DNSRefreshInterval))
+	}
+	return errs
+}
+
+// configure は接続プールにアイドル接続・古い接続の再作成と保持数を設定します
+// 事前に確立した接続がすぐに閉じられないよう、アイドル接続の上限を min_connections 以上にします
+func (c ConnectionPoolConfig) configure(db *sql.DB) {
+	db.SetConnMaxIdleTime(c.MaxIdleTime)
+	db.SetConnMaxLifetime(c.ReconnectInterval)
+	db.SetMaxIdleConns(max(defaultMaxIdleConns, c.MinConnections))
+}
+
+// prewarm は min_connections 個の接続を同時に確立して疎通を確認し、アイドル接続としてプールに戻します
+// 共有プールの既存のアイドル接続は再利用されるため、複数のエクスポーターから呼び出しても接続数は増えません
+func (c ConnectionPoolConfig) prewarm(ctx context.Context, db *sql.DB) error {
+	if c.MinConnections <= 0 {
+		return nil
+	}
+
+	conns := make([]*sql.Conn, c.MinConnections)
+	errs := make([]error, c.MinConnections)
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := db.Conn(ctx)
+			if err == nil {
+				err = conn.PingContext(ctx)
+			}
+			conns[i], errs[i] = conn, err
+		}()
+	}
+	wg.Wait()
+
+	// すべての接続を確立してから返却する（1つずつ返すと同じ接続が再利用されてしまう）
+	for _, conn := range conns {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("接続の事前確立に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// keepaliveConnector - 接続プールのアイドル接続に定期的に ping を送る driver.Connector ラッパー
+// sql.DB.Close から Close が呼び出された時点でキープアライブを停止します
+type keepaliveConnector struct {
+	driver.Connector
+	interval time.Duration
+	logger   *zap.Logger
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newKeepaliveConnector は connector を包む keepaliveConnector を作成します（start を呼ぶまでは ping を送らない）
+func newKeepaliveConnector(connector driver.Connector, interval time.Duration, logger *zap.Logger) *keepaliveConnector {
+	return &keepaliveConnector{Connector: connector, interval: interval, logger: logger, done: make(chan struct{})}
+}
+
+// start は db のアイドル接続へのキープアライブを開始します
+func (c *keepaliveConnector) start(db *sql.DB) {
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	go c.run(ctx, db)
+}
+
+// run は interval ごとにアイドル接続へ ping を送ります
+func (c *keepaliveConnector) run(ctx context.Context, db *sql.DB) {
+	defer close(c.done)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := pingIdle(ctx, db); err != nil && ctx.Err() == nil {
+			c.logger.Debug("アイドル接続のキープアライブに失敗しました、次の送信時に再接続します", zap.Error(err))
+		}
+	}
+}
+
+// Close はキープアライブを停止し、内側の Connector を閉じます（sql.DB.Close から呼び出されます）
+func (c *keepaliveConnector) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		if c.cancel != nil {
+			c.cancel()
+			<-c.done
+		}
+		if closer, ok := c.Connector.(io.Closer); ok {
+			err = closer.Close()
+		}
+	})
+	return err
+}
+
+// pingIdle は呼び出し時点のアイドル接続を1つずつ取り出して ping を送り、まとめてプールに戻します
+// 応答しない接続は database/sql が破棄するため、次の送信は新しい接続で行われます
+func pingIdle(ctx context.Context, db *sql.DB) error {
+	idle := db.Stats().Idle
+	conns := make([]*sql.Conn, 0, idle)
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+
+	var errs error
+	// すべてを取り出してから返却する（1つずつ返すと同じ接続が再利用されてしまう）
+	for range idle {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return errors.Join(errs, err)
+		}
+		conns = append(conns, conn)
+		if err := conn.PingContext(ctx); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,239 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+const (
+	debugStatePath    = "/debug/state" // 実行状態を返すパス
+	debugRecentErrors = 10             // 返す直近のエラーの件数
+	debugPingTimeout  = 2 * time.Second
+)
+
+// DebugConfig - エクスポーターの実行状態をJSONで返すデバッグ用HTTPエンドポイントの設定
+// パイプラインが詰まった場合の調査向けに、DB接続の状態、最後の挿入の所要時間、処理中・退避中のデータ量、
+// シグナルごとの毎秒の挿入行数、直近のエラーを返します（zpages 拡張の expvarz を有効にできない環境でも参照できる）
+//
+//	curl http://localhost:13135/debug/state
+type DebugConfig struct {
+	// Endpoint は待ち受けるHTTPエンドポイント（host:port）です（未指定の場合は無効）
+	// 認証を行わないため、localhost など外部から到達できないアドレスを指定すること
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// validate はデバッグエンドポイントの設定を検証します
+func (c DebugConfig) validate() error {
+	if c.Endpoint == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(c.Endpoint); err != nil {
+		return fmt.Errorf("debug.endpoint は host:port 形式で指定してください: %w", err)
+	}
+	return nil
+}
+
+// debugSource はデバッグエンドポイントに状態を公開する1シグナル分のエクスポーターです
+type debugSource struct {
+	id     component.ID
+	signal string
+	db     *sql.DB      // DB接続（DB無効時は nil）
+	diag   *diagnostics // コンポーネントIDごとに共有する診断情報
+	spool  *diskSpool   // DB障害時のディスク退避（spool.directory 指定時のみ）
+}
+
+// debugServer はデバッグ用のHTTPエンドポイントです（同じエンドポイントを指定したエクスポーター間で共有）
+type debugServer struct {
+	server  *http.Server
+	sources map[*debugSource]struct{}
+}
+
+var (
+	debugMu      sync.Mutex
+	debugServers = map[string]*debugServer{} // エンドポイントごとのサーバー
+)
+
+// registerDebug はエクスポーターをデバッグエンドポイントに登録し、必要であればサーバーを起動します
+func registerDebug(endpoint string, source *debugSource, logger *zap.Logger) error {
+	if endpoint == "" || source == nil {
+		return nil
+	}
+	debugMu.Lock()
+	defer debugMu.Unlock()
+
+	s, ok := debugServers[endpoint]
+	if !ok {
+		listener, err := net.Listen("tcp", endpoint)
+		if err != nil {
+			return fmt.Errorf("debug.endpoint での待ち受けに失敗しました: %w", err)
+		}
+		s = &debugServer{sources: map[*debugSource]struct{}{}}
+		mux := http.NewServeMux()
+		mux.HandleFunc(debugStatePath, s.serveState)
+		s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("デバッグエンドポイントが停止しました", zap.Error(err))
+			}
+		}()
+		debugServers[endpoint] = s
+		logger.Info("デバッグエンドポイントで実行状態を公開します", zap.String("endpoint", endpoint), zap.String("path", debugStatePath))
+	}
+	s.sources[source] = struct{}{}
+	return nil
+}
+
+// unregisterDebug はエクスポーターの登録を解除し、最後の登録であればサーバーを停止します
+func unregisterDebug(endpoint string, source *debugSource) error {
+	if endpoint == "" || source == nil {
+		return nil
+	}
+	debugMu.Lock()
+	defer debugMu.Unlock()
+
+	s, ok := debugServers[endpoint]
+	if !ok {
+		return nil
+	}
+	delete(s.sources, source)
+	if len(s.sources) > 0 {
+		return nil
+	}
+	delete(debugServers, endpoint)
+	return s.server.Close()
+}
+
+// debugComponentState はコンポーネントIDごとの実行状態です
+type debugComponentState struct {
+	Signals      map[string]debugSignalState `json:"signals"`
+	RecentErrors []errorEntry                `json:"recent_errors"` // 直近の debugRecentErrors 件（古い順）
+}
+
+// debugSignalState はシグナルごとの実行状態です
+type debugSignalState struct {
+	Database      *debugDatabaseState `json:"database,omitempty"`    // DB無効時は省略
+	LastInsert    *flushOutcome       `json:"last_insert,omitempty"` // 挿入に成功していない場合は省略
+	InFlightItems int                 `json:"in_flight_items"`       // 送信処理中のアイテム数
+	RowsPerSecond float64             `json:"rows_per_second"`       // 直近1分間の平均の挿入行数
+	Spool         *debugSpoolState    `json:"spool,omitempty"`       // spool.directory 指定時のみ
+}
+
+// debugDatabaseState はリクエスト時に確認したDB接続の状態です
+type debugDatabaseState struct {
+	Connected   bool   `json:"connected"`
+	PingLatency string `json:"ping_latency"`
+	Error       string `json:"error,omitempty"`
+	OpenConns   int    `json:"open_connections"`
+	InUseConns  int    `json:"in_use_connections"`
+}
+
+// debugSpoolState は再挿入を待っているセグメントの量です
+type debugSpoolState struct {
+	Segments int    `json:"segments"`
+	Bytes    int64  `json:"bytes"`
+	Error    string `json:"error,omitempty"`
+}
+
+// serveState は登録されたエクスポーターの実行状態をコンポーネントIDごとに返します
+func (s *debugServer) serveState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	debugMu.Lock()
+	sources := make([]*debugSource, 0, len(s.sources))
+	for source := range s.sources {
+		sources = append(sources, source)
+	}
+	debugMu.Unlock()
+	sort.Slice(sources, func(i, j int) bool { return sources[i].signal < sources[j].signal })
+
+	// DBへの疎通確認はロックを保持せずに行う
+	now := time.Now()
+	state := map[string]*debugComponentState{}
+	for _, source := range sources {
+		c, ok := state[source.id.String()]
+		if !ok {
+			c = &debugComponentState{Signals: map[string]debugSignalState{}, RecentErrors: source.diag.recentErrorsTail(debugRecentErrors)}
+			state[source.id.String()] = c
+		}
+		c.Signals[source.signal] = source.state(r.Context(), now)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(state)
+}
+
+// state はシグナルの実行状態を返します
+func (s *debugSource) state(ctx context.Context, now time.Time) debugSignalState {
+	state := s.diag.signalState(s.signal, now)
+	if s.db != nil {
+		ctx, cancel := context.WithTimeout(ctx, debugPingTimeout)
+		defer cancel()
+		start := time.Now()
+		err := s.db.PingContext(ctx)
+		stats := s.db.Stats()
+		state.Database = &debugDatabaseState{
+			Connected:   err == nil,
+			PingLatency: time.Since(start).String(),
+			OpenConns:   stats.OpenConnections,
+			InUseConns:  stats.InUse,
+		}
+		if err != nil {
+			state.Database.Error = err.Error()
+		}
+	}
+	if s.spool != nil {
+		state.Spool = &debugSpoolState{}
+		segments, err := s.spool.segments()
+		if err != nil {
+			state.Spool.Error = err.Error()
+		}
+		for _, segment := range segments {
+			state.Spool.Segments++
+			state.Spool.Bytes += segment.size
+		}
+	}
+	return state
+}
+
+// signalState はシグナルの処理中アイテム数、最後の挿入、毎秒の挿入行数を返します
+func (d *diagnostics) signalState(signal string, now time.Time) debugSignalState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state := debugSignalState{InFlightItems: d.inFlight[signal]}
+	if last, ok := d.lastInsert[signal]; ok {
+		state.LastInsert = &last
+	}
+	if window, ok := d.throughput[signal]; ok {
+		state.RowsPerSecond = window.perSecond(now)
+	}
+	return state
+}
+
+// recentErrorsTail は直近の n 件のエラーを返します
+func (d *diagnostics) recentErrorsTail(n int) []errorEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	errs := d.recentErrors
+	if len(errs) > n {
+		errs = errs[len(errs)-n:]
+	}
+	return append([]errorEntry{}, errs...)
+}
@@ -0,0 +1,76 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// deduplicationToken は挿入先のテーブルとバッチの内容（OTLPのProtobuf表現）から挿入の重複排除トークンを求めます
+// 同じバッチのリトライは同じトークンになるため、ClickHouseは既に挿入済みのブロックを破棄します
+// 内容から求めるため、別々に送られた内容の全く同じバッチも重複として破棄されます
+func deduplicationToken(database, table string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(database))
+	h.Write([]byte{0})
+	h.Write([]byte(table))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// traceDeduplicationToken はトレースのバッチの重複排除トークンを返します（insert_deduplication_token 無効の場合は空）
+func (cfg *Config) traceDeduplicationToken(database, table string, td ptrace.Traces) string {
+	if !cfg.InsertDeduplicationToken {
+		return ""
+	}
+	payload, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(td)
+	if err != nil {
+		return ""
+	}
+	return deduplicationToken(database, table, payload)
+}
+
+// logDeduplicationToken はログのバッチの重複排除トークンを返します（insert_deduplication_token 無効の場合は空）
+func (cfg *Config) logDeduplicationToken(database, table string, ld plog.Logs) string {
+	if !cfg.InsertDeduplicationToken {
+		return ""
+	}
+	payload, err := (&plog.ProtoMarshaler{}).MarshalLogs(ld)
+	if err != nil {
+		return ""
+	}
+	return deduplicationToken(database, table, payload)
+}
+
+// metricDeduplicationToken はメトリクスのバッチの重複排除トークンを返します（insert_deduplication_token 無効の場合は空）
+// トークンはテーブルごとに異なるため、一部の種類のテーブルへの挿入のみ成功したバッチのリトライでも挿入済みの種類は破棄されます
+func (cfg *Config) metricDeduplicationToken(database, table string, md pmetric.Metrics) string {
+	if !cfg.InsertDeduplicationToken {
+		return ""
+	}
+	payload, err := (&pmetric.ProtoMarshaler{}).MarshalMetrics(md)
+	if err != nil {
+		return ""
+	}
+	return deduplicationToken(database, table, payload)
+}
+
+// profileDeduplicationToken はプロファイルのバッチの重複排除トークンを返します（insert_deduplication_token 無効の場合は空）
+func (cfg *Config) profileDeduplicationToken(database, table string, pd pprofile.Profiles) string {
+	if !cfg.InsertDeduplicationToken {
+		return ""
+	}
+	payload, err := (&pprofile.ProtoMarshaler{}).MarshalProfiles(pd)
+	if err != nil {
+		return ""
+	}
+	return deduplicationToken(database, table, payload)
+}
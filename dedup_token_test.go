@@ -0,0 +1,165 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// dedupTestTraces は1リソース・1スコープに n スパンを持つトレースを返します
+func dedupTestTraces(n int) ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	for i := range n {
+		span := spans.AppendEmpty()
+		span.SetTraceID(pcommon.TraceID{1})
+		span.SetSpanID(pcommon.SpanID{byte(i + 1)})
+		span.SetName(fmt.Sprintf("span-%d", i))
+	}
+	return td
+}
+
+// dedupTestLogs は1リソース・1スコープに n ログレコードを持つログを返します
+func dedupTestLogs(n int) plog.Logs {
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "checkout")
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+	for i := range n {
+		records.AppendEmpty().Body().SetStr(fmt.Sprintf("log-%d", i))
+	}
+	return ld
+}
+
+func TestTraceDeduplicationToken(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InsertDeduplicationToken = true
+	td := dedupTestTraces(4)
+	token := cfg.traceDeduplicationToken("otel", "otel_traces", td)
+	if token == "" {
+		t.Fatal("insert_deduplication_token 有効時はトークンを返します")
+	}
+
+	// リトライで再送された同じ内容のバッチは同じトークンになる
+	retry := ptrace.NewTraces()
+	td.CopyTo(retry)
+	if got := cfg.traceDeduplicationToken("otel", "otel_traces", retry); got != token {
+		t.Errorf("同じバッチのトークンが異なります: %s != %s", got, token)
+	}
+
+	// 分割した挿入（max_insert_rows）は分割ごとに別のトークンになる
+	seen := map[string]bool{token: true}
+	for i, chunk := range splitTraces(td, 2) {
+		got := cfg.traceDeduplicationToken("otel", "otel_traces", chunk)
+		if seen[got] {
+			t.Errorf("分割 %d のトークンが他の挿入と重複しています: %s", i, got)
+		}
+		seen[got] = true
+	}
+
+	// テナント・書き込み先のテーブルが異なる場合は同じ内容でも別のトークンになる
+	for _, target := range [][2]string{{"otel", "otel_traces_acme"}, {"otel_acme", "otel_traces"}, {"otelo", "tel_traces"}} {
+		got := cfg.traceDeduplicationToken(target[0], target[1], td)
+		if seen[got] {
+			t.Errorf("%s.%s のトークンが他のテーブルと重複しています: %s", target[0], target[1], got)
+		}
+		seen[got] = true
+	}
+
+	cfg.InsertDeduplicationToken = false
+	if got := cfg.traceDeduplicationToken("otel", "otel_traces", td); got != "" {
+		t.Errorf("insert_deduplication_token 無効時はトークンを返しません: %s", got)
+	}
+}
+
+func TestLogDeduplicationToken(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InsertDeduplicationToken = true
+	ld := dedupTestLogs(3)
+	token := cfg.logDeduplicationToken("otel", "otel_logs", ld)
+
+	retry := plog.NewLogs()
+	ld.CopyTo(retry)
+	if got := cfg.logDeduplicationToken("otel", "otel_logs", retry); got != token {
+		t.Errorf("同じバッチのトークンが異なります: %s != %s", got, token)
+	}
+
+	seen := map[string]bool{token: true}
+	for i, chunk := range splitLogs(ld, 1) {
+		got := cfg.logDeduplicationToken("otel", "otel_logs", chunk)
+		if seen[got] {
+			t.Errorf("分割 %d のトークンが他の挿入と重複しています: %s", i, got)
+		}
+		seen[got] = true
+	}
+	if got := cfg.logDeduplicationToken("otel", "otel_logs_acme", ld); seen[got] {
+		t.Errorf("テナントのテーブルのトークンが重複しています: %s", got)
+	}
+}
+
+func TestInsertQuerySettings(t *testing.T) {
+	tests := []struct {
+		name           string
+		insertSettings map[string]string
+		driver         string
+		token          string
+		want           map[string]any // nil の場合はクエリ設定を付与しない
+	}{
+		{
+			name: "設定なし",
+		},
+		{
+			name:  "トークンのみ",
+			token: "abc",
+			want:  map[string]any{"insert_deduplication_token": "abc", "insert_deduplicate": "1"},
+		},
+		{
+			name:           "insert_settings の insert_deduplicate を優先",
+			insertSettings: map[string]string{"insert_deduplicate": "0", "max_insert_block_size": "1000"},
+			token:          "abc",
+			want:           map[string]any{"insert_deduplication_token": "abc", "insert_deduplicate": "0", "max_insert_block_size": "1000"},
+		},
+		{
+			name:           "トークンなし",
+			insertSettings: map[string]string{"max_insert_block_size": "1000"},
+			want:           map[string]any{"max_insert_block_size": "1000"},
+		},
+		{
+			name:   "postgres",
+			driver: driverPostgres,
+			token:  "abc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.InsertSettings = tt.insertSettings
+			if tt.driver != "" {
+				cfg.Driver = tt.driver
+			}
+			got := cfg.insertQuerySettings(tt.token)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("クエリ設定を付与しません: %v", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("クエリ設定 = %v, want %v", got, tt.want)
+			}
+			for name, value := range tt.want {
+				if got[name] != value {
+					t.Errorf("%s = %v, want %v", name, got[name], value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// SoftDeleteConfig - 軽量DELETE（論理削除）の設定
+type SoftDeleteConfig struct {
+	// Enabled はテーブルを軽量DELETE互換の設定で作成し、Purger による削除を許可します
+	Enabled bool `mapstructure:"enabled"`
+	// MinInterval はDELETE文を連続実行する際の最小間隔です（ミューテーションの過負荷を防ぐ）
+	MinInterval time.Duration `mapstructure:"min_interval"`
+}
+
+// Purger は管理対象テーブルから属性でフィルタしたデータを軽量DELETEで削除します
+// GDPR等による特定ユーザーのデータ削除要求への対応を想定しています
+// endpoint に加えて targets のすべての書き込み先と、multi_tenancy.routing で作成したテナントのテーブルも対象とします
+type Purger struct {
+	config  *Config
+	logger  *zap.Logger
+	targets []purgeTarget // 削除を実行する接続先（先頭は endpoint）
+
+	mu      sync.Mutex // DELETE文を1つずつ実行するためのロック
+	lastRun time.Time  // 直前のDELETE文の実行時刻（レート制限用）
+}
+
+// purgeTarget は削除を実行する接続先（endpoint または targets の書き込み先）です
+type purgeTarget struct {
+	name   string  // 監査ログに記録する接続先の名前（endpoint の場合は空文字）
+	config *Config // 接続先の設定（cluster_name は書き込み先ごとに異なる場合がある）
+	db     *sql.DB
+}
+
+// NewPurger は endpoint と各書き込み先への削除用のDB接続を持つ Purger を作成します
+func NewPurger(cfg *Config, logger *zap.Logger) (*Purger, error) {
+	if !cfg.SoftDelete.Enabled {
+		return nil, stable.ErrSoftDeleteDisabled
+	}
+	db, err := buildDBConnection(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("データベース接続の構築に失敗しました: %w", err)
+	}
+	p := &Purger{config: cfg, logger: logger, targets: []purgeTarget{{config: cfg, db: db}}}
+	for _, t := range cfg.Targets {
+		target := cfg.targetConfig(t)
+		db, err := buildDBConnection(target, logger)
+		if err != nil {
+			_ = p.Close()
+			return nil, fmt.Errorf("書き込み先 %s へのデータベース接続の構築に失敗しました: %w", t.Name, err)
+		}
+		p.targets = append(p.targets, purgeTarget{name: t.Name, config: target, db: db})
+	}
+	return p, nil
+}
+
+// Close はDB接続を閉じます
+func (p *Purger) Close() error {
+	var errs error
+	for _, t := range p.targets {
+		errs = errors.Join(errs, t.db.Close())
+	}
+	return errs
+}
+
+// DeleteByAttribute はすべての接続先の全シグナルの管理対象テーブルから、いずれかの属性カラムで
+// key = value に一致する行を削除し、削除を実行したテーブル数（接続先ごとに数える）を返します
+// signals を指定した場合はそのシグナル（traces, logs, metrics, profiles）のみを対象とします
+func (p *Purger) DeleteByAttribute(ctx context.Context, key, value string, signals ...string) (int, error) {
+	if key == "" {
+		return 0, stable.ErrMissingAttributeKey
+	}
+
+	deleted := 0
+	for _, target := range p.targets {
+		for _, table := range managedTables(target.config) {
+			if len(signals) > 0 && !slices.Contains(signals, table.signal) {
+				continue
+			}
+			tenants, err := tenantTables(ctx, target.config, target.db, table)
+			if err != nil {
+				return deleted, p.targetError(target, err)
+			}
+			for _, t := range append([]managedTable{table}, tenants...) {
+				if err := p.deleteFromTable(ctx, target, t, key, value); err != nil {
+					return deleted, p.targetError(target, err)
+				}
+				deleted++
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// targetError は書き込み先での失敗の場合に書き込み先の名前を付けたエラーを返します
+func (p *Purger) targetError(target purgeTarget, err error) error {
+	if target.name == "" {
+		return err
+	}
+	return fmt.Errorf("書き込み先 %s: %w", target.name, err)
+}
+
+// tenantTables は multi_tenancy.routing でテナントごとに作成された、table と同じ属性カラムを持つテーブルを返します
+// エクスポーターが記憶するテナントは max_cached_tenants で破棄されるため、サーバーのテーブル一覧から探します
+func tenantTables(ctx context.Context, cfg *Config, db *sql.DB, table managedTable) ([]managedTable, error) {
+	if !cfg.MultiTenancy.Routing.Enabled || (table.signal != "traces" && table.signal != "logs") {
+		return nil, nil
+	}
+	databasePattern, tablePattern := tenantTablePatterns(cfg.MultiTenancy.Routing, table.database, table.name)
+
+	// ビューや検索テーブルなど属性カラムを持たないテーブルは、パターンに一致しても対象外とする
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(table.attributeColumns)), ", ")
+	query := fmt.Sprintf(`SELECT database, table FROM system.columns
+WHERE match(database, ?) AND match(table, ?) AND name IN (%s)
+  AND (database, table) NOT IN (SELECT database, name FROM system.tables WHERE engine IN ('View', 'MaterializedView'))
+GROUP BY database, table HAVING uniqExact(name) = ?
+ORDER BY database, table`, placeholders)
+	args := []any{databasePattern, tablePattern}
+	for _, column := range table.attributeColumns {
+		args = append(args, column)
+	}
+	args = append(args, len(table.attributeColumns))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("テナントのテーブルの取得に失敗しました: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []managedTable
+	for rows.Next() {
+		var database, name string
+		if err := rows.Scan(&database, &name); err != nil {
+			return nil, fmt.Errorf("テナントのテーブルの取得に失敗しました: %w", err)
+		}
+		// クラスター構成ではローカルテーブルを renderDeleteSQL が対象にするため、分散テーブルの名前のみを使う
+		if cfg.ClusterName != "" && strings.HasSuffix(name, localTableSuffix) {
+			continue
+		}
+		tables = append(tables, managedTable{table.signal, database, name, table.attributeColumns, table.jsonAttributes})
+	}
+	return tables, rows.Err()
+}
+
+// tenantTablePatterns は振り分け先のデータベース名・テーブル名のパターンを、任意のテナントに一致する正規表現に変換します
+// テナント名は tenantOf が識別子に使用できる文字のみに置換するため、{tenant} は英数字と _ の1文字以上に一致させます
+func tenantTablePatterns(routing TenantRoutingConfig, database, table string) (string, string) {
+	replacer := strings.NewReplacer("{database}", database, "{table}", table)
+	pattern := func(p string) string {
+		parts := strings.Split(replacer.Replace(p), "{tenant}")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		return "^" + strings.Join(parts, "[0-9A-Za-z_]+") + "$"
+	}
+	return pattern(routing.DatabasePattern), pattern(routing.TablePattern)
+}
+
+// deleteFromTable はレート制限を守りながら1テーブルに対してDELETE文を実行し、監査ログを出力します
+func (p *Purger) deleteFromTable(ctx context.Context, target purgeTarget, table managedTable, key, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.waitForRateLimit(ctx); err != nil {
+		return err
+	}
+
+	query, args := renderDeleteSQL(target.config, table, key, value)
+
+	// 監査ログ: 値そのものは個人情報の可能性があるためハッシュのみ記録する
+	auditFields := []zap.Field{
+		zap.String("audit", "soft_delete"),
+		zap.String("target", target.name),
+		zap.String("signal", table.signal),
+		zap.String("database", table.database),
+		zap.String("table", table.name),
+		zap.String("attribute_key", key),
+		zap.String("attribute_value_sha256", hashValue(value)),
+	}
+
+	start := time.Now()
+	_, err := target.db.ExecContext(ctx, query, args...)
+	p.lastRun = time.Now()
+	if err != nil {
+		p.logger.Error("監査: 属性フィルタによる削除に失敗しました", append(auditFields, zap.Error(err))...)
+		return fmt.Errorf("%s.%s からの削除に失敗しました: %w", table.database, table.name, err)
+	}
+
+	p.logger.Info("監査: 属性フィルタによる削除を実行しました",
+		append(auditFields, zap.Duration("elapsed", time.Since(start)))...)
+	return nil
+}
+
+// waitForRateLimit は直前のDELETE文から MinInterval が経過するまで待機します
+func (p *Purger) waitForRateLimit(ctx context.Context) error {
+	wait := p.config.SoftDelete.MinInterval - time.Since(p.lastRun)
+	if p.lastRun.IsZero() || wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// renderDeleteSQL は軽量DELETE文とバインド引数を生成します
+// 分散テーブルは直接削除できないため、クラスター構成ではローカルテーブルを ON CLUSTER で対象にします
+func renderDeleteSQL(cfg *Config, table managedTable, key, value string) (string, []any) {
+	name := table.name
+	cluster := ""
+	if cfg.ClusterName != "" {
+		name += localTableSuffix
+		cluster = " " + cfg.clusterString()
+	}
+
+	conditions := make([]string, 0, len(table.attributeColumns))
+	args := make([]any, 0, len(table.attributeColumns)*2)
+	for _, column := range table.attributeColumns {
+		conditions = append(conditions, attributeLookupSQL(column, "?", table.jsonAttributes)+" = ?")
+		args = append(args, key, value)
+	}
+
+	query := fmt.Sprintf(`DELETE FROM "%s"."%s"%s WHERE %s`,
+		table.database, name, cluster, strings.Join(conditions, " OR "))
+	return query, args
+}
+
+// hashValue は監査ログ用に値のSHA-256ハッシュを返します
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
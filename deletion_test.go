@@ -0,0 +1,90 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTenantTablePatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		routing  TenantRoutingConfig
+		database string
+		table    string
+		match    [][2]string // 一致するデータベース名・テーブル名
+		noMatch  [][2]string // 一致しないデータベース名・テーブル名
+	}{
+		{
+			name:     "テーブル名にテナントを含む",
+			routing:  TenantRoutingConfig{DatabasePattern: "{database}", TablePattern: "{table}_{tenant}"},
+			database: "otel",
+			table:    "otel_traces",
+			match:    [][2]string{{"otel", "otel_traces_acme"}, {"otel", "otel_traces_team_a"}},
+			noMatch:  [][2]string{{"otel", "otel_traces"}, {"otel2", "otel_traces_acme"}, {"otel", "otel_logs_acme"}},
+		},
+		{
+			name:     "データベース名にテナントを含む",
+			routing:  TenantRoutingConfig{DatabasePattern: "{database}.{tenant}", TablePattern: "{table}"},
+			database: "otel",
+			table:    "otel_logs",
+			match:    [][2]string{{"otel.acme", "otel_logs"}},
+			// . は正規表現のメタ文字として扱わない
+			noMatch: [][2]string{{"otel", "otel_logs"}, {"otelXacme", "otel_logs"}, {"otel.acme", "otel_logs_local"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			databasePattern, tablePattern := tenantTablePatterns(tt.routing, tt.database, tt.table)
+			database, table := regexp.MustCompile(databasePattern), regexp.MustCompile(tablePattern)
+			for _, m := range tt.match {
+				if !database.MatchString(m[0]) || !table.MatchString(m[1]) {
+					t.Errorf("%s.%s が %s / %s に一致しません", m[0], m[1], databasePattern, tablePattern)
+				}
+			}
+			for _, m := range tt.noMatch {
+				if database.MatchString(m[0]) && table.MatchString(m[1]) {
+					t.Errorf("%s.%s が %s / %s に一致しました", m[0], m[1], databasePattern, tablePattern)
+				}
+			}
+		})
+	}
+}
+
+func TestManagedTablesTraceScopeAttributes(t *testing.T) {
+	for _, storeScope := range []bool{false, true} {
+		cfg := DefaultConfig()
+		cfg.Traces.StoreScope = storeScope
+		traces := managedTables(cfg)[0]
+		if traces.signal != "traces" {
+			t.Fatalf("先頭の管理対象テーブルがトレースではありません: %s", traces.signal)
+		}
+		if got := slices.Contains(traces.attributeColumns, "ScopeAttributes"); got != storeScope {
+			t.Errorf("store_scope=%v: ScopeAttributes を含む=%v", storeScope, got)
+		}
+	}
+}
+
+func TestRenderDeleteSQLUsesTargetCluster(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Endpoint = "tcp://127.0.0.1:9000"
+	cfg.Targets = []TargetConfig{{Name: "dr", Endpoint: "tcp://127.0.0.1:19000", ClusterName: "dr_cluster"}}
+	table := managedTable{"logs", "otel", "otel_logs", []string{"ResourceAttributes", "LogAttributes"}, false}
+
+	query, args := renderDeleteSQL(cfg, table, "user.id", "u1")
+	if strings.Contains(query, "ON CLUSTER") || !strings.Contains(query, `"otel"."otel_logs" WHERE`) {
+		t.Errorf("endpoint の DELETE 文が不正です: %s", query)
+	}
+	if len(args) != 4 {
+		t.Errorf("属性カラムごとにキーと値を渡します: %v", args)
+	}
+
+	query, _ = renderDeleteSQL(cfg.targetConfig(cfg.Targets[0]), table, "user.id", "u1")
+	if !strings.Contains(query, `"otel"."otel_logs_local" ON CLUSTER`) || !strings.Contains(query, "dr_cluster") {
+		t.Errorf("書き込み先の DELETE 文は書き込み先のクラスターを対象にします: %s", query)
+	}
+}
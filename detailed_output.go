@@ -0,0 +1,204 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 詳細モード（detailed: true）の出力形式
+const (
+	logFormatText     = "text"      // コレクターのロガーにフィールドとして出力（既定）
+	logFormatJSON     = "json"      // 1件ごとに1行のJSONオブジェクトとして標準出力に出力
+	logFormatOTLPJSON = "otlp_json" // 1件ごとに単独のOTLP JSONドキュメントとして標準出力に出力
+)
+
+// validLogFormats は log_format に指定できる値です
+var validLogFormats = []string{logFormatText, logFormatJSON, logFormatOTLPJSON}
+
+// DetailedSamplingConfig - 詳細モードで出力するデータのサンプリング設定
+// 全件の出力は高スループットのパイプラインでロガーが詰まる原因になるため、送信ごとに出力する件数を絞ります
+// 未指定の場合は全件を出力します
+type DetailedSamplingConfig struct {
+	// Initial は送信ごとに最初に出力する件数です
+	Initial int `mapstructure:"initial"`
+	// Thereafter は Initial 件を超えた後に何件ごとに1件を出力するかです（0 の場合は以降を出力しない）
+	Thereafter int `mapstructure:"thereafter"`
+	// Rate は各データを出力する確率（0より大きく1以下）です（initial, thereafter とは併用不可）
+	Rate float64 `mapstructure:"rate"`
+}
+
+// validate はサンプリング設定を検証します
+func (c DetailedSamplingConfig) validate() error {
+	var errs error
+	if c.Initial < 0 || c.Thereafter < 0 {
+		errs = errors.Join(errs, fmt.Errorf("detailed_sampling.initial と thereafter は0以上である必要があります"))
+	}
+	if c.Rate < 0 || c.Rate > 1 {
+		errs = errors.Join(errs, fmt.Errorf("detailed_sampling.rate は0から1の範囲で指定してください: %g", c.Rate))
+	}
+	if c.Rate > 0 && (c.Initial > 0 || c.Thereafter > 0) {
+		errs = errors.Join(errs, fmt.Errorf("detailed_sampling.rate は initial, thereafter と同時に指定できません"))
+	}
+	return errs
+}
+
+// enabled はサンプリングが設定されているかどうかを返します（未設定の場合は全件出力）
+func (c DetailedSamplingConfig) enabled() bool {
+	return c.Initial > 0 || c.Thereafter > 0 || c.Rate > 0
+}
+
+// detailedSampler は1回の送信内で詳細出力するデータを選びます
+type detailedSampler struct {
+	config  DetailedSamplingConfig
+	seen    int // 判定したデータ数
+	sampled int // 出力したデータ数
+}
+
+// newDetailedSampler は送信1回分のサンプラーを作成します（詳細モードが無効な場合は nil）
+func newDetailedSampler(live *liveSettings) *detailedSampler {
+	if !live.Detailed {
+		return nil
+	}
+	return &detailedSampler{config: live.DetailedSampling}
+}
+
+// sample は次のデータを詳細出力するかどうかを返します
+func (s *detailedSampler) sample() bool {
+	if s == nil {
+		return false
+	}
+	s.seen++
+	keep := true
+	switch c := s.config; {
+	case !c.enabled():
+	case c.Rate > 0:
+		keep = rand.Float64() < c.Rate
+	case s.seen <= c.Initial:
+	default:
+		keep = c.Thereafter > 0 && (s.seen-c.Initial)%c.Thereafter == 0
+	}
+	if keep {
+		s.sampled++
+	}
+	return keep
+}
+
+// field は処理完了ログに記録する詳細出力の件数です（詳細モードが無効な場合は出力しない）
+func (s *detailedSampler) field() zap.Field {
+	if s == nil {
+		return zap.Skip()
+	}
+	return zap.Int("detailed_sampled", s.sampled)
+}
+
+// detailedOutput は詳細モードで受信したスパン・ログ・メトリクス・プロファイルを出力します
+// json と otlp_json はコレクターのログエンコーダーに依存せず、jq や Loki（promtail）にそのまま渡せるよう
+// 1件を1行として標準出力に書き込みます
+type detailedOutput struct {
+	format string
+	logger *zap.Logger // text・json の出力先
+
+	mu sync.Mutex // otlp_json の行が混ざらないよう書き込みを直列化
+	w  io.Writer
+}
+
+// newDetailedOutput は log_format に応じた詳細モードの出力先を作成します
+func newDetailedOutput(format string, logger *zap.Logger) *detailedOutput {
+	out := &detailedOutput{format: format, logger: logger, w: os.Stdout}
+	if format == logFormatJSON {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.TimeKey = "timestamp"
+		encoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+		out.logger = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.Lock(os.Stdout), zapcore.InfoLevel))
+	}
+	return out
+}
+
+// otlp はデータをOTLP JSONドキュメントとして出力するかどうかを返します
+func (o *detailedOutput) otlp() bool {
+	return o.format == logFormatOTLPJSON
+}
+
+// writeSpan はスパン1件をリソース・スコープ付きのOTLP JSONドキュメントとして出力します
+func (o *detailedOutput) writeSpan(rs ptrace.ResourceSpans, ss ptrace.ScopeSpans, span ptrace.Span) {
+	td := ptrace.NewTraces()
+	dstRS := td.ResourceSpans().AppendEmpty()
+	rs.Resource().CopyTo(dstRS.Resource())
+	dstRS.SetSchemaUrl(rs.SchemaUrl())
+	dstSS := dstRS.ScopeSpans().AppendEmpty()
+	ss.Scope().CopyTo(dstSS.Scope())
+	dstSS.SetSchemaUrl(ss.SchemaUrl())
+	span.CopyTo(dstSS.Spans().AppendEmpty())
+
+	o.write((&ptrace.JSONMarshaler{}).MarshalTraces(td))
+}
+
+// writeLogRecord はログレコード1件をリソース・スコープ付きのOTLP JSONドキュメントとして出力します
+func (o *detailedOutput) writeLogRecord(rl plog.ResourceLogs, sl plog.ScopeLogs, lr plog.LogRecord) {
+	ld := plog.NewLogs()
+	dstRL := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().CopyTo(dstRL.Resource())
+	dstRL.SetSchemaUrl(rl.SchemaUrl())
+	dstSL := dstRL.ScopeLogs().AppendEmpty()
+	sl.Scope().CopyTo(dstSL.Scope())
+	dstSL.SetSchemaUrl(sl.SchemaUrl())
+	lr.CopyTo(dstSL.LogRecords().AppendEmpty())
+
+	o.write((&plog.JSONMarshaler{}).MarshalLogs(ld))
+}
+
+// writeMetric はメトリクス1件（全データポイント）をリソース・スコープ付きのOTLP JSONドキュメントとして出力します
+func (o *detailedOutput) writeMetric(rm pmetric.ResourceMetrics, sm pmetric.ScopeMetrics, metric pmetric.Metric) {
+	md := pmetric.NewMetrics()
+	dstRM := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().CopyTo(dstRM.Resource())
+	dstRM.SetSchemaUrl(rm.SchemaUrl())
+	dstSM := dstRM.ScopeMetrics().AppendEmpty()
+	sm.Scope().CopyTo(dstSM.Scope())
+	dstSM.SetSchemaUrl(sm.SchemaUrl())
+	metric.CopyTo(dstSM.Metrics().AppendEmpty())
+
+	o.write((&pmetric.JSONMarshaler{}).MarshalMetrics(md))
+}
+
+// writeProfile はプロファイル1件をリソース・スコープ付きのOTLP JSONドキュメントとして出力します
+// プロファイルの文字列・関数・ロケーションは共有辞書を参照するため、辞書はそのまま含めます
+func (o *detailedOutput) writeProfile(pd pprofile.Profiles, rp pprofile.ResourceProfiles, sp pprofile.ScopeProfiles, profile pprofile.Profile) {
+	doc := pprofile.NewProfiles()
+	pd.ProfilesDictionary().CopyTo(doc.ProfilesDictionary())
+	dstRP := doc.ResourceProfiles().AppendEmpty()
+	rp.Resource().CopyTo(dstRP.Resource())
+	dstRP.SetSchemaUrl(rp.SchemaUrl())
+	dstSP := dstRP.ScopeProfiles().AppendEmpty()
+	sp.Scope().CopyTo(dstSP.Scope())
+	dstSP.SetSchemaUrl(sp.SchemaUrl())
+	profile.CopyTo(dstSP.Profiles().AppendEmpty())
+
+	o.write((&pprofile.JSONMarshaler{}).MarshalProfiles(doc))
+}
+
+// write はドキュメントを1行として書き込みます（失敗しても処理は継続）
+func (o *detailedOutput) write(doc []byte, err error) {
+	if err == nil {
+		o.mu.Lock()
+		_, err = fmt.Fprintf(o.w, "%s\n", doc)
+		o.mu.Unlock()
+	}
+	if err != nil {
+		o.logger.Warn("詳細モードのOTLP JSON出力に失敗しました", zap.Error(err))
+	}
+}
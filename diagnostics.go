@@ -0,0 +1,332 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+// 診断情報はzPages拡張の expvarz ページ（/debug/expvarz）で参照できる
+// zpages 拡張の設定で expvar.enabled: true を指定すること
+const (
+	diagnosticsExpvarName = "mylogexporter" // expvarに公開する変数名
+	diagnosticsHistory    = 20              // 保持するフラッシュ結果・エラーの件数
+)
+
+var (
+	diagnosticsMu       sync.Mutex
+	diagnosticsRegistry = map[string]*diagnostics{} // コンポーネントIDごとの診断情報
+	diagnosticsOnce     sync.Once
+)
+
+// getDiagnostics はコンポーネントIDに対応する診断情報を返します
+// 同じ設定から作成された各シグナルのエクスポーターは同じ診断情報を共有します
+func getDiagnostics(id component.ID) *diagnostics {
+	diagnosticsOnce.Do(func() {
+		expvar.Publish(diagnosticsExpvarName, expvar.Func(snapshotDiagnostics))
+	})
+
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	d, ok := diagnosticsRegistry[id.String()]
+	if !ok {
+		d = &diagnostics{
+			inFlight:   map[string]int{},
+			filtered:   map[string]int64{},
+			truncated:  map[string]int64{},
+			previewed:  map[string]int64{},
+			dropped:    map[string]map[string]int64{},
+			lastErrors: map[string]errorEntry{},
+			tables:     map[string]*tableStats{},
+			lastInsert: map[string]flushOutcome{},
+			throughput: map[string]*rateWindow{},
+		}
+		diagnosticsRegistry[id.String()] = d
+	}
+	return d
+}
+
+// snapshotDiagnostics は全コンポーネントの診断情報のスナップショットを返します
+func snapshotDiagnostics() any {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	snapshot := make(map[string]any, len(diagnosticsRegistry))
+	for id, d := range diagnosticsRegistry {
+		snapshot[id] = d.snapshot()
+	}
+	return snapshot
+}
+
+// diagnostics はコンポーネントの実行状態（処理中データ、フラッシュ結果、エラー、テーブル統計）を保持します
+type diagnostics struct {
+	mu           sync.Mutex
+	inFlight     map[string]int              // シグナルごとの処理中アイテム数
+	filtered     map[string]int64            // シグナルごとのフィルタで破棄したアイテム数（累積）
+	truncated    map[string]int64            // シグナルごとの短縮した属性キー数（累積）
+	dropped      map[string]map[string]int64 // シグナルごと・理由ごとの破棄したアイテム数（累積）
+	previewed    map[string]int64            // filter_preview で規則ごとに破棄・集約されるはずだったアイテム数（累積、キーは 規則.処理）
+	flushes      []flushOutcome              // 直近のフラッシュ結果
+	recentErrors []errorEntry                // 直近のエラー
+	lastErrors   map[string]errorEntry       // シグナルごとの最後のエラー（直近のエラーから押し出された場合も保持）
+	tables       map[string]*tableStats      // テーブルごとの統計
+	lastInsert   map[string]flushOutcome     // シグナルごとの最後に成功したフラッシュ
+	throughput   map[string]*rateWindow      // シグナルごとの直近の挿入件数（毎秒の行数の計算用）
+}
+
+// flushOutcome は1回のフラッシュ（pushX呼び出し）の結果です
+type flushOutcome struct {
+	Time     time.Time `json:"time"`
+	Signal   string    `json:"signal"`
+	Table    string    `json:"table"`
+	Items    int       `json:"items"`
+	Duration string    `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// errorEntry は記録されたエラーです
+type errorEntry struct {
+	Time   time.Time `json:"time"`
+	Signal string    `json:"signal"`
+	Error  string    `json:"error"`
+}
+
+// tableStats はテーブルごとの累積統計です
+type tableStats struct {
+	signal    string    // テーブルに書き込むシグナル（終了時のサマリーの集計用）
+	Items     int64     `json:"items"`
+	Flushes   int64     `json:"flushes"`
+	Failures  int64     `json:"failures"`
+	LastFlush time.Time `json:"last_flush"`
+}
+
+// beginFlush は処理中アイテム数を加算し、フラッシュ結果を記録する関数を返します
+func (d *diagnostics) beginFlush(signal, table string, items int) func(err error) {
+	if d == nil {
+		return func(error) {}
+	}
+
+	start := time.Now()
+	d.mu.Lock()
+	d.inFlight[signal] += items
+	d.mu.Unlock()
+
+	return func(err error) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.inFlight[signal] -= items
+		outcome := flushOutcome{
+			Time:     start,
+			Signal:   signal,
+			Table:    table,
+			Items:    items,
+			Duration: time.Since(start).String(),
+		}
+
+		stats, ok := d.tables[table]
+		if !ok {
+			stats = &tableStats{signal: signal}
+			d.tables[table] = stats
+		}
+		stats.Flushes++
+		stats.LastFlush = start
+		if err != nil {
+			outcome.Error = err.Error()
+			stats.Failures++
+			entry := errorEntry{Time: start, Signal: signal, Error: err.Error()}
+			d.recentErrors = appendBounded(d.recentErrors, entry)
+			d.lastErrors[signal] = entry
+		} else {
+			stats.Items += int64(items)
+			d.lastInsert[signal] = outcome
+			window, ok := d.throughput[signal]
+			if !ok {
+				window = &rateWindow{}
+				d.throughput[signal] = window
+			}
+			window.add(time.Now(), items)
+		}
+		d.flushes = appendBounded(d.flushes, outcome)
+	}
+}
+
+// recordError はフラッシュ以外で発生したエラー（起動時のスキーマ作成など）を記録します
+func (d *diagnostics) recordError(signal string, err error) {
+	if d == nil || err == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry := errorEntry{Time: time.Now(), Signal: signal, Error: err.Error()}
+	d.recentErrors = appendBounded(d.recentErrors, entry)
+	d.lastErrors[signal] = entry
+}
+
+// recordFiltered はフィルタで破棄したアイテム数を加算します
+func (d *diagnostics) recordFiltered(signal string, items int) {
+	if d == nil || items == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.filtered[signal] += int64(items)
+}
+
+// recordTruncatedKeys は max_attribute_key_length により短縮した属性キー数を加算します
+func (d *diagnostics) recordTruncatedKeys(signal string, keys int) {
+	if d == nil || keys == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.truncated[signal] += int64(keys)
+}
+
+// recordDropped は保存できずに破棄したアイテム数を理由ごとに加算します
+func (d *diagnostics) recordDropped(signal, reason string, items int) {
+	if d == nil || items == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dropped[signal] == nil {
+		d.dropped[signal] = map[string]int64{}
+	}
+	d.dropped[signal][reason] += int64(items)
+}
+
+// logSummary はプロセスの起動以降のシグナルの処理結果（テーブルごとのアイテム数、理由ごとの破棄件数、最後のエラー）をログに記録します
+// 終了時に出力し、障害後の振り返りや長時間試験の結果確認に使用します
+func (d *diagnostics) logSummary(signal string, logger *zap.Logger) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	items := map[string]int64{}
+	var flushes, failures int64
+	for table, stats := range d.tables {
+		if stats.signal != signal {
+			continue
+		}
+		items[table] = stats.Items
+		flushes += stats.Flushes
+		failures += stats.Failures
+	}
+	dropped := map[string]int64{}
+	for reason, n := range d.dropped[signal] {
+		dropped[reason] = n
+	}
+	fields := []zap.Field{
+		zap.String("signal", signal),
+		zap.Any("items_per_table", items),
+		zap.Int64("flushes", flushes),
+		zap.Int64("failed_flushes", failures),
+		zap.Any("dropped_items", dropped),
+	}
+	if last, ok := d.lastErrors[signal]; ok {
+		fields = append(fields, zap.String("last_error", last.Error), zap.Time("last_error_time", last.Time))
+	}
+	logger.Info("エクスポーターの処理結果のサマリー", fields...)
+}
+
+// recordPreview は filter_preview で規則により破棄・集約されるはずだったアイテム数を加算します
+func (d *diagnostics) recordPreview(rule, action string, items int) {
+	if d == nil || items == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.previewed[rule+"."+action] += int64(items)
+}
+
+// snapshot は診断情報のコピーを返します
+func (d *diagnostics) snapshot() map[string]any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	inFlight := make(map[string]int, len(d.inFlight))
+	for signal, n := range d.inFlight {
+		inFlight[signal] = n
+	}
+	filtered := make(map[string]int64, len(d.filtered))
+	for signal, n := range d.filtered {
+		filtered[signal] = n
+	}
+	truncated := make(map[string]int64, len(d.truncated))
+	for signal, n := range d.truncated {
+		truncated[signal] = n
+	}
+	dropped := make(map[string]map[string]int64, len(d.dropped))
+	for signal, reasons := range d.dropped {
+		dropped[signal] = make(map[string]int64, len(reasons))
+		for reason, n := range reasons {
+			dropped[signal][reason] = n
+		}
+	}
+	previewed := make(map[string]int64, len(d.previewed))
+	for rule, n := range d.previewed {
+		previewed[rule] = n
+	}
+	tables := make(map[string]tableStats, len(d.tables))
+	for table, stats := range d.tables {
+		tables[table] = *stats
+	}
+	return map[string]any{
+		"in_flight_items": inFlight,
+		"filtered_items":  filtered,
+		"truncated_keys":  truncated,
+		"filter_preview":  previewed,
+		"dropped_items":   dropped,
+		"last_flushes":    append([]flushOutcome(nil), d.flushes...),
+		"recent_errors":   append([]errorEntry(nil), d.recentErrors...),
+		"tables":          tables,
+	}
+}
+
+// appendBounded は最新の diagnosticsHistory 件のみを保持するよう要素を追加します
+func appendBounded[T any](items []T, item T) []T {
+	items = append(items, item)
+	if len(items) > diagnosticsHistory {
+		items = items[len(items)-diagnosticsHistory:]
+	}
+	return items
+}
+
+// rateWindowSeconds は毎秒の行数を平均する期間（秒）です
+const rateWindowSeconds = 60
+
+// rateWindow は直近 rateWindowSeconds 秒の件数を1秒ごとに保持します
+type rateWindow struct {
+	counts  [rateWindowSeconds]int64
+	seconds [rateWindowSeconds]int64 // counts の各要素が表す時刻（Unix秒）
+}
+
+// add は now の秒に件数を加算します
+func (w *rateWindow) add(now time.Time, n int) {
+	sec := now.Unix()
+	i := sec % rateWindowSeconds
+	if w.seconds[i] != sec {
+		w.seconds[i], w.counts[i] = sec, 0
+	}
+	w.counts[i] += int64(n)
+}
+
+// perSecond は直近 rateWindowSeconds 秒の平均の毎秒件数を返します
+func (w *rateWindow) perSecond(now time.Time) float64 {
+	sec := now.Unix()
+	var total int64
+	for i, s := range w.seconds {
+		if sec-s < rateWindowSeconds {
+			total += w.counts[i]
+		}
+	}
+	return float64(total) / rateWindowSeconds
+}
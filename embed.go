@@ -0,0 +1,138 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+// embeddedName はコレクターを介さずに使用する場合のコンポーネント名です（診断情報・lifecycle_events の識別に使用）
+const embeddedName = "embedded"
+
+// DefaultConfig はコレクターの設定と同じ既定値の Config を返します
+// NewTracesWriter などに渡す前に endpoint などを設定してください
+func DefaultConfig() *Config {
+	return createDefaultConfig().(*Config)
+}
+
+// embeddedSettings はコレクターを介さずにエクスポーターを作成するための設定を返します
+// 内部メトリクスはグローバルの MeterProvider（otel.SetMeterProvider で設定したもの）に記録します
+func embeddedSettings(logger *zap.Logger) exporter.Settings {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return exporter.Settings{
+		ID: component.NewIDWithName(component.MustNewType(typeStr), embeddedName),
+		TelemetrySettings: component.TelemetrySettings{
+			Logger:         logger,
+			MeterProvider:  otel.GetMeterProvider(),
+			TracerProvider: otel.GetTracerProvider(),
+			Resource:       pcommon.NewResource(),
+		},
+		BuildInfo: component.NewDefaultBuildInfo(),
+	}
+}
+
+// embeddedHost - コレクターを介さずに使用する場合の component.Host（拡張機能を持たない）
+// state_storage など拡張機能を参照する設定は使用できません
+type embeddedHost struct{}
+
+func (embeddedHost) GetExtensions() map[component.ID]component.Component {
+	return nil
+}
+
+// TracesWriter はコレクターを実行せずに、Go のサービスから直接トレースを ClickHouse に保存します
+// exporterhelper を介さないため、sending_queue と retry_on_failure は適用されません（エラーは呼び出し元で扱う）
+// メトリクス・プロファイルの書き込み先は提供していません（コレクターのエクスポーターとして使用してください）
+type TracesWriter struct {
+	exporter *tracesExporter
+}
+
+// NewTracesWriter は cfg を検証してトレースの書き込み先を作成・開始します（create_schema 有効時はテーブルも作成される）
+// 使用後は Close で停止してください
+func NewTracesWriter(cfg *Config, logger *zap.Logger, opts ...WriterOption) (*TracesWriter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("設定が不正です: %w", err)
+	}
+	o, err := newWriterOptions(cfg, "traces", opts)
+	if err != nil {
+		return nil, fmt.Errorf("オプションが不正です: %w", err)
+	}
+	exp, err := newTracesExporter(embeddedSettings(logger), cfg)
+	if err != nil {
+		return nil, err
+	}
+	exp.transformer = o.transformer
+	if err := exp.start(context.Background(), embeddedHost{}); err != nil {
+		_ = exp.shutdown(context.Background())
+		return nil, err
+	}
+	return &TracesWriter{exporter: exp}, nil
+}
+
+// WriteTraces はトレースを保存します
+// 設定によってはフィルタなどでデータを変更するため、その場合は td のコピーを処理します（td は変更されない）
+func (w *TracesWriter) WriteTraces(ctx context.Context, td ptrace.Traces) error {
+	if w.exporter.config.mutatesData("traces") {
+		clone := ptrace.NewTraces()
+		td.CopyTo(clone)
+		td = clone
+	}
+	return w.exporter.pushTraces(ctx, td)
+}
+
+// Close は書き込み先を停止し、DB接続を解放します
+func (w *TracesWriter) Close(ctx context.Context) error {
+	return w.exporter.shutdown(ctx)
+}
+
+// LogsWriter はコレクターを実行せずに、Go のサービスから直接ログを ClickHouse に保存します（TracesWriter のログ版）
+type LogsWriter struct {
+	exporter *logsExporter
+}
+
+// NewLogsWriter は cfg を検証してログの書き込み先を作成・開始します
+func NewLogsWriter(cfg *Config, logger *zap.Logger, opts ...WriterOption) (*LogsWriter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("設定が不正です: %w", err)
+	}
+	o, err := newWriterOptions(cfg, "logs", opts)
+	if err != nil {
+		return nil, fmt.Errorf("オプションが不正です: %w", err)
+	}
+	exp, err := newLogsExporter(embeddedSettings(logger), cfg)
+	if err != nil {
+		return nil, err
+	}
+	exp.transformer = o.transformer
+	if err := exp.start(context.Background(), embeddedHost{}); err != nil {
+		_ = exp.shutdown(context.Background())
+		return nil, err
+	}
+	return &LogsWriter{exporter: exp}, nil
+}
+
+// WriteLogs はログを保存します（ld は変更されない）
+func (w *LogsWriter) WriteLogs(ctx context.Context, ld plog.Logs) error {
+	if w.exporter.config.mutatesData("logs") {
+		clone := plog.NewLogs()
+		ld.CopyTo(clone)
+		ld = clone
+	}
+	return w.exporter.pushLogs(ctx, ld)
+}
+
+// Close は書き込み先を停止し、DB接続を解放します
+func (w *LogsWriter) Close(ctx context.Context) error {
+	return w.exporter.shutdown(ctx)
+}
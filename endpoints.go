@@ -0,0 +1,195 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"go.uber.org/zap"
+)
+
+// LoadBalancingConfig - 複数エンドポイント指定時の負荷分散とフェイルオーバーの設定
+// endpoint にカンマ区切りまたはリストで複数のエンドポイントを指定すると、新しい接続をラウンドロビンで
+// 各エンドポイントに割り振り、接続できないエンドポイントは健全性確認で回復するまで除外します
+// 挿入は接続プールの接続を再利用するため、同時に実行される挿入（sending_queue.num_consumers）が各エンドポイントに分散されます
+type LoadBalancingConfig struct {
+	HealthCheckInterval time.Duration `mapstructure:"health_check_interval"` // 健全性確認の間隔
+	HealthCheckTimeout  time.Duration `mapstructure:"health_check_timeout"`  // 1エンドポイントあたりの健全性確認のタイムアウト
+}
+
+// validate は負荷分散の設定を検証します
+func (c LoadBalancingConfig) validate() error {
+	var errs error
+	if c.HealthCheckInterval <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("load_balancing.health_check_interval は0より大きい必要があります: %s", c.HealthCheckInterval))
+	}
+	if c.HealthCheckTimeout <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("load_balancing.health_check_timeout は0より大きい必要があります: %s", c.HealthCheckTimeout))
+	}
+	return errs
+}
+
+// endpoints は endpoint に指定されたエンドポイントの一覧を返します（カンマ区切り）
+func (cfg *Config) endpoints() []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(cfg.Endpoint, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// multiEndpoint は複数のエンドポイントが指定されているかどうかを返します
+func (cfg *Config) multiEndpoint() bool {
+	return len(cfg.endpoints()) > 1
+}
+
+// newFailoverConnector はエンドポイントごとの接続を負荷分散・フェイルオーバーする driver.Connector を作成します
+func newFailoverConnector(cfg *Config, database string, logger *zap.Logger) (*failoverConnector, error) {
+	c := &failoverConnector{config: cfg.LoadBalancing, logger: logger, done: make(chan struct{})}
+	for _, endpoint := range cfg.endpoints() {
+		single := *cfg
+		single.Endpoint = endpoint
+		dsn, err := buildDSN(&single, database)
+		if err != nil {
+			return nil, err
+		}
+		opts, err := clickhouse.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("エンドポイント %s のDSNが不正です: %w", endpoint, err)
+		}
+		e := &failoverEndpoint{address: endpoint, connector: clickhouse.Connector(opts)}
+		e.healthy.Store(true)
+		c.endpoints = append(c.endpoints, e)
+	}
+
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	go c.probe(ctx)
+	return c, nil
+}
+
+// failoverEndpoint は負荷分散対象のエンドポイントとその健全性です
+type failoverEndpoint struct {
+	address   string
+	connector driver.Connector
+	healthy   atomic.Bool
+}
+
+// failoverConnector - 健全なエンドポイントにラウンドロビンで接続する driver.Connector
+// 接続に失敗したエンドポイントは除外して次のエンドポイントに接続し、バックグラウンドの健全性確認で回復を検知します
+// 接続プールを閉じると（io.Closer）健全性確認を停止します
+type failoverConnector struct {
+	config    LoadBalancingConfig
+	endpoints []*failoverEndpoint
+	logger    *zap.Logger
+	next      atomic.Uint64
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Connect は健全なエンドポイントを順に試して接続します
+// 全ての健全なエンドポイントに接続できない場合は、除外中のエンドポイントも試します
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	// 試行順: ラウンドロビンの開始位置から健全なエンドポイント、続いて除外中のエンドポイント
+	start := int(c.next.Add(1) - 1)
+	order := make([]*failoverEndpoint, 0, len(c.endpoints))
+	var excluded []*failoverEndpoint
+	for i := range c.endpoints {
+		e := c.endpoints[(start+i)%len(c.endpoints)]
+		if e.healthy.Load() {
+			order = append(order, e)
+		} else {
+			excluded = append(excluded, e)
+		}
+	}
+
+	var errs error
+	for _, e := range append(order, excluded...) {
+		conn, err := e.connector.Connect(ctx)
+		if err == nil {
+			c.setHealthy(e, true, nil)
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, errors.Join(errs, err)
+		}
+		c.setHealthy(e, false, err)
+		errs = errors.Join(errs, fmt.Errorf("%s: %w", e.address, err))
+	}
+	return nil, fmt.Errorf("全てのエンドポイントへの接続に失敗しました: %w", errs)
+}
+
+func (c *failoverConnector) Driver() driver.Driver {
+	return c.endpoints[0].connector.Driver()
+}
+
+// Close は健全性確認を停止します（sql.DB.Close から呼び出されます）
+func (c *failoverConnector) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		<-c.done
+	})
+	return nil
+}
+
+// probe は定期的に各エンドポイントへの接続を確認し、健全性を更新します
+func (c *failoverConnector) probe(ctx context.Context) {
+	defer close(c.done)
+	ticker := time.NewTicker(c.config.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, e := range c.endpoints {
+			err := c.check(ctx, e)
+			if ctx.Err() != nil {
+				return
+			}
+			c.setHealthy(e, err == nil, err)
+		}
+	}
+}
+
+// check はエンドポイントに接続して疎通を確認します
+func (c *failoverConnector) check(ctx context.Context, e *failoverEndpoint) error {
+	ctx, cancel := context.WithTimeout(ctx, c.config.HealthCheckTimeout)
+	defer cancel()
+	conn, err := e.connector.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if pinger, ok := conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// setHealthy はエンドポイントの健全性を更新し、変化した場合はログに記録します
+func (c *failoverConnector) setHealthy(e *failoverEndpoint, healthy bool, err error) {
+	if e.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		c.logger.Info("エンドポイントが回復しました、負荷分散の対象に戻します", zap.String("endpoint", e.address))
+		return
+	}
+	c.logger.Warn("エンドポイントに接続できません、回復するまで負荷分散の対象から除外します",
+		zap.String("endpoint", e.address), zap.Error(err))
+}
@@ -6,76 +6,296 @@ package myexporter
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"strings"
+	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.uber.org/zap"
 
 	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/pdatarows"
+	"github.com/dtamura/myexporter/stable"
 )
 
 type logsExporter struct {
+	id     component.ID
 	config *Config
 	logger *zap.Logger
-	db     *sql.DB // DB接続（clickhouseexporterを参考）
+	db     *sql.DB      // DB接続（clickhouseexporterを参考）
+	diag   *diagnostics // zPages（expvarz）向けの診断情報
+
+	telemetry *exporterTelemetry // コレクターの内部テレメトリに公開するメトリクス
+	forwarder *otlpForwarder     // OTLP転送（passthrough.signals 指定時のみ）
+	kafka     *kafkaPublisher    // Kafkaへの発行（kafka.brokers 指定時のみ）
+	detailed  *detailedOutput    // 詳細モードの出力（log_format に応じた形式）
+	events    *lifecycleEvents   // ライフサイクルイベントの送信（lifecycle_events.endpoint 指定時のみ）
+	status    *componentStatus   // コレクターへの状態報告（start 以降）
+	summary   *pushSummary       // 処理完了ログの集計（summary_interval または live_reload 指定時のみ）
+	live      *liveConfig        // 再起動せずに変更できる設定の現在の値（live_reload）
+	debug     *debugSource       // デバッグエンドポイントへの登録（debug.endpoint 指定時のみ）
+	spool     *diskSpool         // DB障害時のディスク退避（spool.directory 指定時のみ）
+	tenants   *tenantRouter      // テナントごとの挿入先の振り分け（multi_tenancy.routing 有効時のみ）
+	targets   []*exportTarget    // 追加の書き込み先（targets 指定時のみ）
+	warmer    *cacheWarmer       // フラッシュ後のキャッシュの事前読み込み（cache_warming 有効時のみ）
+	source    *sourceStamp       // 行に付与する送信元メタデータ（source_columns 有効時のみ）
+	filter    *logFilter         // OTTL の条件式によるフィルタ（filter.logs 指定時のみ）
+
+	translator *schemaTranslator // スキーマ変換（schema_translation 有効時のみ）
+	capture    *batchCapture     // 挿入バッチのキャプチャ（capture.directory 指定時のみ）
+	breaker    *circuitBreaker   // 実行中のDB障害時の縮退制御（circuit_breaker 有効時のみ）
+	schema     *schemaCache      // 挿入先テーブルの列定義（DB接続時のみ）
+	native     clickhouse.Conn   // ネイティブバッチ挿入用の接続（use_native_batch 有効時のみ）
+	embedder   *bodyEmbedder     // ログ本文の埋め込みベクトルの計算（log_embeddings 指定時のみ）
+
+	transformer RowTransformer // 挿入直前の行の変換（LogsWriter に WithRowTransformer で登録した場合のみ、既定は何もしない）
 }
 
 // newLogsExporter はログエクスポーターの新しいインスタンスを作成します
-func newLogsExporter(logger *zap.Logger, cfg *Config) (*logsExporter, error) {
+func newLogsExporter(set exporter.Settings, cfg *Config) (*logsExporter, error) {
+	logger := set.Logger
 	var db *sql.DB
-	var err error
 
-	// DB接続が設定されている場合のみ接続を確立
-	if cfg.Endpoint != "" {
-		db, err = buildDBConnection(cfg)
+	filter, err := newLogFilter(cfg.Filter, set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	// 転送対象のシグナルはDBに保存せずOTLPで転送する
+	forwarder, err := newOTLPForwarder(cfg.Passthrough, "logs", logger)
+	if err != nil {
+		return nil, err
+	}
+	kafka, err := newKafkaPublisher(cfg.Kafka, "logs", logger)
+	if err != nil {
+		_ = forwarder.shutdown()
+		return nil, err
+	}
+	spool, err := newDiskSpool(cfg.Spool, "logs", logger)
+	if err != nil {
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		return nil, err
+	}
+
+	// DB接続が設定されている場合のみ接続を確立（転送対象・Kafkaにのみ発行するシグナルは接続しない）
+	if cfg.Endpoint != "" && forwarder == nil && !cfg.Kafka.replacesDatabase("logs") {
+		db, err = acquireDBConnection(cfg, logger)
 		if err != nil {
 			logger.Warn("データベース接続に失敗しました、ログ出力のみモードにフォールバックします", zap.Error(err))
 		}
+		warnUnsafeAsyncInsert(cfg, logger)
+		warnDeprecatedTTLDays(cfg, logger)
 	}
 
+	events, err := newLifecycleEvents(cfg.LifecycleEvents, set, "logs", logger)
+	if err != nil {
+		if db != nil {
+			_ = releaseDBConnection(db)
+		}
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		return nil, err
+	}
+
+	telemetry, err := newExporterTelemetry(set.TelemetrySettings, "logs", db, cfg.Utilization)
+	if err != nil {
+		if db != nil {
+			_ = releaseDBConnection(db)
+		}
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		_ = events.shutdown()
+		return nil, fmt.Errorf("内部メトリクスの作成に失敗しました: %w", err)
+	}
+
+	live := newLiveConfig(cfg, logger)
 	return &logsExporter{
+		id:     set.ID,
 		config: cfg,
 		logger: logger,
 		db:     db, // DB接続がない場合はnil
+		diag:   getDiagnostics(set.ID),
+
+		telemetry: telemetry,
+		forwarder: forwarder,
+		kafka:     kafka,
+		detailed:  newDetailedOutput(cfg.LogFormat, logger),
+		events:    events,
+		live:      live,
+		summary:   newPushSummary(live, logger, "ログ処理のサマリー", "resource_logs", "total_logs"),
+		spool:     spool,
+		tenants:   newTenantRouter(cfg),
+		source:    newSourceStamp(cfg.SourceColumns, set),
+		filter:    filter,
+		capture:   newBatchCapture(cfg.Capture, logger),
+		breaker:   newCircuitBreaker(cfg.CircuitBreaker, "logs", db, events, logger),
+
+		transformer: nopRowTransformer{},
 	}, nil
 }
 
 // Capabilities はログエクスポーターの機能を返します
 func (e *logsExporter) Capabilities() consumer.Capabilities {
-	return consumer.Capabilities{MutatesData: false}
+	return consumer.Capabilities{MutatesData: e.config.mutatesData("logs")}
 }
 
 // start はエクスポーター開始時に呼び出されます
 // DB接続テスト、データベース作成、テーブル作成を実行
-func (e *logsExporter) start(ctx context.Context, host component.Host) error {
+func (e *logsExporter) start(ctx context.Context, host component.Host) (err error) {
+	// データベース・テーブルの作成などは start_timeout 以内に終わらない場合は中断する
+	ctx, cancel := e.config.startContext(ctx)
+	defer cancel()
+	defer func() { err = startError(ctx, e.config, err) }()
+
 	e.logger.Info("ログエクスポーターを開始しています",
-		zap.String("prefix", e.config.Prefix),
+		zap.String("prefix", e.live.load().Prefix),
 		zap.Bool("db_enabled", e.db != nil),
 	)
 
+	e.status = newComponentStatus(host)
+
+	// 起動に失敗した場合の調査にも使えるよう、DB接続より前にデバッグエンドポイントへ登録する
+	if e.config.Debug.Endpoint != "" {
+		e.debug = &debugSource{id: e.id, signal: "logs", db: e.db, diag: e.diag, spool: e.spool}
+		if err := registerDebug(e.config.Debug.Endpoint, e.debug, e.logger); err != nil {
+			e.logger.Error("デバッグエンドポイントの起動に失敗しました", zap.Error(err))
+			return err
+		}
+	}
+
+	// スキーマ変換が有効な場合は変換先スキーマファイルを読み込む
+	if e.config.SchemaTranslation.Enabled {
+		translator, err := newSchemaTranslator(ctx, e.config.SchemaTranslation, e.logger)
+		if err != nil {
+			e.logger.Error("スキーマ変換の初期化に失敗しました", zap.Error(err))
+			return err
+		}
+		e.translator = translator
+	}
+
+	// ログ本文の埋め込みベクトルの計算（log_embeddings 指定時のみ、拡張の参照に host が必要）
+	embedder, err := newBodyEmbedder(e.config.LogEmbeddings, host)
+	if err != nil {
+		e.logger.Error("ログ本文の埋め込みの初期化に失敗しました", zap.Error(err))
+		return err
+	}
+	e.embedder = embedder
+
+	// スキーマ作成のドライランが有効な場合は、DDLを実行せずにログまたはファイルへ出力
+	if err := dryRunSchemaDDL(e.config, e.logger); err != nil {
+		e.logger.Error("DDLのドライラン出力に失敗しました", zap.Error(err))
+		return err
+	}
+
 	// DB接続が有効な場合、データベース・テーブル作成と接続テストを実行
 	if e.db != nil {
 		// 1. データベース作成
-		if err := createDatabase(ctx, e.config, e.logger); err != nil {
+		if err := createDatabase(ctx, e.config, e.config.logsDatabase(), e.logger); err != nil {
 			e.logger.Error("データベース作成に失敗しました", zap.Error(err))
 			return err
 		}
 
-		// 2. ログテーブル作成
-		if err := e.createLogsTable(ctx); err != nil {
-			e.logger.Error("ログテーブル作成に失敗しました", zap.Error(err))
-			return err
+		// 2. ログテーブル作成（create_schema が無効またはドライランの場合はスキップ）
+		if e.config.shouldCreateSchema() {
+			// 設定で使用する型をサーバーがサポートしているか確認
+			if err := checkServerVersion(ctx, e.config, e.db); err != nil {
+				e.logger.Error("サーバーのバージョン確認に失敗しました", zap.Error(err))
+				e.diag.recordError("logs", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			if err := e.createLogsTable(ctx); err != nil {
+				e.logger.Error("ログテーブル作成に失敗しました", zap.Error(err))
+				e.diag.recordError("logs", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			// 既存テーブルに未適用のスキーマ変更を適用
+			if err := applyMigrations(ctx, e.config, e.db, "logs", e.config.logsDatabase(), e.config.localTable(e.getLogsTableName()), e.logger); err != nil {
+				e.logger.Error("マイグレーションの適用に失敗しました", zap.Error(err))
+				e.diag.recordError("logs", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			// 容量の大きい列に列TTLを設定（column_ttl 指定時のみ）
+			if err := applyColumnTTL(ctx, e.config, "logs", e.db, e.logger); err != nil {
+				e.logger.Error("列TTLの設定に失敗しました", zap.Error(err))
+				e.status.permanent(err)
+				return err
+			}
+
+			// 3. テナントごとの行ポリシー作成（マルチテナント有効時のみ）
+			if err := createRowPolicies(ctx, e.config, "logs", e.db, e.logger); err != nil {
+				e.logger.Error("行ポリシー作成に失敗しました", zap.Error(err))
+				e.status.permanent(err)
+				return err
+			}
+
+			// イベント送信が有効な場合はスキーマの作成を通知
+			e.events.schemaCreated(e.config.logsDatabase())
 		}
 
-		// 3. 接続テスト
-		if err := e.db.Ping(); err != nil {
+		// 4. 接続テスト
+		if err := e.db.PingContext(ctx); err != nil {
 			e.logger.Error("データベースへの接続テストに失敗しました", zap.Error(err))
 			return err
 		}
 		e.logger.Info("データベース接続とテーブル作成に成功しました")
+		e.status.ok()
+
+		// use_native_batch 有効時はネイティブバッチ用の接続を作成
+		native, err := openNativeConn(e.config)
+		if err != nil {
+			e.logger.Error("ネイティブバッチ用の接続の作成に失敗しました", zap.Error(err))
+			return err
+		}
+		e.native = native
+
+		// 最初の送信で接続確立の遅延が発生しないよう、最小数の接続を確立しておく（失敗しても送信時に再接続する）
+		if err := e.config.ConnectionPool.prewarm(ctx, e.db); err != nil {
+			e.logger.Warn("接続プールの事前接続に失敗しました", zap.Error(err))
+		}
+
+		// 挿入先テーブルの列定義は最初の挿入時に読み込み、テーブルが直接変更された場合は読み直す
+		e.schema = newSchemaCache(e.config, e.db, e.config.logsDatabase(), e.getLogsTableName(), e.config.expectedColumns("logs"), e.logger)
+		if err := registerSchemaRefresh(e.config.SchemaRefresh.Endpoint, e.schema, e.logger); err != nil {
+			e.logger.Error("テーブル定義の再読み込みエンドポイントの起動に失敗しました", zap.Error(err))
+			return err
+		}
+
+		// スキーマを作成しない場合は、挿入時のエラーではなく開始時に列の不足を検出する
+		if e.config.shouldVerifySchema() {
+			if err := e.schema.verify(ctx); err != nil {
+				e.logger.Error("テーブル定義の確認に失敗しました", zap.Error(err))
+				e.diag.recordError("logs", err)
+				e.status.permanent(err)
+				return err
+			}
+		}
+
+		// ダッシュボードの初回表示向けに、大きなフラッシュの後に最新のパーティションを読み込む
+		e.warmer = newCacheWarmer(e.config, e.db, e.config.logsDatabase(), e.getLogsTableName(), e.config.logsTimeColumn(), e.logger)
+
+		// 追加の書き込み先への接続とスキーマ作成（targets 指定時のみ）
+		if err := e.startTargets(ctx); err != nil {
+			e.logger.Error("追加の書き込み先の開始に失敗しました", zap.Error(err))
+			e.status.permanent(err)
+			return err
+		}
+	}
+
+	// スプールが有効な場合は退避したセグメントの再挿入を開始（前回の実行で残ったセグメントも対象）
+	if e.spool != nil && e.db != nil {
+		e.spool.startReplay(e.replaySpooledLogs)
 	}
 
 	return nil
@@ -85,19 +305,81 @@ func (e *logsExporter) start(ctx context.Context, host component.Host) error {
 // clickhouseexporterのshutdown関数を参考
 func (e *logsExporter) shutdown(ctx context.Context) error {
 	e.logger.Info("ログエクスポーターを終了しています")
-
+	e.diag.logSummary("logs", e.logger)
+
+	// 再挿入ループを停止してから接続を解放する
+	e.spool.shutdown()
+	e.kafka.shutdown()
+	e.live.shutdown()
+	e.summary.shutdown()
+	e.warmer.shutdown()
+	telemetryErr := errors.Join(e.telemetry.shutdown(), e.forwarder.shutdown(), e.events.shutdown(),
+		unregisterSchemaRefresh(e.config.SchemaRefresh.Endpoint, e.schema), unregisterDebug(e.config.Debug.Endpoint, e.debug))
+
+	// 共有接続プールの参照を解放（最後の参照の場合のみ接続を閉じる）
+	telemetryErr = errors.Join(telemetryErr, closeExportTargets(e.targets), closeNativeConn(e.native))
 	if e.db != nil {
-		return e.db.Close()
+		return errors.Join(telemetryErr, releaseDBConnection(e.db))
 	}
 
-	return nil
+	return telemetryErr
 }
 
 // pushLogs はログデータを受信して処理します
 // exporterhelper経由で呼び出される実際のログデータ処理関数
 // エラーが返された場合、exporterhelperが自動的にリトライやエラー処理を行う
 func (e *logsExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
+	// 最小重要度未満のログレコードを破棄（出力・挿入の対象外）
+	// filter_preview 有効時は破棄される件数のみを記録し、レコードは保持する
+	if minSeverity := e.config.minSeverityNumber(); minSeverity != plog.SeverityNumberUnspecified && e.config.FilterPreview {
+		if count := countLogsBelowSeverity(ld, minSeverity); count > 0 {
+			e.diag.recordPreview(previewRuleMinSeverity, previewActionDropped, count)
+			e.telemetry.recordPreview(ctx, previewRuleMinSeverity, previewActionDropped, count)
+			e.logger.Debug("最小重要度未満のログレコードがあります（filter_preview のため破棄しません）",
+				zap.Int("would_drop", count),
+				zap.String("min_severity", e.config.MinSeverity))
+		}
+	} else if minSeverity != plog.SeverityNumberUnspecified {
+		if filtered := filterLogsBySeverity(ld, minSeverity); filtered > 0 {
+			e.diag.recordFiltered("logs", filtered)
+			e.diag.recordDropped("logs", stable.DropReasonMinSeverity, filtered)
+			e.logger.Debug("最小重要度未満のログレコードを破棄しました",
+				zap.Int("filtered", filtered),
+				zap.String("min_severity", e.config.MinSeverity))
+		}
+	}
+	// filter.logs の条件に従ってログレコードを破棄（filter_preview 有効時は破棄される件数のみを記録）
+	filtered, err := filterLogs(ctx, e.filter, ld, !e.config.FilterPreview)
+	if err := reportFilter(ctx, e.config, e.diag, e.telemetry, e.events, e.logger, "logs", filtered, err); err != nil {
+		return err
+	}
+
+	// 診断情報にフラッシュ結果を記録
+	finishFlush := e.diag.beginFlush("logs", e.getLogsTableName(), ld.LogRecordCount())
+	// 使用率メトリクス向けに処理中のデータ量と処理時間を記録
+	defer e.telemetry.beginPush((&plog.ProtoMarshaler{}).LogsSize(ld))()
+
+	// 古いセマンティック規約バージョンの属性を変換先バージョンに更新
+	if e.translator != nil {
+		e.translator.translateLogs(ld)
+	}
+
+	// 1つのサービスがバッチの大半を占める場合は、上限を超えたログレコードの挿入を後回しにする
+	deferred := plog.NewLogs()
+	if e.forwarder == nil && e.db != nil && e.config.Fairness.MaxServiceShare > 0 {
+		deferred = deferLogsOverShare(e.config.Fairness, ld)
+		if n := deferred.LogRecordCount(); n > 0 {
+			e.telemetry.recordDeferred(ctx, n)
+			e.logger.Debug("サービスの割合の上限を超えたログレコードの挿入を後回しにしました",
+				zap.Int("deferred", n),
+				zap.Float64("max_service_share", e.config.Fairness.MaxServiceShare))
+		}
+	}
+
 	resourceLogs := ld.ResourceLogs()
+	// 詳細モードで出力するデータをサンプリング（detailed_sampling 未指定の場合は全件）
+	live := e.live.load() // 送信中は同じ値を使用する
+	sampler := newDetailedSampler(live)
 	totalLogs := 0
 	var processingErr error
 
@@ -111,10 +393,17 @@ func (e *logsExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
 			totalLogs += logRecords.Len()
 
 			// 詳細モードが有効な場合、各ログレコードの詳細情報をログ出力
-			if e.config.Detailed {
+			if live.Detailed {
 				for k := 0; k < logRecords.Len(); k++ {
+					if !sampler.sample() {
+						continue
+					}
 					lr := logRecords.At(k)
-					e.logger.Info(fmt.Sprintf("%s ログを受信しました", e.config.Prefix),
+					if e.detailed.otlp() {
+						e.detailed.writeLogRecord(rl, sl, lr)
+						continue
+					}
+					e.detailed.logger.Info(fmt.Sprintf("%s ログを受信しました", live.Prefix),
 						zap.String("severity", lr.SeverityText()),
 						zap.String("body", lr.Body().AsString()),
 						zap.Time("timestamp", lr.Timestamp().AsTime()),
@@ -122,85 +411,310 @@ func (e *logsExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
 				}
 			}
 
-			// 現在はデータ投入を無効化（DB接続テストのみ）
-			// TODO: 将来的にデータ投入機能を実装予定
-			//
-			// デモ目的：意図的にエラーをシミュレートしてメトリクスを生成
+			// DB未接続（ログ出力のみモード）の場合のデモ目的：意図的にエラーをシミュレートしてメトリクスを生成
 			// 8%の確率でエラーを発生させる（メトリクス確認用）
-			if i%12 == 5 {
+			if e.db == nil && e.forwarder == nil && e.kafka == nil && i%12 == 5 {
 				processingErr = fmt.Errorf("デモエラー: ログ処理でシミュレートされたエラー (resource %d)", i)
 				e.logger.Warn("ログ検証用のシミュレートエラー", zap.Error(processingErr))
 			}
 		}
 	}
 
+	// DB接続が有効な場合（またはキャプチャが有効な場合）はログレコードをClickHouseに挿入
+	// サーキットブレーカーがオープンの間は挿入せず、ログ出力のみモードで動作する
+	// 転送対象の場合はDBに保存せずOTLPで転送する
+	if e.forwarder != nil {
+		if err := e.forwarder.forwardLogs(ctx, ld); err != nil {
+			processingErr = err
+			e.logger.Error("ログの転送に失敗しました", zap.Error(err))
+		}
+	} else if e.db != nil || e.capture != nil {
+		// 追加の書き込み先への挿入はバッチを変更しないため、endpoint と同じバッチをすべて書き込む
+		targetErr := e.writeTargets(ctx, ld)
+		if e.breaker.allow(ctx) {
+			rest, err := e.insertRoutedLogs(ctx, ld)
+			e.breaker.record(err)
+			e.status.recordInsert(err)
+			if err != nil {
+				// リトライ・スプールの対象は挿入できなかった残りのみ（挿入に成功した分割・テナントを重複させない）
+				retainLogs(ld, rest)
+			}
+			if err == nil {
+				e.warmer.afterFlush(ld.LogRecordCount())
+			} else {
+				e.logger.Error("ログの挿入に失敗しました", zap.Error(err))
+				if consumererror.IsPermanent(err) {
+					e.events.batchDropped(stable.DropReasonPermanentError, ld.LogRecordCount())
+					e.diag.recordDropped("logs", stable.DropReasonPermanentError, ld.LogRecordCount())
+				}
+				processingErr = e.spoolLogs(ld, err)
+			}
+		} else if e.spool != nil {
+			processingErr = e.spoolLogs(ld, nil)
+		} else {
+			e.logger.Warn("サーキットブレーカーがオープンのためログの挿入をスキップしました",
+				zap.Int("dropped_items", ld.LogRecordCount()))
+			e.events.batchDropped(stable.DropReasonCircuitBreakerOpen, ld.LogRecordCount())
+			e.diag.recordDropped("logs", stable.DropReasonCircuitBreakerOpen, ld.LogRecordCount())
+		}
+		processingErr = errors.Join(processingErr, targetErr)
+	}
+
+	// Kafka出力が有効な場合はデータベースへの保存とは独立して発行する
+	// 発行に失敗した場合はexporterhelperがリトライする（copy モードではデータベースへの挿入も再実行される）
+	if e.kafka != nil {
+		if err := e.kafka.publishLogs(ctx, ld); err != nil {
+			processingErr = errors.Join(processingErr, err)
+			e.logger.Error("ログのKafkaへの発行に失敗しました", zap.Error(err))
+		}
+	}
+
 	// 処理したログデータのサマリーをログ出力
-	e.logger.Info(fmt.Sprintf("%s ログ処理が完了しました", e.config.Prefix),
-		zap.Int("resource_logs", resourceLogs.Len()),
-		zap.Int("total_logs", totalLogs),
-		zap.Bool("db_connected", e.db != nil),
-		zap.Bool("has_error", processingErr != nil),
-	)
+	if e.summary.active() {
+		e.summary.record(processingErr, resourceLogs.Len(), totalLogs)
+	} else {
+		e.logger.Info(fmt.Sprintf("%s ログ処理が完了しました", live.Prefix),
+			zap.Int("resource_logs", resourceLogs.Len()),
+			zap.Int("total_logs", totalLogs),
+			zap.Bool("db_connected", e.db != nil),
+			zap.Bool("has_error", processingErr != nil),
+			sampler.field(),
+		)
+	}
+
+	finishFlush(processingErr)
+
+	// 後回しにしたログレコードのみをリトライで再送させる（他のエラーがある場合はバッチに戻して全体をリトライさせる）
+	if deferred.LogRecordCount() > 0 {
+		if processingErr == nil {
+			return consumererror.NewLogs(errFairnessDeferred, deferred)
+		}
+		deferred.ResourceLogs().MoveAndAppendTo(ld.ResourceLogs())
+	}
 
 	// エラーがある場合はそれを返す（exporterhelperがFailedメトリクスを記録）
 	// エラーがない場合はnilを返す（exporterhelperがSentメトリクスを記録）
 	return processingErr
 }
 
-// insertLogToDB は将来実装予定のDB挿入機能
-// 現在は DB接続テストのみ実装
-// func (e *logsExporter) insertLogToDB(ctx context.Context, lr plog.LogRecord) error {
-// 	// TODO: ClickHouse用の挿入処理を実装
-// 	return nil
-// }
+// filterLogsBySeverity は minSeverity 未満のログレコードを削除し、削除した件数を返します
+// 重要度が未設定（SeverityNumberUnspecified）のレコードは判定できないため保持します
+// レコードがなくなったスコープ・リソースも削除します
+func filterLogsBySeverity(ld plog.Logs, minSeverity plog.SeverityNumber) int {
+	filtered := 0
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				if belowSeverity(lr, minSeverity) {
+					filtered++
+					return true
+				}
+				return false
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return filtered
+}
+
+// belowSeverity はログレコードの重要度が minSeverity 未満かどうかを判定します（未設定の場合は false）
+func belowSeverity(lr plog.LogRecord, minSeverity plog.SeverityNumber) bool {
+	severity := lr.SeverityNumber()
+	return severity != plog.SeverityNumberUnspecified && severity < minSeverity
+}
+
+// logInsertColumns は logs_insert.sql の列順です（insert_sql.logs の名前付きプレースホルダーにも使用）
+var logInsertColumns = pdatarows.LogColumns
+
+// insertLogs はログデータを1トランザクション（1バッチ）でClickHouseに挿入します
+// 属性は attributes_format に応じて Map または JSON に変換されます
+// キャプチャが有効な場合は挿入前のバッチをファイルに出力します（DB未接続の場合は出力のみ）
+// テーブル定義の不一致で失敗した場合は、列定義を読み直して1回だけ再挿入します（retryAfterReload）
+func (e *logsExporter) insertLogs(ctx context.Context, ld plog.Logs) error {
+	err := e.insertLogsOnce(ctx, ld)
+	if e.schema.retryAfterReload(err) {
+		err = e.insertLogsOnce(ctx, ld)
+	}
+	return err
+}
+
+// insertLogsOnce はログデータを1回挿入します（挿入の失敗時は invalidateOnError で列定義を無効化）
+func (e *logsExporter) insertLogsOnce(ctx context.Context, ld plog.Logs) (err error) {
+	columns := e.config.insertColumns("logs")
+	insert, err := renderInsertStatement("logs_insert.sql", e.config.insertTemplate("logs"), e.config.customInsertSQL("logs"),
+		columns, internal.TableTemplateData{
+			Database:      e.config.logsDatabase(),
+			Table:         e.getLogsTableName(),
+			SourceColumns: e.config.SourceColumns.Enabled,
+			BodyJSON:      e.config.Logs.ParseBodyJSON,
+			Truncated:     e.config.truncatedColumnEnabled("logs"),
+
+			EmbeddingDimensions: e.config.LogEmbeddings.dimensions(),
+			IngestTime:          e.config.RecordIngestTime,
+			ExtraColumns:        e.transformer.Columns(),
+		})
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "logs_insert.sql")
+		return err
+	}
+	// insert_sql の名前付きプレースホルダーは ? に展開されるため、PostgreSQLの番号付きに変換
+	if e.config.isPostgres() {
+		insert.sql = rebindPostgres(insert.sql)
+	}
+
+	// テーブルの属性カラムの実際の型に合わせてエンコードする（属性カラムが直接変更された場合に追従するため）
+	conv := pdatarows.NewConverter(e.config.rowOptions(e.schema.get(ctx).jsonAttributes("LogAttributes", e.config.jsonAttributes())))
+	rows, err := conv.Logs(ld)
+	if err != nil {
+		return err
+	}
+	if truncated := conv.TruncatedKeys(); truncated > 0 {
+		e.diag.recordTruncatedKeys("logs", truncated)
+		e.telemetry.recordTruncatedKeys(ctx, truncated)
+		e.logger.Debug("最大長を超えた属性キーを短縮しました",
+			zap.Int("truncated_keys", truncated),
+			zap.Int("max_attribute_key_length", e.config.MaxAttributeKeyLength))
+	}
+	if truncated := e.config.stampTruncated("logs", rows, conv.TruncatedRows()); truncated > 0 {
+		e.logger.Debug("最大長を超えた属性値・本文を短縮しました",
+			zap.Int("truncated_rows", truncated),
+			zap.Int("max_attribute_length", e.config.MaxAttributeLength),
+			zap.Int("max_body_length", e.config.MaxBodyLength))
+	}
+	e.source.stamp(ctx, rows)
+	e.config.Logs.stampBodyJSON(rows)
+	if err := e.embedder.embed(ctx, rows); err != nil {
+		// 埋め込みを計算できなかった行はゼロベクトルのまま挿入し、ログの保存は継続する
+		e.logger.Warn("ログ本文の埋め込みの計算に失敗しました", zap.Error(err))
+		e.diag.recordError("logs", err)
+	}
+	e.config.stampExportTime(rows)
+	if columns, err = transformRows(ctx, e.transformer, columns, rows); err != nil {
+		return err
+	}
+	e.capture.write(e.getLogsTableName(), insert.sql, columns, rows)
+	if e.db == nil {
+		return nil
+	}
+
+	// 挿入結果（行数、所要時間、エラー）を内部メトリクスに記録
+	start := time.Now()
+	defer func() {
+		e.telemetry.recordInsert(ctx, e.getLogsTableName(), len(rows), time.Since(start), err)
+		e.schema.invalidateOnError(err)
+	}()
+
+	// リトライで同じバッチが再送された場合に重複して保存されないよう、内容から重複排除トークンを求める
+	insert.deduplicationToken = e.config.logDeduplicationToken(e.config.logsDatabase(), e.getLogsTableName(), ld)
+	return insertRowsWithTimeout(ctx, e.config, e.db, e.native, insert, rows)
+}
+
+// spoolLogs は挿入できなかったログをスプールに退避します
+// 退避に成功した場合は再挿入で保存されるため nil を返し、スプールが無効または退避に失敗した場合は cause を返します
+func (e *logsExporter) spoolLogs(ld plog.Logs, cause error) error {
+	// 永続的なエラー（スキーマの不一致など）は再挿入しても成功しないため退避しない
+	if e.spool == nil || consumererror.IsPermanent(cause) {
+		return cause
+	}
+	payload, err := (&plog.ProtoMarshaler{}).MarshalLogs(ld)
+	if err == nil {
+		err = e.spool.write(payload)
+	}
+	if err != nil {
+		e.logger.Error("ログのスプールへの退避に失敗しました", zap.Error(err))
+		return errors.Join(cause, err)
+	}
+	e.logger.Warn("挿入できなかったログをスプールに退避しました、復旧後に再挿入します",
+		zap.Int("items", ld.LogRecordCount()), zap.Int("bytes", len(payload)))
+	return nil
+}
+
+// replaySpooledLogs はスプールのセグメントをログに復元して再挿入します
+func (e *logsExporter) replaySpooledLogs(ctx context.Context, payload []byte) error {
+	ld, err := (&plog.ProtoUnmarshaler{}).UnmarshalLogs(payload)
+	if err != nil {
+		// 破損したセグメントは再挿入できないため、ログに記録して破棄する
+		e.logger.Error("スプールのセグメントを復元できないため破棄します", zap.Error(err))
+		return nil
+	}
+	if !e.breaker.allow(ctx) {
+		return fmt.Errorf("サーキットブレーカーがオープンのため再挿入を延期します")
+	}
+	_, err = e.insertRoutedLogs(ctx, ld)
+	e.breaker.record(err)
+	e.status.recordInsert(err)
+	if consumererror.IsPermanent(err) {
+		// 再挿入しても成功しないため、ログに記録して破棄する
+		e.logger.Error("スプールのセグメントを再挿入できないため破棄します", zap.Error(err))
+		e.diag.recordDropped("logs", stable.DropReasonPermanentError, ld.LogRecordCount())
+		return nil
+	}
+	return err
+}
 
 // createLogsTable は包括的なスキーマと最適化を持つログテーブルをClickHouseに作成します
 func (e *logsExporter) createLogsTable(ctx context.Context) error {
-	// ログテーブル作成用のSQLテンプレートを読み込み
-	sqlTemplate, err := internal.LoadSQLTemplate("logs_table.sql")
-	if err != nil {
-		return fmt.Errorf("ログテーブルSQLテンプレートの読み込みに失敗しました: %w", err)
+	if e.config.isPostgres() {
+		return createPostgresTables(ctx, e.config, e.db, "logs", e.logger)
 	}
 
 	// 設定パラメータでSQLテンプレートをレンダリング
-	sql := e.renderLogsTableSQL(sqlTemplate)
+	sql, err := e.renderLogsTableSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "logs_table.sql")
+		return fmt.Errorf("ログテーブルSQLのレンダリングに失敗しました: %w", err)
+	}
+
+	// ベクトル索引は ClickHouse 25.8 より前のバージョンでは実験的機能のため、作成時のみ設定で許可する
+	if e.config.LogEmbeddings.indexDistance() != "" {
+		ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+			"allow_experimental_vector_similarity_index": 1,
+		}))
+	}
 
 	// テーブル作成SQLを実行
 	if err := e.executeSQL(ctx, sql); err != nil {
 		return fmt.Errorf("ログテーブルの作成に失敗しました: %w", err)
 	}
 
+	if err := createDistributedTable(ctx, e.config, e.db, e.config.logsDatabase(),
+		e.getLogsTableName(), e.config.localTable(e.getLogsTableName()), e.logger); err != nil {
+		return err
+	}
+	if err := createAggregationViews(ctx, e.config, e.db, e.telemetry, "logs", e.config.logsDatabase(), e.getLogsTableName(), e.logger); err != nil {
+		return err
+	}
+
 	e.logger.Info("ログテーブルが正常に作成されました",
 		zap.String("table", e.getLogsTableName()),
-		zap.String("database", e.config.Database))
+		zap.String("database", e.config.logsDatabase()))
 	return nil
 }
 
 // renderLogsTableSQL は設定値でログテーブルSQLテンプレートをレンダリングします
-func (e *logsExporter) renderLogsTableSQL(template string) string {
-	// 実際の設定値でテンプレートパラメータを置換
-	// テンプレートは順番に置換される %s プレースホルダーを使用:
-	// 1. データベース名
-	// 2. テーブル名
-	// 3. クラスター句（該当する場合）
-	// 4. エンジン句
-	// 5. TTL句（設定されている場合）
-
-	replacements := []string{
-		e.config.Database,         // Database name
-		e.getLogsTableName(),      // Table name
-		e.buildClusterClause(),    // Cluster clause
-		e.buildLogsEngineClause(), // Engine clause
-		e.buildTTLClause(),        // TTL clause
-	}
-
-	// 順番に置換を適用
-	sql := template
-	for _, replacement := range replacements {
-		sql = strings.Replace(sql, "%s", replacement, 1)
-	}
-
-	return sql
+func (e *logsExporter) renderLogsTableSQL() (string, error) {
+	return internal.RenderSQLTemplate("logs_table.sql", internal.TableTemplateData{
+		Database: e.config.logsDatabase(),
+		Table:    e.config.localTable(e.getLogsTableName()),
+		Cluster:  e.buildClusterClause(),
+		Engine:   e.buildLogsEngineClause(),
+		OrderBy:  e.config.logsOrderBy(),
+		TTL:      e.buildTTLClause(),
+		Settings: e.config.tableSettings(),
+
+		TimeColumn: e.config.logsTimeColumn(),
+
+		AttributesType: e.config.attributesColumnType(),
+		JSONAttributes: e.config.jsonAttributes(),
+		SourceColumns:  e.config.SourceColumns.Enabled,
+		BodyJSON:       e.config.Logs.ParseBodyJSON,
+		Truncated:      e.config.truncatedColumnEnabled("logs"),
+
+		EmbeddingDimensions: e.config.LogEmbeddings.dimensions(),
+		EmbeddingDistance:   e.config.LogEmbeddings.indexDistance(),
+		IngestTime:          e.config.RecordIngestTime,
+	})
 }
 
 // getLogsTableName は適切なフォールバックを持つ設定済みログテーブル名を返します
@@ -214,11 +728,15 @@ func (e *logsExporter) getLogsTableName() string {
 // buildLogsEngineClause はログテーブル用のClickHouseエンジン句を構築します
 func (e *logsExporter) buildLogsEngineClause() string {
 	switch {
+	case e.config.shardedTables() || e.config.ClickHouseCloud:
+		// レプリケーション・分散テーブル構成ではローカルテーブルのエンジン（分散テーブルは別途作成）
+		// ClickHouse Cloud では SharedMergeTree
+		return e.config.replicatedEngine("MergeTree()")
 	case e.config.ClusterName != "":
 		// クラスター展開用の分散エンジン
 		// 分散書き込み用に各シャードのローカルテーブルを指定
 		return fmt.Sprintf("Distributed(%s, %s, %s_local, rand())",
-			e.config.ClusterName, e.config.Database, e.getLogsTableName())
+			e.config.ClusterName, e.config.logsDatabase(), e.getLogsTableName())
 	default:
 		// シングルノード展開用のMergeTreeエンジン
 		// 自動マージ機能を持つ時系列ログデータに最適
@@ -228,7 +746,7 @@ func (e *logsExporter) buildLogsEngineClause() string {
 
 // buildClusterClause はクラスター展開が設定されている場合にクラスター句を構築します
 func (e *logsExporter) buildClusterClause() string {
-	if e.config.ClusterName != "" {
+	if e.config.ClusterName != "" && !e.config.replicatedDatabase() {
 		return fmt.Sprintf("ON CLUSTER %s", e.config.ClusterName)
 	}
 	return ""
@@ -236,11 +754,8 @@ func (e *logsExporter) buildClusterClause() string {
 
 // buildTTLClause は自動データ期限切れ用のTTL句を構築します
 func (e *logsExporter) buildTTLClause() string {
-	if e.config.TTLDays > 0 {
-		// 自動クリーンアップ用にログタイムスタンプベースのTTL
-		return fmt.Sprintf("TTL toDateTime(Timestamp) + toIntervalDay(%d)", e.config.TTLDays)
-	}
-	return ""
+	// 自動クリーンアップ用に logs_primary_time の時刻列ベースのTTL
+	return internal.GenerateTTLExpr(e.config.signalTTL("logs"), fmt.Sprintf("toDateTime(%s)", e.config.logsTimeColumn()))
 }
 
 // executeSQL は適切なエラー処理とログ記録でSQL文を実行します
@@ -259,3 +774,38 @@ func (e *logsExporter) executeSQL(ctx context.Context, sql string) error {
 
 	return nil
 }
+
+// derive は設定とDB接続のみを差し替えたエクスポーターを作成します（テナント・追加の書き込み先への挿入用）
+// 診断情報・内部メトリクス・送信元メタデータなどは元のエクスポーターと共有します
+func (e *logsExporter) derive(cfg *Config, db *sql.DB, logger *zap.Logger) *logsExporter {
+	return &logsExporter{
+		config:    cfg,
+		logger:    logger,
+		db:        db,
+		diag:      e.diag,
+		telemetry: e.telemetry,
+		source:    e.source,
+		capture:   e.capture,
+		native:    e.native,
+		embedder:  e.embedder,
+
+		transformer: e.transformer,
+	}
+}
+
+// prepareSchema は挿入先のデータベースとテーブルを作成し、マイグレーションと列TTLを適用します（create_schema 有効時のみ）
+func (e *logsExporter) prepareSchema(ctx context.Context) error {
+	if !e.config.shouldCreateSchema() {
+		return nil
+	}
+	if err := createDatabase(ctx, e.config, e.config.logsDatabase(), e.logger); err != nil {
+		return err
+	}
+	if err := e.createLogsTable(ctx); err != nil {
+		return err
+	}
+	if err := applyMigrations(ctx, e.config, e.db, "logs", e.config.logsDatabase(), e.config.localTable(e.getLogsTableName()), e.logger); err != nil {
+		return err
+	}
+	return applyColumnTTL(ctx, e.config, "logs", e.db, e.logger)
+}
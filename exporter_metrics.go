@@ -6,76 +6,217 @@ package myexporter
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"strings"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.uber.org/zap"
 
 	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/internal/sqltemplates"
+	"github.com/dtamura/myexporter/pdatarows"
+	"github.com/dtamura/myexporter/stable"
 )
 
 type metricsExporter struct {
+	id     component.ID
 	config *Config
 	logger *zap.Logger
-	db     *sql.DB // DB接続（clickhouseexporterを参考）
+	db     *sql.DB      // DB接続（clickhouseexporterを参考）
+	diag   *diagnostics // zPages（expvarz）向けの診断情報
+
+	telemetry *exporterTelemetry // コレクターの内部テレメトリに公開するメトリクス
+	forwarder *otlpForwarder     // OTLP転送（passthrough.signals 指定時のみ）
+	kafka     *kafkaPublisher    // Kafkaへの発行（kafka.brokers 指定時のみ）
+	detailed  *detailedOutput    // 詳細モードの出力（log_format に応じた形式）
+	events    *lifecycleEvents   // ライフサイクルイベントの送信（lifecycle_events.endpoint 指定時のみ）
+	status    *componentStatus   // コレクターへの状態報告（start 以降）
+	summary   *pushSummary       // 処理完了ログの集計（summary_interval または live_reload 指定時のみ）
+	live      *liveConfig        // 再起動せずに変更できる設定の現在の値（live_reload）
+	debug     *debugSource       // デバッグエンドポイントへの登録（debug.endpoint 指定時のみ）
+	filter    *metricFilter      // OTTL の条件式によるフィルタ（filter.metrics 指定時のみ）
+	capture   *batchCapture      // 挿入バッチのキャプチャ（capture.directory 指定時のみ）
+	targets   []*exportTarget    // 追加の書き込み先（targets 指定時のみ）
+
+	dimensions *metricsDimensionsWriter // ディメンションテーブルへの書き込み（metrics_dimensions 有効時のみ）
 }
 
 // newMetricsExporter はメトリクスエクスポーターの新しいインスタンスを作成します
-func newMetricsExporter(logger *zap.Logger, cfg *Config) (*metricsExporter, error) {
+func newMetricsExporter(set exporter.Settings, cfg *Config) (*metricsExporter, error) {
+	logger := set.Logger
 	var db *sql.DB
-	var err error
 
-	// DB接続が設定されている場合のみ接続を確立
-	if cfg.Endpoint != "" {
-		db, err = buildDBConnection(cfg)
+	filter, err := newMetricFilter(cfg.Filter, set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	// 転送対象のシグナルはDBに保存せずOTLPで転送する
+	forwarder, err := newOTLPForwarder(cfg.Passthrough, "metrics", logger)
+	if err != nil {
+		return nil, err
+	}
+	kafka, err := newKafkaPublisher(cfg.Kafka, "metrics", logger)
+	if err != nil {
+		_ = forwarder.shutdown()
+		return nil, err
+	}
+
+	// DB接続が設定されている場合のみ接続を確立（転送対象・Kafkaにのみ発行するシグナルは接続しない）
+	if cfg.Endpoint != "" && forwarder == nil && !cfg.Kafka.replacesDatabase("metrics") {
+		db, err = acquireDBConnection(cfg, logger)
 		if err != nil {
 			logger.Warn("データベース接続に失敗しました、ログ出力のみモードにフォールバックします", zap.Error(err))
 		}
+		warnUnsafeAsyncInsert(cfg, logger)
+		warnDeprecatedTTLDays(cfg, logger)
+	}
+
+	events, err := newLifecycleEvents(cfg.LifecycleEvents, set, "metrics", logger)
+	if err != nil {
+		if db != nil {
+			_ = releaseDBConnection(db)
+		}
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		return nil, err
 	}
 
+	telemetry, err := newExporterTelemetry(set.TelemetrySettings, "metrics", db, cfg.Utilization)
+	if err != nil {
+		if db != nil {
+			_ = releaseDBConnection(db)
+		}
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		_ = events.shutdown()
+		return nil, fmt.Errorf("内部メトリクスの作成に失敗しました: %w", err)
+	}
+
+	live := newLiveConfig(cfg, logger)
 	return &metricsExporter{
+		id:     set.ID,
 		config: cfg,
 		logger: logger,
 		db:     db, // DB接続がない場合はnil
+		diag:   getDiagnostics(set.ID),
+
+		telemetry: telemetry,
+		forwarder: forwarder,
+		kafka:     kafka,
+		detailed:  newDetailedOutput(cfg.LogFormat, logger),
+		events:    events,
+		live:      live,
+		summary:   newPushSummary(live, logger, "メトリクス処理のサマリー", "resource_metrics", "total_metrics"),
+		filter:    filter,
+		capture:   newBatchCapture(cfg.Capture, logger),
+
+		dimensions: newMetricsDimensionsWriter(cfg, db),
 	}, nil
 }
 
 // Capabilities はメトリクスエクスポーターの機能を返します
 func (e *metricsExporter) Capabilities() consumer.Capabilities {
-	return consumer.Capabilities{MutatesData: false}
+	return consumer.Capabilities{MutatesData: e.config.mutatesData("metrics")}
 }
 
 // start はエクスポーター開始時に呼び出されます
 // DB接続テスト、データベース作成、メトリクステーブル作成を実行
-func (e *metricsExporter) start(ctx context.Context, host component.Host) error {
+func (e *metricsExporter) start(ctx context.Context, host component.Host) (err error) {
+	// データベース・テーブルの作成などは start_timeout 以内に終わらない場合は中断する
+	ctx, cancel := e.config.startContext(ctx)
+	defer cancel()
+	defer func() { err = startError(ctx, e.config, err) }()
+
 	e.logger.Info("メトリクスエクスポーターを開始しています",
-		zap.String("prefix", e.config.Prefix),
+		zap.String("prefix", e.live.load().Prefix),
 		zap.Bool("db_enabled", e.db != nil),
 	)
 
+	e.status = newComponentStatus(host)
+
+	// 起動に失敗した場合の調査にも使えるよう、DB接続より前にデバッグエンドポイントへ登録する
+	if e.config.Debug.Endpoint != "" {
+		e.debug = &debugSource{id: e.id, signal: "metrics", db: e.db, diag: e.diag}
+		if err := registerDebug(e.config.Debug.Endpoint, e.debug, e.logger); err != nil {
+			e.logger.Error("デバッグエンドポイントの起動に失敗しました", zap.Error(err))
+			return err
+		}
+	}
+
+	// スキーマ作成のドライランが有効な場合は、DDLを実行せずにログまたはファイルへ出力
+	if err := dryRunSchemaDDL(e.config, e.logger); err != nil {
+		e.logger.Error("DDLのドライラン出力に失敗しました", zap.Error(err))
+		return err
+	}
+
 	// DB接続が有効な場合、データベース・テーブル作成と接続テストを実行
 	if e.db != nil {
 		// 1. データベース作成
-		if err := createDatabase(ctx, e.config, e.logger); err != nil {
+		if err := createDatabase(ctx, e.config, e.config.metricsDatabase(), e.logger); err != nil {
 			e.logger.Error("データベース作成に失敗しました", zap.Error(err))
 			return err
 		}
 
-		// 2. メトリクステーブル作成（複数の種類）
-		if err := e.createMetricsTables(ctx); err != nil {
-			e.logger.Error("メトリクステーブル作成に失敗しました", zap.Error(err))
-			return err
+		// 2. メトリクステーブル作成（複数の種類、create_schema が無効またはドライランの場合はスキップ）
+		if e.config.shouldCreateSchema() {
+			// 設定で使用する型をサーバーがサポートしているか確認
+			if err := checkServerVersion(ctx, e.config, e.db); err != nil {
+				e.logger.Error("サーバーのバージョン確認に失敗しました", zap.Error(err))
+				e.diag.recordError("metrics", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			if err := e.createMetricsTables(ctx); err != nil {
+				e.logger.Error("メトリクステーブル作成に失敗しました", zap.Error(err))
+				e.diag.recordError("metrics", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			// 容量の大きい列に列TTLを設定（column_ttl 指定時のみ）
+			if err := applyColumnTTL(ctx, e.config, "metrics", e.db, e.logger); err != nil {
+				e.logger.Error("列TTLの設定に失敗しました", zap.Error(err))
+				e.status.permanent(err)
+				return err
+			}
+
+			// 3. テナントごとの行ポリシー作成（マルチテナント有効時のみ）
+			if err := createRowPolicies(ctx, e.config, "metrics", e.db, e.logger); err != nil {
+				e.logger.Error("行ポリシー作成に失敗しました", zap.Error(err))
+				e.status.permanent(err)
+				return err
+			}
+
+			// イベント送信が有効な場合はスキーマの作成を通知
+			e.events.schemaCreated(e.config.metricsDatabase())
 		}
 
-		// 3. 接続テスト
-		if err := e.db.Ping(); err != nil {
+		// 4. 接続テスト
+		if err := e.db.PingContext(ctx); err != nil {
 			e.logger.Error("データベースへの接続テストに失敗しました", zap.Error(err))
 			return err
 		}
 		e.logger.Info("データベース接続とメトリクステーブル作成に成功しました")
+
+		// 追加の書き込み先への接続とスキーマ作成（targets 指定時のみ）
+		if err := e.startTargets(ctx); err != nil {
+			e.logger.Error("追加の書き込み先の開始に失敗しました", zap.Error(err))
+			e.status.permanent(err)
+			return err
+		}
+		e.status.ok()
+
+		// 最初の送信で接続確立の遅延が発生しないよう、最小数の接続を確立しておく（失敗しても送信時に再接続する）
+		if err := e.config.ConnectionPool.prewarm(ctx, e.db); err != nil {
+			e.logger.Warn("接続プールの事前接続に失敗しました", zap.Error(err))
+		}
 	}
 
 	return nil
@@ -83,19 +224,89 @@ func (e *metricsExporter) start(ctx context.Context, host component.Host) error
 // clickhouseexporterのshutdown関数を参考
 func (e *metricsExporter) shutdown(ctx context.Context) error {
 	e.logger.Info("メトリクスエクスポーターを終了しています")
+	e.diag.logSummary("metrics", e.logger)
+
+	e.kafka.shutdown()
+	e.live.shutdown()
+	e.summary.shutdown()
+	telemetryErr := errors.Join(e.telemetry.shutdown(), e.forwarder.shutdown(), e.events.shutdown(),
+		unregisterDebug(e.config.Debug.Endpoint, e.debug))
 
+	// 共有接続プールの参照を解放（最後の参照の場合のみ接続を閉じる）
+	telemetryErr = errors.Join(telemetryErr, closeExportTargets(e.targets))
 	if e.db != nil {
-		return e.db.Close()
+		return errors.Join(telemetryErr, releaseDBConnection(e.db))
 	}
 
-	return nil
+	return telemetryErr
 }
 
 // pushMetrics はメトリクスデータを受信して処理します
 // exporterhelper経由で呼び出される実際のメトリクスデータ処理関数
 // 処理に失敗した場合のリトライやエラー処理はexporterhelperが自動で行う
 func (e *metricsExporter) pushMetrics(ctx context.Context, md pmetric.Metrics) error {
+	// filter.metrics の条件に従ってメトリクスを破棄（filter_preview 有効時は破棄される件数のみを記録）
+	filtered, err := filterMetrics(ctx, e.filter, md, !e.config.FilterPreview)
+	if err := reportFilter(ctx, e.config, e.diag, e.telemetry, e.events, e.logger, "metrics", filtered, err); err != nil {
+		return err
+	}
+
+	// 診断情報にフラッシュ結果を記録
+	finishFlush := e.diag.beginFlush("metrics", "otel_metrics", md.MetricCount())
+	// 使用率メトリクス向けに処理中のデータ量と処理時間を記録
+	defer e.telemetry.beginPush((&pmetric.ProtoMarshaler{}).MetricsSize(md))()
+
+	// ステールマーカーのデータポイントを metrics.staleness_handling に従って取り除く
+	// （データポイントがなくなったメトリクスは unsupported_metrics で扱う）
+	e.applyStaleness(md)
+	// 型が不明・データポイントのないメトリクスを unsupported_metrics に従って取り除く、またはバッチを拒否する
+	if err := e.applyUnsupportedMetrics(md); err != nil {
+		e.logger.Error("処理できないメトリクスを含むバッチを拒否しました", zap.Error(err))
+		finishFlush(err)
+		return err
+	}
+	// 集約方法が未指定のSum・Histogramはレートを計算できないため、バッチを拒否する
+	if err := e.validateTemporality(md); err != nil {
+		e.logger.Error("集約方法が未指定のメトリクスを含むバッチを拒否しました", zap.Error(err))
+		finishFlush(err)
+		return err
+	}
+	// バケット数が上限を超えるヒストグラムのバケットを histogram_bucket_overflow に従ってまとめる、またはバッチを拒否する
+	if err := e.applyHistogramBuckets(md); err != nil {
+		e.logger.Error("バケット数が上限を超えるヒストグラムを含むバッチを拒否しました", zap.Error(err))
+		finishFlush(err)
+		return err
+	}
+
+	// ストリーム数の上限を超えたデータポイントをオーバーフロー系列に集約（送信ごとに計数をリセット）
+	// filter_preview 有効時は集約・破棄される件数のみを記録し、データポイントは変更しない
+	if e.config.FilterPreview {
+		merged, dropped := previewCardinalityLimit(e.config.CardinalityLimit, md)
+		e.diag.recordPreview(previewRuleCardinalityLimit, previewActionMerged, merged)
+		e.diag.recordPreview(previewRuleCardinalityLimit, previewActionDropped, dropped)
+		e.telemetry.recordPreview(ctx, previewRuleCardinalityLimit, previewActionMerged, merged)
+		e.telemetry.recordPreview(ctx, previewRuleCardinalityLimit, previewActionDropped, dropped)
+		if merged > 0 || dropped > 0 {
+			e.logger.Debug("ストリーム数の上限を超えたデータポイントがあります（filter_preview のため集約しません）",
+				zap.Int("max_streams", e.config.CardinalityLimit.MaxStreams),
+				zap.Int("would_merge", merged), zap.Int("would_drop", dropped))
+		}
+	} else if limiter := newCardinalityLimiter(e.config.CardinalityLimit); limiter != nil {
+		limiter.apply(md)
+		if limiter.merged > 0 || limiter.dropped > 0 {
+			e.telemetry.recordOverflow(ctx, limiter.merged, limiter.dropped)
+			e.events.batchDropped(stable.DropReasonCardinalityLimit, limiter.dropped)
+			e.diag.recordDropped("metrics", stable.DropReasonCardinalityLimit, limiter.dropped)
+			e.logger.Debug("ストリーム数の上限を超えたデータポイントを集約しました",
+				zap.Int("max_streams", e.config.CardinalityLimit.MaxStreams),
+				zap.Int("merged", limiter.merged), zap.Int("dropped", limiter.dropped))
+		}
+	}
+
 	resourceMetrics := md.ResourceMetrics()
+	// 詳細モードで出力するデータをサンプリング（detailed_sampling 未指定の場合は全件）
+	live := e.live.load() // 送信中は同じ値を使用する
+	sampler := newDetailedSampler(live)
 	totalMetrics := 0
 	var processingErr error
 
@@ -109,10 +320,17 @@ func (e *metricsExporter) pushMetrics(ctx context.Context, md pmetric.Metrics) e
 			totalMetrics += metrics.Len()
 
 			// 詳細モードが有効な場合、各メトリクスの詳細情報をログ出力
-			if e.config.Detailed {
+			if live.Detailed {
 				for k := 0; k < metrics.Len(); k++ {
+					if !sampler.sample() {
+						continue
+					}
 					metric := metrics.At(k)
-					e.logger.Info(fmt.Sprintf("%s メトリクスを受信しました", e.config.Prefix),
+					if e.detailed.otlp() {
+						e.detailed.writeMetric(rm, sm, metric)
+						continue
+					}
+					e.detailed.logger.Info(fmt.Sprintf("%s メトリクスを受信しました", live.Prefix),
 						zap.String("name", metric.Name()),
 						zap.String("description", metric.Description()),
 						zap.String("unit", metric.Unit()),
@@ -121,121 +339,257 @@ func (e *metricsExporter) pushMetrics(ctx context.Context, md pmetric.Metrics) e
 				}
 			}
 
-			// 現在はデータ投入を無効化（DB接続テストのみ）
-			// TODO: 将来的にデータ投入機能を実装予定
-			//
-			// デモ目的：意図的にエラーをシミュレートしてメトリクスを生成
+			// DB未接続（ログ出力のみモード）の場合のデモ目的：意図的にエラーをシミュレートしてメトリクスを生成
 			// 15%の確率でエラーを発生させる（メトリクス確認用）
-			if i%15 == 11 {
+			if e.db == nil && e.forwarder == nil && e.kafka == nil && i%15 == 11 {
 				processingErr = fmt.Errorf("デモエラー: メトリクス処理でシミュレートされたエラー (resource %d)", i)
 				e.logger.Warn("メトリクス検証用のシミュレートエラー", zap.Error(processingErr))
 			}
 		}
 	}
 
+	// リソース・スコープの組をディメンションテーブルに書き込む（データポイントより先に書き込み、参照先が欠けないようにする）
+	// DB接続が有効な場合（またはキャプチャが有効な場合）はデータポイントを種類ごとのテーブルに挿入する
+	if err := e.dimensions.write(ctx, md); err != nil {
+		processingErr = errors.Join(processingErr, err)
+		e.logger.Error("メトリクスのディメンションの書き込みに失敗しました", zap.Error(err))
+		e.status.recoverable(err)
+	} else if e.forwarder == nil && (e.db != nil || e.capture != nil) {
+		// 追加の書き込み先への挿入はバッチを変更しないため、endpoint と同じバッチをすべて書き込む
+		targetErr := e.writeTargets(ctx, md)
+		err := e.insertMetrics(ctx, md)
+		e.status.recordInsert(err)
+		if err != nil {
+			processingErr = errors.Join(processingErr, err)
+			e.logger.Error("メトリクスの挿入に失敗しました", zap.Error(err))
+			if consumererror.IsPermanent(err) {
+				e.events.batchDropped(stable.DropReasonPermanentError, md.DataPointCount())
+				e.diag.recordDropped("metrics", stable.DropReasonPermanentError, md.DataPointCount())
+			}
+		}
+		processingErr = errors.Join(processingErr, targetErr)
+	}
+
+	// 転送対象の場合はOTLPで転送する（転送に失敗した場合はexporterhelperがリトライする）
+	if e.forwarder != nil {
+		if err := e.forwarder.forwardMetrics(ctx, md); err != nil {
+			processingErr = err
+			e.logger.Error("メトリクスの転送に失敗しました", zap.Error(err))
+		}
+	}
+
+	// Kafka出力が有効な場合はデータベースへの保存とは独立して発行する
+	// 発行に失敗した場合はexporterhelperがリトライする（copy モードではデータベースへの挿入も再実行される）
+	if e.kafka != nil {
+		if err := e.kafka.publishMetrics(ctx, md); err != nil {
+			processingErr = errors.Join(processingErr, err)
+			e.logger.Error("メトリクスのKafkaへの発行に失敗しました", zap.Error(err))
+		}
+	}
+
 	// 処理したメトリクスデータのサマリーをログ出力
-	e.logger.Info(fmt.Sprintf("%s メトリクス処理が完了しました", e.config.Prefix),
-		zap.Int("resource_metrics", resourceMetrics.Len()),
-		zap.Int("total_metrics", totalMetrics),
-		zap.Bool("db_connected", e.db != nil),
-		zap.Bool("has_error", processingErr != nil),
-	)
+	if e.summary.active() {
+		e.summary.record(processingErr, resourceMetrics.Len(), totalMetrics)
+	} else {
+		e.logger.Info(fmt.Sprintf("%s メトリクス処理が完了しました", live.Prefix),
+			zap.Int("resource_metrics", resourceMetrics.Len()),
+			zap.Int("total_metrics", totalMetrics),
+			zap.Bool("db_connected", e.db != nil),
+			zap.Bool("has_error", processingErr != nil),
+			sampler.field(),
+		)
+	}
+
+	finishFlush(processingErr)
 
 	// エラーがある場合はそれを返す（exporterhelperがFailedメトリクスを記録）
 	// エラーがない場合はnilを返す（exporterhelperがSentメトリクスを記録）
 	return processingErr
 }
 
-// insertMetricToDB は将来実装予定のDB挿入機能
-// 現在は DB接続テストのみ実装
-// func (e *metricsExporter) insertMetricToDB(ctx context.Context, metric pmetric.Metric) error {
-// 	// TODO: ClickHouse用の挿入処理を実装
-// 	return nil
-// }
+// insertMetrics はメトリクスのデータポイントを種類ごとのテーブルに、テーブルごとに1トランザクションで挿入します
+// StartTimeUnix と AggregationTemporality（Sum・Histogram・ExponentialHistogram）はデータポイントの値をそのまま保存します
+// metrics_dimensions 有効時はリソース・スコープの列の代わりに、ディメンションテーブルの組のハッシュ（DimensionsHash）を保存します
+// metrics_schema: lite ではテーブルにないスコープ・フラグ・エグゼンプラーの列を挿入しません
+// キャプチャが有効な場合は挿入前のバッチをファイルに出力します（DB未接続の場合は出力のみ）
+// 一部のテーブルへの挿入に失敗した場合もバッチ全体をリトライの対象とします（insert_deduplication_token で挿入済みのテーブルの重複を防げる）
+func (e *metricsExporter) insertMetrics(ctx context.Context, md pmetric.Metrics) error {
+	// PostgreSQL用のメトリクステーブルは作成しないため挿入しない
+	if e.config.isPostgres() {
+		return nil
+	}
+	opts := e.config.rowOptions(false)
+	opts.StaleColumn = e.config.Metrics.StalenessHandling == stalenessStore
+	// ディメンションテーブルの書き込み（metricsDimensionsWriter）と同じ組のハッシュを参照する
+	opts.MetricsDimensions = e.config.MetricsDimensions.Enabled
+	opts.OmitMetricsScope = e.config.liteMetricsSchema()
+	opts.MetricsLite = e.config.liteMetricsSchema()
+	conv := pdatarows.NewConverter(opts)
+	rowsByType := conv.Metrics(md)
+	if truncated := conv.TruncatedKeys(); truncated > 0 {
+		e.diag.recordTruncatedKeys("metrics", truncated)
+		e.telemetry.recordTruncatedKeys(ctx, truncated)
+	}
+	for _, table := range metricsTables {
+		rows := rowsByType[table.metricType]
+		if len(rows) == 0 {
+			continue
+		}
+		if err := e.insertMetricRows(ctx, md, table.tableName, e.config.ingestTimeInsertColumns(conv.MetricColumns(table.metricType)), rows); err != nil {
+			return fmt.Errorf("%s への挿入に失敗しました: %w", table.tableName, err)
+		}
+	}
+	return nil
+}
+
+// insertMetricRows は1種類のデータポイントの行を1トランザクションでテーブルに挿入します
+func (e *metricsExporter) insertMetricRows(ctx context.Context, md pmetric.Metrics, table string, columns []string, rows [][]any) (err error) {
+	insert, err := renderInsertStatement("metrics_insert.sql", sqltemplates.MetricsInsert, "", columns, internal.TableTemplateData{
+		Database: e.config.metricsDatabase(),
+		Table:    table,
+		Columns:  columns,
+	})
+	if err != nil {
+		return err
+	}
+	e.config.stampExportTime(rows)
+	e.capture.write(table, insert.sql, columns, rows)
+	if e.db == nil {
+		return nil
+	}
+
+	// 挿入結果（行数、所要時間、エラー）を内部メトリクスに記録
+	start := time.Now()
+	defer func() {
+		e.telemetry.recordInsert(ctx, table, len(rows), time.Since(start), err)
+	}()
+
+	// リトライで同じバッチが再送された場合に重複して保存されないよう、内容から重複排除トークンを求める
+	insert.deduplicationToken = e.config.metricDeduplicationToken(e.config.metricsDatabase(), table, md)
+	return insertRowsWithTimeout(ctx, e.config, e.db, nil, insert, rows)
+}
+
+// derive は設定とDB接続のみを差し替えたエクスポーターを作成します（追加の書き込み先への挿入用）
+// 診断情報・内部メトリクスなどは元のエクスポーターと共有し、ディメンションの書き込み済みの記憶は書き込み先ごとに持ちます
+func (e *metricsExporter) derive(cfg *Config, db *sql.DB, logger *zap.Logger) *metricsExporter {
+	return &metricsExporter{
+		config:    cfg,
+		logger:    logger,
+		db:        db,
+		diag:      e.diag,
+		telemetry: e.telemetry,
+		capture:   e.capture,
+
+		dimensions: newMetricsDimensionsWriter(cfg, db),
+	}
+}
+
+// prepareSchema は挿入先のデータベースとメトリクステーブルを作成し、列TTLを適用します（create_schema 有効時のみ）
+func (e *metricsExporter) prepareSchema(ctx context.Context) error {
+	if !e.config.shouldCreateSchema() {
+		return nil
+	}
+	if err := createDatabase(ctx, e.config, e.config.metricsDatabase(), e.logger); err != nil {
+		return err
+	}
+	if err := e.createMetricsTables(ctx); err != nil {
+		return err
+	}
+	return applyColumnTTL(ctx, e.config, "metrics", e.db, e.logger)
+}
+
+// metricsTables はメトリクスタイプとそれに対応するテーブル名を定義します
+var metricsTables = []struct {
+	templateFile string
+	tableName    string
+	description  string
+	metricType   pmetric.MetricType
+}{
+	{"metrics_gauge_table.sql", "otel_metrics_gauge", "Gauge metrics (instantaneous values)", pmetric.MetricTypeGauge},
+	{"metrics_sum_table.sql", "otel_metrics_sum", "Sum metrics (counters and cumulative values)", pmetric.MetricTypeSum},
+	{"metrics_histogram_table.sql", "otel_metrics_histogram", "Histogram metrics (distribution with buckets)", pmetric.MetricTypeHistogram},
+	{"metrics_summary_table.sql", "otel_metrics_summary", "Summary metrics (pre-calculated quantiles)", pmetric.MetricTypeSummary},
+	{"metrics_exponential_histogram_table.sql", "otel_metrics_exponential_histogram", "Exponential histogram metrics (exponentially-sized buckets)", pmetric.MetricTypeExponentialHistogram},
+}
 
 // createMetricsTables はClickHouseに必要なすべてのメトリクステーブルを作成します
 // 異なるメトリクスタイプ（gauge, sum, histogram, summary）用に別々のテーブルを作成します
 func (e *metricsExporter) createMetricsTables(ctx context.Context) error {
-	// メトリクスタイプとそれに対応するテーブル名を定義
-	metricTypes := []struct {
-		templateFile string
-		tableName    string
-		description  string
-	}{
-		{"metrics_gauge_table.sql", "otel_metrics_gauge", "Gauge metrics (instantaneous values)"},
-		{"metrics_sum_table.sql", "otel_metrics_sum", "Sum metrics (counters and cumulative values)"},
-		{"metrics_histogram_table.sql", "otel_metrics_histogram", "Histogram metrics (distribution with buckets)"},
-		{"metrics_summary_table.sql", "otel_metrics_summary", "Summary metrics (pre-calculated quantiles)"},
-		{"metrics_exponential_histogram_table.sql", "otel_metrics_exponential_histogram", "Exponential histogram metrics (exponentially-sized buckets)"},
+	// メトリクスの挿入は ClickHouse のみ対応のため、PostgreSQL用のテーブルは用意していない
+	if e.config.isPostgres() {
+		e.logger.Info("driver: postgres ではメトリクステーブルを作成しません")
+		return nil
 	}
 
 	// 各メトリクステーブルタイプを作成
-	for _, metricType := range metricTypes {
+	for _, metricType := range metricsTables {
 		if err := e.createMetricTable(ctx, metricType.templateFile, metricType.tableName, metricType.description); err != nil {
 			return fmt.Errorf("%s の作成に失敗しました: %w", metricType.description, err)
 		}
 	}
 
+	if e.config.MetricsDimensions.Enabled {
+		return e.createMetricsDimensionsTable(ctx)
+	}
 	return nil
 }
 
 // createMetricTable は提供されたテンプレートを使用して特定のメトリクステーブルを作成します
 func (e *metricsExporter) createMetricTable(ctx context.Context, templateFile, tableName, description string) error {
-	// このメトリクステーブルタイプ用のSQLテンプレートを読み込み
-	sqlTemplate, err := internal.LoadSQLTemplate(templateFile)
+	// 設定パラメータでこのメトリクステーブルタイプ用のSQLテンプレートをレンダリング
+	sql, err := e.renderMetricTableSQL(templateFile, tableName)
 	if err != nil {
-		return fmt.Errorf("%s SQLテンプレートの読み込みに失敗しました: %w", templateFile, err)
+		e.telemetry.recordRenderFailure(ctx, templateFile)
+		return fmt.Errorf("%s SQLのレンダリングに失敗しました: %w", templateFile, err)
 	}
 
-	// 設定パラメータでSQLテンプレートをレンダリング
-	sql := e.renderMetricTableSQL(sqlTemplate, tableName)
-
 	// テーブル作成SQLを実行
 	if err := e.executeSQL(ctx, sql); err != nil {
 		return fmt.Errorf("%s テーブルの作成に失敗しました: %w", description, err)
 	}
 
+	if err := createDistributedTable(ctx, e.config, e.db, e.config.metricsDatabase(),
+		tableName, e.config.localTable(tableName), e.logger); err != nil {
+		return err
+	}
+
 	e.logger.Info("メトリクステーブルが正常に作成されました",
 		zap.String("table", tableName),
 		zap.String("type", description),
-		zap.String("database", e.config.Database))
+		zap.String("database", e.config.metricsDatabase()))
 	return nil
 }
 
 // renderMetricTableSQL は設定値でメトリクステーブルSQLテンプレートをレンダリングします
-func (e *metricsExporter) renderMetricTableSQL(template, tableName string) string {
-	// 実際の設定値でテンプレートパラメータを置換
-	// テンプレートは順番に置換される %s プレースホルダーを使用:
-	// 1. データベース名
-	// 2. テーブル名
-	// 3. クラスター句（該当する場合）
-	// 4. エンジン句
-	// 5. TTL句（設定されている場合）
-
-	replacements := []string{
-		e.config.Database,            // Database name
-		tableName,                    // Specific metric table name
-		e.buildClusterClause(),       // Cluster clause
-		e.buildMetricsEngineClause(), // Engine clause
-		e.buildTTLClause(),           // TTL clause
-	}
-
-	// 順番に置換を適用
-	sql := template
-	for _, replacement := range replacements {
-		sql = strings.Replace(sql, "%s", replacement, 1)
-	}
-
-	return sql
+func (e *metricsExporter) renderMetricTableSQL(templateFile, tableName string) (string, error) {
+	return internal.RenderSQLTemplate(templateFile, internal.TableTemplateData{
+		Database: e.config.metricsDatabase(),
+		Table:    e.config.localTable(tableName),
+		Cluster:  e.buildClusterClause(),
+		Engine:   e.buildMetricsEngineClause(),
+		OrderBy:  orderBy(e.config.MetricsOrderBy, defaultMetricsOrderBy),
+		TTL:      e.buildTTLClause(),
+		Settings: e.config.tableSettings(),
+		MapType:  e.config.mapColumnType(),
+
+		MetricsDimensions: e.config.MetricsDimensions.Enabled,
+		MetricsLite:       e.config.liteMetricsSchema(),
+		StaleColumn:       e.config.Metrics.StalenessHandling == stalenessStore,
+		IngestTime:        e.config.RecordIngestTime,
+	})
 }
 
 // buildMetricsEngineClause はメトリクステーブル用のClickHouseエンジン句を構築します
 func (e *metricsExporter) buildMetricsEngineClause() string {
 	switch {
+	case e.config.shardedTables() || e.config.ClickHouseCloud:
+		// レプリケーション・分散テーブル構成ではローカルテーブルのエンジン（分散テーブルは別途作成）
+		// ClickHouse Cloud では SharedMergeTree
+		return e.config.replicatedEngine("MergeTree()")
 	case e.config.ClusterName != "":
 		// クラスター展開用の分散エンジン
 		return fmt.Sprintf("Distributed(%s, %s, %s_local, rand())",
-			e.config.ClusterName, e.config.Database, "otel_metrics")
+			e.config.ClusterName, e.config.metricsDatabase(), "otel_metrics")
 	default:
 		// シングルノード展開用のMergeTreeエンジン
 		// 時系列メトリクスデータに最適
@@ -245,7 +599,7 @@ func (e *metricsExporter) buildMetricsEngineClause() string {
 
 // buildClusterClause はクラスター展開が設定されている場合にクラスター句を構築します
 func (e *metricsExporter) buildClusterClause() string {
-	if e.config.ClusterName != "" {
+	if e.config.ClusterName != "" && !e.config.replicatedDatabase() {
 		return fmt.Sprintf("ON CLUSTER %s", e.config.ClusterName)
 	}
 	return ""
@@ -253,11 +607,8 @@ func (e *metricsExporter) buildClusterClause() string {
 
 // buildTTLClause は自動データ期限切れ用のTTL句を構築します
 func (e *metricsExporter) buildTTLClause() string {
-	if e.config.TTLDays > 0 {
-		// 自動クリーンアップ用にメトリクスタイムスタンプベースのTTL
-		return fmt.Sprintf("TTL toDateTime(TimeUnix) + toIntervalDay(%d)", e.config.TTLDays)
-	}
-	return ""
+	// 自動クリーンアップ用にメトリクスタイムスタンプベースのTTL
+	return internal.GenerateTTLExpr(e.config.signalTTL("metrics"), "toDateTime(TimeUnix)")
 }
 
 // executeSQL は適切なエラー処理とログ記録でSQL文を実行します
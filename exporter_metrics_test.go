@@ -0,0 +1,278 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+var (
+	testMetricStart = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	testMetricTime  = testMetricStart.Add(time.Minute)
+)
+
+// insertTestMetrics は Sum（デルタ）と Histogram（累積）のデータポイントを1つずつ含むメトリクスを返します
+func insertTestMetrics() pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "checkout")
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName("otelhttp")
+
+	sum := sm.Metrics().AppendEmpty()
+	sum.SetName("http.server.requests")
+	s := sum.SetEmptySum()
+	s.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	s.SetIsMonotonic(true)
+	sdp := s.DataPoints().AppendEmpty()
+	sdp.SetStartTimestamp(pcommon.NewTimestampFromTime(testMetricStart))
+	sdp.SetTimestamp(pcommon.NewTimestampFromTime(testMetricTime))
+	sdp.SetIntValue(42)
+
+	histogram := sm.Metrics().AppendEmpty()
+	histogram.SetName("http.server.duration")
+	h := histogram.SetEmptyHistogram()
+	h.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	hdp := h.DataPoints().AppendEmpty()
+	hdp.SetStartTimestamp(pcommon.NewTimestampFromTime(testMetricStart))
+	hdp.SetTimestamp(pcommon.NewTimestampFromTime(testMetricTime))
+	hdp.SetCount(2)
+	hdp.BucketCounts().FromRaw([]uint64{1, 1})
+	hdp.ExplicitBounds().FromRaw([]float64{0.5})
+	return md
+}
+
+// captureMetrics は cfg のキャプチャ先に md を書き込みます
+func captureMetrics(t *testing.T, cfg *Config, md pmetric.Metrics) {
+	t.Helper()
+	exp, err := newMetricsExporter(embeddedSettings(zap.NewNop()), cfg)
+	if err != nil {
+		t.Fatalf("newMetricsExporter: %v", err)
+	}
+	defer func() { _ = exp.shutdown(context.Background()) }()
+	if err := exp.pushMetrics(context.Background(), md); err != nil {
+		t.Fatalf("pushMetrics: %v", err)
+	}
+}
+
+// Sum・Histogram のデータポイントは開始時刻（StartTimeUnix）と集約方法（AggregationTemporality）を保存する
+func TestInsertMetricsStartTimeAndTemporality(t *testing.T) {
+	dir := t.TempDir()
+	captureMetrics(t, captureConfig(dir), insertTestMetrics())
+
+	tests := []struct {
+		table       string
+		temporality pmetric.AggregationTemporality
+		values      map[string]any
+	}{
+		{
+			table:       "otel_metrics_sum",
+			temporality: pmetric.AggregationTemporalityDelta,
+			values:      map[string]any{"MetricName": "http.server.requests", "Value": float64(42), "IsMonotonic": true},
+		},
+		{
+			table:       "otel_metrics_histogram",
+			temporality: pmetric.AggregationTemporalityCumulative,
+			values:      map[string]any{"MetricName": "http.server.duration", "Count": float64(2)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			batch := readCapture(t, dir, tt.table)
+			for _, column := range []string{"StartTimeUnix", "AggregationTemporality"} {
+				if !strings.Contains(batch.SQL, column) {
+					t.Errorf("挿入SQLに %s がありません: %s", column, batch.SQL)
+				}
+			}
+			if len(batch.Rows) != 1 {
+				t.Fatalf("行数 = %d, want 1", len(batch.Rows))
+			}
+			row := batch.Rows[0]
+			if got, want := row["StartTimeUnix"], testMetricStart.Format(time.RFC3339Nano); got != want {
+				t.Errorf("StartTimeUnix = %v, want %v", got, want)
+			}
+			if got, want := row["AggregationTemporality"], float64(tt.temporality); got != want {
+				t.Errorf("AggregationTemporality = %v, want %v", got, want)
+			}
+			for column, want := range tt.values {
+				if got := row[column]; got != want {
+					t.Errorf("%s = %v, want %v", column, got, want)
+				}
+			}
+		})
+	}
+}
+
+// staleness_handling: store ではステールマーカーのデータポイントを保存し、Stale 列に書き込む
+func TestInsertMetricsStaleColumn(t *testing.T) {
+	staleMetrics := func() pmetric.Metrics {
+		md := insertTestMetrics()
+		dps := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints()
+		stale := dps.AppendEmpty()
+		dps.At(0).CopyTo(stale)
+		stale.SetFlags(pmetric.DefaultDataPointFlags.WithNoRecordedValue(true))
+		return md
+	}
+
+	tests := []struct {
+		name      string
+		handling  string
+		wantStale []any // 行ごとの Stale 列の値（列がない場合は nil）
+	}{
+		{name: "store", handling: stalenessStore, wantStale: []any{false, true}},
+		{name: "drop", handling: stalenessDrop, wantStale: []any{nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := captureConfig(dir)
+			cfg.Metrics.StalenessHandling = tt.handling
+			captureMetrics(t, cfg, staleMetrics())
+
+			batch := readCapture(t, dir, "otel_metrics_sum")
+			if len(batch.Rows) != len(tt.wantStale) {
+				t.Fatalf("行数 = %d, want %d", len(batch.Rows), len(tt.wantStale))
+			}
+			for i, want := range tt.wantStale {
+				if got := batch.Rows[i]["Stale"]; got != want {
+					t.Errorf("行 %d の Stale = %v, want %v", i, got, want)
+				}
+			}
+
+			exp := &metricsExporter{config: cfg}
+			ddl, err := exp.renderMetricTableSQL("metrics_sum_table.sql", "otel_metrics_sum")
+			if err != nil {
+				t.Fatalf("renderMetricTableSQL: %v", err)
+			}
+			if got, want := strings.Contains(ddl, "Stale Boolean"), tt.handling == stalenessStore; got != want {
+				t.Errorf("テーブルに Stale 列がある = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// metrics_dimensions 有効時のデータポイントの行は、リソース・スコープの列の代わりに DimensionsHash を保存する
+func TestInsertMetricsDimensionsHash(t *testing.T) {
+	dir := t.TempDir()
+	cfg := captureConfig(dir)
+	cfg.MetricsDimensions.Enabled = true
+	captureMetrics(t, cfg, insertTestMetrics())
+
+	for _, table := range []string{"otel_metrics_sum", "otel_metrics_histogram"} {
+		batch := readCapture(t, dir, table)
+		row := batch.Rows[0]
+		if _, ok := row["DimensionsHash"]; !ok {
+			t.Errorf("%s の行に DimensionsHash がありません: %v", table, row)
+		}
+		for _, column := range []string{"ResourceAttributes", "ResourceSchemaUrl", "ScopeName", "ScopeAttributes"} {
+			if _, ok := row[column]; ok {
+				t.Errorf("%s の行に %s があります", table, column)
+			}
+		}
+		if row["ServiceName"] != "checkout" {
+			t.Errorf("%s の ServiceName = %v, want checkout", table, row["ServiceName"])
+		}
+	}
+}
+
+// tableColumns はテーブル作成SQL（メトリクス・プロファイル）の列名を返します（Nested の列は 親.子、既定値で埋まる IngestTimestamp は除く）
+func tableColumns(ddl string) []string {
+	var columns []string
+	parent := ""
+	for _, line := range strings.Split(ddl, "\n") {
+		line, _, _ = strings.Cut(line, "--")
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(line, "CREATE") {
+			continue
+		}
+		name := fields[0]
+		switch {
+		case name == "INDEX" || strings.HasPrefix(line, ")"):
+			return columns
+		case name == ")":
+			parent = ""
+		case strings.HasPrefix(fields[1], "Nested"):
+			parent = name
+		case strings.HasPrefix(line, "        ") && parent != "":
+			columns = append(columns, parent+"."+name)
+		case name != "IngestTimestamp":
+			parent = ""
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// データポイントの挿入列は、設定ごとのメトリクステーブルの列と一致する
+func TestMetricInsertColumnsMatchTables(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{name: "full", modify: func(*Config) {}},
+		{name: "lite", modify: func(cfg *Config) { cfg.MetricsSchema = metricsSchemaLite }},
+		{name: "metrics_dimensions", modify: func(cfg *Config) { cfg.MetricsDimensions.Enabled = true }},
+		{name: "lite・metrics_dimensions", modify: func(cfg *Config) {
+			cfg.MetricsSchema = metricsSchemaLite
+			cfg.MetricsDimensions.Enabled = true
+		}},
+		{name: "staleness_handling: store・record_ingest_time", modify: func(cfg *Config) {
+			cfg.Metrics.StalenessHandling = stalenessStore
+			cfg.RecordIngestTime = true
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := captureConfig(dir)
+			tt.modify(cfg)
+			md := testMetricsAllTypes()
+			captureMetrics(t, cfg, md)
+
+			exp := &metricsExporter{config: cfg}
+			for _, table := range metricsTables {
+				ddl, err := exp.renderMetricTableSQL(table.templateFile, table.tableName)
+				if err != nil {
+					t.Fatalf("renderMetricTableSQL: %v", err)
+				}
+				want := tableColumns(ddl)
+				batch := readCapture(t, dir, table.tableName)
+				if len(batch.Rows) == 0 {
+					t.Fatalf("%s に行がありません", table.tableName)
+				}
+				got := slices.Collect(maps.Keys(batch.Rows[0]))
+				slices.Sort(got)
+				slices.Sort(want)
+				if !slices.Equal(got, want) {
+					t.Errorf("%s の挿入列 = %v, want %v", table.tableName, got, want)
+				}
+			}
+		})
+	}
+}
+
+// testMetricsAllTypes は insertTestMetrics に Gauge・Summary・ExponentialHistogram のデータポイントを追加したメトリクスを返します
+func testMetricsAllTypes() pmetric.Metrics {
+	md := insertTestMetrics()
+	metrics := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	metrics.AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+	metrics.AppendEmpty().SetEmptySummary().DataPoints().AppendEmpty().SetCount(1)
+	exponential := metrics.AppendEmpty().SetEmptyExponentialHistogram()
+	exponential.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	exponential.DataPoints().AppendEmpty().SetCount(1)
+	for i := range metrics.Len() {
+		metrics.At(i).SetName(fmt.Sprintf("metric.%d", i))
+	}
+	return md
+}
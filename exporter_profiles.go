@@ -0,0 +1,491 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/internal/sqltemplates"
+	"github.com/dtamura/myexporter/pdatarows"
+	"github.com/dtamura/myexporter/stable"
+)
+
+type profilesExporter struct {
+	id     component.ID
+	config *Config
+	logger *zap.Logger
+	db     *sql.DB      // DB接続（clickhouseexporterを参考）
+	diag   *diagnostics // zPages（expvarz）向けの診断情報
+
+	telemetry *exporterTelemetry // コレクターの内部テレメトリに公開するメトリクス
+	forwarder *otlpForwarder     // OTLP転送（passthrough.signals 指定時のみ）
+	kafka     *kafkaPublisher    // Kafkaへの発行（kafka.brokers 指定時のみ）
+	detailed  *detailedOutput    // 詳細モードの出力（log_format に応じた形式）
+	events    *lifecycleEvents   // ライフサイクルイベントの送信（lifecycle_events.endpoint 指定時のみ）
+	status    *componentStatus   // コレクターへの状態報告（start 以降）
+	summary   *pushSummary       // 処理完了ログの集計（summary_interval または live_reload 指定時のみ）
+	live      *liveConfig        // 再起動せずに変更できる設定の現在の値（live_reload）
+	debug     *debugSource       // デバッグエンドポイントへの登録（debug.endpoint 指定時のみ）
+	capture   *batchCapture      // 挿入バッチのキャプチャ（capture.directory 指定時のみ）
+}
+
+// newProfilesExporter はプロファイルエクスポーターの新しいインスタンスを作成します
+func newProfilesExporter(set exporter.Settings, cfg *Config) (*profilesExporter, error) {
+	logger := set.Logger
+	var db *sql.DB
+
+	// 追加の書き込み先には挿入しないため、targets の指定は受け付けない
+	if err := cfg.checkTargetsSupported("profiles"); err != nil {
+		return nil, err
+	}
+
+	// 転送対象のシグナルはDBに保存せずOTLPで転送する
+	forwarder, err := newOTLPForwarder(cfg.Passthrough, "profiles", logger)
+	if err != nil {
+		return nil, err
+	}
+	kafka, err := newKafkaPublisher(cfg.Kafka, "profiles", logger)
+	if err != nil {
+		_ = forwarder.shutdown()
+		return nil, err
+	}
+
+	// DB接続が設定されている場合のみ接続を確立（転送対象・Kafkaにのみ発行するシグナルは接続しない）
+	if cfg.Endpoint != "" && forwarder == nil && !cfg.Kafka.replacesDatabase("profiles") {
+		db, err = acquireDBConnection(cfg, logger)
+		if err != nil {
+			logger.Warn("データベース接続に失敗しました、ログ出力のみモードにフォールバックします", zap.Error(err))
+		}
+		warnUnsafeAsyncInsert(cfg, logger)
+		warnDeprecatedTTLDays(cfg, logger)
+	}
+
+	events, err := newLifecycleEvents(cfg.LifecycleEvents, set, "profiles", logger)
+	if err != nil {
+		if db != nil {
+			_ = releaseDBConnection(db)
+		}
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		return nil, err
+	}
+
+	telemetry, err := newExporterTelemetry(set.TelemetrySettings, "profiles", db, cfg.Utilization)
+	if err != nil {
+		if db != nil {
+			_ = releaseDBConnection(db)
+		}
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		_ = events.shutdown()
+		return nil, fmt.Errorf("内部メトリクスの作成に失敗しました: %w", err)
+	}
+
+	live := newLiveConfig(cfg, logger)
+	return &profilesExporter{
+		id:     set.ID,
+		config: cfg,
+		logger: logger,
+		db:     db, // DB接続がない場合はnil
+		diag:   getDiagnostics(set.ID),
+
+		telemetry: telemetry,
+		forwarder: forwarder,
+		kafka:     kafka,
+		detailed:  newDetailedOutput(cfg.LogFormat, logger),
+		events:    events,
+		live:      live,
+		capture:   newBatchCapture(cfg.Capture, logger),
+		summary:   newPushSummary(live, logger, "プロファイル処理のサマリー", "resource_profiles", "total_profiles", "total_samples"),
+	}, nil
+}
+
+// Capabilities はプロファイルエクスポーターの機能を返します
+func (e *profilesExporter) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: e.config.mutatesData("profiles")}
+}
+
+// start はエクスポーター開始時に呼び出されます
+// DB接続テスト、データベース作成、プロファイルテーブル作成を実行
+func (e *profilesExporter) start(ctx context.Context, host component.Host) (err error) {
+	// データベース・テーブルの作成などは start_timeout 以内に終わらない場合は中断する
+	ctx, cancel := e.config.startContext(ctx)
+	defer cancel()
+	defer func() { err = startError(ctx, e.config, err) }()
+
+	e.logger.Info("プロファイルエクスポーターを開始しています",
+		zap.String("prefix", e.live.load().Prefix),
+		zap.Bool("db_enabled", e.db != nil),
+	)
+
+	e.status = newComponentStatus(host)
+
+	// 起動に失敗した場合の調査にも使えるよう、DB接続より前にデバッグエンドポイントへ登録する
+	if e.config.Debug.Endpoint != "" {
+		e.debug = &debugSource{id: e.id, signal: "profiles", db: e.db, diag: e.diag}
+		if err := registerDebug(e.config.Debug.Endpoint, e.debug, e.logger); err != nil {
+			e.logger.Error("デバッグエンドポイントの起動に失敗しました", zap.Error(err))
+			return err
+		}
+	}
+
+	// スキーマ作成のドライランが有効な場合は、DDLを実行せずにログまたはファイルへ出力
+	if err := dryRunSchemaDDL(e.config, e.logger); err != nil {
+		e.logger.Error("DDLのドライラン出力に失敗しました", zap.Error(err))
+		return err
+	}
+
+	// DB接続が有効な場合、データベース・テーブル作成と接続テストを実行
+	if e.db != nil {
+		// 1. データベース作成
+		if err := createDatabase(ctx, e.config, e.config.profilesDatabase(), e.logger); err != nil {
+			e.logger.Error("データベース作成に失敗しました", zap.Error(err))
+			return err
+		}
+
+		// 2. プロファイルテーブル作成
+		if e.config.shouldCreateSchema() {
+			// 設定で使用する型をサーバーがサポートしているか確認
+			if err := checkServerVersion(ctx, e.config, e.db); err != nil {
+				e.logger.Error("サーバーのバージョン確認に失敗しました", zap.Error(err))
+				e.diag.recordError("profiles", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			if err := e.createProfilesTable(ctx); err != nil {
+				e.logger.Error("プロファイルテーブル作成に失敗しました", zap.Error(err))
+				e.diag.recordError("profiles", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			// 既存テーブルに未適用のスキーマ変更を適用
+			if err := applyMigrations(ctx, e.config, e.db, "profiles", e.config.profilesDatabase(), e.config.localTable(e.getProfilesTableName()), e.logger); err != nil {
+				e.logger.Error("マイグレーションの適用に失敗しました", zap.Error(err))
+				e.diag.recordError("profiles", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			// 容量の大きい列に列TTLを設定（column_ttl 指定時のみ）
+			if err := applyColumnTTL(ctx, e.config, "profiles", e.db, e.logger); err != nil {
+				e.logger.Error("列TTLの設定に失敗しました", zap.Error(err))
+				e.status.permanent(err)
+				return err
+			}
+
+			// テナントごとの行ポリシー作成（マルチテナント有効時のみ）
+			if err := createRowPolicies(ctx, e.config, "profiles", e.db, e.logger); err != nil {
+				e.logger.Error("行ポリシー作成に失敗しました", zap.Error(err))
+				e.status.permanent(err)
+				return err
+			}
+
+			// イベント送信が有効な場合はスキーマの作成を通知
+			e.events.schemaCreated(e.config.profilesDatabase())
+		}
+
+		// 3. 接続テスト
+		if err := e.db.PingContext(ctx); err != nil {
+			e.logger.Error("データベースへの接続テストに失敗しました", zap.Error(err))
+			return err
+		}
+		e.logger.Info("データベース接続とプロファイルテーブル作成に成功しました")
+		e.status.ok()
+
+		// 最初の送信で接続確立の遅延が発生しないよう、最小数の接続を確立しておく（失敗しても送信時に再接続する）
+		if err := e.config.ConnectionPool.prewarm(ctx, e.db); err != nil {
+			e.logger.Warn("接続プールの事前接続に失敗しました", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// shutdown はエクスポーター終了時に呼び出されます
+func (e *profilesExporter) shutdown(ctx context.Context) error {
+	e.logger.Info("プロファイルエクスポーターを終了しています")
+	e.diag.logSummary("profiles", e.logger)
+
+	e.kafka.shutdown()
+	e.live.shutdown()
+	e.summary.shutdown()
+	telemetryErr := errors.Join(e.telemetry.shutdown(), e.forwarder.shutdown(), e.events.shutdown(),
+		unregisterDebug(e.config.Debug.Endpoint, e.debug))
+
+	// 共有接続プールの参照を解放（最後の参照の場合のみ接続を閉じる）
+	if e.db != nil {
+		return errors.Join(telemetryErr, releaseDBConnection(e.db))
+	}
+
+	return telemetryErr
+}
+
+// pushProfiles はプロファイルデータを受信して処理します
+// exporterhelper経由で呼び出される実際のプロファイルデータ処理関数
+// エラーが返された場合、exporterhelperが自動的にリトライやエラー処理を行う
+func (e *profilesExporter) pushProfiles(ctx context.Context, pd pprofile.Profiles) error {
+	// 診断情報にフラッシュ結果を記録
+	finishFlush := e.diag.beginFlush("profiles", e.getProfilesTableName(), pd.SampleCount())
+	// 使用率メトリクス向けに処理中のデータ量と処理時間を記録
+	defer e.telemetry.beginPush((&pprofile.ProtoMarshaler{}).ProfilesSize(pd))()
+
+	resourceProfiles := pd.ResourceProfiles()
+	// 詳細モードで出力するデータをサンプリング（detailed_sampling 未指定の場合は全件）
+	live := e.live.load() // 送信中は同じ値を使用する
+	sampler := newDetailedSampler(live)
+	stringTable := pd.ProfilesDictionary().StringTable()
+	totalProfiles := 0
+	totalSamples := 0
+	var processingErr error
+
+	// 各リソースのプロファイルデータを処理
+	for i := 0; i < resourceProfiles.Len(); i++ {
+		rp := resourceProfiles.At(i)
+		scopeProfiles := rp.ScopeProfiles()
+		for j := 0; j < scopeProfiles.Len(); j++ {
+			sp := scopeProfiles.At(j)
+			profiles := sp.Profiles()
+			totalProfiles += profiles.Len()
+
+			for k := 0; k < profiles.Len(); k++ {
+				profile := profiles.At(k)
+				totalSamples += profile.Sample().Len()
+
+				// 詳細モードが有効な場合、各プロファイルの詳細情報をログ出力
+				switch {
+				case !sampler.sample():
+				case e.detailed.otlp():
+					e.detailed.writeProfile(pd, rp, sp, profile)
+				default:
+					e.detailed.logger.Info(fmt.Sprintf("%s プロファイルを受信しました", live.Prefix),
+						zap.String("profile_id", profile.ProfileID().String()),
+						zap.String("period_type", lookupString(stringTable, profile.PeriodType().TypeStrindex())),
+						zap.Int("samples", profile.Sample().Len()),
+						zap.Time("timestamp", profile.Time().AsTime()),
+						zap.Duration("duration", time.Duration(profile.Duration())),
+					)
+				}
+			}
+
+			// DB未接続（ログ出力のみモード）の場合のデモ目的：意図的にエラーをシミュレートしてメトリクスを生成
+			// 約5%の確率でエラーを発生させる（メトリクス確認用）
+			if e.db == nil && e.forwarder == nil && e.kafka == nil && i%20 == 13 {
+				processingErr = fmt.Errorf("デモエラー: プロファイル処理でシミュレートされたエラー (resource %d)", i)
+				e.logger.Warn("プロファイル検証用のシミュレートエラー", zap.Error(processingErr))
+			}
+		}
+	}
+
+	// DB接続が有効な場合（またはキャプチャが有効な場合）はプロファイルごとに1行をテーブルに挿入する
+	if e.forwarder == nil && (e.db != nil || e.capture != nil) {
+		err := e.insertProfiles(ctx, pd)
+		e.status.recordInsert(err)
+		if err != nil {
+			processingErr = errors.Join(processingErr, err)
+			e.logger.Error("プロファイルの挿入に失敗しました", zap.Error(err))
+			if consumererror.IsPermanent(err) {
+				e.events.batchDropped(stable.DropReasonPermanentError, pd.SampleCount())
+				e.diag.recordDropped("profiles", stable.DropReasonPermanentError, pd.SampleCount())
+			}
+		}
+	}
+
+	// 転送対象の場合はOTLPで転送する（転送に失敗した場合はexporterhelperがリトライする）
+	if e.forwarder != nil {
+		if err := e.forwarder.forwardProfiles(ctx, pd); err != nil {
+			processingErr = err
+			e.logger.Error("プロファイルの転送に失敗しました", zap.Error(err))
+		}
+	}
+
+	// Kafka出力が有効な場合はデータベースへの保存とは独立して発行する
+	// 発行に失敗した場合はexporterhelperがリトライする（copy モードではデータベースへの挿入も再実行される）
+	if e.kafka != nil {
+		if err := e.kafka.publishProfiles(ctx, pd); err != nil {
+			processingErr = errors.Join(processingErr, err)
+			e.logger.Error("プロファイルのKafkaへの発行に失敗しました", zap.Error(err))
+		}
+	}
+
+	// 処理したプロファイルデータのサマリーをログ出力
+	if e.summary.active() {
+		e.summary.record(processingErr, resourceProfiles.Len(), totalProfiles, totalSamples)
+	} else {
+		e.logger.Info(fmt.Sprintf("%s プロファイル処理が完了しました", live.Prefix),
+			zap.Int("resource_profiles", resourceProfiles.Len()),
+			zap.Int("total_profiles", totalProfiles),
+			zap.Int("total_samples", totalSamples),
+			zap.Bool("db_connected", e.db != nil),
+			zap.Bool("has_error", processingErr != nil),
+			sampler.field(),
+		)
+	}
+
+	finishFlush(processingErr)
+
+	// エラーがある場合はそれを返す（exporterhelperがFailedメトリクスを記録）
+	// エラーがない場合はnilを返す（exporterhelperがSentメトリクスを記録）
+	return processingErr
+}
+
+// insertProfiles はプロファイルをプロファイルごとに1行、1トランザクションでテーブルに挿入します
+// DB未接続の場合はキャプチャ（capture.directory 指定時のみ）への書き込みのみを行います
+func (e *profilesExporter) insertProfiles(ctx context.Context, pd pprofile.Profiles) (err error) {
+	// PostgreSQL用のプロファイルテーブルは作成しないため挿入しない
+	if e.config.isPostgres() {
+		return nil
+	}
+	conv := pdatarows.NewConverter(e.config.rowOptions(false))
+	rows := conv.Profiles(pd)
+	if truncated := conv.TruncatedKeys(); truncated > 0 {
+		e.diag.recordTruncatedKeys("profiles", truncated)
+		e.telemetry.recordTruncatedKeys(ctx, truncated)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	table := e.getProfilesTableName()
+	insert, err := renderInsertStatement("profiles_insert.sql", sqltemplates.ProfilesInsert, "", nil, internal.TableTemplateData{
+		Database:   e.config.profilesDatabase(),
+		Table:      table,
+		IngestTime: e.config.RecordIngestTime,
+	})
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "profiles_insert.sql")
+		return err
+	}
+	e.config.stampExportTime(rows)
+	e.capture.write(table, insert.sql, e.config.ingestTimeInsertColumns(pdatarows.ProfileColumns), rows)
+	if e.db == nil {
+		return nil
+	}
+
+	// 挿入結果（行数、所要時間、エラー）を内部メトリクスに記録
+	start := time.Now()
+	defer func() {
+		e.telemetry.recordInsert(ctx, table, len(rows), time.Since(start), err)
+	}()
+
+	// リトライで同じバッチが再送された場合に重複して保存されないよう、内容から重複排除トークンを求める
+	insert.deduplicationToken = e.config.profileDeduplicationToken(e.config.profilesDatabase(), table, pd)
+	return insertRowsWithTimeout(ctx, e.config, e.db, nil, insert, rows)
+}
+
+// lookupString はプロファイル辞書の文字列テーブルからインデックスに対応する文字列を返します
+func lookupString(table pcommon.StringSlice, idx int32) string {
+	if idx < 0 || int(idx) >= table.Len() {
+		return ""
+	}
+	return table.At(int(idx))
+}
+
+// createProfilesTable はClickHouseにプロファイルテーブルを作成します
+func (e *profilesExporter) createProfilesTable(ctx context.Context) error {
+	if e.config.isPostgres() {
+		e.logger.Info("driver: postgres ではプロファイルテーブルを作成しません")
+		return nil
+	}
+
+	// 設定パラメータでSQLテンプレートをレンダリング
+	sql, err := e.renderProfilesTableSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "profiles_table.sql")
+		return fmt.Errorf("プロファイルテーブルSQLのレンダリングに失敗しました: %w", err)
+	}
+
+	// テーブル作成SQLを実行
+	if err := e.executeSQL(ctx, sql); err != nil {
+		return fmt.Errorf("プロファイルテーブルの作成に失敗しました: %w", err)
+	}
+
+	if err := createDistributedTable(ctx, e.config, e.db, e.config.profilesDatabase(),
+		e.getProfilesTableName(), e.config.localTable(e.getProfilesTableName()), e.logger); err != nil {
+		return err
+	}
+
+	e.logger.Info("プロファイルテーブルが正常に作成されました",
+		zap.String("table", e.getProfilesTableName()),
+		zap.String("database", e.config.profilesDatabase()))
+	return nil
+}
+
+// renderProfilesTableSQL は設定値でプロファイルテーブルSQLテンプレートをレンダリングします
+func (e *profilesExporter) renderProfilesTableSQL() (string, error) {
+	return internal.RenderSQLTemplate("profiles_table.sql", internal.TableTemplateData{
+		Database: e.config.profilesDatabase(),
+		Table:    e.config.localTable(e.getProfilesTableName()),
+		Cluster:  e.buildClusterClause(),
+		Engine:   e.buildProfilesEngineClause(),
+		OrderBy:  orderBy(e.config.ProfilesOrderBy, defaultProfilesOrderBy),
+		TTL:      internal.GenerateTTLExpr(e.config.signalTTL("profiles"), "toDateTime(Timestamp)"),
+		Settings: e.config.tableSettings(),
+		MapType:  e.config.mapColumnType(),
+
+		IngestTime: e.config.RecordIngestTime,
+	})
+}
+
+// getProfilesTableName は適切なフォールバックを持つ設定済みプロファイルテーブル名を返します
+func (e *profilesExporter) getProfilesTableName() string {
+	if e.config.ProfilesTableName != "" {
+		return e.config.ProfilesTableName
+	}
+	return "otel_profiles"
+}
+
+// buildProfilesEngineClause はプロファイルテーブル用のClickHouseエンジン句を構築します
+func (e *profilesExporter) buildProfilesEngineClause() string {
+	if e.config.shardedTables() || e.config.ClickHouseCloud {
+		// レプリケーション・分散テーブル構成ではローカルテーブルのエンジン（分散テーブルは別途作成）
+		// ClickHouse Cloud では SharedMergeTree
+		return e.config.replicatedEngine("MergeTree()")
+	}
+	if e.config.ClusterName != "" {
+		// クラスター展開用の分散エンジン
+		return fmt.Sprintf("Distributed(%s, %s, %s_local, rand())",
+			e.config.ClusterName, e.config.profilesDatabase(), e.getProfilesTableName())
+	}
+	return "MergeTree()"
+}
+
+// buildClusterClause はクラスター展開が設定されている場合にクラスター句を構築します
+func (e *profilesExporter) buildClusterClause() string {
+	if e.config.ClusterName != "" && !e.config.replicatedDatabase() {
+		return fmt.Sprintf("ON CLUSTER %s", e.config.ClusterName)
+	}
+	return ""
+}
+
+// executeSQL は適切なエラー処理とログ記録でSQL文を実行します
+func (e *profilesExporter) executeSQL(ctx context.Context, sql string) error {
+	if e.db == nil {
+		return fmt.Errorf("データベース接続が利用できません")
+	}
+
+	e.logger.Debug("SQL文を実行中", zap.String("sql", sql))
+
+	_, err := e.db.ExecContext(ctx, sql)
+	if err != nil {
+		e.logger.Error("SQLの実行に失敗しました", zap.Error(err), zap.String("sql", sql))
+		return fmt.Errorf("SQLの実行に失敗しました: %w", err)
+	}
+
+	return nil
+}
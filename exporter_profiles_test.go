@@ -0,0 +1,57 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+)
+
+// プロファイルはプロファイルごとに1行、テーブルのすべての列（既定値で埋まる IngestTimestamp を除く）を挿入する
+func TestInsertProfilesColumnsMatchTable(t *testing.T) {
+	for _, ingestTime := range []bool{false, true} {
+		dir := t.TempDir()
+		cfg := captureConfig(dir)
+		cfg.RecordIngestTime = ingestTime
+		exp, err := newProfilesExporter(embeddedSettings(zap.NewNop()), cfg)
+		if err != nil {
+			t.Fatalf("newProfilesExporter: %v", err)
+		}
+		pd := pprofile.NewProfiles()
+		pd.ProfilesDictionary().StringTable().Append("", "cpu", "nanoseconds")
+		profiles := pd.ResourceProfiles().AppendEmpty().ScopeProfiles().AppendEmpty().Profiles()
+		for range 2 {
+			profile := profiles.AppendEmpty()
+			profile.PeriodType().SetTypeStrindex(1)
+			profile.PeriodType().SetUnitStrindex(2)
+		}
+		if err := exp.pushProfiles(context.Background(), pd); err != nil {
+			t.Fatalf("pushProfiles: %v", err)
+		}
+		_ = exp.shutdown(context.Background())
+
+		ddl, err := exp.renderProfilesTableSQL()
+		if err != nil {
+			t.Fatalf("renderProfilesTableSQL: %v", err)
+		}
+		batch := readCapture(t, dir, "otel_profiles")
+		if len(batch.Rows) != 2 {
+			t.Fatalf("行数 = %d, want 2", len(batch.Rows))
+		}
+		if got := batch.Rows[0]["PeriodType"]; got != "cpu/nanoseconds" {
+			t.Errorf("PeriodType = %v, want cpu/nanoseconds", got)
+		}
+		got := slices.Sorted(maps.Keys(batch.Rows[0]))
+		want := tableColumns(ddl)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("record_ingest_time: %v の挿入列 = %v, want %v", ingestTime, got, want)
+		}
+	}
+}
@@ -6,71 +6,256 @@ package myexporter
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/zap"
 
 	"github.com/dtamura/myexporter/internal"
 	"github.com/dtamura/myexporter/internal/sqltemplates"
+	"github.com/dtamura/myexporter/pdatarows"
+	"github.com/dtamura/myexporter/stable"
 )
 
 type tracesExporter struct {
+	id     component.ID
 	config *Config
 	logger *zap.Logger
-	db     *sql.DB // DB接続（clickhouseexporterを参考）
+	db     *sql.DB      // DB接続（clickhouseexporterを参考）
+	diag   *diagnostics // zPages（expvarz）向けの診断情報
+
+	telemetry *exporterTelemetry  // コレクターの内部テレメトリに公開するメトリクス
+	forwarder *otlpForwarder      // OTLP転送（passthrough.signals 指定時のみ）
+	kafka     *kafkaPublisher     // Kafkaへの発行（kafka.brokers 指定時のみ）
+	detailed  *detailedOutput     // 詳細モードの出力（log_format に応じた形式）
+	events    *lifecycleEvents    // ライフサイクルイベントの送信（lifecycle_events.endpoint 指定時のみ）
+	status    *componentStatus    // コレクターへの状態報告（start 以降）
+	summary   *pushSummary        // 処理完了ログの集計（summary_interval または live_reload 指定時のみ）
+	live      *liveConfig         // 再起動せずに変更できる設定の現在の値（live_reload）
+	debug     *debugSource        // デバッグエンドポイントへの登録（debug.endpoint 指定時のみ）
+	spool     *diskSpool          // DB障害時のディスク退避（spool.directory 指定時のみ）
+	tenants   *tenantRouter       // テナントごとの挿入先の振り分け（multi_tenancy.routing 有効時のみ）
+	targets   []*exportTarget     // 追加の書き込み先（targets 指定時のみ）
+	warmer    *cacheWarmer        // フラッシュ後のキャッシュの事前読み込み（cache_warming 有効時のみ）
+	source    *sourceStamp        // 行に付与する送信元メタデータ（source_columns 有効時のみ）
+	anomalies *anomalyDetector    // サービスごとの異常度の計算（anomaly_detection 有効時のみ）
+	graph     *serviceGraphWriter // サービスグラフの書き込み（service_graph 有効時のみ）
+	filter    *spanFilter         // OTTL の条件式によるフィルタ（filter.traces 指定時のみ）
+
+	translator *schemaTranslator   // スキーマ変換（schema_translation 有効時のみ）
+	capture    *batchCapture       // 挿入バッチのキャプチャ（capture.directory 指定時のみ）
+	breaker    *circuitBreaker     // 実行中のDB障害時の縮退制御（circuit_breaker 有効時のみ）
+	schema     *schemaCache        // 挿入先テーブルの列定義（DB接続時のみ）
+	native     clickhouse.Conn     // ネイティブバッチ挿入用の接続（use_native_batch 有効時のみ）
+	spanNames  *spanNameNormalizer // スパン名の正規化（traces.span_name_normalization 指定時のみ）
+	state      *stateStore         // 再起動後も引き継ぐ状態の保存先（state_storage 指定時のみ）
+
+	transformer RowTransformer // 挿入直前の行の変換（TracesWriter に WithRowTransformer で登録した場合のみ、既定は何もしない）
 }
 
 // newTracesExporter はトレースエクスポーターの新しいインスタンスを作成します
-func newTracesExporter(logger *zap.Logger, cfg *Config) (*tracesExporter, error) {
+func newTracesExporter(set exporter.Settings, cfg *Config) (*tracesExporter, error) {
+	logger := set.Logger
 	var db *sql.DB
-	var err error
 
-	// DB接続が設定されている場合のみ接続を確立
-	if cfg.Endpoint != "" {
-		db, err = buildDBConnection(cfg)
+	filter, err := newSpanFilter(cfg.Filter, set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	// 転送対象のシグナルはDBに保存せずOTLPで転送する
+	forwarder, err := newOTLPForwarder(cfg.Passthrough, "traces", logger)
+	if err != nil {
+		return nil, err
+	}
+	kafka, err := newKafkaPublisher(cfg.Kafka, "traces", logger)
+	if err != nil {
+		_ = forwarder.shutdown()
+		return nil, err
+	}
+	spool, err := newDiskSpool(cfg.Spool, "traces", logger)
+	if err != nil {
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		return nil, err
+	}
+
+	// DB接続が設定されている場合のみ接続を確立（転送対象・Kafkaにのみ発行するシグナルは接続しない）
+	if cfg.Endpoint != "" && forwarder == nil && !cfg.Kafka.replacesDatabase("traces") {
+		db, err = acquireDBConnection(cfg, logger)
 		if err != nil {
 			logger.Warn("データベース接続に失敗しました、ログ出力のみモードにフォールバックします", zap.Error(err))
 		}
+		warnUnsafeAsyncInsert(cfg, logger)
+		warnDeprecatedTTLDays(cfg, logger)
 	}
 
+	events, err := newLifecycleEvents(cfg.LifecycleEvents, set, "traces", logger)
+	if err != nil {
+		if db != nil {
+			_ = releaseDBConnection(db)
+		}
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		return nil, err
+	}
+
+	telemetry, err := newExporterTelemetry(set.TelemetrySettings, "traces", db, cfg.Utilization)
+	if err != nil {
+		if db != nil {
+			_ = releaseDBConnection(db)
+		}
+		_ = forwarder.shutdown()
+		kafka.shutdown()
+		_ = events.shutdown()
+		return nil, fmt.Errorf("内部メトリクスの作成に失敗しました: %w", err)
+	}
+
+	live := newLiveConfig(cfg, logger)
 	return &tracesExporter{
+		id:     set.ID,
 		config: cfg,
 		logger: logger,
 		db:     db, // DB接続がない場合はnil
+		diag:   getDiagnostics(set.ID),
+
+		telemetry: telemetry,
+		forwarder: forwarder,
+		kafka:     kafka,
+		detailed:  newDetailedOutput(cfg.LogFormat, logger),
+		events:    events,
+		live:      live,
+		summary:   newPushSummary(live, logger, "トレース処理のサマリー", "resource_spans", "total_spans"),
+		spool:     spool,
+		tenants:   newTenantRouter(cfg),
+		source:    newSourceStamp(cfg.SourceColumns, set),
+		anomalies: newAnomalyDetector(cfg.AnomalyDetection, events, logger),
+		graph:     newServiceGraphWriter(cfg, db),
+		filter:    filter,
+		capture:   newBatchCapture(cfg.Capture, logger),
+		breaker:   newCircuitBreaker(cfg.CircuitBreaker, "traces", db, events, logger),
+		spanNames: newSpanNameNormalizer(cfg.Traces.SpanNameNormalization, logger),
+
+		transformer: nopRowTransformer{},
 	}, nil
 }
 
 // Capabilities はトレースエクスポーターの機能を返します
 func (e *tracesExporter) Capabilities() consumer.Capabilities {
-	return consumer.Capabilities{MutatesData: false}
+	return consumer.Capabilities{MutatesData: e.config.mutatesData("traces")}
 }
 
 // start はエクスポーター開始時に呼び出されます
 // DB接続テストとデータベース作成を実行（テーブル作成は行わない）
-func (e *tracesExporter) start(ctx context.Context, host component.Host) error {
+func (e *tracesExporter) start(ctx context.Context, host component.Host) (err error) {
+	// データベース・テーブルの作成などは start_timeout 以内に終わらない場合は中断する
+	ctx, cancel := e.config.startContext(ctx)
+	defer cancel()
+	defer func() { err = startError(ctx, e.config, err) }()
+
 	e.logger.Info("トレースエクスポーターを開始しています",
-		zap.String("prefix", e.config.Prefix),
+		zap.String("prefix", e.live.load().Prefix),
 		zap.Bool("db_enabled", e.db != nil),
 	)
 
+	e.status = newComponentStatus(host)
+
+	// 起動に失敗した場合の調査にも使えるよう、DB接続より前にデバッグエンドポイントへ登録する
+	if e.config.Debug.Endpoint != "" {
+		e.debug = &debugSource{id: e.id, signal: "traces", db: e.db, diag: e.diag, spool: e.spool}
+		if err := registerDebug(e.config.Debug.Endpoint, e.debug, e.logger); err != nil {
+			e.logger.Error("デバッグエンドポイントの起動に失敗しました", zap.Error(err))
+			return err
+		}
+	}
+
+	// スキーマ変換が有効な場合は変換先スキーマファイルを読み込む
+	if e.config.SchemaTranslation.Enabled {
+		translator, err := newSchemaTranslator(ctx, e.config.SchemaTranslation, e.logger)
+		if err != nil {
+			e.logger.Error("スキーマ変換の初期化に失敗しました", zap.Error(err))
+			return err
+		}
+		e.translator = translator
+	}
+
+	// 状態の保存先が指定されている場合は、前回の実行で保存したスパン名の上限の計数を復元
+	state, err := newStateStore(ctx, host, e.config.StateStorage, e.id, "traces")
+	if err != nil {
+		e.logger.Error("状態の保存先の初期化に失敗しました", zap.Error(err))
+		return err
+	}
+	e.state = state
+	var spanNames map[string][]string
+	if ok, err := e.state.load(ctx, spanNameStateKey, &spanNames); err != nil {
+		e.logger.Warn("保存されたスパン名の状態を復元できません、初期状態から開始します", zap.Error(err))
+	} else if ok {
+		e.spanNames.restore(spanNames)
+		e.logger.Info("保存されたスパン名の状態を復元しました", zap.Int("services", len(spanNames)))
+	}
+
+	// スキーマ作成のドライランが有効な場合は、DDLを実行せずにログまたはファイルへ出力
+	if err := dryRunSchemaDDL(e.config, e.logger); err != nil {
+		e.logger.Error("DDLのドライラン出力に失敗しました", zap.Error(err))
+		return err
+	}
+
 	// DB接続が有効な場合、データベース作成と接続テストを実行
 	if e.db != nil {
 		// 1. データベース作成（テーブル作成は無し）
-		if err := createDatabase(ctx, e.config, e.logger); err != nil {
+		if err := createDatabase(ctx, e.config, e.config.tracesDatabase(), e.logger); err != nil {
 			e.logger.Error("データベース作成に失敗しました", zap.Error(err))
 			return err
 		}
 
 		// 2. テーブル作成（新規追加）
 		if e.config.shouldCreateSchema() {
+			// 設定で使用する型をサーバーがサポートしているか確認
+			if err := checkServerVersion(ctx, e.config, e.db); err != nil {
+				e.logger.Error("サーバーのバージョン確認に失敗しました", zap.Error(err))
+				e.diag.recordError("traces", err)
+				e.status.permanent(err)
+				return err
+			}
+
 			if err := e.createTraceTables(ctx); err != nil {
 				e.logger.Error("トレーステーブル作成に失敗しました", zap.Error(err))
+				e.diag.recordError("traces", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			// 既存テーブルに未適用のスキーマ変更を適用
+			if err := applyMigrations(ctx, e.config, e.db, "traces", e.config.tracesDatabase(), e.config.localTable(e.config.TracesTableName), e.logger); err != nil {
+				e.logger.Error("マイグレーションの適用に失敗しました", zap.Error(err))
+				e.diag.recordError("traces", err)
+				e.status.permanent(err)
+				return err
+			}
+
+			// 容量の大きい列に列TTLを設定（column_ttl 指定時のみ）
+			if err := applyColumnTTL(ctx, e.config, "traces", e.db, e.logger); err != nil {
+				e.logger.Error("列TTLの設定に失敗しました", zap.Error(err))
+				e.status.permanent(err)
+				return err
+			}
+
+			// テナントごとの行ポリシー作成（マルチテナント有効時のみ）
+			if err := createRowPolicies(ctx, e.config, "traces", e.db, e.logger); err != nil {
+				e.logger.Error("行ポリシー作成に失敗しました", zap.Error(err))
+				e.status.permanent(err)
 				return err
 			}
+
+			// イベント送信が有効な場合はスキーマの作成を通知
+			e.events.schemaCreated(e.config.tracesDatabase())
 		}
 
 		// 3. 接続テスト
@@ -82,6 +267,52 @@ func (e *tracesExporter) start(ctx context.Context, host component.Host) error {
 			return err
 		}
 		e.logger.Info("データベース接続に成功しました")
+		e.status.ok()
+
+		// use_native_batch 有効時はネイティブバッチ用の接続を作成
+		native, err := openNativeConn(e.config)
+		if err != nil {
+			e.logger.Error("ネイティブバッチ用の接続の作成に失敗しました", zap.Error(err))
+			return err
+		}
+		e.native = native
+
+		// 最初の送信で接続確立の遅延が発生しないよう、最小数の接続を確立しておく（失敗しても送信時に再接続する）
+		if err := e.config.ConnectionPool.prewarm(ctx, e.db); err != nil {
+			e.logger.Warn("接続プールの事前接続に失敗しました", zap.Error(err))
+		}
+
+		// 挿入先テーブルの列定義は最初の挿入時に読み込み、テーブルが直接変更された場合は読み直す
+		e.schema = newSchemaCache(e.config, e.db, e.config.tracesDatabase(), e.config.TracesTableName, e.config.expectedColumns("traces"), e.logger)
+		if err := registerSchemaRefresh(e.config.SchemaRefresh.Endpoint, e.schema, e.logger); err != nil {
+			e.logger.Error("テーブル定義の再読み込みエンドポイントの起動に失敗しました", zap.Error(err))
+			return err
+		}
+
+		// スキーマを作成しない場合は、挿入時のエラーではなく開始時に列の不足を検出する
+		if e.config.shouldVerifySchema() {
+			if err := e.schema.verify(ctx); err != nil {
+				e.logger.Error("テーブル定義の確認に失敗しました", zap.Error(err))
+				e.diag.recordError("traces", err)
+				e.status.permanent(err)
+				return err
+			}
+		}
+
+		// ダッシュボードの初回表示向けに、大きなフラッシュの後に最新のパーティションを読み込む
+		e.warmer = newCacheWarmer(e.config, e.db, e.config.tracesDatabase(), e.config.TracesTableName, "Timestamp", e.logger)
+
+		// 追加の書き込み先への接続とスキーマ作成（targets 指定時のみ）
+		if err := e.startTargets(ctx); err != nil {
+			e.logger.Error("追加の書き込み先の開始に失敗しました", zap.Error(err))
+			e.status.permanent(err)
+			return err
+		}
+	}
+
+	// スプールが有効な場合は退避したセグメントの再挿入を開始（前回の実行で残ったセグメントも対象）
+	if e.spool != nil && e.db != nil {
+		e.spool.startReplay(e.replaySpooledTraces)
 	}
 
 	return nil
@@ -91,19 +322,71 @@ func (e *tracesExporter) start(ctx context.Context, host component.Host) error {
 // clickhouseexporterのshutdown関数を参考
 func (e *tracesExporter) shutdown(ctx context.Context) error {
 	e.logger.Info("トレースエクスポーターを終了しています")
+	e.diag.logSummary("traces", e.logger)
+
+	// 再挿入ループを停止してから接続を解放する
+	e.spool.shutdown()
+	e.kafka.shutdown()
+	e.live.shutdown()
+	e.summary.shutdown()
+	e.warmer.shutdown()
+	telemetryErr := errors.Join(e.telemetry.shutdown(), e.forwarder.shutdown(), e.events.shutdown(),
+		unregisterSchemaRefresh(e.config.SchemaRefresh.Endpoint, e.schema), unregisterDebug(e.config.Debug.Endpoint, e.debug))
+
+	// 次回の起動で復元できるよう状態を保存
+	if names := e.spanNames.snapshot(); names != nil {
+		telemetryErr = errors.Join(telemetryErr, e.state.save(ctx, spanNameStateKey, names))
+	}
+	telemetryErr = errors.Join(telemetryErr, e.state.close(ctx))
 
+	// 共有接続プールの参照を解放（最後の参照の場合のみ接続を閉じる）
+	telemetryErr = errors.Join(telemetryErr, closeExportTargets(e.targets), closeNativeConn(e.native))
 	if e.db != nil {
-		return e.db.Close()
+		return errors.Join(telemetryErr, releaseDBConnection(e.db))
 	}
 
-	return nil
+	return telemetryErr
 }
 
 // pushTraces はトレースデータを受信して処理します
 // exporterhelper経由で呼び出される実際のトレースデータ処理関数
 // エラーが返された場合、exporterhelperが自動的にリトライやエラー処理を行う
 func (e *tracesExporter) pushTraces(ctx context.Context, td ptrace.Traces) error {
+	// filter.traces の条件に従ってスパンを破棄（filter_preview 有効時は破棄される件数のみを記録）
+	filtered, err := filterTraces(ctx, e.filter, td, !e.config.FilterPreview)
+	if err := reportFilter(ctx, e.config, e.diag, e.telemetry, e.events, e.logger, "traces", filtered, err); err != nil {
+		return err
+	}
+
+	// 診断情報にフラッシュ結果を記録
+	finishFlush := e.diag.beginFlush("traces", e.config.TracesTableName, td.SpanCount())
+	// 使用率メトリクス向けに処理中のデータ量と処理時間を記録
+	defer e.telemetry.beginPush((&ptrace.ProtoMarshaler{}).TracesSize(td))()
+
+	// 古いセマンティック規約バージョンの属性を変換先バージョンに更新
+	if e.translator != nil {
+		e.translator.translateTraces(td)
+	}
+
+	// 1つのサービスがバッチの大半を占める場合は、上限を超えたスパンの挿入を後回しにする
+	deferred := ptrace.NewTraces()
+	if e.forwarder == nil && e.db != nil && e.config.Fairness.MaxServiceShare > 0 {
+		deferred = deferTracesOverShare(e.config.Fairness, td)
+		if n := deferred.SpanCount(); n > 0 {
+			e.telemetry.recordDeferred(ctx, n)
+			e.logger.Debug("サービスの割合の上限を超えたスパンの挿入を後回しにしました",
+				zap.Int("deferred", n),
+				zap.Float64("max_service_share", e.config.Fairness.MaxServiceShare))
+		}
+	}
+
+	// サービスごとのエラー率・レイテンシを基準値と比較して異常度を更新（挿入時に AnomalyScore 列に保存する）
+	e.anomalies.observe(td)
+
 	resourceSpans := td.ResourceSpans()
+	// 詳細モードで出力するデータをサンプリング（detailed_sampling 未指定の場合は全件）
+	live := e.live.load() // 送信中は同じ値を使用する
+	sampler := newDetailedSampler(live)
 	totalSpans := 0
 	var processingErr error
 
@@ -117,10 +400,17 @@ func (e *tracesExporter) pushTraces(ctx context.Context, td ptrace.Traces) error
 			totalSpans += spans.Len()
 
 			// 詳細モードが有効な場合、各スパンの詳細情報をログ出力
-			if e.config.Detailed {
+			if live.Detailed {
 				for k := 0; k < spans.Len(); k++ {
+					if !sampler.sample() {
+						continue
+					}
 					span := spans.At(k)
-					e.logger.Info(fmt.Sprintf("%s トレースを受信しました", e.config.Prefix),
+					if e.detailed.otlp() {
+						e.detailed.writeSpan(rs, ss, span)
+						continue
+					}
+					e.detailed.logger.Info(fmt.Sprintf("%s トレースを受信しました", live.Prefix),
 						zap.String("span_id", span.SpanID().String()),
 						zap.String("trace_id", span.TraceID().String()),
 						zap.String("name", span.Name()),
@@ -130,87 +420,269 @@ func (e *tracesExporter) pushTraces(ctx context.Context, td ptrace.Traces) error
 				}
 			}
 
-			// 現在はデータ投入を無効化（DB接続テストのみ）
-			// TODO: 将来的にデータ投入機能を実装予定
-			//
-			// デモ目的：意図的にエラーをシミュレートしてメトリクスを生成
+			// DB未接続（ログ出力のみモード）の場合のデモ目的：意図的にエラーをシミュレートしてメトリクスを生成
 			// 10%の確率でエラーを発生させる（メトリクス確認用）
-			if i%10 == 7 {
+			if e.db == nil && e.forwarder == nil && e.kafka == nil && i%10 == 7 {
 				processingErr = fmt.Errorf("デモエラー: スパン処理でシミュレートされたエラー (resource %d)", i)
 				e.logger.Warn("メトリクス検証用のシミュレートエラー", zap.Error(processingErr))
 			}
 		}
 	}
 
+	// DB接続が有効な場合（またはキャプチャが有効な場合）はスパンをClickHouseに挿入
+	// サーキットブレーカーがオープンの間は挿入せず、ログ出力のみモードで動作する
+	// 転送対象の場合はDBに保存せずOTLPで転送する
+	if e.forwarder != nil {
+		if err := e.forwarder.forwardTraces(ctx, td); err != nil {
+			processingErr = err
+			e.logger.Error("トレースの転送に失敗しました", zap.Error(err))
+		}
+	} else if e.db != nil || e.capture != nil {
+		// 追加の書き込み先への挿入はバッチを変更しないため、endpoint と同じバッチをすべて書き込む
+		targetErr := e.writeTargets(ctx, td)
+		if e.breaker.allow(ctx) {
+			rest, err := e.insertRoutedTraces(ctx, td)
+			e.breaker.record(err)
+			e.status.recordInsert(err)
+			if err != nil {
+				// リトライ・スプールの対象は挿入できなかった残りのみ（挿入に成功した分割・テナントを重複させない）
+				retainTraces(td, rest)
+			}
+			if err == nil {
+				e.warmer.afterFlush(td.SpanCount())
+				// サービスグラフはスパンの挿入に成功したバッチのみ書き込む（リトライで呼び出し数が重複しないようにする）
+				if err := e.graph.write(ctx, td); err != nil {
+					e.logger.Warn("サービスグラフの書き込みに失敗しました", zap.Error(err))
+					e.diag.recordError("traces", err)
+				}
+			} else {
+				e.logger.Error("トレースの挿入に失敗しました", zap.Error(err))
+				if consumererror.IsPermanent(err) {
+					e.events.batchDropped(stable.DropReasonPermanentError, td.SpanCount())
+					e.diag.recordDropped("traces", stable.DropReasonPermanentError, td.SpanCount())
+				}
+				processingErr = e.spoolTraces(td, err)
+			}
+		} else if e.spool != nil {
+			processingErr = e.spoolTraces(td, nil)
+		} else {
+			e.logger.Warn("サーキットブレーカーがオープンのためトレースの挿入をスキップしました",
+				zap.Int("dropped_items", td.SpanCount()))
+			e.events.batchDropped(stable.DropReasonCircuitBreakerOpen, td.SpanCount())
+			e.diag.recordDropped("traces", stable.DropReasonCircuitBreakerOpen, td.SpanCount())
+		}
+		processingErr = errors.Join(processingErr, targetErr)
+	}
+
+	// Kafka出力が有効な場合はデータベースへの保存とは独立して発行する
+	// 発行に失敗した場合はexporterhelperがリトライする（copy モードではデータベースへの挿入も再実行される）
+	if e.kafka != nil {
+		if err := e.kafka.publishTraces(ctx, td); err != nil {
+			processingErr = errors.Join(processingErr, err)
+			e.logger.Error("トレースのKafkaへの発行に失敗しました", zap.Error(err))
+		}
+	}
+
 	// 処理したトレースデータのサマリーをログ出力
-	e.logger.Info(fmt.Sprintf("%s トレース処理が完了しました", e.config.Prefix),
-		zap.Int("resource_spans", resourceSpans.Len()),
-		zap.Int("total_spans", totalSpans),
-		zap.Bool("db_connected", e.db != nil),
-		zap.Bool("has_error", processingErr != nil),
-	)
+	if e.summary.active() {
+		e.summary.record(processingErr, resourceSpans.Len(), totalSpans)
+	} else {
+		e.logger.Info(fmt.Sprintf("%s トレース処理が完了しました", live.Prefix),
+			zap.Int("resource_spans", resourceSpans.Len()),
+			zap.Int("total_spans", totalSpans),
+			zap.Bool("db_connected", e.db != nil),
+			zap.Bool("has_error", processingErr != nil),
+			sampler.field(),
+		)
+	}
+
+	finishFlush(processingErr)
+
+	// 後回しにしたスパンのみをリトライで再送させる
+	// 他のエラーでバッチ全体がリトライされる場合は、後回しにしたスパンをバッチに戻す
+	if deferred.SpanCount() > 0 {
+		if processingErr == nil {
+			return consumererror.NewTraces(errFairnessDeferred, deferred)
+		}
+		deferred.ResourceSpans().MoveAndAppendTo(td.ResourceSpans())
+	}
 
 	// エラーがある場合はそれを返す（exporterhelperがFailedメトリクスを記録）
 	// エラーがない場合はnilを返す（exporterhelperがSentメトリクスを記録）
 	return processingErr
 }
 
+// spoolTraces は挿入できなかったトレースをスプールに退避します
+// 退避に成功した場合は再挿入で保存されるため nil を返し、スプールが無効または退避に失敗した場合は cause を返します
+func (e *tracesExporter) spoolTraces(td ptrace.Traces, cause error) error {
+	// 永続的なエラー（スキーマの不一致など）は再挿入しても成功しないため退避しない
+	if e.spool == nil || consumererror.IsPermanent(cause) {
+		return cause
+	}
+	payload, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(td)
+	if err == nil {
+		err = e.spool.write(payload)
+	}
+	if err != nil {
+		e.logger.Error("トレースのスプールへの退避に失敗しました", zap.Error(err))
+		return errors.Join(cause, err)
+	}
+	e.logger.Warn("挿入できなかったトレースをスプールに退避しました、復旧後に再挿入します",
+		zap.Int("items", td.SpanCount()), zap.Int("bytes", len(payload)))
+	return nil
+}
+
+// replaySpooledTraces はスプールのセグメントをトレースに復元して再挿入します
+func (e *tracesExporter) replaySpooledTraces(ctx context.Context, payload []byte) error {
+	td, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(payload)
+	if err != nil {
+		// 破損したセグメントは再挿入できないため、ログに記録して破棄する
+		e.logger.Error("スプールのセグメントを復元できないため破棄します", zap.Error(err))
+		return nil
+	}
+	if !e.breaker.allow(ctx) {
+		return fmt.Errorf("サーキットブレーカーがオープンのため再挿入を延期します")
+	}
+	_, err = e.insertRoutedTraces(ctx, td)
+	e.breaker.record(err)
+	e.status.recordInsert(err)
+	if consumererror.IsPermanent(err) {
+		// 再挿入しても成功しないため、ログに記録して破棄する
+		e.logger.Error("スプールのセグメントを再挿入できないため破棄します", zap.Error(err))
+		e.diag.recordDropped("traces", stable.DropReasonPermanentError, td.SpanCount())
+		return nil
+	}
+	return err
+}
+
 // createTraceTables - トレース用のテーブルを作成します
 func (e *tracesExporter) createTraceTables(ctx context.Context) error {
+	// PostgreSQLではスキーマとトレーステーブル（ハイパーテーブル）のみを作成（ID-タイムスタンプ検索はインデックスで代替）
+	if e.config.isPostgres() {
+		return createPostgresTables(ctx, e.config, e.db, "traces", e.logger)
+	}
+
 	e.logger.Info("トレーステーブル作成を開始します",
-		zap.String("database", e.config.database()),
+		zap.String("database", e.config.tracesDatabase()),
 		zap.String("table", e.config.TracesTableName))
 
 	// 1. メインのトレーステーブルを作成
-	createTableSQL := e.renderCreateTracesTableSQL()
+	createTableSQL, err := e.renderCreateTracesTableSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "traces_table.sql")
+		return err
+	}
 	if err := e.execSQL(ctx, createTableSQL, "traces table"); err != nil {
 		return err
 	}
+	if err := createDistributedTable(ctx, e.config, e.db, e.config.tracesDatabase(),
+		e.config.TracesTableName, e.config.localTable(e.config.TracesTableName), e.logger); err != nil {
+		return err
+	}
+
+	if e.config.Traces.LinksTable {
+		if err := e.createTraceLinksTable(ctx); err != nil {
+			return err
+		}
+	}
+
+	if e.config.Traces.RootsTable {
+		if err := e.createTraceRootsTable(ctx); err != nil {
+			return err
+		}
+	}
+
+	if e.config.ServiceGraph.Enabled {
+		if err := e.createServiceGraphTable(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := createAggregationViews(ctx, e.config, e.db, e.telemetry, "traces", e.config.tracesDatabase(), e.config.TracesTableName, e.logger); err != nil {
+		return err
+	}
+
+	if !e.config.TraceIDLookup.LookupTableEnabled {
+		e.logger.Info("trace_id_lookup.lookup_table_enabled が無効のため、検索テーブルとマテリアライズドビューは作成しません")
+		e.logger.Info("トレーステーブル作成が完了しました")
+		return nil
+	}
 
 	// 2. トレースID-タイムスタンプ検索用テーブルを作成
-	createTsTableSQL := e.renderCreateTraceIDTsTableSQL()
+	createTsTableSQL, err := e.renderCreateTraceIDTsTableSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "traces_id_ts_lookup_table.sql")
+		return err
+	}
 	if err := e.execSQL(ctx, createTsTableSQL, "trace ID timestamp table"); err != nil {
 		return err
 	}
 
 	// 3. トレースID-タイムスタンプ検索用マテリアライズドビューを作成
-	createTsViewSQL := e.renderTraceIDTsMaterializedViewSQL()
+	createTsViewSQL, err := e.renderTraceIDTsMaterializedViewSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "traces_id_ts_lookup_mv.sql")
+		return err
+	}
 	if err := e.execSQL(ctx, createTsViewSQL, "trace ID timestamp materialized view"); err != nil {
 		return err
 	}
 
+	// 分散テーブル構成の場合、ビューはシャードごとのローカルテーブル間で動作するため検索テーブルの分散テーブルを作成
+	if err := createDistributedTable(ctx, e.config, e.db, e.config.tracesDatabase(),
+		e.config.traceLookupTable(), e.config.localTable(e.config.TracesTableName)+"_trace_id_ts", e.logger); err != nil {
+		return err
+	}
+
 	e.logger.Info("トレーステーブル作成が完了しました")
 	return nil
 }
 
 // renderCreateTracesTableSQL - メインのトレーステーブル作成SQLを生成
-func (e *tracesExporter) renderCreateTracesTableSQL() string {
-	ttlExpr := internal.GenerateTTLExpr(e.config.TTL, "toDateTime(Timestamp)")
-	return fmt.Sprintf(sqltemplates.TracesCreateTable,
-		e.config.database(), e.config.TracesTableName, e.config.clusterString(),
-		e.config.tableEngineString(),
-		ttlExpr,
-	)
+func (e *tracesExporter) renderCreateTracesTableSQL() (string, error) {
+	return internal.ExecuteSQLTemplate("traces_table.sql", sqltemplates.TracesCreateTable, internal.TableTemplateData{
+		Database: e.config.tracesDatabase(),
+		Table:    e.config.localTable(e.config.TracesTableName),
+		Cluster:  e.config.clusterString(),
+		Engine:   e.config.replicatedEngine(e.config.tableEngineString()),
+		OrderBy:  orderBy(e.config.TracesOrderBy, defaultTracesOrderBy),
+		TTL:      internal.GenerateTTLExpr(e.config.signalTTL("traces"), "toDateTime(Timestamp)"),
+		Settings: e.config.tableSettings(),
+
+		AttributesType: e.config.attributesColumnType(),
+		MapType:        e.config.mapColumnType(),
+		JSONAttributes: e.config.jsonAttributes(),
+		SourceColumns:  e.config.SourceColumns.Enabled,
+		AnomalyScore:   e.config.AnomalyDetection.columnEnabled(),
+		Truncated:      e.config.truncatedColumnEnabled("traces"),
+		TraceScope:     e.config.Traces.StoreScope,
+		IngestTime:     e.config.RecordIngestTime,
+	})
 }
 
 // renderCreateTraceIDTsTableSQL - トレースID-タイムスタンプ検索テーブル作成SQLを生成
-func (e *tracesExporter) renderCreateTraceIDTsTableSQL() string {
-	ttlExpr := internal.GenerateTTLExpr(e.config.TTL, "toDateTime(Start)")
-	return fmt.Sprintf(sqltemplates.TracesCreateTsTable,
-		e.config.database(), e.config.TracesTableName, e.config.clusterString(),
-		e.config.tableEngineString(),
-		ttlExpr,
-	)
+func (e *tracesExporter) renderCreateTraceIDTsTableSQL() (string, error) {
+	lookup := e.config.TraceIDLookup
+	return internal.ExecuteSQLTemplate("traces_id_ts_lookup_table.sql", sqltemplates.TracesCreateTsTable, internal.TableTemplateData{
+		Database: e.config.tracesDatabase(),
+		Table:    e.config.localTable(e.config.TracesTableName),
+		Cluster:  e.config.clusterString(),
+		Engine:   e.config.replicatedEngine(lookup.engineString(e.config.tableEngineString())),
+		OrderBy:  lookup.orderBy(),
+		TTL:      internal.GenerateTTLExpr(lookup.ttl(e.config.signalTTL("traces")), "toDateTime(Start)"),
+		Settings: e.config.tableSettings(),
+
+		AggregateColumns: lookup.Engine == "AggregatingMergeTree",
+	})
 }
 
 // renderTraceIDTsMaterializedViewSQL - トレースID-タイムスタンプマテリアライズドビュー作成SQLを生成
-func (e *tracesExporter) renderTraceIDTsMaterializedViewSQL() string {
-	database := e.config.database()
-	return fmt.Sprintf(sqltemplates.TracesCreateTsView,
-		database, e.config.TracesTableName, e.config.clusterString(),
-		database, e.config.TracesTableName,
-		database, e.config.TracesTableName,
-	)
+func (e *tracesExporter) renderTraceIDTsMaterializedViewSQL() (string, error) {
+	return internal.ExecuteSQLTemplate("traces_id_ts_lookup_mv.sql", sqltemplates.TracesCreateTsView, internal.TableTemplateData{
+		Database: e.config.tracesDatabase(),
+		Table:    e.config.localTable(e.config.TracesTableName),
+		Cluster:  e.config.clusterString(),
+	})
 }
 
 // execSQL - SQLを実行してエラーログを出力するヘルパーメソッド
@@ -232,9 +704,122 @@ func (e *tracesExporter) execSQL(ctx context.Context, sql, description string) e
 	return nil
 }
 
-// insertSpanToDB は将来実装予定のDB挿入機能
-// 現在は DB接続テストのみ実装
-// func (e *tracesExporter) insertSpanToDB(ctx context.Context, span ptrace.Span) error {
-// 	// TODO: ClickHouse用の挿入処理を実装
-// 	return nil
-// }
+// traceInsertColumns は traces_insert.sql の列順です（insert_sql.traces の名前付きプレースホルダーにも使用）
+var traceInsertColumns = pdatarows.TraceColumns
+
+// insertTraces はトレースデータを1トランザクション（1バッチ）でClickHouseに挿入します
+// 属性は attributes_format に応じて Map または JSON に変換されます
+// キャプチャが有効な場合は挿入前のバッチをファイルに出力します（DB未接続の場合は出力のみ）
+// テーブル定義の不一致で失敗した場合は、列定義を読み直して1回だけ再挿入します（retryAfterReload）
+func (e *tracesExporter) insertTraces(ctx context.Context, td ptrace.Traces) error {
+	err := e.insertTracesOnce(ctx, td)
+	if e.schema.retryAfterReload(err) {
+		err = e.insertTracesOnce(ctx, td)
+	}
+	return err
+}
+
+// insertTracesOnce はトレースデータを1回挿入します（挿入の失敗時は invalidateOnError で列定義を無効化）
+func (e *tracesExporter) insertTracesOnce(ctx context.Context, td ptrace.Traces) (err error) {
+	columns := e.config.insertColumns("traces")
+	insert, err := renderInsertStatement("traces_insert.sql", e.config.insertTemplate("traces"), e.config.customInsertSQL("traces"),
+		columns, internal.TableTemplateData{
+			Database:      e.config.tracesDatabase(),
+			Table:         e.config.TracesTableName,
+			SourceColumns: e.config.SourceColumns.Enabled,
+			AnomalyScore:  e.config.AnomalyDetection.columnEnabled(),
+			Truncated:     e.config.truncatedColumnEnabled("traces"),
+			TraceScope:    e.config.Traces.StoreScope,
+			IngestTime:    e.config.RecordIngestTime,
+			ExtraColumns:  e.transformer.Columns(),
+		})
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "traces_insert.sql")
+		return err
+	}
+	// insert_sql の名前付きプレースホルダーは ? に展開されるため、PostgreSQLの番号付きに変換
+	if e.config.isPostgres() {
+		insert.sql = rebindPostgres(insert.sql)
+	}
+
+	// テーブルの属性カラムの実際の型に合わせてエンコードする（属性カラムが直接変更された場合に追従するため）
+	opts := e.config.rowOptions(e.schema.get(ctx).jsonAttributes("SpanAttributes", e.config.jsonAttributes()))
+	if e.spanNames != nil {
+		opts.SpanName = e.spanNames.normalize
+	}
+	conv := pdatarows.NewConverter(opts)
+	rows, err := conv.Traces(td)
+	if err != nil {
+		return err
+	}
+	if truncated := conv.TruncatedKeys(); truncated > 0 {
+		e.diag.recordTruncatedKeys("traces", truncated)
+		e.telemetry.recordTruncatedKeys(ctx, truncated)
+		e.logger.Debug("最大長を超えた属性キーを短縮しました",
+			zap.Int("truncated_keys", truncated),
+			zap.Int("max_attribute_key_length", e.config.MaxAttributeKeyLength))
+	}
+	if truncated := e.config.stampTruncated("traces", rows, conv.TruncatedRows()); truncated > 0 {
+		e.logger.Debug("最大長を超えた属性値を短縮しました",
+			zap.Int("truncated_rows", truncated),
+			zap.Int("max_attribute_length", e.config.MaxAttributeLength))
+	}
+	e.source.stamp(ctx, rows)
+	e.anomalies.stamp(rows)
+	e.config.stampExportTime(rows)
+	if columns, err = transformRows(ctx, e.transformer, columns, rows); err != nil {
+		return err
+	}
+	e.capture.write(e.config.TracesTableName, insert.sql, columns, rows)
+	if e.db == nil {
+		return nil
+	}
+
+	// 挿入結果（行数、所要時間、エラー）を内部メトリクスに記録
+	start := time.Now()
+	defer func() {
+		e.telemetry.recordInsert(ctx, e.config.TracesTableName, len(rows), time.Since(start), err)
+		e.schema.invalidateOnError(err)
+	}()
+
+	// リトライで同じバッチが再送された場合に重複して保存されないよう、内容から重複排除トークンを求める
+	insert.deduplicationToken = e.config.traceDeduplicationToken(e.config.tracesDatabase(), e.config.TracesTableName, td)
+	return insertRowsWithTimeout(ctx, e.config, e.db, e.native, insert, rows)
+}
+
+// derive は設定とDB接続のみを差し替えたエクスポーターを作成します（テナント・追加の書き込み先への挿入用）
+// 診断情報・内部メトリクス・送信元メタデータなどは元のエクスポーターと共有します
+func (e *tracesExporter) derive(cfg *Config, db *sql.DB, logger *zap.Logger) *tracesExporter {
+	return &tracesExporter{
+		id:        e.id,
+		config:    cfg,
+		logger:    logger,
+		db:        db,
+		diag:      e.diag,
+		telemetry: e.telemetry,
+		source:    e.source,
+		anomalies: e.anomalies,
+		capture:   e.capture,
+		spanNames: e.spanNames,
+		native:    e.native,
+
+		transformer: e.transformer,
+	}
+}
+
+// prepareSchema は挿入先のデータベースとテーブルを作成し、マイグレーションと列TTLを適用します（create_schema 有効時のみ）
+func (e *tracesExporter) prepareSchema(ctx context.Context) error {
+	if !e.config.shouldCreateSchema() {
+		return nil
+	}
+	if err := createDatabase(ctx, e.config, e.config.tracesDatabase(), e.logger); err != nil {
+		return err
+	}
+	if err := e.createTraceTables(ctx); err != nil {
+		return err
+	}
+	if err := applyMigrations(ctx, e.config, e.db, "traces", e.config.tracesDatabase(), e.config.localTable(e.config.TracesTableName), e.logger); err != nil {
+		return err
+	}
+	return applyColumnTTL(ctx, e.config, "traces", e.db, e.logger)
+}
@@ -10,6 +10,8 @@ import (
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/exporter/exporterhelper/xexporterhelper"
+	"go.opentelemetry.io/collector/exporter/xexporter"
 )
 
 const (
@@ -17,13 +19,15 @@ const (
 )
 
 // NewFactory creates a factory for the my-log exporter.
-func NewFactory() exporter.Factory {
-	return exporter.NewFactory(
+// プロファイルシグナルに対応するため xexporter のファクトリーを使用する
+func NewFactory() xexporter.Factory {
+	return xexporter.NewFactory(
 		component.MustNewType(typeStr),
 		createDefaultConfig,
-		exporter.WithTraces(createTracesExporter, component.StabilityLevelDevelopment),
-		exporter.WithMetrics(createMetricsExporter, component.StabilityLevelDevelopment),
-		exporter.WithLogs(createLogsExporter, component.StabilityLevelDevelopment),
+		xexporter.WithTraces(createTracesExporter, component.StabilityLevelDevelopment),
+		xexporter.WithMetrics(createMetricsExporter, component.StabilityLevelDevelopment),
+		xexporter.WithLogs(createLogsExporter, component.StabilityLevelDevelopment),
+		xexporter.WithProfiles(createProfilesExporter, component.StabilityLevelDevelopment),
 	)
 }
 
@@ -33,7 +37,7 @@ func createTracesExporter(
 	cfg component.Config,
 ) (exporter.Traces, error) {
 	config := cfg.(*Config)
-	exporter, err := newTracesExporter(set.Logger, config)
+	exporter, err := newTracesExporter(set, config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot configure my-log traces exporter: %w", err)
 	}
@@ -59,7 +63,7 @@ func createMetricsExporter(
 	cfg component.Config,
 ) (exporter.Metrics, error) {
 	config := cfg.(*Config)
-	exporter, err := newMetricsExporter(set.Logger, config)
+	exporter, err := newMetricsExporter(set, config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot configure my-log metrics exporter: %w", err)
 	}
@@ -84,7 +88,7 @@ func createLogsExporter(
 	cfg component.Config,
 ) (exporter.Logs, error) {
 	config := cfg.(*Config)
-	exporter, err := newLogsExporter(set.Logger, config)
+	exporter, err := newLogsExporter(set, config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot configure my-log logs exporter: %w", err)
 	}
@@ -102,3 +106,28 @@ func createLogsExporter(
 		exporterhelper.WithCapabilities(exporter.Capabilities()),
 	)
 }
+
+func createProfilesExporter(
+	ctx context.Context,
+	set exporter.Settings,
+	cfg component.Config,
+) (xexporter.Profiles, error) {
+	config := cfg.(*Config)
+	exporter, err := newProfilesExporter(set, config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot configure my-log profiles exporter: %w", err)
+	}
+
+	// xexporterhelper.NewProfilesを使用してプロファイルエクスポーターを作成
+	// 他のシグナルと同様にリトライ、キューイング、タイムアウトが組み込まれる
+	return xexporterhelper.NewProfiles(ctx, set, cfg,
+		exporter.pushProfiles, // 実際のプロファイル処理を行う関数
+		exporterhelper.WithStart(exporter.start),
+		exporterhelper.WithShutdown(exporter.shutdown),
+		exporterhelper.WithTimeout(config.TimeoutSettings),
+		exporterhelper.WithRetry(config.BackOffConfig),
+		exporterhelper.WithQueue(config.QueueSettings),
+		// データを変更しないことを明示（読み取り専用）
+		exporterhelper.WithCapabilities(exporter.Capabilities()),
+	)
+}
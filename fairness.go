@@ -0,0 +1,161 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// errFairnessDeferred は fairness により後回しにしたデータをリトライさせるためのエラーです
+var errFairnessDeferred = stable.ErrFairnessDeferred
+
+// FairnessConfig - サービスごとの挿入の公平性の設定
+// 1つのサービスがバッチの大半を占める場合に、そのサービスの1回の挿入に占める割合を制限し、
+// 超えた分を exporterhelper のリトライで後から挿入します（他のサービスのデータが先に挿入される）
+// 後回しにしたデータはリトライの間隔（retry_on_failure.initial_interval 以降）だけ遅れて挿入されます
+type FairnessConfig struct {
+	// MaxServiceShare はバッチに占める1サービス（service.name）のアイテム数の割合の上限です（0 の場合は無効）
+	MaxServiceShare float64 `mapstructure:"max_service_share"`
+	// MinBatchSize は上限を適用するバッチの最小アイテム数です（小さいバッチは後回しにしない）
+	MinBatchSize int `mapstructure:"min_batch_size"`
+}
+
+// validateFairness は公平性の設定を検証します
+func (cfg *Config) validateFairness() error {
+	c := cfg.Fairness
+	if c.MaxServiceShare == 0 {
+		return nil
+	}
+	var errs error
+	if c.MaxServiceShare < 0 || c.MaxServiceShare >= 1 {
+		errs = errors.Join(errs, fmt.Errorf("fairness.max_service_share は0より大きく1未満である必要があります: %g", c.MaxServiceShare))
+	}
+	if c.MinBatchSize < 0 {
+		errs = errors.Join(errs, fmt.Errorf("fairness.min_batch_size は0以上である必要があります: %d", c.MinBatchSize))
+	}
+	// 後回しにしたデータはリトライでのみ再送されるため、リトライが無効の場合は失われる
+	if !cfg.BackOffConfig.Enabled {
+		errs = errors.Join(errs, errors.New("fairness.max_service_share を指定する場合は retry_on_failure.enabled を有効にしてください"))
+	}
+	// リトライで後回しにしたデータを再送すると、Kafkaに同じデータが重複して発行される
+	if len(cfg.Kafka.Brokers) > 0 {
+		errs = errors.Join(errs, errors.New("fairness.max_service_share と kafka は同時に指定できません"))
+	}
+	return errs
+}
+
+// serviceShareLimit はバッチ内の1サービスあたりのアイテム数の上限を返します（上限を適用しない場合は0）
+// サービスが1つしかないバッチ（後回しにしたデータのリトライを含む）には適用しません
+func (c FairnessConfig) serviceShareLimit(total int, services map[string]int) int {
+	if c.MaxServiceShare <= 0 || total < c.MinBatchSize || len(services) < 2 {
+		return 0
+	}
+	return max(1, int(c.MaxServiceShare*float64(total)))
+}
+
+// deferTracesOverShare は上限を超えたサービスのスパンを td から取り除き、後回しにするスパンとして返します
+func deferTracesOverShare(c FairnessConfig, td ptrace.Traces) ptrace.Traces {
+	deferred := ptrace.NewTraces()
+	services := map[string]int{}
+	for _, rs := range td.ResourceSpans().All() {
+		for _, ss := range rs.ScopeSpans().All() {
+			services[resourceAttributeString(rs.Resource(), "service.name")] += ss.Spans().Len()
+		}
+	}
+	limit := c.serviceShareLimit(td.SpanCount(), services)
+	if limit == 0 {
+		return deferred
+	}
+
+	admitted := map[string]int{}
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		service := resourceAttributeString(rs.Resource(), "service.name")
+		if services[service] <= limit {
+			return false
+		}
+		var deferredRS *ptrace.ResourceSpans
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			var deferredSS *ptrace.ScopeSpans
+			ss.Spans().RemoveIf(func(span ptrace.Span) bool {
+				if admitted[service] < limit {
+					admitted[service]++
+					return false
+				}
+				if deferredSS == nil {
+					if deferredRS == nil {
+						appended := deferred.ResourceSpans().AppendEmpty()
+						deferredRS = &appended
+						rs.Resource().CopyTo(deferredRS.Resource())
+						deferredRS.SetSchemaUrl(rs.SchemaUrl())
+					}
+					appended := deferredRS.ScopeSpans().AppendEmpty()
+					deferredSS = &appended
+					ss.Scope().CopyTo(deferredSS.Scope())
+					deferredSS.SetSchemaUrl(ss.SchemaUrl())
+				}
+				span.MoveTo(deferredSS.Spans().AppendEmpty())
+				return true
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return deferred
+}
+
+// deferLogsOverShare は上限を超えたサービスのログレコードを ld から取り除き、後回しにするログレコードとして返します
+func deferLogsOverShare(c FairnessConfig, ld plog.Logs) plog.Logs {
+	deferred := plog.NewLogs()
+	services := map[string]int{}
+	for _, rl := range ld.ResourceLogs().All() {
+		for _, sl := range rl.ScopeLogs().All() {
+			services[resourceAttributeString(rl.Resource(), "service.name")] += sl.LogRecords().Len()
+		}
+	}
+	limit := c.serviceShareLimit(ld.LogRecordCount(), services)
+	if limit == 0 {
+		return deferred
+	}
+
+	admitted := map[string]int{}
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		service := resourceAttributeString(rl.Resource(), "service.name")
+		if services[service] <= limit {
+			return false
+		}
+		var deferredRL *plog.ResourceLogs
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			var deferredSL *plog.ScopeLogs
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				if admitted[service] < limit {
+					admitted[service]++
+					return false
+				}
+				if deferredSL == nil {
+					if deferredRL == nil {
+						appended := deferred.ResourceLogs().AppendEmpty()
+						deferredRL = &appended
+						rl.Resource().CopyTo(deferredRL.Resource())
+						deferredRL.SetSchemaUrl(rl.SchemaUrl())
+					}
+					appended := deferredRL.ScopeLogs().AppendEmpty()
+					deferredSL = &appended
+					sl.Scope().CopyTo(deferredSL.Scope())
+					deferredSL.SetSchemaUrl(sl.SchemaUrl())
+				}
+				lr.MoveTo(deferredSL.LogRecords().AppendEmpty())
+				return true
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return deferred
+}
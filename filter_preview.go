@@ -0,0 +1,47 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// フィルタのプレビュー（filter_preview）で集計する規則と処理
+// 件数は診断情報（expvarz の filter_preview）と内部メトリクス（otelcol_mylogexporter_filter_preview_items）で参照できる
+const (
+	previewRuleMinSeverity      = "min_severity"
+	previewRuleCardinalityLimit = "cardinality_limit"
+
+	previewActionDropped = "dropped"
+	previewActionMerged  = "merged"
+)
+
+// countLogsBelowSeverity は minSeverity 未満のログレコードの件数を返します（レコードは削除しない）
+func countLogsBelowSeverity(ld plog.Logs, minSeverity plog.SeverityNumber) int {
+	count := 0
+	for _, rl := range ld.ResourceLogs().All() {
+		for _, sl := range rl.ScopeLogs().All() {
+			for _, lr := range sl.LogRecords().All() {
+				if belowSeverity(lr, minSeverity) {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// previewCardinalityLimit はカーディナリティ制限を適用した場合に集約・破棄されるデータポイント数を返します
+// 受信データを変更しないよう、コピーに対して制限を適用します
+func previewCardinalityLimit(cfg CardinalityLimitConfig, md pmetric.Metrics) (merged, dropped int) {
+	limiter := newCardinalityLimiter(cfg)
+	if limiter == nil {
+		return 0, 0
+	}
+	preview := pmetric.NewMetrics()
+	md.CopyTo(preview)
+	limiter.apply(preview)
+	return limiter.merged, limiter.dropped
+}
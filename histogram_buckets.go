@@ -0,0 +1,135 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+// バケット数が max_histogram_buckets を超えたヒストグラムの扱い（histogram_bucket_overflow）
+const (
+	histogramBucketsTruncate = "truncate" // 上限に収まるようにバケットをまとめる（既定）
+	histogramBucketsReject   = "reject"   // バッチ全体を永続的なエラーとして拒否する
+)
+
+// validateHistogramBuckets は max_histogram_buckets と histogram_bucket_overflow を検証します
+func (cfg *Config) validateHistogramBuckets() error {
+	// 明示的なバケットのヒストグラムは、残すバケットとオーバーフローバケットの最低2つが必要
+	if cfg.MaxHistogramBuckets < 0 || cfg.MaxHistogramBuckets == 1 {
+		return fmt.Errorf("max_histogram_buckets は0（無制限）または2以上である必要があります: %d", cfg.MaxHistogramBuckets)
+	}
+	switch cfg.HistogramBucketOverflow {
+	case "", histogramBucketsTruncate, histogramBucketsReject:
+		return nil
+	}
+	return fmt.Errorf("histogram_bucket_overflow は truncate または reject を指定してください: %s", cfg.HistogramBucketOverflow)
+}
+
+// truncatesHistogramBuckets はバケット数の上限を超えたヒストグラムのバケットをまとめるかどうかを返します
+func (cfg *Config) truncatesHistogramBuckets() bool {
+	return cfg.MaxHistogramBuckets > 0 && cfg.HistogramBucketOverflow != histogramBucketsReject
+}
+
+// truncateExplicitBuckets は明示的なバケットを limit 個に減らします
+// 先頭の limit-1 個のバケットはそのまま残し、残りのバケットの件数を末尾のオーバーフローバケット（上限なし）にまとめます
+func truncateExplicitBuckets(dp pmetric.HistogramDataPoint, limit int) {
+	counts := dp.BucketCounts().AsRaw()
+	var overflow uint64
+	for _, c := range counts[limit-1:] {
+		overflow += c
+	}
+	dp.BucketCounts().FromRaw(append(counts[:limit-1], overflow))
+	if bounds := dp.ExplicitBounds().AsRaw(); len(bounds) > limit-1 {
+		dp.ExplicitBounds().FromRaw(bounds[:limit-1])
+	}
+}
+
+// downscaleExponentialBuckets は正・負のバケットの合計が limit 個以下になるまでスケールを1ずつ下げます
+// スケールを1下げると隣り合う2つのバケットが1つにまとまるため、件数を保ったまま解像度のみが下がります
+func downscaleExponentialBuckets(dp pmetric.ExponentialHistogramDataPoint, limit int) {
+	for dp.Positive().BucketCounts().Len()+dp.Negative().BucketCounts().Len() > limit {
+		downscaleBuckets(dp.Positive())
+		downscaleBuckets(dp.Negative())
+		dp.SetScale(dp.Scale() - 1)
+	}
+}
+
+// downscaleBuckets はバケットの位置（offset + i）を半分にして、同じ位置になったバケットの件数を合算します
+func downscaleBuckets(b pmetric.ExponentialHistogramDataPointBuckets) {
+	counts := b.BucketCounts().AsRaw()
+	if len(counts) == 0 {
+		return
+	}
+	offset := b.Offset() >> 1 // 負の位置も切り捨てになるよう算術シフトを使う
+	merged := make([]uint64, ((b.Offset()+int32(len(counts))-1)>>1)-offset+1)
+	for i, c := range counts {
+		merged[((b.Offset()+int32(i))>>1)-offset] += c
+	}
+	b.SetOffset(offset)
+	b.BucketCounts().FromRaw(merged)
+}
+
+// applyHistogramBuckets は max_histogram_buckets を超えるバケットを持つヒストグラムを histogram_bucket_overflow に従って処理します
+// truncate の場合はバケットをまとめ、reject の場合は該当するデータポイントがあればリトライしない永続的なエラーを返します
+func (e *metricsExporter) applyHistogramBuckets(md pmetric.Metrics) error {
+	limit := e.config.MaxHistogramBuckets
+	if limit <= 0 {
+		return nil
+	}
+	reject := e.config.HistogramBucketOverflow == histogramBucketsReject
+	var names []string
+	count := 0
+	exceeded := func(m pmetric.Metric) {
+		count++
+		if len(names) < maxLoggedMetricNames && !slices.Contains(names, m.Name()) {
+			names = append(names, m.Name())
+		}
+	}
+	for _, rm := range md.ResourceMetrics().All() {
+		for _, sm := range rm.ScopeMetrics().All() {
+			for _, m := range sm.Metrics().All() {
+				switch m.Type() {
+				case pmetric.MetricTypeHistogram:
+					for _, dp := range m.Histogram().DataPoints().All() {
+						if dp.BucketCounts().Len() <= limit {
+							continue
+						}
+						exceeded(m)
+						if !reject {
+							truncateExplicitBuckets(dp, limit)
+						}
+					}
+				case pmetric.MetricTypeExponentialHistogram:
+					for _, dp := range m.ExponentialHistogram().DataPoints().All() {
+						if dp.Positive().BucketCounts().Len()+dp.Negative().BucketCounts().Len() <= limit {
+							continue
+						}
+						exceeded(m)
+						if !reject {
+							downscaleExponentialBuckets(dp, limit)
+						}
+					}
+				}
+			}
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	if reject {
+		err := fmt.Errorf("バケット数が max_histogram_buckets（%d）を超えるデータポイントが%d件含まれています: %s", limit, count, strings.Join(names, ", "))
+		e.diag.recordError("metrics", err)
+		return consumererror.NewPermanent(err)
+	}
+	e.logger.Debug("バケット数が max_histogram_buckets を超えるヒストグラムのバケットをまとめました",
+		zap.Int("max_histogram_buckets", limit), zap.Int("data_points", count), zap.Strings("metrics", names))
+	return nil
+}
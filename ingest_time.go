@@ -0,0 +1,35 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"slices"
+	"time"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// exportTimestampColumn はエクスポーターが挿入を送信した時刻の列の名前です（挿入SQLの末尾に追加される）
+// IngestTimestamp 列は既定値（now64）でClickHouseが書き込み時に埋めるため、挿入SQLには含めません
+const exportTimestampColumn = stable.ColumnExportTimestamp
+
+// ingestTimeInsertColumns は record_ingest_time が有効な場合に ExportTimestamp 列を追加した挿入列を返します
+func (cfg *Config) ingestTimeInsertColumns(columns []string) []string {
+	if !cfg.RecordIngestTime {
+		return columns
+	}
+	return slices.Concat(columns, []string{exportTimestampColumn})
+}
+
+// stampExportTime は各行の末尾に挿入を送信する時刻を追加します（record_ingest_time 無効の場合は何もしない）
+// バッチ内の行はすべて同じ時刻とし、リトライした場合は送信し直した時刻になります
+func (cfg *Config) stampExportTime(rows [][]any) {
+	if !cfg.RecordIngestTime {
+		return
+	}
+	now := time.Now()
+	for i, row := range rows {
+		rows[i] = append(row, now)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// insertSettingNamePattern はClickHouseの設定名（例: max_insert_block_size）に一致します
+var insertSettingNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateInsertSettings は insert_settings を検証します
+func (cfg *Config) validateInsertSettings() error {
+	if len(cfg.InsertSettings) == 0 {
+		return nil
+	}
+	if cfg.isPostgres() {
+		return errors.New("driver: postgres では insert_settings を使用できません")
+	}
+	var errs error
+	for _, name := range slices.Sorted(maps.Keys(cfg.InsertSettings)) {
+		if !insertSettingNamePattern.MatchString(name) {
+			errs = errors.Join(errs, fmt.Errorf("insert_settings の設定名 %q が不正です", name))
+		}
+	}
+	return errs
+}
+
+// insertContext は insert_settings と重複排除トークンを挿入ごとのクエリ設定として付与したコンテキストを返します
+// （どちらも指定しない場合は ctx のまま）
+// database/sql とネイティブバッチのどちらの経路でも、clickhouse-go がクエリの SETTINGS として送信します
+// 接続パラメータ（connection_params）の同名の設定より優先されます
+func (cfg *Config) insertContext(ctx context.Context, token string) context.Context {
+	settings := cfg.insertQuerySettings(token)
+	if settings == nil {
+		return ctx
+	}
+	return clickhouse.Context(ctx, clickhouse.WithSettings(settings))
+}
+
+// insertQuerySettings は挿入ごとのクエリ設定を返します（insert_settings とトークンのどちらもない場合は nil）
+func (cfg *Config) insertQuerySettings(token string) clickhouse.Settings {
+	if (len(cfg.InsertSettings) == 0 && token == "") || cfg.isPostgres() {
+		return nil
+	}
+	settings := make(clickhouse.Settings, len(cfg.InsertSettings)+2)
+	for name, value := range cfg.InsertSettings {
+		settings[name] = value
+	}
+	if token != "" {
+		settings["insert_deduplication_token"] = token
+		// insert_settings で明示的に無効化した場合は従う
+		if _, ok := settings["insert_deduplicate"]; !ok {
+			settings["insert_deduplicate"] = "1"
+		}
+	}
+	return settings
+}
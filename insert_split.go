@@ -0,0 +1,152 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// validateMaxInsertRows は max_insert_rows を検証します
+func (cfg *Config) validateMaxInsertRows() error {
+	if cfg.MaxInsertRows < 0 {
+		return fmt.Errorf("max_insert_rows は0（無制限）以上である必要があります: %d", cfg.MaxInsertRows)
+	}
+	return nil
+}
+
+// splitTraces はスパン数が limit 以下になるようにトレースを分割します（リソース・スコープは分割先ごとに複製する）
+func splitTraces(td ptrace.Traces, limit int) []ptrace.Traces {
+	var chunks []ptrace.Traces
+	var current ptrace.Traces
+	count := 0
+	for _, rs := range td.ResourceSpans().All() {
+		for _, ss := range rs.ScopeSpans().All() {
+			var dest ptrace.SpanSlice
+			opened := false // 現在の分割先にこのスコープを作成済みか
+			for _, span := range ss.Spans().All() {
+				if count == 0 || count == limit {
+					current, count = ptrace.NewTraces(), 0
+					chunks = append(chunks, current)
+					opened = false
+				}
+				if !opened {
+					destRS := current.ResourceSpans().AppendEmpty()
+					rs.Resource().CopyTo(destRS.Resource())
+					destRS.SetSchemaUrl(rs.SchemaUrl())
+					destSS := destRS.ScopeSpans().AppendEmpty()
+					ss.Scope().CopyTo(destSS.Scope())
+					destSS.SetSchemaUrl(ss.SchemaUrl())
+					dest, opened = destSS.Spans(), true
+				}
+				span.CopyTo(dest.AppendEmpty())
+				count++
+			}
+		}
+	}
+	return chunks
+}
+
+// splitLogs はログレコード数が limit 以下になるようにログを分割します（リソース・スコープは分割先ごとに複製する）
+func splitLogs(ld plog.Logs, limit int) []plog.Logs {
+	var chunks []plog.Logs
+	var current plog.Logs
+	count := 0
+	for _, rl := range ld.ResourceLogs().All() {
+		for _, sl := range rl.ScopeLogs().All() {
+			var dest plog.LogRecordSlice
+			opened := false // 現在の分割先にこのスコープを作成済みか
+			for _, lr := range sl.LogRecords().All() {
+				if count == 0 || count == limit {
+					current, count = plog.NewLogs(), 0
+					chunks = append(chunks, current)
+					opened = false
+				}
+				if !opened {
+					destRL := current.ResourceLogs().AppendEmpty()
+					rl.Resource().CopyTo(destRL.Resource())
+					destRL.SetSchemaUrl(rl.SchemaUrl())
+					destSL := destRL.ScopeLogs().AppendEmpty()
+					sl.Scope().CopyTo(destSL.Scope())
+					destSL.SetSchemaUrl(sl.SchemaUrl())
+					dest, opened = destSL.LogRecords(), true
+				}
+				lr.CopyTo(dest.AppendEmpty())
+				count++
+			}
+		}
+	}
+	return chunks
+}
+
+// insertTraceChunks は max_insert_rows を超えるトレースを分割して、分割ごとに1回の挿入で挿入します
+// 挿入に失敗した場合は中断し、失敗した分割と未挿入の分割を残り（rest）として返します（td は変更しない）
+// 残りは td と別のトレースのため、呼び出し元は retainTraces で td を残りに置き換えられます
+func (e *tracesExporter) insertTraceChunks(ctx context.Context, td ptrace.Traces) (rest ptrace.Traces, err error) {
+	limit := e.config.MaxInsertRows
+	if limit <= 0 || td.SpanCount() <= limit {
+		if err := e.insertTraces(ctx, td); err != nil {
+			rest = ptrace.NewTraces()
+			td.CopyTo(rest)
+			return rest, err
+		}
+		return ptrace.NewTraces(), nil
+	}
+	chunks := splitTraces(td, limit)
+	e.logger.Debug("max_insert_rows を超えるバッチを分割して挿入します",
+		zap.Int("spans", td.SpanCount()), zap.Int("max_insert_rows", limit), zap.Int("chunks", len(chunks)))
+	rest = ptrace.NewTraces()
+	for i, chunk := range chunks {
+		if err := e.insertTraces(ctx, chunk); err != nil {
+			for _, remaining := range chunks[i:] {
+				remaining.ResourceSpans().MoveAndAppendTo(rest.ResourceSpans())
+			}
+			return rest, err
+		}
+	}
+	return rest, nil
+}
+
+// insertLogChunks は max_insert_rows を超えるログを分割して挿入します（失敗時の扱いは insertTraceChunks と同じ）
+func (e *logsExporter) insertLogChunks(ctx context.Context, ld plog.Logs) (rest plog.Logs, err error) {
+	limit := e.config.MaxInsertRows
+	if limit <= 0 || ld.LogRecordCount() <= limit {
+		if err := e.insertLogs(ctx, ld); err != nil {
+			rest = plog.NewLogs()
+			ld.CopyTo(rest)
+			return rest, err
+		}
+		return plog.NewLogs(), nil
+	}
+	chunks := splitLogs(ld, limit)
+	e.logger.Debug("max_insert_rows を超えるバッチを分割して挿入します",
+		zap.Int("log_records", ld.LogRecordCount()), zap.Int("max_insert_rows", limit), zap.Int("chunks", len(chunks)))
+	rest = plog.NewLogs()
+	for i, chunk := range chunks {
+		if err := e.insertLogs(ctx, chunk); err != nil {
+			for _, remaining := range chunks[i:] {
+				remaining.ResourceLogs().MoveAndAppendTo(rest.ResourceLogs())
+			}
+			return rest, err
+		}
+	}
+	return rest, nil
+}
+
+// retainTraces は td を挿入できなかった残り（rest）に置き換えます（rest は空になる）
+// exporterhelper は同じバッチをリトライするため、挿入に成功した分割・テナントを再送しないようにします
+func retainTraces(td, rest ptrace.Traces) {
+	td.ResourceSpans().RemoveIf(func(ptrace.ResourceSpans) bool { return true })
+	rest.ResourceSpans().MoveAndAppendTo(td.ResourceSpans())
+}
+
+// retainLogs は ld を挿入できなかった残り（rest）に置き換えます（retainTraces のログ版）
+func retainLogs(ld, rest plog.Logs) {
+	ld.ResourceLogs().RemoveIf(func(plog.ResourceLogs) bool { return true })
+	rest.ResourceLogs().MoveAndAppendTo(ld.ResourceLogs())
+}
@@ -0,0 +1,120 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// chunkTransformer は挿入ごと（分割した場合は分割ごと）に呼び出され、failOn 回目の挿入を失敗させます
+// 失敗しなかった挿入の行数を rows に数えます
+type chunkTransformer struct {
+	failOn int // 失敗させる挿入の回数（0 の場合は失敗しない）
+	calls  int
+	rows   int
+}
+
+func (t *chunkTransformer) Columns() []string { return nil }
+
+func (t *chunkTransformer) TransformRows(_ context.Context, _ []string, rows [][]any) error {
+	t.calls++
+	if t.calls == t.failOn {
+		return errors.New("chunk insert failed")
+	}
+	t.rows += len(rows)
+	return nil
+}
+
+// TestTargetChunkFailureKeepsPrimaryBatch は、追加の書き込み先の2つ目の分割の挿入に失敗しても
+// endpoint にはバッチ全体が挿入されることを確認します
+func TestTargetChunkFailureKeepsPrimaryBatch(t *testing.T) {
+	ctx := context.Background()
+	cfg := captureConfig(t.TempDir())
+	cfg.MaxInsertRows = 2
+
+	t.Run("traces", func(t *testing.T) {
+		primary := &chunkTransformer{}
+		w, err := NewTracesWriter(cfg, nil, WithRowTransformer(primary))
+		if err != nil {
+			t.Fatalf("NewTracesWriter: %v", err)
+		}
+		defer func() { _ = w.Close(ctx) }()
+		target := &chunkTransformer{failOn: 2}
+		dr := w.exporter.derive(cfg, nil, zap.NewNop())
+		dr.capture, dr.transformer = nil, target
+		w.exporter.targets = []*exportTarget{{name: "dr", signal: "traces", traces: dr, logger: zap.NewNop()}}
+		defer func() { w.exporter.targets = nil }()
+
+		td := dedupTestTraces(4)
+		if err := w.exporter.pushTraces(ctx, td); err != nil {
+			t.Fatalf("ベストエフォートの書き込み先の失敗でバッチが失敗しました: %v", err)
+		}
+		if target.rows != 2 {
+			t.Errorf("書き込み先に挿入した行数 = %d, want 2", target.rows)
+		}
+		if primary.rows != 4 || td.SpanCount() != 4 {
+			t.Errorf("endpoint に挿入した行数 = %d（バッチのスパン数 %d）, want 4", primary.rows, td.SpanCount())
+		}
+	})
+
+	t.Run("logs", func(t *testing.T) {
+		primary := &chunkTransformer{}
+		w, err := NewLogsWriter(cfg, nil, WithRowTransformer(primary))
+		if err != nil {
+			t.Fatalf("NewLogsWriter: %v", err)
+		}
+		defer func() { _ = w.Close(ctx) }()
+		target := &chunkTransformer{failOn: 2}
+		dr := w.exporter.derive(cfg, nil, zap.NewNop())
+		dr.capture, dr.transformer = nil, target
+		w.exporter.targets = []*exportTarget{{name: "dr", signal: "logs", logs: dr, logger: zap.NewNop()}}
+		defer func() { w.exporter.targets = nil }()
+
+		ld := dedupTestLogs(4)
+		if err := w.exporter.pushLogs(ctx, ld); err != nil {
+			t.Fatalf("ベストエフォートの書き込み先の失敗でバッチが失敗しました: %v", err)
+		}
+		if target.rows != 2 {
+			t.Errorf("書き込み先に挿入した行数 = %d, want 2", target.rows)
+		}
+		if primary.rows != 4 || ld.LogRecordCount() != 4 {
+			t.Errorf("endpoint に挿入した行数 = %d（バッチのログレコード数 %d）, want 4", primary.rows, ld.LogRecordCount())
+		}
+	})
+}
+
+// endpoint への挿入に失敗した場合は、失敗した分割以降のみをリトライの対象としてバッチに残す
+func TestChunkFailureRetainsRemainder(t *testing.T) {
+	ctx := context.Background()
+	cfg := captureConfig(t.TempDir())
+	cfg.MaxInsertRows = 2
+
+	primary := &chunkTransformer{failOn: 2}
+	w, err := NewTracesWriter(cfg, nil, WithRowTransformer(primary))
+	if err != nil {
+		t.Fatalf("NewTracesWriter: %v", err)
+	}
+	defer func() { _ = w.Close(ctx) }()
+
+	td := dedupTestTraces(6)
+	if err := w.exporter.pushTraces(ctx, td); err == nil {
+		t.Fatal("分割の挿入に失敗した場合はエラーを返します")
+	}
+	if td.SpanCount() != 4 {
+		t.Fatalf("バッチに残ったスパン数 = %d, want 4（失敗した分割と未挿入の分割）", td.SpanCount())
+	}
+	spans := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	if name := spans.At(0).Name(); name != "span-2" {
+		t.Errorf("残りの先頭のスパン = %s, want span-2", name)
+	}
+
+	rest, err := w.exporter.insertTraceChunks(ctx, dedupTestTraces(2))
+	if err != nil || rest.SpanCount() != 0 {
+		t.Errorf("insertTraceChunks の成功時の残り = %d, err = %v", rest.SpanCount(), err)
+	}
+}
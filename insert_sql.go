@@ -0,0 +1,282 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+
+	"github.com/dtamura/myexporter/internal"
+)
+
+// InsertSQLConfig - シグナルごとの挿入SQLの上書き設定（上級者向け）
+// 既定の列構成と異なるテーブルへ挿入する場合に、挿入SQLテンプレートを直接指定します
+//
+// テンプレートでは {{.Database}} と {{.Table}} を参照し、値は @列名 の名前付きプレースホルダーで指定します
+// 例: INSERT INTO "{{.Database}}"."{{.Table}}" (ts, trace_id, svc) VALUES (@Timestamp, @TraceId, @ServiceName)
+// 属性列の1つのキーの値は @SpanAttributes[http.method] のように指定します（値は文字列）
+// 列名は既定の挿入SQL（traces_insert.sql, logs_insert.sql）の列名と同じです（source_columns 有効時は Collector* 列も使用可能）
+// env・default・clustered・onCluster のテンプレート関数で、環境ごとに挿入先や設定を切り替えられます
+type InsertSQLConfig struct {
+	Traces string `mapstructure:"traces"` // トレースの挿入SQLテンプレート
+	Logs   string `mapstructure:"logs"`   // ログの挿入SQLテンプレート
+}
+
+// validate は名前付きプレースホルダーが挿入列の列名を参照していることを検証します
+func (c InsertSQLConfig) validate(traceColumns, logColumns []string) error {
+	if c.Traces != "" {
+		if _, _, _, err := bindNamedPlaceholders(c.Traces, traceColumns); err != nil {
+			return fmt.Errorf("insert_sql.traces: %w", err)
+		}
+	}
+	if c.Logs != "" {
+		if _, _, _, err := bindNamedPlaceholders(c.Logs, logColumns); err != nil {
+			return fmt.Errorf("insert_sql.logs: %w", err)
+		}
+	}
+	return nil
+}
+
+// namedPlaceholderPattern は @列名 形式の名前付きプレースホルダーに一致します（Events.Name のようなネスト列と、@属性列[キー] を含む）
+var namedPlaceholderPattern = regexp.MustCompile(`@([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)(?:\[([^\]]+)\])?`)
+
+// keyedAttributeColumns は @列名[キー] でキーの値を取り出せる属性列です
+var keyedAttributeColumns = []string{"ResourceAttributes", "ScopeAttributes", "SpanAttributes", "LogAttributes"}
+
+// insertStatement は挿入SQLと、行の値を引数の順に並べ替えるためのインデックスです
+type insertStatement struct {
+	sql      string
+	argIndex []int    // 既定の列順における各引数の位置（nil の場合は既定の列順のまま）
+	keys     []string // 各引数で属性列から取り出すキー（キーを指定しない引数は空、すべて指定しない場合は nil）
+
+	deduplicationToken string // 挿入の重複排除トークン（insert_deduplication_token 有効時のみ）
+}
+
+// renderInsertStatement は挿入SQLをレンダリングします
+// custom が指定された場合は名前付きプレースホルダーを位置指定（?）に置き換え、引数の並べ替え順を求めます
+func renderInsertStatement(name, defaultSQL, custom string, columns []string, data internal.TableTemplateData) (*insertStatement, error) {
+	if custom == "" {
+		query, err := internal.ExecuteSQLTemplate(name, defaultSQL, data)
+		if err != nil {
+			return nil, err
+		}
+		return &insertStatement{sql: query}, nil
+	}
+
+	rendered, err := internal.ExecuteSQLTemplate(name+" (custom)", custom, data)
+	if err != nil {
+		return nil, err
+	}
+	query, argIndex, keys, err := bindNamedPlaceholders(rendered, columns)
+	if err != nil {
+		return nil, fmt.Errorf("カスタム挿入SQL %s が不正です: %w", name, err)
+	}
+	return &insertStatement{sql: query, argIndex: argIndex, keys: keys}, nil
+}
+
+// bindNamedPlaceholders は @列名 を ? に置き換え、各プレースホルダーに対応する列の位置と属性のキーを返します
+func bindNamedPlaceholders(query string, columns []string) (string, []int, []string, error) {
+	positions := make(map[string]int, len(columns))
+	for i, column := range columns {
+		positions[column] = i
+	}
+
+	var argIndex []int
+	var keys []string
+	keyed := false
+	var unknown, unkeyable []string
+	bound := namedPlaceholderPattern.ReplaceAllStringFunc(query, func(match string) string {
+		groups := namedPlaceholderPattern.FindStringSubmatch(match)
+		column, key := groups[1], groups[2]
+		pos, ok := positions[column]
+		if !ok {
+			unknown = append(unknown, match)
+			return match
+		}
+		if key != "" && !slices.Contains(keyedAttributeColumns, column) {
+			unkeyable = append(unkeyable, match)
+			return match
+		}
+		argIndex = append(argIndex, pos)
+		keys = append(keys, key)
+		keyed = keyed || key != ""
+		return "?"
+	})
+	if len(unknown) > 0 {
+		return "", nil, nil, fmt.Errorf("不明なプレースホルダー %s（使用できる列: %s）",
+			strings.Join(unknown, ", "), strings.Join(columns, ", "))
+	}
+	if len(unkeyable) > 0 {
+		return "", nil, nil, fmt.Errorf("キーを指定できない列のプレースホルダー %s（キーを指定できる列: %s）",
+			strings.Join(unkeyable, ", "), strings.Join(keyedAttributeColumns, ", "))
+	}
+	if len(argIndex) == 0 {
+		return "", nil, nil, fmt.Errorf("@列名 形式のプレースホルダーが含まれていません")
+	}
+	if !keyed {
+		keys = nil
+	}
+	return bound, argIndex, keys, nil
+}
+
+// args は既定の列順の行の値を、挿入SQLの引数の順に並べ替えて返します
+func (s *insertStatement) args(row []any) []any {
+	if s.argIndex == nil {
+		return row
+	}
+	args := make([]any, len(s.argIndex))
+	for i, pos := range s.argIndex {
+		args[i] = row[pos]
+		if s.keys != nil && s.keys[i] != "" {
+			args[i] = attributeKeyValue(row[pos], s.keys[i])
+		}
+	}
+	return args
+}
+
+// attributeKeyValue は属性列の値（map[string]string またはJSON文字列）から指定キーの値を文字列で返します（存在しない場合は空文字）
+func attributeKeyValue(attrs any, key string) string {
+	switch attrs := attrs.(type) {
+	case map[string]string:
+		return attrs[key]
+	case string:
+		var m map[string]any
+		if err := json.Unmarshal([]byte(attrs), &m); err != nil {
+			return ""
+		}
+		switch v := m[key].(type) {
+		case nil:
+			return ""
+		case string:
+			return v
+		default:
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// validateInsertTimeout は insert_timeout を検証します
+// timeout（pushX全体のタイムアウト）より長い場合は先に timeout に達するため指定できません
+func (cfg *Config) validateInsertTimeout() error {
+	if cfg.InsertTimeout < 0 {
+		return fmt.Errorf("insert_timeout は0以上である必要があります: %s", cfg.InsertTimeout)
+	}
+	if timeout := cfg.TimeoutSettings.Timeout; timeout > 0 && cfg.InsertTimeout > timeout {
+		return fmt.Errorf("insert_timeout は timeout（%s）以下である必要があります: %s", timeout, cfg.InsertTimeout)
+	}
+	return nil
+}
+
+// insertRowsWithTimeout は insert_timeout を適用して行を挿入します（native を指定した場合はネイティブバッチで挿入）
+// 応答の遅いノードで timeout まで待たずに失敗させ、exporterhelper のリトライ（別の接続・エンドポイント）に委ねます
+// リトライしても成功しないエラーは classifyInsertError により永続的なエラーとして返します
+// insert_settings と重複排除トークンはここで挿入のクエリ設定として付与します
+func insertRowsWithTimeout(ctx context.Context, cfg *Config, db *sql.DB, native clickhouse.Conn, insert *insertStatement, rows [][]any) error {
+	ctx = cfg.insertContext(ctx, insert.deduplicationToken)
+	write := func(ctx context.Context) error {
+		if native != nil {
+			return insertRowsNative(ctx, native, insert, rows)
+		}
+		return insertRows(ctx, db, insert, rows)
+	}
+	if cfg.InsertTimeout <= 0 {
+		return classifyInsertError(write(ctx))
+	}
+	insertCtx, cancel := context.WithTimeout(ctx, cfg.InsertTimeout)
+	defer cancel()
+	err := write(insertCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(insertCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("挿入が insert_timeout（%s）内に完了しませんでした: %w", cfg.InsertTimeout, err)
+	}
+	return classifyInsertError(err)
+}
+
+// permanentClickHouseErrors は挿入をリトライしても成功しないClickHouseのエラーコードです
+// テーブル・列の不一致や型の不一致など、スキーマまたはデータを修正しない限り同じ結果になるものに限ります
+// schemaErrorCodes に含まれるコードは、列定義を読み直して再挿入しても失敗した場合に確定します（retryAfterReload）
+var permanentClickHouseErrors = map[int32]string{
+	6:  "CANNOT_PARSE_TEXT",
+	8:  "THERE_IS_NO_COLUMN",
+	10: "NOT_FOUND_COLUMN_IN_BLOCK",
+	16: "NO_SUCH_COLUMN_IN_TABLE",
+	43: "ILLEGAL_TYPE_OF_ARGUMENT",
+	44: "ILLEGAL_COLUMN",
+	47: "UNKNOWN_IDENTIFIER",
+	53: "TYPE_MISMATCH",
+	60: "UNKNOWN_TABLE",
+	62: "SYNTAX_ERROR",
+	70: "CANNOT_CONVERT_TYPE",
+	81: "UNKNOWN_DATABASE",
+}
+
+// permanentPostgresErrors は挿入をリトライしても成功しないPostgreSQLのエラー（SQLSTATE）です
+var permanentPostgresErrors = map[string]string{
+	"42P01": "undefined_table",
+	"42703": "undefined_column",
+	"42804": "datatype_mismatch",
+	"42601": "syntax_error",
+	"22P02": "invalid_text_representation",
+	"3F000": "invalid_schema_name",
+}
+
+// classifyInsertError はリトライしても成功しない挿入エラーを consumererror.NewPermanent で包みます
+// 永続的なエラーは exporterhelper がリトライせずにバッチを破棄し、スプールにも退避しません
+func classifyInsertError(err error) error {
+	if err == nil || consumererror.IsPermanent(err) {
+		return err
+	}
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		if name, ok := permanentClickHouseErrors[exception.Code]; ok {
+			return consumererror.NewPermanent(fmt.Errorf("リトライしても成功しないエラーのため挿入を中止します（%s）: %w", name, err))
+		}
+		return err
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if name, ok := permanentPostgresErrors[pgErr.Code]; ok {
+			return consumererror.NewPermanent(fmt.Errorf("リトライしても成功しないエラーのため挿入を中止します（%s）: %w", name, err))
+		}
+	}
+	return err
+}
+
+// insertRows は行を1トランザクション（1バッチ）で挿入します
+// clickhouse-go ではトランザクション内の準備済みINSERT文がバッチ送信として扱われます
+func insertRows(ctx context.Context, db *sql.DB, insert *insertStatement, rows [][]any) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("トランザクションの開始に失敗しました: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, insert.sql)
+	if err != nil {
+		return fmt.Errorf("挿入文の準備に失敗しました: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, insert.args(row)...); err != nil {
+			return fmt.Errorf("行の挿入に失敗しました: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("挿入のコミットに失敗しました: %w", err)
+	}
+	return nil
+}
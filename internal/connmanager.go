@@ -0,0 +1,71 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// ConnectionManager はDSNをキーにDB接続プールを共有し、参照カウントで管理します
+// 同じ設定から作成されたトレース・ログ・メトリクスの各エクスポーターが1つの接続プールを使用します
+type ConnectionManager struct {
+	mu      sync.Mutex
+	entries map[string]*connEntry
+	keys    map[*sql.DB]string
+}
+
+type connEntry struct {
+	db   *sql.DB
+	refs int
+}
+
+// NewConnectionManager は空の ConnectionManager を作成します
+func NewConnectionManager() *ConnectionManager {
+	return &ConnectionManager{
+		entries: map[string]*connEntry{},
+		keys:    map[*sql.DB]string{},
+	}
+}
+
+// Acquire はキーに対応する共有接続プールを返します
+// まだ存在しない場合は open で作成し、参照カウントを1つ増やします
+func (m *ConnectionManager) Acquire(key string, open func() (*sql.DB, error)) (*sql.DB, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.entries[key]; ok {
+		entry.refs++
+		return entry.db, nil
+	}
+
+	db, err := open()
+	if err != nil {
+		return nil, err
+	}
+	m.entries[key] = &connEntry{db: db, refs: 1}
+	m.keys[db] = key
+	return db, nil
+}
+
+// Release は参照カウントを1つ減らし、最後の参照が解放された時点で接続プールを閉じます
+func (m *ConnectionManager) Release(db *sql.DB) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, ok := m.keys[db]
+	if !ok {
+		return fmt.Errorf("管理対象外のDB接続です")
+	}
+	entry := m.entries[key]
+	entry.refs--
+	if entry.refs > 0 {
+		return nil
+	}
+
+	delete(m.entries, key)
+	delete(m.keys, db)
+	return db.Close()
+}
@@ -0,0 +1,79 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package migrations はテーブル作成後のスキーマ変更（バージョン付きDDL）を管理します
+//
+// マイグレーションは sql/<シグナル>/<バージョン>_<名前>.sql に1ファイル1文で配置します
+// 新しく作成したテーブルにも適用されるため、ADD COLUMN IF NOT EXISTS のように冪等に記述してください
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql
+var files embed.FS
+
+// Migration は1つのバージョン付きDDLです
+type Migration struct {
+	Signal  string // 対象シグナル（traces, logs, profiles）
+	Version int    // シグナル内で単調増加するバージョン
+	Name    string // 説明（ファイル名のバージョン以降）
+	SQL     string // SQLテンプレート（{{.Database}}、{{.Table}}、{{.Cluster}} を参照可能）
+}
+
+// ForSignal は指定シグナルのマイグレーションをバージョン順に返します
+func ForSignal(signal string) ([]Migration, error) {
+	dir := path.Join("sql", signal)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("マイグレーションの読み込みに失敗しました: %w", err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		version, name, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		text, err := files.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("マイグレーション %s の読み込みに失敗しました: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Signal: signal, Version: version, Name: name, SQL: string(text)})
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("シグナル %s のマイグレーションのバージョン %d が重複しています", signal, migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// parseFileName は "0001_add_index.sql" 形式のファイル名からバージョンと名前を取り出します
+func parseFileName(fileName string) (int, string, error) {
+	prefix, name, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), "_")
+	if !ok || name == "" {
+		return 0, "", fmt.Errorf("マイグレーションのファイル名 %s は <バージョン>_<名前>.sql の形式である必要があります", fileName)
+	}
+	version, err := strconv.Atoi(prefix)
+	if err != nil || version <= 0 {
+		return 0, "", fmt.Errorf("マイグレーションのファイル名 %s のバージョンが不正です", fileName)
+	}
+	return version, name, nil
+}
@@ -0,0 +1,3 @@
+-- ログ本文の全文検索用インデックス（初期バージョンで作成されたテーブルには存在しない）
+ALTER TABLE "{{.Database}}"."{{.Table}}" {{.Cluster}}
+    ADD INDEX IF NOT EXISTS idx_body Body TYPE tokenbf_v1(32768, 3, 0) GRANULARITY 1
@@ -0,0 +1,3 @@
+-- プロファイルID検索用のインデックス
+ALTER TABLE "{{.Database}}"."{{.Table}}" {{.Cluster}}
+    ADD INDEX IF NOT EXISTS idx_profile_id ProfileId TYPE bloom_filter(0.01) GRANULARITY 1
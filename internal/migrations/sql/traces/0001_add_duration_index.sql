@@ -0,0 +1,3 @@
+-- 実行時間範囲検索用のインデックス（初期バージョンで作成されたテーブルには存在しない）
+ALTER TABLE "{{.Database}}"."{{.Table}}" {{.Cluster}}
+    ADD INDEX IF NOT EXISTS idx_duration Duration TYPE minmax GRANULARITY 1
@@ -0,0 +1,47 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package queries はエクスポーターが作成したテーブルを参照するクエリを提供します
+//
+// エクスポーターと同じテーブル定義を前提とするため、トレース検索APIなどのコンパニオン拡張から使用します
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// ErrTraceNotFound は検索テーブルにトレースIDが見つからない場合のエラーです
+var ErrTraceNotFound = stable.ErrTraceNotFound
+
+// TraceTimeRange はトレースに含まれるスパンの時刻の範囲です
+type TraceTimeRange struct {
+	Start time.Time // 最初のスパンの開始時刻
+	End   time.Time // 最後のスパンの開始時刻
+}
+
+// FindTraceTimeRange はトレースID-タイムスタンプ検索テーブル（<traces_table_name>_trace_id_ts）から
+// トレースの時刻の範囲を返します
+// 検索テーブルはエンジンによって同じトレースIDの行が複数残るため、min/max で集約します
+// 返された範囲でスパンテーブルの Timestamp を絞り込むと、パーティションを限定して検索できます
+func FindTraceTimeRange(ctx context.Context, db *sql.DB, database, lookupTable, traceID string) (TraceTimeRange, error) {
+	if id, err := hex.DecodeString(traceID); err != nil || len(id) != 16 {
+		return TraceTimeRange{}, fmt.Errorf("トレースIDは32桁の16進数で指定してください: %q", traceID)
+	}
+
+	query := fmt.Sprintf(`SELECT min(Start), max(End), count() FROM "%s"."%s" WHERE TraceId = ?`, database, lookupTable)
+	var r TraceTimeRange
+	var count uint64
+	if err := db.QueryRowContext(ctx, query, traceID).Scan(&r.Start, &r.End, &count); err != nil {
+		return TraceTimeRange{}, fmt.Errorf("検索テーブルの参照に失敗しました: %w", err)
+	}
+	if count == 0 {
+		return TraceTimeRange{}, ErrTraceNotFound
+	}
+	return r, nil
+}
@@ -0,0 +1,5 @@
+-- クラスター展開用の分散テーブル（ファサード）作成SQL
+-- 各シャードのローカルテーブルと同じ列構成で作成し、挿入・検索をシャードに振り分ける
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}}
+AS "{{.Database}}"."{{.LocalTable}}"
+ENGINE = {{.Engine}}
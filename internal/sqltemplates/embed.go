@@ -20,7 +20,87 @@ var TracesCreateTsTable string
 //go:embed traces_id_ts_lookup_mv.sql
 var TracesCreateTsView string
 
+// TracesCreateLinksTable - スパンリンクテーブル作成SQLテンプレート
+//
+//go:embed traces_links_table.sql
+var TracesCreateLinksTable string
+
+// TracesCreateLinksView - スパンリンクを展開するマテリアライズドビュー作成SQLテンプレート
+//
+//go:embed traces_links_mv.sql
+var TracesCreateLinksView string
+
+// TracesCreateRootsTable - ルートスパンテーブル作成SQLテンプレート
+//
+//go:embed traces_roots_table.sql
+var TracesCreateRootsTable string
+
+// TracesCreateRootsView - ルートスパンを抽出するマテリアライズドビュー作成SQLテンプレート
+//
+//go:embed traces_roots_mv.sql
+var TracesCreateRootsView string
+
+// ServiceGraphCreateTable - サービスグラフテーブル作成SQLテンプレート
+//
+//go:embed service_graph_table.sql
+var ServiceGraphCreateTable string
+
+// ServiceGraphInsert - サービスグラフ挿入用のSQLテンプレート
+//
+//go:embed service_graph_insert.sql
+var ServiceGraphInsert string
+
 // TracesInsert - トレースデータ挿入用のSQLテンプレート
 //
 //go:embed traces_insert.sql
 var TracesInsert string
+
+// LogsInsert - ログデータ挿入用のSQLテンプレート
+//
+//go:embed logs_insert.sql
+var LogsInsert string
+
+// MetricsDimensionsInsert - メトリクスのディメンション（リソース・スコープ）挿入用のSQLテンプレート
+//
+//go:embed metrics_dimensions_insert.sql
+var MetricsDimensionsInsert string
+
+// MetricsInsert - メトリクスのデータポイント挿入用のSQLテンプレート（列は種類・設定ごとに Columns で指定）
+//
+//go:embed metrics_insert.sql
+var MetricsInsert string
+
+// ProfilesInsert - プロファイルデータ挿入用のSQLテンプレート
+//
+//go:embed profiles_insert.sql
+var ProfilesInsert string
+
+// DistributedCreateTable - クラスター展開用の分散テーブル作成SQLテンプレート
+//
+//go:embed distributed_table.sql
+var DistributedCreateTable string
+
+// SchemaVersionCreateTable - マイグレーション適用履歴テーブル作成SQLテンプレート
+//
+//go:embed schema_version_table.sql
+var SchemaVersionCreateTable string
+
+// PostgresTracesCreateTable - PostgreSQL（TimescaleDB）バックエンド用のトレーステーブル作成SQLテンプレート
+//
+//go:embed postgres_traces_table.sql
+var PostgresTracesCreateTable string
+
+// PostgresLogsCreateTable - PostgreSQL（TimescaleDB）バックエンド用のログテーブル作成SQLテンプレート
+//
+//go:embed postgres_logs_table.sql
+var PostgresLogsCreateTable string
+
+// PostgresTracesInsert - PostgreSQLバックエンド用のトレースデータ挿入SQLテンプレート
+//
+//go:embed postgres_traces_insert.sql
+var PostgresTracesInsert string
+
+// PostgresLogsInsert - PostgreSQLバックエンド用のログデータ挿入SQLテンプレート
+//
+//go:embed postgres_logs_insert.sql
+var PostgresLogsInsert string
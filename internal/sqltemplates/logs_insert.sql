@@ -0,0 +1,81 @@
+INSERT INTO "{{.Database}}"."{{.Table}}" (
+    Timestamp,
+    ObservedTimestamp,
+    TraceId,
+    SpanId,
+    TraceFlags,
+    SeverityText,
+    SeverityNumber,
+    ServiceName,
+    ServiceVersion,
+    Body,
+    ResourceAttributes,
+    ResourceSchemaUrl,
+    ScopeName,
+    ScopeVersion,
+    ScopeAttributes,
+    ScopeDroppedAttrCount,
+    ScopeSchemaUrl,
+    LogAttributes,
+    LogDroppedAttrCount
+    {{- if .Truncated}},
+    Truncated
+    {{- end}}
+    {{- if .SourceColumns}},
+    CollectorInstanceId,
+    CollectorPipeline,
+    CollectorReceiver
+    {{- end}}
+    {{- if .BodyJSON}},
+    BodyJSON
+    {{- end}}
+    {{- if .EmbeddingDimensions}},
+    BodyEmbedding
+    {{- end}}
+    {{- if .IngestTime}},
+    ExportTimestamp
+    {{- end}}
+    {{- range .ExtraColumns}},
+    {{.}}
+    {{- end}}
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+    {{- if .Truncated}},
+    ?
+    {{- end}}
+    {{- if .SourceColumns}},
+    ?,
+    ?,
+    ?
+    {{- end}}
+    {{- if .BodyJSON}},
+    ?
+    {{- end}}
+    {{- if .EmbeddingDimensions}},
+    ?
+    {{- end}}
+    {{- if .IngestTime}},
+    ?
+    {{- end}}
+    {{- range .ExtraColumns}},
+    ?
+    {{- end}}
+)
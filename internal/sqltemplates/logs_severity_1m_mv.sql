@@ -0,0 +1,10 @@
+CREATE MATERIALIZED VIEW IF NOT EXISTS "{{.Database}}"."{{.Table}}_severity_1m_mv" {{.Cluster}}
+TO "{{.Database}}"."{{.Table}}_severity_1m"
+AS SELECT
+    toStartOfMinute(Timestamp) AS Timestamp,
+    ServiceName,
+    SeverityNumber,
+    SeverityText,
+    count() AS LogCount
+FROM "{{.Database}}"."{{.Table}}"
+GROUP BY Timestamp, ServiceName, SeverityNumber, SeverityText
@@ -0,0 +1,17 @@
+-- サービスごと・重要度ごと・1分ごとのログ件数（create_aggregation_views 有効時のみ）
+-- SummingMergeTree はマージ時に同じキーの行を合算するため、参照時は sum() で集計してください
+-- 例: 直近1時間の重要度ごとのログ件数
+--   SELECT Timestamp, SeverityText, sum(LogCount) AS Logs
+--   FROM otel_logs_severity_1m WHERE Timestamp >= now() - INTERVAL 1 HOUR
+--   GROUP BY Timestamp, SeverityText ORDER BY Timestamp
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}_severity_1m" {{.Cluster}} (
+    Timestamp DateTime CODEC(Delta, ZSTD(1)),           -- 集計期間（1分）の開始時刻
+    ServiceName LowCardinality(String) CODEC(ZSTD(1)),  -- サービス名
+    SeverityNumber Int32 CODEC(ZSTD(1)),                -- 数値重要度レベル（OTel仕様の1-24）
+    SeverityText LowCardinality(String) CODEC(ZSTD(1)), -- 重要度（ERROR, WARN, INFO, DEBUG など）
+    LogCount UInt64 CODEC(ZSTD(1))                      -- ログ件数
+) ENGINE = {{.Engine}}
+PARTITION BY toDate(Timestamp)
+ORDER BY {{.OrderBy}}
+{{.TTL}}
+SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}
@@ -2,7 +2,7 @@
 -- このテーブルは包括的なインデックス化と最適化を備えた構造化ログデータを保存します
 -- OpenTelemetryログ データモデルに基づく: https://opentelemetry.io/docs/specs/otel/logs/data-model/
 
-CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
     -- ===== タイムスタンプ フィールド =====
     -- これらのフィールドはログデータの重要な時間的側面を処理します
     Timestamp DateTime64(9) CODEC(Delta, ZSTD(1)),              -- ナノ秒精度での主要ログイベント タイムスタンプ
@@ -35,7 +35,7 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     
     -- ===== RESOURCE ATTRIBUTES =====  
     -- Metadata about the resource (container, host, cloud instance) generating logs
-    ResourceAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ResourceAttributes {{.AttributesType}},
                                                                   -- Key-value pairs: host.name, k8s.pod.name, cloud.region, etc.
                                                                   -- Map type enables flexible querying of nested attributes
     ResourceSchemaUrl String CODEC(ZSTD(1)),                    -- Schema version URL for resource attributes
@@ -44,21 +44,55 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     -- Information about the logging library/framework used
     ScopeName String CODEC(ZSTD(1)),                            -- Name of instrumentation library (e.g., "myapp.logging")
     ScopeVersion String CODEC(ZSTD(1)),                         -- Version of instrumentation library
-    ScopeAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ScopeAttributes {{.AttributesType}},
                                                                   -- Additional scope metadata
     ScopeDroppedAttrCount UInt32 CODEC(ZSTD(1)),               -- Count of dropped attributes due to limits
     ScopeSchemaUrl String CODEC(ZSTD(1)),                       -- Schema version URL for scope attributes
     
     -- ===== LOG ATTRIBUTES =====
     -- Custom attributes specific to this log entry
-    LogAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    LogAttributes {{.AttributesType}},
                                                                   -- Application-specific key-value pairs
                                                                   -- Examples: user.id, request.method, error.code
     LogDroppedAttrCount UInt32 CODEC(ZSTD(1)),                 -- Count of dropped log attributes
+    {{- if .Truncated}}
+
+    -- ===== TRUNCATION (max_attribute_length / max_body_length) =====
+    Truncated Bool CODEC(ZSTD(1)),                              -- True when an attribute value or Body was shortened
+    {{- end}}
+    {{- if .SourceColumns}}
+
+    -- ===== SOURCE METADATA (source_columns) =====
+    -- Which collector / pipeline / receiver wrote the row
+    CollectorInstanceId LowCardinality(String) CODEC(ZSTD(1)),  -- service.instance.id of the collector
+    CollectorPipeline LowCardinality(String) CODEC(ZSTD(1)),    -- Pipeline name
+    CollectorReceiver LowCardinality(String) CODEC(ZSTD(1)),    -- Receiver name
+    {{- end}}
+    {{- if .BodyJSON}}
+
+    -- ===== STRUCTURED BODY (logs.parse_body_json) =====
+    -- JSON object bodies parsed for structured queries (Body keeps the raw string)
+    BodyJSON JSON,                                                -- Empty object when Body is not a JSON object
+    {{- end}}
+    {{- if .EmbeddingDimensions}}
+
+    -- ===== BODY EMBEDDING (log_embeddings) =====
+    -- Embedding vector of Body for semantic search experiments
+    BodyEmbedding Array(Float32) CODEC(ZSTD(1)),              -- Zero vector when the embedding could not be computed
+    {{- end}}
+    {{- if .IngestTime}}
+
+    -- ===== INGEST TIME (record_ingest_time) =====
+    -- Pipeline lag: IngestTimestamp - Timestamp (end to end), ExportTimestamp - ObservedTimestamp (collector)
+    IngestTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- When ClickHouse wrote the row
+    ExportTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- When the exporter sent the insert
+    {{- end}}
     
     -- ===== PERFORMANCE INDEXES =====
     -- Bloom filter indexes for high-speed attribute searches
     -- These dramatically improve query performance on Map-type columns
+    -- mapKeys/mapValues indexes are omitted when attributes are stored as JSON
+    {{- if not .JSONAttributes}}
     INDEX idx_res_attr_key mapKeys(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- Fast lookup of resource attribute keys
     INDEX idx_res_attr_value mapValues(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
@@ -71,6 +105,11 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
                                                                   -- Fast lookup of log attribute keys
     INDEX idx_log_attr_value mapValues(LogAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- Fast lookup of log attribute values
+    {{- end}}
+    {{- if .EmbeddingDistance}}
+    INDEX idx_body_embedding BodyEmbedding TYPE vector_similarity('hnsw', '{{.EmbeddingDistance}}', {{.EmbeddingDimensions}}) GRANULARITY 100000000,
+                                                                  -- Approximate nearest neighbor search on body embeddings
+    {{- end}}
     INDEX idx_trace_id TraceId TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- Fast trace ID lookups for correlation
     INDEX idx_span_id SpanId TYPE bloom_filter(0.01) GRANULARITY 1,
@@ -78,14 +117,14 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     INDEX idx_body Body TYPE tokenbf_v1(32768, 3, 0) GRANULARITY 1
                                                                   -- Full-text search index on log body content
                                                                   -- tokenbf_v1 is optimized for text search
-    ) ENGINE = %s
-    %s
-    PARTITION BY toDate(Timestamp)                               -- Daily partitions for efficient data management
-    ORDER BY (ServiceName, SeverityNumber, Timestamp, TraceId)  -- Optimal sort order for typical queries:
+    ) ENGINE = {{.Engine}}
+    {{.TTL}}
+    PARTITION BY toDate({{.TimeColumn}})                         -- Daily partitions on logs_primary_time (default: Timestamp)
+    ORDER BY {{.OrderBy}}  -- logs_order_by (default: ServiceName, SeverityNumber, <logs_primary_time>, TraceId):
                                                                   -- 1. Filter by service
                                                                   -- 2. Filter by severity 
                                                                   -- 3. Time-based ordering
                                                                   -- 4. Trace correlation
-    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1    -- Performance tuning:
+    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}    -- Performance tuning:
                                                                   -- index_granularity: Balance between memory and precision
                                                                   -- ttl_only_drop_parts: Drop entire partitions when TTL expires
@@ -0,0 +1,14 @@
+INSERT INTO "{{.Database}}"."{{.Table}}" (
+    DimensionsHash,
+    ServiceName,
+    ResourceAttributes,
+    ResourceSchemaUrl,
+    {{- if not .MetricsLite}}
+    ScopeName,
+    ScopeVersion,
+    ScopeAttributes,
+    ScopeDroppedAttrCount,
+    ScopeSchemaUrl,
+    {{- end}}
+    LastSeen
+) VALUES (?, ?, ?, ?, {{- if not .MetricsLite}} ?, ?, ?, ?, ?,{{- end}} ?)
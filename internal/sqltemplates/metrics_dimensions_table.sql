@@ -0,0 +1,29 @@
+-- メトリクスのディメンション（リソース・スコープ）テーブル スキーマ
+-- metrics_dimensions.enabled 有効時、データポイントの行はリソースとスコープの組のハッシュ（DimensionsHash）のみを保持し、
+-- 属性の実体はこのテーブルに1組1行で保存します（リソース属性が多いほど保存容量を削減できる）
+-- 参照例: SELECT ... FROM otel_metrics_gauge g JOIN otel_metrics_dimensions d ON g.DimensionsHash = d.DimensionsHash
+
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
+    DimensionsHash UInt64,                                        -- リソースとスコープの組のハッシュ（データポイントの行と結合するキー、lite スキーマではリソースのみ）
+    ServiceName LowCardinality(String) CODEC(ZSTD(1)),            -- リソースの service.name
+    ResourceAttributes {{.MapType}} CODEC(ZSTD(1)),               -- リソース属性
+    ResourceSchemaUrl String CODEC(ZSTD(1)),                      -- リソース属性のスキーマ バージョンURL
+{{- if not .MetricsLite}}
+    ScopeName String CODEC(ZSTD(1)),                              -- インストルメンテーション ライブラリ名
+    ScopeVersion String CODEC(ZSTD(1)),                           -- インストルメンテーション ライブラリのバージョン
+    ScopeAttributes {{.MapType}} CODEC(ZSTD(1)),                  -- スコープ属性
+    ScopeDroppedAttrCount UInt32 CODEC(ZSTD(1)),                  -- 制限により削除されたスコープ属性数
+    ScopeSchemaUrl String CODEC(ZSTD(1)),                         -- スコープ属性のスキーマ バージョンURL
+{{- end}}
+    LastSeen DateTime CODEC(Delta, ZSTD(1)),                      -- 最後に書き込んだ日時（重複行のうち最新を残す）
+
+    INDEX idx_res_attr_key mapKeys(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
+    INDEX idx_res_attr_value mapValues(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1
+{{- if not .MetricsLite}},
+    INDEX idx_scope_attr_key mapKeys(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
+    INDEX idx_scope_attr_value mapValues(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1
+{{- end}}
+) ENGINE = {{.Engine}}
+ORDER BY DimensionsHash                                           -- 同じハッシュの行はマージ時に1行にまとめられる
+{{.TTL}}
+SETTINGS index_granularity=8192{{.Settings}}
@@ -3,22 +3,30 @@
 -- Exponential Histogramは指数的サイズのバケットを使用し、より良い精度とストレージ効率を実現します
 -- OpenTelemetryメトリクス データモデルに基づく: https://opentelemetry.io/docs/specs/otel/metrics/data-model/
 
-CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
+{{- if .MetricsDimensions}}
+    -- ===== リソース・スコープ =====
+    -- リソースとスコープはディメンションテーブルに1組1行で保存し、データポイントはハッシュのみで参照する
+    DimensionsHash UInt64 CODEC(ZSTD(1)),                       -- ディメンションテーブルの DimensionsHash
+{{- else}}
     -- ===== リソース識別 =====
     -- メトリクスを発行するリソース（サービス、ホスト、コンテナ）に関するメタデータ
-    ResourceAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ResourceAttributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- リソースメタデータ: service.name, host.name, k8s.pod.name
                                                                   -- Map型によりリソースプロパティの柔軟なクエリが可能
     ResourceSchemaUrl String CODEC(ZSTD(1)),                    -- リソース属性のスキーマ バージョンURL
     
+{{- if not .MetricsLite}}
     -- ===== インストルメンテーション スコープ =====
     -- メトリクス収集ライブラリ/フレームワークに関する情報
     ScopeName String CODEC(ZSTD(1)),                            -- インストルメンテーション ライブラリ名（例: "http-server", "database-client"）
     ScopeVersion String CODEC(ZSTD(1)),                         -- インストルメンテーション ライブラリのバージョン
-    ScopeAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ScopeAttributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- インストルメンテーション スコープに関する追加メタデータ
     ScopeDroppedAttrCount UInt32 CODEC(ZSTD(1)),               -- 制限により削除されたスコープ属性数
     ScopeSchemaUrl String CODEC(ZSTD(1)),                       -- スコープ属性のスキーマ バージョンURL
+{{- end}}
+{{- end}}
     
     -- ===== サービスとメトリクス識別 =====
     ServiceName LowCardinality(String) CODEC(ZSTD(1)),          -- グループ化とフィルタリングのためのサービス名
@@ -29,7 +37,7 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     
     -- ===== メトリクス ディメンション =====
     -- メトリクス値にコンテキストを提供するラベル/ディメンション
-    Attributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    Attributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- メトリクス ディメンション: method, endpoint, status_code, instance
                                                                   -- これらがユニークな時系列の識別子を作成する
     
@@ -71,19 +79,26 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
                                                                   -- 正の値と同じ精度で負の値を処理
                                                                   -- バケット境界: -(base^(scale) * 2^(offset + i))
     
+{{- if not .MetricsLite}}
     -- ===== エグゼンプラー =====
     -- このExponential Histogramに寄与したサンプル トレース
     -- エグゼンプラーはレイテンシー パターンに寄与した特定のリクエストの特定に役立つ
     Exemplars Nested (
-        FilteredAttributes Map(LowCardinality(String), String), -- 追加のエグゼンプラー属性（user.id, trace.sampledなど）
+        FilteredAttributes {{.MapType}}, -- 追加のエグゼンプラー属性（user.id, trace.sampledなど）
         TimeUnix DateTime64(9),                                  -- このエグゼンプラーがキャプチャされた時刻
         Value Float64,                                           -- 実際に測定された値（例: 特定のレイテンシー）
         SpanId String,                                           -- このエグゼンプラーを生成したトレースのSpan ID
         TraceId String                                           -- 深掘り分析用のTrace ID
     ) CODEC(ZSTD(1)),                                           -- Nested型により、1つのHistogramに複数のエグゼンプラーが可能
+{{- end}}
     
+{{- if not .MetricsLite}}
     -- ===== メタデータとフラグ =====
     Flags UInt32 CODEC(ZSTD(1)),                               -- OpenTelemetryデータポイントフラグ（将来の利用のために予約）
+{{- end}}
+    {{- if .StaleColumn}}
+    Stale Boolean CODEC(ZSTD(1)),                              -- ステールマーカー（FLAG_NO_RECORDED_VALUE、系列の終わりを示す）のデータポイントかどうか
+    {{- end}}
     
     -- ===== EXPONENTIAL HISTOGRAM拡張 =====
     -- 拡張統計情報のオプション フィールド  
@@ -96,32 +111,44 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     AggregationTemporality Int32 CODEC(ZSTD(1)),               -- Histogramデータポイントの集約方法:
                                                                   -- 1 = DELTA（バケットは最後のレポート以降の変化を表す）
                                                                   -- 2 = CUMULATIVE（バケットは開始以降の合計を表す）
+                                                                  -- 0 = UNSPECIFIED のデータポイントは保存せずに拒否する
+    
+    {{- if .IngestTime}}
+
+    -- ===== 取り込み時刻（record_ingest_time 有効時のみ） =====
+    IngestTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- ClickHouseが行を書き込んだ時刻
+    ExportTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- エクスポーターが挿入を送信した時刻（値を送信しない場合は書き込み時刻）
+    {{- end}}
     
     -- ===== パフォーマンス インデックス =====
     -- Bloom filter indexes for high-speed attribute searches
     -- Critical for performance when filtering by dimensions/labels
+{{- if not .MetricsDimensions}}
     INDEX idx_res_attr_key mapKeys(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- Fast lookup of resource attribute keys
     INDEX idx_res_attr_value mapValues(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- Fast lookup of resource attribute values
+{{- if not .MetricsLite}}
     INDEX idx_scope_attr_key mapKeys(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- Fast lookup of scope attribute keys
     INDEX idx_scope_attr_value mapValues(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- Fast lookup of scope attribute values
+{{- end}}
+{{- end}}
     INDEX idx_attr_key mapKeys(Attributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- Fast lookup of metric attribute keys (labels)
     INDEX idx_attr_value mapValues(Attributes) TYPE bloom_filter(0.01) GRANULARITY 1
                                                                   -- Fast lookup of metric attribute values (label values)
-    ) ENGINE = %s
-    %s
+    ) ENGINE = {{.Engine}}
+    {{.TTL}}
     PARTITION BY toDate(TimeUnix)                               -- Daily partitions for efficient data lifecycle management
-    ORDER BY (ServiceName, MetricName, Attributes, toUnixTimestamp64Nano(TimeUnix))
+    ORDER BY {{.OrderBy}}  -- metrics_order_by（既定の順序は以下）
                                                                   -- Optimal sort order for typical exponential histogram queries:
                                                                   -- 1. Filter by service
                                                                   -- 2. Filter by metric name (e.g., latency histograms)
                                                                   -- 3. Filter by dimensions (endpoint, method)
                                                                   -- 4. Time-based ordering for trend analysis
-    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1   -- Performance tuning:
+    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}   -- Performance tuning:
                                                                   -- index_granularity: Balance memory vs precision
                                                                   -- ttl_only_drop_parts: Efficient partition-level TTL
 
@@ -3,22 +3,30 @@
 -- Histogramは事前定義されたバケットでの値の分布を表します（レイテンシー、レスポンスサイズなど）
 -- OpenTelemetryメトリクス データモデルに基づく: https://opentelemetry.io/docs/specs/otel/metrics/data-model/
 
-CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
+{{- if .MetricsDimensions}}
+    -- ===== リソース・スコープ =====
+    -- リソースとスコープはディメンションテーブルに1組1行で保存し、データポイントはハッシュのみで参照する
+    DimensionsHash UInt64 CODEC(ZSTD(1)),                       -- ディメンションテーブルの DimensionsHash
+{{- else}}
     -- ===== リソース識別 =====
     -- メトリクスを発行するリソース（サービス、ホスト、コンテナ）に関するメタデータ
-    ResourceAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ResourceAttributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- リソースメタデータ: service.name, host.name, k8s.pod.name
                                                                   -- Map型によりリソースプロパティの柔軟なクエリが可能
     ResourceSchemaUrl String CODEC(ZSTD(1)),                    -- リソース属性のスキーマ バージョンURL
     
+{{- if not .MetricsLite}}
     -- ===== インストルメンテーション スコープ =====
     -- メトリクス収集ライブラリ/フレームワークに関する情報  
     ScopeName String CODEC(ZSTD(1)),                            -- インストルメンテーション ライブラリ名（例: "http-server", "database-client"）
     ScopeVersion String CODEC(ZSTD(1)),                         -- インストルメンテーション ライブラリのバージョン
-    ScopeAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ScopeAttributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- インストルメンテーション スコープに関する追加メタデータ
     ScopeDroppedAttrCount UInt32 CODEC(ZSTD(1)),               -- 制限により削除されたスコープ属性数
     ScopeSchemaUrl String CODEC(ZSTD(1)),                       -- スコープ属性のスキーマ バージョンURL
+{{- end}}
+{{- end}}
     
     -- ===== サービスとメトリクス識別 =====
     ServiceName LowCardinality(String) CODEC(ZSTD(1)),          -- グループ化とフィルタリングのためのサービス名
@@ -29,7 +37,7 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     
     -- ===== メトリクス ディメンション =====
     -- メトリクス値にコンテキストを提供するラベル/ディメンション
-    Attributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    Attributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- メトリクス ディメンション: method, endpoint, status_code, instance
                                                                   -- これらがユニークな時系列の識別子を作成する
     
@@ -55,19 +63,26 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
                                                                   -- 最後のバケットは暗黙的に(+Inf)
                                                                   -- パーセンタイル計算に重要
     
+{{- if not .MetricsLite}}
     -- ===== エグゼンプラー =====
     -- このHistogramに寄与したサンプル トレース
     -- エグゼンプラーはレイテンシー スパイクに寄与した特定のリクエストの特定に役立つ
     Exemplars Nested (
-        FilteredAttributes Map(LowCardinality(String), String), -- 追加のエグゼンプラー属性（user.id, trace.sampledなど）
+        FilteredAttributes {{.MapType}}, -- 追加のエグゼンプラー属性（user.id, trace.sampledなど）
         TimeUnix DateTime64(9),                                  -- このエグゼンプラーがキャプチャされた時刻
         Value Float64,                                           -- 実際に測定された値（例: 特定のレイテンシー）
         SpanId String,                                           -- このエグゼンプラーを生成したトレースのSpan ID
         TraceId String                                           -- 深掘り分析用のTrace ID
     ) CODEC(ZSTD(1)),                                           -- Nested型により、1つのHistogramに複数のエグゼンプラーが可能
+{{- end}}
     
+{{- if not .MetricsLite}}
     -- ===== メタデータとフラグ =====
     Flags UInt32 CODEC(ZSTD(1)),                               -- OpenTelemetryデータポイントフラグ（将来の利用のために予約）
+{{- end}}
+    {{- if .StaleColumn}}
+    Stale Boolean CODEC(ZSTD(1)),                              -- ステールマーカー（FLAG_NO_RECORDED_VALUE、系列の終わりを示す）のデータポイントかどうか
+    {{- end}}
     
     -- ===== HISTOGRAM拡張 =====
     -- 拡張統計情報のオプション フィールド
@@ -80,31 +95,43 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     AggregationTemporality Int32 CODEC(ZSTD(1)),               -- Histogramデータポイントの集約方法:
                                                                   -- 1 = DELTA（バケットは最後のレポート以降の変化を表す）
                                                                   -- 2 = CUMULATIVE（バケットは開始以降の合計を表す）
+                                                                  -- 0 = UNSPECIFIED のデータポイントは保存せずに拒否する
+    
+    {{- if .IngestTime}}
+
+    -- ===== 取り込み時刻（record_ingest_time 有効時のみ） =====
+    IngestTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- ClickHouseが行を書き込んだ時刻
+    ExportTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- エクスポーターが挿入を送信した時刻（値を送信しない場合は書き込み時刻）
+    {{- end}}
     
     -- ===== パフォーマンス インデックス =====
     -- 高速属性検索のためのBloomフィルタインデックス
     -- ディメンション/ラベルによるフィルタリングのパフォーマンスに重要
+{{- if not .MetricsDimensions}}
     INDEX idx_res_attr_key mapKeys(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- リソース属性キーの高速検索
     INDEX idx_res_attr_value mapValues(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- リソース属性値の高速検索
+{{- if not .MetricsLite}}
     INDEX idx_scope_attr_key mapKeys(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- スコープ属性キーの高速検索
     INDEX idx_scope_attr_value mapValues(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- スコープ属性値の高速検索  
+{{- end}}
+{{- end}}
     INDEX idx_attr_key mapKeys(Attributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- メトリクス属性キー（ラベル）の高速検索
     INDEX idx_attr_value mapValues(Attributes) TYPE bloom_filter(0.01) GRANULARITY 1
                                                                   -- メトリクス属性値（ラベル値）の高速検索
-    ) ENGINE = %s
-    %s
+    ) ENGINE = {{.Engine}}
+    {{.TTL}}
     PARTITION BY toDate(TimeUnix)                               -- 効率的なデータライフサイクル管理のための日次パーティション
-    ORDER BY (ServiceName, MetricName, Attributes, toUnixTimestamp64Nano(TimeUnix))
+    ORDER BY {{.OrderBy}}  -- metrics_order_by（既定の順序は以下）
                                                                   -- 典型的なHistogramクエリに最適化されたソート順序:
                                                                   -- 1. サービス名でフィルタ
                                                                   -- 2. メトリクス名でフィルタ（例: レイテンシー メトリクス）
                                                                   -- 3. ディメンションでフィルタ（endpoint, methodなど）
                                                                   -- 4. トレンド分析のための時系列順序
-    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1   -- パフォーマンス調整:
+    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}   -- パフォーマンス調整:
                                                                   -- index_granularity: メモリと精度のバランス調整
                                                                   -- ttl_only_drop_parts: パーティション レベルの効率的なTTL
@@ -0,0 +1,9 @@
+INSERT INTO "{{.Database}}"."{{.Table}}" (
+    {{- range $i, $column := .Columns}}{{if $i}},{{end}}
+    {{$column}}
+    {{- end}}
+) VALUES (
+    {{- range $i, $column := .Columns}}{{if $i}},{{end}}
+    ?
+    {{- end}}
+)
@@ -3,22 +3,30 @@
 -- Sumメトリクスは時間とともに蓄積される値を表します（リクエスト数、転送バイト数、エラーなど）
 -- OpenTelemetryメトリクス データモデルに基づく: https://opentelemetry.io/docs/specs/otel/metrics/data-model/
 
-CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
+{{- if .MetricsDimensions}}
+    -- ===== リソース・スコープ =====
+    -- リソースとスコープはディメンションテーブルに1組1行で保存し、データポイントはハッシュのみで参照する
+    DimensionsHash UInt64 CODEC(ZSTD(1)),                       -- ディメンションテーブルの DimensionsHash
+{{- else}}
     -- ===== リソース識別情報 =====
     -- メトリクスを送信するリソース（サービス、ホスト、コンテナ）に関するメタデータ
-    ResourceAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ResourceAttributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- リソースメタデータ: service.name, host.name, k8s.pod.name  
                                                                   -- Map型によりリソースプロパティの柔軟なクエリが可能
     ResourceSchemaUrl String CODEC(ZSTD(1)),                    -- リソース属性のスキーマバージョンURL
     
+{{- if not .MetricsLite}}
     -- ===== インストゥルメンテーションスコープ =====
     -- メトリクス収集ライブラリ/フレームワークに関する情報
     ScopeName String CODEC(ZSTD(1)),                            -- インストゥルメンテーションライブラリ名（例：「prometheus」、「custom-metrics」）
     ScopeVersion String CODEC(ZSTD(1)),                         -- インストゥルメンテーションライブラリのバージョン
-    ScopeAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ScopeAttributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- インストゥルメンテーションスコープに関する追加メタデータ
     ScopeDroppedAttrCount UInt32 CODEC(ZSTD(1)),               -- 制限により削除されたスコープ属性数
     ScopeSchemaUrl String CODEC(ZSTD(1)),                       -- スコープ属性のスキーマバージョンURL
+{{- end}}
+{{- end}}
     
     -- ===== サービスとメトリクス識別情報 =====
     ServiceName LowCardinality(String) CODEC(ZSTD(1)),          -- グループ化とフィルタリング用のサービス名
@@ -29,7 +37,7 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     
     -- ===== メトリクスディメンション =====
     -- メトリクス値にコンテキストを提供するラベル/ディメンション
-    Attributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    Attributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- メトリクスディメンション：method、status_code、endpoint、instance
                                                                   -- これらが固有の時系列アイデンティティを作成
     
@@ -46,52 +54,71 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
                                                                   -- カウンターの場合：通常単調増加
                                                                   -- デルタ合計の場合：変化を表す任意の値
     
+{{- if not .MetricsLite}}
     -- ===== メタデータとフラグ =====
     Flags UInt32 CODEC(ZSTD(1)),                               -- OpenTelemetryデータポイントフラグ（将来使用のため予約済み）
+{{- end}}
+    {{- if .StaleColumn}}
+    Stale Boolean CODEC(ZSTD(1)),                              -- ステールマーカー（FLAG_NO_RECORDED_VALUE、系列の終わりを示す）のデータポイントかどうか
+    {{- end}}
     
+{{- if not .MetricsLite}}
     -- ===== エグゼンプラー =====
     -- このメトリクスデータポイントに貢献したサンプルトレース
     -- エグゼンプラーは根本原因分析のためのメトリクスと分散トレースの連携を提供
     Exemplars Nested (
-        FilteredAttributes Map(LowCardinality(String), String), -- 追加のエグゼンプラー属性
+        FilteredAttributes {{.MapType}}, -- 追加のエグゼンプラー属性
         TimeUnix DateTime64(9),                                  -- このエグゼンプラーがキャプチャされた時刻
         Value Float64,                                           -- このエグゼンプラーに関連付けられた値（多くの場合単一のインクリメント）
         SpanId String,                                           -- このエグゼンプラーを生成したトレースのスパンID
         TraceId String                                           -- トレーシングデータとの相関用のトレースID
     ) CODEC(ZSTD(1)),                                           -- Nested型により1つのデータポイントあたり複数のエグゼンプラーが可能
+{{- end}}
     
     -- ===== Sum固有のメタデータ =====
     AggregationTemporality Int32 CODEC(ZSTD(1)),               -- データポイントの集約方法：
                                                                   -- 1 = DELTA（値は前回レポートからの変化を表す）
                                                                   -- 2 = CUMULATIVE（値は開始からの合計を表す）
+                                                                  -- 0 = UNSPECIFIED のデータポイントは保存せずに拒否する
     IsMonotonic Boolean CODEC(Delta, ZSTD(1)),                 -- 合計が増加のみか（カウンターの場合true）
                                                                   -- Deltaコーデックにより真偽値を効率化
                                                                   -- レート計算とアラートに重要
     
+    {{- if .IngestTime}}
+
+    -- ===== 取り込み時刻（record_ingest_time 有効時のみ） =====
+    IngestTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- ClickHouseが行を書き込んだ時刻
+    ExportTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- エクスポーターが挿入を送信した時刻（値を送信しない場合は書き込み時刻）
+    {{- end}}
+    
     -- ===== パフォーマンスインデックス =====
     -- 高速属性検索のためのBloom filterインデックス
     -- ラベル/ディメンションでのフィルタリング時のパフォーマンスに必須
+{{- if not .MetricsDimensions}}
     INDEX idx_res_attr_key mapKeys(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- リソース属性キーの高速ルックアップ
     INDEX idx_res_attr_value mapValues(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- リソース属性値の高速ルックアップ
+{{- if not .MetricsLite}}
     INDEX idx_scope_attr_key mapKeys(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- スコープ属性キーの高速ルックアップ
     INDEX idx_scope_attr_value mapValues(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- スコープ属性値の高速ルックアップ
+{{- end}}
+{{- end}}
     INDEX idx_attr_key mapKeys(Attributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- メトリクス属性キー（ラベル）の高速ルックアップ
     INDEX idx_attr_value mapValues(Attributes) TYPE bloom_filter(0.01) GRANULARITY 1
                                                                   -- メトリクス属性値（ラベル値）の高速ルックアップ
-    ) ENGINE = %s
-    %s
+    ) ENGINE = {{.Engine}}
+    {{.TTL}}
     PARTITION BY toDate(TimeUnix)                               -- 効率的なデータライフサイクル管理のための日次パーティション
-    ORDER BY (ServiceName, MetricName, Attributes, toUnixTimestamp64Nano(TimeUnix))
+    ORDER BY {{.OrderBy}}  -- metrics_order_by（既定の順序は以下）
                                                                   -- 典型的なメトリクスクエリに最適なソート順序：
                                                                   -- 1. サービスでフィルタ
                                                                   -- 2. メトリクス名でフィルタ
                                                                   -- 3. ディメンション/ラベルでフィルタ
                                                                   -- 4. レート計算のための時系列順序付け
-    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1   -- パフォーマンスチューニング：
+    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}   -- パフォーマンスチューニング：
                                                                   -- index_granularity：メモリと精度のバランス
                                                                   -- ttl_only_drop_parts：効率的なパーティションレベルTTL
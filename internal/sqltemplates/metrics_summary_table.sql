@@ -3,22 +3,30 @@
 -- Summariesは観測値の事前計算済み分位数を表します（P50、P95、P99レイテンシなど）
 -- OpenTelemetry メトリクスデータモデルに基づく: https://opentelemetry.io/docs/specs/otel/metrics/data-model/
 
-CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
+{{- if .MetricsDimensions}}
+    -- ===== リソース・スコープ =====
+    -- リソースとスコープはディメンションテーブルに1組1行で保存し、データポイントはハッシュのみで参照する
+    DimensionsHash UInt64 CODEC(ZSTD(1)),                       -- ディメンションテーブルの DimensionsHash
+{{- else}}
     -- ===== リソース識別 =====
     -- メトリクスを出力するリソース（サービス、ホスト、コンテナ）に関するメタデータ
-    ResourceAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ResourceAttributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- リソースメタデータ: service.name, host.name, k8s.pod.name
                                                                   -- Map型によりリソースプロパティの柔軟なクエリが可能
     ResourceSchemaUrl String CODEC(ZSTD(1)),                    -- リソース属性のスキーマバージョンURL
     
+{{- if not .MetricsLite}}
     -- ===== インストルメンテーションスコープ =====
     -- メトリクス収集ライブラリ/フレームワークに関する情報
     ScopeName String CODEC(ZSTD(1)),                            -- インストルメンテーションライブラリ名 (例: "prometheus-client", "custom-metrics")
     ScopeVersion String CODEC(ZSTD(1)),                         -- インストルメンテーションライブラリのバージョン
-    ScopeAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    ScopeAttributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- インストルメンテーションスコープに関する追加メタデータ
     ScopeDroppedAttrCount UInt32 CODEC(ZSTD(1)),               -- 制限により削除されたスコープ属性の数
     ScopeSchemaUrl String CODEC(ZSTD(1)),                       -- スコープ属性のスキーマバージョンURL
+{{- end}}
+{{- end}}
     
     -- ===== サービスとメトリクス識別 =====
     ServiceName LowCardinality(String) CODEC(ZSTD(1)),          -- グループ化とフィルタリング用のサービス名
@@ -29,7 +37,7 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     
     -- ===== メトリクスディメンション =====
     -- メトリクス値にコンテキストを提供するラベル/ディメンション
-    Attributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    Attributes {{.MapType}} CODEC(ZSTD(1)),
                                                                   -- メトリクスディメンション: job, instance, method, handler
                                                                   -- これらがユニークな時系列アイデンティティを作成
     
@@ -57,34 +65,50 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
                                                                   -- 一般的な分位数: 0.5 (中央値), 0.9, 0.95, 0.99
                                                                   -- バケット計算なしで直接SLAモニタリングが可能
     
+{{- if not .MetricsLite}}
     -- ===== メタデータとフラグ =====
     Flags UInt32 CODEC(ZSTD(1)),                               -- OpenTelemetryデータポイントフラグ (将来の利用のために予約)
+{{- end}}
+    {{- if .StaleColumn}}
+    Stale Boolean CODEC(ZSTD(1)),                              -- ステールマーカー（FLAG_NO_RECORDED_VALUE、系列の終わりを示す）のデータポイントかどうか
+    {{- end}}
+    
+    {{- if .IngestTime}}
+
+    -- ===== 取り込み時刻（record_ingest_time 有効時のみ） =====
+    IngestTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- ClickHouseが行を書き込んだ時刻
+    ExportTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- エクスポーターが挿入を送信した時刻（値を送信しない場合は書き込み時刻）
+    {{- end}}
     
     -- ===== パフォーマンス インデックス =====
     -- 高速属性検索のためのBloomフィルタインデックス
     -- ラベル/ディメンションによるクエリのパフォーマンスに必須
+{{- if not .MetricsDimensions}}
     INDEX idx_res_attr_key mapKeys(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- リソース属性キーの高速検索
     INDEX idx_res_attr_value mapValues(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- リソース属性値の高速検索
+{{- if not .MetricsLite}}
     INDEX idx_scope_attr_key mapKeys(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- スコープ属性キーの高速検索
     INDEX idx_scope_attr_value mapValues(ScopeAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- スコープ属性値の高速検索
+{{- end}}
+{{- end}}
     INDEX idx_attr_key mapKeys(Attributes) TYPE bloom_filter(0.01) GRANULARITY 1,
                                                                   -- メトリクス属性キー（ラベル）の高速検索
     INDEX idx_attr_value mapValues(Attributes) TYPE bloom_filter(0.01) GRANULARITY 1
                                                                   -- メトリクス属性値（ラベル値）の高速検索
-    ) ENGINE = %s
-    %s
+    ) ENGINE = {{.Engine}}
+    {{.TTL}}
     PARTITION BY toDate(TimeUnix)                               -- 効率的なデータライフサイクル管理のための日次パーティション
-    ORDER BY (ServiceName, MetricName, Attributes, toUnixTimestamp64Nano(TimeUnix))
+    ORDER BY {{.OrderBy}}  -- metrics_order_by（既定の順序は以下）
                                                                   -- 典型的なSummaryクエリに最適化されたソート順序:
                                                                   -- 1. サービス名でフィルタ
                                                                   -- 2. メトリクス名でフィルタ（例: レイテンシーサマリー）
                                                                   -- 3. ディメンションでフィルタ（job, instanceなど）
                                                                   -- 4. トレンド分析のための時系列順序
-    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1   -- パフォーマンス調整:
+    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}   -- パフォーマンス調整:
                                                                   -- index_granularity: メモリと精度のバランス調整
                                                                   -- ttl_only_drop_parts: パーティション レベルの効率的なTTL
 
@@ -0,0 +1,51 @@
+INSERT INTO "{{.Database}}"."{{.Table}}" (
+    "Timestamp",
+    "ObservedTimestamp",
+    "TraceId",
+    "SpanId",
+    "TraceFlags",
+    "SeverityText",
+    "SeverityNumber",
+    "ServiceName",
+    "ServiceVersion",
+    "Body",
+    "ResourceAttributes",
+    "ResourceSchemaUrl",
+    "ScopeName",
+    "ScopeVersion",
+    "ScopeAttributes",
+    "ScopeDroppedAttrCount",
+    "ScopeSchemaUrl",
+    "LogAttributes",
+    "LogDroppedAttrCount"
+    {{- if .SourceColumns}},
+    "CollectorInstanceId",
+    "CollectorPipeline",
+    "CollectorReceiver"
+    {{- end}}
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15,
+    $16,
+    $17,
+    $18,
+    $19
+    {{- if .SourceColumns}},
+    $20,
+    $21,
+    $22
+    {{- end}}
+)
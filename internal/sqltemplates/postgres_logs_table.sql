@@ -0,0 +1,29 @@
+-- OpenTelemetry ログデータ格納用PostgreSQL（TimescaleDB）テーブル作成SQL
+-- 列名・列順はClickHouseのログテーブルと同じ（大文字小文字を保持するため引用符で囲む）
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" (
+    "Timestamp" TIMESTAMPTZ NOT NULL,         -- ログイベント時刻（既定のハイパーテーブルの時間列）
+    "ObservedTimestamp" TIMESTAMPTZ NOT NULL, -- ログが観測/収集された時刻（logs_primary_time: observed_timestamp の場合の時間列）
+    "TraceId" TEXT NOT NULL,                  -- 相関するトレースID
+    "SpanId" TEXT NOT NULL,                   -- 相関するスパンID
+    "TraceFlags" BIGINT NOT NULL,             -- W3Cトレースコンテキストのフラグ
+    "SeverityText" TEXT NOT NULL,             -- 重要度（ERROR, WARN, INFO など）
+    "SeverityNumber" INTEGER NOT NULL,        -- 数値重要度（1-24）
+    "ServiceName" TEXT NOT NULL,              -- ログを生成したサービス
+    "ServiceVersion" TEXT NOT NULL,           -- サービスバージョン
+    "Body" TEXT NOT NULL,                     -- ログ本文
+    "ResourceAttributes" JSONB NOT NULL,      -- リソース属性
+    "ResourceSchemaUrl" TEXT NOT NULL,        -- リソース属性のスキーマURL
+    "ScopeName" TEXT NOT NULL,                -- ライブラリ名
+    "ScopeVersion" TEXT NOT NULL,             -- ライブラリバージョン
+    "ScopeAttributes" JSONB NOT NULL,         -- スコープ属性
+    "ScopeDroppedAttrCount" BIGINT NOT NULL,  -- 破棄されたスコープ属性数
+    "ScopeSchemaUrl" TEXT NOT NULL,           -- スコープ属性のスキーマURL
+    "LogAttributes" JSONB NOT NULL,           -- ログ属性
+    -- 破棄されたログ属性数（source_columns の列が続く場合があるため、行末にコメントを置かない）
+    "LogDroppedAttrCount" BIGINT NOT NULL
+    {{- if .SourceColumns}},
+    "CollectorInstanceId" TEXT NOT NULL,      -- 書き込んだコレクターの service.instance.id
+    "CollectorPipeline" TEXT NOT NULL,        -- パイプライン名
+    "CollectorReceiver" TEXT NOT NULL         -- レシーバー名
+    {{- end}}
+)
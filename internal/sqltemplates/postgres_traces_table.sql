@@ -0,0 +1,33 @@
+-- OpenTelemetry トレースデータ格納用PostgreSQL（TimescaleDB）テーブル作成SQL
+-- 列名・列順はClickHouseのトレーステーブルと同じ（大文字小文字を保持するため引用符で囲む）
+-- Nested 型の代わりにイベント・リンクは配列（属性はJSONBの配列）で保存
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" (
+    "Timestamp" TIMESTAMPTZ NOT NULL,       -- スパン開始時刻（マイクロ秒精度、ハイパーテーブルの時間列）
+    "TraceId" TEXT NOT NULL,                -- トレース識別子（16進数文字列）
+    "SpanId" TEXT NOT NULL,                 -- スパン識別子（16進数文字列）
+    "ParentSpanId" TEXT NOT NULL,           -- 親スパン識別子
+    "TraceState" TEXT NOT NULL,             -- トレース状態情報
+    "SpanName" TEXT NOT NULL,               -- 操作名・エンドポイント名
+    "SpanKind" TEXT NOT NULL,               -- スパン種別
+    "ServiceName" TEXT NOT NULL,            -- マイクロサービス名
+    "ResourceAttributes" JSONB NOT NULL,    -- リソース属性
+    "ScopeName" TEXT NOT NULL,              -- ライブラリ名
+    "ScopeVersion" TEXT NOT NULL,           -- ライブラリバージョン
+    "SpanAttributes" JSONB NOT NULL,        -- スパン属性
+    "Duration" BIGINT NOT NULL,             -- スパン実行時間（ナノ秒）
+    "StatusCode" TEXT NOT NULL,             -- 実行結果
+    "StatusMessage" TEXT NOT NULL,          -- エラーメッセージ等の詳細
+    "Events.Timestamp" TIMESTAMPTZ[] NOT NULL, -- イベント発生時刻
+    "Events.Name" TEXT[] NOT NULL,             -- イベント名
+    "Events.Attributes" JSONB NOT NULL,        -- イベント属性（オブジェクトの配列）
+    "Links.TraceId" TEXT[] NOT NULL,           -- リンク先トレースID
+    "Links.SpanId" TEXT[] NOT NULL,            -- リンク先スパンID
+    "Links.TraceState" TEXT[] NOT NULL,        -- リンク先状態
+    -- リンク属性（オブジェクトの配列、source_columns の列が続く場合があるため行末にコメントを置かない）
+    "Links.Attributes" JSONB NOT NULL
+    {{- if .SourceColumns}},
+    "CollectorInstanceId" TEXT NOT NULL,    -- 書き込んだコレクターの service.instance.id
+    "CollectorPipeline" TEXT NOT NULL,      -- パイプライン名
+    "CollectorReceiver" TEXT NOT NULL       -- レシーバー名
+    {{- end}}
+)
@@ -0,0 +1,41 @@
+INSERT INTO "{{.Database}}"."{{.Table}}" (
+    Timestamp,
+    Duration,
+    ProfileId,
+    ServiceName,
+    ResourceAttributes,
+    ResourceSchemaUrl,
+    ScopeName,
+    ScopeVersion,
+    ScopeAttributes,
+    ScopeSchemaUrl,
+    PeriodType,
+    Period,
+    SampleCount,
+    DroppedAttributesCount,
+    OriginalPayloadFormat,
+    OriginalPayload
+    {{- if .IngestTime}},
+    ExportTimestamp
+    {{- end}}
+) VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+    {{- if .IngestTime}},
+    ?
+    {{- end}}
+)
@@ -0,0 +1,52 @@
+-- OpenTelemetryデータのためのClickHouse Profilesテーブル スキーマ
+-- このテーブルはプロファイル（CPU・メモリ等のサンプリング結果）をプロファイル単位で保存します
+-- OpenTelemetryプロファイル データモデルに基づく: https://opentelemetry.io/docs/specs/otel/profiles/
+
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
+    -- ===== タイムスタンプ フィールド =====
+    Timestamp DateTime64(9) CODEC(Delta, ZSTD(1)),              -- プロファイル収集の開始時刻（ナノ秒精度）
+    Duration UInt64 CODEC(ZSTD(1)),                             -- プロファイル収集期間（ナノ秒）
+
+    -- ===== プロファイル識別 =====
+    ProfileId String CODEC(ZSTD(1)),                            -- プロファイル識別子（32文字の16進文字列）
+    ServiceName LowCardinality(String) CODEC(ZSTD(1)),          -- プロファイルを生成したサービス（フィルタリング/グループ化用）
+
+    -- ===== リソースとスコープ =====
+    ResourceAttributes {{.MapType}} CODEC(ZSTD(1)),
+                                                                  -- リソースメタデータ: host.name, k8s.pod.name など
+    ResourceSchemaUrl String CODEC(ZSTD(1)),                    -- リソース属性のスキーマ バージョンURL
+    ScopeName String CODEC(ZSTD(1)),                            -- プロファイラー（インストルメンテーション ライブラリ）名
+    ScopeVersion String CODEC(ZSTD(1)),                         -- プロファイラーのバージョン
+    ScopeAttributes {{.MapType}} CODEC(ZSTD(1)),
+                                                                  -- スコープに関する追加メタデータ
+    ScopeSchemaUrl String CODEC(ZSTD(1)),                       -- スコープ属性のスキーマ バージョンURL
+
+    -- ===== サンプリング情報 =====
+    PeriodType LowCardinality(String) CODEC(ZSTD(1)),           -- サンプリング周期の種類（例: cpu/nanoseconds）
+    Period Int64 CODEC(ZSTD(1)),                                -- サンプリング周期
+    SampleCount UInt64 CODEC(ZSTD(1)),                          -- プロファイルに含まれるサンプル数
+    DroppedAttributesCount UInt32 CODEC(ZSTD(1)),               -- 制限により削除された属性数
+
+    -- ===== 元データ =====
+    OriginalPayloadFormat LowCardinality(String) CODEC(ZSTD(1)),-- 変換前のフォーマット（例: pprof, jfr）
+    OriginalPayload String CODEC(ZSTD(3)),                      -- 変換前の生データ（存在する場合）
+
+    {{- if .IngestTime}}
+
+    -- ===== 取り込み時刻（record_ingest_time 有効時のみ） =====
+    IngestTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- ClickHouseが行を書き込んだ時刻
+    ExportTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- エクスポーターが挿入を送信した時刻（値を送信しない場合は書き込み時刻）
+    {{- end}}
+    
+    -- ===== パフォーマンス インデックス =====
+    INDEX idx_profile_id ProfileId TYPE bloom_filter(0.01) GRANULARITY 1,
+                                                                  -- プロファイルIDの高速ルックアップ
+    INDEX idx_res_attr_key mapKeys(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
+                                                                  -- リソース属性キーの高速ルックアップ
+    INDEX idx_res_attr_value mapValues(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1
+                                                                  -- リソース属性値の高速ルックアップ
+    ) ENGINE = {{.Engine}}
+    {{.TTL}}
+    PARTITION BY toDate(Timestamp)                               -- 日次パーティション
+    ORDER BY {{.OrderBy}}                -- profiles_order_by（既定: サービス・プロファイル種別ごとの時系列検索に最適化）
+    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}
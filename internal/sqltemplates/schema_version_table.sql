@@ -0,0 +1,10 @@
+-- マイグレーションの適用履歴を記録するテーブル
+-- シグナルごとに適用済みのバージョンを保持し、起動時に未適用のマイグレーションのみを実行する
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
+    Signal LowCardinality(String),   -- 対象シグナル（traces, logs, profiles）
+    TableName String,                -- マイグレーションを適用したテーブル
+    Version UInt32,                  -- マイグレーションのバージョン
+    Name String,                     -- マイグレーション名
+    AppliedAt DateTime64(3)          -- 適用日時
+) ENGINE = {{.Engine}}
+ORDER BY (Signal, TableName, Version)
@@ -0,0 +1,11 @@
+INSERT INTO "{{.Database}}"."{{.Table}}" (
+    Timestamp,
+    Client,
+    Server,
+    ConnectionType,
+    Calls,
+    Failed,
+    LatencySum,
+    LatencyBuckets,
+    LatencyBounds
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
@@ -0,0 +1,23 @@
+-- サービスグラフ（サービス間の呼び出し関係）テーブル（service_graph.enabled 有効時のみ）
+-- スパンの親子関係から求めた呼び出し元→呼び出し先の組ごとに、1分単位の呼び出し数・失敗数・レイテンシのヒストグラムを保存します
+-- 同じ分・組の行はバッチごとに書き込まれるため、参照時に集計してください
+-- 例: 直近1時間のサービスマップ
+--   SELECT Client, Server, ConnectionType, sum(Calls), sum(Failed), sum(LatencySum) / sum(Calls) AS AvgLatencyMs,
+--          sumForEach(LatencyBuckets) AS Buckets, any(LatencyBounds) AS Bounds
+--   FROM otel_service_graph WHERE Timestamp > now() - INTERVAL 1 HOUR
+--   GROUP BY Client, Server, ConnectionType
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
+    Timestamp DateTime CODEC(Delta, ZSTD(1)),               -- 呼び出し元スパンの開始時刻（分単位に切り捨て）
+    Client LowCardinality(String) CODEC(ZSTD(1)),           -- 呼び出し元のサービス名
+    Server LowCardinality(String) CODEC(ZSTD(1)),           -- 呼び出し先のサービス名（ピア属性から決めた場合はその値）
+    ConnectionType LowCardinality(String) CODEC(ZSTD(1)),   -- 呼び出しの種類（空: 直接, messaging_system, database, virtual_node）
+    Calls UInt64 CODEC(Delta, ZSTD(1)),                     -- 呼び出し数
+    Failed UInt64 CODEC(Delta, ZSTD(1)),                    -- 呼び出し元または呼び出し先のスパンがエラーの呼び出し数
+    LatencySum Float64 CODEC(ZSTD(1)),                      -- レイテンシの合計（ミリ秒）
+    LatencyBuckets Array(UInt64) CODEC(ZSTD(1)),            -- レイテンシのヒストグラム（LatencyBounds の各境界以下の件数、末尾は最後の境界を超えた件数）
+    LatencyBounds Array(Float64) CODEC(ZSTD(1))             -- ヒストグラムの境界（ミリ秒、service_graph.latency_buckets）
+) ENGINE = {{.Engine}}
+PARTITION BY toDate(Timestamp)
+ORDER BY (Client, Server, Timestamp)
+{{.TTL}}
+SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}
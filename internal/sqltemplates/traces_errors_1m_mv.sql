@@ -0,0 +1,9 @@
+CREATE MATERIALIZED VIEW IF NOT EXISTS "{{.Database}}"."{{.Table}}_errors_1m_mv" {{.Cluster}}
+TO "{{.Database}}"."{{.Table}}_errors_1m"
+AS SELECT
+    toStartOfMinute(Timestamp) AS Timestamp,
+    ServiceName,
+    count() AS SpanCount,
+    countIf(StatusCode = 'Error') AS ErrorCount
+FROM "{{.Database}}"."{{.Table}}"
+GROUP BY Timestamp, ServiceName
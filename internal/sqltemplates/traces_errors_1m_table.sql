@@ -0,0 +1,16 @@
+-- サービスごと・1分ごとのスパン数とエラー数（create_aggregation_views 有効時のみ）
+-- SummingMergeTree はマージ時に同じキーの行を合算するため、参照時は sum() で集計してください
+-- 例: 直近1時間のサービスごとのエラー率
+--   SELECT Timestamp, ServiceName, sum(ErrorCount) / sum(SpanCount) AS ErrorRate
+--   FROM otel_traces_errors_1m WHERE Timestamp >= now() - INTERVAL 1 HOUR
+--   GROUP BY Timestamp, ServiceName ORDER BY Timestamp
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}_errors_1m" {{.Cluster}} (
+    Timestamp DateTime CODEC(Delta, ZSTD(1)),          -- 集計期間（1分）の開始時刻
+    ServiceName LowCardinality(String) CODEC(ZSTD(1)), -- サービス名
+    SpanCount UInt64 CODEC(ZSTD(1)),                   -- スパン数
+    ErrorCount UInt64 CODEC(ZSTD(1))                   -- ステータスがエラーのスパン数
+) ENGINE = {{.Engine}}
+PARTITION BY toDate(Timestamp)
+ORDER BY {{.OrderBy}}
+{{.TTL}}
+SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}
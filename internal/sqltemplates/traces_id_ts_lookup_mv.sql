@@ -1,9 +1,9 @@
-CREATE MATERIALIZED VIEW IF NOT EXISTS "%s"."%s_trace_id_ts_mv" %s
-TO "%s"."%s_trace_id_ts"
+CREATE MATERIALIZED VIEW IF NOT EXISTS "{{.Database}}"."{{.Table}}_trace_id_ts_mv" {{.Cluster}}
+TO "{{.Database}}"."{{.Table}}_trace_id_ts"
 AS SELECT
     TraceId,
     min(Timestamp) as Start,
     max(Timestamp) as End
-FROM "%s"."%s"
+FROM "{{.Database}}"."{{.Table}}"
 WHERE TraceId != ''
 GROUP BY TraceId
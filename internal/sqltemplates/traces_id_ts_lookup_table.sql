@@ -1,10 +1,15 @@
-CREATE TABLE IF NOT EXISTS "%s"."%s_trace_id_ts" %s (
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}_trace_id_ts" {{.Cluster}} (
     TraceId String CODEC(ZSTD(1)),
+{{- if .AggregateColumns}}
+    Start SimpleAggregateFunction(min, DateTime) CODEC(Delta, ZSTD(1)),
+    End SimpleAggregateFunction(max, DateTime) CODEC(Delta, ZSTD(1)),
+{{- else}}
     Start DateTime CODEC(Delta, ZSTD(1)),
     End DateTime CODEC(Delta, ZSTD(1)),
+{{- end}}
     INDEX idx_trace_id TraceId TYPE bloom_filter(0.01) GRANULARITY 1
-) ENGINE = %s
+) ENGINE = {{.Engine}}
     PARTITION BY toDate(Start)
-    ORDER BY (TraceId, Start)
-    %s
-    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1
+    ORDER BY {{.OrderBy}}
+    {{.TTL}}
+    SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}
@@ -1,4 +1,4 @@
-INSERT INTO "%s"."%s" (
+INSERT INTO "{{.Database}}"."{{.Table}}" (
     Timestamp,
     TraceId,
     SpanId,
@@ -21,6 +21,28 @@ INSERT INTO "%s"."%s" (
     Links.SpanId,
     Links.TraceState,
     Links.Attributes
+    {{- if .TraceScope}},
+    ResourceSchemaUrl,
+    ScopeAttributes,
+    ScopeSchemaUrl
+    {{- end}}
+    {{- if .Truncated}},
+    Truncated
+    {{- end}}
+    {{- if .SourceColumns}},
+    CollectorInstanceId,
+    CollectorPipeline,
+    CollectorReceiver
+    {{- end}}
+    {{- if .AnomalyScore}},
+    AnomalyScore
+    {{- end}}
+    {{- if .IngestTime}},
+    ExportTimestamp
+    {{- end}}
+    {{- range .ExtraColumns}},
+    {{.}}
+    {{- end}}
 ) VALUES (
     ?,
     ?,
@@ -44,4 +66,26 @@ INSERT INTO "%s"."%s" (
     ?,
     ?,
     ?
+    {{- if .TraceScope}},
+    ?,
+    ?,
+    ?
+    {{- end}}
+    {{- if .Truncated}},
+    ?
+    {{- end}}
+    {{- if .SourceColumns}},
+    ?,
+    ?,
+    ?
+    {{- end}}
+    {{- if .AnomalyScore}},
+    ?
+    {{- end}}
+    {{- if .IngestTime}},
+    ?
+    {{- end}}
+    {{- range .ExtraColumns}},
+    ?
+    {{- end}}
 )
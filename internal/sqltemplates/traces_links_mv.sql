@@ -0,0 +1,14 @@
+CREATE MATERIALIZED VIEW IF NOT EXISTS "{{.Database}}"."{{.Table}}_links_mv" {{.Cluster}}
+TO "{{.Database}}"."{{.Table}}_links"
+AS SELECT
+    Timestamp,
+    TraceId,
+    SpanId,
+    ServiceName,
+    SpanName,
+    Links.TraceId AS LinkTraceId,
+    Links.SpanId AS LinkSpanId,
+    Links.TraceState AS LinkTraceState,
+    Links.Attributes AS LinkAttributes
+FROM "{{.Database}}"."{{.Table}}"
+ARRAY JOIN Links
@@ -0,0 +1,27 @@
+-- スパンリンクのテーブル（traces.links_table 有効時のみ）
+-- メインテーブルの Links.* 配列をリンク1件につき1行に展開し、トレースをまたぐ因果関係を検索しやすくします
+-- 例: あるトレースにリンクしているスパンの検索
+--   SELECT TraceId, SpanId FROM otel_traces_links WHERE LinkTraceId = '<trace_id>'
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}_links" {{.Cluster}} (
+    -- === リンク元のスパン ===
+    Timestamp DateTime64(9) CODEC(Delta, ZSTD(1)),     -- リンク元スパンの開始時刻
+    TraceId String CODEC(ZSTD(1)),                     -- リンク元トレースID
+    SpanId String CODEC(ZSTD(1)),                      -- リンク元スパンID
+    ServiceName LowCardinality(String) CODEC(ZSTD(1)), -- リンク元のサービス名
+    SpanName LowCardinality(String) CODEC(ZSTD(1)),    -- リンク元のスパン名
+
+    -- === リンク先 ===
+    LinkTraceId String CODEC(ZSTD(1)),                 -- リンク先トレースID
+    LinkSpanId String CODEC(ZSTD(1)),                  -- リンク先スパンID
+    LinkTraceState String CODEC(ZSTD(1)),              -- リンク先の状態
+    LinkAttributes {{.MapType}} CODEC(ZSTD(1)),        -- リンク属性
+
+    -- リンク元のトレースIDからの検索（リンク先からの検索は ORDER BY で高速化）
+    INDEX idx_trace_id TraceId TYPE bloom_filter(0.001) GRANULARITY 1,
+    INDEX idx_link_attr_key mapKeys(LinkAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
+    INDEX idx_link_attr_value mapValues(LinkAttributes) TYPE bloom_filter(0.01) GRANULARITY 1
+) ENGINE = {{.Engine}}
+PARTITION BY toDate(Timestamp)
+ORDER BY {{.OrderBy}}
+{{.TTL}}
+SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}
@@ -0,0 +1,14 @@
+CREATE MATERIALIZED VIEW IF NOT EXISTS "{{.Database}}"."{{.Table}}_roots_mv" {{.Cluster}}
+TO "{{.Database}}"."{{.Table}}_roots"
+AS SELECT
+    Timestamp,
+    TraceId,
+    SpanId,
+    ServiceName,
+    SpanName,
+    SpanKind,
+    Duration,
+    StatusCode,
+    StatusMessage
+FROM "{{.Database}}"."{{.Table}}"
+WHERE ParentSpanId = ''
@@ -0,0 +1,24 @@
+-- ルートスパンのテーブル（traces.roots_table 有効時のみ）
+-- 親を持たないスパン（トレースの起点）のみを保存し、全スパンを走査せずに最近のトレースを一覧できるようにします
+-- 例: サービスの最近のエラートレースの一覧
+--   SELECT Timestamp, TraceId, SpanName, Duration FROM otel_traces_roots
+--   WHERE ServiceName = 'frontend' AND StatusCode = 'Error' ORDER BY Timestamp DESC LIMIT 100
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}_roots" {{.Cluster}} (
+    Timestamp DateTime64(9) CODEC(Delta, ZSTD(1)),     -- ルートスパンの開始時刻（トレースの開始時刻）
+    TraceId String CODEC(ZSTD(1)),                     -- トレースID
+    SpanId String CODEC(ZSTD(1)),                      -- ルートスパンのスパンID
+    ServiceName LowCardinality(String) CODEC(ZSTD(1)), -- トレースを開始したサービス
+    SpanName LowCardinality(String) CODEC(ZSTD(1)),    -- ルートスパンの名前（エンドポイント・操作）
+    SpanKind LowCardinality(String) CODEC(ZSTD(1)),    -- ルートスパンの種類
+    Duration UInt64 CODEC(ZSTD(1)),                    -- ルートスパンの実行時間（ナノ秒、概ねトレース全体の所要時間）
+    StatusCode LowCardinality(String) CODEC(ZSTD(1)),  -- ルートスパンの実行結果
+    StatusMessage String CODEC(ZSTD(1)),               -- エラーメッセージ
+
+    -- トレースIDからの検索と所要時間での絞り込み（サービス・時刻での検索は ORDER BY で高速化）
+    INDEX idx_trace_id TraceId TYPE bloom_filter(0.001) GRANULARITY 1,
+    INDEX idx_duration Duration TYPE minmax GRANULARITY 1
+) ENGINE = {{.Engine}}
+PARTITION BY toDate(Timestamp)
+ORDER BY {{.OrderBy}}
+{{.TTL}}
+SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}
@@ -1,6 +1,6 @@
 -- OpenTelemetry トレースデータ格納用ClickHouseテーブル作成SQL
 -- 大規模分散トレーシングデータの効率的な保存・検索のために最適化
-CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
+CREATE TABLE IF NOT EXISTS "{{.Database}}"."{{.Table}}" {{.Cluster}} (
     -- === 基本トレーシング情報 ===
     -- スパン開始時刻（ナノ秒精度、Delta+ZSTD圧縮で時系列データを最適化）
     Timestamp DateTime64(9) CODEC(Delta, ZSTD(1)),
@@ -19,14 +19,14 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     
     -- === 動的属性データ（Map型で柔軟なスキーマ） ===
     -- OpenTelemetryセマンティックコンベンションに準拠した動的属性
-    ResourceAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)), -- リソース属性
+    ResourceAttributes {{.AttributesType}}, -- リソース属性
     
     -- インストゥルメンテーション情報
     ScopeName String CODEC(ZSTD(1)),        -- ライブラリ名
     ScopeVersion String CODEC(ZSTD(1)),     -- ライブラリバージョン
     
     -- スパン固有の属性（HTTP、DB、RPC等のプロトコル情報）
-    SpanAttributes Map(LowCardinality(String), String) CODEC(ZSTD(1)),
+    SpanAttributes {{.AttributesType}},
     
     -- === 性能・状態情報 ===
     Duration UInt64 CODEC(ZSTD(1)),                    -- スパン実行時間（ナノ秒）
@@ -38,7 +38,7 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
     Events Nested (
         Timestamp DateTime64(9),                                    -- イベント発生時刻
         Name LowCardinality(String),                               -- イベント名
-        Attributes Map(LowCardinality(String), String)             -- イベント属性
+        Attributes {{.MapType}}             -- イベント属性
     ) CODEC(ZSTD(1)),
     
     -- 他のトレース・スパンとの関係性（バッチ処理、非同期処理等）
@@ -46,23 +46,56 @@ CREATE TABLE IF NOT EXISTS "%s"."%s" %s (
         TraceId String,                                            -- リンク先トレースID
         SpanId String,                                             -- リンク先スパンID
         TraceState String,                                         -- リンク先状態
-        Attributes Map(LowCardinality(String), String)             -- リンク属性
+        Attributes {{.MapType}}             -- リンク属性
     ) CODEC(ZSTD(1)),
+    {{- if .TraceScope}}
+
+    -- === スキーマURL・スコープ属性（traces.store_scope 有効時のみ） ===
+    ResourceSchemaUrl String CODEC(ZSTD(1)),                    -- リソース属性のスキーマURL
+    ScopeAttributes {{.AttributesType}},                         -- スコープ属性（store_scope_attributes: false の場合は空）
+    ScopeSchemaUrl String CODEC(ZSTD(1)),                       -- スコープ属性のスキーマURL
+    {{- end}}
+    {{- if .Truncated}}
+
+    -- === 短縮の記録（max_attribute_length 指定時のみ） ===
+    Truncated Bool CODEC(ZSTD(1)),                              -- 属性値を max_attribute_length に短縮した行はtrue
+    {{- end}}
+    {{- if .SourceColumns}}
+
+    -- === 送信元メタデータ（source_columns 有効時のみ） ===
+    CollectorInstanceId LowCardinality(String) CODEC(ZSTD(1)),  -- 書き込んだコレクターの service.instance.id
+    CollectorPipeline LowCardinality(String) CODEC(ZSTD(1)),    -- パイプライン名
+    CollectorReceiver LowCardinality(String) CODEC(ZSTD(1)),    -- レシーバー名
+    {{- end}}
+    {{- if .AnomalyScore}}
+
+    -- === 異常度（anomaly_detection.column 有効時のみ） ===
+    AnomalyScore Float32 CODEC(ZSTD(1)),                        -- サービスのエラー率・レイテンシの基準値からの逸脱（標準偏差の倍数）
+    {{- end}}
+    {{- if .IngestTime}}
+
+    -- === 取り込み時刻（record_ingest_time 有効時のみ） ===
+    IngestTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- ClickHouseが行を書き込んだ時刻
+    ExportTimestamp DateTime64(9) DEFAULT now64(9) CODEC(Delta, ZSTD(1)), -- エクスポーターが挿入を送信した時刻
+    {{- end}}
     
     -- === 高速検索用インデックス群 ===
     -- TraceID検索（最重要・最高精度）: デバッグ時の特定トレース詳細調査
     INDEX idx_trace_id TraceId TYPE bloom_filter(0.001) GRANULARITY 1,
     
     -- 属性検索（探索的分析用）: サービス・環境・バージョン等での絞り込み
+    -- JSON型の属性カラムではmapKeys/mapValuesが使えないため省略
+    {{- if not .JSONAttributes}}
     INDEX idx_res_attr_key mapKeys(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
     INDEX idx_res_attr_value mapValues(ResourceAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
     INDEX idx_span_attr_key mapKeys(SpanAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
     INDEX idx_span_attr_value mapValues(SpanAttributes) TYPE bloom_filter(0.01) GRANULARITY 1,
+    {{- end}}
     
     -- 実行時間範囲検索: 性能問題の特定・SLA監視
     INDEX idx_duration Duration TYPE minmax GRANULARITY 1
-) ENGINE = %s                              -- 通常はMergeTree（高性能分析エンジン）
+) ENGINE = {{.Engine}}                              -- 通常はMergeTree（高性能分析エンジン）
 PARTITION BY toDate(Timestamp)             -- 日付単位の物理分割（効率的な範囲検索・TTL削除）
-ORDER BY (ServiceName, SpanName, toDateTime(Timestamp))  -- クラスタリング（サービス・操作別の高速検索）
-%s                                        -- TTL設定（自動データ削除）のプレースホルダー
-SETTINGS index_granularity=8192, ttl_only_drop_parts = 1  -- 性能・運用最適化設定
+ORDER BY {{.OrderBy}}  -- traces_order_by（既定: サービス・操作別の高速検索）
+{{.TTL}}                                        -- TTL設定（自動データ削除）のプレースホルダー
+SETTINGS index_granularity=8192, ttl_only_drop_parts = 1{{.Settings}}  -- 性能・運用最適化設定
@@ -0,0 +1,146 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// TableTemplateData - SQLテンプレートに渡す名前付きフィールド
+// テンプレート内では {{.Database}} のように参照する
+type TableTemplateData struct {
+	Database string // データベース名
+	Table    string // テーブル名
+	Cluster  string // クラスター句（ON CLUSTER ...、未設定の場合は空）
+	Engine   string // エンジン句
+	OrderBy  string // ORDER BY句の式（テンプレートが参照する場合のみ）
+	TTL      string // TTL句（未設定の場合は空）
+	Settings string // 追加のテーブル設定（", key = value" 形式、未設定の場合は空）
+
+	TimeColumn string // パーティションの基準とする時刻列（テーブルが参照する場合のみ）
+
+	AttributesType string   // 属性カラムの型定義（テンプレートが参照する場合のみ）
+	MapType        string   // 形式が固定の Map 型カラム（イベント・リンク・メトリクスの属性など）の型（テンプレートが参照する場合のみ）
+	JSONAttributes bool     // 属性カラムがJSON型の場合はtrue（Map専用のインデックスを省略する）
+	SourceColumns  bool     // 送信元メタデータカラム（Collector*）を含める場合はtrue
+	BodyJSON       bool     // ログ本文のJSONオブジェクトの列（BodyJSON）を含める場合はtrue
+	AnomalyScore   bool     // サービスの異常度の列（AnomalyScore）を含める場合はtrue
+	Truncated      bool     // 属性値・本文を短縮した行を示す列（Truncated）を含める場合はtrue
+	TraceScope     bool     // トレーステーブルにスキーマURL・スコープ属性の列（ResourceSchemaUrl など）を含める場合はtrue
+	StaleColumn    bool     // メトリクステーブルにステールマーカーを示す Stale 列を含める場合はtrue（metrics.staleness_handling: store）
+	IngestTime     bool     // 取り込み時刻の列（IngestTimestamp, ExportTimestamp）を含める場合はtrue
+	ExtraColumns   []string // 挿入SQLの末尾に追加する列（RowTransformer の Columns、挿入SQLのみ）
+	Columns        []string // 挿入する列（列構成が種類・設定で変わるメトリクスの挿入SQLのみ）
+
+	EmbeddingDimensions int    // ログ本文の埋め込みベクトル列（BodyEmbedding）の次元数（0の場合は列を含めない）
+	EmbeddingDistance   string // 埋め込みベクトル列のベクトル索引の距離関数（空の場合は索引を作成しない）
+
+	MetricsDimensions bool // メトリクスのリソース・スコープをディメンションテーブルに分離し、ハッシュのみを保存する場合はtrue
+	MetricsLite       bool // メトリクステーブルからエグゼンプラー・フラグ・スコープのメタデータ列を省略する場合はtrue（metrics_schema: lite）
+
+	LocalTable       string // 分散テーブルが参照するローカルテーブル名（テンプレートが参照する場合のみ）
+	AggregateColumns bool   // 検索テーブルの列を SimpleAggregateFunction 型で作成する場合はtrue
+}
+
+// requiredTemplateFields はすべてのテンプレートが参照し、かつ空であってはならないフィールドです
+var requiredTemplateFields = []string{"Database", "Table"}
+
+// RenderSQLTemplate は組み込みSQLテンプレートを読み込み、名前付きフィールドでレンダリングします
+func RenderSQLTemplate(filename string, data TableTemplateData) (string, error) {
+	text, err := LoadSQLTemplate(filename)
+	if err != nil {
+		return "", err
+	}
+	return ExecuteSQLTemplate(filename, text, data)
+}
+
+// templateFuncs はSQLテンプレートで使用できる関数を返します
+// 開発環境（単一ノード）と本番環境（クラスター）で同じテンプレートを使い回せるよう、環境に応じた分岐に使用します
+//
+//	env "NAME"             環境変数の値（未設定の場合は空文字）
+//	default "値" 値         値が空の場合に既定値を返す（{{env "CODEC" | default "ZSTD(1)"}} のようにパイプで使用）
+//	clustered              クラスター句（cluster_name）が設定されている場合はtrue（{{if clustered}}...{{end}}）
+//	onCluster "SQL"        クラスター句が設定されている場合のみ SQL を返す
+func templateFuncs(data TableTemplateData) template.FuncMap {
+	clustered := func() bool { return data.Cluster != "" }
+	return template.FuncMap{
+		"env": os.Getenv,
+		"default": func(fallback, value string) string {
+			if value == "" {
+				return fallback
+			}
+			return value
+		},
+		"clustered": clustered,
+		"onCluster": func(sql string) string {
+			if clustered() {
+				return sql
+			}
+			return ""
+		},
+	}
+}
+
+// ExecuteSQLTemplate はSQLテンプレート文字列をレンダリングし、全フィールドが置換されたことを検証します
+func ExecuteSQLTemplate(name, text string, data TableTemplateData) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs(data)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("SQLテンプレート %s の解析に失敗しました: %w", name, err)
+	}
+
+	// 必須フィールドの参照と、参照されているフィールドの値を検証
+	// Cluster、TTL、Settings は未設定（空文字）を許容する
+	fields := []struct {
+		name     string
+		value    string
+		optional bool
+	}{
+		{"Database", data.Database, false},
+		{"Table", data.Table, false},
+		{"Engine", data.Engine, false},
+		{"OrderBy", data.OrderBy, false},
+		{"AttributesType", data.AttributesType, false},
+		{"MapType", data.MapType, false},
+		{"LocalTable", data.LocalTable, false},
+		{"TimeColumn", data.TimeColumn, false},
+		{"Cluster", data.Cluster, true},
+		{"TTL", data.TTL, true},
+		{"Settings", data.Settings, true},
+	}
+	tree := tmpl.Tree.Root.String()
+	var errs error
+	for _, field := range requiredTemplateFields {
+		if !strings.Contains(tree, "."+field) {
+			errs = errors.Join(errs, fmt.Errorf("SQLテンプレート %s が必須フィールド %s を参照していません", name, field))
+		}
+	}
+	for _, field := range fields {
+		if field.value == "" && !field.optional && strings.Contains(tree, "."+field.name) {
+			errs = errors.Join(errs, fmt.Errorf("SQLテンプレート %s のフィールド %s が空です", name, field.name))
+		}
+	}
+	if errs != nil {
+		return "", errs
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("SQLテンプレート %s のレンダリングに失敗しました: %w", name, err)
+	}
+
+	// 旧形式の位置指定プレースホルダーや未置換の値が残っていないことを確認
+	sql := buf.String()
+	for _, leftover := range []string{"%s", "{{", "<no value>"} {
+		if strings.Contains(sql, leftover) {
+			return "", fmt.Errorf("SQLテンプレート %s に未置換のプレースホルダー %q が残っています", name, leftover)
+		}
+	}
+
+	return sql, nil
+}
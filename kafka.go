@@ -0,0 +1,201 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// Kafkaメッセージのエンコーディング（kafkaexporter と同じ名前）
+const (
+	kafkaEncodingProto = "otlp_proto" // OTLP protobuf
+	kafkaEncodingJSON  = "otlp_json"  // OTLP JSON
+)
+
+// Kafka出力とデータベースの関係
+const (
+	kafkaModeCopy = "copy" // データベースへの保存と並行してKafkaへ発行する
+	kafkaModeOnly = "only" // データベースには保存せずKafkaへのみ発行する
+)
+
+// KafkaConfig - 受信したテレメトリをKafkaへ発行する設定
+// 取り込みとデータベースを切り離し、下流のコンシューマー（別のコレクターや kafkareceiver）で
+// バッファリング・再処理できるようにします
+type KafkaConfig struct {
+	// Brokers はKafkaブローカーのアドレス（host:port）です（未指定の場合はKafka出力を無効化）
+	Brokers []string `mapstructure:"brokers"`
+	// Encoding はメッセージのエンコーディング（otlp_proto または otlp_json）です
+	Encoding string `mapstructure:"encoding"`
+	// Mode は copy（データベースと並行して発行）または only（Kafkaにのみ発行）です
+	Mode string `mapstructure:"mode"`
+	// Signals は発行するシグナルです（未指定の場合は全シグナル）
+	Signals []string `mapstructure:"signals"`
+
+	// シグナルごとのトピック名
+	TracesTopic   string `mapstructure:"traces_topic"`
+	LogsTopic     string `mapstructure:"logs_topic"`
+	MetricsTopic  string `mapstructure:"metrics_topic"`
+	ProfilesTopic string `mapstructure:"profiles_topic"`
+}
+
+// validate はKafka出力の設定を検証します
+func (c KafkaConfig) validate() error {
+	if len(c.Brokers) == 0 {
+		return nil
+	}
+	var errs error
+	if c.Encoding != kafkaEncodingProto && c.Encoding != kafkaEncodingJSON {
+		errs = errors.Join(errs, fmt.Errorf("kafka.encoding は otlp_proto または otlp_json を指定してください: %s", c.Encoding))
+	}
+	if c.Mode != kafkaModeCopy && c.Mode != kafkaModeOnly {
+		errs = errors.Join(errs, fmt.Errorf("kafka.mode は copy または only を指定してください: %s", c.Mode))
+	}
+	for _, signal := range c.Signals {
+		if !slices.Contains(passthroughSignals, signal) {
+			errs = errors.Join(errs, fmt.Errorf("kafka.signals に不明なシグナルが指定されています: %s", signal))
+		}
+	}
+	for _, signal := range passthroughSignals {
+		if c.publishes(signal) && c.topic(signal) == "" {
+			errs = errors.Join(errs, fmt.Errorf("kafka.%s_topic を指定してください", signal))
+		}
+	}
+	return errs
+}
+
+// publishes はシグナルをKafkaへ発行するかどうかを返します
+func (c KafkaConfig) publishes(signal string) bool {
+	return len(c.Brokers) > 0 && (len(c.Signals) == 0 || slices.Contains(c.Signals, signal))
+}
+
+// replacesDatabase はシグナルをデータベースに保存せずKafkaにのみ発行するかどうかを返します
+func (c KafkaConfig) replacesDatabase(signal string) bool {
+	return c.publishes(signal) && c.Mode == kafkaModeOnly
+}
+
+// topic はシグナルの発行先トピックを返します
+func (c KafkaConfig) topic(signal string) string {
+	switch signal {
+	case "traces":
+		return c.TracesTopic
+	case "logs":
+		return c.LogsTopic
+	case "metrics":
+		return c.MetricsTopic
+	case "profiles":
+		return c.ProfilesTopic
+	default:
+		return ""
+	}
+}
+
+// kafkaPublisher はシグナルを1つのメッセージ（受信したバッチ単位）としてKafkaへ発行します
+type kafkaPublisher struct {
+	signal string
+	topic  string
+	json   bool
+	client *kgo.Client
+	logger *zap.Logger
+}
+
+// newKafkaPublisher はシグナルが発行対象の場合のみKafkaクライアントを作成します（対象外の場合は nil）
+// ブローカーへの接続は最初の発行時に確立されます
+func newKafkaPublisher(cfg KafkaConfig, signal string, logger *zap.Logger) (*kafkaPublisher, error) {
+	if !cfg.publishes(signal) {
+		return nil, nil
+	}
+
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.DefaultProduceTopic(cfg.topic(signal)),
+		// exporterhelper のバッチ単位で同期的に発行するため、送信を遅延させない
+		kgo.ProducerLinger(0),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Kafkaクライアントの作成に失敗しました: %w", err)
+	}
+
+	logger.Info("シグナルをKafkaへ発行します",
+		zap.String("signal", signal), zap.String("topic", cfg.topic(signal)),
+		zap.String("encoding", cfg.Encoding), zap.String("mode", cfg.Mode))
+	return &kafkaPublisher{
+		signal: signal,
+		topic:  cfg.topic(signal),
+		json:   cfg.Encoding == kafkaEncodingJSON,
+		client: client,
+		logger: logger,
+	}, nil
+}
+
+// publishTraces はトレースを発行します
+func (p *kafkaPublisher) publishTraces(ctx context.Context, td ptrace.Traces) error {
+	var marshaler ptrace.Marshaler = &ptrace.ProtoMarshaler{}
+	if p.json {
+		marshaler = &ptrace.JSONMarshaler{}
+	}
+	payload, err := marshaler.MarshalTraces(td)
+	return p.publish(ctx, payload, err, td.SpanCount())
+}
+
+// publishLogs はログを発行します
+func (p *kafkaPublisher) publishLogs(ctx context.Context, ld plog.Logs) error {
+	var marshaler plog.Marshaler = &plog.ProtoMarshaler{}
+	if p.json {
+		marshaler = &plog.JSONMarshaler{}
+	}
+	payload, err := marshaler.MarshalLogs(ld)
+	return p.publish(ctx, payload, err, ld.LogRecordCount())
+}
+
+// publishMetrics はメトリクスを発行します
+func (p *kafkaPublisher) publishMetrics(ctx context.Context, md pmetric.Metrics) error {
+	var marshaler pmetric.Marshaler = &pmetric.ProtoMarshaler{}
+	if p.json {
+		marshaler = &pmetric.JSONMarshaler{}
+	}
+	payload, err := marshaler.MarshalMetrics(md)
+	return p.publish(ctx, payload, err, md.DataPointCount())
+}
+
+// publishProfiles はプロファイルを発行します
+func (p *kafkaPublisher) publishProfiles(ctx context.Context, pd pprofile.Profiles) error {
+	var marshaler pprofile.Marshaler = &pprofile.ProtoMarshaler{}
+	if p.json {
+		marshaler = &pprofile.JSONMarshaler{}
+	}
+	payload, err := marshaler.MarshalProfiles(pd)
+	return p.publish(ctx, payload, err, pd.SampleCount())
+}
+
+// publish はエンコード済みのメッセージを同期的に発行します
+// 失敗した場合はエラーを返し、exporterhelper のリトライに委ねます
+func (p *kafkaPublisher) publish(ctx context.Context, payload []byte, marshalErr error, items int) error {
+	if marshalErr != nil {
+		return fmt.Errorf("%s のエンコードに失敗しました: %w", p.signal, marshalErr)
+	}
+	if err := p.client.ProduceSync(ctx, &kgo.Record{Topic: p.topic, Value: payload}).FirstErr(); err != nil {
+		return fmt.Errorf("%s のKafkaへの発行に失敗しました (topic: %s): %w", p.signal, p.topic, err)
+	}
+	p.logger.Debug("Kafkaへ発行しました",
+		zap.String("signal", p.signal), zap.String("topic", p.topic), zap.Int("items", items), zap.Int("bytes", len(payload)))
+	return nil
+}
+
+// shutdown はKafkaクライアントを閉じます（未送信のメッセージはありません）
+func (p *kafkaPublisher) shutdown() {
+	if p == nil {
+		return
+	}
+	p.client.Close()
+}
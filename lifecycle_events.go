@@ -0,0 +1,253 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// エクスポーターのライフサイクルイベント名（ログレコードの EventName）
+const (
+	eventSchemaCreated      = stable.EventSchemaCreated
+	eventConnectionLost     = stable.EventConnectionLost
+	eventConnectionRestored = stable.EventConnectionRestored
+	eventBatchDropped       = stable.EventBatchDropped
+	eventAnomalyDetected    = stable.EventAnomalyDetected
+)
+
+// lifecycleEventsQueueSize は送信待ちのイベントの上限です（超えた場合は破棄）
+const lifecycleEventsQueueSize = 256
+
+// LifecycleEventsConfig - エクスポーター自身の重要なイベントをOTelログレコードとして送信する設定
+// コレクター自身の otlp レシーバーを Endpoint に指定すると、そのレシーバーを含むパイプラインに
+// イベントが流れ、フリート全体のエクスポーターの状態をテレメトリと同じバックエンドで検索できます
+//
+//	lifecycle_events:
+//	  endpoint: localhost:4317
+//	  insecure: true
+type LifecycleEventsConfig struct {
+	// Endpoint はイベントを送信するOTLP gRPCエンドポイント（host:port）です（未指定の場合は無効）
+	Endpoint string `mapstructure:"endpoint"`
+	// Insecure はTLSを使用せずに接続します
+	Insecure bool `mapstructure:"insecure"`
+	// Headers は送信時に付与するgRPCメタデータです
+	Headers map[string]configopaque.String `mapstructure:"headers"`
+	// DropSummaryInterval はデータ破棄イベントをまとめて送信する間隔です
+	// 送信先のパイプラインが障害中のこのエクスポーターを含む場合に、破棄イベント自体の破棄でイベントが増え続けないよう集約します
+	DropSummaryInterval time.Duration `mapstructure:"drop_summary_interval"`
+}
+
+// validate はライフサイクルイベントの設定を検証します
+func (c LifecycleEventsConfig) validate() error {
+	if c.Endpoint != "" && c.DropSummaryInterval <= 0 {
+		return fmt.Errorf("lifecycle_events.drop_summary_interval は0より大きい必要があります: %s", c.DropSummaryInterval)
+	}
+	return nil
+}
+
+// lifecycleEvents はイベントをログレコードとしてバックグラウンドで送信します
+// 送信は挿入処理を待たせないよう非同期で行い、送信できなかったイベントはログに記録して破棄します
+type lifecycleEvents struct {
+	signal   string
+	conn     *grpc.ClientConn
+	headers  metadata.MD
+	resource pcommon.Resource // コレクター自身のリソース（service.name, service.instance.id など）
+	scope    string
+	logger   *zap.Logger
+
+	queue chan plog.Logs
+	stop  chan struct{}
+	done  chan struct{}
+
+	mu      sync.Mutex
+	dropped map[string]int // 理由ごとの未送信の破棄件数
+}
+
+// newLifecycleEvents はイベント送信を開始します（endpoint 未指定の場合は nil）
+func newLifecycleEvents(cfg LifecycleEventsConfig, set exporter.Settings, signal string, logger *zap.Logger) (*lifecycleEvents, error) {
+	if cfg.Endpoint == "" {
+		return nil, nil
+	}
+
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("イベント送信先 %s への接続の作成に失敗しました: %w", cfg.Endpoint, err)
+	}
+	headers := metadata.MD{}
+	for key, value := range cfg.Headers {
+		headers.Set(key, string(value))
+	}
+
+	e := &lifecycleEvents{
+		signal:   signal,
+		conn:     conn,
+		headers:  headers,
+		resource: pcommon.NewResource(),
+		scope:    set.ID.String(),
+		logger:   logger,
+		queue:    make(chan plog.Logs, lifecycleEventsQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		dropped:  map[string]int{},
+	}
+	set.Resource.CopyTo(e.resource)
+	go e.run(cfg.DropSummaryInterval)
+	return e, nil
+}
+
+// emit はイベントを送信キューに追加します（キューが一杯の場合は破棄）
+func (e *lifecycleEvents) emit(name string, severity plog.SeverityNumber, body string, attrs map[string]any) {
+	if e == nil {
+		return
+	}
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	e.resource.CopyTo(rl.Resource())
+	sl := rl.ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(e.scope)
+	lr := sl.LogRecords().AppendEmpty()
+	now := pcommon.NewTimestampFromTime(time.Now())
+	lr.SetTimestamp(now)
+	lr.SetObservedTimestamp(now)
+	lr.SetEventName(name)
+	lr.SetSeverityNumber(severity)
+	lr.SetSeverityText(severity.String())
+	lr.Body().SetStr(body)
+	if err := lr.Attributes().FromRaw(attrs); err != nil {
+		e.logger.Debug("イベント属性の設定に失敗しました", zap.String("event", name), zap.Error(err))
+	}
+	lr.Attributes().PutStr("signal", e.signal)
+
+	select {
+	case e.queue <- ld:
+	default:
+		e.logger.Warn("イベントの送信キューが一杯のため破棄しました", zap.String("event", name))
+	}
+}
+
+// schemaCreated はテーブル作成（スキーマの適用）が完了したことを送信します
+func (e *lifecycleEvents) schemaCreated(database string) {
+	e.emit(eventSchemaCreated, plog.SeverityNumberInfo, "スキーマを作成しました", map[string]any{"db.namespace": database})
+}
+
+// connectionLost はデータベースに到達できなくなったことを送信します
+func (e *lifecycleEvents) connectionLost(failures int, cause error) {
+	e.emit(eventConnectionLost, plog.SeverityNumberError, "データベースに到達できなくなりました", map[string]any{
+		"consecutive_failures": failures,
+		"error.message":        cause.Error(),
+	})
+}
+
+// connectionRestored はデータベースへの接続が復旧したことを送信します
+func (e *lifecycleEvents) connectionRestored() {
+	e.emit(eventConnectionRestored, plog.SeverityNumberInfo, "データベースへの接続が復旧しました", nil)
+}
+
+// anomalyDetected はサービスのエラー率またはレイテンシが基準値から大きく逸脱したことを送信します
+func (e *lifecycleEvents) anomalyDetected(service string, score, errorRate, baselineErrorRate, latencyMillis, baselineLatencyMillis float64) {
+	e.emit(eventAnomalyDetected, plog.SeverityNumberWarn, "サービスのエラー率またはレイテンシが基準値から大きく逸脱しています", map[string]any{
+		"service.name":        service,
+		"anomaly_score":       score,
+		"error_rate":          errorRate,
+		"baseline_error_rate": baselineErrorRate,
+		"latency_ms":          latencyMillis,
+		"baseline_latency_ms": baselineLatencyMillis,
+	})
+}
+
+// batchDropped は保存できずに破棄したデータ件数を集計します
+// 破棄のたびには送信せず、drop_summary_interval ごとに理由別の合計を1件のイベントとして送信します
+func (e *lifecycleEvents) batchDropped(reason string, items int) {
+	if e == nil || items <= 0 {
+		return
+	}
+	e.mu.Lock()
+	e.dropped[reason] += items
+	e.mu.Unlock()
+}
+
+// flushDropped は集計した破棄件数をイベントとして送信キューに追加します
+func (e *lifecycleEvents) flushDropped() {
+	e.mu.Lock()
+	dropped := e.dropped
+	e.dropped = map[string]int{}
+	e.mu.Unlock()
+
+	for reason, items := range dropped {
+		e.emit(eventBatchDropped, plog.SeverityNumberWarn, "データを保存できずに破棄しました", map[string]any{
+			"reason":        reason,
+			"dropped_items": items,
+		})
+	}
+}
+
+// run はキューのイベントを送信し、定期的に破棄件数の集計を送信します
+func (e *lifecycleEvents) run(dropInterval time.Duration) {
+	defer close(e.done)
+	ticker := time.NewTicker(dropInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ld := <-e.queue:
+			e.send(ld)
+		case <-ticker.C:
+			e.flushDropped()
+		case <-e.stop:
+			// 終了時は集計済みの破棄件数を含め、キューに残ったイベントを送信する
+			e.flushDropped()
+			for {
+				select {
+				case ld := <-e.queue:
+					e.send(ld)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send はイベントをOTLPで送信します
+func (e *lifecycleEvents) send(ld plog.Logs) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if len(e.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, e.headers)
+	}
+	if _, err := plogotlp.NewGRPCClient(e.conn).Export(ctx, plogotlp.NewExportRequestFromLogs(ld)); err != nil {
+		e.logger.Warn("ライフサイクルイベントの送信に失敗しました", zap.Error(err))
+	}
+}
+
+// shutdown は残りのイベントを送信してから接続を閉じます
+func (e *lifecycleEvents) shutdown() error {
+	if e == nil {
+		return nil
+	}
+	close(e.stop)
+	<-e.done
+	return e.conn.Close()
+}
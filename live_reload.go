@@ -0,0 +1,188 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v3"
+)
+
+// LiveReloadConfig - 再起動せずに変更できる設定の上書きファイル
+// file に prefix, detailed, detailed_sampling, summary_interval を書いたYAMLを置くと、interval ごとに読み込んで反映します
+// ファイルにないキーはコレクター設定の値を使用し、ファイルを削除するとコレクター設定の値に戻ります
+// 不正な内容の場合は警告を記録して、直前の値を使い続けます
+//
+//	live_reload:
+//	  file: /etc/otelcol/mylogexporter-overrides.yaml
+type LiveReloadConfig struct {
+	File     string        `mapstructure:"file"`     // 上書き設定のYAMLファイル
+	Interval time.Duration `mapstructure:"interval"` // ファイルの変更を確認する間隔（既定: 10秒）
+}
+
+// validate は上書きファイルの設定を検証します
+func (c LiveReloadConfig) validate() error {
+	if c.File != "" && c.Interval <= 0 {
+		return fmt.Errorf("live_reload.interval は0より大きい必要があります: %s", c.Interval)
+	}
+	return nil
+}
+
+// liveSettings は実行中に変更できる設定の値です（キーはコレクター設定と同じ）
+type liveSettings struct {
+	Prefix           string                 `mapstructure:"prefix"`
+	Detailed         bool                   `mapstructure:"detailed"`
+	DetailedSampling DetailedSamplingConfig `mapstructure:"detailed_sampling"`
+	SummaryInterval  time.Duration          `mapstructure:"summary_interval"`
+}
+
+// validate は上書き後の値を検証します
+func (s liveSettings) validate() error {
+	var errs error
+	if err := s.DetailedSampling.validate(); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if s.SummaryInterval < 0 {
+		errs = errors.Join(errs, fmt.Errorf("summary_interval は0以上である必要があります: %s", s.SummaryInterval))
+	}
+	return errs
+}
+
+// liveConfig は実行中に変更できる設定の現在の値を保持し、上書きファイルの変更を反映します
+// 送信処理は load で取得した値を使用するため、値の切り替えは送信の途中には影響しません
+type liveConfig struct {
+	base   liveSettings // コレクター設定の値（上書きファイルにないキーに使用）
+	file   string
+	logger *zap.Logger
+
+	current   atomic.Pointer[liveSettings]
+	raw       []byte // 直前に読み込んだファイルの内容（変更がない場合は解析しない、ファイルがない場合は空）
+	loaded    bool
+	mu        sync.Mutex
+	listeners []func(liveSettings)
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newLiveConfig はコレクター設定の値で初期化し、live_reload.file 指定時は上書きファイルの確認を開始します
+func newLiveConfig(cfg *Config, logger *zap.Logger) *liveConfig {
+	l := &liveConfig{
+		base: liveSettings{
+			Prefix:           cfg.Prefix,
+			Detailed:         cfg.Detailed,
+			DetailedSampling: cfg.DetailedSampling,
+			SummaryInterval:  cfg.SummaryInterval,
+		},
+		file:   cfg.LiveReload.File,
+		logger: logger,
+	}
+	l.current.Store(&l.base)
+	if l.file == "" {
+		return l
+	}
+	l.reload()
+	l.stop, l.done = make(chan struct{}), make(chan struct{})
+	go l.run(cfg.LiveReload.Interval)
+	return l
+}
+
+// load は現在の値を返します
+func (l *liveConfig) load() *liveSettings {
+	return l.current.Load()
+}
+
+// reloadable は上書きファイルにより値が変わりうるかどうかを返します
+func (l *liveConfig) reloadable() bool {
+	return l.file != ""
+}
+
+// subscribe は値が変わった場合に呼び出す関数を登録します
+func (l *liveConfig) subscribe(f func(liveSettings)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, f)
+}
+
+// run は interval ごとに上書きファイルを確認します
+func (l *liveConfig) run(interval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.reload()
+		}
+	}
+}
+
+// reload は上書きファイルを読み込み、内容が変わっていれば値を切り替えます
+func (l *liveConfig) reload() {
+	raw, err := os.ReadFile(l.file)
+	if errors.Is(err, os.ErrNotExist) {
+		raw, err = nil, nil
+	}
+	if err != nil {
+		l.logger.Warn("上書き設定ファイルを読み込めません、現在の設定を使い続けます", zap.String("file", l.file), zap.Error(err))
+		return
+	}
+	if l.loaded && bytes.Equal(raw, l.raw) {
+		return
+	}
+	// 不正な内容も記録し、修正されるまで同じ警告を繰り返さない
+	l.raw, l.loaded = raw, true
+	next, err := l.parse(raw)
+	if err != nil {
+		l.logger.Warn("上書き設定ファイルが不正です、現在の設定を使い続けます", zap.String("file", l.file), zap.Error(err))
+		return
+	}
+	prev := l.current.Swap(&next)
+	if *prev == next {
+		return
+	}
+	l.logger.Info("上書き設定ファイルの内容を反映しました",
+		zap.String("file", l.file),
+		zap.String("prefix", next.Prefix),
+		zap.Bool("detailed", next.Detailed),
+		zap.Duration("summary_interval", next.SummaryInterval))
+	l.mu.Lock()
+	listeners := l.listeners
+	l.mu.Unlock()
+	for _, f := range listeners {
+		f(next)
+	}
+}
+
+// parse は上書きファイルの内容をコレクター設定の値に重ねます（未知のキーはエラー）
+func (l *liveConfig) parse(raw []byte) (liveSettings, error) {
+	next := l.base
+	var overrides map[string]any
+	if err := yaml.Unmarshal(raw, &overrides); err != nil {
+		return next, err
+	}
+	if err := confmap.NewFromStringMap(overrides).Unmarshal(&next); err != nil {
+		return next, err
+	}
+	return next, next.validate()
+}
+
+// shutdown は上書きファイルの確認を停止します
+func (l *liveConfig) shutdown() {
+	if l.stop == nil {
+		return
+	}
+	close(l.stop)
+	<-l.done
+}
@@ -0,0 +1,47 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// logBodyJSONColumn はJSONオブジェクトの本文を保存する列の名前です（挿入SQLの末尾に追加される）
+const logBodyJSONColumn = stable.ColumnBodyJSON
+
+// emptyBodyJSON はJSONオブジェクトでない本文の BodyJSON 列の値です
+const emptyBodyJSON = "{}"
+
+// insertColumns は parse_body_json が有効な場合に BodyJSON 列を追加した挿入列を返します
+func (c LogsConfig) insertColumns(columns []string) []string {
+	if !c.ParseBodyJSON {
+		return columns
+	}
+	return slices.Concat(columns, []string{logBodyJSONColumn})
+}
+
+// stampBodyJSON は各行の末尾に本文のJSONオブジェクトを追加します（parse_body_json 無効の場合は何もしない）
+// 本文が Map の場合は AsString でJSONに変換済みのため、文字列の本文と同様に判定します
+func (c LogsConfig) stampBodyJSON(rows [][]any) {
+	if !c.ParseBodyJSON {
+		return
+	}
+	for i, row := range rows {
+		body, _ := row[logBodyIndex].(string)
+		rows[i] = append(row, bodyJSONObject(body))
+	}
+}
+
+// bodyJSONObject は本文がJSONオブジェクトの場合はその文字列を、それ以外の場合は空のオブジェクトを返します
+func bodyJSONObject(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if !strings.HasPrefix(trimmed, "{") || !json.Valid([]byte(trimmed)) {
+		return emptyBodyJSON
+	}
+	return trimmed
+}
@@ -0,0 +1,252 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configopaque"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// LogBodyEmbedder はログ本文の埋め込みベクトルを計算します
+// log_embeddings.extension に指定する拡張はこのインターフェースを実装する必要があります（コレクターに組み込んだモデルの利用向け）
+// 戻り値は bodies と同じ順・同じ数のベクトルで、各ベクトルの次元数は log_embeddings.dimensions と一致する必要があります
+type LogBodyEmbedder interface {
+	EmbedLogBodies(ctx context.Context, bodies []string) ([][]float32, error)
+}
+
+// logEmbeddingColumn はログ本文の埋め込みベクトルを保存する列の名前です（挿入SQLの末尾に追加される）
+const logEmbeddingColumn = stable.ColumnBodyEmbedding
+
+// ベクトル索引の距離関数
+var validEmbeddingDistances = []string{"cosineDistance", "L2Distance"}
+
+// LogEmbeddingsConfig - ログ本文の埋め込みベクトルの設定（意味検索の実験向け）
+// 埋め込みは OpenAI 互換の埋め込みAPI（endpoint）または LogBodyEmbedder を実装した拡張（extension）で計算し、
+// ログテーブルの BodyEmbedding 列（Array(Float32)）にベクトル索引付きで保存します
+// 既存のテーブルには列が追加されないため、有効化する場合は ALTER TABLE で列を追加してください
+// 埋め込みを計算できなかった行はゼロベクトルで保存し、ログの挿入は継続します
+type LogEmbeddingsConfig struct {
+	// Endpoint は OpenAI 互換の埋め込みAPIのURLです（例: http://localhost:11434/v1/embeddings）
+	Endpoint string `mapstructure:"endpoint"`
+	// Model は埋め込みAPIに指定するモデル名です
+	Model string `mapstructure:"model"`
+	// APIKey は埋め込みAPIの認証に使用するキーです（Authorization: Bearer で送信）
+	APIKey configopaque.String `mapstructure:"api_key"`
+	// Extension は LogBodyEmbedder を実装した拡張のIDです（endpoint とは同時に指定できない）
+	Extension *component.ID `mapstructure:"extension"`
+	// Dimensions は埋め込みベクトルの次元数です（モデルの出力と一致させる）
+	Dimensions int `mapstructure:"dimensions"`
+	// BatchSize は1回の計算で渡す本文の最大数です
+	BatchSize int `mapstructure:"batch_size"`
+	// Timeout は1回の計算のタイムアウトです
+	Timeout time.Duration `mapstructure:"timeout"`
+	// VectorIndex はベクトル索引（vector_similarity）を作成するかどうかです（ClickHouse 25.1 以降）
+	VectorIndex bool `mapstructure:"vector_index"`
+	// Distance はベクトル索引の距離関数です（cosineDistance または L2Distance）
+	Distance string `mapstructure:"distance"`
+}
+
+// enabled は埋め込みの計算が有効かどうかを返します
+func (c LogEmbeddingsConfig) enabled() bool {
+	return c.Endpoint != "" || c.Extension != nil
+}
+
+// validate は埋め込みの設定を検証します
+func (c LogEmbeddingsConfig) validate(cfg *Config) error {
+	if !c.enabled() {
+		return nil
+	}
+	var errs error
+	if c.Endpoint != "" && c.Extension != nil {
+		errs = errors.Join(errs, errors.New("log_embeddings.endpoint と log_embeddings.extension は同時に指定できません"))
+	}
+	if c.Dimensions <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("log_embeddings.dimensions は1以上である必要があります: %d", c.Dimensions))
+	}
+	if c.BatchSize <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("log_embeddings.batch_size は1以上である必要があります: %d", c.BatchSize))
+	}
+	if c.Timeout <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("log_embeddings.timeout は0より大きい必要があります: %s", c.Timeout))
+	}
+	if c.VectorIndex && !slices.Contains(validEmbeddingDistances, c.Distance) {
+		errs = errors.Join(errs, fmt.Errorf("log_embeddings.distance は cosineDistance または L2Distance を指定してください: %s", c.Distance))
+	}
+	if cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では log_embeddings を使用できません"))
+	}
+	return errs
+}
+
+// insertColumns は埋め込みが有効な場合に BodyEmbedding 列を追加した挿入列を返します
+func (c LogEmbeddingsConfig) insertColumns(columns []string) []string {
+	if !c.enabled() {
+		return columns
+	}
+	return slices.Concat(columns, []string{logEmbeddingColumn})
+}
+
+// dimensions はテーブルに作成する埋め込みベクトル列の次元数を返します（無効の場合は0）
+func (c LogEmbeddingsConfig) dimensions() int {
+	if !c.enabled() {
+		return 0
+	}
+	return c.Dimensions
+}
+
+// indexDistance はベクトル索引の距離関数を返します（索引を作成しない場合は空）
+func (c LogEmbeddingsConfig) indexDistance() string {
+	if !c.enabled() || !c.VectorIndex {
+		return ""
+	}
+	return c.Distance
+}
+
+// logBodyIndex は行における Body 列の位置です
+var logBodyIndex = slices.Index(logInsertColumns, "Body")
+
+// bodyEmbedder はログの各行に本文の埋め込みベクトルを追加します
+type bodyEmbedder struct {
+	config   LogEmbeddingsConfig
+	embedder LogBodyEmbedder
+}
+
+// newBodyEmbedder は埋め込みの計算を作成します（log_embeddings 未指定の場合は nil）
+func newBodyEmbedder(cfg LogEmbeddingsConfig, host component.Host) (*bodyEmbedder, error) {
+	if !cfg.enabled() {
+		return nil, nil
+	}
+	if cfg.Endpoint != "" {
+		return &bodyEmbedder{config: cfg, embedder: &httpEmbedder{config: cfg, client: &http.Client{}}}, nil
+	}
+	ext, ok := host.GetExtensions()[*cfg.Extension]
+	if !ok {
+		return nil, fmt.Errorf("log_embeddings.extension に指定された拡張 %s が見つかりません", cfg.Extension)
+	}
+	embedder, ok := ext.(LogBodyEmbedder)
+	if !ok {
+		return nil, fmt.Errorf("log_embeddings.extension に指定された拡張 %s は LogBodyEmbedder を実装していません", cfg.Extension)
+	}
+	return &bodyEmbedder{config: cfg, embedder: embedder}, nil
+}
+
+// embed は各行の末尾に本文の埋め込みベクトルを追加します
+// バッチ内で同じ本文は1回だけ計算し、空の本文と計算に失敗した本文はゼロベクトルとします（失敗した場合はエラーを返す）
+func (b *bodyEmbedder) embed(ctx context.Context, rows [][]any) error {
+	if b == nil {
+		return nil
+	}
+	vectors := map[string][]float32{}
+	var bodies []string
+	for _, row := range rows {
+		body, _ := row[logBodyIndex].(string)
+		if _, ok := vectors[body]; !ok && body != "" {
+			vectors[body] = nil
+			bodies = append(bodies, body)
+		}
+	}
+
+	var errs error
+	for chunk := range slices.Chunk(bodies, b.config.BatchSize) {
+		embeddings, err := b.embedChunk(ctx, chunk)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		for i, body := range chunk {
+			vectors[body] = embeddings[i]
+		}
+	}
+
+	zero := make([]float32, b.config.Dimensions)
+	for i, row := range rows {
+		body, _ := row[logBodyIndex].(string)
+		vector := vectors[body]
+		if vector == nil {
+			vector = zero
+		}
+		rows[i] = append(row, vector)
+	}
+	return errs
+}
+
+// embedChunk は本文の埋め込みを計算し、数と次元数を検証します
+func (b *bodyEmbedder) embedChunk(ctx context.Context, bodies []string) ([][]float32, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	defer cancel()
+	embeddings, err := b.embedder.EmbedLogBodies(ctx, bodies)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(bodies) {
+		return nil, fmt.Errorf("埋め込みの数が本文の数と一致しません: %d != %d", len(embeddings), len(bodies))
+	}
+	for _, embedding := range embeddings {
+		if len(embedding) != b.config.Dimensions {
+			return nil, fmt.Errorf("埋め込みの次元数が log_embeddings.dimensions と一致しません: %d != %d", len(embedding), b.config.Dimensions)
+		}
+	}
+	return embeddings, nil
+}
+
+// httpEmbedder は OpenAI 互換の埋め込みAPI（POST {"model", "input"}）で埋め込みを計算します
+type httpEmbedder struct {
+	config LogEmbeddingsConfig
+	client *http.Client
+}
+
+// EmbedLogBodies は本文をまとめて埋め込みAPIに送信し、応答の index の順にベクトルを返します
+func (h *httpEmbedder) EmbedLogBodies(ctx context.Context, bodies []string) ([][]float32, error) {
+	payload, err := json.Marshal(map[string]any{"model": h.config.Model, "input": bodies})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+string(h.config.APIKey))
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("埋め込みAPIの呼び出しに失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("埋め込みAPIがエラーを返しました（%s）: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("埋め込みAPIの応答を解析できません: %w", err)
+	}
+	embeddings := make([][]float32, len(bodies))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(embeddings) {
+			return nil, fmt.Errorf("埋め込みAPIの応答の index が範囲外です: %d", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,153 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/internal/sqltemplates"
+	"github.com/dtamura/myexporter/pdatarows"
+)
+
+const (
+	// dimensionsRefreshInterval は書き込み済みのディメンションを再度書き込むまでの間隔です
+	// LastSeen を更新し、TTLで使用中のディメンションが削除されないようにします
+	dimensionsRefreshInterval = time.Hour
+	// maxSeenDimensions は書き込み済みとして記憶するディメンションの数の上限です（超えた場合は記憶を破棄）
+	maxSeenDimensions = 100000
+)
+
+// MetricsDimensionsConfig - メトリクスのリソース・スコープの正規化設定
+// 有効にすると、リソースとスコープの組をディメンションテーブルに1組1行で保存し、
+// データポイントのテーブルにはその組のハッシュ（DimensionsHash）のみを保存します
+// リソース属性はデータポイントごとに同じ値が繰り返されるため、属性が多い環境ほど保存容量を削減できます
+type MetricsDimensionsConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`    // 正規化したスキーマでメトリクステーブルを作成する
+	TableName string `mapstructure:"table_name"` // ディメンションテーブル名（既定: otel_metrics_dimensions）
+}
+
+// validate はメトリクスのディメンション設定を検証します
+func (c MetricsDimensionsConfig) validate() error {
+	if c.Enabled && c.TableName == "" {
+		return errors.New("metrics_dimensions.table_name を指定してください")
+	}
+	return nil
+}
+
+// metricsDimensionsTemplate はディメンションテーブル作成SQLのテンプレートファイル名です
+const metricsDimensionsTemplate = "metrics_dimensions_table.sql"
+
+// renderMetricsDimensionsTableSQL はディメンションテーブル作成SQLをレンダリングします
+func (e *metricsExporter) renderMetricsDimensionsTableSQL() (string, error) {
+	// 書き込みが途絶えたディメンションのみ削除する（使用中のものは LastSeen が定期的に更新される）
+	ttl := internal.GenerateTTLExpr(e.config.signalTTL("metrics"), "LastSeen")
+	return internal.RenderSQLTemplate(metricsDimensionsTemplate, internal.TableTemplateData{
+		Database: e.config.metricsDatabase(),
+		Table:    e.config.localTable(e.config.MetricsDimensions.TableName),
+		Cluster:  e.buildClusterClause(),
+		Engine:   e.config.replicatedEngine("ReplacingMergeTree(LastSeen)"),
+		TTL:      ttl,
+		Settings: e.config.tableSettings(),
+		MapType:  e.config.mapColumnType(),
+
+		MetricsLite: e.config.liteMetricsSchema(),
+	})
+}
+
+// createMetricsDimensionsTable はディメンションテーブル（分散テーブル構成では分散テーブルも）を作成します
+func (e *metricsExporter) createMetricsDimensionsTable(ctx context.Context) error {
+	sql, err := e.renderMetricsDimensionsTableSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, metricsDimensionsTemplate)
+		return fmt.Errorf("%s SQLのレンダリングに失敗しました: %w", metricsDimensionsTemplate, err)
+	}
+	if err := e.executeSQL(ctx, sql); err != nil {
+		return fmt.Errorf("ディメンションテーブルの作成に失敗しました: %w", err)
+	}
+	table := e.config.MetricsDimensions.TableName
+	if err := createDistributedTable(ctx, e.config, e.db, e.config.metricsDatabase(),
+		table, e.config.localTable(table), e.logger); err != nil {
+		return err
+	}
+	e.logger.Info("メトリクスのディメンションテーブルが正常に作成されました",
+		zap.String("table", table),
+		zap.String("database", e.config.metricsDatabase()))
+	return nil
+}
+
+// metricsDimensionsWriter はディメンションテーブルに未登録のリソース・スコープの組を書き込みます
+// 書き込み済みの組は記憶して再送しません（dimensionsRefreshInterval ごとに LastSeen を更新するため再度書き込む）
+type metricsDimensionsWriter struct {
+	config *Config
+	db     *sql.DB
+
+	mu   sync.Mutex
+	seen map[uint64]time.Time // 書き込み済みの組と書き込んだ日時
+}
+
+// newMetricsDimensionsWriter はディメンションの書き込みを作成します（metrics_dimensions 無効またはDB未接続の場合は nil）
+func newMetricsDimensionsWriter(cfg *Config, db *sql.DB) *metricsDimensionsWriter {
+	if !cfg.MetricsDimensions.Enabled || db == nil || cfg.isPostgres() {
+		return nil
+	}
+	return &metricsDimensionsWriter{config: cfg, db: db, seen: map[uint64]time.Time{}}
+}
+
+// write はバッチに含まれるリソース・スコープの組のうち、未登録のものを1トランザクションで書き込みます
+func (w *metricsDimensionsWriter) write(ctx context.Context, md pmetric.Metrics) error {
+	if w == nil {
+		return nil
+	}
+	now := time.Now()
+	var rows [][]any
+	var hashes []uint64
+	w.mu.Lock()
+	opts := w.config.rowOptions(false)
+	opts.OmitMetricsScope = w.config.liteMetricsSchema()
+	for _, row := range pdatarows.NewConverter(opts).MetricsDimensions(md, now) {
+		hash := row[0].(uint64)
+		if written, ok := w.seen[hash]; ok && now.Sub(written) < dimensionsRefreshInterval {
+			continue
+		}
+		hashes = append(hashes, hash)
+		rows = append(rows, row)
+	}
+	w.mu.Unlock()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	insert, err := renderInsertStatement("metrics_dimensions_insert.sql", sqltemplates.MetricsDimensionsInsert, "", nil, internal.TableTemplateData{
+		Database: w.config.metricsDatabase(),
+		Table:    w.config.MetricsDimensions.TableName,
+
+		MetricsLite: w.config.liteMetricsSchema(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := insertRowsWithTimeout(ctx, w.config, w.db, nil, insert, rows); err != nil {
+		return fmt.Errorf("ディメンションの書き込みに失敗しました: %w", err)
+	}
+
+	// 書き込みに成功した組のみ記憶する（失敗した場合は次のバッチで再度書き込む）
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.seen)+len(hashes) > maxSeenDimensions {
+		w.seen = map[uint64]time.Time{}
+	}
+	for _, hash := range hashes {
+		w.seen[hash] = now
+	}
+	return nil
+}
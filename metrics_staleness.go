@@ -0,0 +1,85 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// ステールマーカーのデータポイントの扱い（metrics.staleness_handling）
+const (
+	stalenessStore = "store" // 保存し、Stale 列で区別する
+	stalenessDrop  = "drop"  // 取り除く（既定）
+)
+
+// validate はメトリクスの設定を検証します
+func (c MetricsConfig) validate() error {
+	switch c.StalenessHandling {
+	case "", stalenessStore, stalenessDrop:
+		return nil
+	}
+	return fmt.Errorf("metrics.staleness_handling は store または drop を指定してください: %s", c.StalenessHandling)
+}
+
+// removeStaleDataPoints はメトリクスからステールマーカーのデータポイントを取り除き、取り除いた数を返します
+func removeStaleDataPoints(m pmetric.Metric) int {
+	removed := 0
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		m.Gauge().DataPoints().RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return countIf(&removed, dp.Flags().NoRecordedValue())
+		})
+	case pmetric.MetricTypeSum:
+		m.Sum().DataPoints().RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			return countIf(&removed, dp.Flags().NoRecordedValue())
+		})
+	case pmetric.MetricTypeHistogram:
+		m.Histogram().DataPoints().RemoveIf(func(dp pmetric.HistogramDataPoint) bool {
+			return countIf(&removed, dp.Flags().NoRecordedValue())
+		})
+	case pmetric.MetricTypeExponentialHistogram:
+		m.ExponentialHistogram().DataPoints().RemoveIf(func(dp pmetric.ExponentialHistogramDataPoint) bool {
+			return countIf(&removed, dp.Flags().NoRecordedValue())
+		})
+	case pmetric.MetricTypeSummary:
+		m.Summary().DataPoints().RemoveIf(func(dp pmetric.SummaryDataPoint) bool {
+			return countIf(&removed, dp.Flags().NoRecordedValue())
+		})
+	}
+	return removed
+}
+
+// countIf は cond が true の場合に n を1増やし、cond をそのまま返します
+func countIf(n *int, cond bool) bool {
+	if cond {
+		*n++
+	}
+	return cond
+}
+
+// applyStaleness は metrics.staleness_handling が drop の場合にステールマーカーのデータポイントを取り除きます
+func (e *metricsExporter) applyStaleness(md pmetric.Metrics) {
+	if e.config.Metrics.StalenessHandling != stalenessDrop {
+		return
+	}
+	dropped := 0
+	for _, rm := range md.ResourceMetrics().All() {
+		for _, sm := range rm.ScopeMetrics().All() {
+			for _, m := range sm.Metrics().All() {
+				dropped += removeStaleDataPoints(m)
+			}
+		}
+	}
+	if dropped == 0 {
+		return
+	}
+	e.diag.recordDropped("metrics", stable.DropReasonStaleMarker, dropped)
+	e.events.batchDropped(stable.DropReasonStaleMarker, dropped)
+	e.logger.Debug("ステールマーカーのデータポイントを取り除きました", zap.Int("dropped_data_points", dropped))
+}
@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// metricTemporality はSum・Histogram・ExponentialHistogramの集約方法（AggregationTemporality 列の値）を返します
+// 集約方法を持たないメトリクス（Gauge・Summary）の場合は false を返します
+func metricTemporality(m pmetric.Metric) (pmetric.AggregationTemporality, bool) {
+	switch m.Type() {
+	case pmetric.MetricTypeSum:
+		return m.Sum().AggregationTemporality(), true
+	case pmetric.MetricTypeHistogram:
+		return m.Histogram().AggregationTemporality(), true
+	case pmetric.MetricTypeExponentialHistogram:
+		return m.ExponentialHistogram().AggregationTemporality(), true
+	default:
+		return pmetric.AggregationTemporalityUnspecified, false
+	}
+}
+
+// validateTemporality は集約方法が未指定（UNSPECIFIED）のSum・Histogram・ExponentialHistogramを含むバッチを拒否します
+// DELTA と CUMULATIVE のどちらか分からない値は StartTimeUnix と組み合わせてもレートを計算できないため、
+// 保存せずにリトライしない永続的なエラーを返します
+func (e *metricsExporter) validateTemporality(md pmetric.Metrics) error {
+	var names []string
+	count := 0
+	for _, rm := range md.ResourceMetrics().All() {
+		for _, sm := range rm.ScopeMetrics().All() {
+			for _, m := range sm.Metrics().All() {
+				if temporality, ok := metricTemporality(m); !ok || temporality != pmetric.AggregationTemporalityUnspecified {
+					continue
+				}
+				count++
+				if len(names) < maxLoggedMetricNames {
+					names = append(names, fmt.Sprintf("%s(%s)", m.Name(), m.Type()))
+				}
+			}
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	err := fmt.Errorf("集約方法（AggregationTemporality）が未指定のメトリクスが%d件含まれています（DELTA または CUMULATIVE を指定してください）: %s",
+		count, strings.Join(names, ", "))
+	e.diag.recordError("metrics", err)
+	return consumererror.NewPermanent(err)
+}
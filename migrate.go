@@ -0,0 +1,159 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/internal/migrations"
+	"github.com/dtamura/myexporter/internal/sqltemplates"
+)
+
+// MigrationsConfig - 起動時のスキーママイグレーション設定
+// CREATE TABLE IF NOT EXISTS では既存テーブルにスキーマ変更が反映されないため、
+// バージョン付きのDDL（internal/migrations）を適用履歴テーブルと照合して未適用のものだけを実行します
+type MigrationsConfig struct {
+	// Enabled は起動時のマイグレーションを有効化します
+	Enabled bool `mapstructure:"enabled"`
+	// DryRun は未適用のマイグレーションのSQLをログに出力するのみで実行しません
+	DryRun bool `mapstructure:"dry_run"`
+	// Table は適用履歴テーブル名です（各シグナルのデータベースに作成）
+	Table string `mapstructure:"table"`
+}
+
+// validate はマイグレーション設定を検証します
+func (c MigrationsConfig) validate() error {
+	if c.Enabled && c.Table == "" {
+		return fmt.Errorf("migrations.table を指定してください")
+	}
+	return nil
+}
+
+// applyMigrations はシグナルの未適用マイグレーションをバージョン順に適用し、適用履歴を記録します
+// table はマイグレーションを適用するテーブル（分散テーブル構成ではローカルテーブル）です
+func applyMigrations(ctx context.Context, cfg *Config, db *sql.DB, signal, database, table string, logger *zap.Logger) error {
+	// マイグレーションはClickHouseのDDLのため、PostgreSQLではテーブル作成時のDDLのみを使用する
+	if !cfg.Migrations.Enabled || cfg.isPostgres() {
+		return nil
+	}
+	pending, err := migrations.ForSignal(signal)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	if !cfg.Migrations.DryRun {
+		if err := createSchemaVersionTable(ctx, cfg, db, database); err != nil {
+			return err
+		}
+	}
+	applied, err := appliedMigrationVersions(ctx, cfg, db, signal, database, table)
+	if err != nil {
+		if !cfg.Migrations.DryRun {
+			return err
+		}
+		// dry_run では適用履歴テーブルを作成しないため、存在しない場合は全て未適用として扱う
+		logger.Debug("適用履歴を取得できないため、全てのマイグレーションを未適用として扱います", zap.Error(err))
+	}
+
+	for _, m := range pending {
+		if applied[m.Version] {
+			continue
+		}
+		query, err := renderMigrationSQL(cfg, m, database, table)
+		if err != nil {
+			return err
+		}
+
+		if cfg.Migrations.DryRun {
+			logger.Info("未適用のマイグレーションがあります（dry_run のため実行しません）",
+				zap.String("signal", signal), zap.Int("version", m.Version), zap.String("name", m.Name), zap.String("sql", query))
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("マイグレーション %s/%04d_%s の適用に失敗しました: %w", signal, m.Version, m.Name, err)
+		}
+		insert := &insertStatement{sql: fmt.Sprintf(`INSERT INTO "%s"."%s" (Signal, TableName, Version, Name, AppliedAt) VALUES (?, ?, ?, ?, ?)`,
+			database, cfg.Migrations.Table)}
+		if err := insertRows(ctx, db, insert, [][]any{{signal, table, uint32(m.Version), m.Name, time.Now()}}); err != nil {
+			return fmt.Errorf("マイグレーション %s/%04d_%s の適用履歴の記録に失敗しました: %w", signal, m.Version, m.Name, err)
+		}
+		logger.Info("マイグレーションを適用しました",
+			zap.String("signal", signal), zap.String("table", table), zap.Int("version", m.Version), zap.String("name", m.Name))
+	}
+	return nil
+}
+
+// renderMigrationSQL はマイグレーションのSQLテンプレートを対象テーブルでレンダリングします
+func renderMigrationSQL(cfg *Config, m migrations.Migration, database, table string) (string, error) {
+	return internal.ExecuteSQLTemplate(fmt.Sprintf("migration %s/%04d_%s", m.Signal, m.Version, m.Name), m.SQL, internal.TableTemplateData{
+		Database: database,
+		Table:    table,
+		Cluster:  cfg.clusterString(),
+	})
+}
+
+// renderMigrationStatements はシグナルの全マイグレーションのSQLを返します（適用履歴は参照しない）
+// いずれも冪等なDDLのため、適用済みのテーブルに対して実行しても問題ありません
+func renderMigrationStatements(cfg *Config, signal, database, table string) ([]SchemaStatement, error) {
+	if !cfg.Migrations.Enabled || cfg.isPostgres() {
+		return nil, nil
+	}
+	all, err := migrations.ForSignal(signal)
+	if err != nil {
+		return nil, err
+	}
+	stmts := make([]SchemaStatement, 0, len(all))
+	for _, m := range all {
+		query, err := renderMigrationSQL(cfg, m, database, table)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, SchemaStatement{fmt.Sprintf("migration %s/%04d_%s", signal, m.Version, m.Name), query})
+	}
+	return stmts, nil
+}
+
+// createSchemaVersionTable は適用履歴テーブルを作成します
+func createSchemaVersionTable(ctx context.Context, cfg *Config, db *sql.DB, database string) error {
+	query, err := internal.ExecuteSQLTemplate("schema_version_table.sql", sqltemplates.SchemaVersionCreateTable, internal.TableTemplateData{
+		Database: database,
+		Table:    cfg.Migrations.Table,
+		Cluster:  cfg.clusterString(),
+		Engine:   cfg.replicatedEngine("ReplacingMergeTree"),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("適用履歴テーブルの作成に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrationVersions はテーブルに適用済みのマイグレーションのバージョンを返します
+func appliedMigrationVersions(ctx context.Context, cfg *Config, db *sql.DB, signal, database, table string) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT Version FROM "%s"."%s" FINAL WHERE Signal = ? AND TableName = ?`,
+		database, cfg.Migrations.Table), signal, table)
+	if err != nil {
+		return nil, fmt.Errorf("適用履歴の取得に失敗しました: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version uint32
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("適用履歴の取得に失敗しました: %w", err)
+		}
+		applied[int(version)] = true
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,67 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// openNativeConn は use_native_batch 有効時に、ネイティブバッチ挿入用の clickhouse-go の接続を作成します（無効の場合は nil）
+// database/sql の接続プールとは別の接続で、DSN（エンドポイント・認証・圧縮・接続パラメータ）は同じものを使用します
+// 障害注入（chaos）は database/sql の接続にのみ適用され、この接続には適用されません
+func openNativeConn(cfg *Config) (clickhouse.Conn, error) {
+	if !cfg.UseNativeBatch {
+		return nil, nil
+	}
+	// password_file のパスワードは開始時に読み込んだものを使用する（ローテーションは反映されない）
+	cfg, err := cfg.withPasswordFile()
+	if err != nil {
+		return nil, err
+	}
+	dsn, err := buildDSN(cfg, cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	options, err := clickhouse.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("ネイティブバッチ用のDSNの解析に失敗しました: %w", err)
+	}
+	conn, err := clickhouse.Open(options)
+	if err != nil {
+		return nil, fmt.Errorf("ネイティブバッチ用の接続の作成に失敗しました: %w", err)
+	}
+	return conn, nil
+}
+
+// closeNativeConn はネイティブバッチ用の接続を閉じます（未作成の場合は何もしない）
+func closeNativeConn(conn clickhouse.Conn) error {
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// insertRowsNative は clickhouse-go のネイティブバッチ（PrepareBatch/Append/Send）で行を1バッチで挿入します
+// 行は列ごとのブロックに変換して送信されるため、行ごとに ExecContext を呼ぶ database/sql の経路より高速です
+// PrepareBatch は挿入SQLの VALUES 以降を無視し、INSERT INTO ... (列) の部分のみを使用します
+func insertRowsNative(ctx context.Context, conn clickhouse.Conn, insert *insertStatement, rows [][]any) error {
+	batch, err := conn.PrepareBatch(ctx, insert.sql)
+	if err != nil {
+		return fmt.Errorf("バッチの準備に失敗しました: %w", err)
+	}
+	defer func() { _ = batch.Abort() }()
+
+	for _, row := range rows {
+		if err := batch.Append(insert.args(row)...); err != nil {
+			return fmt.Errorf("行の追加に失敗しました: %w", err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("バッチの送信に失敗しました: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlmetric"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/ottlfuncs"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// 条件に一致したアイテムの扱い（filter.mode）
+const (
+	filterModeDrop = "drop" // 条件のいずれかに一致したアイテムを破棄する（既定）
+	filterModeKeep = "keep" // 条件のいずれかに一致したアイテムのみを残す
+)
+
+// previewRuleFilter は filter_preview で OTTL のフィルタを集計する規則の名前です
+const previewRuleFilter = "filter"
+
+// FilterConfig - OTTL の条件式によるフィルタの設定
+// 出力・挿入の前にシグナルごとの条件を評価し、mode に従ってアイテムを破棄します（プロセッサーを追加せずに最後の防御として使用する）
+// 条件は traces がスパン、logs がログレコード、metrics がメトリクスのコンテキストで評価し、複数の条件はいずれかに一致した場合に一致とします
+//
+//	filter:
+//	  mode: drop
+//	  traces:
+//	    - attributes["http.route"] == "/healthz"
+//	  logs:
+//	    - severity_number < SEVERITY_NUMBER_INFO and resource.attributes["env"] == "dev"
+type FilterConfig struct {
+	Mode string `mapstructure:"mode"` // drop（既定）または keep
+	// ErrorMode は条件の評価に失敗した場合の扱いです（propagate, ignore, silent、既定: propagate）
+	// propagate はバッチをリトライしない永続的なエラーとし、ignore・silent は失敗した条件を一致しないものとして扱う
+	ErrorMode ottl.ErrorMode `mapstructure:"error_mode"`
+	Traces    []string       `mapstructure:"traces"`  // スパンの条件
+	Logs      []string       `mapstructure:"logs"`    // ログレコードの条件
+	Metrics   []string       `mapstructure:"metrics"` // メトリクスの条件
+}
+
+// validate はフィルタの設定を検証し、条件を解析できるかを確認します
+func (c FilterConfig) validate() error {
+	var errs error
+	switch c.Mode {
+	case "", filterModeDrop, filterModeKeep:
+	default:
+		errs = errors.Join(errs, fmt.Errorf("filter.mode は drop または keep を指定してください: %s", c.Mode))
+	}
+	set := component.TelemetrySettings{Logger: zap.NewNop()}
+	if _, err := newSpanFilter(c, set); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if _, err := newLogFilter(c, set); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	if _, err := newMetricFilter(c, set); err != nil {
+		errs = errors.Join(errs, err)
+	}
+	return errs
+}
+
+// removesItems は指定シグナルのフィルタがアイテムを取り除くかどうかを返します（filter_preview では件数を数えるのみ）
+func (c FilterConfig) removesItems(signal string, preview bool) bool {
+	if preview {
+		return false
+	}
+	switch signal {
+	case "traces":
+		return len(c.Traces) > 0
+	case "logs":
+		return len(c.Logs) > 0
+	case "metrics":
+		return len(c.Metrics) > 0
+	default:
+		return false
+	}
+}
+
+// ottlFilter は1シグナル分の条件と、一致したアイテムの扱いです
+type ottlFilter[K any] struct {
+	keep       bool
+	conditions ottl.ConditionSequence[K]
+}
+
+// シグナルごとのフィルタ（スパン・ログレコード・メトリクスのコンテキストで条件を評価する）
+type (
+	spanFilter   = ottlFilter[ottlspan.TransformContext]
+	logFilter    = ottlFilter[ottllog.TransformContext]
+	metricFilter = ottlFilter[ottlmetric.TransformContext]
+)
+
+// newOTTLFilter は条件を解析してフィルタを作成します（条件がない場合は nil）
+func newOTTLFilter[K any](c FilterConfig, name string, conditions []string, set component.TelemetrySettings,
+	newParser func(map[string]ottl.Factory[K], component.TelemetrySettings, ...ottl.Option[K]) (ottl.Parser[K], error),
+) (*ottlFilter[K], error) {
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+	parser, err := newParser(ottlfuncs.StandardConverters[K](), set)
+	if err != nil {
+		return nil, fmt.Errorf("filter.%s: %w", name, err)
+	}
+	parsed, err := parser.ParseConditions(conditions)
+	if err != nil {
+		return nil, fmt.Errorf("filter.%s の条件を解析できません: %w", name, err)
+	}
+	errorMode := c.ErrorMode
+	if errorMode == "" {
+		errorMode = ottl.PropagateError
+	}
+	return &ottlFilter[K]{
+		keep: c.Mode == filterModeKeep,
+		conditions: ottl.NewConditionSequence(parsed, set,
+			ottl.WithConditionSequenceErrorMode[K](errorMode), ottl.WithLogicOperation[K](ottl.Or)),
+	}, nil
+}
+
+// newSpanFilter はスパンのフィルタを作成します（filter.traces 未指定の場合は nil）
+func newSpanFilter(c FilterConfig, set component.TelemetrySettings) (*spanFilter, error) {
+	return newOTTLFilter(c, "traces", c.Traces, set, ottlspan.NewParser)
+}
+
+// newLogFilter はログレコードのフィルタを作成します（filter.logs 未指定の場合は nil）
+func newLogFilter(c FilterConfig, set component.TelemetrySettings) (*logFilter, error) {
+	return newOTTLFilter(c, "logs", c.Logs, set, ottllog.NewParser)
+}
+
+// newMetricFilter はメトリクスのフィルタを作成します（filter.metrics 未指定の場合は nil）
+func newMetricFilter(c FilterConfig, set component.TelemetrySettings) (*metricFilter, error) {
+	return newOTTLFilter(c, "metrics", c.Metrics, set, ottlmetric.NewParser)
+}
+
+// drops はアイテムを破棄するかどうかを返します（keep の場合は条件に一致しないアイテムを破棄する）
+func (f *ottlFilter[K]) drops(ctx context.Context, tCtx K) (bool, error) {
+	match, err := f.conditions.Eval(ctx, tCtx)
+	if err != nil {
+		return false, err
+	}
+	return match != f.keep, nil
+}
+
+// filterTraces はフィルタで破棄するスパンの件数を返します
+// remove が true の場合はスパン（空になったスコープ・リソースも）を取り除き、false（filter_preview）の場合は件数を数えるのみです
+// 評価に失敗した場合（error_mode: propagate）はバッチを変更せずにエラーを返します
+func filterTraces(ctx context.Context, f *spanFilter, td ptrace.Traces, remove bool) (int, error) {
+	if f == nil {
+		return 0, nil
+	}
+	// 全てのスパンを評価してから取り除き、途中で失敗した場合にバッチが一部だけ変更されないようにする
+	var drops []bool
+	count := 0
+	for _, rs := range td.ResourceSpans().All() {
+		for _, ss := range rs.ScopeSpans().All() {
+			for _, span := range ss.Spans().All() {
+				drop, err := f.drops(ctx, ottlspan.NewTransformContext(span, ss.Scope(), rs.Resource(), ss, rs))
+				if err != nil {
+					return 0, err
+				}
+				drops = append(drops, drop)
+				if drop {
+					count++
+				}
+			}
+		}
+	}
+	if !remove || count == 0 {
+		return count, nil
+	}
+	i := 0
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(func(ptrace.Span) bool {
+				i++
+				return drops[i-1]
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return count, nil
+}
+
+// filterLogs はフィルタで破棄するログレコードの件数を返します（remove とエラーの扱いは filterTraces と同じ）
+func filterLogs(ctx context.Context, f *logFilter, ld plog.Logs, remove bool) (int, error) {
+	if f == nil {
+		return 0, nil
+	}
+	var drops []bool
+	count := 0
+	for _, rl := range ld.ResourceLogs().All() {
+		for _, sl := range rl.ScopeLogs().All() {
+			for _, lr := range sl.LogRecords().All() {
+				drop, err := f.drops(ctx, ottllog.NewTransformContext(lr, sl.Scope(), rl.Resource(), sl, rl))
+				if err != nil {
+					return 0, err
+				}
+				drops = append(drops, drop)
+				if drop {
+					count++
+				}
+			}
+		}
+	}
+	if !remove || count == 0 {
+		return count, nil
+	}
+	i := 0
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(plog.LogRecord) bool {
+				i++
+				return drops[i-1]
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return count, nil
+}
+
+// filterMetrics はフィルタで破棄するメトリクスの件数を返します（remove とエラーの扱いは filterTraces と同じ）
+func filterMetrics(ctx context.Context, f *metricFilter, md pmetric.Metrics, remove bool) (int, error) {
+	if f == nil {
+		return 0, nil
+	}
+	var drops []bool
+	count := 0
+	for _, rm := range md.ResourceMetrics().All() {
+		for _, sm := range rm.ScopeMetrics().All() {
+			for _, m := range sm.Metrics().All() {
+				drop, err := f.drops(ctx, ottlmetric.NewTransformContext(m, sm.Metrics(), sm.Scope(), rm.Resource(), sm, rm))
+				if err != nil {
+					return 0, err
+				}
+				drops = append(drops, drop)
+				if drop {
+					count++
+				}
+			}
+		}
+	}
+	if !remove || count == 0 {
+		return count, nil
+	}
+	i := 0
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(pmetric.Metric) bool {
+				i++
+				return drops[i-1]
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	return count, nil
+}
+
+// reportFilter はフィルタの結果を記録し、評価に失敗した場合はリトライしない永続的なエラーを返します
+// filter_preview 有効時は破棄されるはずだった件数として記録します
+func reportFilter(ctx context.Context, cfg *Config, diag *diagnostics, telemetry *exporterTelemetry, events *lifecycleEvents,
+	logger *zap.Logger, signal string, count int, err error,
+) error {
+	if err != nil {
+		err = fmt.Errorf("filter.%s の条件の評価に失敗しました: %w", signal, err)
+		diag.recordError(signal, err)
+		logger.Error("フィルタの条件を評価できないためバッチを拒否しました", zap.Error(err))
+		return consumererror.NewPermanent(err)
+	}
+	if count == 0 {
+		return nil
+	}
+	if cfg.FilterPreview {
+		diag.recordPreview(previewRuleFilter, previewActionDropped, count)
+		telemetry.recordPreview(ctx, previewRuleFilter, previewActionDropped, count)
+		logger.Debug("フィルタの条件により破棄されるアイテムがあります（filter_preview のため破棄しません）", zap.Int("would_drop", count))
+		return nil
+	}
+	diag.recordFiltered(signal, count)
+	diag.recordDropped(signal, stable.DropReasonFilter, count)
+	events.batchDropped(stable.DropReasonFilter, count)
+	logger.Debug("フィルタの条件によりアイテムを破棄しました", zap.Int("filtered", count), zap.String("mode", cfg.Filter.Mode))
+	return nil
+}
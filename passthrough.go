@@ -0,0 +1,151 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/pprofile/pprofileotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// passthroughSignals は転送対象として指定できるシグナルです
+var passthroughSignals = []string{"traces", "logs", "metrics", "profiles"}
+
+// PassthroughConfig - ClickHouseに保存しないシグナルをOTLP（gRPC）で転送する設定
+// Signals に指定したシグナルはDBに保存せず、Endpoint に転送します
+// シグナルを1つずつClickHouseへ移行する間、残りのシグナルを既存のバックエンドへ流し続けるために使用します
+type PassthroughConfig struct {
+	// Endpoint は転送先のOTLP gRPCエンドポイント（host:port）です
+	Endpoint string `mapstructure:"endpoint"`
+	// Insecure はTLSを使用せずに接続します
+	Insecure bool `mapstructure:"insecure"`
+	// Headers は転送時に付与するgRPCメタデータです（認証ヘッダー等）
+	Headers map[string]configopaque.String `mapstructure:"headers"`
+	// Signals は転送するシグナル（traces, logs, metrics, profiles）です
+	Signals []string `mapstructure:"signals"`
+}
+
+// validate は転送設定を検証します
+func (c PassthroughConfig) validate() error {
+	var errs error
+	for _, signal := range c.Signals {
+		if !slices.Contains(passthroughSignals, signal) {
+			errs = errors.Join(errs, fmt.Errorf("passthrough.signals に不明なシグナルが指定されています: %s", signal))
+		}
+	}
+	if len(c.Signals) > 0 && c.Endpoint == "" {
+		errs = errors.Join(errs, fmt.Errorf("passthrough.signals を指定する場合は passthrough.endpoint が必要です"))
+	}
+	return errs
+}
+
+// forwards はシグナルが転送対象（DBに保存しない）かどうかを返します
+func (c PassthroughConfig) forwards(signal string) bool {
+	return slices.Contains(c.Signals, signal)
+}
+
+// otlpForwarder はシグナルをOTLP gRPCエンドポイントへ転送します
+type otlpForwarder struct {
+	signal  string
+	conn    *grpc.ClientConn
+	headers metadata.MD
+	logger  *zap.Logger
+}
+
+// newOTLPForwarder はシグナルが転送対象の場合のみ転送クライアントを作成します（対象外の場合は nil）
+// 接続は最初の転送時に確立されます
+func newOTLPForwarder(cfg PassthroughConfig, signal string, logger *zap.Logger) (*otlpForwarder, error) {
+	if !cfg.forwards(signal) {
+		return nil, nil
+	}
+
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("転送先 %s への接続の作成に失敗しました: %w", cfg.Endpoint, err)
+	}
+
+	headers := metadata.MD{}
+	for key, value := range cfg.Headers {
+		headers.Set(key, string(value))
+	}
+
+	logger.Info("シグナルをDBに保存せずOTLPで転送します",
+		zap.String("signal", signal), zap.String("endpoint", cfg.Endpoint))
+	return &otlpForwarder{
+		signal:  signal,
+		conn:    conn,
+		headers: headers,
+		logger:  logger,
+	}, nil
+}
+
+// outgoingContext は転送用のメタデータを付与したコンテキストを返します
+func (f *otlpForwarder) outgoingContext(ctx context.Context) context.Context {
+	if len(f.headers) == 0 {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, f.headers)
+}
+
+// forwardTraces はトレースを転送します
+func (f *otlpForwarder) forwardTraces(ctx context.Context, td ptrace.Traces) error {
+	_, err := ptraceotlp.NewGRPCClient(f.conn).Export(f.outgoingContext(ctx), ptraceotlp.NewExportRequestFromTraces(td))
+	return f.result(err, td.SpanCount())
+}
+
+// forwardLogs はログを転送します
+func (f *otlpForwarder) forwardLogs(ctx context.Context, ld plog.Logs) error {
+	_, err := plogotlp.NewGRPCClient(f.conn).Export(f.outgoingContext(ctx), plogotlp.NewExportRequestFromLogs(ld))
+	return f.result(err, ld.LogRecordCount())
+}
+
+// forwardMetrics はメトリクスを転送します
+func (f *otlpForwarder) forwardMetrics(ctx context.Context, md pmetric.Metrics) error {
+	_, err := pmetricotlp.NewGRPCClient(f.conn).Export(f.outgoingContext(ctx), pmetricotlp.NewExportRequestFromMetrics(md))
+	return f.result(err, md.DataPointCount())
+}
+
+// forwardProfiles はプロファイルを転送します
+func (f *otlpForwarder) forwardProfiles(ctx context.Context, pd pprofile.Profiles) error {
+	_, err := pprofileotlp.NewGRPCClient(f.conn).Export(f.outgoingContext(ctx), pprofileotlp.NewExportRequestFromProfiles(pd))
+	return f.result(err, pd.SampleCount())
+}
+
+// result は転送結果をログに記録し、失敗した場合はリトライ対象のエラーを返します
+func (f *otlpForwarder) result(err error, items int) error {
+	if err != nil {
+		return fmt.Errorf("%s の転送に失敗しました: %w", f.signal, err)
+	}
+	f.logger.Debug("OTLPで転送しました", zap.String("signal", f.signal), zap.Int("items", items))
+	return nil
+}
+
+// shutdown は転送先への接続を閉じます
+func (f *otlpForwarder) shutdown() error {
+	if f == nil {
+		return nil
+	}
+	return f.conn.Close()
+}
@@ -0,0 +1,249 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.uber.org/zap"
+)
+
+// validatePasswordFile は password_file と password_file_reload_interval を検証します
+func (cfg *Config) validatePasswordFile() error {
+	var errs error
+	used := cfg.PasswordFile != ""
+	if cfg.PasswordFile != "" && cfg.Password != "" {
+		errs = errors.Join(errs, errors.New("password と password_file は同時に指定できません"))
+	}
+	for i, t := range cfg.Targets {
+		if t.PasswordFile != "" && t.Password != "" {
+			errs = errors.Join(errs, fmt.Errorf("targets[%d] の password と password_file は同時に指定できません", i))
+		}
+		used = used || t.PasswordFile != ""
+	}
+	if used && cfg.PasswordFileReloadInterval <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("password_file_reload_interval は0より大きい必要があります: %s", cfg.PasswordFileReloadInterval))
+	}
+	return errs
+}
+
+// readPasswordFile はファイルからパスワードを読み込みます（シークレットの書き出しツールが付ける末尾の改行は取り除く）
+func readPasswordFile(path string) (configopaque.String, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("password_file の読み込みに失敗しました: %w", err)
+	}
+	return configopaque.String(strings.TrimRight(string(b), "\r\n")), nil
+}
+
+// withPasswordFile は password_file 指定時に、ファイルのパスワードを password に設定した設定のコピーを返します
+func (cfg *Config) withPasswordFile() (*Config, error) {
+	if cfg.PasswordFile == "" {
+		return cfg, nil
+	}
+	password, err := readPasswordFile(cfg.PasswordFile)
+	if err != nil {
+		return nil, err
+	}
+	resolved := *cfg
+	resolved.Password = password
+	return &resolved, nil
+}
+
+// passwordFileConnector - password_file のパスワードで接続し、ファイルの変更を検知して接続し直す driver.Connector
+// password_file_reload_interval ごとにファイルを読み込み、パスワードが変わった場合は新しいパスワードで接続を作成します
+// 古いパスワードで確立した接続は、接続プールに戻された時点で破棄します（使用中の接続は処理を中断しない）
+// 接続プールを閉じると（io.Closer）ファイルの確認を停止します
+type passwordFileConnector struct {
+	config   *Config
+	database string
+	logger   *zap.Logger
+
+	mu         sync.Mutex
+	password   configopaque.String
+	inner      driver.Connector
+	generation atomic.Uint64 // パスワードが変わるたびに増やす（古い接続の判定に使用）
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newPasswordFileConnector は password_file のパスワードで接続する driver.Connector を作成します
+// ファイルを読み込めない場合はエラーを返します
+func newPasswordFileConnector(cfg *Config, database string, logger *zap.Logger) (*passwordFileConnector, error) {
+	c := &passwordFileConnector{config: cfg, database: database, logger: logger, done: make(chan struct{})}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	go c.watch(ctx)
+	return c, nil
+}
+
+// reload はファイルのパスワードを読み込み、変わっていた場合は新しいパスワードの Connector に切り替えます
+func (c *passwordFileConnector) reload() error {
+	resolved, err := c.config.withPasswordFile()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inner != nil && resolved.Password == c.password {
+		return nil
+	}
+	inner, err := newConnector(resolved, c.database, c.logger)
+	if err != nil {
+		return err
+	}
+	old := c.inner
+	c.inner, c.password = inner, resolved.Password
+	if old == nil {
+		return nil
+	}
+	c.generation.Add(1)
+	if closer, ok := old.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	c.logger.Info("password_file のパスワードが変更されました、新しいパスワードで接続し直します",
+		zap.String("password_file", c.config.PasswordFile))
+	return nil
+}
+
+// watch は password_file_reload_interval ごとにファイルを確認します
+// 読み込みに失敗した場合（ローテーション中の一時的な欠落など）は現在のパスワードで接続を続けます
+func (c *passwordFileConnector) watch(ctx context.Context) {
+	defer close(c.done)
+	ticker := time.NewTicker(c.config.PasswordFileReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := c.reload(); err != nil {
+			c.logger.Warn("password_file を再読み込みできません、現在のパスワードで接続を続けます", zap.Error(err))
+		}
+	}
+}
+
+func (c *passwordFileConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	inner, generation := c.inner, c.generation.Load()
+	c.mu.Unlock()
+	conn, err := inner.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &passwordFileConn{Conn: conn, connector: c, generation: generation}, nil
+}
+
+func (c *passwordFileConnector) Driver() driver.Driver {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inner.Driver()
+}
+
+// Close はファイルの確認を停止し、内側の Connector を閉じます（sql.DB.Close から呼び出されます）
+func (c *passwordFileConnector) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.cancel()
+		<-c.done
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if closer, ok := c.inner.(io.Closer); ok {
+			err = closer.Close()
+		}
+	})
+	return err
+}
+
+// passwordFileConn - 接続時のパスワードの世代を保持する driver.Conn ラッパー
+// パスワードが変わった後は IsValid が false を返し、database/sql は接続プールに戻さずに閉じます
+type passwordFileConn struct {
+	driver.Conn
+	connector  *passwordFileConnector
+	generation uint64
+}
+
+// stale は古いパスワードで確立した接続かどうかを返します
+func (c *passwordFileConn) stale() bool {
+	return c.generation != c.connector.generation.Load()
+}
+
+func (c *passwordFileConn) IsValid() bool {
+	if c.stale() {
+		return false
+	}
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *passwordFileConn) ResetSession(ctx context.Context) error {
+	// ErrBadConn を返すと database/sql は接続を破棄して新しい接続を使用する
+	if c.stale() {
+		return driver.ErrBadConn
+	}
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *passwordFileConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *passwordFileConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *passwordFileConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint:staticcheck // ConnBeginTx 未実装ドライバー向けのフォールバック
+}
+
+func (c *passwordFileConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *passwordFileConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *passwordFileConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
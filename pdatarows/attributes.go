@@ -0,0 +1,62 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pdatarows
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// AttributeKeyHashLength は短縮したキーに付与するハッシュ接尾辞（~ と16進8桁）の長さです
+const AttributeKeyHashLength = 9
+
+// TruncateAttributeKey はキーを limit バイト以内に短縮し、元のキーのハッシュを接尾辞として付与します
+// 同じ接頭辞を持つ異なるキーが同一のキーに潰れないよう、ハッシュは元のキー全体から計算します
+func TruncateAttributeKey(key string, limit int) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	// マルチバイト文字の途中で切らないよう、UTF-8の文字境界まで戻す
+	end := limit - AttributeKeyHashLength
+	for end > 0 && !utf8.RuneStart(key[end]) {
+		end--
+	}
+	return fmt.Sprintf("%s~%08x", key[:end], h.Sum32())
+}
+
+// TruncateString は文字列を limit バイト以内に短縮します（マルチバイト文字の途中では切らない）
+func TruncateString(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	end := limit
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
+
+// AttributesToMap は属性を Map(String, String) カラム用に平坦化します
+// 文字列以外の値（数値、配列、マップ等）は文字列表現に変換されます
+func AttributesToMap(attrs pcommon.Map) map[string]string {
+	m := make(map[string]string, attrs.Len())
+	for k, v := range attrs.All() {
+		m[k] = v.AsString()
+	}
+	return m
+}
+
+// AttributesToJSON は属性を JSON カラム用のJSON文字列に変換します
+// 値の型（数値、真偽値、配列、ネストしたマップ）はそのまま保持されます
+func AttributesToJSON(attrs pcommon.Map) (string, error) {
+	b, err := json.Marshal(attrs.AsRaw())
+	if err != nil {
+		return "", fmt.Errorf("属性のJSON変換に失敗しました: %w", err)
+	}
+	return string(b), nil
+}
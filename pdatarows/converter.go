@@ -0,0 +1,219 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pdatarows は OTLP のデータ（ptrace, plog, pmetric, pprofile）を ClickHouse テーブルの行の値に変換します
+//
+// 行は TraceColumns などの列順の []any で、database/sql の挿入にそのまま渡せます
+// エクスポーター以外のツールやテストからも、エクスポーターと同じ列の対応で変換できます
+package pdatarows
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// Options - 行への変換の設定
+type Options struct {
+	// JSONAttributes は属性カラムをJSON文字列に変換します（false の場合は map[string]string）
+	JSONAttributes bool
+	// KeepResourceAttribute は保存するリソース属性を選択します（nil の場合はすべて保存）
+	KeepResourceAttribute func(key string) bool
+	// MaxAttributeKeyLength を超える属性キーはハッシュ接尾辞付きで短縮します（0 の場合は無制限）
+	// 0 以外を指定する場合は 2*AttributeKeyHashLength 以上である必要があります
+	MaxAttributeKeyLength int
+	// MaxAttributeValueLength を超える文字列の属性値は先頭のみに短縮します（0 の場合は無制限）
+	// 文字列以外の値（配列・マップなど）は短縮しません
+	MaxAttributeValueLength int
+	// MaxBodyLength を超えるログ本文は先頭のみに短縮します（0 の場合は無制限）
+	MaxBodyLength int
+	// StoreEvents が false の場合、Events の Nested カラムは空の配列になります
+	StoreEvents bool
+	// StoreLinks が false の場合、Links の Nested カラムは空の配列になります
+	StoreLinks bool
+	// FillObservedTimestamp は ObservedTimestamp が未設定のログレコードの ObservedTimestamp に Timestamp を使用します
+	// ObservedTimestamp でパーティションを分けるテーブル向けで、未設定のレコードが1970年のパーティションに入るのを防ぎます
+	FillObservedTimestamp bool
+	// SpanName は保存するスパン名を変換します（nil の場合はそのまま保存）
+	SpanName func(service, name string) string
+	// MetricsDimensions はメトリクスのデータポイントの行のリソース・スコープの列を、ディメンションテーブルの組のハッシュ（DimensionsHash）に置き換えます
+	MetricsDimensions bool
+	// MetricsLite はメトリクスのデータポイントの行からスコープ・フラグ・エグゼンプラーの列を省略します（metrics_schema: lite のテーブル向け）
+	MetricsLite bool
+	// OmitMetricsScope はメトリクスのディメンションの行からスコープの列を省略し、リソースのみで組を識別します
+	OmitMetricsScope bool
+	// StaleColumn はメトリクスの行の末尾に、ステールマーカー（FLAG_NO_RECORDED_VALUE）のデータポイントかどうかの Stale 列を追加します
+	StaleColumn bool
+	// OmitScopeAttributes はスコープ属性の列に空の値を保存します（列は残し、行の幅のみを抑える）
+	OmitScopeAttributes bool
+	// TraceScope はトレースの行の末尾に TraceScopeColumns（リソース・スコープのスキーマURLとスコープ属性）を追加します
+	TraceScope bool
+	// TimestampAttribute を指定した場合、スパン・ログレコードのこの属性の値を Timestamp 列に使用します（過去のデータの投入向け）
+	// 文字列は RFC 3339、整数は Unix 時刻（ナノ秒）として解釈し、属性がない・解釈できない場合は OTLP の時刻を使用します
+	TimestampAttribute string
+}
+
+// Converter は Options に従ってデータを行に変換します
+// 短縮した属性キーの数を数えるため、並行して使用しないでください（バッチごとに作成する）
+type Converter struct {
+	opts          Options
+	truncatedKeys int
+
+	truncated     bool   // 変換中の行で値を短縮した場合はtrue
+	truncatedRows []bool // 直近の変換で値を短縮した行（MaxAttributeValueLength・MaxBodyLength 指定時のみ）
+}
+
+// NewConverter は変換を作成します
+func NewConverter(opts Options) *Converter {
+	return &Converter{opts: opts}
+}
+
+// TruncatedKeys はこれまでの変換で短縮した属性キーの数を返します
+func (c *Converter) TruncatedKeys() int {
+	return c.truncatedKeys
+}
+
+// TruncatedRows は直近の Traces・Logs の変換で属性値または本文を短縮した行を、行と同じ順で返します
+// MaxAttributeValueLength・MaxBodyLength のいずれも指定しない場合は nil を返します
+func (c *Converter) TruncatedRows() []bool {
+	return c.truncatedRows
+}
+
+// limitsValues は属性値または本文の短縮が有効かどうかを返します
+func (c *Converter) limitsValues() bool {
+	return c.opts.MaxAttributeValueLength > 0 || c.opts.MaxBodyLength > 0
+}
+
+// beginRows は行ごとの短縮の記録をリセットします
+func (c *Converter) beginRows() {
+	c.truncatedRows = nil
+}
+
+// endRow は変換した行で値を短縮したかどうかを記録します
+func (c *Converter) endRow() {
+	if c.limitsValues() {
+		c.truncatedRows = append(c.truncatedRows, c.truncated)
+	}
+}
+
+// timestamp は TimestampAttribute の属性値を時刻として解釈できる場合はその時刻を、それ以外は ts を返します
+func (c *Converter) timestamp(attrs pcommon.Map, ts pcommon.Timestamp) pcommon.Timestamp {
+	if c.opts.TimestampAttribute == "" {
+		return ts
+	}
+	v, ok := attrs.Get(c.opts.TimestampAttribute)
+	if !ok {
+		return ts
+	}
+	switch v.Type() {
+	case pcommon.ValueTypeStr:
+		if t, err := time.Parse(time.RFC3339Nano, v.Str()); err == nil {
+			return pcommon.NewTimestampFromTime(t)
+		}
+	case pcommon.ValueTypeInt:
+		if v.Int() > 0 {
+			return pcommon.Timestamp(v.Int())
+		}
+	}
+	return ts
+}
+
+// body はログ本文を文字列に変換し、MaxBodyLength を超える場合は短縮します
+func (c *Converter) body(v pcommon.Value) string {
+	body := v.AsString()
+	if limit := c.opts.MaxBodyLength; limit > 0 && len(body) > limit {
+		body = TruncateString(body, limit)
+		c.truncated = true
+	}
+	return body
+}
+
+// resourceValue はリソース属性を KeepResourceAttribute で絞り込んでから属性カラムの値に変換します
+// 受信データは変更せず、保存対象の属性のみをコピーします
+func (c *Converter) resourceValue(res pcommon.Resource, json bool) (any, error) {
+	return c.value(c.resourceAttributes(res), json)
+}
+
+// resourceAttributes は保存対象のリソース属性を返します
+func (c *Converter) resourceAttributes(res pcommon.Resource) pcommon.Map {
+	keep := c.opts.KeepResourceAttribute
+	if keep == nil {
+		return res.Attributes()
+	}
+	kept := pcommon.NewMap()
+	for k, v := range res.Attributes().All() {
+		if keep(k) {
+			v.CopyTo(kept.PutEmpty(k))
+		}
+	}
+	return kept
+}
+
+// scopeValue はスコープ属性を属性カラムの値に変換します（OmitScopeAttributes の場合は空の値）
+func (c *Converter) scopeValue(scope pcommon.InstrumentationScope, json bool) (any, error) {
+	if c.opts.OmitScopeAttributes {
+		return c.value(pcommon.NewMap(), json)
+	}
+	return c.value(scope.Attributes(), json)
+}
+
+// value は属性を属性カラムの値（json の場合はJSON文字列、それ以外は map[string]string）に変換します
+func (c *Converter) value(attrs pcommon.Map, json bool) (any, error) {
+	attrs = c.limitAttributes(attrs)
+	if json {
+		return AttributesToJSON(attrs)
+	}
+	return AttributesToMap(attrs), nil
+}
+
+// toMap は属性を Map(String, String) 型に変換します（イベント・リンク属性など形式が固定のカラム用）
+func (c *Converter) toMap(attrs pcommon.Map) map[string]string {
+	return AttributesToMap(c.limitAttributes(attrs))
+}
+
+// limitAttributes は長すぎるキーと文字列の値を短縮した属性を返します
+// 短縮が不要な場合は受信データをそのまま返し、必要な場合のみコピーします
+func (c *Converter) limitAttributes(attrs pcommon.Map) pcommon.Map {
+	keyLimit, valueLimit := c.opts.MaxAttributeKeyLength, c.opts.MaxAttributeValueLength
+	if keyLimit <= 0 && valueLimit <= 0 {
+		return attrs
+	}
+	tooLong := func(k string, v pcommon.Value) (bool, bool) {
+		return keyLimit > 0 && len(k) > keyLimit, valueLimit > 0 && v.Type() == pcommon.ValueTypeStr && len(v.Str()) > valueLimit
+	}
+	needed := false
+	for k, v := range attrs.All() {
+		if longKey, longValue := tooLong(k, v); longKey || longValue {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return attrs
+	}
+
+	limited := pcommon.NewMap()
+	limited.EnsureCapacity(attrs.Len())
+	for k, v := range attrs.All() {
+		longKey, longValue := tooLong(k, v)
+		if longKey {
+			k = TruncateAttributeKey(k, keyLimit)
+			c.truncatedKeys++
+		}
+		if longValue {
+			limited.PutStr(k, TruncateString(v.Str(), valueLimit))
+			c.truncated = true
+			continue
+		}
+		v.CopyTo(limited.PutEmpty(k))
+	}
+	return limited
+}
+
+// resourceString はリソース属性の値を文字列で返します（存在しない場合は空文字）
+func resourceString(res pcommon.Resource, key string) string {
+	if v, ok := res.Attributes().Get(key); ok {
+		return v.AsString()
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pdatarows
+
+import (
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+// LogColumns は Logs が返す行の列順です（logs_insert.sql の列順）
+var LogColumns = []string{
+	"Timestamp", "ObservedTimestamp", "TraceId", "SpanId", "TraceFlags", "SeverityText", "SeverityNumber",
+	"ServiceName", "ServiceVersion", "Body", "ResourceAttributes", "ResourceSchemaUrl",
+	"ScopeName", "ScopeVersion", "ScopeAttributes", "ScopeDroppedAttrCount", "ScopeSchemaUrl",
+	"LogAttributes", "LogDroppedAttrCount",
+}
+
+// Logs はログデータをログレコードごとに1行、LogColumns の列順の行に変換します
+// Timestamp が未設定のログレコードは観測時刻（ObservedTimestamp）を Timestamp とします
+// FillObservedTimestamp を指定した場合は、逆に ObservedTimestamp が未設定のログレコードに Timestamp を使用します
+// TimestampAttribute を指定した場合は、その属性の時刻を Timestamp とします
+func (c *Converter) Logs(ld plog.Logs) ([][]any, error) {
+	rows := make([][]any, 0, ld.LogRecordCount())
+	c.beginRows()
+	for _, rl := range ld.ResourceLogs().All() {
+		res := rl.Resource()
+		c.truncated = false
+		resAttrs, err := c.resourceValue(res, c.opts.JSONAttributes)
+		if err != nil {
+			return nil, err
+		}
+		resTruncated := c.truncated
+		serviceName := resourceString(res, "service.name")
+		serviceVersion := resourceString(res, "service.version")
+
+		for _, sl := range rl.ScopeLogs().All() {
+			scope := sl.Scope()
+			c.truncated = resTruncated
+			scopeAttrs, err := c.scopeValue(scope, c.opts.JSONAttributes)
+			if err != nil {
+				return nil, err
+			}
+			scopeTruncated := c.truncated
+
+			for _, lr := range sl.LogRecords().All() {
+				c.truncated = scopeTruncated
+				logAttrs, err := c.value(lr.Attributes(), c.opts.JSONAttributes)
+				if err != nil {
+					return nil, err
+				}
+
+				timestamp, observed := lr.Timestamp(), lr.ObservedTimestamp()
+				if timestamp == 0 {
+					timestamp = observed
+				}
+				if observed == 0 && c.opts.FillObservedTimestamp {
+					observed = timestamp
+				}
+				// 観測時刻は受信した値のまま、Timestamp のみを属性の時刻で置き換える
+				timestamp = c.timestamp(lr.Attributes(), timestamp)
+
+				rows = append(rows, []any{
+					timestamp.AsTime(),
+					observed.AsTime(),
+					lr.TraceID().String(),
+					lr.SpanID().String(),
+					uint32(lr.Flags()),
+					lr.SeverityText(),
+					int32(lr.SeverityNumber()),
+					serviceName,
+					serviceVersion,
+					c.body(lr.Body()),
+					resAttrs,
+					rl.SchemaUrl(),
+					scope.Name(),
+					scope.Version(),
+					scopeAttrs,
+					scope.DroppedAttributesCount(),
+					sl.SchemaUrl(),
+					logAttrs,
+					lr.DroppedAttributesCount(),
+				})
+				c.endRow()
+			}
+		}
+	}
+	return rows, nil
+}
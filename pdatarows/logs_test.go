@@ -0,0 +1,196 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pdatarows
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+var testObserved = testStart.Add(2 * time.Second)
+
+// testLogs はトレースに紐づくログレコードを1つ含むログを返します
+func testLogs() plog.Logs {
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.SetSchemaUrl("https://opentelemetry.io/schemas/1.26.0")
+	rl.Resource().Attributes().PutStr("service.name", "checkout")
+	rl.Resource().Attributes().PutStr("service.version", "1.4.2")
+	sl := rl.ScopeLogs().AppendEmpty()
+	sl.SetSchemaUrl("https://opentelemetry.io/schemas/1.25.0")
+	sl.Scope().SetName("zap")
+	sl.Scope().SetVersion("1.27.0")
+	sl.Scope().Attributes().PutStr("library.language", "go")
+	sl.Scope().SetDroppedAttributesCount(1)
+
+	lr := sl.LogRecords().AppendEmpty()
+	lr.SetTimestamp(pcommon.NewTimestampFromTime(testStart))
+	lr.SetObservedTimestamp(pcommon.NewTimestampFromTime(testObserved))
+	lr.SetTraceID(testTraceID)
+	lr.SetSpanID(testSpanID)
+	lr.SetFlags(plog.DefaultLogRecordFlags.WithIsSampled(true))
+	lr.SetSeverityText("ERROR")
+	lr.SetSeverityNumber(plog.SeverityNumberError)
+	lr.Body().SetStr("payment declined")
+	lr.Attributes().PutStr("user.id", "u-42")
+	lr.Attributes().PutBool("retryable", false)
+	lr.SetDroppedAttributesCount(2)
+	return ld
+}
+
+func TestConverterLogs(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		modify func(plog.LogRecord)
+		want   map[string]any
+	}{
+		{
+			name: "ID・時刻・重要度",
+			want: map[string]any{
+				"Timestamp":             testStart,
+				"ObservedTimestamp":     testObserved,
+				"TraceId":               "0102030405060708090a0b0c0d0e0f10",
+				"SpanId":                "1112131415161718",
+				"TraceFlags":            uint32(1),
+				"SeverityText":          "ERROR",
+				"SeverityNumber":        int32(plog.SeverityNumberError),
+				"ServiceName":           "checkout",
+				"ServiceVersion":        "1.4.2",
+				"Body":                  "payment declined",
+				"ResourceSchemaUrl":     "https://opentelemetry.io/schemas/1.26.0",
+				"ScopeName":             "zap",
+				"ScopeVersion":          "1.27.0",
+				"ScopeDroppedAttrCount": uint32(1),
+				"ScopeSchemaUrl":        "https://opentelemetry.io/schemas/1.25.0",
+				"LogDroppedAttrCount":   uint32(2),
+			},
+		},
+		{
+			name: "トレースに紐づかないログのIDは空",
+			modify: func(lr plog.LogRecord) {
+				lr.SetTraceID(pcommon.NewTraceIDEmpty())
+				lr.SetSpanID(pcommon.NewSpanIDEmpty())
+			},
+			want: map[string]any{"TraceId": "", "SpanId": ""},
+		},
+		{
+			name: "属性は Map",
+			want: map[string]any{
+				"ResourceAttributes": map[string]string{"service.name": "checkout", "service.version": "1.4.2"},
+				"ScopeAttributes":    map[string]string{"library.language": "go"},
+				"LogAttributes":      map[string]string{"user.id": "u-42", "retryable": "false"},
+			},
+		},
+		{
+			name: "attributes_format: json の属性はJSON文字列（値の型を保持）",
+			opts: Options{JSONAttributes: true},
+			want: map[string]any{
+				"ScopeAttributes": `{"library.language":"go"}`,
+				"LogAttributes":   `{"retryable":false,"user.id":"u-42"}`,
+			},
+		},
+		{
+			name: "スコープ属性を保存しない",
+			opts: Options{OmitScopeAttributes: true},
+			want: map[string]any{"ScopeAttributes": map[string]string{}},
+		},
+		{
+			name: "Timestamp が未設定の場合は観測時刻",
+			modify: func(lr plog.LogRecord) {
+				lr.SetTimestamp(0)
+			},
+			want: map[string]any{"Timestamp": testObserved, "ObservedTimestamp": testObserved},
+		},
+		{
+			name: "ObservedTimestamp が未設定の場合はそのまま",
+			modify: func(lr plog.LogRecord) {
+				lr.SetObservedTimestamp(0)
+			},
+			want: map[string]any{"Timestamp": testStart, "ObservedTimestamp": time.Unix(0, 0).UTC()},
+		},
+		{
+			name: "ObservedTimestamp が未設定の場合は Timestamp で補う",
+			opts: Options{FillObservedTimestamp: true},
+			modify: func(lr plog.LogRecord) {
+				lr.SetObservedTimestamp(0)
+			},
+			want: map[string]any{"Timestamp": testStart, "ObservedTimestamp": testStart},
+		},
+		{
+			name: "タイムスタンプ属性は Timestamp のみを置き換える",
+			opts: Options{TimestampAttribute: "event.time"},
+			modify: func(lr plog.LogRecord) {
+				lr.Attributes().PutStr("event.time", "2023-01-02T03:04:05Z")
+			},
+			want: map[string]any{
+				"Timestamp":         time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+				"ObservedTimestamp": testObserved,
+			},
+		},
+		{
+			name: "文字列以外の本文",
+			modify: func(lr plog.LogRecord) {
+				lr.Body().SetEmptyMap().PutStr("event", "declined")
+			},
+			want: map[string]any{"Body": `{"event":"declined"}`},
+		},
+		{
+			name: "本文の短縮",
+			opts: Options{MaxBodyLength: 7},
+			want: map[string]any{"Body": "payment"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := testLogs()
+			if tt.modify != nil {
+				tt.modify(ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0))
+			}
+			rows, err := NewConverter(tt.opts).Logs(ld)
+			if err != nil {
+				t.Fatalf("Logs: %v", err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("行数 = %d, want 1", len(rows))
+			}
+			values := columnValues(t, LogColumns, rows[0])
+			for column, want := range tt.want {
+				if got := values[column]; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", column, got, want)
+				}
+			}
+		})
+	}
+}
+
+// 同じリソース・スコープのログレコードは属性の値を共有し、レコードごとに1行になる
+func TestConverterLogsRows(t *testing.T) {
+	ld := testLogs()
+	records := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	second := records.AppendEmpty()
+	second.Body().SetStr("retrying")
+	second.SetTimestamp(pcommon.NewTimestampFromTime(testStart.Add(time.Second)))
+
+	conv := NewConverter(Options{MaxBodyLength: 10})
+	rows, err := conv.Logs(ld)
+	if err != nil {
+		t.Fatalf("Logs: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("行数 = %d, want 2", len(rows))
+	}
+	first, got := columnValues(t, LogColumns, rows[0]), columnValues(t, LogColumns, rows[1])
+	if got["Body"] != "retrying" || got["ServiceName"] != "checkout" || !reflect.DeepEqual(got["ScopeAttributes"], first["ScopeAttributes"]) {
+		t.Errorf("2行目 = %v", got)
+	}
+	// 本文を短縮したのは1行目のみ
+	if want := []bool{true, false}; !reflect.DeepEqual(conv.TruncatedRows(), want) {
+		t.Errorf("TruncatedRows = %v, want %v", conv.TruncatedRows(), want)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pdatarows
+
+import (
+	"hash/fnv"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// MetricsDimensionsColumns は MetricsDimensions が返す行の列順です（metrics_dimensions_insert.sql の列順）
+var MetricsDimensionsColumns = []string{
+	"DimensionsHash", "ServiceName", "ResourceAttributes", "ResourceSchemaUrl",
+	"ScopeName", "ScopeVersion", "ScopeAttributes", "ScopeDroppedAttrCount", "ScopeSchemaUrl", "LastSeen",
+}
+
+// metricResourceColumns はメトリクスの行の先頭のリソースの列です
+var metricResourceColumns = []string{"ResourceAttributes", "ResourceSchemaUrl"}
+
+// metricScopeColumns はリソースの列に続くスコープの列です（MetricsLite の場合は省略）
+var metricScopeColumns = []string{"ScopeName", "ScopeVersion", "ScopeAttributes", "ScopeDroppedAttrCount", "ScopeSchemaUrl"}
+
+// metricPointColumns は種類に関わらずすべてのデータポイントの行に含まれる列です
+var metricPointColumns = []string{
+	"ServiceName", "MetricName", "MetricDescription", "MetricUnit", "Attributes", "StartTimeUnix", "TimeUnix",
+}
+
+// exemplarColumns はエグゼンプラーの Nested カラムの列です
+var exemplarColumns = []string{
+	"Exemplars.FilteredAttributes", "Exemplars.TimeUnix", "Exemplars.Value", "Exemplars.SpanId", "Exemplars.TraceId",
+}
+
+// MetricColumns は Metrics が返す種類 t の行の列順です（metrics_*_table.sql の列、StaleColumn の場合は末尾に Stale）
+// MetricsDimensions の場合はリソース・スコープの列の代わりに先頭を DimensionsHash とし、
+// MetricsLite の場合はスコープ・フラグ（Flags）・エグゼンプラー（Exemplars.*）の列を省略します
+// 種類が Empty の場合は nil を返します
+func (c *Converter) MetricColumns(t pmetric.MetricType) []string {
+	flags, exemplars, scope := []string{"Flags"}, exemplarColumns, metricScopeColumns
+	if c.opts.MetricsLite {
+		flags, exemplars, scope = nil, nil, nil
+	}
+	var values []string
+	switch t {
+	case pmetric.MetricTypeGauge, pmetric.MetricTypeSum:
+		values = slices.Concat([]string{"Value"}, flags, exemplars, []string{"AggregationTemporality", "IsMonotonic"})
+	case pmetric.MetricTypeHistogram:
+		values = slices.Concat([]string{"Count", "Sum", "BucketCounts", "ExplicitBounds"}, exemplars, flags,
+			[]string{"Min", "Max", "AggregationTemporality"})
+	case pmetric.MetricTypeExponentialHistogram:
+		values = slices.Concat([]string{"Count", "Sum", "Scale", "ZeroCount",
+			"PositiveOffset", "PositiveBucketCounts", "NegativeOffset", "NegativeBucketCounts"}, exemplars, flags,
+			[]string{"Min", "Max", "AggregationTemporality"})
+	case pmetric.MetricTypeSummary:
+		values = slices.Concat([]string{"Count", "Sum", "ValueAtQuantiles.Quantile", "ValueAtQuantiles.Value"}, flags)
+	default:
+		return nil
+	}
+	if c.opts.StaleColumn {
+		values = append(values, "Stale")
+	}
+	if c.opts.MetricsDimensions {
+		return slices.Concat([]string{"DimensionsHash"}, metricPointColumns, values)
+	}
+	return slices.Concat(metricResourceColumns, scope, metricPointColumns, values)
+}
+
+// Metrics はメトリクスをデータポイントごとに1行、種類ごとに MetricColumns の列順の行に変換します
+// 種類が Empty のメトリクスは変換しません
+// メトリクステーブルの属性カラムは常に Map 型のため、JSONAttributes は使用しません
+func (c *Converter) Metrics(md pmetric.Metrics) map[pmetric.MetricType][][]any {
+	rows := map[pmetric.MetricType][][]any{}
+	for _, rm := range md.ResourceMetrics().All() {
+		res := rm.Resource()
+		resourceAttrs := c.toMap(c.resourceAttributes(res))
+		serviceName := resourceString(res, "service.name")
+		for _, sm := range rm.ScopeMetrics().All() {
+			scope := sm.Scope()
+			scopeAttrs := map[string]string{}
+			if !c.opts.OmitScopeAttributes {
+				scopeAttrs = c.toMap(scope.Attributes())
+			}
+			prefix := []any{
+				resourceAttrs, rm.SchemaUrl(),
+				scope.Name(), scope.Version(), scopeAttrs, scope.DroppedAttributesCount(), sm.SchemaUrl(),
+			}
+			if c.opts.MetricsLite {
+				prefix = prefix[:len(metricResourceColumns)]
+			}
+			if c.opts.MetricsDimensions {
+				// MetricsDimensions の行と同じハッシュで、ディメンションテーブルの組を参照する
+				hash := dimensionsHash(resourceAttrs, rm.SchemaUrl(), scope, scopeAttrs, sm.SchemaUrl())
+				if c.opts.OmitMetricsScope {
+					hash = dimensionsHash(resourceAttrs, rm.SchemaUrl(), pcommon.NewInstrumentationScope(), nil, "")
+				}
+				prefix = []any{hash}
+			}
+			for _, m := range sm.Metrics().All() {
+				// point はデータポイントに共通の列の値を返します（prefix は共有するため、行ごとにコピーする）
+				point := func(attrs pcommon.Map, start, ts pcommon.Timestamp) []any {
+					return append(slices.Clone(prefix),
+						serviceName, m.Name(), m.Description(), m.Unit(), c.toMap(attrs), start.AsTime(), ts.AsTime())
+				}
+				switch m.Type() {
+				case pmetric.MetricTypeGauge:
+					for _, dp := range m.Gauge().DataPoints().All() {
+						row := append(point(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp()), numberValue(dp))
+						row = append(row, c.flags(dp.Flags())...)
+						row = append(row, c.exemplars(dp.Exemplars())...)
+						row = append(row, int32(pmetric.AggregationTemporalityUnspecified), false)
+						rows[m.Type()] = append(rows[m.Type()], c.stale(row, dp.Flags()))
+					}
+				case pmetric.MetricTypeSum:
+					sum := m.Sum()
+					for _, dp := range sum.DataPoints().All() {
+						row := append(point(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp()), numberValue(dp))
+						row = append(row, c.flags(dp.Flags())...)
+						row = append(row, c.exemplars(dp.Exemplars())...)
+						row = append(row, int32(sum.AggregationTemporality()), sum.IsMonotonic())
+						rows[m.Type()] = append(rows[m.Type()], c.stale(row, dp.Flags()))
+					}
+				case pmetric.MetricTypeHistogram:
+					histogram := m.Histogram()
+					for _, dp := range histogram.DataPoints().All() {
+						row := append(point(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp()),
+							dp.Count(), dp.Sum(), dp.BucketCounts().AsRaw(), dp.ExplicitBounds().AsRaw())
+						row = append(row, c.exemplars(dp.Exemplars())...)
+						row = append(row, c.flags(dp.Flags())...)
+						row = append(row, dp.Min(), dp.Max(), int32(histogram.AggregationTemporality()))
+						rows[m.Type()] = append(rows[m.Type()], c.stale(row, dp.Flags()))
+					}
+				case pmetric.MetricTypeExponentialHistogram:
+					histogram := m.ExponentialHistogram()
+					for _, dp := range histogram.DataPoints().All() {
+						row := append(point(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp()),
+							dp.Count(), dp.Sum(), dp.Scale(), dp.ZeroCount(),
+							dp.Positive().Offset(), dp.Positive().BucketCounts().AsRaw(),
+							dp.Negative().Offset(), dp.Negative().BucketCounts().AsRaw())
+						row = append(row, c.exemplars(dp.Exemplars())...)
+						row = append(row, c.flags(dp.Flags())...)
+						row = append(row, dp.Min(), dp.Max(), int32(histogram.AggregationTemporality()))
+						rows[m.Type()] = append(rows[m.Type()], c.stale(row, dp.Flags()))
+					}
+				case pmetric.MetricTypeSummary:
+					for _, dp := range m.Summary().DataPoints().All() {
+						quantiles := make([]float64, 0, dp.QuantileValues().Len())
+						values := make([]float64, 0, dp.QuantileValues().Len())
+						for _, q := range dp.QuantileValues().All() {
+							quantiles = append(quantiles, q.Quantile())
+							values = append(values, q.Value())
+						}
+						row := append(point(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp()),
+							dp.Count(), dp.Sum(), quantiles, values)
+						row = append(row, c.flags(dp.Flags())...)
+						rows[m.Type()] = append(rows[m.Type()], c.stale(row, dp.Flags()))
+					}
+				}
+			}
+		}
+	}
+	return rows
+}
+
+// stale は StaleColumn の場合に、データポイントがステールマーカーかどうかを行の末尾に追加します
+func (c *Converter) stale(row []any, flags pmetric.DataPointFlags) []any {
+	if !c.opts.StaleColumn {
+		return row
+	}
+	return append(row, flags.NoRecordedValue())
+}
+
+// flags はデータポイントのフラグの列の値を返します（MetricsLite の場合は列がないため nil）
+func (c *Converter) flags(flags pmetric.DataPointFlags) []any {
+	if c.opts.MetricsLite {
+		return nil
+	}
+	return []any{uint32(flags)}
+}
+
+// exemplars はエグゼンプラーを exemplarColumns の列順の配列の値に変換します（MetricsLite の場合は列がないため nil）
+func (c *Converter) exemplars(exemplars pmetric.ExemplarSlice) []any {
+	if c.opts.MetricsLite {
+		return nil
+	}
+	attrs := make([]map[string]string, 0, exemplars.Len())
+	times := make([]time.Time, 0, exemplars.Len())
+	values := make([]float64, 0, exemplars.Len())
+	spanIDs := make([]string, 0, exemplars.Len())
+	traceIDs := make([]string, 0, exemplars.Len())
+	for _, ex := range exemplars.All() {
+		attrs = append(attrs, c.toMap(ex.FilteredAttributes()))
+		times = append(times, ex.Timestamp().AsTime())
+		value := ex.DoubleValue()
+		if ex.ValueType() == pmetric.ExemplarValueTypeInt {
+			value = float64(ex.IntValue())
+		}
+		values = append(values, value)
+		spanIDs = append(spanIDs, ex.SpanID().String())
+		traceIDs = append(traceIDs, ex.TraceID().String())
+	}
+	return []any{attrs, times, values, spanIDs, traceIDs}
+}
+
+// numberValue は Gauge・Sum のデータポイントの値を Float64 で返します
+func numberValue(dp pmetric.NumberDataPoint) float64 {
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		return float64(dp.IntValue())
+	}
+	return dp.DoubleValue()
+}
+
+// MetricsDimensions はメトリクスのリソースとスコープの組を、組ごとに1行、MetricsDimensionsColumns の列順の行に変換します
+// バッチ内で同じ組は1行にまとめます。行の先頭の DimensionsHash（uint64）はデータポイントのテーブルが参照する組のハッシュです
+// OmitMetricsScope の場合はリソースごとに1行とし、スコープの列を除いた列順の行を返します
+// ディメンションテーブルの属性カラムは常に Map 型のため、JSONAttributes は使用しません
+func (c *Converter) MetricsDimensions(md pmetric.Metrics, lastSeen time.Time) [][]any {
+	var rows [][]any
+	seen := map[uint64]struct{}{}
+	for _, rm := range md.ResourceMetrics().All() {
+		resourceAttrs := c.toMap(c.resourceAttributes(rm.Resource()))
+		for _, sm := range rm.ScopeMetrics().All() {
+			if c.opts.OmitMetricsScope {
+				hash := dimensionsHash(resourceAttrs, rm.SchemaUrl(), pcommon.NewInstrumentationScope(), nil, "")
+				if _, ok := seen[hash]; !ok {
+					seen[hash] = struct{}{}
+					rows = append(rows, []any{hash, resourceString(rm.Resource(), "service.name"), resourceAttrs, rm.SchemaUrl(), lastSeen})
+				}
+				continue
+			}
+			scope := sm.Scope()
+			scopeAttrs := map[string]string{}
+			if !c.opts.OmitScopeAttributes {
+				scopeAttrs = c.toMap(scope.Attributes())
+			}
+			hash := dimensionsHash(resourceAttrs, rm.SchemaUrl(), scope, scopeAttrs, sm.SchemaUrl())
+			if _, ok := seen[hash]; ok {
+				continue
+			}
+			seen[hash] = struct{}{}
+			rows = append(rows, []any{
+				hash,
+				resourceString(rm.Resource(), "service.name"),
+				resourceAttrs,
+				rm.SchemaUrl(),
+				scope.Name(),
+				scope.Version(),
+				scopeAttrs,
+				scope.DroppedAttributesCount(),
+				sm.SchemaUrl(),
+				lastSeen,
+			})
+		}
+	}
+	return rows
+}
+
+// dimensionsHash はリソースとスコープの組のハッシュを返します
+// 属性はキー順に並べてからハッシュするため、属性の順序が異なっても同じ値になります
+func dimensionsHash(resourceAttrs map[string]string, resourceSchemaURL string, scope pcommon.InstrumentationScope, scopeAttrs map[string]string, scopeSchemaURL string) uint64 {
+	h := fnv.New64a()
+	writeStringMap := func(m map[string]string) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			h.Write([]byte(k + "\x00" + m[k] + "\x00"))
+		}
+		h.Write([]byte{0x01})
+	}
+	writeStringMap(resourceAttrs)
+	writeStringMap(scopeAttrs)
+	for _, s := range []string{resourceSchemaURL, scope.Name(), scope.Version(), scopeSchemaURL} {
+		h.Write([]byte(s + "\x00"))
+	}
+	return h.Sum64()
+}
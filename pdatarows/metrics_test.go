@@ -0,0 +1,228 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pdatarows
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+var testMetricTime = testStart.Add(10 * time.Second)
+
+// testMetrics は種類ごとに1つのデータポイントを持つメトリクスを返します
+func testMetrics() pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.SetSchemaUrl("https://opentelemetry.io/schemas/1.26.0")
+	rm.Resource().Attributes().PutStr("service.name", "checkout")
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.SetSchemaUrl("https://opentelemetry.io/schemas/1.25.0")
+	sm.Scope().SetName("otelhttp")
+	sm.Scope().SetVersion("0.49.0")
+	sm.Scope().Attributes().PutStr("library.language", "go")
+
+	setPoint := func(attrs pcommon.Map, start, ts func(pcommon.Timestamp)) {
+		attrs.PutStr("http.route", "/cart")
+		start(pcommon.NewTimestampFromTime(testStart))
+		ts(pcommon.NewTimestampFromTime(testMetricTime))
+	}
+
+	gauge := sm.Metrics().AppendEmpty()
+	gauge.SetName("queue.depth")
+	gdp := gauge.SetEmptyGauge().DataPoints().AppendEmpty()
+	setPoint(gdp.Attributes(), gdp.SetStartTimestamp, gdp.SetTimestamp)
+	gdp.SetIntValue(7)
+
+	sum := sm.Metrics().AppendEmpty()
+	sum.SetName("http.server.requests")
+	sum.SetDescription("リクエスト数")
+	sum.SetUnit("{request}")
+	s := sum.SetEmptySum()
+	s.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	s.SetIsMonotonic(true)
+	sdp := s.DataPoints().AppendEmpty()
+	setPoint(sdp.Attributes(), sdp.SetStartTimestamp, sdp.SetTimestamp)
+	sdp.SetDoubleValue(12.5)
+	ex := sdp.Exemplars().AppendEmpty()
+	ex.SetTimestamp(pcommon.NewTimestampFromTime(testStart))
+	ex.SetIntValue(3)
+	ex.SetTraceID(testTraceID)
+	ex.SetSpanID(testSpanID)
+	ex.FilteredAttributes().PutStr("user.id", "u-42")
+
+	histogram := sm.Metrics().AppendEmpty()
+	histogram.SetName("http.server.duration")
+	h := histogram.SetEmptyHistogram()
+	h.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	hdp := h.DataPoints().AppendEmpty()
+	setPoint(hdp.Attributes(), hdp.SetStartTimestamp, hdp.SetTimestamp)
+	hdp.SetCount(4)
+	hdp.SetSum(1.5)
+	hdp.SetMin(0.1)
+	hdp.SetMax(0.9)
+	hdp.BucketCounts().FromRaw([]uint64{1, 3})
+	hdp.ExplicitBounds().FromRaw([]float64{0.5})
+
+	exponential := sm.Metrics().AppendEmpty()
+	exponential.SetName("http.client.duration")
+	e := exponential.SetEmptyExponentialHistogram()
+	e.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	edp := e.DataPoints().AppendEmpty()
+	setPoint(edp.Attributes(), edp.SetStartTimestamp, edp.SetTimestamp)
+	edp.SetCount(3)
+	edp.SetScale(2)
+	edp.SetZeroCount(1)
+	edp.Positive().SetOffset(-1)
+	edp.Positive().BucketCounts().FromRaw([]uint64{2})
+
+	summary := sm.Metrics().AppendEmpty()
+	summary.SetName("rpc.latency")
+	qdp := summary.SetEmptySummary().DataPoints().AppendEmpty()
+	setPoint(qdp.Attributes(), qdp.SetStartTimestamp, qdp.SetTimestamp)
+	qdp.SetCount(10)
+	q := qdp.QuantileValues().AppendEmpty()
+	q.SetQuantile(0.99)
+	q.SetValue(0.25)
+	return md
+}
+
+func TestConverterMetrics(t *testing.T) {
+	common := map[string]any{
+		"ResourceAttributes":    map[string]string{"service.name": "checkout"},
+		"ResourceSchemaUrl":     "https://opentelemetry.io/schemas/1.26.0",
+		"ScopeName":             "otelhttp",
+		"ScopeVersion":          "0.49.0",
+		"ScopeAttributes":       map[string]string{"library.language": "go"},
+		"ScopeDroppedAttrCount": uint32(0),
+		"ScopeSchemaUrl":        "https://opentelemetry.io/schemas/1.25.0",
+		"ServiceName":           "checkout",
+		"Attributes":            map[string]string{"http.route": "/cart"},
+		"StartTimeUnix":         testStart,
+		"TimeUnix":              testMetricTime,
+	}
+	tests := []struct {
+		metricType pmetric.MetricType
+		want       map[string]any
+	}{
+		{
+			metricType: pmetric.MetricTypeGauge,
+			want: map[string]any{
+				"MetricName":             "queue.depth",
+				"Value":                  float64(7),
+				"AggregationTemporality": int32(pmetric.AggregationTemporalityUnspecified),
+				"IsMonotonic":            false,
+				"Exemplars.Value":        []float64{},
+			},
+		},
+		{
+			metricType: pmetric.MetricTypeSum,
+			want: map[string]any{
+				"MetricName":                   "http.server.requests",
+				"MetricDescription":            "リクエスト数",
+				"MetricUnit":                   "{request}",
+				"Value":                        12.5,
+				"Flags":                        uint32(0),
+				"AggregationTemporality":       int32(pmetric.AggregationTemporalityDelta),
+				"IsMonotonic":                  true,
+				"Exemplars.FilteredAttributes": []map[string]string{{"user.id": "u-42"}},
+				"Exemplars.TimeUnix":           []time.Time{testStart},
+				"Exemplars.Value":              []float64{3},
+				"Exemplars.SpanId":             []string{"1112131415161718"},
+				"Exemplars.TraceId":            []string{"0102030405060708090a0b0c0d0e0f10"},
+			},
+		},
+		{
+			metricType: pmetric.MetricTypeHistogram,
+			want: map[string]any{
+				"MetricName":             "http.server.duration",
+				"Count":                  uint64(4),
+				"Sum":                    1.5,
+				"Min":                    0.1,
+				"Max":                    0.9,
+				"BucketCounts":           []uint64{1, 3},
+				"ExplicitBounds":         []float64{0.5},
+				"AggregationTemporality": int32(pmetric.AggregationTemporalityCumulative),
+			},
+		},
+		{
+			metricType: pmetric.MetricTypeExponentialHistogram,
+			want: map[string]any{
+				"MetricName":             "http.client.duration",
+				"Count":                  uint64(3),
+				"Scale":                  int32(2),
+				"ZeroCount":              uint64(1),
+				"PositiveOffset":         int32(-1),
+				"PositiveBucketCounts":   []uint64{2},
+				"NegativeBucketCounts":   []uint64(nil),
+				"AggregationTemporality": int32(pmetric.AggregationTemporalityDelta),
+			},
+		},
+		{
+			metricType: pmetric.MetricTypeSummary,
+			want: map[string]any{
+				"MetricName":                "rpc.latency",
+				"Count":                     uint64(10),
+				"ValueAtQuantiles.Quantile": []float64{0.99},
+				"ValueAtQuantiles.Value":    []float64{0.25},
+			},
+		},
+	}
+	conv := NewConverter(Options{})
+	rows := conv.Metrics(testMetrics())
+	for _, tt := range tests {
+		t.Run(tt.metricType.String(), func(t *testing.T) {
+			if len(rows[tt.metricType]) != 1 {
+				t.Fatalf("行数 = %d, want 1", len(rows[tt.metricType]))
+			}
+			values := columnValues(t, conv.MetricColumns(tt.metricType), rows[tt.metricType][0])
+			for _, want := range []map[string]any{common, tt.want} {
+				for column, want := range want {
+					if got := values[column]; !reflect.DeepEqual(got, want) {
+						t.Errorf("%s = %#v, want %#v", column, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// 種類が Empty のメトリクスは行にしない
+func TestConverterMetricsSkipsEmpty(t *testing.T) {
+	md := pmetric.NewMetrics()
+	md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetName("empty")
+	if rows := NewConverter(Options{}).Metrics(md); len(rows) != 0 {
+		t.Errorf("行 = %v, want なし", rows)
+	}
+	if columns := NewConverter(Options{}).MetricColumns(pmetric.MetricTypeEmpty); columns != nil {
+		t.Errorf("MetricColumns(Empty) = %v, want nil", columns)
+	}
+}
+
+// MetricsDimensions のデータポイントの行は、リソース・スコープの列の代わりにディメンションの行と同じハッシュを持つ
+func TestConverterMetricsDimensionsHash(t *testing.T) {
+	for _, omitScope := range []bool{false, true} {
+		opts := Options{MetricsDimensions: true, OmitMetricsScope: omitScope}
+		conv := NewConverter(opts)
+		md := testMetrics()
+		dimensions := conv.MetricsDimensions(md, testStart)
+		if len(dimensions) != 1 {
+			t.Fatalf("ディメンションの行数 = %d, want 1", len(dimensions))
+		}
+		for metricType, rows := range conv.Metrics(md) {
+			columns := conv.MetricColumns(metricType)
+			if columns[0] != "DimensionsHash" || slices.Contains(columns, "ResourceAttributes") || slices.Contains(columns, "ScopeName") {
+				t.Errorf("OmitMetricsScope=%v の %s の列 = %v", omitScope, metricType, columns)
+			}
+			values := columnValues(t, columns, rows[0])
+			if got, want := values["DimensionsHash"], dimensions[0][0]; got != want {
+				t.Errorf("OmitMetricsScope=%v の %s の DimensionsHash = %v, want %v", omitScope, metricType, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pdatarows
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// ProfileColumns は Profiles が返す行の列順です（profiles_insert.sql の列順）
+var ProfileColumns = []string{
+	"Timestamp", "Duration", "ProfileId", "ServiceName",
+	"ResourceAttributes", "ResourceSchemaUrl", "ScopeName", "ScopeVersion", "ScopeAttributes", "ScopeSchemaUrl",
+	"PeriodType", "Period", "SampleCount", "DroppedAttributesCount", "OriginalPayloadFormat", "OriginalPayload",
+}
+
+// Profiles はプロファイルデータをプロファイルごとに1行、ProfileColumns の列順の行に変換します
+// サンプリング周期の種類（PeriodType）はプロファイル辞書の文字列テーブルから「種類/単位」の形式で求めます
+// プロファイルテーブルの属性カラムは常に Map 型のため、JSONAttributes は使用しません
+func (c *Converter) Profiles(pd pprofile.Profiles) [][]any {
+	var rows [][]any
+	stringTable := pd.ProfilesDictionary().StringTable()
+	for _, rp := range pd.ResourceProfiles().All() {
+		res := rp.Resource()
+		resourceAttrs := c.toMap(c.resourceAttributes(res))
+		serviceName := resourceString(res, "service.name")
+		for _, sp := range rp.ScopeProfiles().All() {
+			scope := sp.Scope()
+			scopeAttrs := map[string]string{}
+			if !c.opts.OmitScopeAttributes {
+				scopeAttrs = c.toMap(scope.Attributes())
+			}
+			for _, profile := range sp.Profiles().All() {
+				rows = append(rows, []any{
+					profile.Time().AsTime(), uint64(profile.Duration()), profile.ProfileID().String(), serviceName,
+					resourceAttrs, rp.SchemaUrl(), scope.Name(), scope.Version(), scopeAttrs, sp.SchemaUrl(),
+					periodType(stringTable, profile.PeriodType()), profile.Period(), uint64(profile.Sample().Len()),
+					profile.DroppedAttributesCount(), profile.OriginalPayloadFormat(), string(profile.OriginalPayload().AsRaw()),
+				})
+			}
+		}
+	}
+	return rows
+}
+
+// periodType はサンプリング周期の種類を「種類/単位」（例: cpu/nanoseconds）の形式で返します（単位がない場合は種類のみ）
+func periodType(table pcommon.StringSlice, vt pprofile.ValueType) string {
+	typ, unit := lookupString(table, vt.TypeStrindex()), lookupString(table, vt.UnitStrindex())
+	if unit == "" {
+		return typ
+	}
+	return typ + "/" + unit
+}
+
+// lookupString は文字列テーブルからインデックスに対応する文字列を返します（範囲外の場合は空）
+func lookupString(table pcommon.StringSlice, idx int32) string {
+	if idx < 0 || int(idx) >= table.Len() {
+		return ""
+	}
+	return table.At(int(idx))
+}
@@ -0,0 +1,66 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pdatarows
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+)
+
+// testProfiles はサンプルを2つ持つCPUプロファイルを1つ含むプロファイルを返します
+func testProfiles() pprofile.Profiles {
+	pd := pprofile.NewProfiles()
+	pd.ProfilesDictionary().StringTable().Append("", "cpu", "nanoseconds")
+	rp := pd.ResourceProfiles().AppendEmpty()
+	rp.SetSchemaUrl("https://opentelemetry.io/schemas/1.26.0")
+	rp.Resource().Attributes().PutStr("service.name", "checkout")
+	sp := rp.ScopeProfiles().AppendEmpty()
+	sp.Scope().SetName("ebpf-profiler")
+	sp.Scope().SetVersion("0.0.1")
+
+	profile := sp.Profiles().AppendEmpty()
+	profile.SetProfileID(pprofile.ProfileID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}))
+	profile.SetTime(pcommon.NewTimestampFromTime(testStart))
+	profile.SetDuration(pcommon.Timestamp(10 * time.Second))
+	profile.PeriodType().SetTypeStrindex(1)
+	profile.PeriodType().SetUnitStrindex(2)
+	profile.SetPeriod(50_000_000)
+	profile.SetOriginalPayloadFormat("pprof")
+	profile.OriginalPayload().FromRaw([]byte("payload"))
+	profile.Sample().AppendEmpty()
+	profile.Sample().AppendEmpty()
+	return pd
+}
+
+func TestConverterProfiles(t *testing.T) {
+	rows := NewConverter(Options{}).Profiles(testProfiles())
+	if len(rows) != 1 {
+		t.Fatalf("行数 = %d, want 1", len(rows))
+	}
+	values := columnValues(t, ProfileColumns, rows[0])
+	want := map[string]any{
+		"Timestamp":             testStart,
+		"Duration":              uint64(10 * time.Second),
+		"ProfileId":             "0102030405060708090a0b0c0d0e0f10",
+		"ServiceName":           "checkout",
+		"ResourceAttributes":    map[string]string{"service.name": "checkout"},
+		"ResourceSchemaUrl":     "https://opentelemetry.io/schemas/1.26.0",
+		"ScopeName":             "ebpf-profiler",
+		"ScopeVersion":          "0.0.1",
+		"PeriodType":            "cpu/nanoseconds",
+		"Period":                int64(50_000_000),
+		"SampleCount":           uint64(2),
+		"OriginalPayloadFormat": "pprof",
+		"OriginalPayload":       "payload",
+	}
+	for column, want := range want {
+		if got := values[column]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %#v, want %#v", column, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pdatarows
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// TraceColumns は Traces が返す行の列順です（traces_insert.sql の列順）
+var TraceColumns = []string{
+	"Timestamp", "TraceId", "SpanId", "ParentSpanId", "TraceState", "SpanName", "SpanKind",
+	"ServiceName", "ResourceAttributes", "ScopeName", "ScopeVersion", "SpanAttributes",
+	"Duration", "StatusCode", "StatusMessage",
+	"Events.Timestamp", "Events.Name", "Events.Attributes",
+	"Links.TraceId", "Links.SpanId", "Links.TraceState", "Links.Attributes",
+}
+
+// TraceScopeColumns は Options.TraceScope を指定した場合に TraceColumns の後に追加される列です
+var TraceScopeColumns = []string{"ResourceSchemaUrl", "ScopeAttributes", "ScopeSchemaUrl"}
+
+// Traces はトレースデータをスパンごとに1行、TraceColumns の列順の行に変換します
+// Timestamp はスパンの開始時刻です（TimestampAttribute を指定した場合はその属性の時刻、Duration は変わらない）
+func (c *Converter) Traces(td ptrace.Traces) ([][]any, error) {
+	rows := make([][]any, 0, td.SpanCount())
+	c.beginRows()
+	for _, rs := range td.ResourceSpans().All() {
+		c.truncated = false
+		resAttrs, err := c.resourceValue(rs.Resource(), c.opts.JSONAttributes)
+		if err != nil {
+			return nil, err
+		}
+		resTruncated := c.truncated
+		serviceName := resourceString(rs.Resource(), "service.name")
+
+		for _, ss := range rs.ScopeSpans().All() {
+			scope := ss.Scope()
+			c.truncated = resTruncated
+			var scopeAttrs any
+			if c.opts.TraceScope {
+				if scopeAttrs, err = c.scopeValue(scope, c.opts.JSONAttributes); err != nil {
+					return nil, err
+				}
+			}
+			scopeTruncated := c.truncated
+
+			for _, span := range ss.Spans().All() {
+				c.truncated = scopeTruncated
+				spanAttrs, err := c.value(span.Attributes(), c.opts.JSONAttributes)
+				if err != nil {
+					return nil, err
+				}
+				eventTimes, eventNames, eventAttrs := c.events(span.Events())
+				linkTraceIDs, linkSpanIDs, linkStates, linkAttrs := c.links(span.Links())
+
+				name := span.Name()
+				if c.opts.SpanName != nil {
+					name = c.opts.SpanName(serviceName, name)
+				}
+				row := []any{
+					c.timestamp(span.Attributes(), span.StartTimestamp()).AsTime(),
+					span.TraceID().String(),
+					span.SpanID().String(),
+					span.ParentSpanID().String(),
+					span.TraceState().AsRaw(),
+					name,
+					span.Kind().String(),
+					serviceName,
+					resAttrs,
+					scope.Name(),
+					scope.Version(),
+					spanAttrs,
+					uint64(span.EndTimestamp() - span.StartTimestamp()),
+					span.Status().Code().String(),
+					span.Status().Message(),
+					eventTimes,
+					eventNames,
+					eventAttrs,
+					linkTraceIDs,
+					linkSpanIDs,
+					linkStates,
+					linkAttrs,
+				}
+				if c.opts.TraceScope {
+					row = append(row, rs.SchemaUrl(), scopeAttrs, ss.SchemaUrl())
+				}
+				rows = append(rows, row)
+				c.endRow()
+			}
+		}
+	}
+	return rows, nil
+}
+
+// events はスパンイベントを Events Nested カラムの配列に変換します
+// ネストしたイベント属性は JSONAttributes に関わらず Map 型で保存します
+func (c *Converter) events(events ptrace.SpanEventSlice) ([]time.Time, []string, []map[string]string) {
+	n := events.Len()
+	if !c.opts.StoreEvents {
+		n = 0
+	}
+	times := make([]time.Time, 0, n)
+	names := make([]string, 0, n)
+	attrs := make([]map[string]string, 0, n)
+	for i := 0; i < n; i++ {
+		event := events.At(i)
+		times = append(times, event.Timestamp().AsTime())
+		names = append(names, event.Name())
+		attrs = append(attrs, c.toMap(event.Attributes()))
+	}
+	return times, names, attrs
+}
+
+// links はスパンリンクを Links Nested カラムの配列に変換します
+func (c *Converter) links(links ptrace.SpanLinkSlice) ([]string, []string, []string, []map[string]string) {
+	n := links.Len()
+	if !c.opts.StoreLinks {
+		n = 0
+	}
+	traceIDs := make([]string, 0, n)
+	spanIDs := make([]string, 0, n)
+	states := make([]string, 0, n)
+	attrs := make([]map[string]string, 0, n)
+	for i := 0; i < n; i++ {
+		link := links.At(i)
+		traceIDs = append(traceIDs, link.TraceID().String())
+		spanIDs = append(spanIDs, link.SpanID().String())
+		states = append(states, link.TraceState().AsRaw())
+		attrs = append(attrs, c.toMap(link.Attributes()))
+	}
+	return traceIDs, spanIDs, states, attrs
+}
@@ -0,0 +1,263 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pdatarows
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+var (
+	testTraceID = pcommon.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+	testSpanID  = pcommon.SpanID{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+	testStart   = time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+)
+
+// testTraces はイベント・リンクを持つスパンを1つ含むトレースを返します
+func testTraces() ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.SetSchemaUrl("https://opentelemetry.io/schemas/1.26.0")
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	rs.Resource().Attributes().PutStr("host.name", "node-1")
+	ss := rs.ScopeSpans().AppendEmpty()
+	ss.SetSchemaUrl("https://opentelemetry.io/schemas/1.25.0")
+	ss.Scope().SetName("otelhttp")
+	ss.Scope().SetVersion("0.49.0")
+	ss.Scope().Attributes().PutStr("library.language", "go")
+
+	span := ss.Spans().AppendEmpty()
+	span.SetTraceID(testTraceID)
+	span.SetSpanID(testSpanID)
+	span.SetParentSpanID(pcommon.SpanID{0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28})
+	span.TraceState().FromRaw("vendor=a")
+	span.SetName("GET /cart")
+	span.SetKind(ptrace.SpanKindServer)
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(testStart))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(testStart.Add(250 * time.Millisecond)))
+	span.Status().SetCode(ptrace.StatusCodeError)
+	span.Status().SetMessage("upstream timeout")
+	span.Attributes().PutStr("http.method", "GET")
+	span.Attributes().PutInt("http.status_code", 504)
+
+	event := span.Events().AppendEmpty()
+	event.SetName("exception")
+	event.SetTimestamp(pcommon.NewTimestampFromTime(testStart.Add(200 * time.Millisecond)))
+	event.Attributes().PutStr("exception.type", "TimeoutError")
+
+	link := span.Links().AppendEmpty()
+	link.SetTraceID(pcommon.TraceID{0xff})
+	link.SetSpanID(pcommon.SpanID{0xee})
+	link.TraceState().FromRaw("vendor=b")
+	link.Attributes().PutStr("link.kind", "follows_from")
+	return td
+}
+
+// columnValues は行の値を列名で引けるようにします
+func columnValues(t *testing.T, columns []string, row []any) map[string]any {
+	t.Helper()
+	if len(row) != len(columns) {
+		t.Fatalf("行の値の数 = %d, want %d（%v）", len(row), len(columns), columns)
+	}
+	values := make(map[string]any, len(columns))
+	for i, column := range columns {
+		values[column] = row[i]
+	}
+	return values
+}
+
+func TestConverterTraces(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		modify  func(ptrace.Span)
+		columns []string
+		want    map[string]any
+	}{
+		{
+			name: "ID・時刻・状態",
+			opts: Options{StoreEvents: true, StoreLinks: true},
+			want: map[string]any{
+				"Timestamp":     testStart,
+				"TraceId":       "0102030405060708090a0b0c0d0e0f10",
+				"SpanId":        "1112131415161718",
+				"ParentSpanId":  "2122232425262728",
+				"TraceState":    "vendor=a",
+				"SpanName":      "GET /cart",
+				"SpanKind":      "Server",
+				"ServiceName":   "checkout",
+				"ScopeName":     "otelhttp",
+				"ScopeVersion":  "0.49.0",
+				"Duration":      uint64(250 * time.Millisecond),
+				"StatusCode":    "Error",
+				"StatusMessage": "upstream timeout",
+			},
+		},
+		{
+			name: "ルートスパンの親スパンIDは空",
+			opts: Options{StoreEvents: true, StoreLinks: true},
+			modify: func(span ptrace.Span) {
+				span.SetParentSpanID(pcommon.NewSpanIDEmpty())
+			},
+			want: map[string]any{"ParentSpanId": ""},
+		},
+		{
+			name: "属性は Map",
+			opts: Options{StoreEvents: true, StoreLinks: true},
+			want: map[string]any{
+				"ResourceAttributes": map[string]string{"service.name": "checkout", "host.name": "node-1"},
+				"SpanAttributes":     map[string]string{"http.method": "GET", "http.status_code": "504"},
+			},
+		},
+		{
+			name: "attributes_format: json の属性はJSON文字列（値の型を保持）",
+			opts: Options{JSONAttributes: true, StoreEvents: true, StoreLinks: true},
+			want: map[string]any{
+				"ResourceAttributes": `{"host.name":"node-1","service.name":"checkout"}`,
+				"SpanAttributes":     `{"http.method":"GET","http.status_code":504}`,
+				// イベント・リンクの属性は常に Map
+				"Events.Attributes": []map[string]string{{"exception.type": "TimeoutError"}},
+			},
+		},
+		{
+			name: "保存するリソース属性の選択",
+			opts: Options{KeepResourceAttribute: func(key string) bool { return key == "service.name" }},
+			want: map[string]any{
+				"ResourceAttributes": map[string]string{"service.name": "checkout"},
+				"ServiceName":        "checkout",
+			},
+		},
+		{
+			name: "イベントとリンク",
+			opts: Options{StoreEvents: true, StoreLinks: true},
+			want: map[string]any{
+				"Events.Timestamp":  []time.Time{testStart.Add(200 * time.Millisecond)},
+				"Events.Name":       []string{"exception"},
+				"Events.Attributes": []map[string]string{{"exception.type": "TimeoutError"}},
+				"Links.TraceId":     []string{"ff000000000000000000000000000000"},
+				"Links.SpanId":      []string{"ee00000000000000"},
+				"Links.TraceState":  []string{"vendor=b"},
+				"Links.Attributes":  []map[string]string{{"link.kind": "follows_from"}},
+			},
+		},
+		{
+			name: "イベントとリンクを保存しない",
+			opts: Options{},
+			want: map[string]any{
+				"Events.Timestamp":  []time.Time{},
+				"Events.Name":       []string{},
+				"Events.Attributes": []map[string]string{},
+				"Links.TraceId":     []string{},
+				"Links.SpanId":      []string{},
+				"Links.TraceState":  []string{},
+				"Links.Attributes":  []map[string]string{},
+			},
+		},
+		{
+			name: "スパン名の正規化",
+			opts: Options{SpanName: func(service, name string) string { return service + ":" + name }},
+			want: map[string]any{"SpanName": "checkout:GET /cart"},
+		},
+		{
+			name: "タイムスタンプ属性（RFC 3339）",
+			opts: Options{TimestampAttribute: "event.time"},
+			modify: func(span ptrace.Span) {
+				span.Attributes().PutStr("event.time", "2023-01-02T03:04:05.5Z")
+			},
+			want: map[string]any{
+				"Timestamp": time.Date(2023, 1, 2, 3, 4, 5, 500000000, time.UTC),
+				"Duration":  uint64(250 * time.Millisecond),
+			},
+		},
+		{
+			name: "タイムスタンプ属性（Unix ナノ秒）",
+			opts: Options{TimestampAttribute: "event.time"},
+			modify: func(span ptrace.Span) {
+				span.Attributes().PutInt("event.time", testStart.Add(-time.Hour).UnixNano())
+			},
+			want: map[string]any{"Timestamp": testStart.Add(-time.Hour)},
+		},
+		{
+			name: "解釈できないタイムスタンプ属性は開始時刻",
+			opts: Options{TimestampAttribute: "event.time"},
+			modify: func(span ptrace.Span) {
+				span.Attributes().PutStr("event.time", "yesterday")
+			},
+			want: map[string]any{"Timestamp": testStart},
+		},
+		{
+			name:    "スキーマURLとスコープ属性",
+			opts:    Options{TraceScope: true},
+			columns: slices.Concat(TraceColumns, TraceScopeColumns),
+			want: map[string]any{
+				"ResourceSchemaUrl": "https://opentelemetry.io/schemas/1.26.0",
+				"ScopeAttributes":   map[string]string{"library.language": "go"},
+				"ScopeSchemaUrl":    "https://opentelemetry.io/schemas/1.25.0",
+			},
+		},
+		{
+			name:    "スコープ属性を保存しない",
+			opts:    Options{TraceScope: true, OmitScopeAttributes: true},
+			columns: slices.Concat(TraceColumns, TraceScopeColumns),
+			want:    map[string]any{"ScopeAttributes": map[string]string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := testTraces()
+			if tt.modify != nil {
+				tt.modify(td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0))
+			}
+			rows, err := NewConverter(tt.opts).Traces(td)
+			if err != nil {
+				t.Fatalf("Traces: %v", err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("行数 = %d, want 1", len(rows))
+			}
+			columns := tt.columns
+			if columns == nil {
+				columns = TraceColumns
+			}
+			values := columnValues(t, columns, rows[0])
+			for column, want := range tt.want {
+				if got := values[column]; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", column, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConverterTracesAttributeLimits(t *testing.T) {
+	td := testTraces()
+	span := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	span.Attributes().PutStr("db.statement", "SELECT * FROM orders WHERE id = 1")
+	span.Attributes().PutStr("a.very.long.attribute.key.that.exceeds.the.limit", "x")
+
+	conv := NewConverter(Options{MaxAttributeKeyLength: 2 * AttributeKeyHashLength, MaxAttributeValueLength: 6})
+	rows, err := conv.Traces(td)
+	if err != nil {
+		t.Fatalf("Traces: %v", err)
+	}
+	attrs := columnValues(t, TraceColumns, rows[0])["SpanAttributes"].(map[string]string)
+	if got := attrs["db.statement"]; got != "SELECT" {
+		t.Errorf("db.statement = %q, want %q", got, "SELECT")
+	}
+	if _, ok := attrs["a.very.long.attribute.key.that.exceeds.the.limit"]; ok {
+		t.Error("最大長を超えた属性キーが短縮されていません")
+	}
+	if conv.TruncatedKeys() != 1 || !slices.Equal(conv.TruncatedRows(), []bool{true}) {
+		t.Errorf("TruncatedKeys = %d, TruncatedRows = %v", conv.TruncatedKeys(), conv.TruncatedRows())
+	}
+	// 受信データは変更しない
+	if v, _ := span.Attributes().Get("db.statement"); v.Str() != "SELECT * FROM orders WHERE id = 1" {
+		t.Errorf("受信データが変更されました: %s", v.Str())
+	}
+}
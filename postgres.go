@@ -0,0 +1,265 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/internal/sqltemplates"
+
+	// PostgreSQL driver（driver: postgres の場合に使用）
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// 保存先のデータベース（driver）
+const (
+	driverClickHouse = "clickhouse" // ClickHouse（既定）
+	driverPostgres   = "postgres"   // PostgreSQL / TimescaleDB
+)
+
+// postgresDriverName は database/sql に登録されたPostgreSQLドライバー名です
+const postgresDriverName = "pgx"
+
+// PostgresConfig - driver: postgres の場合のPostgreSQL（TimescaleDB）固有の設定
+// database, traces_database などはPostgreSQLのスキーマとして扱い、接続先のデータベースは endpoint のパスで指定します
+// 例: endpoint: postgres://otel@timescaledb:5432/telemetry
+type PostgresConfig struct {
+	// Hypertables はトレース・ログテーブルをTimescaleDBのハイパーテーブルに変換します（timescaledb 拡張が必要）
+	Hypertables bool `mapstructure:"hypertables"`
+	// ChunkInterval はハイパーテーブルのチャンク（時間パーティション）の幅です
+	ChunkInterval time.Duration `mapstructure:"chunk_interval"`
+}
+
+// validatePostgres は driver: postgres で使用できない（ClickHouse固有の）設定を検証します
+func (cfg *Config) validatePostgres() error {
+	var errs error
+	if cfg.Endpoint != "" {
+		if u, err := url.Parse(cfg.Endpoint); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			errs = errors.Join(errs, fmt.Errorf("driver: postgres の endpoint は postgres://host:port/database 形式で指定してください: %s", cfg.Endpoint))
+		}
+	}
+	if cfg.Postgres.Hypertables && cfg.Postgres.ChunkInterval <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("postgres.chunk_interval は0より大きい必要があります: %s", cfg.Postgres.ChunkInterval))
+	}
+	for _, unsupported := range []struct {
+		name string
+		set  bool
+	}{
+		{"cluster_name", cfg.ClusterName != ""},
+		{"database_engine", cfg.DatabaseEngine != ""},
+		{"replication", cfg.Replication.Enabled || cfg.Replication.Distributed},
+		{"multi_tenancy.row_policies", cfg.MultiTenancy.rowPoliciesEnabled()},
+		{"soft_delete", cfg.SoftDelete.Enabled},
+		{"column_ttl", len(renderColumnTTLSQL(cfg, "")) > 0},
+	} {
+		if unsupported.set {
+			errs = errors.Join(errs, fmt.Errorf("%s は driver: postgres では使用できません", unsupported.name))
+		}
+	}
+	return errs
+}
+
+// isPostgres - 保存先がPostgreSQLかどうかを判定します
+func (cfg *Config) isPostgres() bool {
+	return cfg.Driver == driverPostgres
+}
+
+// sqlDriverName - database/sql のドライバー名を返します
+func (cfg *Config) sqlDriverName() string {
+	if cfg.isPostgres() {
+		return postgresDriverName
+	}
+	return driverName
+}
+
+// buildPostgresDSN はPostgreSQLの接続文字列を生成します
+// ClickHouseと異なりシグナルごとのデータベースはスキーマで表すため、接続先は常に endpoint のデータベースです
+func buildPostgresDSN(cfg *Config) (string, error) {
+	if cfg.Endpoint == "" {
+		return "", fmt.Errorf("endpoint must be specified")
+	}
+	dsnURL, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint format: %w", err)
+	}
+
+	queryParams := dsnURL.Query()
+	for k, v := range cfg.ConnectionParams {
+		queryParams.Set(k, v)
+	}
+	if cfg.Username != "" {
+		dsnURL.User = url.UserPassword(cfg.Username, string(cfg.Password))
+	}
+	dsnURL.RawQuery = queryParams.Encode()
+	return dsnURL.String(), nil
+}
+
+// insertTemplate - 保存先に応じたシグナルの既定の挿入SQLテンプレートを返します
+func (cfg *Config) insertTemplate(signal string) string {
+	switch {
+	case signal == "traces" && cfg.isPostgres():
+		return sqltemplates.PostgresTracesInsert
+	case signal == "traces":
+		return sqltemplates.TracesInsert
+	case cfg.isPostgres():
+		return sqltemplates.PostgresLogsInsert
+	default:
+		return sqltemplates.LogsInsert
+	}
+}
+
+// renderCreateSchemaSQL - PostgreSQLのスキーマ作成SQLを生成します
+func renderCreateSchemaSQL(schema string) string {
+	return fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS "%s"`, schema)
+}
+
+// postgresTable はPostgreSQLバックエンドで作成するテーブルです
+type postgresTable struct {
+	signal     string
+	template   string
+	schema     string
+	table      string
+	retention  time.Duration // 保持期間（0の場合は無期限）
+	timeColumn string        // ハイパーテーブルの時間列（インデックスを作成する時刻列）
+	indexes    []string      // 追加で作成するインデックスの対象列
+}
+
+// postgresTables はシグナルごとに作成するテーブルを返します（メトリクス・プロファイルは未対応）
+func postgresTables(cfg *Config) []postgresTable {
+	le := &logsExporter{config: cfg}
+	return []postgresTable{
+		{"traces", sqltemplates.PostgresTracesCreateTable, cfg.tracesDatabase(), cfg.TracesTableName, cfg.signalTTL("traces"),
+			"Timestamp", []string{"TraceId", "ServiceName"}},
+		{"logs", sqltemplates.PostgresLogsCreateTable, cfg.logsDatabase(), le.getLogsTableName(), cfg.signalTTL("logs"),
+			cfg.logsTimeColumn(), []string{"TraceId", "ServiceName"}},
+	}
+}
+
+// renderPostgresSchemaDDL はPostgreSQLバックエンドのDDL（スキーマ・テーブル・インデックス・ハイパーテーブル）を実行順に返します
+// signal を指定した場合はそのシグナルのテーブルのみを対象とします
+func renderPostgresSchemaDDL(cfg *Config, signal string) ([]SchemaStatement, error) {
+	var stmts []SchemaStatement
+	for _, t := range postgresTables(cfg) {
+		if signal != "" && t.signal != signal {
+			continue
+		}
+		stmts = append(stmts, SchemaStatement{"schema " + t.schema, renderCreateSchemaSQL(t.schema)})
+
+		query, err := internal.ExecuteSQLTemplate("postgres "+t.signal+" table", t.template, internal.TableTemplateData{
+			Database:      t.schema,
+			Table:         t.table,
+			SourceColumns: cfg.SourceColumns.Enabled,
+		})
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, SchemaStatement{t.signal + " table", query})
+
+		// ハイパーテーブルでは時間列のインデックスが自動で作成される
+		indexes := t.indexes
+		if !cfg.Postgres.Hypertables {
+			indexes = append([]string{t.timeColumn}, indexes...)
+		}
+		for _, column := range indexes {
+			stmts = append(stmts, SchemaStatement{t.signal + " index " + column, fmt.Sprintf(
+				`CREATE INDEX IF NOT EXISTS "%s_%s_idx" ON "%s"."%s" ("%s")`, t.table, strings.ToLower(column), t.schema, t.table, column)})
+		}
+
+		if !cfg.Postgres.Hypertables {
+			continue
+		}
+		qualified := quoteString(fmt.Sprintf(`"%s"."%s"`, t.schema, t.table))
+		stmts = append(stmts, SchemaStatement{t.signal + " hypertable", fmt.Sprintf(
+			`SELECT create_hypertable(%s, '%s', chunk_time_interval => INTERVAL '%s', if_not_exists => TRUE, migrate_data => TRUE)`,
+			qualified, t.timeColumn, postgresInterval(cfg.Postgres.ChunkInterval))})
+		if t.retention > 0 {
+			stmts = append(stmts, SchemaStatement{t.signal + " retention policy", fmt.Sprintf(
+				`SELECT add_retention_policy(%s, INTERVAL '%s', if_not_exists => TRUE)`, qualified, postgresInterval(t.retention))})
+		}
+	}
+	return stmts, nil
+}
+
+// postgresInterval は期間をPostgreSQLの INTERVAL リテラル（秒単位）に変換します
+func postgresInterval(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10) + " seconds"
+}
+
+// createPostgresTables はシグナルのスキーマとテーブルをPostgreSQLに作成します
+func createPostgresTables(ctx context.Context, cfg *Config, db *sql.DB, signal string, logger *zap.Logger) error {
+	stmts, err := renderPostgresSchemaDDL(cfg, signal)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		logger.Debug("SQL実行開始", zap.String("description", stmt.Description), zap.String("sql", stmt.SQL))
+		if _, err := db.ExecContext(ctx, stmt.SQL); err != nil {
+			return fmt.Errorf("%s の作成に失敗しました: %w", stmt.Description, err)
+		}
+	}
+	logger.Info("PostgreSQLのテーブル作成が完了しました", zap.String("signal", signal))
+	return nil
+}
+
+// checkPostgresPermissions はスキーマ作成とデータ挿入に必要な権限をPostgreSQLの権限関数で確認します
+func checkPostgresPermissions(ctx context.Context, cfg *Config, db *sql.DB) error {
+	if cfg.shouldCreateSchema() {
+		var granted bool
+		if err := db.QueryRowContext(ctx, "SELECT has_database_privilege(current_database(), 'CREATE')").Scan(&granted); err != nil {
+			return fmt.Errorf("権限の確認に失敗しました (CREATE): %w", err)
+		}
+		if !granted {
+			return fmt.Errorf("接続先データベースに対する CREATE 権限がありません")
+		}
+		return nil
+	}
+
+	// スキーマを作成しない場合は既存テーブルへの挿入権限を確認
+	for _, t := range postgresTables(cfg) {
+		var granted bool
+		table := fmt.Sprintf(`"%s"."%s"`, t.schema, t.table)
+		if err := db.QueryRowContext(ctx, "SELECT has_table_privilege($1, 'INSERT')", table).Scan(&granted); err != nil {
+			return fmt.Errorf("権限の確認に失敗しました (INSERT): %w", err)
+		}
+		if !granted {
+			return fmt.Errorf("INSERT 権限がありません (table: %s)", table)
+		}
+	}
+	return nil
+}
+
+// rebindPostgres は位置指定プレースホルダー ? をPostgreSQLの $1, $2, ... に置き換えます
+// insert_sql の名前付きプレースホルダーは ? に展開されるため、PostgreSQLでは番号付きに変換します
+// 文字列リテラル・引用符で囲まれた識別子内の ? は置き換えません
+func rebindPostgres(query string) string {
+	var b strings.Builder
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,27 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// validateQueueStorage は永続キュー（sending_queue.storage）と組み合わせられない設定を検証します
+// 永続キューはバッチをストレージ拡張機能（file_storage など）に保存し、コレクターの再起動後も送信を継続します
+// 保存されるのはテレメトリデータのみで、リクエストのクライアントメタデータは再起動後に復元されません
+func (cfg *Config) validateQueueStorage() error {
+	storage := cfg.QueueSettings.StorageID
+	if storage == nil {
+		return nil
+	}
+	var errs error
+	if !cfg.QueueSettings.Enabled {
+		errs = errors.Join(errs, fmt.Errorf("sending_queue.storage（%s）を使用するには sending_queue.enabled を true にしてください", storage))
+	}
+	if cfg.SourceColumns.Enabled && cfg.SourceColumns.ReceiverMetadataKey != "" {
+		errs = errors.Join(errs, errors.New("sending_queue.storage の永続キューはクライアントメタデータを保存しないため、source_columns.receiver_metadata_key と組み合わせられません"))
+	}
+	return errs
+}
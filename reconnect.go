@@ -0,0 +1,139 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// endpointHosts は DNS で名前解決するエンドポイントのホスト名を返します（IPアドレスを指定したエンドポイントは含まない）
+func (cfg *Config) endpointHosts() []string {
+	var hosts []string
+	for _, endpoint := range cfg.endpoints() {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			continue
+		}
+		host := u.Hostname()
+		if host == "" || net.ParseIP(host) != nil || slices.Contains(hosts, host) {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// dnsWatchConnector - エンドポイントのホスト名を定期的に名前解決し、アドレスが変わった場合にアイドル接続を閉じる driver.Connector ラッパー
+// Kubernetes の Service などでフェイルオーバー後に古いアドレスへの接続を使い続けないようにします
+// 新しい接続は接続時に改めて名前解決されるため、変更後のアドレスに接続します
+// sql.DB.Close から Close が呼び出された時点で名前解決を停止します
+type dnsWatchConnector struct {
+	driver.Connector
+	hosts    []string
+	interval time.Duration
+	pool     ConnectionPoolConfig
+	resolver *net.Resolver
+	logger   *zap.Logger
+
+	addrs map[string][]string // ホストごとの前回の名前解決の結果（run のゴルーチンのみが参照する）
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newDNSWatchConnector は connector を包む dnsWatchConnector を作成します（start を呼ぶまでは名前解決しない）
+func newDNSWatchConnector(connector driver.Connector, cfg *Config, logger *zap.Logger) *dnsWatchConnector {
+	return &dnsWatchConnector{
+		Connector: connector,
+		hosts:     cfg.endpointHosts(),
+		interval:  cfg.ConnectionPool.DNSRefreshInterval,
+		pool:      cfg.ConnectionPool,
+		resolver:  net.DefaultResolver,
+		logger:    logger,
+		addrs:     map[string][]string{},
+		done:      make(chan struct{}),
+	}
+}
+
+// start は db の接続先の名前解決の監視を開始します
+func (c *dnsWatchConnector) start(db *sql.DB) {
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	go c.run(ctx, db)
+}
+
+// run は interval ごとにホスト名を名前解決し、前回と異なるアドレスが返された場合はアイドル接続を閉じます
+// 最初の名前解決の結果は比較の基準としてのみ使用します
+func (c *dnsWatchConnector) run(ctx context.Context, db *sql.DB) {
+	defer close(c.done)
+	c.resolve(ctx)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if changed := c.resolve(ctx); len(changed) > 0 {
+			c.logger.Info("エンドポイントのアドレスが変わりました、アイドル接続を閉じて接続し直します", zap.Strings("hosts", changed))
+			closeIdleConns(db, c.pool)
+		}
+	}
+}
+
+// resolve は各ホストを名前解決し、前回からアドレスが変わったホストを返します
+// 名前解決に失敗した場合（DNSの一時的な障害など）は前回の結果のまま接続を続けます
+func (c *dnsWatchConnector) resolve(ctx context.Context) []string {
+	var changed []string
+	for _, host := range c.hosts {
+		addrs, err := c.resolver.LookupHost(ctx, host)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.Debug("エンドポイントの名前解決に失敗しました、現在の接続を使い続けます", zap.String("host", host), zap.Error(err))
+			}
+			continue
+		}
+		slices.Sort(addrs)
+		previous, ok := c.addrs[host]
+		c.addrs[host] = addrs
+		if ok && !slices.Equal(previous, addrs) {
+			changed = append(changed, host)
+		}
+	}
+	return changed
+}
+
+// Close は名前解決の監視を停止し、内側の Connector を閉じます（sql.DB.Close から呼び出されます）
+func (c *dnsWatchConnector) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		if c.cancel != nil {
+			c.cancel()
+			<-c.done
+		}
+		if closer, ok := c.Connector.(io.Closer); ok {
+			err = closer.Close()
+		}
+	})
+	return err
+}
+
+// closeIdleConns は接続プールのアイドル接続をすべて閉じ、接続プールの設定を元に戻します
+// 使用中の接続は処理を中断せず、reconnect_interval（接続の最大存続時間）に従って作り直されます
+func closeIdleConns(db *sql.DB, pool ConnectionPoolConfig) {
+	db.SetMaxIdleConns(0)
+	pool.configure(db)
+}
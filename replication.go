@@ -0,0 +1,137 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/internal/sqltemplates"
+)
+
+// localTableSuffix は分散テーブル構成で各シャードに作成するローカルテーブルの接尾辞です
+const localTableSuffix = "_local"
+
+// ReplicationConfig - クラスター展開向けのレプリケーション・分散テーブル設定
+type ReplicationConfig struct {
+	// Enabled はテーブルエンジンを Replicated*MergeTree（例: ReplicatedMergeTree）に置き換えます
+	Enabled bool `mapstructure:"enabled"`
+	// ZooKeeperPath はレプリカが共有するZooKeeper（Keeper）上のパスです（{shard} 等のマクロを使用可能）
+	ZooKeeperPath string `mapstructure:"zookeeper_path"`
+	// ReplicaName はレプリカ名です（通常は {replica} マクロ）
+	ReplicaName string `mapstructure:"replica_name"`
+	// Distributed は各シャードの <table>_local テーブルを ON CLUSTER で作成し、
+	// それらを束ねる Distributed エンジンの <table> テーブルを作成します（挿入・検索は <table> に対して行う）
+	Distributed bool `mapstructure:"distributed"`
+}
+
+// validate はレプリケーション設定を検証します
+func (c ReplicationConfig) validate(cfg *Config) error {
+	var errs error
+	if c.Enabled {
+		if c.ZooKeeperPath == "" || c.ReplicaName == "" {
+			errs = errors.Join(errs, fmt.Errorf("replication.zookeeper_path と replication.replica_name を指定してください"))
+		}
+		if !strings.HasSuffix(engineName(cfg.tableEngineString()), "MergeTree") {
+			errs = errors.Join(errs, fmt.Errorf("replication.enabled は MergeTree 系のテーブルエンジンでのみ使用できます: %s", cfg.TableEngine))
+		}
+	}
+	// Replicated データベースのテーブルはデータベースが複製先を管理するため、ZooKeeperパスを指定しない
+	if c.Enabled && cfg.replicatedDatabase() {
+		errs = errors.Join(errs, fmt.Errorf("database_engine が Replicated の場合は replication.enabled を使用できません（table_engine に ReplicatedMergeTree を指定してください）"))
+	}
+	if c.Distributed && cfg.ClusterName == "" {
+		errs = errors.Join(errs, fmt.Errorf("replication.distributed を使用する場合は cluster_name が必要です"))
+	}
+	return errs
+}
+
+// shardedTables - ローカルテーブルを Replicated または分散テーブル構成で作成するかどうかを判定します
+func (cfg *Config) shardedTables() bool {
+	return cfg.Replication.Enabled || cfg.Replication.Distributed
+}
+
+// replicatedDatabase - データベースを Replicated エンジンで作成するかどうかを判定します
+func (cfg *Config) replicatedDatabase() bool {
+	return engineName(cfg.DatabaseEngine) == "Replicated"
+}
+
+// localTable - テーブルの実体（データを保持するテーブル）の名前を返します
+// 分散テーブル構成では <table>_local、それ以外は table のままです
+func (cfg *Config) localTable(table string) string {
+	if cfg.Replication.Distributed {
+		return table + localTableSuffix
+	}
+	return table
+}
+
+// replicatedEngine - レプリケーションが有効な場合に MergeTree 系エンジンを Replicated 版に変換します
+// 例: ReplacingMergeTree(End) → ReplicatedReplacingMergeTree('<path>', '<replica>', End)
+// clickhouse_cloud が有効な場合は Shared 版（SharedReplacingMergeTree(End)）に変換します
+func (cfg *Config) replicatedEngine(engine string) string {
+	if cfg.ClickHouseCloud {
+		return sharedEngine(engine)
+	}
+	if !cfg.Replication.Enabled {
+		return engine
+	}
+	name := engineName(engine)
+	if strings.HasPrefix(name, "Replicated") {
+		return engine
+	}
+	params := fmt.Sprintf("'%s', '%s'", cfg.Replication.ZooKeeperPath, cfg.Replication.ReplicaName)
+	if args := engineArgs(engine); args != "" {
+		params += ", " + args
+	}
+	return fmt.Sprintf("Replicated%s(%s)", name, params)
+}
+
+// engineName はエンジン句からエンジン名（括弧より前）を返します
+func engineName(engine string) string {
+	name, _, _ := strings.Cut(engine, "(")
+	return strings.TrimSpace(name)
+}
+
+// engineArgs はエンジン句の括弧内の引数を返します（引数がない場合は空）
+func engineArgs(engine string) string {
+	_, args, ok := strings.Cut(engine, "(")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(args), ")"))
+}
+
+// renderDistributedTableSQL - ローカルテーブルを束ねる分散テーブルの作成SQLを生成します
+func renderDistributedTableSQL(cfg *Config, database, table, localTable string) (string, error) {
+	return internal.ExecuteSQLTemplate("distributed_table.sql", sqltemplates.DistributedCreateTable, internal.TableTemplateData{
+		Database:   database,
+		Table:      table,
+		LocalTable: localTable,
+		Cluster:    cfg.clusterString(),
+		Engine:     fmt.Sprintf("Distributed('%s', '%s', '%s', rand())", cfg.ClusterName, database, localTable),
+	})
+}
+
+// createDistributedTable - 分散テーブル構成の場合に、作成済みのローカルテーブルを束ねる分散テーブルを作成します
+func createDistributedTable(ctx context.Context, cfg *Config, db *sql.DB, database, table, localTable string, logger *zap.Logger) error {
+	if !cfg.Replication.Distributed {
+		return nil
+	}
+	query, err := renderDistributedTableSQL(cfg, database, table, localTable)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("分散テーブル %s.%s の作成に失敗しました: %w", database, table, err)
+	}
+	logger.Info("分散テーブルを作成しました",
+		zap.String("database", database), zap.String("table", table), zap.String("local_table", localTable))
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// transformerColumnPattern は RowTransformer が追加する列の名前に一致します（挿入SQLにそのまま埋め込むため）
+var transformerColumnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RowTransformer は挿入直前の行を変更・拡張するフックです（TracesWriter・LogsWriter の作成時に WithRowTransformer で登録）
+// 例えば属性からコストセンターを求めて列として追加する、といった用途に使用します
+// 行の値は columns の列順で、属性列の値は attributes_format に応じて map[string]string または JSON 文字列です
+type RowTransformer interface {
+	// Columns は TransformRows が各行の末尾に追加する列の名前を返します（追加しない場合は nil）
+	// 既存のテーブルには列が追加されないため、ALTER TABLE で列を追加してから使用してください
+	Columns() []string
+	// TransformRows は行の値を変更し、Columns の列の値を各行の末尾に追加します（rows[i] = append(rows[i], ...)）
+	// エラーを返した場合はバッチを挿入しません
+	TransformRows(ctx context.Context, columns []string, rows [][]any) error
+}
+
+// nopRowTransformer は行を変更しない既定の RowTransformer です
+type nopRowTransformer struct{}
+
+func (nopRowTransformer) Columns() []string { return nil }
+
+func (nopRowTransformer) TransformRows(context.Context, []string, [][]any) error { return nil }
+
+// WriterOption は TracesWriter・LogsWriter の作成時のオプションです
+type WriterOption func(*writerOptions)
+
+// writerOptions は WriterOption を適用した結果です
+type writerOptions struct {
+	transformer RowTransformer
+}
+
+// WithRowTransformer は挿入直前の行を変換する RowTransformer を登録します
+func WithRowTransformer(t RowTransformer) WriterOption {
+	return func(o *writerOptions) {
+		o.transformer = t
+	}
+}
+
+// newWriterOptions はオプションを適用し、登録された RowTransformer がシグナルの挿入列と組み合わせられるかを検証します
+func newWriterOptions(cfg *Config, signal string, opts []WriterOption) (writerOptions, error) {
+	o := writerOptions{transformer: nopRowTransformer{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.transformer == nil {
+		o.transformer = nopRowTransformer{}
+	}
+	extra := o.transformer.Columns()
+	if len(extra) == 0 {
+		return o, nil
+	}
+
+	var errs error
+	if cfg.customInsertSQL(signal) != "" {
+		errs = errors.Join(errs, fmt.Errorf("insert_sql・column_mapping（%s）を指定した場合は RowTransformer で列を追加できません", signal))
+	}
+	columns := cfg.insertColumns(signal)
+	for i, name := range extra {
+		switch {
+		case !transformerColumnPattern.MatchString(name):
+			errs = errors.Join(errs, fmt.Errorf("RowTransformer の列名 %q が不正です", name))
+		case slices.Contains(columns, name) || slices.Contains(extra[:i], name):
+			errs = errors.Join(errs, fmt.Errorf("RowTransformer の列 %s は既に挿入列に含まれています", name))
+		}
+	}
+	return o, errs
+}
+
+// transformRows は RowTransformer で行を変換し、各行の値の数が挿入列の数と一致するかを確認します
+// columns は変換前の挿入列で、戻り値は RowTransformer が追加した列を含む挿入列です
+func transformRows(ctx context.Context, t RowTransformer, columns []string, rows [][]any) ([]string, error) {
+	if _, ok := t.(nopRowTransformer); ok {
+		return columns, nil
+	}
+	if err := t.TransformRows(ctx, columns, rows); err != nil {
+		return nil, fmt.Errorf("行の変換に失敗しました: %w", err)
+	}
+	columns = slices.Concat(columns, t.Columns())
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			// 変換の実装の誤りのため、リトライしても成功しない
+			return nil, consumererror.NewPermanent(fmt.Errorf("変換後の行 %d の値の数（%d）が挿入列の数（%d）と一致しません", i, len(row), len(columns)))
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,223 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// costCenterTransformer はサービス名から CostCenter 列を追加し、SpanName・Body を大文字に変更します
+type costCenterTransformer struct {
+	columns []string
+	err     error
+}
+
+func (t costCenterTransformer) Columns() []string { return t.columns }
+
+func (t costCenterTransformer) TransformRows(_ context.Context, columns []string, rows [][]any) error {
+	if t.err != nil {
+		return t.err
+	}
+	service := slices.Index(columns, "ServiceName")
+	for i, row := range rows {
+		for _, name := range []string{"SpanName", "Body"} {
+			if c := slices.Index(columns, name); c >= 0 {
+				row[c] = strings.ToUpper(row[c].(string))
+			}
+		}
+		for range t.columns {
+			row = append(row, "cc-"+row[service].(string))
+		}
+		rows[i] = row
+	}
+	return nil
+}
+
+// captureConfig はDBに接続せず、挿入バッチを dir にキャプチャする設定を返します
+func captureConfig(dir string) *Config {
+	cfg := DefaultConfig()
+	cfg.Capture = CaptureConfig{Directory: dir, MaxBatches: 1}
+	return cfg
+}
+
+// readCapture はテーブルの最初のキャプチャを読み込みます
+func readCapture(t *testing.T, dir, table string) capturedBatch {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, table+"-0001.json"))
+	if err != nil {
+		t.Fatalf("キャプチャの読み込みに失敗しました: %v", err)
+	}
+	var batch capturedBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		t.Fatalf("キャプチャの解析に失敗しました: %v", err)
+	}
+	return batch
+}
+
+// captureTraces は opts を指定した TracesWriter で dedupTestTraces(n) を書き込み、キャプチャを返します
+func captureTraces(t *testing.T, n int, opts ...WriterOption) capturedBatch {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := captureConfig(dir)
+	w, err := NewTracesWriter(cfg, nil, opts...)
+	if err != nil {
+		t.Fatalf("NewTracesWriter: %v", err)
+	}
+	defer func() { _ = w.Close(context.Background()) }()
+	if err := w.WriteTraces(context.Background(), dedupTestTraces(n)); err != nil {
+		t.Fatalf("WriteTraces: %v", err)
+	}
+	return readCapture(t, dir, cfg.TracesTableName)
+}
+
+func TestRowTransformerTraces(t *testing.T) {
+	base := captureTraces(t, 2)
+	columns := DefaultConfig().insertColumns("traces")
+
+	t.Run("既定では挿入SQLと行を変更しない", func(t *testing.T) {
+		for _, row := range base.Rows {
+			if len(row) != len(columns) {
+				t.Errorf("行の列数 = %d, want %d", len(row), len(columns))
+			}
+		}
+		// 列を追加せず行も変更しない RowTransformer と同じ結果になる
+		noop := captureTraces(t, 2, WithRowTransformer(nopRowTransformer{}), WithRowTransformer(nil))
+		if noop.SQL != base.SQL {
+			t.Errorf("挿入SQLが変更されました:\n%s\n---\n%s", noop.SQL, base.SQL)
+		}
+		if !reflect.DeepEqual(noop.Rows, base.Rows) {
+			t.Errorf("行が変更されました: %v", noop.Rows)
+		}
+	})
+
+	t.Run("列の追加と行の変更", func(t *testing.T) {
+		got := captureTraces(t, 2, WithRowTransformer(costCenterTransformer{columns: []string{"CostCenter"}}))
+		// 列のリストの末尾にのみ追加し、既存の列はそのまま
+		head := func(sql string) string { return sql[:strings.Index(sql, ") VALUES")] }
+		if want := strings.TrimSuffix(head(base.SQL), "\n") + ",\n    CostCenter\n"; head(got.SQL) != want {
+			t.Errorf("挿入SQLの列の末尾に CostCenter 列が追加されていません:\n%s", got.SQL)
+		}
+		if placeholders := strings.Count(got.SQL, "?"); placeholders != len(columns)+1 {
+			t.Errorf("プレースホルダーの数 = %d, want %d", placeholders, len(columns)+1)
+		}
+		for i, row := range got.Rows {
+			if row["CostCenter"] != "cc-checkout" {
+				t.Errorf("行 %d の CostCenter = %v", i, row["CostCenter"])
+			}
+			if want := strings.ToUpper(base.Rows[i]["SpanName"].(string)); row["SpanName"] != want {
+				t.Errorf("行 %d の SpanName = %v, want %s", i, row["SpanName"], want)
+			}
+			if row["TraceId"] != base.Rows[i]["TraceId"] {
+				t.Errorf("変更していない列 TraceId が変わりました: %v", row["TraceId"])
+			}
+		}
+	})
+}
+
+func TestRowTransformerLogs(t *testing.T) {
+	dir := t.TempDir()
+	cfg := captureConfig(dir)
+	w, err := NewLogsWriter(cfg, nil, WithRowTransformer(costCenterTransformer{columns: []string{"CostCenter"}}))
+	if err != nil {
+		t.Fatalf("NewLogsWriter: %v", err)
+	}
+	defer func() { _ = w.Close(context.Background()) }()
+	ld := dedupTestLogs(2)
+	if err := w.WriteLogs(context.Background(), ld); err != nil {
+		t.Fatalf("WriteLogs: %v", err)
+	}
+	// 書き込んだログは変更されない
+	if body := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str(); body != "log-0" {
+		t.Errorf("呼び出し元のログが変更されました: %s", body)
+	}
+
+	got := readCapture(t, dir, cfg.LogsTableName)
+	if !strings.Contains(got.SQL, "CostCenter") {
+		t.Errorf("挿入SQLに CostCenter 列が追加されていません:\n%s", got.SQL)
+	}
+	for i, row := range got.Rows {
+		if row["CostCenter"] != "cc-checkout" || row["Body"] != strings.ToUpper(ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(i).Body().Str()) {
+			t.Errorf("行 %d が変換されていません: CostCenter=%v Body=%v", i, row["CostCenter"], row["Body"])
+		}
+	}
+}
+
+func TestTransformRows(t *testing.T) {
+	columns := []string{"A", "B"}
+
+	t.Run("既定の RowTransformer", func(t *testing.T) {
+		rows := [][]any{{1, 2}}
+		got, err := transformRows(context.Background(), nopRowTransformer{}, columns, rows)
+		if err != nil || !slices.Equal(got, columns) || len(rows[0]) != 2 {
+			t.Errorf("transformRows = %v, %v（行: %v）", got, err, rows)
+		}
+	})
+
+	t.Run("値の数が一致しない行は恒久的なエラー", func(t *testing.T) {
+		_, err := transformRows(context.Background(), mismatchTransformer{}, columns, [][]any{{1, 2}})
+		if !consumererror.IsPermanent(err) {
+			t.Errorf("恒久的なエラーになりません: %v", err)
+		}
+	})
+
+	t.Run("変換のエラー", func(t *testing.T) {
+		want := errors.New("lookup failed")
+		_, err := transformRows(context.Background(), costCenterTransformer{columns: []string{"X"}, err: want}, columns, [][]any{{1, 2}})
+		if !errors.Is(err, want) || consumererror.IsPermanent(err) {
+			t.Errorf("transformRows のエラー = %v", err)
+		}
+	})
+}
+
+// mismatchTransformer は列を宣言して値を追加しない誤った RowTransformer です
+type mismatchTransformer struct{}
+
+func (mismatchTransformer) Columns() []string { return []string{"Missing"} }
+
+func (mismatchTransformer) TransformRows(context.Context, []string, [][]any) error { return nil }
+
+func TestNewWriterOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		config  func(*Config)
+		wantErr bool
+	}{
+		{name: "追加の列なし"},
+		{name: "追加の列", columns: []string{"CostCenter", "team_id"}},
+		{name: "不正な列名", columns: []string{"Cost Center"}, wantErr: true},
+		{name: "既存の挿入列", columns: []string{"ServiceName"}, wantErr: true},
+		{name: "重複", columns: []string{"CostCenter", "CostCenter"}, wantErr: true},
+		{
+			name:    "insert_sql との併用",
+			columns: []string{"CostCenter"},
+			config: func(cfg *Config) {
+				cfg.InsertSQL.Traces = `INSERT INTO "{{.Database}}"."{{.Table}}" (TraceId) VALUES (@TraceId)`
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.config != nil {
+				tt.config(cfg)
+			}
+			_, err := newWriterOptions(cfg, "traces", []WriterOption{WithRowTransformer(costCenterTransformer{columns: tt.columns})})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newWriterOptions のエラー = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
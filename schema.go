@@ -0,0 +1,379 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+)
+
+// SchemaStatement は実行予定のDDL文とその説明を表します
+type SchemaStatement struct {
+	Description string
+	SQL         string
+}
+
+// RenderSchemaDDL は設定値で全シグナルのDDL（データベース・テーブル・ビュー）を生成します
+// DBには接続せず、start時に実行されるのと同じSQLを実行順に返します
+func RenderSchemaDDL(cfg *Config) ([]SchemaStatement, error) {
+	if cfg.isPostgres() {
+		return renderPostgresSchemaDDL(cfg, "")
+	}
+
+	var stmts []SchemaStatement
+
+	// 接続先のデータベースはシグナルごとのデータベースをすべて上書きした場合も作成する（createDatabase と同じ）
+	databases := cfg.databases()
+	if !slices.Contains(databases, cfg.database()) {
+		databases = append([]string{cfg.database()}, databases...)
+	}
+	for _, database := range databases {
+		if database != internal.DefaultDatabase {
+			stmts = append(stmts, SchemaStatement{"database " + database, renderCreateDatabaseSQL(cfg, database)})
+		}
+	}
+
+	te := &tracesExporter{config: cfg, logger: zap.NewNop()}
+	le := &logsExporter{config: cfg, logger: zap.NewNop()}
+	pe := &profilesExporter{config: cfg, logger: zap.NewNop()}
+	me := &metricsExporter{config: cfg, logger: zap.NewNop()}
+
+	renderers := []struct {
+		description string
+		render      func() (string, error)
+	}{
+		// トレース: メインテーブル
+		{"traces table", te.renderCreateTracesTableSQL},
+		// ログ
+		{"logs table", le.renderLogsTableSQL},
+		// プロファイル
+		{"profiles table", pe.renderProfilesTableSQL},
+	}
+	// トレース: ID-タイムスタンプ検索テーブル、マテリアライズドビュー（lookup_table_enabled 有効時のみ）
+	if cfg.TraceIDLookup.LookupTableEnabled {
+		renderers = slices.Insert(renderers, 1, []struct {
+			description string
+			render      func() (string, error)
+		}{
+			{"trace ID timestamp table", te.renderCreateTraceIDTsTableSQL},
+			{"trace ID timestamp materialized view", te.renderTraceIDTsMaterializedViewSQL},
+		}...)
+	}
+	// トレース: スパンリンクテーブル、マテリアライズドビュー（traces.links_table 有効時のみ）
+	if cfg.Traces.LinksTable {
+		renderers = append(renderers, []struct {
+			description string
+			render      func() (string, error)
+		}{
+			{"trace links table", te.renderCreateTraceLinksTableSQL},
+			{"trace links materialized view", te.renderTraceLinksMaterializedViewSQL},
+		}...)
+	}
+	// トレース: ルートスパンテーブル、マテリアライズドビュー（traces.roots_table 有効時のみ）
+	if cfg.Traces.RootsTable {
+		renderers = append(renderers, []struct {
+			description string
+			render      func() (string, error)
+		}{
+			{"trace roots table", te.renderCreateTraceRootsTableSQL},
+			{"trace roots materialized view", te.renderTraceRootsMaterializedViewSQL},
+		}...)
+	}
+	if cfg.ServiceGraph.Enabled {
+		renderers = append(renderers, struct {
+			description string
+			render      func() (string, error)
+		}{"service graph table", te.renderCreateServiceGraphTableSQL})
+	}
+	// メトリクス（タイプごとのテーブル）
+	for _, table := range metricsTables {
+		renderers = append(renderers, struct {
+			description string
+			render      func() (string, error)
+		}{table.description, func() (string, error) {
+			return me.renderMetricTableSQL(table.templateFile, table.tableName)
+		}})
+	}
+	if cfg.MetricsDimensions.Enabled {
+		renderers = append(renderers, struct {
+			description string
+			render      func() (string, error)
+		}{"metrics dimensions table", me.renderMetricsDimensionsTableSQL})
+	}
+	// 集計テーブル、マテリアライズドビュー（create_aggregation_views 有効時のみ）
+	var aggregations []struct {
+		view            aggregationView
+		database, table string
+	}
+	for _, signal := range []struct{ name, database, table string }{
+		{"traces", cfg.tracesDatabase(), cfg.TracesTableName},
+		{"logs", cfg.logsDatabase(), le.getLogsTableName()},
+	} {
+		for _, v := range cfg.signalAggregationViews(signal.name) {
+			aggregations = append(aggregations, struct {
+				view            aggregationView
+				database, table string
+			}{v, signal.database, signal.table})
+		}
+	}
+	for _, a := range aggregations {
+		renderers = append(renderers, []struct {
+			description string
+			render      func() (string, error)
+		}{
+			{a.view.description + " table", func() (string, error) { return a.view.renderTableSQL(cfg, a.database, a.table) }},
+			{a.view.description + " materialized view", func() (string, error) { return a.view.renderViewSQL(cfg, a.database, a.table) }},
+		}...)
+	}
+
+	for _, r := range renderers {
+		sql, err := r.render()
+		if err != nil {
+			return nil, fmt.Errorf("%s のレンダリングに失敗しました: %w", r.description, err)
+		}
+		stmts = append(stmts, SchemaStatement{r.description, sql})
+	}
+
+	// 分散テーブル（replication.distributed 有効時のみ、ローカルテーブルの作成後に作成）
+	if cfg.Replication.Distributed {
+		facades := []struct{ database, table, local string }{
+			{cfg.tracesDatabase(), cfg.TracesTableName, cfg.localTable(cfg.TracesTableName)},
+			{cfg.logsDatabase(), le.getLogsTableName(), cfg.localTable(le.getLogsTableName())},
+			{cfg.profilesDatabase(), pe.getProfilesTableName(), cfg.localTable(pe.getProfilesTableName())},
+		}
+		if cfg.TraceIDLookup.LookupTableEnabled {
+			facades = append(facades, struct{ database, table, local string }{
+				cfg.tracesDatabase(), cfg.traceLookupTable(), cfg.localTable(cfg.TracesTableName) + "_trace_id_ts"})
+		}
+		if cfg.Traces.LinksTable {
+			facades = append(facades, struct{ database, table, local string }{
+				cfg.tracesDatabase(), cfg.traceLinksTable(), cfg.localTable(cfg.TracesTableName) + "_links"})
+		}
+		if cfg.Traces.RootsTable {
+			facades = append(facades, struct{ database, table, local string }{
+				cfg.tracesDatabase(), cfg.traceRootsTable(), cfg.localTable(cfg.TracesTableName) + "_roots"})
+		}
+		if cfg.ServiceGraph.Enabled {
+			graph := cfg.ServiceGraph.TableName
+			facades = append(facades, struct{ database, table, local string }{cfg.tracesDatabase(), graph, cfg.localTable(graph)})
+		}
+		for _, table := range metricsTables {
+			facades = append(facades, struct{ database, table, local string }{cfg.metricsDatabase(), table.tableName, cfg.localTable(table.tableName)})
+		}
+		if cfg.MetricsDimensions.Enabled {
+			dimensions := cfg.MetricsDimensions.TableName
+			facades = append(facades, struct{ database, table, local string }{cfg.metricsDatabase(), dimensions, cfg.localTable(dimensions)})
+		}
+		for _, a := range aggregations {
+			facades = append(facades, struct{ database, table, local string }{
+				a.database, a.table + a.view.suffix, cfg.localTable(a.table) + a.view.suffix})
+		}
+		for _, f := range facades {
+			sql, err := renderDistributedTableSQL(cfg, f.database, f.table, f.local)
+			if err != nil {
+				return nil, fmt.Errorf("分散テーブル %s のレンダリングに失敗しました: %w", f.table, err)
+			}
+			stmts = append(stmts, SchemaStatement{"distributed table " + f.table, sql})
+		}
+	}
+
+	// マイグレーション（テーブル作成後にローカルテーブルへ適用）
+	for _, m := range []struct{ signal, database, table string }{
+		{"traces", cfg.tracesDatabase(), cfg.localTable(cfg.TracesTableName)},
+		{"logs", cfg.logsDatabase(), cfg.localTable(le.getLogsTableName())},
+		{"profiles", cfg.profilesDatabase(), cfg.localTable(pe.getProfilesTableName())},
+	} {
+		migrationStmts, err := renderMigrationStatements(cfg, m.signal, m.database, m.table)
+		if err != nil {
+			return nil, fmt.Errorf("%s のマイグレーションのレンダリングに失敗しました: %w", m.signal, err)
+		}
+		stmts = append(stmts, migrationStmts...)
+	}
+
+	// 列TTL（column_ttl 指定時のみ）
+	stmts = append(stmts, renderColumnTTLSQL(cfg, "")...)
+
+	// テナントごとの行ポリシー（マルチテナント有効時のみ）
+	stmts = append(stmts, renderRowPolicySQL(cfg, "")...)
+
+	return stmts, nil
+}
+
+// WriteSchemaDDL はDDLを説明コメント付きのSQLスクリプトとして書き出します
+// 出力は clickhouse-client --multiquery などでそのまま実行できます
+func WriteSchemaDDL(w io.Writer, stmts []SchemaStatement) error {
+	for _, stmt := range stmts {
+		if _, err := fmt.Fprintf(w, "\n-- %s\n%s;\n", stmt.Description, strings.TrimSpace(stmt.SQL)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaDryRunDone は設定ごとにドライランのDDLを出力済みかを記録します
+// 同じ設定を共有する各シグナルのエクスポーターが start するたびに同じDDLを出力しないようにするため
+var schemaDryRunDone sync.Map
+
+// dryRunSchemaDDL はスキーマ作成のドライランが有効な場合に、全DDLを実行せずに出力します
+// create_schema_dry_run_file が指定されている場合はファイルに、それ以外はログに出力します
+func dryRunSchemaDDL(cfg *Config, logger *zap.Logger) error {
+	if !cfg.CreateSchemaDryRun || !cfg.CreateSchema {
+		return nil
+	}
+	if _, done := schemaDryRunDone.LoadOrStore(cfg, struct{}{}); done {
+		return nil
+	}
+
+	stmts, err := RenderSchemaDDL(cfg)
+	if err != nil {
+		schemaDryRunDone.Delete(cfg)
+		return err
+	}
+
+	if cfg.CreateSchemaDryRunFile == "" {
+		for _, stmt := range stmts {
+			logger.Info("スキーマ作成のドライラン: 次のDDLは実行されません",
+				zap.String("description", stmt.Description), zap.String("sql", stmt.SQL))
+		}
+		return nil
+	}
+
+	var script strings.Builder
+	script.WriteString("-- create_schema_dry_run により生成したDDL（エクスポーターは実行していません）\n")
+	if err := WriteSchemaDDL(&script, stmts); err != nil {
+		return err
+	}
+	if err := os.WriteFile(cfg.CreateSchemaDryRunFile, []byte(script.String()), 0o644); err != nil {
+		schemaDryRunDone.Delete(cfg)
+		return fmt.Errorf("DDLのファイル出力に失敗しました: %w", err)
+	}
+	logger.Info("スキーマ作成のドライラン: DDLをファイルに出力しました（実行はされません）",
+		zap.String("file", cfg.CreateSchemaDryRunFile), zap.Int("statements", len(stmts)))
+	return nil
+}
+
+// CheckPermissions はデータ変更を伴わない読み取り専用の問い合わせで
+// スキーマ作成とデータ挿入に必要な権限を持っているかを確認します
+func CheckPermissions(ctx context.Context, cfg *Config) error {
+	db, err := buildDB(cfg, internal.DefaultDatabase, zap.NewNop())
+	if err != nil {
+		return fmt.Errorf("データベース接続の構築に失敗しました: %w", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("データベースへの接続テストに失敗しました: %w", err)
+	}
+	if cfg.isPostgres() {
+		return checkPostgresPermissions(ctx, cfg, db)
+	}
+
+	// CHECK GRANT は権限の有無を 1/0 で返す（ClickHouse 24.3以降）
+	for _, database := range cfg.databases() {
+		grants := []string{"INSERT", "SELECT"}
+		if cfg.shouldCreateSchema() {
+			grants = append(grants, "CREATE TABLE", "CREATE VIEW")
+			if database != internal.DefaultDatabase {
+				grants = append(grants, "CREATE DATABASE")
+			}
+		}
+
+		for _, grant := range grants {
+			var granted uint8
+			query := fmt.Sprintf("CHECK GRANT %s ON %s.*", grant, database)
+			if err := db.QueryRowContext(ctx, query).Scan(&granted); err != nil {
+				return fmt.Errorf("権限の確認に失敗しました (%s): %w", grant, err)
+			}
+			if granted != 1 {
+				return fmt.Errorf("%s 権限がありません (database: %s)", grant, database)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CreateSchema は設定値でデータベース・テーブル・ビューの作成のみを実行し、各DDLの結果を w に書き出します
+// コレクターをデプロイする前のプロビジョニング用で、start時と同じDDLを同じ順に実行します（いずれも冪等）
+// マイグレーションは適用履歴を記録せずに実行するため、コレクターの起動時に改めて履歴が記録されます
+func CreateSchema(ctx context.Context, cfg *Config, w io.Writer) error {
+	stmts, err := RenderSchemaDDL(cfg)
+	if err != nil {
+		return err
+	}
+
+	db, err := buildDB(cfg, internal.DefaultDatabase, zap.NewNop())
+	if err != nil {
+		return fmt.Errorf("データベース接続の構築に失敗しました: %w", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("データベースへの接続テストに失敗しました: %w", err)
+	}
+	for i, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt.SQL); err != nil {
+			_, _ = fmt.Fprintf(w, "-- [%d/%d] 失敗: %s\n", i+1, len(stmts), stmt.Description)
+			return fmt.Errorf("%s の作成に失敗しました: %w", stmt.Description, err)
+		}
+		if _, err := fmt.Fprintf(w, "-- [%d/%d] 完了: %s\n", i+1, len(stmts), stmt.Description); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// managedTable はエクスポーターが作成・管理するデータテーブルを表します
+type managedTable struct {
+	signal           string   // シグナル種別（traces, logs, metrics, profiles）
+	database         string   // データベース名
+	name             string   // テーブル名
+	attributeColumns []string // 属性を保持するカラム
+	jsonAttributes   bool     // 属性カラムがJSON型の場合はtrue
+}
+
+// managedTables は設定値から属性カラムを持つ管理対象テーブルの一覧を返します
+func managedTables(cfg *Config) []managedTable {
+	le := &logsExporter{config: cfg}
+	pe := &profilesExporter{config: cfg}
+
+	// traces.store_scope 有効時はスパンテーブルにスコープ属性の列を作成する
+	traceColumns := []string{"ResourceAttributes", "SpanAttributes"}
+	if cfg.Traces.StoreScope {
+		traceColumns = []string{"ResourceAttributes", "ScopeAttributes", "SpanAttributes"}
+	}
+
+	tables := []managedTable{
+		// attributes_format はトレース・ログテーブルにのみ適用される
+		{"traces", cfg.tracesDatabase(), cfg.TracesTableName, traceColumns, cfg.jsonAttributes()},
+		{"logs", cfg.logsDatabase(), le.getLogsTableName(), []string{"ResourceAttributes", "ScopeAttributes", "LogAttributes"}, cfg.jsonAttributes()},
+		{"profiles", cfg.profilesDatabase(), pe.getProfilesTableName(), []string{"ResourceAttributes", "ScopeAttributes"}, false},
+	}
+	// metrics_dimensions 有効時はリソース・スコープ属性をディメンションテーブルに保存する（データポイントの行は Attributes のみ）
+	// metrics_schema: lite の場合はスコープ属性の列を作成しない
+	metricColumns := []string{"ResourceAttributes", "ScopeAttributes", "Attributes"}
+	switch {
+	case cfg.MetricsDimensions.Enabled:
+		metricColumns = []string{"Attributes"}
+	case cfg.liteMetricsSchema():
+		metricColumns = []string{"ResourceAttributes", "Attributes"}
+	}
+	for _, table := range metricsTables {
+		tables = append(tables, managedTable{"metrics", cfg.metricsDatabase(), table.tableName, metricColumns, false})
+	}
+	return tables
+}
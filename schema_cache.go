@@ -0,0 +1,285 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// schemaErrorCodes はテーブル定義と挿入内容の不一致を示すClickHouseのエラーコードです
+// （16: NO_SUCH_COLUMN_IN_TABLE, 47: UNKNOWN_IDENTIFIER, 53: TYPE_MISMATCH）
+var schemaErrorCodes = []string{"16", "47", "53"}
+
+// schemaRefreshPath はテーブル定義の再読み込みを受け付けるパスです
+const schemaRefreshPath = "/schema/refresh"
+
+// SchemaRefreshConfig - テーブル定義の再読み込み（キャッシュ無効化）の設定
+// DBAが管理対象のテーブルを直接変更した場合（例: 属性カラムを Map から JSON に変更）に、
+// コレクターを再起動せずに列定義を読み直して属性のエンコード方法を切り替えます
+//
+//	curl -X POST http://localhost:13134/schema/refresh
+type SchemaRefreshConfig struct {
+	// Endpoint は再読み込みを受け付けるHTTPエンドポイント（host:port）です（未指定の場合は無効）
+	// 挿入が列の不一致で失敗した場合は、エンドポイントの有無に関わらず次の挿入前に再読み込みします
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// validate は再読み込み設定を検証します
+func (c SchemaRefreshConfig) validate() error {
+	if c.Endpoint == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(c.Endpoint); err != nil {
+		return fmt.Errorf("schema_refresh.endpoint は host:port 形式で指定してください: %w", err)
+	}
+	return nil
+}
+
+// tableSchema はデータベースから読み込んだテーブルの列定義（列名: 型）です
+type tableSchema struct {
+	columns map[string]string
+}
+
+// jsonAttributes は属性カラムがJSON型かどうかを返します（列が見つからない場合は fallback）
+func (s *tableSchema) jsonAttributes(column string, fallback bool) bool {
+	if s == nil {
+		return fallback
+	}
+	columnType, ok := s.columns[column]
+	if !ok {
+		return fallback
+	}
+	return strings.HasPrefix(columnType, "JSON")
+}
+
+// schemaCache は挿入先テーブルの列定義を保持し、無効化された場合は次の挿入前に読み直します
+type schemaCache struct {
+	config   *Config
+	db       *sql.DB
+	database string
+	table    string
+	expected []string // 挿入に必要な列
+	logger   *zap.Logger
+
+	current atomic.Pointer[tableSchema]
+	stale   atomic.Bool
+}
+
+// newSchemaCache は列定義のキャッシュを作成します（DB未接続の場合は nil）
+func newSchemaCache(cfg *Config, db *sql.DB, database, table string, expected []string, logger *zap.Logger) *schemaCache {
+	if db == nil {
+		return nil
+	}
+	c := &schemaCache{config: cfg, db: db, database: database, table: table, expected: expected, logger: logger}
+	c.stale.Store(true)
+	return c
+}
+
+// get は列定義を返します（無効化されている場合は読み直し、失敗した場合は前回の定義）
+func (c *schemaCache) get(ctx context.Context) *tableSchema {
+	if c == nil {
+		return nil
+	}
+	if c.stale.CompareAndSwap(true, false) {
+		if err := c.reload(ctx); err != nil {
+			c.stale.Store(true)
+			c.logger.Warn("テーブル定義の読み込みに失敗しました、前回の定義を使用します",
+				zap.String("table", c.table), zap.Error(err))
+		}
+	}
+	return c.current.Load()
+}
+
+// invalidate は列定義を無効化し、次の挿入前に読み直させます
+func (c *schemaCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.stale.Store(true)
+}
+
+// isSchemaError は挿入エラーがテーブル定義の不一致によるものかどうかを返します
+func isSchemaError(err error) bool {
+	return err != nil && slices.Contains(schemaErrorCodes, dbErrorCode(err))
+}
+
+// invalidateOnError は挿入エラーがテーブル定義の不一致によるものであれば列定義を無効化します
+func (c *schemaCache) invalidateOnError(err error) {
+	if c != nil && isSchemaError(err) {
+		c.logger.Info("挿入がテーブル定義の不一致で失敗したため、テーブル定義を読み直します", zap.String("table", c.table))
+		c.invalidate()
+	}
+}
+
+// retryAfterReload は挿入エラーがテーブル定義の不一致によるもので、読み直した列定義で再挿入すべきかどうかを返します
+// 不一致のエラーは永続的なエラーに分類されるため（permanentClickHouseErrors）、DBAがテーブルを変更した直後の
+// バッチを破棄しないよう、読み直した列定義で1回だけ再挿入してから失敗を確定させます
+func (c *schemaCache) retryAfterReload(err error) bool {
+	return c != nil && isSchemaError(err)
+}
+
+// reload はデータベースから列定義を読み込みます
+func (c *schemaCache) reload(ctx context.Context) error {
+	schema, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+	if missing := c.missingColumns(schema); len(missing) > 0 {
+		c.logger.Warn("挿入に必要な列がテーブルにありません", zap.String("table", c.table), zap.Strings("missing_columns", missing))
+	}
+
+	c.current.Store(schema)
+	c.logger.Info("テーブル定義を読み込みました", zap.String("table", c.table), zap.Int("columns", len(schema.columns)))
+	return nil
+}
+
+// verify は列定義を読み込み、挿入に必要な列がすべてテーブルにあるかを確認します
+// 不足している列がある場合は、不足している列とテーブルの列の一覧をエラーで返します
+func (c *schemaCache) verify(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	schema, err := c.load(ctx)
+	if err != nil {
+		return fmt.Errorf("テーブル定義を確認できません: %w", err)
+	}
+	if missing := c.missingColumns(schema); len(missing) > 0 {
+		return fmt.Errorf("テーブル %s.%s に挿入に必要な列がありません: 不足 [%s]、テーブルの列 [%s]",
+			c.database, c.table, strings.Join(missing, ", "), strings.Join(slices.Sorted(maps.Keys(schema.columns)), ", "))
+	}
+	c.current.Store(schema)
+	c.stale.Store(false)
+	c.logger.Info("テーブル定義を確認しました", zap.String("table", c.table), zap.Int("columns", len(schema.columns)))
+	return nil
+}
+
+// load はデータベースから列定義を読み込みます（テーブルがない場合はエラー）
+func (c *schemaCache) load(ctx context.Context) (*tableSchema, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	query := "SELECT name, type FROM system.columns WHERE database = ? AND table = ?"
+	if c.config.isPostgres() {
+		query = "SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2"
+	}
+	rows, err := c.db.QueryContext(ctx, query, c.database, c.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schema := &tableSchema{columns: map[string]string{}}
+	for rows.Next() {
+		var name, columnType string
+		if err := rows.Scan(&name, &columnType); err != nil {
+			return nil, err
+		}
+		schema.columns[name] = columnType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(schema.columns) == 0 {
+		return nil, fmt.Errorf("テーブル %s.%s が見つかりません", c.database, c.table)
+	}
+	return schema, nil
+}
+
+// missingColumns は挿入に必要な列のうち、テーブルにない列を返します
+func (c *schemaCache) missingColumns(schema *tableSchema) []string {
+	var missing []string
+	for _, column := range c.expected {
+		if _, ok := schema.columns[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	return missing
+}
+
+// schemaRefreshServer は再読み込みのHTTPエンドポイントです（同じエンドポイントを指定したエクスポーター間で共有）
+type schemaRefreshServer struct {
+	server *http.Server
+	caches map[*schemaCache]struct{}
+}
+
+var (
+	schemaRefreshMu      sync.Mutex
+	schemaRefreshServers = map[string]*schemaRefreshServer{} // エンドポイントごとのサーバー
+)
+
+// registerSchemaRefresh は列定義のキャッシュを再読み込みエンドポイントに登録し、必要であればサーバーを起動します
+func registerSchemaRefresh(endpoint string, cache *schemaCache, logger *zap.Logger) error {
+	if endpoint == "" || cache == nil {
+		return nil
+	}
+	schemaRefreshMu.Lock()
+	defer schemaRefreshMu.Unlock()
+
+	s, ok := schemaRefreshServers[endpoint]
+	if !ok {
+		listener, err := net.Listen("tcp", endpoint)
+		if err != nil {
+			return fmt.Errorf("schema_refresh.endpoint での待ち受けに失敗しました: %w", err)
+		}
+		s = &schemaRefreshServer{caches: map[*schemaCache]struct{}{}}
+		mux := http.NewServeMux()
+		mux.HandleFunc(schemaRefreshPath, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			schemaRefreshMu.Lock()
+			for cache := range s.caches {
+				cache.invalidate()
+			}
+			count := len(s.caches)
+			schemaRefreshMu.Unlock()
+			logger.Info("テーブル定義の再読み込みを受け付けました", zap.Int("tables", count))
+			w.WriteHeader(http.StatusAccepted)
+		})
+		s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("テーブル定義の再読み込みエンドポイントが停止しました", zap.Error(err))
+			}
+		}()
+		schemaRefreshServers[endpoint] = s
+		logger.Info("テーブル定義の再読み込みを受け付けます", zap.String("endpoint", endpoint), zap.String("path", schemaRefreshPath))
+	}
+	s.caches[cache] = struct{}{}
+	return nil
+}
+
+// unregisterSchemaRefresh はキャッシュの登録を解除し、最後の登録であればサーバーを停止します
+func unregisterSchemaRefresh(endpoint string, cache *schemaCache) error {
+	if endpoint == "" || cache == nil {
+		return nil
+	}
+	schemaRefreshMu.Lock()
+	defer schemaRefreshMu.Unlock()
+
+	s, ok := schemaRefreshServers[endpoint]
+	if !ok {
+		return nil
+	}
+	delete(s.caches, cache)
+	if len(s.caches) > 0 {
+		return nil
+	}
+	delete(schemaRefreshServers, endpoint)
+	return s.server.Close()
+}
@@ -0,0 +1,77 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"go.uber.org/zap"
+)
+
+// schemaErrorTransformer は最初の failures 回の挿入をテーブル定義の不一致（NO_SUCH_COLUMN_IN_TABLE）で失敗させます
+type schemaErrorTransformer struct {
+	failures int
+	calls    int
+	rows     int
+}
+
+func (t *schemaErrorTransformer) Columns() []string { return nil }
+
+func (t *schemaErrorTransformer) TransformRows(_ context.Context, _ []string, rows [][]any) error {
+	t.calls++
+	if t.calls <= t.failures {
+		return &clickhouse.Exception{Code: 16, Name: "NO_SUCH_COLUMN_IN_TABLE"}
+	}
+	t.rows += len(rows)
+	return nil
+}
+
+// テーブル定義の不一致で失敗したバッチは、読み直した列定義で1回だけ再挿入する
+func TestInsertRetriesAfterSchemaError(t *testing.T) {
+	ctx := context.Background()
+	cfg := captureConfig(t.TempDir())
+
+	tests := []struct {
+		name      string
+		failures  int
+		wantErr   bool
+		wantCalls int
+		wantRows  int
+	}{
+		{name: "再挿入で成功", failures: 1, wantCalls: 2, wantRows: 3},
+		{name: "再挿入も失敗", failures: 2, wantErr: true, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer := &schemaErrorTransformer{failures: tt.failures}
+			w, err := NewTracesWriter(cfg, nil, WithRowTransformer(transformer))
+			if err != nil {
+				t.Fatalf("NewTracesWriter: %v", err)
+			}
+			defer func() { _ = w.Close(ctx) }()
+			w.exporter.schema = &schemaCache{config: cfg, logger: zap.NewNop()}
+
+			err = w.exporter.insertTraces(ctx, dedupTestTraces(3))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("insertTraces のエラー = %v, wantErr %v", err, tt.wantErr)
+			}
+			if transformer.calls != tt.wantCalls || transformer.rows != tt.wantRows {
+				t.Errorf("挿入回数 = %d、挿入した行数 = %d, want %d, %d", transformer.calls, transformer.rows, tt.wantCalls, tt.wantRows)
+			}
+		})
+	}
+
+	// 列定義のキャッシュがない場合（DB未接続）は再挿入しない
+	transformer := &schemaErrorTransformer{failures: 1}
+	w, err := NewLogsWriter(cfg, nil, WithRowTransformer(transformer))
+	if err != nil {
+		t.Fatalf("NewLogsWriter: %v", err)
+	}
+	defer func() { _ = w.Close(ctx) }()
+	if err := w.exporter.insertLogs(ctx, dedupTestLogs(3)); err == nil || transformer.calls != 1 {
+		t.Errorf("insertLogs のエラー = %v、挿入回数 = %d, want エラー, 1", err, transformer.calls)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// すべてのシグナルのデータベースを上書きした場合も、接続先のデータベース（database）を作成する
+func TestRenderSchemaDDLCreatesConnectionDatabase(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TracesDatabase, cfg.LogsDatabase, cfg.MetricsDatabase, cfg.ProfilesDatabase = "traces", "logs", "metrics", "profiles"
+
+	stmts, err := RenderSchemaDDL(cfg)
+	if err != nil {
+		t.Fatalf("RenderSchemaDDL: %v", err)
+	}
+	var databases []string
+	for _, stmt := range stmts {
+		if database, ok := strings.CutPrefix(stmt.Description, "database "); ok {
+			databases = append(databases, database)
+		}
+	}
+	if want := []string{cfg.Database, "traces", "logs", "metrics", "profiles"}; !slices.Equal(databases, want) {
+		t.Errorf("作成するデータベース = %v, want %v", databases, want)
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	ast10 "go.opentelemetry.io/otel/schema/v1.0/ast"
+	schema "go.opentelemetry.io/otel/schema/v1.1"
+	"go.opentelemetry.io/otel/schema/v1.1/ast"
+	"go.uber.org/zap"
+)
+
+// SchemaTranslationConfig - スキーマURLに基づく属性変換の設定
+// 古いセマンティック規約バージョンの schema_url を持つデータの属性名を、
+// 変換先スキーマファイルの rename_attributes 定義に従って保存前に更新します
+type SchemaTranslationConfig struct {
+	// Enabled はスキーマ変換を有効化します（トレース・ログに適用）
+	Enabled bool `mapstructure:"enabled"`
+	// TargetSchemaURL は変換先のスキーマURLです（例: https://opentelemetry.io/schemas/1.26.0）
+	TargetSchemaURL string `mapstructure:"target_schema_url"`
+	// SchemaFile は変換先スキーマファイルのローカルパスです
+	// 指定した場合は TargetSchemaURL を取得せずにこのファイルを使用します（オフライン環境向け）
+	SchemaFile string `mapstructure:"schema_file"`
+}
+
+// schemaFetchTimeout はスキーマファイル取得のタイムアウトです
+const schemaFetchTimeout = 10 * time.Second
+
+// validate はスキーマ変換設定を検証します
+func (c SchemaTranslationConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.TargetSchemaURL == "" {
+		return fmt.Errorf("schema_translation.target_schema_url を指定してください")
+	}
+	if _, _, err := splitSchemaURL(c.TargetSchemaURL); err != nil {
+		return fmt.Errorf("schema_translation.target_schema_url が不正です: %w", err)
+	}
+	return nil
+}
+
+// schemaVersion はスキーマファイル内の1バージョン分の変更定義です
+type schemaVersion struct {
+	version *semver.Version
+	def     ast.VersionDef
+}
+
+// schemaTranslator は変換先スキーマファイルに基づいて属性名を更新します
+// 構築後は読み取り専用のため、複数のエクスポーターから並行して使用できます
+type schemaTranslator struct {
+	family    string          // スキーマファミリー（バージョンを除いたURL）
+	targetURL string          // 変換先のスキーマURL
+	target    *semver.Version // 変換先のバージョン
+	versions  []schemaVersion // バージョン昇順の変更定義
+	logger    *zap.Logger
+}
+
+// newSchemaTranslator は変換先スキーマファイルを読み込み、スキーマ変換器を作成します
+func newSchemaTranslator(ctx context.Context, cfg SchemaTranslationConfig, logger *zap.Logger) (*schemaTranslator, error) {
+	family, target, err := splitSchemaURL(cfg.TargetSchemaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed *ast.Schema
+	if cfg.SchemaFile != "" {
+		parsed, err = schema.ParseFile(cfg.SchemaFile)
+	} else {
+		parsed, err = fetchSchema(ctx, cfg.TargetSchemaURL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("スキーマファイルの読み込みに失敗しました (%s): %w", cfg.TargetSchemaURL, err)
+	}
+
+	t := &schemaTranslator{
+		family:    family,
+		targetURL: cfg.TargetSchemaURL,
+		target:    target,
+		logger:    logger,
+	}
+	for v, def := range parsed.Versions {
+		version, err := semver.NewVersion(string(v))
+		if err != nil {
+			return nil, fmt.Errorf("スキーマファイルのバージョン %q が不正です: %w", v, err)
+		}
+		if version.GreaterThan(target) {
+			continue
+		}
+		t.versions = append(t.versions, schemaVersion{version: version, def: def})
+	}
+	slices.SortFunc(t.versions, func(a, b schemaVersion) int {
+		return a.version.Compare(b.version)
+	})
+
+	logger.Info("スキーマ変換を有効化しました",
+		zap.String("target_schema_url", cfg.TargetSchemaURL),
+		zap.Int("versions", len(t.versions)))
+	return t, nil
+}
+
+// fetchSchema はスキーマURLからスキーマファイルを取得して解析します
+func fetchSchema(ctx context.Context, schemaURL string) (*ast.Schema, error) {
+	ctx, cancel := context.WithTimeout(ctx, schemaFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, schemaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("予期しないHTTPステータス: %s", resp.Status)
+	}
+	return schema.Parse(resp.Body)
+}
+
+// splitSchemaURL はスキーマURLをファミリー（バージョンを除いたURL）とバージョンに分割します
+func splitSchemaURL(schemaURL string) (string, *semver.Version, error) {
+	idx := strings.LastIndex(schemaURL, "/")
+	if idx < 0 || idx == len(schemaURL)-1 {
+		return "", nil, fmt.Errorf("スキーマURLにバージョンが含まれていません: %s", schemaURL)
+	}
+	version, err := semver.NewVersion(schemaURL[idx+1:])
+	if err != nil {
+		return "", nil, fmt.Errorf("スキーマURLのバージョンが不正です: %s: %w", schemaURL, err)
+	}
+	return schemaURL[:idx], version, nil
+}
+
+// pendingVersions は schemaURL から変換先までに適用すべき変更定義を返します
+// 同じファミリーの古いバージョンのみが対象で、それ以外（空、別ファミリー、同一・新しいバージョン）は nil を返します
+func (t *schemaTranslator) pendingVersions(schemaURL string) []schemaVersion {
+	if schemaURL == "" || schemaURL == t.targetURL {
+		return nil
+	}
+	family, version, err := splitSchemaURL(schemaURL)
+	if err != nil || family != t.family || !version.LessThan(t.target) {
+		return nil
+	}
+	var pending []schemaVersion
+	for _, v := range t.versions {
+		if v.version.GreaterThan(version) {
+			pending = append(pending, v)
+		}
+	}
+	return pending
+}
+
+// translateResource はリソース属性を変換先バージョンに更新し、更新したかどうかを返します
+func (t *schemaTranslator) translateResource(res pcommon.Resource, schemaURL string) bool {
+	pending := t.pendingVersions(schemaURL)
+	if len(pending) == 0 {
+		return false
+	}
+	for _, v := range pending {
+		applyAttributeChanges(res.Attributes(), v.def.All.Changes)
+		applyAttributeChanges(res.Attributes(), v.def.Resources.Changes)
+	}
+	t.logger.Debug("リソース属性のスキーマを変換しました",
+		zap.String("from", schemaURL), zap.String("to", t.targetURL))
+	return true
+}
+
+// translateTraces はトレースデータのリソース・スパン・スパンイベント属性を変換先バージョンに更新します
+// 変換したリソースとスコープの schema_url は変換先のURLに書き換えます
+func (t *schemaTranslator) translateTraces(td ptrace.Traces) {
+	resourceSpans := td.ResourceSpans()
+	for i := 0; i < resourceSpans.Len(); i++ {
+		rs := resourceSpans.At(i)
+		resourceURL := rs.SchemaUrl()
+		if t.translateResource(rs.Resource(), resourceURL) {
+			rs.SetSchemaUrl(t.targetURL)
+		}
+
+		scopeSpans := rs.ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			ss := scopeSpans.At(j)
+			// スコープの schema_url が未設定の場合はリソースの schema_url に従う
+			scopeURL := cmp.Or(ss.SchemaUrl(), resourceURL)
+			pending := t.pendingVersions(scopeURL)
+			if len(pending) == 0 {
+				continue
+			}
+			spans := ss.Spans()
+			for k := 0; k < spans.Len(); k++ {
+				for _, v := range pending {
+					translateSpan(spans.At(k), v.def)
+				}
+			}
+			t.logger.Debug("スパン属性のスキーマを変換しました",
+				zap.String("from", scopeURL), zap.String("to", t.targetURL))
+			ss.SetSchemaUrl(t.targetURL)
+		}
+	}
+}
+
+// translateLogs はログデータのリソース・ログ属性を変換先バージョンに更新します
+// 変換したリソースとスコープの schema_url は変換先のURLに書き換えます
+func (t *schemaTranslator) translateLogs(ld plog.Logs) {
+	resourceLogs := ld.ResourceLogs()
+	for i := 0; i < resourceLogs.Len(); i++ {
+		rl := resourceLogs.At(i)
+		resourceURL := rl.SchemaUrl()
+		if t.translateResource(rl.Resource(), resourceURL) {
+			rl.SetSchemaUrl(t.targetURL)
+		}
+
+		scopeLogs := rl.ScopeLogs()
+		for j := 0; j < scopeLogs.Len(); j++ {
+			sl := scopeLogs.At(j)
+			// スコープの schema_url が未設定の場合はリソースの schema_url に従う
+			scopeURL := cmp.Or(sl.SchemaUrl(), resourceURL)
+			pending := t.pendingVersions(scopeURL)
+			if len(pending) == 0 {
+				continue
+			}
+			logRecords := sl.LogRecords()
+			for k := 0; k < logRecords.Len(); k++ {
+				attrs := logRecords.At(k).Attributes()
+				for _, v := range pending {
+					applyAttributeChanges(attrs, v.def.All.Changes)
+					for _, change := range v.def.Logs.Changes {
+						if change.RenameAttributes != nil {
+							renameAttributes(attrs, change.RenameAttributes.AttributeMap)
+						}
+					}
+				}
+			}
+			t.logger.Debug("ログ属性のスキーマを変換しました",
+				zap.String("from", scopeURL), zap.String("to", t.targetURL))
+			sl.SetSchemaUrl(t.targetURL)
+		}
+	}
+}
+
+// translateSpan は1バージョン分の変更定義をスパンとそのイベントに適用します
+func translateSpan(span ptrace.Span, def ast.VersionDef) {
+	applyAttributeChanges(span.Attributes(), def.All.Changes)
+	for _, change := range def.Spans.Changes {
+		if change.RenameAttributes == nil {
+			continue
+		}
+		if matchesName(change.RenameAttributes.ApplyToSpans, span.Name()) {
+			renameAttributes(span.Attributes(), change.RenameAttributes.AttributeMap)
+		}
+	}
+
+	events := span.Events()
+	for i := 0; i < events.Len(); i++ {
+		event := events.At(i)
+		applyAttributeChanges(event.Attributes(), def.All.Changes)
+		for _, change := range def.SpanEvents.Changes {
+			if change.RenameEvents != nil {
+				if name, ok := change.RenameEvents.EventNameMap[event.Name()]; ok {
+					event.SetName(name)
+				}
+			}
+			if change.RenameAttributes != nil &&
+				matchesName(change.RenameAttributes.ApplyToSpans, span.Name()) &&
+				matchesName(change.RenameAttributes.ApplyToEvents, event.Name()) {
+				renameAttributes(event.Attributes(), change.RenameAttributes.AttributeMap)
+			}
+		}
+	}
+}
+
+// applyAttributeChanges は all / resources セクションの属性名変更を適用します
+func applyAttributeChanges(attrs pcommon.Map, changes []ast10.AttributeChange) {
+	for _, change := range changes {
+		if change.RenameAttributes != nil {
+			renameAttributes(attrs, change.RenameAttributes.AttributeMap)
+		}
+	}
+}
+
+// renameAttributes は属性名を変更します
+// 新しい名前の属性が既に存在する場合は、その値を優先して古い属性を削除します
+func renameAttributes(attrs pcommon.Map, renames map[string]string) {
+	for from, to := range renames {
+		v, ok := attrs.Get(from)
+		if !ok {
+			continue
+		}
+		if _, exists := attrs.Get(to); !exists {
+			value := pcommon.NewValueEmpty()
+			v.CopyTo(value)
+			value.CopyTo(attrs.PutEmpty(to))
+		}
+		attrs.Remove(from)
+	}
+}
+
+// matchesName は適用対象の名前リストに name が含まれるかを判定します（空のリストは全てに一致）
+func matchesName[T ~string](names []T, name string) bool {
+	return len(names) == 0 || slices.Contains(names, T(name))
+}
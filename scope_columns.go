@@ -0,0 +1,19 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"slices"
+
+	"github.com/dtamura/myexporter/pdatarows"
+)
+
+// insertColumns は store_scope が有効な場合にスキーマURL・スコープ属性の列を追加した挿入列を返します
+// 値は変換時に各行の末尾に追加されるため、max_attribute_length などで追加される列より前に置きます
+func (c TracesConfig) insertColumns(columns []string) []string {
+	if !c.StoreScope {
+		return columns
+	}
+	return slices.Concat(columns, pdatarows.TraceScopeColumns)
+}
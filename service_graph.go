@@ -0,0 +1,267 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/internal/sqltemplates"
+)
+
+// サービス間の呼び出しの種類（ConnectionType 列の値）
+const (
+	connectionDirect    = ""                 // 呼び出し元・呼び出し先のスパンがともにバッチに含まれる呼び出し
+	connectionMessaging = "messaging_system" // プロデューサーからコンシューマーへのメッセージの受け渡し
+	connectionDatabase  = "database"         // db.system を持つクライアントスパン（呼び出し先はデータベース）
+	connectionVirtual   = "virtual_node"     // 呼び出し先のスパンがなく、ピア属性から呼び出し先を決めた呼び出し
+)
+
+// ServiceGraphConfig - サービス間の呼び出し関係（サービスグラフ）テーブルの設定（トレースのみ）
+// 挿入に成功したスパンの親子関係から呼び出し元→呼び出し先のサービスの組を求め、
+// 1分ごと・組ごとの呼び出し数、失敗数、レイテンシのヒストグラムを table_name のテーブルに書き込みます
+// 親子関係は同じバッチに含まれるスパンの間でのみ判定するため、呼び出し先のスパンが別のバッチで届いた場合は
+// 呼び出し元のクライアントスパンのピア属性（peer_attributes）から呼び出し先を決めます
+// サービスグラフの書き込みはベストエフォートで、失敗してもスパンの挿入はリトライしません
+type ServiceGraphConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	TableName string `mapstructure:"table_name"` // サービスグラフのテーブル名（既定: otel_service_graph）
+	// LatencyBuckets はレイテンシのヒストグラムの境界です（昇順、最後の境界を超えた呼び出しは末尾のバケットに数える）
+	LatencyBuckets []time.Duration `mapstructure:"latency_buckets"`
+	// PeerAttributes は呼び出し先のスパンがない場合に呼び出し先とするスパン属性です（先頭から順に参照する）
+	PeerAttributes []string `mapstructure:"peer_attributes"`
+}
+
+// validate はサービスグラフの設定を検証します
+func (c ServiceGraphConfig) validate(cfg *Config) error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs error
+	if c.TableName == "" {
+		errs = errors.Join(errs, errors.New("service_graph.table_name を指定してください"))
+	}
+	if len(c.LatencyBuckets) == 0 {
+		errs = errors.Join(errs, errors.New("service_graph.latency_buckets を1つ以上指定してください"))
+	}
+	for i, b := range c.LatencyBuckets {
+		if b <= 0 || (i > 0 && b <= c.LatencyBuckets[i-1]) {
+			errs = errors.Join(errs, fmt.Errorf("service_graph.latency_buckets は0より大きい昇順の値である必要があります: %v", c.LatencyBuckets))
+			break
+		}
+	}
+	if cfg.isPostgres() {
+		errs = errors.Join(errs, errors.New("driver: postgres では service_graph を使用できません"))
+	}
+	return errs
+}
+
+// bucketBounds はレイテンシのヒストグラムの境界をミリ秒で返します（LatencyBounds 列の値）
+func (c ServiceGraphConfig) bucketBounds() []float64 {
+	bounds := make([]float64, len(c.LatencyBuckets))
+	for i, b := range c.LatencyBuckets {
+		bounds[i] = float64(b) / float64(time.Millisecond)
+	}
+	return bounds
+}
+
+// serviceGraphTemplate はサービスグラフテーブル作成SQLのテンプレートファイル名です
+const serviceGraphTemplate = "service_graph_table.sql"
+
+// renderCreateServiceGraphTableSQL - サービスグラフテーブル作成SQLを生成
+// 保持期間はトレースのメインテーブルと同じ
+func (e *tracesExporter) renderCreateServiceGraphTableSQL() (string, error) {
+	return internal.ExecuteSQLTemplate(serviceGraphTemplate, sqltemplates.ServiceGraphCreateTable, internal.TableTemplateData{
+		Database: e.config.tracesDatabase(),
+		Table:    e.config.localTable(e.config.ServiceGraph.TableName),
+		Cluster:  e.config.clusterString(),
+		Engine:   e.config.replicatedEngine("MergeTree()"),
+		TTL:      internal.GenerateTTLExpr(e.config.signalTTL("traces"), "Timestamp"),
+		Settings: e.config.tableSettings(),
+	})
+}
+
+// createServiceGraphTable - サービスグラフテーブル（分散テーブル構成では分散テーブルも）を作成します
+func (e *tracesExporter) createServiceGraphTable(ctx context.Context) error {
+	createTableSQL, err := e.renderCreateServiceGraphTableSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, serviceGraphTemplate)
+		return err
+	}
+	if err := e.execSQL(ctx, createTableSQL, "service graph table"); err != nil {
+		return err
+	}
+	table := e.config.ServiceGraph.TableName
+	if err := createDistributedTable(ctx, e.config, e.db, e.config.tracesDatabase(),
+		table, e.config.localTable(table), e.logger); err != nil {
+		return err
+	}
+	e.logger.Info("サービスグラフテーブルが正常に作成されました", zap.String("table", table))
+	return nil
+}
+
+// graphSpan はサービスグラフの判定に使用するスパンの情報です
+type graphSpan struct {
+	service string
+	kind    ptrace.SpanKind
+	start   pcommon.Timestamp
+	latency time.Duration
+	failed  bool
+	peer    string // peer_attributes のうち最初に値を持つ属性の値
+	db      bool   // db.system 属性を持つ場合はtrue
+	matched bool   // 別のサービスの子スパンと組になった場合はtrue（ピア属性からの呼び出し先を求めない）
+}
+
+// serviceGraphKey はサービスグラフの行の集計キーです
+type serviceGraphKey struct {
+	minute         time.Time
+	client, server string
+	connectionType string
+}
+
+// serviceGraphEdge は1分・1組あたりの呼び出しの集計です
+type serviceGraphEdge struct {
+	calls, failed uint64
+	latencySum    float64 // ミリ秒
+	buckets       []uint64
+}
+
+// serviceGraphRows はスパンの親子関係から呼び出し元→呼び出し先の組を求め、1分・1組ごとの行に集計します
+// 子スパン（サーバー・コンシューマー）の親が別のサービスのスパンの場合に呼び出しとし、
+// 親がクライアントスパンの場合は呼び出し元から見たレイテンシ（親のスパンの長さ）を使用します
+// 組にならなかったクライアント・プロデューサーのスパンは、ピア属性の値を呼び出し先とします
+func serviceGraphRows(cfg ServiceGraphConfig, td ptrace.Traces) [][]any {
+	spans := map[pcommon.SpanID]*graphSpan{}
+	type child struct {
+		span   *graphSpan
+		parent pcommon.SpanID
+	}
+	var children []child
+	for _, rs := range td.ResourceSpans().All() {
+		service := resourceAttributeString(rs.Resource(), "service.name")
+		for _, ss := range rs.ScopeSpans().All() {
+			for _, span := range ss.Spans().All() {
+				gs := &graphSpan{
+					service: service,
+					kind:    span.Kind(),
+					start:   span.StartTimestamp(),
+					latency: span.EndTimestamp().AsTime().Sub(span.StartTimestamp().AsTime()),
+					failed:  span.Status().Code() == ptrace.StatusCodeError,
+				}
+				if gs.kind == ptrace.SpanKindClient || gs.kind == ptrace.SpanKindProducer {
+					attrs := span.Attributes()
+					for _, key := range cfg.PeerAttributes {
+						if v, ok := attrs.Get(key); ok && v.AsString() != "" {
+							gs.peer = v.AsString()
+							break
+						}
+					}
+					_, gs.db = attrs.Get("db.system")
+				}
+				spans[span.SpanID()] = gs
+				if (gs.kind == ptrace.SpanKindServer || gs.kind == ptrace.SpanKindConsumer) && !span.ParentSpanID().IsEmpty() {
+					children = append(children, child{gs, span.ParentSpanID()})
+				}
+			}
+		}
+	}
+
+	edges := map[serviceGraphKey]*serviceGraphEdge{}
+	observe := func(caller *graphSpan, server, connectionType string, latency time.Duration, failed bool) {
+		key := serviceGraphKey{caller.start.AsTime().UTC().Truncate(time.Minute), caller.service, server, connectionType}
+		edge, ok := edges[key]
+		if !ok {
+			edge = &serviceGraphEdge{buckets: make([]uint64, len(cfg.LatencyBuckets)+1)}
+			edges[key] = edge
+		}
+		edge.calls++
+		if failed {
+			edge.failed++
+		}
+		edge.latencySum += float64(latency) / float64(time.Millisecond)
+		edge.buckets[sort.Search(len(cfg.LatencyBuckets), func(i int) bool { return latency <= cfg.LatencyBuckets[i] })]++
+	}
+
+	for _, c := range children {
+		parent, ok := spans[c.parent]
+		if !ok || parent.service == c.span.service {
+			continue
+		}
+		parent.matched = true
+		connectionType, latency := connectionDirect, c.span.latency
+		switch {
+		case c.span.kind == ptrace.SpanKindConsumer:
+			connectionType = connectionMessaging
+		case parent.kind == ptrace.SpanKindClient:
+			latency = parent.latency
+		}
+		observe(parent, c.span.service, connectionType, latency, parent.failed || c.span.failed)
+	}
+	for _, s := range spans {
+		if s.matched || s.peer == "" {
+			continue
+		}
+		connectionType := connectionVirtual
+		switch {
+		case s.kind == ptrace.SpanKindProducer:
+			connectionType = connectionMessaging
+		case s.db:
+			connectionType = connectionDatabase
+		}
+		observe(s, s.peer, connectionType, s.latency, s.failed)
+	}
+
+	bounds := cfg.bucketBounds()
+	rows := make([][]any, 0, len(edges))
+	for key, edge := range edges {
+		rows = append(rows, []any{key.minute, key.client, key.server, key.connectionType,
+			edge.calls, edge.failed, edge.latencySum, edge.buckets, bounds})
+	}
+	return rows
+}
+
+// serviceGraphWriter は挿入に成功したスパンのサービスグラフを書き込みます
+type serviceGraphWriter struct {
+	config *Config
+	db     *sql.DB
+}
+
+// newServiceGraphWriter はサービスグラフの書き込みを作成します（service_graph 無効またはDB未接続の場合は nil）
+func newServiceGraphWriter(cfg *Config, db *sql.DB) *serviceGraphWriter {
+	if !cfg.ServiceGraph.Enabled || db == nil || cfg.isPostgres() {
+		return nil
+	}
+	return &serviceGraphWriter{config: cfg, db: db}
+}
+
+// write はバッチのサービスグラフを1トランザクションで書き込みます
+func (w *serviceGraphWriter) write(ctx context.Context, td ptrace.Traces) error {
+	if w == nil {
+		return nil
+	}
+	rows := serviceGraphRows(w.config.ServiceGraph, td)
+	if len(rows) == 0 {
+		return nil
+	}
+	insert, err := renderInsertStatement("service_graph_insert.sql", sqltemplates.ServiceGraphInsert, "", nil, internal.TableTemplateData{
+		Database: w.config.tracesDatabase(),
+		Table:    w.config.ServiceGraph.TableName,
+	})
+	if err != nil {
+		return err
+	}
+	if err := insertRowsWithTimeout(ctx, w.config, w.db, nil, insert, rows); err != nil {
+		return fmt.Errorf("サービスグラフの書き込みに失敗しました: %w", err)
+	}
+	return nil
+}
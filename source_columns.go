@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"slices"
+
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/exporter"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// sourceColumnNames は送信元メタデータカラムの列名です（挿入SQLの末尾に追加される順）
+var sourceColumnNames = []string{stable.ColumnCollectorInstanceID, stable.ColumnCollectorPipeline, stable.ColumnCollectorReceiver}
+
+// SourceColumnsConfig - 各行に送信元（コレクター、パイプライン、レシーバー）を記録する設定
+// 複数のコレクターが1つのテーブルに書き込む場合でも、行がどこから来たかを追跡できるようにします
+// 既存のテーブルには列が追加されないため、有効化する場合は ALTER TABLE で列を追加してください
+type SourceColumnsConfig struct {
+	// Enabled は送信元メタデータカラムを有効化します
+	Enabled bool `mapstructure:"enabled"`
+	// Pipeline はパイプライン名です（エクスポーターからは参照できないため設定で指定、未指定の場合はエクスポーターのID）
+	Pipeline string `mapstructure:"pipeline"`
+	// Receiver はレシーバー名です
+	Receiver string `mapstructure:"receiver"`
+	// ReceiverMetadataKey を指定した場合、リクエストのクライアントメタデータの値をレシーバー名として使用します
+	// （レシーバーの include_metadata と sending_queue の metadata_keys の設定が必要、値がない場合は Receiver）
+	ReceiverMetadataKey string `mapstructure:"receiver_metadata_key"`
+}
+
+// insertColumns は送信元メタデータカラムが有効な場合に列を追加した挿入列を返します
+func (c SourceColumnsConfig) insertColumns(columns []string) []string {
+	if !c.Enabled {
+		return columns
+	}
+	return slices.Concat(columns, sourceColumnNames)
+}
+
+// sourceStamp は行に付与する送信元メタデータです
+type sourceStamp struct {
+	config     SourceColumnsConfig
+	instanceID string // コレクターの service.instance.id
+	pipeline   string
+}
+
+// newSourceStamp は送信元メタデータカラムが有効な場合のみ sourceStamp を作成します（無効の場合は nil）
+func newSourceStamp(cfg SourceColumnsConfig, set exporter.Settings) *sourceStamp {
+	if !cfg.Enabled {
+		return nil
+	}
+	stamp := &sourceStamp{config: cfg, pipeline: cfg.Pipeline}
+	if stamp.pipeline == "" {
+		stamp.pipeline = set.ID.String()
+	}
+	if v, ok := set.Resource.Attributes().Get("service.instance.id"); ok {
+		stamp.instanceID = v.AsString()
+	}
+	return stamp
+}
+
+// stamp は各行の末尾に送信元メタデータ（sourceColumnNames の順）を追加します
+func (s *sourceStamp) stamp(ctx context.Context, rows [][]any) {
+	if s == nil {
+		return
+	}
+	receiver := s.config.Receiver
+	if key := s.config.ReceiverMetadataKey; key != "" {
+		if values := client.FromContext(ctx).Metadata.Get(key); len(values) > 0 && values[0] != "" {
+			receiver = values[0]
+		}
+	}
+	for i, row := range rows {
+		rows[i] = append(row, s.instanceID, s.pipeline, receiver)
+	}
+}
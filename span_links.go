@@ -0,0 +1,75 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/internal/sqltemplates"
+)
+
+// traceLinksOrderBy はスパンリンクテーブルのORDER BYです（リンク先のトレースからリンク元を検索するため）
+const traceLinksOrderBy = "(LinkTraceId, LinkSpanId, Timestamp)"
+
+// traceLinksTable はスパンリンクテーブル名を返します
+func (cfg *Config) traceLinksTable() string {
+	return cfg.TracesTableName + "_links"
+}
+
+// renderCreateTraceLinksTableSQL - スパンリンクテーブル作成SQLを生成
+// 保持期間はメインテーブルと同じ（リンク元のスパンと同時に削除される）
+func (e *tracesExporter) renderCreateTraceLinksTableSQL() (string, error) {
+	return internal.ExecuteSQLTemplate("traces_links_table.sql", sqltemplates.TracesCreateLinksTable, internal.TableTemplateData{
+		Database: e.config.tracesDatabase(),
+		Table:    e.config.localTable(e.config.TracesTableName),
+		Cluster:  e.config.clusterString(),
+		Engine:   e.config.replicatedEngine("MergeTree()"),
+		OrderBy:  traceLinksOrderBy,
+		TTL:      internal.GenerateTTLExpr(e.config.signalTTL("traces"), "toDateTime(Timestamp)"),
+		Settings: e.config.tableSettings(),
+		MapType:  e.config.mapColumnType(),
+	})
+}
+
+// renderTraceLinksMaterializedViewSQL - スパンリンクを展開するマテリアライズドビュー作成SQLを生成
+func (e *tracesExporter) renderTraceLinksMaterializedViewSQL() (string, error) {
+	return internal.ExecuteSQLTemplate("traces_links_mv.sql", sqltemplates.TracesCreateLinksView, internal.TableTemplateData{
+		Database: e.config.tracesDatabase(),
+		Table:    e.config.localTable(e.config.TracesTableName),
+		Cluster:  e.config.clusterString(),
+	})
+}
+
+// createTraceLinksTable - スパンリンクテーブルとマテリアライズドビューを作成します
+// ビューは作成後に挿入されたスパンのみを展開します（既存のスパンのリンクは展開しない）
+func (e *tracesExporter) createTraceLinksTable(ctx context.Context) error {
+	createTableSQL, err := e.renderCreateTraceLinksTableSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "traces_links_table.sql")
+		return err
+	}
+	if err := e.execSQL(ctx, createTableSQL, "trace links table"); err != nil {
+		return err
+	}
+
+	createViewSQL, err := e.renderTraceLinksMaterializedViewSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "traces_links_mv.sql")
+		return err
+	}
+	if err := e.execSQL(ctx, createViewSQL, "trace links materialized view"); err != nil {
+		return err
+	}
+
+	if err := createDistributedTable(ctx, e.config, e.db, e.config.tracesDatabase(),
+		e.config.traceLinksTable(), e.config.localTable(e.config.TracesTableName)+"_links", e.logger); err != nil {
+		return err
+	}
+	e.logger.Info("スパンリンクテーブルが正常に作成されました",
+		zap.String("table", e.config.traceLinksTable()))
+	return nil
+}
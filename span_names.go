@@ -0,0 +1,184 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// spanNameStateKey は state_storage に保存するスパン名の状態のキーです
+const spanNameStateKey = "span_names"
+
+// defaultSpanNameOverflow はサービスごとの上限を超えたスパン名を置き換える名前の既定値です
+const defaultSpanNameOverflow = "otel.span_name.overflow"
+
+// builtinSpanNameRules は replace_ids で適用する置換規則です（UUID、16桁以上の16進数ID、数値の順に適用）
+var builtinSpanNameRules = []SpanNameRule{
+	{Pattern: `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`, Replacement: "{uuid}"},
+	{Pattern: `\b[0-9a-fA-F]{16,}\b`, Replacement: "{id}"},
+	{Pattern: `\b[0-9]+\b`, Replacement: "{num}"},
+}
+
+// SpanNameNormalizationConfig - スパン名の正規化設定
+// パスやIDを含むスパン名（例: GET /users/12345）はカーディナリティが高く、
+// ORDER BY (ServiceName, SpanName) の並びが細分化されて検索・圧縮の効率が落ちるため、保存前に正規化します
+type SpanNameNormalizationConfig struct {
+	// ReplaceIDs はUUID・16進数ID・数値をそれぞれ {uuid}, {id}, {num} に置き換えます
+	ReplaceIDs bool `mapstructure:"replace_ids"`
+	// Rules は replace_ids の後に順に適用する正規表現の置換規則です
+	Rules []SpanNameRule `mapstructure:"rules"`
+	// MaxNamesPerService はサービスごとに保存する異なるスパン名の数です（0 の場合は無制限）
+	// 上限を超えた新しい名前は OverflowName に置き換えます（計数は state_storage 指定時のみ再起動後も引き継ぐ）
+	MaxNamesPerService int `mapstructure:"max_names_per_service"`
+	// OverflowName は上限を超えたスパン名の置き換え先です（既定: otel.span_name.overflow）
+	OverflowName string `mapstructure:"overflow_name"`
+}
+
+// SpanNameRule - スパン名の置換規則
+type SpanNameRule struct {
+	Pattern     string `mapstructure:"pattern"`     // 正規表現（RE2）
+	Replacement string `mapstructure:"replacement"` // 置換後の文字列（$1 などで部分一致を参照可能）
+}
+
+// validate はスパン名の正規化設定を検証します
+func (c SpanNameNormalizationConfig) validate() error {
+	var errs error
+	for i, rule := range c.Rules {
+		if rule.Pattern == "" {
+			errs = errors.Join(errs, fmt.Errorf("traces.span_name_normalization.rules[%d].pattern を指定してください", i))
+			continue
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("traces.span_name_normalization.rules[%d].pattern が不正です: %w", i, err))
+		}
+	}
+	if c.MaxNamesPerService < 0 {
+		errs = errors.Join(errs, fmt.Errorf("traces.span_name_normalization.max_names_per_service は0以上である必要があります: %d", c.MaxNamesPerService))
+	}
+	return errs
+}
+
+// spanNameRule はコンパイル済みの置換規則です
+type spanNameRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// spanNameNormalizer は保存前にスパン名を正規化し、サービスごとの異なる名前の数を制限します
+type spanNameNormalizer struct {
+	rules    []spanNameRule
+	limit    int
+	overflow string
+	logger   *zap.Logger
+
+	mu    sync.Mutex
+	names map[string]map[string]struct{} // サービスごとの許容済みのスパン名
+}
+
+// newSpanNameNormalizer はスパン名の正規化を作成します（正規化が設定されていない場合は nil）
+func newSpanNameNormalizer(cfg SpanNameNormalizationConfig, logger *zap.Logger) *spanNameNormalizer {
+	if !cfg.ReplaceIDs && len(cfg.Rules) == 0 && cfg.MaxNamesPerService == 0 {
+		return nil
+	}
+	n := &spanNameNormalizer{
+		limit:    cfg.MaxNamesPerService,
+		overflow: cfg.OverflowName,
+		logger:   logger,
+		names:    map[string]map[string]struct{}{},
+	}
+	if n.overflow == "" {
+		n.overflow = defaultSpanNameOverflow
+	}
+	rules := cfg.Rules
+	if cfg.ReplaceIDs {
+		rules = append(append([]SpanNameRule{}, builtinSpanNameRules...), cfg.Rules...)
+	}
+	for _, rule := range rules {
+		// パターンは validate で検証済み
+		n.rules = append(n.rules, spanNameRule{pattern: regexp.MustCompile(rule.Pattern), replacement: rule.Replacement})
+	}
+	return n
+}
+
+// normalize はサービスのスパン名を正規化した名前を返します
+func (n *spanNameNormalizer) normalize(service, name string) string {
+	if n == nil {
+		return name
+	}
+	for _, rule := range n.rules {
+		name = rule.pattern.ReplaceAllString(name, rule.replacement)
+	}
+	if n.limit == 0 {
+		return name
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	seen := n.names[service]
+	if seen == nil {
+		seen = map[string]struct{}{}
+		n.names[service] = seen
+	}
+	if _, ok := seen[name]; ok {
+		return name
+	}
+	if len(seen) < n.limit {
+		seen[name] = struct{}{}
+		return name
+	}
+	if _, ok := seen[n.overflow]; !ok {
+		// 上限に達したことをサービスごとに1回だけ通知する（置き換え先は上限の計数に含めない）
+		seen[n.overflow] = struct{}{}
+		n.logger.Warn("サービスの異なるスパン名の数が上限に達しました、以降の新しい名前は置き換えて保存します",
+			zap.String("service", service),
+			zap.Int("max_names_per_service", n.limit),
+			zap.String("overflow_name", n.overflow))
+	}
+	return n.overflow
+}
+
+// snapshot はサービスごとの許容済みのスパン名を返します（上限を設定していない場合は nil）
+func (n *spanNameNormalizer) snapshot() map[string][]string {
+	if n == nil || n.limit == 0 {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	names := make(map[string][]string, len(n.names))
+	for service, seen := range n.names {
+		for name := range seen {
+			names[service] = append(names[service], name)
+		}
+	}
+	return names
+}
+
+// restore は保存された許容済みのスパン名を復元します
+// 上限を下げて再起動した場合は、上限を超える分を復元しません
+func (n *spanNameNormalizer) restore(names map[string][]string) {
+	if n == nil || n.limit == 0 {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for service, list := range names {
+		seen := map[string]struct{}{}
+		admitted := 0
+		for _, name := range list {
+			switch {
+			case name == n.overflow:
+				seen[name] = struct{}{}
+			case admitted < n.limit:
+				seen[name] = struct{}{}
+				admitted++
+			}
+		}
+		n.names[service] = seen
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// spoolSegmentExt はスプールのセグメントファイルの拡張子です（中身はOTLP protobuf）
+const spoolSegmentExt = ".pb"
+
+// SpoolConfig - DB障害時にデータをローカルディスクに退避する設定（ライトアヘッドスプール）
+// 挿入に失敗したバッチをOTLP protobufのセグメントファイルとして保存し、ClickHouseの復旧後に再挿入します
+// 送信キュー（メモリ）に収まらない長時間の障害でもデータを失わないようにするために使用します
+type SpoolConfig struct {
+	// Directory はセグメントファイルの保存先です（指定した場合のみ有効化、シグナルごとにサブディレクトリを作成）
+	Directory string `mapstructure:"directory"`
+	// MaxSize はシグナルごとのスプールの最大サイズ（バイト）です。超える場合は古いセグメントから破棄します
+	MaxSize int64 `mapstructure:"max_size"`
+	// MaxAge はセグメントの保持期間です。経過したセグメントは再挿入せずに破棄します（0 の場合は無制限）
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// ReplayInterval は退避したセグメントの再挿入を試みる間隔です
+	ReplayInterval time.Duration `mapstructure:"replay_interval"`
+}
+
+// validate はスプール設定を検証します
+func (c SpoolConfig) validate() error {
+	if c.Directory == "" {
+		return nil
+	}
+	var errs error
+	if c.MaxSize <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("spool.max_size は1以上である必要があります: %d", c.MaxSize))
+	}
+	if c.MaxAge < 0 {
+		errs = errors.Join(errs, fmt.Errorf("spool.max_age は0以上である必要があります: %s", c.MaxAge))
+	}
+	if c.ReplayInterval <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("spool.replay_interval は0より大きい必要があります: %s", c.ReplayInterval))
+	}
+	return errs
+}
+
+// diskSpool はシグナルごとのセグメントファイルを管理します
+type diskSpool struct {
+	config SpoolConfig
+	dir    string
+	logger *zap.Logger
+
+	mu  sync.Mutex
+	seq int // 同一時刻に書き込んだセグメントを区別する連番
+
+	stop context.CancelFunc // 再挿入ループの停止
+	done chan struct{}      // 再挿入ループの終了通知
+}
+
+// newDiskSpool はスプールが設定されている場合のみ diskSpool を作成します（未設定の場合は nil）
+func newDiskSpool(cfg SpoolConfig, signal string, logger *zap.Logger) (*diskSpool, error) {
+	if cfg.Directory == "" {
+		return nil, nil
+	}
+	dir := filepath.Join(cfg.Directory, signal)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("スプールディレクトリ %s の作成に失敗しました: %w", dir, err)
+	}
+	return &diskSpool{
+		config: cfg,
+		dir:    dir,
+		logger: logger.With(zap.String("spool", dir)),
+	}, nil
+}
+
+// write はペイロードをセグメントファイルとして保存します
+// 最大サイズを超える場合は古いセグメントから破棄して空きを作ります
+func (s *diskSpool) write(payload []byte) error {
+	if int64(len(payload)) > s.config.MaxSize {
+		return fmt.Errorf("バッチのサイズ %d バイトが spool.max_size（%d バイト）を超えています", len(payload), s.config.MaxSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, segment := range segments {
+		total += segment.size
+	}
+	for len(segments) > 0 && total+int64(len(payload)) > s.config.MaxSize {
+		oldest := segments[0]
+		if err := os.Remove(oldest.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("古いセグメントの削除に失敗しました: %w", err)
+		}
+		s.logger.Warn("spool.max_size を超えたため古いセグメントを破棄しました",
+			zap.String("segment", oldest.path), zap.Int64("size", oldest.size))
+		total -= oldest.size
+		segments = segments[1:]
+	}
+
+	// 書き込み途中のファイルを再挿入しないよう、一時ファイルに書き込んでから名前を変更する
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq%1000000, spoolSegmentExt)
+	path := filepath.Join(s.dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		return fmt.Errorf("セグメントの書き込みに失敗しました: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("セグメントの書き込みに失敗しました: %w", err)
+	}
+	return nil
+}
+
+// replay は古い順にセグメントを読み込んで insert に渡し、成功したセグメントを削除します
+// 保持期間を過ぎたセグメントは破棄し、insert が失敗した時点で中断します（残りは次回に再挿入）
+func (s *diskSpool) replay(ctx context.Context, insert func(context.Context, []byte) error) error {
+	s.mu.Lock()
+	segments, err := s.segments()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	replayed := 0
+	for _, segment := range segments {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.config.MaxAge > 0 && time.Since(segment.modTime) > s.config.MaxAge {
+			s.logger.Warn("spool.max_age を過ぎたセグメントを破棄しました",
+				zap.String("segment", segment.path), zap.Time("written_at", segment.modTime))
+			_ = os.Remove(segment.path)
+			continue
+		}
+
+		payload, err := os.ReadFile(segment.path)
+		if err != nil {
+			return fmt.Errorf("セグメント %s の読み込みに失敗しました: %w", segment.path, err)
+		}
+		if err := insert(ctx, payload); err != nil {
+			return fmt.Errorf("セグメント %s の再挿入に失敗しました: %w", segment.path, err)
+		}
+		if err := os.Remove(segment.path); err != nil {
+			return fmt.Errorf("再挿入したセグメント %s の削除に失敗しました: %w", segment.path, err)
+		}
+		replayed++
+	}
+	if replayed > 0 {
+		s.logger.Info("退避したセグメントを再挿入しました", zap.Int("segments", replayed))
+	}
+	return nil
+}
+
+// startReplay は replay_interval ごとに再挿入を試みるループを開始します
+func (s *diskSpool) startReplay(insert func(context.Context, []byte) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stop = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.config.ReplayInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.replay(ctx, insert); err != nil && !errors.Is(err, context.Canceled) {
+					s.logger.Warn("セグメントの再挿入を中断しました、次回に再試行します", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// shutdown は再挿入ループを停止します（未再挿入のセグメントはディスクに残り、次回起動時に再挿入されます）
+func (s *diskSpool) shutdown() {
+	if s == nil || s.stop == nil {
+		return
+	}
+	s.stop()
+	<-s.done
+}
+
+// spoolSegment はセグメントファイルの情報です
+type spoolSegment struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// segments はセグメントファイルを古い順に返します（ファイル名は書き込み時刻で始まるため名前順が時刻順）
+func (s *diskSpool) segments() ([]spoolSegment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("スプールディレクトリの読み込みに失敗しました: %w", err)
+	}
+	segments := make([]spoolSegment, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), spoolSegmentExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, spoolSegment{
+			path:    filepath.Join(s.dir, entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	slices.SortFunc(segments, func(a, b spoolSegment) int { return strings.Compare(a.path, b.path) })
+	return segments, nil
+}
@@ -0,0 +1,9 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package stable はダッシュボード・アラート・コンパニオン拡張から参照される、エクスポーターの安定した識別子を提供します
+//
+// 内部メトリクス名、ライフサイクルイベント名、破棄理由、エクスポーターが追加する列名、公開APIが返すエラーを定義します
+// このパッケージの識別子と値はメジャーバージョン内で変更・削除せず、追加のみ行います（セマンティックバージョニングの互換性の対象）
+// エクスポーターはこのパッケージの値を使用するため、リファクタリングで名前が変わることはありません
+package stable
@@ -0,0 +1,23 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package stable
+
+import "errors"
+
+// 公開API（FindTraceTimeRange, NewPurger など）とパイプラインに返すエラー
+// エラーは文脈を付加して返すことがあるため、errors.Is で判定してください
+var (
+	// ErrTraceNotFound は検索テーブルにトレースIDが見つからない場合のエラーです
+	ErrTraceNotFound = errors.New("トレースIDが見つかりません")
+	// ErrLookupTableUnavailable はトレースID-タイムスタンプ検索テーブルが作成されない設定の場合のエラーです
+	ErrLookupTableUnavailable = errors.New("検索テーブルがありません")
+	// ErrSoftDeleteDisabled は soft_delete.enabled が無効の設定で削除しようとした場合のエラーです
+	ErrSoftDeleteDisabled = errors.New("soft_delete.enabled が無効です")
+	// ErrMissingAttributeKey は削除対象の属性キーが空の場合のエラーです
+	ErrMissingAttributeKey = errors.New("削除対象の属性キーを指定してください")
+	// ErrFairnessDeferred は fairness.max_service_share を超えたデータをリトライに後回しにした場合にパイプラインに返すエラーです
+	ErrFairnessDeferred = errors.New("fairness.max_service_share を超えたデータを次の送信に後回しにしました")
+	// ErrCircuitBreakerOpen はサーキットブレーカーがオープンのため挿入しなかった場合のエラーです
+	ErrCircuitBreakerOpen = errors.New("サーキットブレーカーがオープンです")
+)
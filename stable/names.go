@@ -0,0 +1,54 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package stable
+
+// コレクターの内部テレメトリに公開するメトリクス名（属性 signal でシグナルを区別する）
+const (
+	MetricRowsInserted           = "otelcol_mylogexporter_rows_inserted"            // ClickHouseに挿入した行数
+	MetricInsertBatchSize        = "otelcol_mylogexporter_insert_batch_size"        // 1回の挿入バッチに含まれる行数
+	MetricInsertDuration         = "otelcol_mylogexporter_insert_duration"          // 挿入バッチのコミットまでにかかった時間
+	MetricDBErrors               = "otelcol_mylogexporter_db_errors"                // 挿入時に発生したDBエラー数（属性 error.code）
+	MetricTemplateRenderFailures = "otelcol_mylogexporter_template_render_failures" // SQLテンプレートのレンダリングに失敗した回数
+	MetricTruncatedAttributeKeys = "otelcol_mylogexporter_truncated_attribute_keys" // 最大長を超えたため短縮した属性キーの数
+	MetricOverflowPoints         = "otelcol_mylogexporter_metric_overflow_points"   // ストリーム数の上限を超えたデータポイント数（属性 action）
+	MetricFilterPreviewItems     = "otelcol_mylogexporter_filter_preview_items"     // filter_preview で破棄・集約されるはずだったアイテム数
+	MetricFairnessDeferredItems  = "otelcol_mylogexporter_fairness_deferred_items"  // fairness により挿入を後回しにしたアイテム数
+	MetricDBConnections          = "otelcol_mylogexporter_db_connections"           // 接続プールの接続数（属性 state）
+	MetricUtilization            = "otelcol_mylogexporter_utilization"              // エクスポーターの使用率
+)
+
+// ライフサイクルイベントのログレコードの EventName
+const (
+	EventSchemaCreated      = "mylogexporter.schema.created"
+	EventConnectionLost     = "mylogexporter.connection.lost"
+	EventConnectionRestored = "mylogexporter.connection.restored"
+	EventBatchDropped       = "mylogexporter.batch.dropped"
+	EventAnomalyDetected    = "mylogexporter.anomaly.detected"
+)
+
+// データを破棄した理由（mylogexporter.batch.dropped イベントの reason 属性と診断情報の破棄件数のキー）
+const (
+	DropReasonPermanentError     = "permanent_error"      // リトライしても成功しない挿入エラー
+	DropReasonCircuitBreakerOpen = "circuit_breaker_open" // サーキットブレーカーがオープン（スプール無効時）
+	DropReasonCardinalityLimit   = "cardinality_limit"    // メトリクスのストリーム数の上限
+	DropReasonMinSeverity        = "min_severity"         // ログの最小重要度未満
+	DropReasonUnsupportedMetric  = "unsupported_metric"   // 型が不明、またはデータポイントのないメトリクス
+	DropReasonTargetFailed       = "target_failed"        // ベストエフォートの追加の書き込み先への挿入の失敗
+	DropReasonFilter             = "filter"               // OTTL の条件式によるフィルタ
+	DropReasonStaleMarker        = "stale_marker"         // メトリクスのステールマーカー（metrics.staleness_handling: drop）
+)
+
+// エクスポーターが設定に応じて既定の列に追加する列名
+const (
+	ColumnCollectorInstanceID = "CollectorInstanceId" // source_columns: 書き込んだコレクターの service.instance.id
+	ColumnCollectorPipeline   = "CollectorPipeline"   // source_columns: パイプライン名
+	ColumnCollectorReceiver   = "CollectorReceiver"   // source_columns: レシーバー名
+	ColumnAnomalyScore        = "AnomalyScore"        // anomaly_detection: サービスの異常度（トレース）
+	ColumnBodyJSON            = "BodyJSON"            // logs.parse_body_json: JSONオブジェクトの本文（ログ）
+	ColumnBodyEmbedding       = "BodyEmbedding"       // log_embeddings: 本文の埋め込みベクトル（ログ）
+	ColumnDimensionsHash      = "DimensionsHash"      // metrics_dimensions: リソース・スコープの組のハッシュ（メトリクス）
+	ColumnTruncated           = "Truncated"           // max_attribute_length・max_body_length: 属性値・本文を短縮した行（トレース・ログ）
+	ColumnIngestTimestamp     = "IngestTimestamp"     // record_ingest_time: ClickHouseが行を書き込んだ時刻（全シグナル）
+	ColumnExportTimestamp     = "ExportTimestamp"     // record_ingest_time: エクスポーターが挿入を送信した時刻（全シグナル）
+)
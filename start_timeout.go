@@ -0,0 +1,38 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// validateStartTimeout は start_timeout を検証します
+func (cfg *Config) validateStartTimeout() error {
+	if cfg.StartTimeout < 0 {
+		return fmt.Errorf("start_timeout は0以上である必要があります: %s", cfg.StartTimeout)
+	}
+	return nil
+}
+
+// startContext は開始処理（データベース・テーブルの作成、接続テストなど）に start_timeout を適用したコンテキストを返します（0の場合はタイムアウトなし）
+// 応答しないClickHouseノードでDDLが終わらず、コレクターの起動が止まり続けるのを防ぎます
+func (cfg *Config) startContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cfg.StartTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, cfg.StartTimeout)
+}
+
+// startError は開始処理がタイムアウト・キャンセルにより中断された場合に、その旨をエラーに付加します
+func startError(ctx context.Context, cfg *Config, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && cfg.StartTimeout > 0 {
+		return fmt.Errorf("start_timeout（%s）以内に開始処理が完了しませんでした: %w", cfg.StartTimeout, err)
+	}
+	return fmt.Errorf("開始処理が中断されました: %w", err)
+}
@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/xextension/storage"
+)
+
+// stateStore はストレージ拡張（state_storage）にエクスポーターの状態を保存します
+// 状態を持つ変換（スパン名の上限など）が再起動で初期化されないよう、終了時に保存し開始時に復元します
+type stateStore struct {
+	client storage.Client
+}
+
+// newStateStore はストレージ拡張のクライアントを取得します（state_storage 未指定の場合は nil）
+func newStateStore(ctx context.Context, host component.Host, storageID *component.ID, id component.ID, signal string) (*stateStore, error) {
+	if storageID == nil {
+		return nil, nil
+	}
+	ext, ok := host.GetExtensions()[*storageID]
+	if !ok {
+		return nil, fmt.Errorf("state_storage に指定された拡張 %s が見つかりません", storageID)
+	}
+	storageExt, ok := ext.(storage.Extension)
+	if !ok {
+		return nil, fmt.Errorf("state_storage に指定された拡張 %s はストレージ拡張ではありません", storageID)
+	}
+	client, err := storageExt.GetClient(ctx, component.KindExporter, id, signal)
+	if err != nil {
+		return nil, fmt.Errorf("ストレージ拡張 %s のクライアントの取得に失敗しました: %w", storageID, err)
+	}
+	return &stateStore{client: client}, nil
+}
+
+// load は保存された状態を v に読み込み、状態が保存されていたかどうかを返します
+func (s *stateStore) load(ctx context.Context, key string, v any) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	data, err := s.client.Get(ctx, key)
+	if err != nil || data == nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("保存された状態 %s の読み込みに失敗しました: %w", key, err)
+	}
+	return true, nil
+}
+
+// save は状態を保存します
+func (s *stateStore) save(ctx context.Context, key string, v any) error {
+	if s == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, key, data)
+}
+
+// close はクライアントを閉じます
+func (s *stateStore) close(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	return s.client.Close(ctx)
+}
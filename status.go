@@ -0,0 +1,68 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+// componentStatus はDB接続の状態をコレクターに報告し、ヘルスチェック拡張（health_check など）に反映させます
+// 挿入のたびに報告しないよう、状態が変化した場合のみ報告します
+type componentStatus struct {
+	host component.Host
+
+	mu   sync.Mutex
+	last componentstatus.Status
+}
+
+// newComponentStatus はホストへの状態報告を作成します
+func newComponentStatus(host component.Host) *componentStatus {
+	return &componentStatus{host: host, last: componentstatus.StatusStarting}
+}
+
+// ok はデータベースに接続できていることを報告します
+func (s *componentStatus) ok() {
+	s.report(componentstatus.NewEvent(componentstatus.StatusOK))
+}
+
+// recoverable はデータベースに一時的に接続できないことを報告します（挿入が成功すれば ok に戻る）
+func (s *componentStatus) recoverable(err error) {
+	s.report(componentstatus.NewRecoverableErrorEvent(err))
+}
+
+// permanent はスキーマの作成に失敗するなど、設定の見直しが必要なエラーを報告します
+func (s *componentStatus) permanent(err error) {
+	s.report(componentstatus.NewPermanentErrorEvent(err))
+}
+
+// recordInsert は挿入結果に応じた状態を報告します
+// スキーマの不一致など、リトライしても成功しないエラーは永続的なエラーとして報告します
+func (s *componentStatus) recordInsert(err error) {
+	switch {
+	case err == nil:
+		s.ok()
+	case consumererror.IsPermanent(err):
+		s.permanent(err)
+	default:
+		s.recoverable(err)
+	}
+}
+
+func (s *componentStatus) report(event *componentstatus.Event) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// エラーの状態は内容が変わっても同じ状態として扱う
+	if s.last == event.Status() {
+		return
+	}
+	s.last = event.Status()
+	componentstatus.ReportStatus(s.host, event)
+}
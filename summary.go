@@ -0,0 +1,150 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pushSummary は送信ごとの処理結果を集計し、summary_interval ごとに1行のサマリーとしてログに記録します
+// 送信が毎秒数百回に及ぶ環境で、送信ごとのInfoログが大量に出力されるのを防ぎます
+// live_reload で summary_interval が変わった場合は、それまでの集計結果を記録してから新しい間隔で集計します
+type pushSummary struct {
+	live     *liveConfig
+	logger   *zap.Logger
+	message  string   // ログのメッセージ（prefix の後に続ける）
+	keys     []string // 集計する件数のフィールド名（record の values と同じ順）
+	interval atomic.Int64
+	reset    chan time.Duration
+
+	mu       sync.Mutex
+	since    time.Time
+	pushes   int
+	failures int
+	totals   []int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newPushSummary は処理結果の集計を開始します
+// summary_interval が0で live_reload も指定されていない場合は nil で、送信ごとにログを記録します
+func newPushSummary(live *liveConfig, logger *zap.Logger, message string, keys ...string) *pushSummary {
+	interval := live.load().SummaryInterval
+	if interval <= 0 && !live.reloadable() {
+		return nil
+	}
+	s := &pushSummary{
+		live:    live,
+		logger:  logger,
+		message: message,
+		keys:    keys,
+		reset:   make(chan time.Duration),
+		since:   time.Now(),
+		totals:  make([]int, len(keys)),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	s.interval.Store(int64(interval))
+	go s.run(interval)
+	live.subscribe(func(settings liveSettings) { s.setInterval(settings.SummaryInterval) })
+	return s
+}
+
+// active は集計中かどうかを返します（false の場合は送信ごとにログを記録する）
+func (s *pushSummary) active() bool {
+	return s != nil && s.interval.Load() > 0
+}
+
+// setInterval は集計の間隔を変更します（0 の場合は集計を止めて送信ごとのログに戻す）
+func (s *pushSummary) setInterval(interval time.Duration) {
+	if time.Duration(s.interval.Swap(int64(interval))) == interval {
+		return
+	}
+	select {
+	case s.reset <- interval:
+	case <-s.done:
+	}
+}
+
+// record は1回の送信の処理結果を加算します
+func (s *pushSummary) record(err error, values ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pushes++
+	if err != nil {
+		s.failures++
+	}
+	for i, v := range values {
+		s.totals[i] += v
+	}
+}
+
+// run は summary_interval ごとに集計結果をログに記録します
+func (s *pushSummary) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(time.Hour)
+	ticker.Stop()
+	if interval > 0 {
+		ticker.Reset(interval)
+	}
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			s.flush()
+			return
+		case interval := <-s.reset:
+			s.flush()
+			ticker.Stop()
+			if interval > 0 {
+				ticker.Reset(interval)
+			}
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// flush は前回の記録以降の集計結果（合計と1秒あたりの件数）をログに記録し、集計をリセットします
+// 送信がなかった期間は記録しません
+func (s *pushSummary) flush() {
+	s.mu.Lock()
+	now := time.Now()
+	elapsed := now.Sub(s.since)
+	pushes, failures, totals := s.pushes, s.failures, s.totals
+	s.since, s.pushes, s.failures, s.totals = now, 0, 0, make([]int, len(s.keys))
+	s.mu.Unlock()
+
+	if pushes == 0 {
+		return
+	}
+	seconds := elapsed.Seconds()
+	fields := []zap.Field{
+		zap.Duration("interval", elapsed),
+		zap.Int("pushes", pushes),
+		zap.Int("failed_pushes", failures),
+		zap.Float64("pushes_per_second", float64(pushes)/seconds),
+	}
+	for i, key := range s.keys {
+		fields = append(fields,
+			zap.Int(key, totals[i]),
+			zap.Float64(key+"_per_second", float64(totals[i])/seconds))
+	}
+	s.logger.Info(fmt.Sprintf("%s %s", s.live.load().Prefix, s.message), fields...)
+}
+
+// shutdown は集計を停止し、未記録の集計結果をログに記録します
+func (s *pushSummary) shutdown() {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
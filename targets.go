@@ -0,0 +1,313 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// TargetConfig - 追加の書き込み先（DRリージョンなど別のクラスター）の設定
+// トレース・ログ・メトリクスの各バッチを endpoint と同時に書き込みます（データベース名・テーブル名は endpoint と同じ）
+// プロファイルは書き込み先への挿入に対応していないため、targets を指定した設定ではプロファイルのエクスポーターを作成できません
+// 書き込み先ごとにサーキットブレーカー（circuit_breaker の閾値を使用）で健全性を管理し、障害中の書き込み先はスキップします
+// スプールは endpoint のみが対象で、書き込み先への挿入は退避しません
+//
+//	targets:
+//	  - name: dr
+//	    endpoint: tcp://clickhouse-dr:9000
+//	    required: false
+type TargetConfig struct {
+	Name     string              `mapstructure:"name"`     // ログ・診断情報に使用する書き込み先の名前
+	Endpoint string              `mapstructure:"endpoint"` // 書き込み先のエンドポイント（カンマ区切りで複数指定可）
+	Username string              `mapstructure:"username"` // 認証用ユーザー名（未指定の場合は username）
+	Password configopaque.String `mapstructure:"password"` // 認証用パスワード（未指定の場合は password）
+	// PasswordFile はパスワードを読み込むファイルです（password・password_file とも未指定の場合は endpoint と同じ認証情報）
+	PasswordFile string `mapstructure:"password_file"`
+	ClusterName  string `mapstructure:"cluster_name"` // ClickHouseクラスタ名（未指定の場合は cluster_name）
+	// Required は書き込みの失敗をバッチの失敗とするかどうかです
+	// true の場合はエラーを返してバッチ全体をリトライさせるため、成功済みの書き込み先にも重複して挿入されます
+	// （insert_deduplication_token を有効にすると、重複した挿入は各クラスターのClickHouseが破棄します）
+	// false（ベストエフォート）の場合は失敗をログと診断情報に記録して、その書き込み先への挿入のみを破棄します
+	Required bool `mapstructure:"required"`
+}
+
+// validateTargets は追加の書き込み先の設定を検証します
+func (cfg *Config) validateTargets() error {
+	if len(cfg.Targets) == 0 {
+		return nil
+	}
+	var errs error
+	if cfg.Endpoint == "" {
+		errs = errors.Join(errs, errors.New("targets を指定する場合は endpoint も指定してください"))
+	}
+	names := map[string]bool{}
+	for i, t := range cfg.Targets {
+		switch {
+		case t.Name == "":
+			errs = errors.Join(errs, fmt.Errorf("targets[%d].name を指定してください", i))
+		case names[t.Name]:
+			errs = errors.Join(errs, fmt.Errorf("targets の名前 %s が重複しています", t.Name))
+		}
+		names[t.Name] = true
+		if t.Endpoint == "" {
+			errs = errors.Join(errs, fmt.Errorf("targets[%d].endpoint を指定してください", i))
+			continue
+		}
+		target := cfg.targetConfig(t)
+		if cfg.isPostgres() && target.multiEndpoint() {
+			errs = errors.Join(errs, fmt.Errorf("driver: postgres では targets[%d].endpoint に複数のエンドポイントを指定できません", i))
+			continue
+		}
+		if _, err := buildDSN(target, target.Database); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("targets[%d]: %w", i, err))
+		}
+	}
+	return errs
+}
+
+// checkTargetsSupported は追加の書き込み先に対応していないシグナル（プロファイル）で targets が指定されていないか確認します
+// 指定されている場合、endpoint にのみ書き込まれて書き込み先のデータが欠けるため、エクスポーターの作成を拒否します
+func (cfg *Config) checkTargetsSupported(signal string) error {
+	if len(cfg.Targets) == 0 {
+		return nil
+	}
+	return fmt.Errorf("targets はトレース・ログ・メトリクスのみに対応しています（%s のエクスポーターでは指定できません）", signal)
+}
+
+// targetConfig は接続情報を書き込み先のものに差し替えた設定を返します
+func (cfg *Config) targetConfig(t TargetConfig) *Config {
+	target := *cfg
+	target.Endpoint = t.Endpoint
+	if t.Username != "" {
+		target.Username = t.Username
+	}
+	switch {
+	case t.Password != "":
+		target.Password, target.PasswordFile = t.Password, ""
+	case t.PasswordFile != "":
+		target.Password, target.PasswordFile = "", t.PasswordFile
+	}
+	if t.ClusterName != "" {
+		target.ClusterName = t.ClusterName
+	}
+	target.Targets = nil
+	return &target
+}
+
+// exportTarget は追加の書き込み先への接続と健全性の状態です
+type exportTarget struct {
+	name     string
+	required bool
+	signal   string
+	config   *Config
+	db       *sql.DB
+	native   clickhouse.Conn // ネイティブバッチ挿入用の接続（use_native_batch 有効時のみ）
+	breaker  *circuitBreaker // 書き込み先ごとのサーキットブレーカー（他の書き込み先の障害の影響を受けない）
+	diag     *diagnostics
+	logger   *zap.Logger
+
+	traces  *tracesExporter  // 書き込み先に挿入するトレースエクスポーター（トレースの場合のみ）
+	logs    *logsExporter    // 書き込み先に挿入するログエクスポーター（ログの場合のみ）
+	metrics *metricsExporter // 書き込み先に挿入するメトリクスエクスポーター（メトリクスの場合のみ）
+}
+
+// openExportTargets は追加の書き込み先への接続を作成します
+// ベストエフォートの書き込み先に接続できない場合は警告を記録して除外し、必須の書き込み先の場合はエラーを返します
+func openExportTargets(cfg *Config, signal string, diag *diagnostics, events *lifecycleEvents, logger *zap.Logger) ([]*exportTarget, error) {
+	breaker := cfg.CircuitBreaker
+	breaker.Enabled = true
+
+	var targets []*exportTarget
+	for _, t := range cfg.Targets {
+		target := cfg.targetConfig(t)
+		targetLogger := logger.With(zap.String("target", t.Name))
+		db, err := acquireDBConnection(target, targetLogger)
+		var native clickhouse.Conn
+		if err == nil {
+			if native, err = openNativeConn(target); err != nil {
+				_ = releaseDBConnection(db)
+			}
+		}
+		if err != nil {
+			if t.Required {
+				return targets, fmt.Errorf("書き込み先 %s への接続に失敗しました: %w", t.Name, err)
+			}
+			targetLogger.Warn("書き込み先への接続に失敗しました、この書き込み先には挿入しません", zap.Error(err))
+			continue
+		}
+		targets = append(targets, &exportTarget{
+			name:     t.Name,
+			required: t.Required,
+			signal:   signal,
+			config:   target,
+			db:       db,
+			native:   native,
+			breaker:  newCircuitBreaker(breaker, signal, db, events, targetLogger),
+			diag:     diag,
+			logger:   targetLogger,
+		})
+	}
+	return targets, nil
+}
+
+// closeExportTargets は追加の書き込み先への接続を解放します
+func closeExportTargets(targets []*exportTarget) error {
+	var errs error
+	for _, t := range targets {
+		errs = errors.Join(errs, closeNativeConn(t.native), releaseDBConnection(t.db))
+	}
+	return errs
+}
+
+// schemaError は書き込み先のスキーマ作成・確認の失敗を処理します
+// 必須の書き込み先の場合はエラーを返し、ベストエフォートの場合は警告を記録して挿入時のエラーに任せます
+func (t *exportTarget) schemaError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if t.required {
+		return fmt.Errorf("書き込み先 %s のスキーマの準備に失敗しました: %w", t.name, err)
+	}
+	t.logger.Warn("書き込み先のスキーマの準備に失敗しました", zap.Error(err))
+	return nil
+}
+
+// write は書き込み先への挿入を実行し、失敗した場合は必須の書き込み先のみエラーを返します
+func (t *exportTarget) write(ctx context.Context, items int, insert func() error) error {
+	var err error
+	if t.breaker.allow(ctx) {
+		err = insert()
+		t.breaker.record(err)
+	} else {
+		err = stable.ErrCircuitBreakerOpen
+	}
+	if err == nil {
+		return nil
+	}
+	t.diag.recordError(t.signal, fmt.Errorf("書き込み先 %s: %w", t.name, err))
+	if t.required {
+		t.logger.Error("必須の書き込み先への挿入に失敗しました", zap.Int("items", items), zap.Error(err))
+		return fmt.Errorf("書き込み先 %s への挿入に失敗しました: %w", t.name, err)
+	}
+	t.logger.Warn("書き込み先への挿入に失敗しました、ベストエフォートのため破棄します", zap.Int("dropped_items", items), zap.Error(err))
+	t.diag.recordDropped(t.signal, stable.DropReasonTargetFailed, items)
+	return nil
+}
+
+// startTargets は追加の書き込み先への接続を作成し、書き込み先ごとにスキーマを作成します
+func (e *tracesExporter) startTargets(ctx context.Context) error {
+	targets, err := openExportTargets(e.config, "traces", e.diag, e.events, e.logger)
+	e.targets = targets
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		t.traces = e.derive(t.config, t.db, t.logger)
+		t.traces.native = t.native
+		t.traces.tenants = newTenantRouter(t.config)
+		if err := t.schemaError(t.traces.prepareSchema(ctx)); err != nil {
+			return err
+		}
+		t.traces.schema = newSchemaCache(t.config, t.db, t.config.tracesDatabase(), t.config.TracesTableName, t.config.expectedColumns("traces"), t.logger)
+		if t.config.shouldVerifySchema() {
+			if err := t.schemaError(t.traces.schema.verify(ctx)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeTargets は追加の書き込み先にトレースを挿入します（必須の書き込み先の失敗のみエラーを返す）
+func (e *tracesExporter) writeTargets(ctx context.Context, td ptrace.Traces) error {
+	var errs error
+	for _, t := range e.targets {
+		// 挿入は td を変更しないため、すべての書き込み先と endpoint に同じバッチを渡せる
+		// 失敗した残りは破棄する（必須の書き込み先の失敗はバッチ全体をリトライさせる）
+		errs = errors.Join(errs, t.write(ctx, td.SpanCount(), func() error {
+			_, err := t.traces.insertRoutedTraces(ctx, td)
+			return err
+		}))
+	}
+	return errs
+}
+
+// startTargets は追加の書き込み先への接続を作成し、書き込み先ごとにスキーマを作成します
+func (e *logsExporter) startTargets(ctx context.Context) error {
+	targets, err := openExportTargets(e.config, "logs", e.diag, e.events, e.logger)
+	e.targets = targets
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		t.logs = e.derive(t.config, t.db, t.logger)
+		t.logs.native = t.native
+		t.logs.tenants = newTenantRouter(t.config)
+		if err := t.schemaError(t.logs.prepareSchema(ctx)); err != nil {
+			return err
+		}
+		t.logs.schema = newSchemaCache(t.config, t.db, t.config.logsDatabase(), t.logs.getLogsTableName(), t.config.expectedColumns("logs"), t.logger)
+		if t.config.shouldVerifySchema() {
+			if err := t.schemaError(t.logs.schema.verify(ctx)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeTargets は追加の書き込み先にログを挿入します（必須の書き込み先の失敗のみエラーを返す）
+func (e *logsExporter) writeTargets(ctx context.Context, ld plog.Logs) error {
+	var errs error
+	for _, t := range e.targets {
+		errs = errors.Join(errs, t.write(ctx, ld.LogRecordCount(), func() error {
+			_, err := t.logs.insertRoutedLogs(ctx, ld)
+			return err
+		}))
+	}
+	return errs
+}
+
+// startTargets は追加の書き込み先への接続を作成し、書き込み先ごとにスキーマを作成します
+func (e *metricsExporter) startTargets(ctx context.Context) error {
+	targets, err := openExportTargets(e.config, "metrics", e.diag, e.events, e.logger)
+	e.targets = targets
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		t.metrics = e.derive(t.config, t.db, t.logger)
+		if err := t.schemaError(t.metrics.prepareSchema(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTargets は追加の書き込み先にメトリクスを挿入します（必須の書き込み先の失敗のみエラーを返す）
+// 書き込み先ごとに、ディメンション（metrics_dimensions 有効時）を書き込んでからデータポイントを挿入します
+func (e *metricsExporter) writeTargets(ctx context.Context, md pmetric.Metrics) error {
+	var errs error
+	for _, t := range e.targets {
+		errs = errors.Join(errs, t.write(ctx, md.DataPointCount(), func() error {
+			if err := t.metrics.dimensions.write(ctx, md); err != nil {
+				return err
+			}
+			return t.metrics.insertMetrics(ctx, md)
+		}))
+	}
+	return errs
+}
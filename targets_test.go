@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// 追加の書き込み先に挿入しないプロファイルでは targets を指定できない
+func TestTargetsRejectedForProfiles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Endpoint = "tcp://127.0.0.1:9000"
+	cfg.Targets = []TargetConfig{{Name: "dr", Endpoint: "tcp://127.0.0.1:19000"}}
+	set := embeddedSettings(zap.NewNop())
+
+	if _, err := newProfilesExporter(set, cfg); err == nil {
+		t.Error("newProfilesExporter: targets を指定した設定でエラーが返されませんでした")
+	}
+	if _, err := newMetricsExporter(set, cfg); err != nil {
+		t.Errorf("newMetricsExporter: targets を指定した設定でエラーが返されました: %v", err)
+	}
+
+	cfg.Targets = nil
+	if err := cfg.checkTargetsSupported("profiles"); err != nil {
+		t.Errorf("targets 未指定の場合はエラーになりません: %v", err)
+	}
+}
+
+// メトリクスのデータポイントは endpoint と追加の書き込み先の両方に挿入する
+func TestMetricsTargetsFanOut(t *testing.T) {
+	ctx := context.Background()
+	primaryDir, targetDir := t.TempDir(), t.TempDir()
+	cfg := captureConfig(primaryDir)
+	e, err := newMetricsExporter(embeddedSettings(zap.NewNop()), cfg)
+	if err != nil {
+		t.Fatalf("newMetricsExporter: %v", err)
+	}
+	defer func() { _ = e.shutdown(ctx) }()
+	dr := e.derive(cfg, nil, zap.NewNop())
+	dr.capture = newBatchCapture(CaptureConfig{Directory: targetDir, MaxBatches: 1}, zap.NewNop())
+	e.targets = []*exportTarget{{name: "dr", signal: "metrics", metrics: dr, logger: zap.NewNop()}}
+
+	if err := e.pushMetrics(ctx, insertTestMetrics()); err != nil {
+		t.Fatalf("pushMetrics: %v", err)
+	}
+	for name, dir := range map[string]string{"endpoint": primaryDir, "dr": targetDir} {
+		if batch := readCapture(t, dir, "otel_metrics_sum"); len(batch.Rows) == 0 {
+			t.Errorf("%s: sum テーブルに挿入した行がありません", name)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// meterScope はエクスポーター内部メトリクスの計測スコープ名です
+const meterScope = "github.com/dtamura/myexporter"
+
+// utilizationLatencyWeight は処理時間の指数移動平均で直近の値に与える重みです
+const utilizationLatencyWeight = 0.2
+
+// UtilizationConfig - オートスケーリング（HPA/KEDA）向けの使用率メトリクスの設定
+// 使用率は「処理中のデータ量 / BufferBudget」と「処理時間の移動平均 / TargetLatency」の大きい方で、
+// 1.0 を超えるとエクスポーターが処理能力の限界に達していることを表します
+type UtilizationConfig struct {
+	// BufferBudget は同時に処理中とみなせるデータ量（OTLP protobuf換算のバイト数）の目安です
+	BufferBudget int64 `mapstructure:"buffer_budget"`
+	// TargetLatency は1回の送信処理（挿入・転送を含む）にかける時間の目標です
+	TargetLatency time.Duration `mapstructure:"target_latency"`
+}
+
+// validate は使用率メトリクスの設定を検証します
+func (c UtilizationConfig) validate() error {
+	var errs error
+	if c.BufferBudget <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("utilization.buffer_budget は1以上である必要があります: %d", c.BufferBudget))
+	}
+	if c.TargetLatency <= 0 {
+		errs = errors.Join(errs, fmt.Errorf("utilization.target_latency は0より大きい必要があります: %s", c.TargetLatency))
+	}
+	return errs
+}
+
+// exporterTelemetry はコレクターの内部テレメトリ（TelemetrySettings の MeterProvider）に
+// 挿入性能のメトリクスを公開します
+type exporterTelemetry struct {
+	signal attribute.KeyValue
+
+	rowsInserted    metric.Int64Counter     // 挿入に成功した行数
+	batchSize       metric.Int64Histogram   // 1回の挿入（バッチ）の行数
+	insertDuration  metric.Float64Histogram // 挿入にかかった時間（秒）
+	dbErrors        metric.Int64Counter     // DBエラー数（ClickHouseのエラーコード別）
+	renderFailures  metric.Int64Counter     // SQLテンプレートのレンダリング失敗数
+	truncatedKeys   metric.Int64Counter     // max_attribute_key_length により短縮した属性キー数
+	overflowPoints  metric.Int64Counter     // カーディナリティ制限によりオーバーフロー系列に集約・破棄したデータポイント数
+	previewItems    metric.Int64Counter     // filter_preview で規則により破棄・集約されるはずだったアイテム数
+	deferredItems   metric.Int64Counter     // fairness によりリトライに後回しにしたアイテム数
+	connections     metric.Int64ObservableGauge
+	connectionsStop metric.Registration // 接続数コールバックの登録（shutdownで解除）
+	utilization     metric.Float64ObservableGauge
+	utilizationStop metric.Registration // 使用率コールバックの登録（shutdownで解除）
+
+	budget UtilizationConfig
+	mu     sync.Mutex
+	bytes  int64   // 処理中のデータ量（バイト）
+	ewma   float64 // 送信処理時間の指数移動平均（秒）
+}
+
+// newExporterTelemetry はシグナルごとの内部メトリクスを作成します
+// db が nil でない場合は接続プールの接続数も公開します（同一DSNのシグナル間では共有プールの値）
+func newExporterTelemetry(set component.TelemetrySettings, signal string, db *sql.DB, budget UtilizationConfig) (*exporterTelemetry, error) {
+	meter := set.MeterProvider.Meter(meterScope)
+	t := &exporterTelemetry{signal: attribute.String("signal", signal), budget: budget}
+
+	var errs, err error
+	t.rowsInserted, err = meter.Int64Counter(stable.MetricRowsInserted,
+		metric.WithDescription("ClickHouseに挿入した行数"), metric.WithUnit("{row}"))
+	errs = errors.Join(errs, err)
+	t.batchSize, err = meter.Int64Histogram(stable.MetricInsertBatchSize,
+		metric.WithDescription("1回の挿入バッチに含まれる行数"), metric.WithUnit("{row}"),
+		metric.WithExplicitBucketBoundaries(1, 10, 100, 500, 1000, 5000, 10000, 50000, 100000))
+	errs = errors.Join(errs, err)
+	t.insertDuration, err = meter.Float64Histogram(stable.MetricInsertDuration,
+		metric.WithDescription("挿入バッチのコミットまでにかかった時間"), metric.WithUnit("s"))
+	errs = errors.Join(errs, err)
+	t.dbErrors, err = meter.Int64Counter(stable.MetricDBErrors,
+		metric.WithDescription("挿入時に発生したDBエラー数（error.code はClickHouseのエラーコード）"), metric.WithUnit("{error}"))
+	errs = errors.Join(errs, err)
+	t.renderFailures, err = meter.Int64Counter(stable.MetricTemplateRenderFailures,
+		metric.WithDescription("SQLテンプレートのレンダリングに失敗した回数"), metric.WithUnit("{failure}"))
+	errs = errors.Join(errs, err)
+	t.truncatedKeys, err = meter.Int64Counter(stable.MetricTruncatedAttributeKeys,
+		metric.WithDescription("最大長を超えたため短縮した属性キーの数"), metric.WithUnit("{key}"))
+	errs = errors.Join(errs, err)
+	t.overflowPoints, err = meter.Int64Counter(stable.MetricOverflowPoints,
+		metric.WithDescription("ストリーム数の上限を超えたデータポイント数（action: merged はオーバーフロー系列に集約、dropped は破棄）"), metric.WithUnit("{datapoint}"))
+	errs = errors.Join(errs, err)
+	t.previewItems, err = meter.Int64Counter(stable.MetricFilterPreviewItems,
+		metric.WithDescription("filter_preview で規則（rule）により破棄・集約されるはずだったアイテム数（実際には破棄しない）"), metric.WithUnit("{item}"))
+	errs = errors.Join(errs, err)
+	t.deferredItems, err = meter.Int64Counter(stable.MetricFairnessDeferredItems,
+		metric.WithDescription("サービスの割合の上限（fairness.max_service_share）を超えたため挿入を後回しにしたアイテム数"), metric.WithUnit("{item}"))
+	errs = errors.Join(errs, err)
+	t.connections, err = meter.Int64ObservableGauge(stable.MetricDBConnections,
+		metric.WithDescription("接続プールの接続数（state: in_use, idle）"), metric.WithUnit("{connection}"))
+	errs = errors.Join(errs, err)
+	t.utilization, err = meter.Float64ObservableGauge(stable.MetricUtilization,
+		metric.WithDescription("エクスポーターの使用率（処理中データ量/予算と処理時間/目標の大きい方、1を超えるとスケールアウトの目安）"), metric.WithUnit("1"))
+	errs = errors.Join(errs, err)
+	if errs != nil {
+		return nil, errs
+	}
+
+	t.utilizationStop, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveFloat64(t.utilization, t.currentUtilization(), metric.WithAttributes(t.signal))
+		return nil
+	}, t.utilization)
+	if err != nil {
+		return nil, err
+	}
+
+	if db != nil {
+		t.connectionsStop, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+			stats := db.Stats()
+			o.ObserveInt64(t.connections, int64(stats.InUse), metric.WithAttributes(t.signal, attribute.String("state", "in_use")))
+			o.ObserveInt64(t.connections, int64(stats.Idle), metric.WithAttributes(t.signal, attribute.String("state", "idle")))
+			return nil
+		}, t.connections)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// recordInsert は挿入バッチの結果（行数、所要時間、エラー）を記録します
+func (t *exporterTelemetry) recordInsert(ctx context.Context, table string, rows int, duration time.Duration, err error) {
+	if t == nil {
+		return
+	}
+	attrs := metric.WithAttributes(t.signal, attribute.String("table", table))
+	t.batchSize.Record(ctx, int64(rows), attrs)
+	t.insertDuration.Record(ctx, duration.Seconds(), attrs)
+	if err != nil {
+		t.dbErrors.Add(ctx, 1, metric.WithAttributes(t.signal, attribute.String("table", table), attribute.String("error.code", dbErrorCode(err))))
+		return
+	}
+	t.rowsInserted.Add(ctx, int64(rows), attrs)
+}
+
+// recordRenderFailure はSQLテンプレートのレンダリング失敗を記録します
+func (t *exporterTelemetry) recordRenderFailure(ctx context.Context, template string) {
+	if t == nil {
+		return
+	}
+	t.renderFailures.Add(ctx, 1, metric.WithAttributes(t.signal, attribute.String("template", template)))
+}
+
+// recordTruncatedKeys は短縮した属性キー数を記録します
+func (t *exporterTelemetry) recordTruncatedKeys(ctx context.Context, keys int) {
+	if t == nil || keys == 0 {
+		return
+	}
+	t.truncatedKeys.Add(ctx, int64(keys), metric.WithAttributes(t.signal))
+}
+
+// recordOverflow はカーディナリティ制限によりオーバーフロー系列に集約・破棄したデータポイント数を記録します
+func (t *exporterTelemetry) recordOverflow(ctx context.Context, merged, dropped int) {
+	if t == nil {
+		return
+	}
+	if merged > 0 {
+		t.overflowPoints.Add(ctx, int64(merged), metric.WithAttributes(t.signal, attribute.String("action", "merged")))
+	}
+	if dropped > 0 {
+		t.overflowPoints.Add(ctx, int64(dropped), metric.WithAttributes(t.signal, attribute.String("action", "dropped")))
+	}
+}
+
+// recordPreview は filter_preview で規則により破棄・集約されるはずだったアイテム数を記録します
+func (t *exporterTelemetry) recordPreview(ctx context.Context, rule, action string, items int) {
+	if t == nil || items == 0 {
+		return
+	}
+	t.previewItems.Add(ctx, int64(items), metric.WithAttributes(t.signal,
+		attribute.String("rule", rule), attribute.String("action", action)))
+}
+
+// recordDeferred は fairness により挿入を後回しにしたアイテム数を記録します
+func (t *exporterTelemetry) recordDeferred(ctx context.Context, items int) {
+	if t == nil {
+		return
+	}
+	t.deferredItems.Add(ctx, int64(items), metric.WithAttributes(t.signal))
+}
+
+// beginPush は送信処理の開始を記録し、終了時に呼び出す関数を返します
+// 処理中のデータ量と処理時間は使用率メトリクスの計算に使用されます
+func (t *exporterTelemetry) beginPush(bytes int) func() {
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	t.mu.Lock()
+	t.bytes += int64(bytes)
+	t.mu.Unlock()
+
+	return func() {
+		elapsed := time.Since(start).Seconds()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.bytes -= int64(bytes)
+		if t.ewma == 0 {
+			t.ewma = elapsed
+		} else {
+			t.ewma = utilizationLatencyWeight*elapsed + (1-utilizationLatencyWeight)*t.ewma
+		}
+	}
+}
+
+// currentUtilization は現在の使用率を返します
+func (t *exporterTelemetry) currentUtilization() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return max(
+		float64(t.bytes)/float64(t.budget.BufferBudget),
+		t.ewma/t.budget.TargetLatency.Seconds(),
+	)
+}
+
+// shutdown はメトリクスのコールバックの登録を解除します
+func (t *exporterTelemetry) shutdown() error {
+	if t == nil {
+		return nil
+	}
+	var errs error
+	for _, registration := range []metric.Registration{t.connectionsStop, t.utilizationStop} {
+		if registration != nil {
+			errs = errors.Join(errs, registration.Unregister())
+		}
+	}
+	return errs
+}
+
+// dbErrorCode はエラーからClickHouseのエラーコードを取り出します
+// サーバー例外以外（接続断、タイムアウト等）の場合は種類を表す文字列を返します
+func dbErrorCode(err error) string {
+	var exception *clickhouse.Exception
+	switch {
+	case errors.As(err, &exception):
+		return strconv.Itoa(int(exception.Code))
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
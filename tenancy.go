@@ -0,0 +1,124 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// MultiTenancyConfig - マルチテナント設定
+type MultiTenancyConfig struct {
+	// Enabled はマルチテナント機能を有効化します
+	Enabled bool `mapstructure:"enabled"`
+	// TenantAttribute はテナントを識別するリソース属性キーです（例: tenant.id）
+	TenantAttribute string `mapstructure:"tenant_attribute"`
+	// CreateRowPolicies は管理対象テーブルにテナントごとの行ポリシーを作成します
+	CreateRowPolicies bool `mapstructure:"create_row_policies"`
+	// Tenants はテナントIDと、そのテナントのデータのみ参照できるユーザー/ロールの対応です
+	Tenants map[string][]string `mapstructure:"tenants"`
+	// Routing はテナントごとに挿入先のデータベース・テーブルを振り分けます
+	Routing TenantRoutingConfig `mapstructure:"routing"`
+}
+
+// rowPolicyNamePattern はポリシー名に使用できない文字を判定します
+var rowPolicyNamePattern = regexp.MustCompile(`[^0-9A-Za-z_]`)
+
+// validate はマルチテナント設定を検証します
+func (c MultiTenancyConfig) validate() error {
+	if err := c.Routing.validate(c); err != nil {
+		return err
+	}
+	if !c.Enabled {
+		return nil
+	}
+	if c.TenantAttribute == "" {
+		return fmt.Errorf("multi_tenancy.tenant_attribute を指定してください")
+	}
+	if c.CreateRowPolicies {
+		for tenant, grantees := range c.Tenants {
+			if len(grantees) == 0 {
+				return fmt.Errorf("multi_tenancy.tenants.%s に参照を許可するユーザーまたはロールを指定してください", tenant)
+			}
+		}
+	}
+	return nil
+}
+
+// rowPoliciesEnabled - 行ポリシーを作成するかどうかを判定します
+func (c MultiTenancyConfig) rowPoliciesEnabled() bool {
+	return c.Enabled && c.CreateRowPolicies && len(c.Tenants) > 0
+}
+
+// renderRowPolicySQL は指定シグナルの管理対象テーブルに対するテナントごとの行ポリシー作成SQLを生成します
+// signal が空の場合は全シグナルを対象とします
+func renderRowPolicySQL(cfg *Config, signal string) []SchemaStatement {
+	mt := cfg.MultiTenancy
+	if !mt.rowPoliciesEnabled() {
+		return nil
+	}
+
+	// 出力を安定させるためテナントIDでソート
+	tenants := make([]string, 0, len(mt.Tenants))
+	for tenant := range mt.Tenants {
+		tenants = append(tenants, tenant)
+	}
+	slices.Sort(tenants)
+
+	var stmts []SchemaStatement
+	for _, table := range managedTables(cfg) {
+		if signal != "" && table.signal != signal {
+			continue
+		}
+		for _, tenant := range tenants {
+			policy := "otel_tenant_" + rowPolicyNamePattern.ReplaceAllString(tenant, "_")
+			grantees := make([]string, 0, len(mt.Tenants[tenant]))
+			for _, grantee := range mt.Tenants[tenant] {
+				grantees = append(grantees, quoteIdentifier(grantee))
+			}
+			// ON CLUSTER 句はポリシー名の直後に置く
+			cluster := ""
+			if cfg.ClusterName != "" {
+				cluster = " " + cfg.clusterString()
+			}
+			sql := fmt.Sprintf(`CREATE ROW POLICY IF NOT EXISTS %s%s ON "%s"."%s" FOR SELECT USING %s = %s TO %s`,
+				policy, cluster, table.database, table.name,
+				attributeLookupSQL("ResourceAttributes", quoteString(mt.TenantAttribute), table.jsonAttributes),
+				quoteString(tenant), strings.Join(grantees, ", "))
+			stmts = append(stmts, SchemaStatement{
+				Description: fmt.Sprintf("row policy %s on %s", policy, table.name),
+				SQL:         sql,
+			})
+		}
+	}
+	return stmts
+}
+
+// createRowPolicies は指定シグナルの管理対象テーブルにテナントごとの行ポリシーを作成します
+func createRowPolicies(ctx context.Context, cfg *Config, signal string, db *sql.DB, logger *zap.Logger) error {
+	for _, stmt := range renderRowPolicySQL(cfg, signal) {
+		logger.Debug("行ポリシーを作成しています", zap.String("description", stmt.Description), zap.String("sql", stmt.SQL))
+		if _, err := db.ExecContext(ctx, stmt.SQL); err != nil {
+			return fmt.Errorf("%s の作成に失敗しました: %w", stmt.Description, err)
+		}
+		logger.Info("行ポリシーを作成しました", zap.String("description", stmt.Description))
+	}
+	return nil
+}
+
+// quoteString はClickHouseの文字列リテラルとしてエスケープします
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// quoteIdentifier はClickHouseの識別子（ユーザー名・ロール名など）としてエスケープします
+func quoteIdentifier(s string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(s) + "`"
+}
@@ -0,0 +1,209 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// TenantRoutingConfig - テナントごとの挿入先の振り分け設定（トレース・ログのみ）
+// リソースの multi_tenancy.tenant_attribute の値ごとに、テナント専用のデータベース・テーブルへ挿入します
+// テナントのテーブルは最初にそのテナントのデータを受信した時に作成します（create_schema 有効時のみ）
+// テナント属性のないリソースは通常のテーブルに挿入します
+type TenantRoutingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// DatabasePattern はテナントの挿入先データベース名です（{database}, {tenant} を置換、既定: {database}）
+	DatabasePattern string `mapstructure:"database_pattern"`
+	// TablePattern はテナントの挿入先テーブル名です（{table}, {tenant} を置換、既定: {table}_{tenant}）
+	TablePattern string `mapstructure:"table_pattern"`
+	// MaxCachedTenants は挿入先を記憶するテナントの数の上限です（超えた場合は記憶を破棄し、次の挿入時にテーブルの作成を再実行する）
+	MaxCachedTenants int `mapstructure:"max_cached_tenants"`
+}
+
+// validate はテナントの振り分け設定を検証します
+func (c TenantRoutingConfig) validate(mt MultiTenancyConfig) error {
+	if !c.Enabled {
+		return nil
+	}
+	var errs error
+	if !mt.Enabled || mt.TenantAttribute == "" {
+		errs = errors.Join(errs, errors.New("multi_tenancy.routing を使用する場合は multi_tenancy.enabled と tenant_attribute を指定してください"))
+	}
+	if !strings.Contains(c.DatabasePattern+c.TablePattern, "{tenant}") {
+		errs = errors.Join(errs, errors.New("multi_tenancy.routing の database_pattern または table_pattern に {tenant} を含めてください"))
+	}
+	if c.MaxCachedTenants <= 0 {
+		errs = errors.Join(errs, errors.New("multi_tenancy.routing.max_cached_tenants は1以上である必要があります"))
+	}
+	return errs
+}
+
+// tenantRouter はテナントの挿入先を決定し、挿入先ごとのエクスポーターを初回のみ作成します
+type tenantRouter struct {
+	config *Config
+
+	mu        sync.Mutex
+	exporters map[string]any // 挿入先（データベース.テーブル）ごとの作成済みのエクスポーター
+}
+
+// newTenantRouter はテナントの振り分けを作成します（multi_tenancy.routing 無効の場合は nil）
+func newTenantRouter(cfg *Config) *tenantRouter {
+	if !cfg.MultiTenancy.Routing.Enabled {
+		return nil
+	}
+	return &tenantRouter{config: cfg, exporters: map[string]any{}}
+}
+
+// tenantOf はリソースのテナント名を返します（識別子に使用できない文字は _ に置換、属性がない場合は空文字）
+func (r *tenantRouter) tenantOf(res pcommon.Resource) string {
+	return rowPolicyNamePattern.ReplaceAllString(resourceAttributeString(res, r.config.MultiTenancy.TenantAttribute), "_")
+}
+
+// target はテナントの挿入先データベース・テーブル名を返します
+func (r *tenantRouter) target(database, table, tenant string) (string, string) {
+	routing := r.config.MultiTenancy.Routing
+	replacer := strings.NewReplacer("{database}", database, "{table}", table, "{tenant}", tenant)
+	return replacer.Replace(routing.DatabasePattern), replacer.Replace(routing.TablePattern)
+}
+
+// exporter は挿入先のエクスポーターを返し、未作成の場合は create で作成します
+// 作成中は他の挿入を待たせ、同じテナントのテーブルを重複して作成しないようにします
+func (r *tenantRouter) exporter(database, table string, create func() (any, error)) (any, error) {
+	key := database + "." + table
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.exporters[key]; ok {
+		return e, nil
+	}
+	e, err := create()
+	if err != nil {
+		return nil, err
+	}
+	if len(r.exporters) >= r.config.MultiTenancy.Routing.MaxCachedTenants {
+		r.exporters = map[string]any{}
+	}
+	r.exporters[key] = e
+	return e, nil
+}
+
+// insertRoutedTraces はテナントごとの挿入先にトレースを挿入します（振り分けが無効の場合はそのまま挿入）
+// 挿入に失敗した場合は、失敗したテナント（分割した場合は失敗した分割以降）のスパンを残り（rest）として返します
+// td は変更しないため、同じバッチを複数の挿入先（追加の書き込み先など）に渡せます
+func (e *tracesExporter) insertRoutedTraces(ctx context.Context, td ptrace.Traces) (rest ptrace.Traces, err error) {
+	if e.tenants == nil {
+		return e.insertTraceChunks(ctx, td)
+	}
+	parts := map[string]ptrace.Traces{}
+	for _, rs := range td.ResourceSpans().All() {
+		tenant := e.tenants.tenantOf(rs.Resource())
+		part, ok := parts[tenant]
+		if !ok {
+			part = ptrace.NewTraces()
+			parts[tenant] = part
+		}
+		rs.CopyTo(part.ResourceSpans().AppendEmpty())
+	}
+
+	rest = ptrace.NewTraces()
+	var errs error
+	for tenant, part := range parts {
+		exporter := e
+		if tenant != "" {
+			var err error
+			if exporter, err = e.tenantExporter(ctx, tenant); err != nil {
+				part.ResourceSpans().MoveAndAppendTo(rest.ResourceSpans())
+				errs = errors.Join(errs, err)
+				continue
+			}
+		}
+		if remaining, err := exporter.insertTraceChunks(ctx, part); err != nil {
+			remaining.ResourceSpans().MoveAndAppendTo(rest.ResourceSpans())
+			errs = errors.Join(errs, err)
+		}
+	}
+	return rest, errs
+}
+
+// tenantExporter はテナントの挿入先に挿入するエクスポーターを返します
+// 初回は挿入先のスキーマを作成します（create_schema 有効時のみ）
+func (e *tracesExporter) tenantExporter(ctx context.Context, tenant string) (*tracesExporter, error) {
+	cfg := *e.config
+	cfg.TracesDatabase, cfg.TracesTableName = e.tenants.target(e.config.tracesDatabase(), e.config.TracesTableName, tenant)
+	te, err := e.tenants.exporter(cfg.tracesDatabase(), cfg.TracesTableName, func() (any, error) {
+		te := e.derive(&cfg, e.db, e.logger.With(zap.String("tenant", tenant)))
+		if err := te.prepareSchema(ctx); err != nil {
+			return nil, err
+		}
+		te.schema = newSchemaCache(&cfg, e.db, cfg.tracesDatabase(), cfg.TracesTableName, cfg.expectedColumns("traces"), te.logger)
+		return te, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return te.(*tracesExporter), nil
+}
+
+// insertRoutedLogs はテナントごとの挿入先にログを挿入します（振り分けが無効の場合はそのまま挿入）
+// 挿入に失敗した場合は、失敗したテナントのログレコードを残り（rest）として返します（ld は変更しない）
+func (e *logsExporter) insertRoutedLogs(ctx context.Context, ld plog.Logs) (rest plog.Logs, err error) {
+	if e.tenants == nil {
+		return e.insertLogChunks(ctx, ld)
+	}
+	parts := map[string]plog.Logs{}
+	for _, rl := range ld.ResourceLogs().All() {
+		tenant := e.tenants.tenantOf(rl.Resource())
+		part, ok := parts[tenant]
+		if !ok {
+			part = plog.NewLogs()
+			parts[tenant] = part
+		}
+		rl.CopyTo(part.ResourceLogs().AppendEmpty())
+	}
+
+	rest = plog.NewLogs()
+	var errs error
+	for tenant, part := range parts {
+		exporter := e
+		if tenant != "" {
+			var err error
+			if exporter, err = e.tenantExporter(ctx, tenant); err != nil {
+				part.ResourceLogs().MoveAndAppendTo(rest.ResourceLogs())
+				errs = errors.Join(errs, err)
+				continue
+			}
+		}
+		if remaining, err := exporter.insertLogChunks(ctx, part); err != nil {
+			remaining.ResourceLogs().MoveAndAppendTo(rest.ResourceLogs())
+			errs = errors.Join(errs, err)
+		}
+	}
+	return rest, errs
+}
+
+// tenantExporter はテナントの挿入先に挿入するエクスポーターを返します
+// 初回は挿入先のスキーマを作成します（create_schema 有効時のみ）
+func (e *logsExporter) tenantExporter(ctx context.Context, tenant string) (*logsExporter, error) {
+	cfg := *e.config
+	cfg.LogsDatabase, cfg.LogsTableName = e.tenants.target(e.config.logsDatabase(), e.getLogsTableName(), tenant)
+	le, err := e.tenants.exporter(cfg.logsDatabase(), cfg.LogsTableName, func() (any, error) {
+		le := e.derive(&cfg, e.db, e.logger.With(zap.String("tenant", tenant)))
+		if err := le.prepareSchema(ctx); err != nil {
+			return nil, err
+		}
+		le.schema = newSchemaCache(&cfg, e.db, cfg.logsDatabase(), cfg.LogsTableName, cfg.expectedColumns("logs"), le.logger)
+		return le, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return le.(*logsExporter), nil
+}
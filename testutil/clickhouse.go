@@ -0,0 +1,183 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package testutil はエクスポーターの統合テスト用のハーネスを提供します
+//
+// testcontainers-go でClickHouseを起動し、そのClickHouseに書き込むエクスポーターの作成、
+// テストデータの生成、書き込まれた行の確認を行います。フォークで追加したテーブル・列のテストにも使用できます
+//
+//	func TestInsert(t *testing.T) {
+//		ch := testutil.StartClickHouse(t)
+//		cfg := ch.Config(t)
+//		exp := testutil.StartExporters(t, cfg)
+//		if err := exp.Traces.ConsumeTraces(context.Background(), testutil.Traces(10)); err != nil {
+//			t.Fatal(err)
+//		}
+//		if n := ch.CountRows(t, cfg.Database, cfg.TracesTableName); n != 10 {
+//			t.Fatalf("rows = %d", n)
+//		}
+//	}
+//
+// Dockerが利用できない場合はテストをスキップします
+// 環境変数 MYEXPORTER_TEST_CLICKHOUSE_ENDPOINT を指定した場合はコンテナを起動せず、既存のClickHouseを使用します
+package testutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"testing"
+
+	_ "github.com/ClickHouse/clickhouse-go/v2" // clickhouse ドライバーの登録
+	"github.com/testcontainers/testcontainers-go"
+	tcclickhouse "github.com/testcontainers/testcontainers-go/modules/clickhouse"
+	"go.opentelemetry.io/collector/config/configopaque"
+
+	"github.com/dtamura/myexporter"
+)
+
+const (
+	// DefaultImage は起動するClickHouseのイメージです（環境変数 MYEXPORTER_TEST_CLICKHOUSE_IMAGE で変更可）
+	DefaultImage = "clickhouse/clickhouse-server:25.3-alpine"
+
+	// 既存のClickHouseを使用する場合の接続先（tcp://host:port）・ユーザー名・パスワード
+	endpointEnv = "MYEXPORTER_TEST_CLICKHOUSE_ENDPOINT"
+	usernameEnv = "MYEXPORTER_TEST_CLICKHOUSE_USERNAME"
+	passwordEnv = "MYEXPORTER_TEST_CLICKHOUSE_PASSWORD"
+	imageEnv    = "MYEXPORTER_TEST_CLICKHOUSE_IMAGE"
+
+	testUsername = "otel"
+	testPassword = "otel"
+)
+
+// ClickHouse はテスト用のClickHouseへの接続です
+type ClickHouse struct {
+	Endpoint string  // エクスポーターの endpoint に指定する接続先（tcp://host:port）
+	Username string  // 認証用ユーザー名
+	Password string  // 認証用パスワード
+	DB       *sql.DB // 書き込まれた行の確認に使用する接続
+}
+
+// StartClickHouse はClickHouseのコンテナを起動し、テストの終了時に停止します
+// Dockerが利用できない場合はテストをスキップし、起動に失敗した場合はテストを失敗させます
+func StartClickHouse(tb testing.TB) *ClickHouse {
+	tb.Helper()
+	ctx := context.Background()
+
+	ch := &ClickHouse{Endpoint: os.Getenv(endpointEnv), Username: os.Getenv(usernameEnv), Password: os.Getenv(passwordEnv)}
+	if ch.Endpoint == "" {
+		skipIfDockerUnavailable(tb)
+		image := os.Getenv(imageEnv)
+		if image == "" {
+			image = DefaultImage
+		}
+		container, err := tcclickhouse.Run(ctx, image,
+			tcclickhouse.WithUsername(testUsername), tcclickhouse.WithPassword(testPassword))
+		testcontainers.CleanupContainer(tb, container)
+		if err != nil {
+			tb.Fatalf("ClickHouseのコンテナの起動に失敗しました: %v", err)
+		}
+		host, err := container.ConnectionHost(ctx)
+		if err != nil {
+			tb.Fatalf("ClickHouseのコンテナの接続先を取得できません: %v", err)
+		}
+		ch.Endpoint, ch.Username, ch.Password = "tcp://"+host, testUsername, testPassword
+	}
+
+	db, err := sql.Open("clickhouse", fmt.Sprintf("%s?username=%s&password=%s", ch.Endpoint, ch.Username, ch.Password))
+	if err != nil {
+		tb.Fatalf("ClickHouseへの接続に失敗しました: %v", err)
+	}
+	tb.Cleanup(func() { _ = db.Close() })
+	if err := db.PingContext(ctx); err != nil {
+		tb.Fatalf("ClickHouseへの接続に失敗しました: %v", err)
+	}
+	ch.DB = db
+	return ch
+}
+
+// skipIfDockerUnavailable はDockerが利用できない場合にテスト・ベンチマークをスキップします
+// （testcontainers.SkipIfProviderIsNotHealthy は *testing.T のみを受け付けるため、testing.TB 向けに同じ確認を行う）
+func skipIfDockerUnavailable(tb testing.TB) {
+	tb.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			tb.Skipf("Dockerが利用できないためスキップします: %v", r)
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err == nil {
+		err = provider.Health(context.Background())
+	}
+	if err != nil {
+		tb.Skipf("Dockerが利用できないためスキップします: %v", err)
+	}
+}
+
+// Config はこのClickHouseに書き込むエクスポーターの設定を返します
+// テストごとに別のデータベースを使用するため、同じClickHouseで並行してテストを実行できます
+// 送信キューは無効にしているため、Consume* はClickHouseへの挿入が完了してから戻ります
+func (c *ClickHouse) Config(tb testing.TB) *myexporter.Config {
+	tb.Helper()
+	cfg := myexporter.NewFactory().CreateDefaultConfig().(*myexporter.Config)
+	cfg.Endpoint = c.Endpoint
+	cfg.Username = c.Username
+	cfg.Password = configopaque.String(c.Password)
+	cfg.Database = testDatabase(tb)
+	cfg.CreateSchema = true
+	cfg.QueueSettings.Enabled = false
+	cfg.BackOffConfig.Enabled = false
+
+	tb.Cleanup(func() {
+		_, _ = c.DB.ExecContext(context.Background(), fmt.Sprintf("DROP DATABASE IF EXISTS %q", cfg.Database))
+	})
+	return cfg
+}
+
+// testDatabase はテスト名から一意のデータベース名を作成します（サブテスト名の / などを含まない）
+func testDatabase(tb testing.TB) string {
+	sum := sha256.Sum256([]byte(tb.Name()))
+	return "test_" + hex.EncodeToString(sum[:8])
+}
+
+// CountRows はテーブルの行数を返します
+func (c *ClickHouse) CountRows(tb testing.TB, database, table string) uint64 {
+	tb.Helper()
+	var n uint64
+	if err := c.DB.QueryRowContext(context.Background(),
+		fmt.Sprintf("SELECT count() FROM %q.%q", database, table)).Scan(&n); err != nil {
+		tb.Fatalf("%s.%s の行数を取得できません: %v", database, table, err)
+	}
+	return n
+}
+
+// Strings はクエリの結果の1列目を文字列で返します（列の値の確認用）
+func (c *ClickHouse) Strings(tb testing.TB, query string, args ...any) []string {
+	tb.Helper()
+	rows, err := c.DB.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		tb.Fatalf("クエリの実行に失敗しました: %v\n%s", err, query)
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			tb.Fatalf("クエリの結果を読み取れません: %v", err)
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		tb.Fatalf("クエリの結果を読み取れません: %v", err)
+	}
+	return values
+}
+
+// Columns はテーブルの列名を定義順に返します
+func (c *ClickHouse) Columns(tb testing.TB, database, table string) []string {
+	tb.Helper()
+	return c.Strings(tb, "SELECT name FROM system.columns WHERE database = ? AND table = ? ORDER BY position", database, table)
+}
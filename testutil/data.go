@@ -0,0 +1,172 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package testutil
+
+import (
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// ServiceName はテストデータのリソースの service.name です
+const ServiceName = "testutil"
+
+// Traces は n 件のスパン（同じトレースの親子関係、n 件目ごとにエラー）を含むテストデータを返します
+// スパン名は span-0, span-1, ... で、行の並びに依存しない確認に使用できます
+func Traces(n int) ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", ServiceName)
+	ss := rs.ScopeSpans().AppendEmpty()
+	ss.Scope().SetName("testutil")
+
+	now := time.Now()
+	traceID := pcommon.TraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	for i := range n {
+		span := ss.Spans().AppendEmpty()
+		span.SetTraceID(traceID)
+		span.SetSpanID(spanID(i))
+		if i > 0 {
+			span.SetParentSpanID(spanID(0))
+		}
+		span.SetName(fmt.Sprintf("span-%d", i))
+		span.SetKind(ptrace.SpanKindServer)
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(now))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(now.Add(time.Duration(i+1) * time.Millisecond)))
+		span.Attributes().PutInt("testutil.index", int64(i))
+		if i == n-1 {
+			span.Status().SetCode(ptrace.StatusCodeError)
+		}
+	}
+	return td
+}
+
+// spanID はテストデータの i 番目のスパンIDです
+func spanID(i int) pcommon.SpanID {
+	return pcommon.SpanID([8]byte{0, 0, 0, 0, byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i + 1)})
+}
+
+// Logs は n 件のログレコード（本文は log-0, log-1, ...）を含むテストデータを返します
+func Logs(n int) plog.Logs {
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", ServiceName)
+	sl := rl.ScopeLogs().AppendEmpty()
+	sl.Scope().SetName("testutil")
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	for i := range n {
+		lr := sl.LogRecords().AppendEmpty()
+		lr.SetTimestamp(now)
+		lr.SetObservedTimestamp(now)
+		lr.SetSeverityNumber(plog.SeverityNumberInfo)
+		lr.SetSeverityText("INFO")
+		lr.Body().SetStr(fmt.Sprintf("log-%d", i))
+		lr.Attributes().PutInt("testutil.index", int64(i))
+	}
+	return ld
+}
+
+// MetricInterval は Metrics のデータポイントの開始時刻から時刻までの間隔です
+const MetricInterval = time.Minute
+
+// Metrics はメトリクスの型（gauge, sum, histogram, exponential_histogram, summary）ごとに
+// n 件のデータポイントを持つメトリクスを含むテストデータを返します
+// Gauge 以外のデータポイントの開始時刻（StartTimeUnix）は時刻（TimeUnix）の MetricInterval 前です
+func Metrics(n int) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", ServiceName)
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName("testutil")
+
+	now := time.Now()
+	start := pcommon.NewTimestampFromTime(now.Add(-MetricInterval))
+	ts := pcommon.NewTimestampFromTime(now)
+	gauge := sm.Metrics().AppendEmpty()
+	gauge.SetName("testutil.gauge")
+	sum := sm.Metrics().AppendEmpty()
+	sum.SetName("testutil.sum")
+	sum.SetEmptySum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	histogram := sm.Metrics().AppendEmpty()
+	histogram.SetName("testutil.histogram")
+	histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	exponential := sm.Metrics().AppendEmpty()
+	exponential.SetName("testutil.exponential_histogram")
+	exponential.SetEmptyExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	summary := sm.Metrics().AppendEmpty()
+	summary.SetName("testutil.summary")
+	summary.SetEmptySummary()
+	gaugePoints := gauge.SetEmptyGauge().DataPoints()
+
+	for i := range n {
+		g := gaugePoints.AppendEmpty()
+		g.SetTimestamp(ts)
+		g.SetDoubleValue(float64(i))
+		g.Attributes().PutInt("testutil.index", int64(i))
+
+		s := sum.Sum().DataPoints().AppendEmpty()
+		s.SetStartTimestamp(start)
+		s.SetTimestamp(ts)
+		s.SetIntValue(int64(i))
+		s.Attributes().PutInt("testutil.index", int64(i))
+
+		h := histogram.Histogram().DataPoints().AppendEmpty()
+		h.SetStartTimestamp(start)
+		h.SetTimestamp(ts)
+		h.SetCount(uint64(i + 1))
+		h.SetSum(float64(i))
+		h.ExplicitBounds().FromRaw([]float64{1, 10})
+		h.BucketCounts().FromRaw([]uint64{uint64(i + 1), 0, 0})
+		h.Attributes().PutInt("testutil.index", int64(i))
+
+		e := exponential.ExponentialHistogram().DataPoints().AppendEmpty()
+		e.SetStartTimestamp(start)
+		e.SetTimestamp(ts)
+		e.SetCount(uint64(i + 1))
+		e.SetSum(float64(i))
+		e.Positive().BucketCounts().FromRaw([]uint64{uint64(i + 1)})
+		e.Attributes().PutInt("testutil.index", int64(i))
+
+		q := summary.Summary().DataPoints().AppendEmpty()
+		q.SetStartTimestamp(start)
+		q.SetTimestamp(ts)
+		q.SetCount(uint64(i + 1))
+		q.SetSum(float64(i))
+		q.Attributes().PutInt("testutil.index", int64(i))
+	}
+	return md
+}
+
+// Profiles は n 件のプロファイル（i 件目はサンプル数 i+1、サンプリング周期の種類は cpu/nanoseconds）を含むテストデータを返します
+// プロファイルIDは i+1 を末尾のバイトとする値で、行の並びに依存しない確認に使用できます
+func Profiles(n int) pprofile.Profiles {
+	pd := pprofile.NewProfiles()
+	// 文字列テーブルの先頭は空文字列とする（インデックス 0 は未設定を表す）
+	pd.ProfilesDictionary().StringTable().Append("", "cpu", "nanoseconds")
+	rp := pd.ResourceProfiles().AppendEmpty()
+	rp.Resource().Attributes().PutStr("service.name", ServiceName)
+	sp := rp.ScopeProfiles().AppendEmpty()
+	sp.Scope().SetName("testutil")
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	for i := range n {
+		profile := sp.Profiles().AppendEmpty()
+		profile.SetProfileID(pprofile.ProfileID([16]byte{15: byte(i + 1)}))
+		profile.SetTime(now)
+		profile.SetDuration(pcommon.Timestamp(time.Second))
+		profile.PeriodType().SetTypeStrindex(1)
+		profile.PeriodType().SetUnitStrindex(2)
+		profile.SetPeriod(10_000_000)
+		for range i + 1 {
+			profile.Sample().AppendEmpty()
+		}
+	}
+	return pd
+}
@@ -0,0 +1,62 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package testutil
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/exporter/xexporter"
+
+	"github.com/dtamura/myexporter"
+)
+
+// Exporters は起動済みのシグナルごとのエクスポーターです
+type Exporters struct {
+	Traces   exporter.Traces
+	Logs     exporter.Logs
+	Metrics  exporter.Metrics
+	Profiles xexporter.Profiles
+}
+
+// StartExporters はすべてのシグナルのエクスポーターを作成・起動し（create_schema 有効時はテーブルも作成される）、
+// テストの終了時に停止します
+func StartExporters(tb testing.TB, cfg *myexporter.Config) *Exporters {
+	tb.Helper()
+	ctx := context.Background()
+	factory := myexporter.NewFactory()
+	set := exportertest.NewNopSettings(factory.Type())
+
+	var exp Exporters
+	var err error
+	if exp.Traces, err = factory.CreateTraces(ctx, set, cfg); err != nil {
+		tb.Fatalf("トレースエクスポーターの作成に失敗しました: %v", err)
+	}
+	if exp.Logs, err = factory.CreateLogs(ctx, set, cfg); err != nil {
+		tb.Fatalf("ログエクスポーターの作成に失敗しました: %v", err)
+	}
+	if exp.Metrics, err = factory.CreateMetrics(ctx, set, cfg); err != nil {
+		tb.Fatalf("メトリクスエクスポーターの作成に失敗しました: %v", err)
+	}
+	if exp.Profiles, err = factory.CreateProfiles(ctx, set, cfg); err != nil {
+		tb.Fatalf("プロファイルエクスポーターの作成に失敗しました: %v", err)
+	}
+
+	host := componenttest.NewNopHost()
+	for _, c := range []component.Component{exp.Traces, exp.Logs, exp.Metrics, exp.Profiles} {
+		if err := c.Start(ctx, host); err != nil {
+			tb.Fatalf("エクスポーターの起動に失敗しました: %v", err)
+		}
+		tb.Cleanup(func() {
+			if err := c.Shutdown(context.Background()); err != nil {
+				tb.Errorf("エクスポーターの停止に失敗しました: %v", err)
+			}
+		})
+	}
+	return &exp
+}
@@ -0,0 +1,241 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build integration
+
+// 統合テストはClickHouseのコンテナ（または MYEXPORTER_TEST_CLICKHOUSE_ENDPOINT）が必要なため、
+// go test -tags integration ./testutil/ で実行します
+
+package testutil_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/dtamura/myexporter/testutil"
+)
+
+const integrationRows = 10
+
+func TestIntegrationTraces(t *testing.T) {
+	ch := testutil.StartClickHouse(t)
+	cfg := ch.Config(t)
+	exp := testutil.StartExporters(t, cfg)
+
+	if err := exp.Traces.ConsumeTraces(context.Background(), testutil.Traces(integrationRows)); err != nil {
+		t.Fatalf("ConsumeTraces: %v", err)
+	}
+	if n := ch.CountRows(t, cfg.Database, cfg.TracesTableName); n != integrationRows {
+		t.Fatalf("行数 = %d, want %d", n, integrationRows)
+	}
+
+	table := fmt.Sprintf("%q.%q", cfg.Database, cfg.TracesTableName)
+	var want []string
+	for i := range integrationRows {
+		want = append(want, fmt.Sprintf("span-%d", i))
+	}
+	slices.Sort(want)
+	if got := ch.Strings(t, "SELECT SpanName FROM "+table+" ORDER BY SpanName"); !slices.Equal(got, want) {
+		t.Errorf("SpanName = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "ServiceName",
+			query: "SELECT DISTINCT ServiceName FROM " + table,
+			want:  []string{testutil.ServiceName},
+		},
+		{
+			name:  "TraceId",
+			query: "SELECT DISTINCT TraceId FROM " + table,
+			want:  []string{"0102030405060708090a0b0c0d0e0f10"},
+		},
+		{
+			name:  "ルートスパンの親スパンIDは空",
+			query: "SELECT ParentSpanId FROM " + table + " WHERE SpanName = 'span-0'",
+			want:  []string{""},
+		},
+		{
+			name:  "子スパンの親スパンID",
+			query: "SELECT DISTINCT ParentSpanId FROM " + table + " WHERE SpanName != 'span-0'",
+			want:  []string{"0000000000000001"},
+		},
+		{
+			name:  "最後のスパンのみエラー",
+			query: "SELECT SpanName FROM " + table + " WHERE StatusCode = 'Error'",
+			want:  []string{fmt.Sprintf("span-%d", integrationRows-1)},
+		},
+		{
+			name:  "スパン属性",
+			query: "SELECT SpanAttributes['testutil.index'] FROM " + table + " WHERE SpanName = 'span-3'",
+			want:  []string{"3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ch.Strings(t, tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntegrationLogs(t *testing.T) {
+	ch := testutil.StartClickHouse(t)
+	cfg := ch.Config(t)
+	exp := testutil.StartExporters(t, cfg)
+
+	if err := exp.Logs.ConsumeLogs(context.Background(), testutil.Logs(integrationRows)); err != nil {
+		t.Fatalf("ConsumeLogs: %v", err)
+	}
+	if n := ch.CountRows(t, cfg.Database, cfg.LogsTableName); n != integrationRows {
+		t.Fatalf("行数 = %d, want %d", n, integrationRows)
+	}
+
+	table := fmt.Sprintf("%q.%q", cfg.Database, cfg.LogsTableName)
+	var want []string
+	for i := range integrationRows {
+		want = append(want, fmt.Sprintf("log-%d", i))
+	}
+	slices.Sort(want)
+	if got := ch.Strings(t, "SELECT Body FROM "+table+" ORDER BY Body"); !slices.Equal(got, want) {
+		t.Errorf("Body = %v, want %v", got, want)
+	}
+	if got := ch.Strings(t, "SELECT DISTINCT concat(ServiceName, ' ', SeverityText, ' ', toString(SeverityNumber)) FROM "+table); !slices.Equal(got, []string{testutil.ServiceName + " INFO 9"}) {
+		t.Errorf("ServiceName・SeverityText・SeverityNumber = %v", got)
+	}
+	if got := ch.Strings(t, "SELECT LogAttributes['testutil.index'] FROM "+table+" WHERE Body = 'log-7'"); !slices.Equal(got, []string{"7"}) {
+		t.Errorf("LogAttributes['testutil.index'] = %v, want [7]", got)
+	}
+}
+
+func TestIntegrationMetrics(t *testing.T) {
+	ch := testutil.StartClickHouse(t)
+	cfg := ch.Config(t)
+	exp := testutil.StartExporters(t, cfg)
+
+	if err := exp.Metrics.ConsumeMetrics(context.Background(), testutil.Metrics(integrationRows)); err != nil {
+		t.Fatalf("ConsumeMetrics: %v", err)
+	}
+
+	// AggregationTemporality は Delta = 1、Cumulative = 2（Gauge・Summary の行には列がない）
+	tests := []struct {
+		table       string
+		metric      string
+		temporality string
+	}{
+		{table: "otel_metrics_gauge", metric: "testutil.gauge"},
+		{table: "otel_metrics_sum", metric: "testutil.sum", temporality: "2"},
+		{table: "otel_metrics_histogram", metric: "testutil.histogram", temporality: "1"},
+		{table: "otel_metrics_exponential_histogram", metric: "testutil.exponential_histogram", temporality: "1"},
+		{table: "otel_metrics_summary", metric: "testutil.summary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			if n := ch.CountRows(t, cfg.Database, tt.table); n != integrationRows {
+				t.Fatalf("行数 = %d, want %d", n, integrationRows)
+			}
+			table := fmt.Sprintf("%q.%q", cfg.Database, tt.table)
+			if got := ch.Strings(t, "SELECT DISTINCT concat(ServiceName, ' ', MetricName) FROM "+table); !slices.Equal(got, []string{testutil.ServiceName + " " + tt.metric}) {
+				t.Errorf("ServiceName・MetricName = %v", got)
+			}
+			if got := ch.Strings(t, "SELECT toString(count()) FROM "+table+" WHERE Attributes['testutil.index'] = '7'"); !slices.Equal(got, []string{"1"}) {
+				t.Errorf("Attributes['testutil.index'] = '7' の行数 = %v, want [1]", got)
+			}
+			if tt.temporality == "" {
+				return
+			}
+			if got := ch.Strings(t, "SELECT DISTINCT toString(AggregationTemporality) FROM "+table); !slices.Equal(got, []string{tt.temporality}) {
+				t.Errorf("AggregationTemporality = %v, want [%s]", got, tt.temporality)
+			}
+			want := fmt.Sprint(testutil.MetricInterval.Milliseconds())
+			if got := ch.Strings(t, "SELECT DISTINCT toString(toUnixTimestamp64Milli(TimeUnix) - toUnixTimestamp64Milli(StartTimeUnix)) FROM "+table); !slices.Equal(got, []string{want}) {
+				t.Errorf("StartTimeUnix から TimeUnix までの間隔（ミリ秒） = %v, want [%s]", got, want)
+			}
+		})
+	}
+}
+
+// metrics_dimensions を有効にした場合は、リソースとスコープの組をディメンションテーブルに書き込み、
+// データポイントの行はその組を DimensionsHash で参照する
+func TestIntegrationMetricsDimensions(t *testing.T) {
+	ch := testutil.StartClickHouse(t)
+	cfg := ch.Config(t)
+	cfg.MetricsDimensions.Enabled = true
+	exp := testutil.StartExporters(t, cfg)
+
+	if err := exp.Metrics.ConsumeMetrics(context.Background(), testutil.Metrics(integrationRows)); err != nil {
+		t.Fatalf("ConsumeMetrics: %v", err)
+	}
+	// リソースとスコープの組は1つのみ
+	if n := ch.CountRows(t, cfg.Database, cfg.MetricsDimensions.TableName); n != 1 {
+		t.Fatalf("行数 = %d, want 1", n)
+	}
+	table := fmt.Sprintf("%q.%q", cfg.Database, cfg.MetricsDimensions.TableName)
+	if got := ch.Strings(t, "SELECT concat(ServiceName, ' ', ScopeName) FROM "+table); !slices.Equal(got, []string{testutil.ServiceName + " testutil"}) {
+		t.Errorf("ServiceName・ScopeName = %v", got)
+	}
+
+	if n := ch.CountRows(t, cfg.Database, "otel_metrics_sum"); n != integrationRows {
+		t.Fatalf("otel_metrics_sum の行数 = %d, want %d", n, integrationRows)
+	}
+	sum := fmt.Sprintf("%q.%q", cfg.Database, "otel_metrics_sum")
+	query := "SELECT toString(count()) FROM " + sum + " WHERE DimensionsHash IN (SELECT DimensionsHash FROM " + table + ")"
+	if got := ch.Strings(t, query); !slices.Equal(got, []string{fmt.Sprint(integrationRows)}) {
+		t.Errorf("ディメンションテーブルの組を参照するデータポイント数 = %v, want [%d]", got, integrationRows)
+	}
+}
+
+func TestIntegrationProfiles(t *testing.T) {
+	ch := testutil.StartClickHouse(t)
+	cfg := ch.Config(t)
+	exp := testutil.StartExporters(t, cfg)
+
+	if err := exp.Profiles.ConsumeProfiles(context.Background(), testutil.Profiles(integrationRows)); err != nil {
+		t.Fatalf("ConsumeProfiles: %v", err)
+	}
+	if n := ch.CountRows(t, cfg.Database, cfg.ProfilesTableName); n != integrationRows {
+		t.Fatalf("行数 = %d, want %d", n, integrationRows)
+	}
+
+	table := fmt.Sprintf("%q.%q", cfg.Database, cfg.ProfilesTableName)
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "ServiceName・ScopeName",
+			query: "SELECT DISTINCT concat(ServiceName, ' ', ScopeName) FROM " + table,
+			want:  []string{testutil.ServiceName + " testutil"},
+		},
+		{
+			name:  "PeriodType・Period",
+			query: "SELECT DISTINCT concat(PeriodType, ' ', toString(Period)) FROM " + table,
+			want:  []string{"cpu/nanoseconds 10000000"},
+		},
+		{
+			name:  "Duration",
+			query: "SELECT DISTINCT toString(Duration) FROM " + table,
+			want:  []string{"1000000000"},
+		},
+		{
+			name:  "SampleCount",
+			query: "SELECT toString(SampleCount) FROM " + table + " WHERE ProfileId = '00000000000000000000000000000004'",
+			want:  []string{"4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ch.Strings(t, tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal/queries"
+	"github.com/dtamura/myexporter/stable"
+)
+
+// traceLookupTable は検索テーブル名を返します（分散テーブル構成では全シャードを参照する分散テーブル）
+func (cfg *Config) traceLookupTable() string {
+	return cfg.TracesTableName + "_trace_id_ts"
+}
+
+// FindTraceTimeRange はトレースID-タイムスタンプ検索テーブルからトレースの時刻の範囲を返します
+// エクスポーターと同じ設定を渡すことで、トレース検索APIなどのコンパニオン拡張から共有接続プールを使って検索できます
+// トレースIDが見つからない場合は stable.ErrTraceNotFound を返します
+func FindTraceTimeRange(ctx context.Context, cfg *Config, traceID string) (queries.TraceTimeRange, error) {
+	switch {
+	case cfg.isPostgres():
+		return queries.TraceTimeRange{}, fmt.Errorf("driver: postgres では検索テーブルを作成しません: %w", stable.ErrLookupTableUnavailable)
+	case !cfg.TraceIDLookup.LookupTableEnabled:
+		return queries.TraceTimeRange{}, fmt.Errorf("trace_id_lookup.lookup_table_enabled が無効です: %w", stable.ErrLookupTableUnavailable)
+	}
+
+	db, err := acquireDBConnection(cfg, zap.NewNop())
+	if err != nil {
+		return queries.TraceTimeRange{}, err
+	}
+	defer func() { _ = releaseDBConnection(db) }()
+
+	return queries.FindTraceTimeRange(ctx, db, cfg.tracesDatabase(), cfg.traceLookupTable(), traceID)
+}
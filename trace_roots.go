@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/internal"
+	"github.com/dtamura/myexporter/internal/sqltemplates"
+)
+
+// traceRootsOrderBy はルートスパンテーブルのORDER BYです（サービスごとの最近のトレースの一覧のため）
+const traceRootsOrderBy = "(ServiceName, Timestamp)"
+
+// traceRootsTable はルートスパンテーブル名を返します
+func (cfg *Config) traceRootsTable() string {
+	return cfg.TracesTableName + "_roots"
+}
+
+// renderCreateTraceRootsTableSQL - ルートスパンテーブル作成SQLを生成
+// 保持期間はメインテーブルと同じ（トレースのスパンと同時に削除される）
+func (e *tracesExporter) renderCreateTraceRootsTableSQL() (string, error) {
+	return internal.ExecuteSQLTemplate("traces_roots_table.sql", sqltemplates.TracesCreateRootsTable, internal.TableTemplateData{
+		Database: e.config.tracesDatabase(),
+		Table:    e.config.localTable(e.config.TracesTableName),
+		Cluster:  e.config.clusterString(),
+		Engine:   e.config.replicatedEngine("MergeTree()"),
+		OrderBy:  traceRootsOrderBy,
+		TTL:      internal.GenerateTTLExpr(e.config.signalTTL("traces"), "toDateTime(Timestamp)"),
+		Settings: e.config.tableSettings(),
+	})
+}
+
+// renderTraceRootsMaterializedViewSQL - ルートスパンを抽出するマテリアライズドビュー作成SQLを生成
+func (e *tracesExporter) renderTraceRootsMaterializedViewSQL() (string, error) {
+	return internal.ExecuteSQLTemplate("traces_roots_mv.sql", sqltemplates.TracesCreateRootsView, internal.TableTemplateData{
+		Database: e.config.tracesDatabase(),
+		Table:    e.config.localTable(e.config.TracesTableName),
+		Cluster:  e.config.clusterString(),
+	})
+}
+
+// createTraceRootsTable - ルートスパンテーブルとマテリアライズドビューを作成します
+// スパンの挿入時にビューが親を持たないスパンのみを書き込みます（作成前に挿入されたスパンは含まない）
+func (e *tracesExporter) createTraceRootsTable(ctx context.Context) error {
+	createTableSQL, err := e.renderCreateTraceRootsTableSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "traces_roots_table.sql")
+		return err
+	}
+	if err := e.execSQL(ctx, createTableSQL, "trace roots table"); err != nil {
+		return err
+	}
+
+	createViewSQL, err := e.renderTraceRootsMaterializedViewSQL()
+	if err != nil {
+		e.telemetry.recordRenderFailure(ctx, "traces_roots_mv.sql")
+		return err
+	}
+	if err := e.execSQL(ctx, createViewSQL, "trace roots materialized view"); err != nil {
+		return err
+	}
+
+	if err := createDistributedTable(ctx, e.config, e.db, e.config.tracesDatabase(),
+		e.config.traceRootsTable(), e.config.localTable(e.config.TracesTableName)+"_roots", e.logger); err != nil {
+		return err
+	}
+	e.logger.Info("ルートスパンテーブルが正常に作成されました",
+		zap.String("table", e.config.traceRootsTable()))
+	return nil
+}
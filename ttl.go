@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// signalTTL はシグナルのテーブルのデータ保持期間を返します（0の場合は無期限）
+// シグナルごとの指定（traces_ttl など）、ttl、非推奨の ttl_days の順に使用します
+func (cfg *Config) signalTTL(signal string) time.Duration {
+	var override time.Duration
+	switch signal {
+	case "traces":
+		override = cfg.TracesTTL
+	case "logs":
+		override = cfg.LogsTTL
+	case "metrics":
+		override = cfg.MetricsTTL
+	}
+	switch {
+	case override > 0:
+		return override
+	case cfg.TTL > 0:
+		return cfg.TTL
+	default:
+		return time.Duration(cfg.TTLDays) * 24 * time.Hour
+	}
+}
+
+// validateTTL はデータ保持期間の設定を検証します
+func (cfg *Config) validateTTL() error {
+	var errs error
+	for _, t := range []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{"ttl", cfg.TTL},
+		{"traces_ttl", cfg.TracesTTL},
+		{"logs_ttl", cfg.LogsTTL},
+		{"metrics_ttl", cfg.MetricsTTL},
+	} {
+		if t.ttl < 0 {
+			errs = errors.Join(errs, fmt.Errorf("%s は0以上である必要があります: %s", t.name, t.ttl))
+		}
+	}
+	if cfg.TTLDays < 0 {
+		errs = errors.Join(errs, fmt.Errorf("ttl_days は0以上である必要があります: %d", cfg.TTLDays))
+	}
+	// 以前はシグナルによって ttl と ttl_days のどちらを使用するかが異なっていたため、両方の指定は意図と異なる結果になりうる
+	if cfg.TTL > 0 && cfg.TTLDays > 0 {
+		errs = errors.Join(errs, errors.New("ttl と ttl_days は同時に指定できません（ttl_days は非推奨のため ttl を使用し、シグナルごとに変える場合は traces_ttl, logs_ttl, metrics_ttl を指定してください）"))
+	}
+	return errs
+}
+
+// warnDeprecatedTTLDays は非推奨の ttl_days が指定されている場合に ttl への移行を促します
+func warnDeprecatedTTLDays(cfg *Config, logger *zap.Logger) {
+	if cfg.TTLDays > 0 {
+		logger.Warn("ttl_days は非推奨です、ttl を使用してください",
+			zap.Int("ttl_days", cfg.TTLDays),
+			zap.String("ttl", (time.Duration(cfg.TTLDays)*24*time.Hour).String()))
+	}
+}
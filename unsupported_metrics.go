@@ -0,0 +1,105 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// 型が不明、またはデータポイントのないメトリクスの扱い（unsupported_metrics）
+const (
+	unsupportedMetricsKeep   = "keep"   // そのまま処理し、件数にのみ含める（従来の動作）
+	unsupportedMetricsSkip   = "skip"   // 通知せずに取り除く
+	unsupportedMetricsWarn   = "warn"   // 取り除いて警告ログを記録する
+	unsupportedMetricsReject = "reject" // バッチ全体を永続的なエラーとして拒否する
+)
+
+// maxLoggedMetricNames はログ・エラーに含めるメトリクス名の上限です
+const maxLoggedMetricNames = 10
+
+// validateUnsupportedMetrics は unsupported_metrics を検証します
+func (cfg *Config) validateUnsupportedMetrics() error {
+	switch cfg.UnsupportedMetrics {
+	case "", unsupportedMetricsKeep, unsupportedMetricsSkip, unsupportedMetricsWarn, unsupportedMetricsReject:
+		return nil
+	}
+	return fmt.Errorf("unsupported_metrics は keep, skip, warn, reject のいずれかを指定してください: %s", cfg.UnsupportedMetrics)
+}
+
+// removesUnsupportedMetrics は型が不明・データポイントのないメトリクスをバッチから取り除くかどうかを返します
+func (cfg *Config) removesUnsupportedMetrics() bool {
+	return cfg.UnsupportedMetrics == unsupportedMetricsSkip || cfg.UnsupportedMetrics == unsupportedMetricsWarn
+}
+
+// unsupportedMetricReason はメトリクスを処理できない理由を返します（処理できる場合は空文字）
+func unsupportedMetricReason(m pmetric.Metric) string {
+	var points int
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		points = m.Gauge().DataPoints().Len()
+	case pmetric.MetricTypeSum:
+		points = m.Sum().DataPoints().Len()
+	case pmetric.MetricTypeHistogram:
+		points = m.Histogram().DataPoints().Len()
+	case pmetric.MetricTypeExponentialHistogram:
+		points = m.ExponentialHistogram().DataPoints().Len()
+	case pmetric.MetricTypeSummary:
+		points = m.Summary().DataPoints().Len()
+	default:
+		return "unknown_type"
+	}
+	if points == 0 {
+		return "no_data_points"
+	}
+	return ""
+}
+
+// applyUnsupportedMetrics は unsupported_metrics に従って型が不明・データポイントのないメトリクスを処理します
+// reject の場合は該当するメトリクスがあればリトライしない永続的なエラーを返し、skip・warn の場合はバッチから取り除きます
+func (e *metricsExporter) applyUnsupportedMetrics(md pmetric.Metrics) error {
+	policy := e.config.UnsupportedMetrics
+	if policy == "" || policy == unsupportedMetricsKeep {
+		return nil
+	}
+	var names []string
+	count := 0
+	for _, rm := range md.ResourceMetrics().All() {
+		for _, sm := range rm.ScopeMetrics().All() {
+			sm.Metrics().RemoveIf(func(m pmetric.Metric) bool {
+				reason := unsupportedMetricReason(m)
+				if reason == "" {
+					return false
+				}
+				count++
+				if len(names) < maxLoggedMetricNames {
+					names = append(names, fmt.Sprintf("%s(%s)", m.Name(), reason))
+				}
+				return policy != unsupportedMetricsReject
+			})
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	if policy == unsupportedMetricsReject {
+		err := fmt.Errorf("型が不明、またはデータポイントのないメトリクスが%d件含まれています: %s", count, strings.Join(names, ", "))
+		e.diag.recordError("metrics", err)
+		return consumererror.NewPermanent(err)
+	}
+	e.diag.recordDropped("metrics", stable.DropReasonUnsupportedMetric, count)
+	e.events.batchDropped(stable.DropReasonUnsupportedMetric, count)
+	if policy == unsupportedMetricsWarn {
+		e.logger.Warn("型が不明、またはデータポイントのないメトリクスを取り除きました",
+			zap.Int("dropped_metrics", count), zap.Strings("metrics", names))
+	}
+	return nil
+}
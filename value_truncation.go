@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package myexporter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/dtamura/myexporter/stable"
+)
+
+// truncatedColumn は属性値・本文を短縮した行を示す列の名前です（挿入SQLの基本の列の直後に追加される）
+const truncatedColumn = stable.ColumnTruncated
+
+// validateValueLengths は max_attribute_length と max_body_length を検証します
+func (cfg *Config) validateValueLengths() error {
+	var errs error
+	if cfg.MaxAttributeLength < 0 {
+		errs = errors.Join(errs, fmt.Errorf("max_attribute_length は0（無制限）以上である必要があります: %d", cfg.MaxAttributeLength))
+	}
+	if cfg.MaxBodyLength < 0 {
+		errs = errors.Join(errs, fmt.Errorf("max_body_length は0（無制限）以上である必要があります: %d", cfg.MaxBodyLength))
+	}
+	return errs
+}
+
+// truncatedColumnEnabled は Truncated 列を作成・挿入するかどうかを返します
+// トレースは属性値のみ、ログは属性値と本文の短縮が対象で、driver: postgres では列を作成しません
+func (cfg *Config) truncatedColumnEnabled(signal string) bool {
+	if cfg.isPostgres() {
+		return false
+	}
+	return cfg.MaxAttributeLength > 0 || (signal == "logs" && cfg.MaxBodyLength > 0)
+}
+
+// truncatedInsertColumns は Truncated 列が有効な場合に列を追加した挿入列を返します
+func (cfg *Config) truncatedInsertColumns(signal string, columns []string) []string {
+	if !cfg.truncatedColumnEnabled(signal) {
+		return columns
+	}
+	return slices.Concat(columns, []string{truncatedColumn})
+}
+
+// stampTruncated は各行の末尾に値を短縮したかどうかを追加します（Truncated 列が無効の場合は何もしない）
+// truncated は変換した行と同じ順の短縮の有無です
+func (cfg *Config) stampTruncated(signal string, rows [][]any, truncated []bool) int {
+	if !cfg.truncatedColumnEnabled(signal) {
+		return 0
+	}
+	count := 0
+	for i, row := range rows {
+		t := i < len(truncated) && truncated[i]
+		if t {
+			count++
+		}
+		rows[i] = append(row, t)
+	}
+	return count
+}